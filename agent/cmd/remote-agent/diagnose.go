@@ -0,0 +1,52 @@
+//go:build windows || darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/stangtennis/Remote/netcheck"
+	"github.com/stangtennis/remote-agent/internal/auth"
+	"github.com/stangtennis/remote-agent/internal/config"
+	"github.com/stangtennis/remote-agent/internal/webrtc"
+)
+
+// runDiagnose runs the network diagnostics (--diagnose) and prints the
+// report. Uses the saved login for TURN credentials when available; without
+// it only STUN and the env-var TURN fallback are tested.
+func runDiagnose(jsonOut bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ Kunne ikke indlæse config: %v\n", err)
+		os.Exit(1)
+	}
+
+	token := ""
+	if creds, err := auth.LoadCredentials(); err == nil {
+		tp := auth.NewTokenProvider(auth.AuthConfig{SupabaseURL: cfg.SupabaseURL, AnonKey: cfg.SupabaseAnonKey}, creds)
+		token, _ = tp.GetToken()
+	}
+	if token == "" && !jsonOut {
+		fmt.Println("⚠️  Ikke logget ind — TURN credentials kan ikke hentes, tester kun STUN")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	servers := webrtc.ICEServers(cfg.SupabaseURL, cfg.SupabaseAnonKey, token, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report := netcheck.Run(ctx, netcheck.Options{
+		ICEServers:  servers,
+		SupabaseURL: cfg.SupabaseURL,
+	})
+
+	if jsonOut {
+		report.WriteJSON(os.Stdout)
+		return
+	}
+	report.WriteText(os.Stdout)
+}
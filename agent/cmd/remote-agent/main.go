@@ -25,6 +25,8 @@ import (
 	"github.com/stangtennis/remote-agent/internal/desktop"
 	"github.com/stangtennis/remote-agent/internal/device"
 	"github.com/stangtennis/remote-agent/internal/metrics"
+	"github.com/stangtennis/remote-agent/internal/printer"
+	"github.com/stangtennis/remote-agent/internal/safemode"
 	"github.com/stangtennis/remote-agent/internal/screen"
 	"github.com/stangtennis/remote-agent/internal/tray"
 	"github.com/stangtennis/remote-agent/internal/updater"
@@ -329,6 +331,13 @@ func main() {
 		return
 	}
 
+	// Network diagnostics need no admin rights and must work even when the
+	// agent cannot connect, so handle them before elevation and service setup.
+	if hasArgument("--diagnose") || hasArgument("-diagnose") {
+		runDiagnose(hasArgument("--json") || hasArgument("-json"))
+		return
+	}
+
 	// Portable support is an explicit, temporary mode. It may elevate via UAC
 	// for approved admin actions, but it never installs a service or firewall rule.
 	programName := strings.ToLower(filepath.Base(os.Args[0]))
@@ -365,6 +374,8 @@ func main() {
 	helpFlag := flag.Bool("help", false, "Show help")
 	silentFlag := flag.Bool("silent", false, "Run without GUI prompts")
 	consoleFlag := flag.Bool("console", false, "Run in console mode without system tray (full logging)")
+	installPrinterFlag := flag.Bool("install-printer", false, "Install the Remote Desktop virtual printer")
+	uninstallPrinterFlag := flag.Bool("uninstall-printer", false, "Remove the Remote Desktop virtual printer")
 	flag.Parse()
 
 	// Handle command-line flags (for advanced users / scripting)
@@ -386,6 +397,23 @@ func main() {
 		}
 		return
 	}
+	if *installPrinterFlag {
+		if err := printer.Install(); err != nil {
+			fmt.Printf("❌ Kunne ikke installere printer: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Printeren \"%s\" er installeret.\n", printer.Name)
+		fmt.Println("   Genstart agenten, så udskrifter kan sendes til supporteren.")
+		return
+	}
+	if *uninstallPrinterFlag {
+		if err := printer.Uninstall(); err != nil {
+			fmt.Printf("❌ Kunne ikke fjerne printer: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Printeren er fjernet.")
+		return
+	}
 	if *startFlag {
 		if err := startService(); err != nil {
 			fmt.Printf("❌ Kunne ikke starte service: %v\n", err)
@@ -959,6 +987,9 @@ func printUsage() {
 	fmt.Println("  remote-agent.exe -start       Start the Windows Service")
 	fmt.Println("  remote-agent.exe -stop        Stop the Windows Service")
 	fmt.Println("  remote-agent.exe -status      Show service status")
+	fmt.Println("  remote-agent.exe -install-printer    Install the virtual printer (requires Admin)")
+	fmt.Println("  remote-agent.exe -uninstall-printer  Remove the virtual printer (requires Admin)")
+	fmt.Println("  remote-agent.exe --diagnose   Check NAT type, TURN reachability, MTU and clock skew (--json for JSON)")
 	fmt.Println("  remote-agent.exe -help        Show this help")
 	fmt.Println()
 	fmt.Println("Service Mode:")
@@ -1385,6 +1416,10 @@ func (s *windowsService) Execute(args []string, r <-chan svc.ChangeRequest, chan
 	// Sikr SCM recovery er korrekt konfigureret (auto-fix eksisterende installationer)
 	ensureRecoveryConfig()
 
+	// Efter genstart i fejlsikret tilstand: ryd boot-flaget i Safe Mode og
+	// fjern SafeBoot-registreringen efter næste normale opstart
+	safemode.Reconcile()
+
 	// Auto-update timers (30m interval; startup check still runs after 2m)
 	updateTicker := time.NewTicker(serviceAutoUpdateInterval)
 	defer updateTicker.Stop()
@@ -1984,6 +2019,13 @@ func serviceCheckAndApplyUpdate(forceCheck bool) bool {
 		return false
 	}
 
+	// Forrige opdatering er ikke verificeret endnu — dens .old skal blive
+	// liggende til en eventuel rollback
+	if updater.UpdatePending() {
+		log.Println("⏭️  Service update: forrige opdatering verificeres stadig")
+		return false
+	}
+
 	// Opryd .old filer fra forrige update
 	cleanupOldBinaries()
 
@@ -2079,6 +2121,7 @@ func serviceCheckAndApplyUpdate(forceCheck bool) bool {
 	// vi har downloaded den FRA samme path som console (begge bygges
 	// fra samme kode med forskellige ldflags). Vi peger på den anden
 	// binær i samme mappe og kopierer den om muligt.
+	replaced := []updater.ReplacedFile{{Path: currentExe, Backup: oldExe}}
 	if runtime.GOOS == "windows" {
 		guiExe := filepath.Join(filepath.Dir(currentExe), "remote-agent.exe")
 		if guiExe != currentExe { // service kører console.exe, GUI ligger ved siden
@@ -2094,9 +2137,19 @@ func serviceCheckAndApplyUpdate(forceCheck bool) bool {
 			// updater-instans og henter remote-agent (uden -console).
 			guiURL := "https://updates.hawkeye123.dk/remote-agent-" + info.TagName + ".exe"
 			tmpGUI := guiExe + ".new"
-			if dlErr := downloadHTTP(guiURL, tmpGUI); dlErr == nil {
+			dlErr := downloadHTTP(guiURL, tmpGUI)
+			if dlErr == nil {
+				// Samme krav som service-binæren: ingen gyldig signatur, ingen installation
+				if dlErr = updater.NewDownloader().VerifySignature(tmpGUI, guiURL); dlErr != nil {
+					os.Remove(tmpGUI)
+				}
+			}
+			if dlErr == nil {
 				if cpErr := os.Rename(tmpGUI, guiExe); cpErr == nil {
 					log.Printf("✅ GUI tray-binær også opdateret: %s", guiExe)
+					if renamed {
+						replaced = append(replaced, updater.ReplacedFile{Path: guiExe, Backup: oldGUI})
+					}
 				} else {
 					log.Printf("⚠️ GUI tray-binær kopi fejlede: %v", cpErr)
 					if renamed {
@@ -2112,6 +2165,12 @@ func serviceCheckAndApplyUpdate(forceCheck bool) bool {
 		}
 	}
 
+	// Den nye version skal forbinde inden for health-timeout, ellers
+	// ruller den selv tilbage til .old (se device/update_health.go)
+	if err := u.MarkInstalled(replaced...); err != nil {
+		log.Printf("⚠️ Service update: kunne ikke markere opdatering til health check: %v", err)
+	}
+
 	// Opryd gamle downloads (behold kun den nye version)
 	u.CleanOldDownloads(info.TagName)
 
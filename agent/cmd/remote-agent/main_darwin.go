@@ -17,6 +17,7 @@ import (
 	"github.com/stangtennis/remote-agent/internal/device"
 	"github.com/stangtennis/remote-agent/internal/input"
 	"github.com/stangtennis/remote-agent/internal/metrics"
+	"github.com/stangtennis/remote-agent/internal/printer"
 	"github.com/stangtennis/remote-agent/internal/screen"
 	"github.com/stangtennis/remote-agent/internal/tray"
 	"github.com/stangtennis/remote-agent/internal/updater"
@@ -63,6 +64,10 @@ func main() {
 	consoleFlag := flag.Bool("console", false, "Run in console mode (full logging)")
 	logoutFlag := flag.Bool("logout", false, "Log out and clear saved credentials")
 	helpFlag := flag.Bool("help", false, "Show help")
+	diagnoseFlag := flag.Bool("diagnose", false, "Run network diagnostics and exit")
+	jsonFlag := flag.Bool("json", false, "JSON output for --diagnose")
+	installPrinterFlag := flag.Bool("install-printer", false, "Install the Remote Desktop virtual printer (requires root)")
+	uninstallPrinterFlag := flag.Bool("uninstall-printer", false, "Remove the Remote Desktop virtual printer (requires root)")
 	flag.Parse()
 
 	if *helpFlag {
@@ -70,6 +75,28 @@ func main() {
 		return
 	}
 
+	if *diagnoseFlag {
+		runDiagnose(*jsonFlag)
+		return
+	}
+
+	if *installPrinterFlag {
+		if err := printer.Install(); err != nil {
+			fmt.Printf("Could not install printer: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Printer %q installed. Restart the agent to forward print jobs.\n", printer.Name)
+		return
+	}
+	if *uninstallPrinterFlag {
+		if err := printer.Uninstall(); err != nil {
+			fmt.Printf("Could not remove printer: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Printer removed.")
+		return
+	}
+
 	if *logoutFlag {
 		if err := auth.ClearCredentials(); err != nil {
 			fmt.Printf("Could not clear credentials: %v\n", err)
@@ -101,6 +128,9 @@ func printUsage() {
 	fmt.Println("  remote-agent              Run interactively (with system tray)")
 	fmt.Println("  remote-agent --console    Run in console mode (full logging)")
 	fmt.Println("  remote-agent --logout     Clear saved credentials")
+	fmt.Println("  remote-agent --install-printer     Install the virtual printer (sudo)")
+	fmt.Println("  remote-agent --uninstall-printer   Remove the virtual printer (sudo)")
+	fmt.Println("  remote-agent --diagnose   Check NAT type, TURN reachability, MTU and clock skew (--json for JSON)")
 	fmt.Println("  remote-agent --help       Show this help")
 }
 
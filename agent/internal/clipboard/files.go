@@ -0,0 +1,67 @@
+package clipboard
+
+import (
+	"log"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// File lists are what Explorer, Finder and Linux file managers put on the
+// clipboard when files are copied (CF_HDROP, file URLs, text/uri-list).
+// Only the paths are read here; the content is transferred when pasted.
+
+// ParseURIList returns the local paths in a text/uri-list (RFC 2483).
+// Comments and non-file URIs are skipped.
+func ParseURIList(list string) []string {
+	var paths []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+			continue
+		}
+		path := u.Path
+		if runtime.GOOS == "windows" {
+			path = filepath.FromSlash(strings.TrimPrefix(path, "/"))
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// URIList formats paths as a text/uri-list.
+func URIList(paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		p = filepath.ToSlash(p)
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p // Windows drive path
+		}
+		b.WriteString((&url.URL{Scheme: "file", Path: p}).String())
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// hashPaths identifies a file list for change detection.
+func hashPaths(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	return hashString(strings.Join(paths, "\n"))
+}
+
+// SetFiles puts a file list on the clipboard, as if the files had been
+// copied in the file manager.
+func (r *Receiver) SetFiles(paths []string) error {
+	if err := writeFiles(paths); err != nil {
+		return err
+	}
+	log.Printf("📋 Agent clipboard updated with %d file(s)", len(paths))
+	return nil
+}
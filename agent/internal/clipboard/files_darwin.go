@@ -0,0 +1,79 @@
+//go:build darwin
+
+package clipboard
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+#include <stdlib.h>
+
+static long pbChangeCount(void) {
+    return [[NSPasteboard generalPasteboard] changeCount];
+}
+
+// pbReadFiles returns the copied files as newline-separated paths, or NULL.
+// Finder's NSFilenamesPboardType is bridged to file URLs by the pasteboard.
+static char *pbReadFiles(void) {
+    @autoreleasepool {
+        NSArray *urls = [[NSPasteboard generalPasteboard]
+            readObjectsForClasses:@[[NSURL class]]
+            options:@{NSPasteboardURLReadingFileURLsOnlyKey: @YES}];
+        if (urls.count == 0) {
+            return NULL;
+        }
+        NSMutableArray *paths = [NSMutableArray arrayWithCapacity:urls.count];
+        for (NSURL *u in urls) {
+            [paths addObject:u.path];
+        }
+        return strdup([[paths componentsJoinedByString:@"\n"] UTF8String]);
+    }
+}
+
+static int pbWriteFiles(const char *joined) {
+    @autoreleasepool {
+        NSMutableArray *urls = [NSMutableArray array];
+        for (NSString *p in [[NSString stringWithUTF8String:joined] componentsSeparatedByString:@"\n"]) {
+            [urls addObject:[NSURL fileURLWithPath:p]];
+        }
+        NSPasteboard *pb = [NSPasteboard generalPasteboard];
+        [pb clearContents];
+        return [pb writeObjects:urls] ? 1 : 0;
+    }
+}
+*/
+import "C"
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// changeCount is the general pasteboard's change count.
+func changeCount() (uint64, bool) {
+	return uint64(C.pbChangeCount()), true
+}
+
+// readFiles reads the file URLs Finder puts on the pasteboard.
+func readFiles() ([]string, bool) {
+	joined := C.pbReadFiles()
+	if joined == nil {
+		return nil, false
+	}
+	defer C.free(unsafe.Pointer(joined))
+	return strings.Split(C.GoString(joined), "\n"), true
+}
+
+// writeFiles puts file URLs on the pasteboard, which Finder pastes as a
+// copy of the files.
+func writeFiles(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no files")
+	}
+	joined := C.CString(strings.Join(paths, "\n"))
+	defer C.free(unsafe.Pointer(joined))
+	if C.pbWriteFiles(joined) == 0 {
+		return fmt.Errorf("pasteboard refused the file list")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build !windows && !darwin
+
+package clipboard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stangtennis/Remote/linuxclip"
+)
+
+// readFiles reads the text/uri-list target that file managers offer.
+func readFiles() ([]string, bool) {
+	conn, err := selection()
+	if err != nil {
+		return nil, false
+	}
+	data, _, err := linuxclip.ReadFirst(conn, linuxclip.Clipboard, []string{"text/uri-list"})
+	if err != nil {
+		return nil, false
+	}
+	paths := ParseURIList(string(data))
+	return paths, len(paths) > 0
+}
+
+// writeFiles offers the paths as text/uri-list, plus the list GNOME and
+// its derivatives paste files from.
+func writeFiles(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no files")
+	}
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	uris := URIList(paths)
+	gnome := "copy\n" + strings.Join(strings.Fields(uris), "\n")
+	return conn.Write(linuxclip.Clipboard, []linuxclip.Item{
+		{Type: "text/uri-list", Data: []byte(uris)},
+		{Type: "x-special/gnome-copied-files", Data: []byte(gnome)},
+	})
+}
@@ -0,0 +1,22 @@
+package clipboard
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseURIList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix paths")
+	}
+	list := "# copied from Files\r\nfile:///home/ann/My%20Report.pdf\r\nfile://localhost/tmp/a.txt\r\nhttps://example.com/x\r\nfile://otherhost/share/b\r\n"
+	got := ParseURIList(list)
+	want := []string{"/home/ann/My Report.pdf", "/tmp/a.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseURIList = %q, want %q", got, want)
+	}
+	if back := ParseURIList(URIList(want)); !reflect.DeepEqual(back, want) {
+		t.Fatalf("round trip = %q, want %q", back, want)
+	}
+}
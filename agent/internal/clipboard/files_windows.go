@@ -0,0 +1,123 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	cfHDrop        = 15
+	gMemZeroInit   = 0x0040
+	dropEffectCopy = 1
+)
+
+var (
+	shell32                      = windows.NewLazySystemDLL("shell32.dll")
+	procDragQueryFileW           = shell32.NewProc("DragQueryFileW")
+	procRegisterClipboardFormatW = user32.NewProc("RegisterClipboardFormatW")
+	procGlobalFree               = kernel32.NewProc("GlobalFree")
+)
+
+// changeCount is the clipboard sequence number.
+func changeCount() (uint64, bool) {
+	return uint64(rawSequence()), true
+}
+
+// readFiles reads CF_HDROP, the list Explorer puts on the clipboard.
+func readFiles() ([]string, bool) {
+	if ret, _, _ := procIsClipboardFormatAvailable.Call(cfHDrop); ret == 0 {
+		return nil, false // No need to open the clipboard
+	}
+	if !rawOpen(5) {
+		return nil, false
+	}
+	defer rawClose()
+
+	hDrop, _, _ := procGetClipboardData.Call(cfHDrop)
+	if hDrop == 0 {
+		return nil, false
+	}
+	n, _, _ := procDragQueryFileW.Call(hDrop, 0xFFFFFFFF, 0, 0)
+	paths := make([]string, 0, n)
+	for i := uintptr(0); i < n; i++ {
+		size, _, _ := procDragQueryFileW.Call(hDrop, i, 0, 0)
+		buf := make([]uint16, size+1)
+		procDragQueryFileW.Call(hDrop, i, uintptr(unsafe.Pointer(&buf[0])), size+1)
+		paths = append(paths, windows.UTF16ToString(buf))
+	}
+	return paths, len(paths) > 0
+}
+
+// writeFiles sets CF_HDROP (a DROPFILES header followed by a double-NUL
+// terminated UTF-16 list) plus "Preferred DropEffect" so Explorer copies
+// rather than moves on paste.
+func writeFiles(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no files")
+	}
+	var list []uint16
+	for _, p := range paths {
+		list = append(list, utf16.Encode([]rune(p))...)
+		list = append(list, 0)
+	}
+	list = append(list, 0)
+
+	const headerSize = 20 // DROPFILES: pFiles, pt.x, pt.y, fNC, fWide
+	drop := make([]byte, headerSize+2*len(list))
+	binary.LittleEndian.PutUint32(drop[0:], headerSize)
+	binary.LittleEndian.PutUint32(drop[16:], 1) // fWide
+	for i, c := range list {
+		binary.LittleEndian.PutUint16(drop[headerSize+2*i:], c)
+	}
+	effect := make([]byte, 4)
+	binary.LittleEndian.PutUint32(effect, dropEffectCopy)
+
+	clipboardWriteMu.Lock()
+	defer clipboardWriteMu.Unlock()
+	if !rawOpen(20) {
+		return fmt.Errorf("OpenClipboard failed after retries")
+	}
+	defer rawClose()
+	procEmptyClipboard.Call()
+
+	if err := setClipboardBytes(cfHDrop, drop); err != nil {
+		return err
+	}
+	name, _ := windows.UTF16PtrFromString("Preferred DropEffect")
+	if format, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(name))); format != 0 {
+		_ = setClipboardBytes(format, effect) // Optional; paste still works without it
+	}
+	return nil
+}
+
+// setClipboardBytes copies data into global memory and hands it to the
+// open clipboard, which then owns it.
+func setClipboardBytes(format uintptr, data []byte) error {
+	r, _, _ := procGlobalAlloc.Call(gMemMoveable|gMemZeroInit, uintptr(len(data)))
+	hMem := windows.Handle(r)
+	if hMem == 0 {
+		return fmt.Errorf("GlobalAlloc failed")
+	}
+	ptr, _, _ := procGlobalLock.Call(uintptr(hMem))
+	if ptr == 0 {
+		procGlobalFree.Call(uintptr(hMem))
+		return fmt.Errorf("GlobalLock failed")
+	}
+	basePtr := unsafe.Pointer(ptr)
+	for i, b := range data {
+		*(*byte)(unsafe.Add(basePtr, i)) = b
+	}
+	procGlobalUnlock.Call(uintptr(hMem))
+
+	if r, _, _ := procSetClipboardData.Call(format, uintptr(hMem)); r == 0 {
+		procGlobalFree.Call(uintptr(hMem)) // Still ours: ownership only passes on success
+		return fmt.Errorf("SetClipboardData failed")
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ package clipboard
 import "fmt"
 
 // SessionHelper is a no-op stub on non-Windows platforms — Windows is the
-// only OS where the agent runs in a different session than the user.
+// only OS where the agent runs in a different session than the user. On
+// Linux the monitor connects to the session's X11 or Wayland display
+// itself (see system_other.go).
 type SessionHelper struct{}
 
 func NewSessionHelper() *SessionHelper                                 { return &SessionHelper{} }
@@ -13,10 +15,16 @@ func (h *SessionHelper) Start() error                                  { return
 func (h *SessionHelper) Stop()                                         {}
 func (h *SessionHelper) SetOnTextChange(_ func(string))                {}
 func (h *SessionHelper) SetOnImageChange(_ func([]byte))               {}
+func (h *SessionHelper) SetOnFilesChange(_ func([]string))             {}
+func (h *SessionHelper) SetOnRichChange(_ func(Rich))                  {}
+func (h *SessionHelper) Files() []string                               { return nil }
 func (h *SessionHelper) SetText(_ string) error                        { return nil }
 func (h *SessionHelper) SetImage(_ []byte) error                       { return nil }
+func (h *SessionHelper) SetFiles(_ []string) error                     { return nil }
+func (h *SessionHelper) SetRich(_, _, _ string) error                  { return nil }
 func (h *SessionHelper) RememberText(_ string)                         {}
 func (h *SessionHelper) RememberImage(_ []byte)                        {}
+func (h *SessionHelper) RememberRich(_, _ string)                      {}
 
 // RunHelper is unreachable on non-windows; kept for build symmetry.
 func RunHelper(_ string) error { return fmt.Errorf("clipboard helper only on windows") }
@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 	"unsafe"
@@ -40,6 +41,9 @@ type SessionHelper struct {
 	cancelCtx     context.CancelFunc
 	onTextChange  func(text string)
 	onImageChange func(imageData []byte)
+	onFilesChange func(paths []string)
+	onRichChange  func(rich Rich)
+	files         []string // Last file list the helper reported
 	pipeName      string
 	closed        bool
 }
@@ -90,6 +94,15 @@ func NewSessionHelper() *SessionHelper {
 
 func (h *SessionHelper) SetOnTextChange(cb func(text string))         { h.onTextChange = cb }
 func (h *SessionHelper) SetOnImageChange(cb func(imageData []byte))   { h.onImageChange = cb }
+func (h *SessionHelper) SetOnFilesChange(cb func(paths []string))      { h.onFilesChange = cb }
+func (h *SessionHelper) SetOnRichChange(cb func(rich Rich))            { h.onRichChange = cb }
+
+// Files returns the file list on the user's clipboard, if any.
+func (h *SessionHelper) Files() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.files
+}
 
 // Start brings up the named pipe server, launches the helper as the active
 // console user, and begins consuming messages.
@@ -188,6 +201,9 @@ func (h *SessionHelper) readLoop(ctx context.Context, pipe windows.Handle) {
 			var msg struct {
 				Type    string `json:"type"`
 				Content string `json:"content"`
+				HTML    string `json:"html"`
+				RTF     string `json:"rtf"`
+				Text    string `json:"text"`
 			}
 			if err := json.Unmarshal(body, &msg); err != nil {
 				log.Printf("⚠️  Clipboard helper sent bad JSON: %v", err)
@@ -204,6 +220,22 @@ func (h *SessionHelper) readLoop(ctx context.Context, pipe windows.Handle) {
 				if err == nil && h.onImageChange != nil {
 					h.onImageChange(data)
 				}
+			case "files":
+				// Newline-separated paths; empty when files left the clipboard
+				var paths []string
+				if msg.Content != "" {
+					paths = strings.Split(msg.Content, "\n")
+				}
+				h.mu.Lock()
+				h.files = paths
+				h.mu.Unlock()
+				if h.onFilesChange != nil {
+					h.onFilesChange(paths)
+				}
+			case "rich":
+				if h.onRichChange != nil {
+					h.onRichChange(Rich{HTML: msg.HTML, RTF: msg.RTF, Text: msg.Text})
+				}
 			}
 		}
 	}
@@ -214,6 +246,9 @@ func (h *SessionHelper) readLoop(ctx context.Context, pipe windows.Handle) {
 // the clipboard from the controller, bypassing the helper entirely.
 func (h *SessionHelper) SetText(text string) error  { _ = text; return nil }
 func (h *SessionHelper) SetImage(pngData []byte) error { _ = pngData; return nil }
+func (h *SessionHelper) SetFiles(paths []string) error  { _ = paths; return nil }
+func (h *SessionHelper) SetRich(_, _, _ string) error    { return nil }
+func (h *SessionHelper) RememberRich(_, _ string)        {}
 func (h *SessionHelper) RememberText(_ string)         {}
 func (h *SessionHelper) RememberImage(_ []byte)        {}
 
@@ -293,7 +328,7 @@ func RunHelper(pipeName string) error {
 	// (no OpenClipboard required) plus raw OpenClipboard with bounded
 	// retries for the actual read. Bypasses golang.design/x/clipboard
 	// whose internal OpenClipboard retry loop hangs in this context.
-	var lastTextHash string
+	var lastTextHash, lastFilesHash, lastRichHash string
 	go func() {
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
@@ -336,6 +371,48 @@ func RunHelper(pipeName string) error {
 			log.Printf("📋 seq changed: %d → %d", lastSeq, seq)
 			lastSeq = seq
 
+			// Files copied in Explorer come as CF_HDROP; only the paths
+			// are forwarded, the service reads the files itself.
+			paths, _ := readFiles()
+			if fh := hashPaths(paths); fh != lastFilesHash {
+				lastFilesHash = fh
+				body, _ := json.Marshal(map[string]interface{}{
+					"type":    "files",
+					"content": strings.Join(paths, "\n"),
+				})
+				if err := w.Write(body); err != nil {
+					log.Printf("write files event failed: %v", err)
+					cancel()
+					return
+				}
+				log.Printf("📋 sent files event (%d file(s), seq=%d)", len(paths), seq)
+			}
+			if len(paths) > 0 {
+				continue
+			}
+
+			// Formatted copies (Office, browsers) come with an HTML or
+			// RTF rendering beside the text read below.
+			rich, _ := readRich()
+			format, content, _ := rich.Preferred()
+			if rh := hashRich(format, content); rh != lastRichHash {
+				lastRichHash = rh
+				if rh != "" {
+					body, _ := json.Marshal(map[string]interface{}{
+						"type": "rich",
+						"html": rich.HTML,
+						"rtf":  rich.RTF,
+						"text": rich.Text,
+					})
+					if err := w.Write(body); err != nil {
+						log.Printf("write rich event failed: %v", err)
+						cancel()
+						return
+					}
+					log.Printf("📋 sent rich event (%s, %d bytes, seq=%d)", format, len(content), seq)
+				}
+			}
+
 			text, ok := rawReadText()
 			if !ok || text == "" {
 				log.Printf("rawReadText after seq change: ok=%v len=%d", ok, len(text))
@@ -8,16 +8,22 @@ import (
 	"image"
 	"image/png"
 	"log"
-
-	"golang.design/x/clipboard"
+	"sync"
+	"time"
 )
 
 // Monitor watches the system clipboard for changes using native events
 type Monitor struct {
 	lastTextHash  string
 	lastImageHash string
+	lastFilesHash string
+	lastRichHash  string
 	onTextChange  func(text string)
 	onImageChange func(imageData []byte)
+	onFilesChange func(paths []string)
+	onRichChange  func(rich Rich)
+	filesMu       sync.Mutex
+	files         []string
 	cancelFunc    context.CancelFunc
 	running       bool
 }
@@ -37,9 +43,27 @@ func (m *Monitor) SetOnImageChange(callback func(imageData []byte)) {
 	m.onImageChange = callback
 }
 
+// SetOnFilesChange sets the callback for file list changes. It gets nil
+// when the clipboard stops holding files.
+func (m *Monitor) SetOnFilesChange(callback func(paths []string)) {
+	m.onFilesChange = callback
+}
+
+// SetOnRichChange sets the callback for HTML/RTF clipboard changes.
+func (m *Monitor) SetOnRichChange(callback func(rich Rich)) {
+	m.onRichChange = callback
+}
+
+// Files returns the file list currently on the clipboard, if any.
+func (m *Monitor) Files() []string {
+	m.filesMu.Lock()
+	defer m.filesMu.Unlock()
+	return m.files
+}
+
 // Start begins monitoring the clipboard using native OS events (no polling)
 func (m *Monitor) Start() error {
-	err := clipboard.Init()
+	err := initClipboard()
 	if err != nil {
 		return err
 	}
@@ -49,12 +73,12 @@ func (m *Monitor) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelFunc = cancel
 
-	// Use clipboard.Watch for native event-driven monitoring (uses WM_CLIPBOARDUPDATE on Windows)
-	textCh := clipboard.Watch(ctx, clipboard.FmtText)
-	imgCh := clipboard.Watch(ctx, clipboard.FmtImage)
+	// Native event-driven monitoring (WM_CLIPBOARDUPDATE on Windows, XFixes on X11)
+	textCh, imgCh := watchClipboard(ctx)
 
 	go m.watchText(textCh)
 	go m.watchImage(imgCh)
+	go m.watchNative(ctx)
 
 	log.Println("📋 Clipboard monitor started (native events)")
 	return nil
@@ -115,6 +139,74 @@ func (m *Monitor) watchImage(ch <-chan []byte) {
 	}
 }
 
+// watchNative polls for file lists and rich text, which watchClipboard
+// does not report. The clipboard is only read after its change counter
+// moved, where the platform has one.
+func (m *Monitor) watchNative(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var lastCount uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if count, ok := changeCount(); ok {
+			if count == lastCount {
+				continue
+			}
+			lastCount = count
+		}
+		paths, _ := readFiles()
+		hash := hashPaths(paths)
+
+		m.filesMu.Lock()
+		changed := hash != m.lastFilesHash
+		m.lastFilesHash = hash
+		m.files = paths
+		m.filesMu.Unlock()
+
+		if changed {
+			log.Printf("📋 File clipboard changed (%d file(s))", len(paths))
+			if m.onFilesChange != nil {
+				m.onFilesChange(paths)
+			}
+		}
+		if len(paths) == 0 {
+			m.checkRich()
+		}
+	}
+}
+
+// checkRich reports HTML or RTF that differs from the last seen.
+func (m *Monitor) checkRich() {
+	rich, _ := readRich()
+	format, content, ok := rich.Preferred()
+	hash := hashRich(format, content)
+	if hash == m.lastRichHash {
+		return
+	}
+	m.lastRichHash = hash
+	if ok && m.onRichChange != nil {
+		log.Printf("📋 Rich clipboard changed (%s, %d bytes)", format, len(content))
+		m.onRichChange(rich)
+	}
+}
+
+// RememberFiles marks a file list as handled to avoid echo loops.
+func (m *Monitor) RememberFiles(paths []string) {
+	m.filesMu.Lock()
+	m.lastFilesHash = hashPaths(paths)
+	m.files = paths
+	m.filesMu.Unlock()
+}
+
+// RememberRich marks HTML or RTF as handled to avoid echo loops.
+func (m *Monitor) RememberRich(format, content string) {
+	m.lastRichHash = hashRich(format, content)
+}
+
 // RememberText marks text as handled to avoid echo loops when we just set the clipboard ourselves.
 func (m *Monitor) RememberText(text string) {
 	m.lastTextHash = hashString(text)
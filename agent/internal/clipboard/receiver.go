@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"image/png"
 	"log"
-
-	"golang.design/x/clipboard"
 )
 
 // Receiver applies incoming clipboard data locally on the agent.
@@ -21,7 +19,7 @@ func (r *Receiver) ensureInit() error {
 	if r.initialized {
 		return nil
 	}
-	if err := clipboard.Init(); err != nil {
+	if err := initClipboard(); err != nil {
 		return err
 	}
 	r.initialized = true
@@ -33,7 +31,9 @@ func (r *Receiver) SetText(text string) error {
 	if err := r.ensureInit(); err != nil {
 		return err
 	}
-	clipboard.Write(clipboard.FmtText, []byte(text))
+	if err := writeText(text); err != nil {
+		return err
+	}
 	log.Printf("?? Agent clipboard updated with text (%d bytes)", len(text))
 	return nil
 }
@@ -47,7 +47,9 @@ func (r *Receiver) SetImage(imageData []byte) error {
 	img, err := png.Decode(bytes.NewReader(imageData))
 	if err != nil {
 		// If decode fails, try raw write
-		clipboard.Write(clipboard.FmtImage, imageData)
+		if err := writeImage(imageData); err != nil {
+			return err
+		}
 		log.Printf("?? Agent clipboard set with raw image (%d bytes)", len(imageData))
 		return nil
 	}
@@ -57,7 +59,9 @@ func (r *Receiver) SetImage(imageData []byte) error {
 		return err
 	}
 
-	clipboard.Write(clipboard.FmtImage, buf.Bytes())
+	if err := writeImage(buf.Bytes()); err != nil {
+		return err
+	}
 	log.Printf("?? Agent clipboard updated with image (%d bytes)", buf.Len())
 	return nil
 }
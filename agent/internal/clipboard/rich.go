@@ -0,0 +1,38 @@
+package clipboard
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/stangtennis/Remote/richclip"
+)
+
+// Rich is the formatted content on the clipboard; see richclip.
+type Rich = richclip.Rich
+
+// Rich formats, named like the protocol's clipboard formats.
+const (
+	FormatHTML = richclip.FormatHTML
+	FormatRTF  = richclip.FormatRTF
+)
+
+// hashRich identifies rich content for change detection.
+func hashRich(format, content string) string {
+	if content == "" {
+		return ""
+	}
+	return hashString(format + "\x00" + content)
+}
+
+// SetRich puts HTML or RTF on the clipboard together with its plain-text
+// alternative.
+func (r *Receiver) SetRich(format, content, text string) error {
+	if format != FormatHTML && format != FormatRTF {
+		return fmt.Errorf("unknown rich format %q", format)
+	}
+	if err := writeRich(format, content, text); err != nil {
+		return err
+	}
+	log.Printf("📋 Agent clipboard updated with %s (%d bytes)", format, len(content))
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build darwin
+
+package clipboard
+
+import "github.com/stangtennis/Remote/richclip"
+
+func readRich() (Rich, bool) {
+	return richclip.Read()
+}
+
+func writeRich(format, content, text string) error {
+	return richclip.Write(format, content, text)
+}
@@ -0,0 +1,125 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/stangtennis/Remote/richclip"
+	"golang.org/x/sys/windows"
+)
+
+// Registered clipboard formats for rich text; zero until registered.
+var (
+	richFormatsOnce sync.Once
+	cfHTML, cfRTF   uintptr
+)
+
+func richFormats() (html, rtf uintptr) {
+	richFormatsOnce.Do(func() {
+		cfHTML = registerFormat("HTML Format")
+		cfRTF = registerFormat("Rich Text Format")
+	})
+	return cfHTML, cfRTF
+}
+
+func registerFormat(name string) uintptr {
+	p, _ := windows.UTF16PtrFromString(name)
+	format, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(p)))
+	return format
+}
+
+// readRich reads "HTML Format" and "Rich Text Format" plus the plain text
+// copied with them, in one clipboard open.
+func readRich() (Rich, bool) {
+	htmlFormat, rtfFormat := richFormats()
+	available := func(format uintptr) bool {
+		ret, _, _ := procIsClipboardFormatAvailable.Call(format)
+		return format != 0 && ret != 0
+	}
+	if !available(htmlFormat) && !available(rtfFormat) {
+		return Rich{}, false // No need to open the clipboard
+	}
+	if !rawOpen(5) {
+		return Rich{}, false
+	}
+	defer rawClose()
+
+	var r Rich
+	if data := clipboardBytes(htmlFormat); len(data) > 0 {
+		r.HTML = richclip.ParseCFHTML(bytes.TrimRight(data, "\x00"))
+	}
+	if data := clipboardBytes(rtfFormat); len(data) > 0 {
+		r.RTF = string(bytes.TrimRight(data, "\x00"))
+	}
+	if data := clipboardBytes(cfUnicodeText); len(data) >= 2 {
+		u16 := unsafe.Slice((*uint16)(unsafe.Pointer(&data[0])), len(data)/2)
+		r.Text = windows.UTF16ToString(u16)
+	}
+	return r, r.HTML != "" || r.RTF != ""
+}
+
+// clipboardBytes copies one format off the open clipboard, capped at
+// richclip.MaxBytes.
+func clipboardBytes(format uintptr) []byte {
+	if format == 0 {
+		return nil
+	}
+	hData, _, _ := procGetClipboardData.Call(format)
+	if hData == 0 {
+		return nil
+	}
+	size, _, _ := procGlobalSize.Call(hData)
+	if size == 0 || size > richclip.MaxBytes {
+		return nil
+	}
+	ptr, _, _ := procGlobalLock.Call(hData)
+	if ptr == 0 {
+		return nil
+	}
+	defer procGlobalUnlock.Call(hData)
+	data := make([]byte, size)
+	basePtr := unsafe.Pointer(ptr)
+	for i := range data {
+		data[i] = *(*byte)(unsafe.Add(basePtr, i))
+	}
+	return data
+}
+
+// writeRich sets HTML (as CF_HTML) or RTF together with CF_UNICODETEXT, so
+// both formatted and plain pastes work.
+func writeRich(format, content, text string) error {
+	htmlFormat, rtfFormat := richFormats()
+	var cf uintptr
+	var data []byte
+	switch format {
+	case FormatHTML:
+		cf, data = htmlFormat, append(richclip.BuildCFHTML(content), 0)
+	case FormatRTF:
+		cf, data = rtfFormat, append([]byte(content), 0)
+	}
+	if cf == 0 {
+		return fmt.Errorf("clipboard format %s not registered", format)
+	}
+
+	clipboardWriteMu.Lock()
+	defer clipboardWriteMu.Unlock()
+	if !rawOpen(20) {
+		return fmt.Errorf("OpenClipboard failed after retries")
+	}
+	defer rawClose()
+	procEmptyClipboard.Call()
+
+	if err := setClipboardBytes(cf, data); err != nil {
+		return err
+	}
+	if text != "" {
+		u16 := utf16.Encode([]rune(text + "\x00"))
+		_ = setClipboardBytes(cfUnicodeText, unsafe.Slice((*byte)(unsafe.Pointer(&u16[0])), 2*len(u16)))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build windows || darwin
+
+package clipboard
+
+import (
+	"context"
+
+	"golang.design/x/clipboard"
+)
+
+// initClipboard prepares golang.design/x/clipboard, which covers text and
+// images natively here.
+func initClipboard() error {
+	return clipboard.Init()
+}
+
+// watchClipboard delivers copied text and images, via WM_CLIPBOARDUPDATE
+// on Windows and the pasteboard change count on macOS.
+func watchClipboard(ctx context.Context) (text, img <-chan []byte) {
+	return clipboard.Watch(ctx, clipboard.FmtText), clipboard.Watch(ctx, clipboard.FmtImage)
+}
+
+func writeText(text string) error {
+	clipboard.Write(clipboard.FmtText, []byte(text))
+	return nil
+}
+
+func writeImage(data []byte) error {
+	clipboard.Write(clipboard.FmtImage, data)
+	return nil
+}
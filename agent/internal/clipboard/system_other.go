@@ -0,0 +1,142 @@
+//go:build !windows && !darwin
+
+package clipboard
+
+import (
+	"context"
+	_ "image/gif" // Copied images reach the controller as PNG via convertImageToPNG
+	_ "image/jpeg"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stangtennis/Remote/linuxclip"
+	_ "golang.org/x/image/bmp"
+)
+
+// The session's clipboard, shared by monitor and receiver. It lives as
+// long as the agent: on X11 what the agent copies is served from this
+// connection until another application copies.
+var (
+	selectionMu   sync.Mutex
+	selectionConn linuxclip.Conn
+)
+
+// selection connects on first use and retries after failures, as the
+// display may come up after the agent.
+func selection() (linuxclip.Conn, error) {
+	selectionMu.Lock()
+	defer selectionMu.Unlock()
+	if selectionConn != nil {
+		return selectionConn, nil
+	}
+	conn, err := linuxclip.Open()
+	if err != nil {
+		return nil, err
+	}
+	selectionConn = conn
+	return conn, nil
+}
+
+func initClipboard() error {
+	_, err := selection()
+	return err
+}
+
+// watchClipboard delivers the text and image on CLIPBOARD after each
+// change. PRIMARY is not watched: it changes with every mouse selection.
+func watchClipboard(ctx context.Context) (text, img <-chan []byte) {
+	textCh, imgCh := make(chan []byte), make(chan []byte)
+	go func() {
+		defer close(textCh)
+		defer close(imgCh)
+		conn, err := selection()
+		if err != nil {
+			return
+		}
+		changes, err := conn.Watch(ctx, linuxclip.Clipboard)
+		if err != nil {
+			log.Printf("❌ Clipboard watch failed: %v", err)
+			return
+		}
+		for range changes {
+			targets, err := conn.Targets(linuxclip.Clipboard)
+			if err != nil {
+				continue
+			}
+			for _, out := range []struct {
+				ch    chan []byte
+				types []string
+			}{{textCh, linuxclip.TextTypes}, {imgCh, linuxclip.ImageTypes}} {
+				i := slices.IndexFunc(out.types, func(t string) bool { return slices.Contains(targets, t) })
+				if i < 0 {
+					continue
+				}
+				data, err := conn.Read(linuxclip.Clipboard, out.types[i])
+				if err != nil {
+					continue
+				}
+				select {
+				case out.ch <- data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return textCh, imgCh
+}
+
+// writeText sets CLIPBOARD and PRIMARY, so both Ctrl+V and middle-click
+// paste it.
+func writeText(text string) error {
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	items := []linuxclip.Item{{Type: linuxclip.TextType, Data: []byte(text)}}
+	if err := conn.Write(linuxclip.Clipboard, items); err != nil {
+		return err
+	}
+	return conn.Write(linuxclip.Primary, items)
+}
+
+func writeImage(data []byte) error {
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	return conn.Write(linuxclip.Clipboard, []linuxclip.Item{{Type: "image/png", Data: data}})
+}
+
+// Clipboard changes counted by a watch started on first use.
+var (
+	changeWatchOnce sync.Once
+	changes         atomic.Uint64
+	changesWatched  atomic.Bool
+)
+
+// changeCount counts CLIPBOARD changes, so file lists and rich text are
+// only read after one.
+func changeCount() (uint64, bool) {
+	changeWatchOnce.Do(func() {
+		conn, err := selection()
+		if err != nil {
+			return
+		}
+		ch, err := conn.Watch(context.Background(), linuxclip.Clipboard)
+		if err != nil {
+			return
+		}
+		changes.Add(1) // Read what is already there
+		changesWatched.Store(true)
+		go func() {
+			for range ch {
+				changes.Add(1)
+			}
+			changesWatched.Store(false)
+		}()
+	})
+	return changes.Load(), changesWatched.Load()
+}
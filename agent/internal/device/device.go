@@ -38,6 +38,9 @@ type Device struct {
 	// som virker pålideligt på Windows.
 	forceUpdateHandler func() bool
 
+	// Queued device_jobs runner (see jobs.go)
+	jobs jobState
+
 	// Heartbeat health telemetry (atomic for lock-free reads from any goroutine)
 	consecutiveHeartbeatFailures int32 // updated by StartPresence
 	lastHeartbeatSuccess         int64 // unix timestamp; updated by StartPresence
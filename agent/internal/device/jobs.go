@@ -0,0 +1,570 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stangtennis/Remote/scriptlib"
+	"github.com/stangtennis/remote-agent/internal/filetransfer"
+	"github.com/stangtennis/remote-agent/internal/shell"
+)
+
+// Job types in device_jobs.job_type.
+const (
+	JobRunScript     = "run_script"
+	JobCopyFile      = "copy_file"
+	JobInstallUpdate = "install_update"
+	JobReboot        = "reboot"
+)
+
+const (
+	jobOutputTailBytes   = 4096
+	jobDefaultTimeoutSec = 600
+	jobMaxDownloadBytes  = 2 << 30 // 2 GB
+	jobFetchLimit        = 10
+)
+
+// Job is one row of device_jobs.
+type Job struct {
+	ID            string          `json:"id"`
+	JobType       string          `json:"job_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	RunAt         time.Time       `json:"run_at"`
+	ExpiresAt     *time.Time      `json:"expires_at"`
+	MaxAttempts   int             `json:"max_attempts"`
+	Attempts      int             `json:"attempts"`
+	RetryDelaySec int             `json:"retry_delay_sec"`
+}
+
+// jobResult is what a job reports back.
+type jobResult struct {
+	ExitCode   *int
+	OutputTail string
+	Err        error
+	// details are added to the audit event (script id and version)
+	details map[string]interface{}
+	// after runs once the result has been written (reboot, restart for update)
+	after func()
+}
+
+// runScriptPayload carries a signed library script, not a shell body: a
+// queued job runs with the agent's privileges long after it was written, so
+// it gets the same signature check as a live run_script request.
+type runScriptPayload struct {
+	Script     json.RawMessage   `json:"script"`
+	Params     map[string]string `json:"params"`
+	AsUser     bool              `json:"as_user"`
+	TimeoutSec int               `json:"timeout_sec"`
+}
+
+// copyFilePayload must name a signed Storage URL on the agent's own
+// Supabase project and the file's SHA-256; see checkCopySource and
+// checkCopyDest for what dest may be.
+type copyFilePayload struct {
+	URL    string `json:"url"`
+	Dest   string `json:"dest"`
+	SHA256 string `json:"sha256"`
+}
+
+type rebootPayload struct {
+	DelaySec int `json:"delay_sec"`
+}
+
+// jobRunner state lives on Device; one runner at a time so a slow job and
+// the next heartbeat never claim the same queue twice.
+type jobState struct {
+	mu          sync.Mutex
+	running     bool
+	recoverOnce sync.Once
+}
+
+// handleJobs runs due jobs when the heartbeat reports one. Called after
+// every successful heartbeat.
+func (d *Device) handleJobs(config RegistrationConfig, result *HeartbeatResult) {
+	d.jobs.recoverOnce.Do(func() { d.recoverInterruptedJobs(config) })
+
+	if result == nil || result.NextJobAt == nil || result.NextJobAt.After(time.Now()) {
+		return
+	}
+
+	d.jobs.mu.Lock()
+	if d.jobs.running {
+		d.jobs.mu.Unlock()
+		return
+	}
+	d.jobs.running = true
+	d.jobs.mu.Unlock()
+
+	go func() {
+		defer func() {
+			d.jobs.mu.Lock()
+			d.jobs.running = false
+			d.jobs.mu.Unlock()
+		}()
+		d.runDueJobs(config)
+	}()
+}
+
+// runDueJobs fetches queued jobs whose run_at has passed and runs them in
+// order, one at a time.
+func (d *Device) runDueJobs(config RegistrationConfig) {
+	q := url.Values{}
+	q.Set("device_id", "eq."+d.ID)
+	q.Set("status", "eq.queued")
+	q.Set("run_at", "lte."+time.Now().UTC().Format(time.RFC3339))
+	q.Set("order", "run_at.asc")
+	q.Set("limit", fmt.Sprint(jobFetchLimit))
+	var jobs []Job
+	if err := jobsRequest(config, "GET", q, nil, &jobs); err != nil {
+		log.Printf("⚠️  Jobs: fetch failed: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+			d.finishJob(config, job, "expired", jobResult{Err: fmt.Errorf("expired before it could run")})
+			continue
+		}
+		claimed, ok := d.claimJob(config, job)
+		if !ok {
+			continue // cancelled or picked up elsewhere meanwhile
+		}
+
+		log.Printf("📋 Job %s (%s) started, attempt %d/%d", claimed.ID, claimed.JobType, claimed.Attempts, claimed.MaxAttempts)
+		res := d.runJob(claimed)
+
+		status := "succeeded"
+		if res.Err != nil {
+			status = "failed"
+			if claimed.Attempts < claimed.MaxAttempts {
+				d.retryJob(config, claimed, res)
+				continue
+			}
+		}
+		d.finishJob(config, claimed, status, res)
+		if res.after != nil {
+			res.after()
+			return // reboot/update — nothing else should start now
+		}
+	}
+}
+
+// claimJob moves a job from queued to running. The status filter makes the
+// PATCH a compare-and-swap: a job cancelled from the CLI is not started.
+func (d *Device) claimJob(config RegistrationConfig, job Job) (Job, bool) {
+	q := url.Values{}
+	q.Set("id", "eq."+job.ID)
+	q.Set("status", "eq.queued")
+	var rows []Job
+	err := jobsRequest(config, "PATCH", q, map[string]interface{}{
+		"status":     "running",
+		"attempts":   job.Attempts + 1,
+		"started_at": time.Now().UTC(),
+	}, &rows)
+	if err != nil {
+		log.Printf("⚠️  Jobs: claim %s failed: %v", job.ID, err)
+		return job, false
+	}
+	if len(rows) == 0 {
+		return job, false
+	}
+	return rows[0], true
+}
+
+func (d *Device) runJob(job Job) jobResult {
+	switch job.JobType {
+	case JobRunScript:
+		var p runScriptPayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return jobResult{Err: fmt.Errorf("invalid payload: %w", err)}
+		}
+		return runScriptJob(p)
+	case JobCopyFile:
+		var p copyFilePayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return jobResult{Err: fmt.Errorf("invalid payload: %w", err)}
+		}
+		return copyFileJob(p, d.cfg.SupabaseURL)
+	case JobInstallUpdate:
+		installed, detail, err := d.applyUpdate()
+		res := jobResult{OutputTail: detail, Err: err}
+		if installed {
+			res.after = exitForRestart
+		}
+		return res
+	case JobReboot:
+		var p rebootPayload
+		_ = json.Unmarshal(job.Payload, &p)
+		if p.DelaySec <= 0 {
+			p.DelaySec = 30
+		}
+		delay := time.Duration(p.DelaySec) * time.Second
+		return jobResult{
+			OutputTail: fmt.Sprintf("restarting in %s", delay),
+			after: func() {
+				if err := restartOS(delay); err != nil {
+					log.Printf("❌ Jobs: restart failed: %v", err)
+				}
+			},
+		}
+	default:
+		return jobResult{Err: fmt.Errorf("unsupported job type %q (agent too old?)", job.JobType)}
+	}
+}
+
+func runScriptJob(p runScriptPayload) jobResult {
+	cmd, details, err := renderScriptJob(p, scriptlib.AgentTrustedKeys(), runtime.GOOS)
+	if err != nil {
+		return jobResult{Err: err, details: details}
+	}
+	if p.TimeoutSec <= 0 {
+		p.TimeoutSec = jobDefaultTimeoutSec
+	}
+
+	out := &tailBuffer{max: jobOutputTailBytes}
+	res := shell.Run(context.Background(), shell.ExecOptions{
+		Cmd:        cmd,
+		AsUser:     p.AsUser,
+		TimeoutSec: p.TimeoutSec,
+	}, nil, out.Write, out.Write)
+
+	exitCode := res.ExitCode
+	jr := jobResult{ExitCode: &exitCode, OutputTail: out.String(), Err: res.Err, details: details}
+	if jr.Err == nil && exitCode != 0 {
+		jr.Err = fmt.Errorf("exit code %d", exitCode)
+	}
+	return jr
+}
+
+// renderScriptJob verifies the script in a run_script payload against the
+// trusted keys and renders it with the queued parameters. Payloads from
+// before the script library (a plain "script" string) are refused.
+func renderScriptJob(p runScriptPayload, trusted map[string]ed25519.PublicKey, goos string) (string, map[string]interface{}, error) {
+	if len(p.Script) == 0 || p.Script[0] != '{' {
+		return "", nil, fmt.Errorf("payload has no signed library script (raw scripts are not run from the queue)")
+	}
+	var s scriptlib.Script
+	if err := json.Unmarshal(p.Script, &s); err != nil {
+		return "", nil, fmt.Errorf("invalid script: %w", err)
+	}
+	details := map[string]interface{}{
+		"script_id":      s.ID,
+		"script_name":    s.Name,
+		"script_version": s.Version,
+		"script_key_id":  s.KeyID,
+	}
+	if err := s.Verify(trusted); err != nil {
+		return "", details, fmt.Errorf("script %s v%d rejected: %w", s.Name, s.Version, err)
+	}
+	cmd, err := s.Render(goos, p.Params)
+	if err != nil {
+		return "", details, err
+	}
+	return cmd, details, nil
+}
+
+func copyFileJob(p copyFilePayload, supabaseURL string) jobResult {
+	if p.URL == "" || p.Dest == "" || p.SHA256 == "" {
+		return jobResult{Err: fmt.Errorf("url, dest and sha256 are required")}
+	}
+	if b, err := hex.DecodeString(p.SHA256); err != nil || len(b) != sha256.Size {
+		return jobResult{Err: fmt.Errorf("sha256 must be 64 hex digits")}
+	}
+	if err := checkCopySource(p.URL, supabaseURL); err != nil {
+		return jobResult{Err: err}
+	}
+	dest := filepath.Clean(p.Dest)
+	if !filepath.IsAbs(dest) {
+		return jobResult{Err: fmt.Errorf("dest must be an absolute path")}
+	}
+	if filetransfer.IsProtectedPath(dest) {
+		return jobResult{Err: fmt.Errorf("refusing to write protected path %s", dest)}
+	}
+	if err := checkCopyDest(dest); err != nil {
+		return jobResult{Err: err}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Minute}
+	resp, err := client.Get(p.URL)
+	if err != nil {
+		return jobResult{Err: fmt.Errorf("download: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return jobResult{Err: fmt.Errorf("download: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return jobResult{Err: err}
+	}
+	tmp := dest + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return jobResult{Err: err}
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, jobMaxDownloadBytes+1))
+	f.Close()
+	if err == nil && n > jobMaxDownloadBytes {
+		err = fmt.Errorf("file larger than %d bytes", int64(jobMaxDownloadBytes))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return jobResult{Err: fmt.Errorf("download: %w", err)}
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(p.SHA256, sum) {
+		os.Remove(tmp)
+		return jobResult{Err: fmt.Errorf("sha256 mismatch: got %s", sum)}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return jobResult{Err: err}
+	}
+	return jobResult{OutputTail: fmt.Sprintf("wrote %d bytes to %s (sha256 %s)", n, dest, sum)}
+}
+
+// checkCopySource accepts only a signed Storage URL
+// (/storage/v1/object/sign/...?token=...) on the Supabase project the agent
+// is registered with, so a queue row cannot point the agent, running as
+// SYSTEM or root, at an arbitrary server.
+func checkCopySource(rawURL, supabaseURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	base, err := url.Parse(supabaseURL)
+	if err != nil || base.Host == "" {
+		return fmt.Errorf("no Supabase URL configured")
+	}
+	prefix := strings.TrimSuffix(base.Path, "/") + "/storage/v1/object/sign/"
+	if u.Scheme != base.Scheme || !strings.EqualFold(u.Host, base.Host) || u.User != nil ||
+		!strings.HasPrefix(u.Path, prefix) || len(u.Path) == len(prefix) || u.Query().Get("token") == "" {
+		return fmt.Errorf("url must be a signed storage URL under %s", supabaseURL)
+	}
+	return nil
+}
+
+// copyDestDenyDirs are autostart and PATH directories: a file dropped there
+// runs at the next logon or boot, or in place of a real command. Matched as
+// whole path segments anywhere in dest, lower-cased with forward slashes.
+var copyDestDenyDirs = []string{
+	"start menu/programs/startup",
+	"appdata/local/microsoft/windowsapps",
+	"library/launchagents",
+	"library/launchdaemons",
+	"library/startupitems",
+	"applications",
+	".config/autostart",
+	".config/systemd",
+	".local/bin",
+	".ssh",
+}
+
+// copyDestDenyNames are shell startup files and other files that are run or
+// trusted by name.
+var copyDestDenyNames = map[string]bool{
+	".bashrc": true, ".bash_profile": true, ".bash_login": true, ".profile": true,
+	".zshrc": true, ".zprofile": true, ".zshenv": true, ".zlogin": true,
+	".login": true, ".cshrc": true, ".tcshrc": true,
+	"crontab": true, "authorized_keys": true,
+}
+
+// copyDestDenyExts are file types the OS runs or loads when opened.
+var copyDestDenyExts = map[string]bool{
+	".exe": true, ".dll": true, ".sys": true, ".com": true, ".scr": true, ".cpl": true,
+	".msi": true, ".msp": true, ".bat": true, ".cmd": true, ".ps1": true, ".psm1": true,
+	".vbs": true, ".vbe": true, ".js": true, ".jse": true, ".wsf": true, ".wsh": true,
+	".hta": true, ".lnk": true, ".url": true, ".reg": true, ".jar": true,
+	".sh": true, ".command": true, ".app": true, ".pkg": true, ".dylib": true, ".so": true,
+	".desktop": true, ".service": true, ".plist": true,
+}
+
+// checkCopyDest refuses destinations a queued copy could use to get code
+// run: executables and scripts, autostart folders and shell startup files.
+// Beyond filetransfer.IsProtectedPath, which only covers system trees, this
+// also applies inside user profiles.
+func checkCopyDest(dest string) error {
+	p := strings.ToLower(strings.ReplaceAll(dest, `\`, "/"))
+	for _, d := range copyDestDenyDirs {
+		if strings.Contains(p+"/", "/"+d+"/") {
+			return fmt.Errorf("refusing to write %s: autostart or program directory", dest)
+		}
+	}
+	name := p[strings.LastIndex(p, "/")+1:]
+	if copyDestDenyNames[name] {
+		return fmt.Errorf("refusing to write %s: shell startup or trust file", dest)
+	}
+	if ext := filepath.Ext(name); copyDestDenyExts[ext] {
+		return fmt.Errorf("refusing to write %s: executable file type %s", dest, ext)
+	}
+	return nil
+}
+
+// retryJob puts a failed job back in the queue after its retry delay.
+func (d *Device) retryJob(config RegistrationConfig, job Job, res jobResult) {
+	delay := time.Duration(job.RetryDelaySec) * time.Second
+	log.Printf("⚠️  Job %s attempt %d/%d failed (%v) — retrying in %s", job.ID, job.Attempts, job.MaxAttempts, res.Err, delay)
+	q := url.Values{}
+	q.Set("id", "eq."+job.ID)
+	patch := resultFields(res)
+	patch["status"] = "queued"
+	patch["run_at"] = time.Now().Add(delay).UTC()
+	if err := jobsRequest(config, "PATCH", q, patch, nil); err != nil {
+		log.Printf("⚠️  Jobs: requeue %s failed: %v", job.ID, err)
+	}
+}
+
+// finishJob writes the final status and result, and audits it.
+func (d *Device) finishJob(config RegistrationConfig, job Job, status string, res jobResult) {
+	if res.Err != nil {
+		log.Printf("❌ Job %s (%s) %s: %v", job.ID, job.JobType, status, res.Err)
+	} else {
+		log.Printf("✅ Job %s (%s) %s", job.ID, job.JobType, status)
+	}
+
+	q := url.Values{}
+	q.Set("id", "eq."+job.ID)
+	patch := resultFields(res)
+	patch["status"] = status
+	patch["finished_at"] = time.Now().UTC()
+	if err := jobsRequest(config, "PATCH", q, patch, nil); err != nil {
+		log.Printf("⚠️  Jobs: report %s failed: %v", job.ID, err)
+	}
+
+	details := map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": job.JobType,
+		"status":   status,
+		"attempts": job.Attempts,
+	}
+	if res.ExitCode != nil {
+		details["exit_code"] = *res.ExitCode
+	}
+	for k, v := range res.details {
+		details[k] = v
+	}
+	severity := "info"
+	if res.Err != nil {
+		severity = "warning"
+		details["error"] = res.Err.Error()
+	}
+	d.WriteAudit(AuditEvent{Event: "device_job_" + status, Severity: severity, Details: details})
+}
+
+func resultFields(res jobResult) map[string]interface{} {
+	fields := map[string]interface{}{
+		"output_tail": res.OutputTail,
+		"exit_code":   nil,
+		"error":       nil,
+	}
+	if res.ExitCode != nil {
+		fields["exit_code"] = *res.ExitCode
+	}
+	if res.Err != nil {
+		fields["error"] = res.Err.Error()
+	}
+	return fields
+}
+
+// recoverInterruptedJobs handles jobs left in "running" by a previous agent
+// process (crash, power loss, service restart). They are retried if they
+// have attempts left, otherwise failed.
+func (d *Device) recoverInterruptedJobs(config RegistrationConfig) {
+	q := url.Values{}
+	q.Set("device_id", "eq."+d.ID)
+	q.Set("status", "eq.running")
+	var jobs []Job
+	if err := jobsRequest(config, "GET", q, nil, &jobs); err != nil || len(jobs) == 0 {
+		return
+	}
+	res := jobResult{Err: fmt.Errorf("agent restarted while the job was running")}
+	for _, job := range jobs {
+		if job.Attempts < job.MaxAttempts {
+			d.retryJob(config, job, res)
+		} else {
+			d.finishJob(config, job, "failed", res)
+		}
+	}
+}
+
+// jobsRequest performs a PostgREST call against device_jobs. When out is
+// non-nil the response rows are decoded into it.
+func jobsRequest(config RegistrationConfig, method string, query url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, config.SupabaseURL+"/rest/v1/device_jobs?"+query.Encode(), reader)
+	if err != nil {
+		return err
+	}
+	config.applyAuthHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	if method == "PATCH" {
+		if out != nil {
+			req.Header.Set("Prefer", "return=representation")
+		} else {
+			req.Header.Set("Prefer", "return=minimal")
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.ToValidUTF8(string(t.buf), "")
+}
@@ -0,0 +1,338 @@
+package device
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stangtennis/Remote/scriptlib"
+	"github.com/stangtennis/remote-agent/internal/filetransfer"
+)
+
+// fakeJobsAPI is a minimal PostgREST stand-in for device_jobs. It keeps the
+// rows in memory and honours the id and status filters used by the agent.
+type fakeJobsAPI struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	patches []map[string]interface{}
+}
+
+func newFakeJobsAPI(t *testing.T, jobs ...Job) (*fakeJobsAPI, RegistrationConfig) {
+	t.Helper()
+	f := &fakeJobsAPI{jobs: map[string]*Job{}}
+	for i := range jobs {
+		j := jobs[i]
+		f.jobs[j.ID] = &j
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, RegistrationConfig{SupabaseURL: srv.URL}
+}
+
+func (f *fakeJobsAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := r.URL.Query()
+	var matched []*Job
+	for _, j := range f.jobs {
+		if id := q.Get("id"); id != "" && "eq."+j.ID != id {
+			continue
+		}
+		if st := q.Get("status"); st != "" && "eq."+j.Status != st {
+			continue
+		}
+		matched = append(matched, j)
+	}
+
+	switch r.Method {
+	case "GET":
+		rows := []Job{}
+		for _, j := range matched {
+			rows = append(rows, *j)
+		}
+		json.NewEncoder(w).Encode(rows)
+	case "PATCH":
+		body, _ := io.ReadAll(r.Body)
+		var patch map[string]interface{}
+		json.Unmarshal(body, &patch)
+		f.patches = append(f.patches, patch)
+		rows := []Job{}
+		for _, j := range matched {
+			if v, ok := patch["status"].(string); ok {
+				j.Status = v
+			}
+			if v, ok := patch["attempts"].(float64); ok {
+				j.Attempts = int(v)
+			}
+			if v, ok := patch["run_at"].(string); ok {
+				j.RunAt, _ = time.Parse(time.RFC3339Nano, v)
+			}
+			rows = append(rows, *j)
+		}
+		if r.Header.Get("Prefer") == "return=representation" {
+			json.NewEncoder(w).Encode(rows)
+		}
+	default:
+		http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeJobsAPI) job(id string) Job {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.jobs[id]
+}
+
+func TestClaimJob(t *testing.T) {
+	queued := Job{ID: "a", JobType: JobReboot, Status: "queued", MaxAttempts: 2}
+	cancelled := Job{ID: "b", JobType: JobReboot, Status: "cancelled", MaxAttempts: 1}
+	f, config := newFakeJobsAPI(t, queued, cancelled)
+	d := &Device{ID: "dev"}
+
+	claimed, ok := d.claimJob(config, queued)
+	if !ok {
+		t.Fatal("queued job was not claimed")
+	}
+	if claimed.Status != "running" || claimed.Attempts != 1 {
+		t.Errorf("claimed = %s attempt %d, want running attempt 1", claimed.Status, claimed.Attempts)
+	}
+
+	// The status filter makes the claim a compare-and-swap: a job that is
+	// already running, or was cancelled meanwhile, is not claimed again.
+	if _, ok := d.claimJob(config, queued); ok {
+		t.Error("running job was claimed twice")
+	}
+	if _, ok := d.claimJob(config, cancelled); ok {
+		t.Error("cancelled job was claimed")
+	}
+	if got := f.job("b"); got.Status != "cancelled" || got.Attempts != 0 {
+		t.Errorf("cancelled job changed to %s attempt %d", got.Status, got.Attempts)
+	}
+}
+
+func TestRunDueJobsRetry(t *testing.T) {
+	// An unsupported job type fails without touching the system.
+	job := Job{ID: "a", JobType: "unknown", Status: "queued", MaxAttempts: 2, RetryDelaySec: 300}
+	f, config := newFakeJobsAPI(t, job)
+	d := &Device{ID: "dev"}
+
+	before := time.Now()
+	d.runDueJobs(config)
+	got := f.job("a")
+	if got.Status != "queued" || got.Attempts != 1 {
+		t.Fatalf("after first failure: %s attempt %d, want queued attempt 1", got.Status, got.Attempts)
+	}
+	// Backoff: the job goes back in the queue retry_delay_sec from now.
+	delay := got.RunAt.Sub(before)
+	if delay < 300*time.Second || delay > 301*time.Second {
+		t.Errorf("retry scheduled %s after failure, want 5m", delay)
+	}
+
+	// The fake ignores run_at, so the next pass picks the job up straight
+	// away. The second attempt is the last one, so the job fails for good.
+	d.runDueJobs(config)
+	got = f.job("a")
+	if got.Status != "failed" || got.Attempts != 2 {
+		t.Errorf("after last attempt: %s attempt %d, want failed attempt 2", got.Status, got.Attempts)
+	}
+	last := f.patches[len(f.patches)-1]
+	if e, _ := last["error"].(string); !strings.Contains(e, "unsupported job type") {
+		t.Errorf("final error = %q", e)
+	}
+}
+
+func TestRecoverInterruptedJobs(t *testing.T) {
+	retry := Job{ID: "a", JobType: JobReboot, Status: "running", Attempts: 1, MaxAttempts: 3, RetryDelaySec: 60}
+	spent := Job{ID: "b", JobType: JobReboot, Status: "running", Attempts: 3, MaxAttempts: 3}
+	f, config := newFakeJobsAPI(t, retry, spent)
+	d := &Device{ID: "dev"}
+
+	d.recoverInterruptedJobs(config)
+	if got := f.job("a"); got.Status != "queued" || got.Attempts != 1 {
+		t.Errorf("job with attempts left: %s attempt %d, want queued attempt 1", got.Status, got.Attempts)
+	}
+	if got := f.job("b"); got.Status != "failed" {
+		t.Errorf("job without attempts left: %s, want failed", got.Status)
+	}
+}
+
+func TestRenderScriptJob(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := map[string]ed25519.PublicKey{scriptlib.KeyID(pub): pub}
+
+	s := scriptlib.Script{
+		ID:       "s1",
+		Name:     "greet",
+		Version:  2,
+		Platform: "any",
+		Params:   []scriptlib.Param{{Name: "who", Type: "string", Required: true}},
+		Body:     "echo \"$who\"\n",
+	}
+	if err := s.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	signed, _ := json.Marshal(s)
+
+	cmd, details, err := renderScriptJob(runScriptPayload{Script: signed, Params: map[string]string{"who": "world"}}, trusted, "linux")
+	if err != nil {
+		t.Fatalf("signed script rejected: %v", err)
+	}
+	if cmd != "who='world'\necho \"$who\"\n" {
+		t.Errorf("rendered %q", cmd)
+	}
+	if details["script_id"] != "s1" || details["script_version"] != 2 {
+		t.Errorf("details = %v", details)
+	}
+
+	tampered := s
+	tampered.Body = "rm -rf /\n"
+	tamperedJSON, _ := json.Marshal(tampered)
+	raw, _ := json.Marshal("whoami")
+
+	tests := []struct {
+		name    string
+		payload runScriptPayload
+		want    string
+	}{
+		{"raw script", runScriptPayload{Script: raw}, "no signed library script"},
+		{"missing script", runScriptPayload{}, "no signed library script"},
+		{"tampered body", runScriptPayload{Script: tamperedJSON, Params: map[string]string{"who": "x"}}, "signature verification failed"},
+		{"missing param", runScriptPayload{Script: signed}, "missing required parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := renderScriptJob(tt.payload, trusted, "linux")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	other := map[string]ed25519.PublicKey{scriptlib.KeyID(otherPub): otherPub}
+	if _, _, err := renderScriptJob(runScriptPayload{Script: signed, Params: map[string]string{"who": "x"}}, other, "linux"); err == nil || !strings.Contains(err.Error(), "untrusted key") {
+		t.Errorf("other key: err = %v", err)
+	}
+	if _, _, err := renderScriptJob(runScriptPayload{Script: signed, Params: map[string]string{"who": "x"}}, nil, "linux"); !errors.Is(err, scriptlib.ErrNoTrustedKeys) {
+		t.Errorf("no trusted keys: err = %v", err)
+	}
+}
+
+func TestCheckCopySource(t *testing.T) {
+	const base = "https://abc.supabase.co"
+	cases := map[string]bool{
+		base + "/storage/v1/object/sign/files/setup.zip?token=eyJ":                true,
+		"https://ABC.supabase.co/storage/v1/object/sign/f/a.txt?token=x":          true,
+		base + "/storage/v1/object/sign/files/setup.zip":                          false, // no token
+		base + "/storage/v1/object/public/files/setup.zip?token=x":                false,
+		base + "/storage/v1/object/sign/?token=x":                                 false,
+		"http://abc.supabase.co/storage/v1/object/sign/f/a?token=x":               false,
+		"https://evil.example/storage/v1/object/sign/f/a?token=x":                 false,
+		"https://abc.supabase.co.evil.example/storage/v1/object/sign/f/a?token=x": false,
+		"https://user@abc.supabase.co/storage/v1/object/sign/f/a?token=x":         false,
+		"not a url\x7f": false,
+	}
+	for u, want := range cases {
+		if err := checkCopySource(u, base); (err == nil) != want {
+			t.Errorf("checkCopySource(%q) = %v, want ok %v", u, err, want)
+		}
+	}
+	if err := checkCopySource(base+"/storage/v1/object/sign/f/a?token=x", ""); err == nil {
+		t.Error("accepted a URL without a configured Supabase URL")
+	}
+}
+
+func TestCheckCopyDest(t *testing.T) {
+	cases := map[string]bool{
+		`C:\Users\bob\Documents\report.pdf`:  true,
+		`C:\Users\bob\Desktop\notes.txt`:     true,
+		"/home/bob/Downloads/data.csv":       true,
+		"/Users/bob/Documents/backup.tar.gz": true,
+		`C:\Users\bob\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Startup\notes.txt`: false,
+		`C:\Users\bob\AppData\Local\Microsoft\WindowsApps\python.txt`:                          false,
+		`C:\Users\bob\Downloads\setup.EXE`:                                                     false,
+		`D:\tools\run.ps1`:                                                                     false,
+		`C:\Users\bob\Desktop\Invoice.pdf.lnk`:                                                 false,
+		"/Users/bob/Library/LaunchAgents/com.example.agent.plist":                              false,
+		"/Users/bob/Applications/Tool.app/Contents/Info.txt":                                   false,
+		"/home/bob/.config/autostart/x.txt":                                                    false,
+		"/home/bob/.ssh/authorized_keys":                                                       false,
+		"/home/bob/.bashrc":                                                                    false,
+		"/home/bob/.local/bin/ls":                                                              false,
+		"/home/bob/tool.sh":                                                                    false,
+	}
+	for dest, want := range cases {
+		if err := checkCopyDest(dest); (err == nil) != want {
+			t.Errorf("checkCopyDest(%q) = %v, want ok %v", dest, err, want)
+		}
+	}
+}
+
+func TestCopyFileJob(t *testing.T) {
+	content := []byte("quarterly numbers\n")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/object/sign/files/report.csv" || r.URL.Query().Get("token") == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	if filetransfer.IsProtectedPath(dir) {
+		t.Skipf("temp dir %s is a protected path on this system", dir)
+	}
+	src := srv.URL + "/storage/v1/object/sign/files/report.csv?token=t"
+	dest := filepath.Join(dir, "report.csv")
+
+	tests := []struct {
+		name string
+		p    copyFilePayload
+		want string // error substring, "" for success
+	}{
+		{"ok", copyFilePayload{URL: src, Dest: dest, SHA256: hex.EncodeToString(sum[:])}, ""},
+		{"no sha256", copyFilePayload{URL: src, Dest: dest}, "required"},
+		{"bad sha256", copyFilePayload{URL: src, Dest: dest, SHA256: "abc"}, "64 hex digits"},
+		{"sha256 mismatch", copyFilePayload{URL: src, Dest: dest, SHA256: strings.Repeat("0", 64)}, "mismatch"},
+		{"foreign url", copyFilePayload{URL: "https://example.com/report.csv", Dest: dest, SHA256: hex.EncodeToString(sum[:])}, "signed storage URL"},
+		{"relative dest", copyFilePayload{URL: src, Dest: "report.csv", SHA256: hex.EncodeToString(sum[:])}, "absolute"},
+		{"executable dest", copyFilePayload{URL: src, Dest: filepath.Join(dir, "report.exe"), SHA256: hex.EncodeToString(sum[:])}, "executable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(dest)
+			res := copyFileJob(tt.p, srv.URL)
+			if tt.want == "" {
+				if res.Err != nil {
+					t.Fatalf("err = %v", res.Err)
+				}
+				if got, _ := os.ReadFile(dest); string(got) != string(content) {
+					t.Errorf("dest holds %q", got)
+				}
+				return
+			}
+			if res.Err == nil || !strings.Contains(res.Err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", res.Err, tt.want)
+			}
+			if _, err := os.Stat(dest); err == nil {
+				t.Error("dest written despite the error")
+			}
+		})
+	}
+}
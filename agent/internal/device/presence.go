@@ -1,6 +1,7 @@
 package device
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -8,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/stangtennis/remote-agent/internal/safemode"
 	"github.com/stangtennis/remote-agent/internal/updater"
 	"github.com/stangtennis/remote-agent/internal/version"
 )
@@ -35,6 +37,8 @@ func (d *Device) StartPresence() {
 		}
 	}
 
+	go d.verifyPendingUpdate()
+
 	if d.APIKey == "" {
 		log.Println("⚠️  Heartbeat: api_key missing — falling back to JWT-only auth (expect failures after token expiry)")
 	}
@@ -62,7 +66,9 @@ func (d *Device) StartPresence() {
 			return false
 		}
 		d.lastHeartbeatErr = nil
+		updater.SetRolloutTarget(updater.RolloutTarget{DeviceID: d.ID, Tags: result.UpdateTags, Pin: result.UpdatePin})
 		d.handlePendingCommand(config, result)
+		d.handleJobs(config, result)
 		return true
 	}
 
@@ -163,6 +169,8 @@ func (d *Device) handlePendingCommand(config RegistrationConfig, result *Heartbe
 		go d.executeRelayMode(false)
 	case "restart":
 		go d.executeRestart()
+	case "restart_safe_mode":
+		go d.executeSafeModeRestart()
 	case "lock":
 		go d.executeLock()
 	case "shutdown":
@@ -206,28 +214,30 @@ func (d *Device) executeRelayMode(enabled bool) {
 }
 
 // executeForceUpdate downloads and installs agent update.
+func (d *Device) executeForceUpdate() {
+	log.Println("🔄 Force update triggered via dashboard command")
+	if installed, _, _ := d.applyUpdate(); installed {
+		exitForRestart()
+	}
+}
+
+// applyUpdate checks for, downloads and installs an agent update. Returns
+// installed=true when a new version is in place and the caller should exit
+// so the service manager restarts the agent; detail describes the outcome.
 //
 // Foretrækker den injected forceUpdateHandler (sat fra main.go) som bruger
 // rename-trick — virker pålideligt under Windows Service. Falder tilbage
 // til den brudte --update-from-pattern hvis ingen handler er sat (fx i
 // macOS-mode eller standalone tray uden service).
-func (d *Device) executeForceUpdate() {
-	log.Println("🔄 Force update triggered via dashboard command")
-
+func (d *Device) applyUpdate() (installed bool, detail string, err error) {
 	if d.forceUpdateHandler != nil {
 		log.Println("📦 Using service-side update handler (rename-trick)")
-		updated := d.forceUpdateHandler()
-		if updated {
+		if d.forceUpdateHandler() {
 			log.Println("✅ Force update: installed via service handler, agent will restart")
-			go func() {
-				time.Sleep(1 * time.Second)
-				log.Println("🔄 Exiting for SCM restart...")
-				os.Exit(0)
-			}()
-		} else {
-			log.Println("ℹ️ Force update: ingen ny version eller fejl (se ovenstående)")
+			return true, "installed via service handler", nil
 		}
-		return
+		log.Println("ℹ️ Force update: ingen ny version eller fejl (se ovenstående)")
+		return false, "no new version installed (see agent log)", nil
 	}
 
 	// Fallback: --update-from-pattern. Bemærk at denne flow er ustabil
@@ -236,17 +246,17 @@ func (d *Device) executeForceUpdate() {
 	u, err := updater.NewUpdater(version.Version)
 	if err != nil {
 		log.Printf("❌ Force update: could not create updater: %v", err)
-		return
+		return false, "", fmt.Errorf("could not create updater: %w", err)
 	}
 
 	if err := u.CheckForUpdate(); err != nil {
 		log.Printf("❌ Force update: check failed: %v", err)
-		return
+		return false, "", fmt.Errorf("check failed: %w", err)
 	}
 
 	if u.GetAvailableUpdate() == nil {
 		log.Println("✅ Force update: already up to date (" + version.Version + ")")
-		return
+		return false, "already up to date (" + version.Version + ")", nil
 	}
 
 	info := u.GetAvailableUpdate()
@@ -254,19 +264,23 @@ func (d *Device) executeForceUpdate() {
 
 	if err := u.DownloadUpdate(); err != nil {
 		log.Printf("❌ Force update: download failed: %v", err)
-		return
+		return false, "", fmt.Errorf("download failed: %w", err)
 	}
 
 	log.Printf("📦 Installing %s...", info.TagName)
 	if err := u.InstallUpdate(); err != nil {
 		log.Printf("❌ Force update: install failed: %v", err)
-		return
+		return false, "", fmt.Errorf("install failed: %w", err)
 	}
 
 	log.Printf("✅ Force update: installed %s, agent will restart", info.TagName)
+	return true, "installed " + info.TagName, nil
+}
 
-	// Exit the current process so the new version can replace the binary
-	// Windows: SCM will restart the service; macOS: LaunchAgent KeepAlive restarts
+// exitForRestart exits the current process so the new version can replace
+// the binary. Windows: SCM will restart the service; macOS: LaunchAgent
+// KeepAlive restarts.
+func exitForRestart() {
 	go func() {
 		time.Sleep(1 * time.Second)
 		log.Println("🔄 Exiting for update...")
@@ -277,17 +291,38 @@ func (d *Device) executeForceUpdate() {
 // executeRestart triggers an OS restart.
 func (d *Device) executeRestart() {
 	log.Println("🔄 Restart triggered via dashboard command")
+	if err := restartOS(5 * time.Second); err != nil {
+		log.Printf("❌ Restart failed: %v", err)
+	}
+}
+
+// executeSafeModeRestart restarts Windows into Safe Mode with Networking.
+// The agent service is registered to start there and clears the boot flag
+// once it does (see safemode.Reconcile).
+func (d *Device) executeSafeModeRestart() {
+	log.Println("🛟 Safe Mode restart triggered via dashboard command")
+	err := safemode.Restart(30 * time.Second)
+	details := map[string]interface{}{"source": "pending_command"}
+	severity := "warning"
+	if err != nil {
+		log.Printf("❌ Safe Mode restart failed: %v", err)
+		details["error"] = err.Error()
+		severity = "error"
+	}
+	d.WriteAudit(AuditEvent{Event: "RESTART_SAFE_MODE", Severity: severity, Details: details})
+}
+
+// restartOS schedules an OS restart after delay. shutdown on macOS/Linux
+// takes whole minutes, so the delay is rounded up there.
+func restartOS(delay time.Duration) error {
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("shutdown", "/r", "/t", "5", "/c", "Remote Desktop: Genstart anmodet")
-	} else if runtime.GOOS == "darwin" {
-		cmd = exec.Command("sudo", "shutdown", "-r", "+1")
+		cmd = exec.Command("shutdown", "/r", "/t", fmt.Sprint(int(delay.Seconds())), "/c", "Remote Desktop: Genstart anmodet")
 	} else {
-		cmd = exec.Command("sudo", "shutdown", "-r", "+1")
-	}
-	if err := cmd.Run(); err != nil {
-		log.Printf("❌ Restart failed: %v", err)
+		minutes := int((delay + time.Minute - 1) / time.Minute)
+		cmd = exec.Command("sudo", "shutdown", "-r", fmt.Sprintf("+%d", minutes))
 	}
+	return cmd.Run()
 }
 
 // executeLock locks the workstation screen.
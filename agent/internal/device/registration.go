@@ -258,7 +258,10 @@ func extractAPIKey(body []byte, device *DeviceInfo) {
 
 // HeartbeatResult contains information read back from the heartbeat response.
 type HeartbeatResult struct {
-	PendingCommand string // Non-empty if dashboard sent a command (e.g. "force_update")
+	PendingCommand string     // Non-empty if dashboard sent a command (e.g. "force_update")
+	NextJobAt      *time.Time // Earliest run_at of queued device_jobs, nil if none
+	UpdatePin      string     // Version the device is pinned to, "" if none
+	UpdateTags     []string   // device_tags, for update rollout rings
 }
 
 // ConnectionInfo holds optional WebRTC connection metrics for heartbeat
@@ -328,10 +331,20 @@ func UpdateHeartbeat(config RegistrationConfig, deviceID string, isOnline bool,
 	// Parse response to check for pending commands
 	if len(body) > 0 {
 		var rows []struct {
-			PendingCommand *string `json:"pending_command"`
+			PendingCommand *string    `json:"pending_command"`
+			NextJobAt      *time.Time `json:"next_job_at"`
+			UpdatePin      *string    `json:"update_pin"`
+			UpdateTags     []string   `json:"update_tags"`
 		}
-		if err := json.Unmarshal(body, &rows); err == nil && len(rows) > 0 && rows[0].PendingCommand != nil {
-			result.PendingCommand = *rows[0].PendingCommand
+		if err := json.Unmarshal(body, &rows); err == nil && len(rows) > 0 {
+			if rows[0].PendingCommand != nil {
+				result.PendingCommand = *rows[0].PendingCommand
+			}
+			result.NextJobAt = rows[0].NextJobAt
+			if rows[0].UpdatePin != nil {
+				result.UpdatePin = *rows[0].UpdatePin
+			}
+			result.UpdateTags = rows[0].UpdateTags
 		}
 	}
 
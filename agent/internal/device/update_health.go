@@ -0,0 +1,112 @@
+package device
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/stangtennis/remote-agent/internal/updater"
+	"github.com/stangtennis/remote-agent/internal/version"
+)
+
+// verifyPendingUpdate runs once per agent start. After an update it waits
+// for the new version to reconnect — a heartbeat and healthy signaling —
+// and rolls back to the previous binaries when that does not happen within
+// the release's health timeout, or the new version keeps crashing. The
+// outcome goes to audit_logs.
+func (d *Device) verifyPendingUpdate() {
+	p, err := updater.LoadPendingUpdate()
+	if err != nil {
+		log.Printf("⚠️ Update health check: %v", err)
+		return
+	}
+	if p == nil {
+		return
+	}
+	details := map[string]interface{}{
+		"from_version": p.FromVersion,
+		"to_version":   p.ToVersion,
+	}
+
+	if p.RolledBack {
+		// The old version is back: report what happened and stay off the release
+		if updater.SameVersion(version.Version, p.FromVersion) {
+			log.Printf("↩️ Opdatering til %s blev rullet tilbage: %s", p.ToVersion, p.Reason)
+			details["reason"] = p.Reason
+			d.WriteAudit(AuditEvent{Event: "UPDATE_ROLLED_BACK", Severity: "error", Details: details})
+			if err := updater.IgnoreVersion(p.ToVersion); err != nil {
+				log.Printf("⚠️ Kunne ikke springe %s over: %v", p.ToVersion, err)
+			}
+		}
+		updater.ClearPendingUpdate()
+		return
+	}
+	if !updater.SameVersion(version.Version, p.ToVersion) {
+		// Replaced by hand since; nothing to verify
+		updater.ClearPendingUpdate()
+		return
+	}
+
+	p.Starts++
+	if err := p.Save(); err != nil {
+		log.Printf("⚠️ Update health check: %v", err)
+	}
+	if p.Starts > updater.MaxUpdateStarts {
+		d.rollBackUpdate(p, fmt.Sprintf("restarted %d times without reconnecting", p.Starts-1))
+		return
+	}
+
+	started := time.Now()
+	timeout := time.Duration(p.TimeoutMin) * time.Minute
+	deadline := started.Add(timeout)
+	log.Printf("🩺 Verificerer opdatering til %s (rulles tilbage hvis ikke forbundet inden %d min)", p.ToVersion, p.TimeoutMin)
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if d.reconnectedSince(started) {
+			log.Printf("✅ Opdatering til %s verificeret", p.ToVersion)
+			details["seconds"] = int(time.Since(started).Seconds())
+			d.WriteAudit(AuditEvent{Event: "UPDATE_HEALTHY", Details: details})
+			p.Done()
+			return
+		}
+		if time.Now().Before(deadline) {
+			continue
+		}
+		if !updater.ServerReachable() {
+			// The device is offline, not the release broken: keep waiting
+			deadline = time.Now().Add(timeout)
+			continue
+		}
+		d.rollBackUpdate(p, fmt.Sprintf("no reconnect within %d min", p.TimeoutMin))
+		return
+	}
+}
+
+// reconnectedSince reports whether a heartbeat got through after t and
+// the signaling connection is healthy.
+func (d *Device) reconnectedSince(t time.Time) bool {
+	if atomic.LoadInt64(&d.lastHeartbeatSuccess) < t.Unix() {
+		return false
+	}
+	return d.healthCheck == nil || d.healthCheck()
+}
+
+func (d *Device) rollBackUpdate(p *updater.PendingUpdate, reason string) {
+	log.Printf("↩️ Ruller opdatering til %s tilbage: %s", p.ToVersion, reason)
+	if err := p.RollBack(reason); err != nil {
+		log.Printf("❌ Rollback fejlede: %v", err)
+		d.WriteAudit(AuditEvent{Event: "UPDATE_ROLLBACK_FAILED", Severity: "error", Details: map[string]interface{}{
+			"from_version": p.FromVersion,
+			"to_version":   p.ToVersion,
+			"reason":       reason,
+			"error":        err.Error(),
+		}})
+		updater.ClearPendingUpdate()
+		return
+	}
+	// The old version reports the rollback once it is running
+	exitForRestart()
+}
@@ -0,0 +1,169 @@
+package filetransfer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// clipStageDir is where files copied on the controller are uploaded
+// before they go on the local clipboard (below the download dir).
+const clipStageDir = "Clipboard"
+
+// ExpandClipboard flattens a clipboard file list: folders are walked and
+// every regular file is listed with its path below the copied item.
+// Protected system paths are skipped, and lists over
+// protocol.MaxClipboardFiles or protocol.MaxClipboardBytes are refused.
+// Symlinks are not followed.
+func ExpandClipboard(paths []string) ([]protocol.ClipFile, int64, error) {
+	var files []protocol.ClipFile
+	var total int64
+	add := func(rel, path string, size int64) error {
+		files = append(files, protocol.ClipFile{Rel: filepath.ToSlash(rel), Path: path, Size: size})
+		total += size
+		if len(files) > protocol.MaxClipboardFiles {
+			return fmt.Errorf("more than %d files", protocol.MaxClipboardFiles)
+		}
+		if total > protocol.MaxClipboardBytes {
+			return fmt.Errorf("more than %d MB", protocol.MaxClipboardBytes>>20)
+		}
+		return nil
+	}
+
+	for _, root := range paths {
+		root = filepath.Clean(root)
+		if isProtectedPath(root) {
+			continue
+		}
+		info, err := os.Lstat(root)
+		if err != nil {
+			continue // Gone since it was copied
+		}
+		base := filepath.Base(root)
+		if info.Mode().IsRegular() {
+			if err := add(base, root, info.Size()); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if !info.IsDir() {
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Unreadable entries are left out
+			}
+			if d.IsDir() {
+				if path != root && isProtectedPath(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			return add(filepath.Join(base, rel), path, fi.Size())
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return files, total, nil
+}
+
+// SetClipboardSource sets the function returning the paths currently on
+// the local clipboard, served by the "clip" op.
+func (h *Handler) SetClipboardSource(source func() []string) {
+	h.mu.Lock()
+	h.clipSource = source
+	h.mu.Unlock()
+}
+
+// handleClipOp lists the clipboard's files for a lazy paste; the
+// controller then fetches each one with "get".
+func (h *Handler) handleClipOp(fid uint16) error {
+	h.mu.Lock()
+	source := h.clipSource
+	h.mu.Unlock()
+	if source == nil {
+		return h.sendClipError(fid, "clipboard not available")
+	}
+	paths := source()
+	if len(paths) == 0 {
+		return h.sendClipError(fid, "no files on the clipboard")
+	}
+	files, total, err := ExpandClipboard(paths)
+	if err != nil {
+		return h.sendClipError(fid, err.Error())
+	}
+	return h.sendJSON(map[string]interface{}{
+		"op":    protocol.OpClip,
+		"fid":   fid,
+		"files": files,
+		"total": total,
+	})
+}
+
+// handleStageOp creates a fresh folder for files copied on the
+// controller. Each copy gets its own folder so names cannot clash.
+func (h *Handler) handleStageOp(fid uint16) error {
+	dir := filepath.Join(h.downloadDir, clipStageDir, time.Now().Format("20060102-150405.000"))
+	if isProtectedPath(dir) {
+		return h.sendClipError(fid, "no writable folder for clipboard files")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return h.sendClipError(fid, err.Error())
+	}
+	return h.sendJSON(map[string]interface{}{
+		"op":   protocol.OpStage,
+		"fid":  fid,
+		"path": dir,
+	})
+}
+
+// StagedClipboardPaths checks paths the controller wants on the local
+// clipboard: each must exist inside a "clip_stage" folder and not be
+// protected.
+func (h *Handler) StagedClipboardPaths(paths []string) ([]string, error) {
+	root := filepath.Join(h.downloadDir, clipStageDir)
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved // sanitizePath resolves symlinks too
+	}
+	root += string(filepath.Separator)
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		clean, err := sanitizePath(p)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(clean, root) || isProtectedPath(clean) {
+			return nil, fmt.Errorf("not a staged clipboard file: %s", p)
+		}
+		if _, err := os.Lstat(clean); err != nil {
+			return nil, err
+		}
+		out = append(out, clean)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no files")
+	}
+	return out, nil
+}
+
+func (h *Handler) sendClipError(fid uint16, errMsg string) error {
+	return h.sendJSON(map[string]interface{}{
+		"op":    protocol.OpErr,
+		"fid":   fid,
+		"error": errMsg,
+	})
+}
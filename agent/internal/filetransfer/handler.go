@@ -20,6 +20,7 @@ type Handler struct {
 	mu              sync.Mutex
 	downloadDir     string
 	sendData        func(data []byte) error
+	clipSource      func() []string
 }
 
 // activeTransfer represents an ongoing file transfer
@@ -138,6 +139,12 @@ func isProtectedPath(path string) bool {
 	return hasProtectedPrefix(p, protected)
 }
 
+// IsProtectedPath reports whether path is inside a critical system
+// directory. Exposed for writers outside the file channel (device jobs).
+func IsProtectedPath(path string) bool {
+	return isProtectedPath(path)
+}
+
 // NewHandler creates a new file transfer handler
 func NewHandler(downloadDir string) *Handler {
 	// Create download directory if it doesn't exist
@@ -266,6 +273,15 @@ func (h *Handler) handleTotalCMDMessage(op string, message map[string]interface{
 			return h.sendTotalCMDError(err.Error())
 		}
 		return h.handleMvOp(path, target)
+	case "clip", "clip_stage":
+		fid, _ := message["fid"].(float64)
+		if fid < 0 || fid > 65535 {
+			return h.sendTotalCMDError("invalid fid")
+		}
+		if op == "clip" {
+			return h.handleClipOp(uint16(fid))
+		}
+		return h.handleStageOp(uint16(fid))
 	}
 	return nil
 }
@@ -66,3 +66,67 @@ func TestSanitizePath_ResolvesSymlink(t *testing.T) {
 		t.Errorf("expected symlink to resolve to %s, got %s", target, resolved)
 	}
 }
+
+func TestExpandClipboard_FlattensFolders(t *testing.T) {
+	tmp := t.TempDir()
+	if isProtectedPath(tmp) {
+		t.Skip("temp dir is a protected path on this OS")
+	}
+	mustWrite := func(rel, content string) string {
+		p := filepath.Join(tmp, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	single := mustWrite("notes.txt", "hello")
+	mustWrite(filepath.Join("photos", "a.jpg"), "aaa")
+	mustWrite(filepath.Join("photos", "2024", "b.jpg"), "bb")
+
+	files, total, err := ExpandClipboard([]string{single, filepath.Join(tmp, "photos"), filepath.Join(tmp, "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, f := range files {
+		got[f.Rel] = f.Size
+	}
+	want := map[string]int64{"notes.txt": 5, "photos/a.jpg": 3, "photos/2024/b.jpg": 2}
+	if len(got) != len(want) || total != 10 {
+		t.Fatalf("got %v (total %d), want %v (total 10)", got, total, want)
+	}
+	for rel, size := range want {
+		if got[rel] != size {
+			t.Errorf("%s: size %d, want %d", rel, got[rel], size)
+		}
+	}
+}
+
+func TestStagedClipboardPaths_RequiresStageFolder(t *testing.T) {
+	tmp := t.TempDir()
+	if isProtectedPath(tmp) {
+		t.Skip("temp dir is a protected path on this OS")
+	}
+	h := NewHandler(tmp)
+	staged := filepath.Join(tmp, clipStageDir, "1", "report.pdf")
+	if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(staged, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(tmp, "report.pdf")
+	if err := os.WriteFile(outside, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.StagedClipboardPaths([]string{staged}); err != nil {
+		t.Fatalf("staged file rejected: %v", err)
+	}
+	if _, err := h.StagedClipboardPaths([]string{staged, outside}); err == nil {
+		t.Fatal("file outside the stage folder accepted")
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"strings"
 )
 
-type KeyboardController struct{}
+type KeyboardController struct {
+	translator keyTranslator
+}
 
 func NewKeyboardController() *KeyboardController {
 	return &KeyboardController{}
@@ -77,16 +79,16 @@ func (k *KeyboardController) SendKeyWithModifiers(code string, down bool, ctrl,
 	return nil
 }
 
-// SendUnicodeChar sends a Unicode character (macOS stub — uses CGEvent with key mapping)
+// SendUnicodeChar types a Unicode character with CGEventKeyboardSetUnicodeString,
+// independent of the active keyboard layout.
 func (k *KeyboardController) SendUnicodeChar(char rune) error {
-	// On macOS, we'd need CGEventKeyboardSetUnicodeString
-	// For now, fall back to key code mapping
-	return fmt.Errorf("unicode char input not implemented on macOS")
+	return typeUnicode(string(char))
 }
 
 // ClearModifiers releases all modifier keys to prevent stuck modifier state
 // (e.g., after a session drops while modifier keys were held)
 func (k *KeyboardController) ClearModifiers() {
+	k.translator.reset()
 	modifiers := []int{
 		0x37, // Left Command
 		0x36, // Right Command
@@ -102,6 +104,11 @@ func (k *KeyboardController) ClearModifiers() {
 	}
 }
 
+// postKey posts a key event with explicit modifier flags.
+func postKey(keyCode int, down bool, flags uint64) {
+	C.keyEventWithFlags(C.int(keyCode), boolToInt(down), C.uint64_t(flags))
+}
+
 func boolToInt(b bool) C.int {
 	if b {
 		return 1
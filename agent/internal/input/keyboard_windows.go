@@ -23,7 +23,9 @@ var (
 	procSendInput = user32.NewProc("SendInput")
 )
 
-type KeyboardController struct{}
+type KeyboardController struct {
+	translator keyTranslator
+}
 
 func NewKeyboardController() *KeyboardController {
 	return &KeyboardController{}
@@ -144,6 +146,7 @@ func (k *KeyboardController) SendKeyWithModifiers(code string, down bool, ctrl,
 
 // ClearModifiers releases all modifier keys to prevent stuck modifier state
 func (k *KeyboardController) ClearModifiers() {
+	k.translator.reset()
 	for _, mod := range []string{"ctrl", "shift", "alt", "cmd"} {
 		robotgo.KeyUp(mod)
 	}
@@ -0,0 +1,194 @@
+package input
+
+import (
+	"sync"
+	"unicode"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Layout identifies the active keyboard layout on this machine.
+type Layout struct {
+	ID   string // Windows HKL ("04060406") or macOS input source ID
+	Lang string // BCP 47 tag, empty when unknown
+}
+
+// KeyEvent is a key event from a layout-aware controller (see protocol.Key).
+type KeyEvent struct {
+	Code  string // DOM KeyboardEvent.code, empty for composed text
+	Scan  uint16 // Set-1 scancode, 0 = derive from Code
+	Key   string // DOM KeyboardEvent.key on the controller's layout
+	Down  bool
+	Ctrl  bool
+	Shift bool
+	Alt   bool
+	Meta  bool
+}
+
+// keyRoute is how a key event is injected.
+type keyRoute int
+
+const (
+	routeSkip     keyRoute = iota // Dead key, IME keystroke or stray release
+	routePhysical                 // Press the same physical key (scancode)
+	routeLayout                   // Press the key producing Key on our layout
+	routeUnicode                  // Type Key as Unicode text
+)
+
+// layoutKey is a key on the local layout and the modifiers it needs.
+type layoutKey struct {
+	Code  uint16 // Windows VK or macOS virtual keycode
+	Scan  uint16 // Windows scancode, unused on macOS
+	Shift bool
+	AltGr bool // Ctrl+Alt on Windows, Option on macOS
+}
+
+// modState is a modifier state. AltGr is Ctrl and Alt together.
+type modState struct {
+	Ctrl, Shift, Alt, Meta bool
+}
+
+type translatedKey struct {
+	route keyRoute
+	key   layoutKey
+	mods  modState // Modifiers to hold while the key goes down
+	have  modState // Modifiers held by earlier key events
+	text  string   // routeUnicode
+}
+
+// heldMods is the modifier state the controller reports for ev.
+func heldMods(ev KeyEvent) modState {
+	return modState{Ctrl: ev.Ctrl, Shift: ev.Shift, Alt: ev.Alt, Meta: ev.Meta}
+}
+
+// modifierCodes maps modifier key codes to the modState field they set.
+var modifierCodes = map[string]func(*modState) *bool{
+	"ControlLeft":  func(m *modState) *bool { return &m.Ctrl },
+	"ControlRight": func(m *modState) *bool { return &m.Ctrl },
+	"ShiftLeft":    func(m *modState) *bool { return &m.Shift },
+	"ShiftRight":   func(m *modState) *bool { return &m.Shift },
+	"AltLeft":      func(m *modState) *bool { return &m.Alt },
+	"AltRight":     func(m *modState) *bool { return &m.Alt },
+	"MetaLeft":     func(m *modState) *bool { return &m.Meta },
+	"MetaRight":    func(m *modState) *bool { return &m.Meta },
+}
+
+// routeKey picks the injection route for a key-down. lookup finds the key
+// producing a character on the local layout.
+//
+// Characters are matched by what they are, not where they sit: typing "@"
+// on a Danish controller presses whatever gives "@" here, and Ctrl+Z hits
+// the key labelled Z even when it is in another place. Characters with no
+// key here are typed as Unicode. Named keys (Enter, arrows, modifiers, F1)
+// keep their physical position.
+//
+// mods is the modifier state the key needs. The controller's flags are
+// taken as wanted rather than already held: a browser sends the modifier
+// keys as well, but the CLI only sets flags.
+func routeKey(ev KeyEvent, lookup func(rune) (layoutKey, bool)) translatedKey {
+	if ev.Key == protocol.KeyDead || ev.Key == protocol.KeyProcess {
+		// The controller composes; the result comes with a later key
+		return translatedKey{route: routeSkip}
+	}
+
+	runes := []rune(ev.Key)
+	if ev.Code == "" {
+		switch {
+		case len(runes) == 0:
+			return translatedKey{route: routeSkip}
+		case len(runes) > 1:
+			return translatedKey{route: routeUnicode, text: ev.Key}
+		}
+	}
+	if len(runes) != 1 || unicode.IsControl(runes[0]) {
+		return translatedKey{route: routePhysical, mods: heldMods(ev)}
+	}
+	r := runes[0]
+
+	if ev.Meta || (ev.Ctrl && !ev.Alt) {
+		// Shortcut: the held modifiers stay as they are
+		if k, ok := lookup(unicode.ToLower(r)); ok && !k.AltGr {
+			return translatedKey{route: routeLayout, key: k, mods: heldMods(ev)}
+		}
+		if ev.Code == "" {
+			return translatedKey{route: routeSkip}
+		}
+		return translatedKey{route: routePhysical, mods: heldMods(ev)}
+	}
+
+	if k, ok := lookup(r); ok {
+		return translatedKey{route: routeLayout, key: k, mods: modState{Ctrl: k.AltGr, Shift: k.Shift, Alt: k.AltGr}}
+	}
+	return translatedKey{route: routeUnicode, text: string(r)}
+}
+
+// keyTranslator routes key events and remembers how each pressed key went
+// down, so its release takes the same route even when the modifiers (and
+// with them the reported Key) changed in between. It also tracks the
+// modifier keys it pressed, which is what is really held on this side.
+type keyTranslator struct {
+	mu      sync.Mutex
+	pressed map[string]translatedKey
+	held    map[string]bool // Modifier codes currently down
+}
+
+// heldState is the modifier state from modifier keys pressed so far.
+func (t *keyTranslator) heldState() modState {
+	var m modState
+	for code := range t.held {
+		*modifierCodes[code](&m) = true
+	}
+	return m
+}
+
+func (t *keyTranslator) translate(ev KeyEvent, lookup func(rune) (layoutKey, bool)) translatedKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pressed == nil {
+		t.pressed = make(map[string]translatedKey)
+		t.held = make(map[string]bool)
+	}
+
+	if _, ok := modifierCodes[ev.Code]; ok {
+		// Modifier keys go through as they are and are not wrapped
+		have := t.heldState()
+		if ev.Down {
+			t.held[ev.Code] = true
+		} else {
+			delete(t.held, ev.Code)
+		}
+		return translatedKey{route: routePhysical, mods: have, have: have}
+	}
+
+	if ev.Down {
+		tk := routeKey(ev, lookup)
+		tk.have = t.heldState()
+		if ev.Code != "" {
+			t.pressed[ev.Code] = tk
+		}
+		return tk
+	}
+
+	if ev.Code == "" {
+		return translatedKey{route: routeSkip}
+	}
+	tk, ok := t.pressed[ev.Code]
+	if !ok {
+		// Released without a press we saw (pressed before the session
+		// started): release the physical key to be safe
+		return translatedKey{route: routePhysical}
+	}
+	delete(t.pressed, ev.Code)
+	if tk.route == routeUnicode {
+		return translatedKey{route: routeSkip} // Typed on key-down
+	}
+	return tk
+}
+
+// reset forgets pressed keys, e.g. after ClearModifiers.
+func (t *keyTranslator) reset() {
+	t.mu.Lock()
+	t.pressed = nil
+	t.held = nil
+	t.mu.Unlock()
+}
@@ -0,0 +1,221 @@
+//go:build darwin
+
+package input
+
+/*
+#cgo LDFLAGS: -framework Carbon -framework CoreGraphics -framework ApplicationServices
+#include <Carbon/Carbon.h>
+#include <ApplicationServices/ApplicationServices.h>
+
+#include <dispatch/dispatch.h>
+#include <pthread.h>
+
+static void cfString(CFStringRef s, char *buf, int n) {
+    buf[0] = 0;
+    if (s != NULL) {
+        CFStringGetCString(s, buf, n, kCFStringEncodingUTF8);
+    }
+}
+
+// Text Input Source calls must run on the main thread, where the tray's
+// run loop lives.
+static void onMain(void *ctx, dispatch_function_t fn) {
+    if (pthread_main_np()) {
+        fn(ctx);
+    } else {
+        dispatch_sync_f(dispatch_get_main_queue(), ctx, fn);
+    }
+}
+
+typedef struct {
+    char *id, *lang;
+    int idLen, langLen;
+    UniChar *chars;
+    int ok;
+} tisCall;
+
+static void layoutInfoMain(void *p) {
+    tisCall *c = p;
+    TISInputSourceRef src = TISCopyCurrentKeyboardInputSource();
+    if (src == NULL) {
+        return;
+    }
+    cfString((CFStringRef)TISGetInputSourceProperty(src, kTISPropertyInputSourceID), c->id, c->idLen);
+    c->lang[0] = 0;
+    CFArrayRef langs = (CFArrayRef)TISGetInputSourceProperty(src, kTISPropertyInputSourceLanguages);
+    if (langs != NULL && CFArrayGetCount(langs) > 0) {
+        cfString((CFStringRef)CFArrayGetValueAtIndex(langs, 0), c->lang, c->langLen);
+    }
+    CFRelease(src);
+    c->ok = 1;
+}
+
+// layoutInfo writes the current input source ID and its first language.
+static int layoutInfo(char *id, int idLen, char *lang, int langLen) {
+    tisCall c = {id, lang, idLen, langLen, NULL, 0};
+    onMain(&c, layoutInfoMain);
+    return c.ok;
+}
+
+static void layoutCharsMain(void *p) {
+    tisCall *c = p;
+    TISInputSourceRef src = TISCopyCurrentKeyboardLayoutInputSource();
+    if (src == NULL) {
+        return;
+    }
+    CFDataRef data = (CFDataRef)TISGetInputSourceProperty(src, kTISPropertyUnicodeKeyLayoutData);
+    if (data == NULL) {
+        CFRelease(src);
+        return;
+    }
+    const UCKeyboardLayout *layout = (const UCKeyboardLayout *)CFDataGetBytePtr(data);
+    UInt32 states[4] = {0, shiftKey >> 8, optionKey >> 8, (shiftKey | optionKey) >> 8};
+    for (int s = 0; s < 4; s++) {
+        for (int code = 0; code < 128; code++) {
+            UInt32 dead = 0;
+            UniChar chars[4];
+            UniCharCount n = 0;
+            OSStatus err = UCKeyTranslate(layout, code, kUCKeyActionDown, states[s],
+                LMGetKbdType(), 0, &dead, 4, &n, chars);
+            c->chars[s * 128 + code] = (err == noErr && dead == 0 && n == 1) ? chars[0] : 0;
+        }
+    }
+    CFRelease(src);
+    c->ok = 1;
+}
+
+// layoutChars fills out[state*128 + keycode] with the character each key
+// types in four modifier states (none, shift, option, shift+option), or 0
+// for dead keys and keys typing nothing or several characters.
+static int layoutChars(UniChar *out) {
+    tisCall c = {NULL, NULL, 0, 0, out, 0};
+    onMain(&c, layoutCharsMain);
+    return c.ok;
+}
+
+// unicodeEvent types UTF-16 text as one key press.
+static void unicodeEvent(UniChar *chars, int n) {
+    CGEventRef down = CGEventCreateKeyboardEvent(NULL, 0, true);
+    CGEventRef up = CGEventCreateKeyboardEvent(NULL, 0, false);
+    CGEventSetFlags(down, 0);
+    CGEventSetFlags(up, 0);
+    CGEventKeyboardSetUnicodeString(down, n, chars);
+    CGEventKeyboardSetUnicodeString(up, n, chars);
+    CGEventPost(kCGSessionEventTap, down);
+    CGEventPost(kCGSessionEventTap, up);
+    CFRelease(down);
+    CFRelease(up);
+}
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// layoutCache holds the reverse character map of the last layout seen.
+var layoutCache struct {
+	sync.Mutex
+	id   string
+	keys map[rune]layoutKey
+}
+
+// CurrentLayout reports the current keyboard input source.
+func CurrentLayout() (Layout, error) {
+	var id, lang [256]C.char
+	if C.layoutInfo(&id[0], C.int(len(id)), &lang[0], C.int(len(lang))) == 0 {
+		return Layout{}, fmt.Errorf("no keyboard input source")
+	}
+	return Layout{ID: C.GoString(&id[0]), Lang: C.GoString(&lang[0])}, nil
+}
+
+// layoutKeys returns char -> key for the current layout, rebuilding the
+// map when the layout changes. The lowest modifier state wins for
+// characters that several keys type.
+func layoutKeys() map[rune]layoutKey {
+	layout, _ := CurrentLayout()
+
+	layoutCache.Lock()
+	defer layoutCache.Unlock()
+	if layoutCache.keys != nil && layoutCache.id == layout.ID {
+		return layoutCache.keys
+	}
+
+	var chars [4 * 128]uint16
+	keys := make(map[rune]layoutKey)
+	if C.layoutChars((*C.UniChar)(unsafe.Pointer(&chars[0]))) != 0 {
+		for state := 0; state < 4; state++ {
+			for code := 0; code < 128; code++ {
+				r := rune(chars[state*128+code])
+				if r == 0 || r < 0x20 {
+					continue
+				}
+				if _, ok := keys[r]; ok {
+					continue
+				}
+				keys[r] = layoutKey{Code: uint16(code), Shift: state&1 != 0, AltGr: state&2 != 0}
+			}
+		}
+	}
+	layoutCache.id = layout.ID
+	layoutCache.keys = keys
+	return keys
+}
+
+// SendKeyEvent injects a key from a layout-aware controller, choosing per
+// key between the physical key, the matching key on the current layout
+// and Unicode typing. Ctrl and Meta map to Command and Control as in
+// SendKeyWithModifiers.
+func (k *KeyboardController) SendKeyEvent(ev KeyEvent) error {
+	keys := layoutKeys()
+	tk := k.translator.translate(ev, func(r rune) (layoutKey, bool) {
+		key, ok := keys[r]
+		return key, ok
+	})
+
+	switch tk.route {
+	case routePhysical:
+		return k.SendKeyWithModifiers(ev.Code, ev.Down, ev.Ctrl, ev.Shift, ev.Alt, ev.Meta)
+
+	case routeLayout:
+		var flags uint64
+		if tk.mods.Shift {
+			flags |= 0x20000 // kCGEventFlagMaskShift
+		}
+		if tk.mods.Alt {
+			flags |= 0x80000 // kCGEventFlagMaskAlternate (also AltGr)
+		}
+		if tk.mods.Ctrl && !tk.mods.Alt {
+			flags |= 0x100000 // kCGEventFlagMaskCommand
+		}
+		if tk.mods.Meta {
+			flags |= 0x40000 // kCGEventFlagMaskControl
+		}
+		postKey(int(tk.key.Code), ev.Down, flags)
+		return nil
+
+	case routeUnicode:
+		return typeUnicode(tk.text)
+	}
+	return nil
+}
+
+// typeUnicode types text as Unicode key events, up to 20 UTF-16 units per
+// event (the most CGEventKeyboardSetUnicodeString reliably delivers).
+func typeUnicode(text string) error {
+	units := utf16.Encode([]rune(text))
+	for len(units) > 0 {
+		n := len(units)
+		if n > 20 {
+			n = 20
+			if utf16.IsSurrogate(rune(units[n-1])) {
+				n-- // Keep surrogate pairs together
+			}
+		}
+		C.unicodeEvent((*C.UniChar)(unsafe.Pointer(&units[0])), C.int(n))
+		units = units[n:]
+	}
+	return nil
+}
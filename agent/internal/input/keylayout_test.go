@@ -0,0 +1,144 @@
+package input
+
+import "testing"
+
+// germanKeys is a slice of a German layout as VkKeyScanEx would see it.
+func germanKeys(r rune) (layoutKey, bool) {
+	keys := map[rune]layoutKey{
+		'z': {Code: 'Z'},
+		'Z': {Code: 'Z', Shift: true},
+		'y': {Code: 'Y'},
+		'q': {Code: 'Q'},
+		'@': {Code: 'Q', AltGr: true},
+		'a': {Code: 'A'},
+		'A': {Code: 'A', Shift: true},
+	}
+	k, ok := keys[r]
+	return k, ok
+}
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		ev    KeyEvent
+		route keyRoute
+		key   layoutKey
+		mods  modState
+		text  string
+	}{
+		{
+			name:  "AltGr symbol moves to the local key",
+			ev:    KeyEvent{Code: "Digit2", Key: "@", Down: true, Ctrl: true, Alt: true},
+			route: routeLayout,
+			key:   layoutKey{Code: 'Q', AltGr: true},
+			mods:  modState{Ctrl: true, Alt: true},
+		},
+		{
+			name:  "Shifted letter keeps Shift",
+			ev:    KeyEvent{Code: "KeyA", Key: "A", Down: true, Shift: true},
+			route: routeLayout,
+			key:   layoutKey{Code: 'A', Shift: true},
+			mods:  modState{Shift: true},
+		},
+		{
+			name:  "Shortcut follows the letter, not the position",
+			ev:    KeyEvent{Code: "KeyZ", Key: "z", Down: true, Ctrl: true},
+			route: routeLayout,
+			key:   layoutKey{Code: 'Z'},
+			mods:  modState{Ctrl: true},
+		},
+		{
+			name:  "Shortcut with Shift keeps Shift",
+			ev:    KeyEvent{Code: "KeyZ", Key: "Z", Down: true, Ctrl: true, Shift: true},
+			route: routeLayout,
+			key:   layoutKey{Code: 'Z'},
+			mods:  modState{Ctrl: true, Shift: true},
+		},
+		{
+			name:  "Character missing here is typed as Unicode",
+			ev:    KeyEvent{Code: "Semicolon", Key: "æ", Down: true},
+			route: routeUnicode,
+			text:  "æ",
+		},
+		{
+			name:  "Named key stays physical",
+			ev:    KeyEvent{Code: "Enter", Key: "Enter", Down: true},
+			route: routePhysical,
+		},
+		{
+			name:  "Dead key waits for the composed character",
+			ev:    KeyEvent{Code: "Equal", Key: "Dead", Down: true},
+			route: routeSkip,
+		},
+		{
+			name:  "IME keystroke is skipped",
+			ev:    KeyEvent{Code: "KeyN", Key: "Process", Down: true},
+			route: routeSkip,
+		},
+		{
+			name:  "Composed IME text is typed",
+			ev:    KeyEvent{Key: "日本", Down: true},
+			route: routeUnicode,
+			text:  "日本",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := routeKey(tt.ev, germanKeys)
+			if got.route != tt.route || got.key != tt.key || got.mods != tt.mods || got.text != tt.text {
+				t.Errorf("routeKey = %+v, want route %d key %+v mods %+v text %q",
+					got, tt.route, tt.key, tt.mods, tt.text)
+			}
+		})
+	}
+}
+
+func TestKeyTranslator_ReleaseFollowsPress(t *testing.T) {
+	var tr keyTranslator
+
+	down := tr.translate(KeyEvent{Code: "KeyA", Key: "a", Down: true}, germanKeys)
+	// Shift went down in between, so the release reports "A"
+	up := tr.translate(KeyEvent{Code: "KeyA", Key: "A", Shift: true}, germanKeys)
+	if up.route != routeLayout || up.key != down.key {
+		t.Fatalf("release = %+v, want the key pressed (%+v)", up, down.key)
+	}
+
+	tr.translate(KeyEvent{Code: "Semicolon", Key: "æ", Down: true}, germanKeys)
+	if up := tr.translate(KeyEvent{Code: "Semicolon", Key: "æ"}, germanKeys); up.route != routeSkip {
+		t.Fatalf("release after Unicode typing = %+v, want skip", up)
+	}
+
+	tr.translate(KeyEvent{Code: "Equal", Key: "Dead", Down: true}, germanKeys)
+	if up := tr.translate(KeyEvent{Code: "Equal", Key: "Dead"}, germanKeys); up.route != routeSkip {
+		t.Fatalf("dead key release = %+v, want skip", up)
+	}
+
+	if up := tr.translate(KeyEvent{Code: "KeyQ", Key: "q"}, germanKeys); up.route != routePhysical {
+		t.Fatalf("unpaired release = %+v, want physical", up)
+	}
+}
+
+func TestKeyTranslator_TracksHeldModifiers(t *testing.T) {
+	var tr keyTranslator
+
+	// CLI style: Ctrl only as a flag, nothing held, so Ctrl must be pressed
+	tk := tr.translate(KeyEvent{Code: "KeyZ", Key: "z", Down: true, Ctrl: true}, germanKeys)
+	if tk.have != (modState{}) || tk.mods != (modState{Ctrl: true}) {
+		t.Fatalf("flag-only shortcut: have %+v mods %+v, want none -> Ctrl", tk.have, tk.mods)
+	}
+	tr.translate(KeyEvent{Code: "KeyZ", Key: "z", Ctrl: true}, germanKeys)
+
+	// Browser style: ControlLeft goes down first and passes straight through
+	if mod := tr.translate(KeyEvent{Code: "ControlLeft", Key: "Control", Down: true, Ctrl: true}, germanKeys); mod.route != routePhysical || mod.have != mod.mods {
+		t.Fatalf("modifier key = %+v, want physical and unwrapped", mod)
+	}
+	tk = tr.translate(KeyEvent{Code: "KeyZ", Key: "z", Down: true, Ctrl: true}, germanKeys)
+	if tk.have != tk.mods {
+		t.Fatalf("held shortcut: have %+v mods %+v, want equal", tk.have, tk.mods)
+	}
+
+	tr.translate(KeyEvent{Code: "ControlLeft", Key: "Control"}, germanKeys)
+	if got := tr.heldState(); got != (modState{}) {
+		t.Fatalf("held after release = %+v, want none", got)
+	}
+}
@@ -0,0 +1,181 @@
+//go:build windows
+
+package input
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const (
+	keyeventfExtendedKey = 0x0001
+	keyeventfScancode    = 0x0008
+
+	mapvkVKToVSC  = 0
+	mapvkVKToChar = 2
+
+	vkShift   = 0x10
+	vkControl = 0x11
+	vkMenu    = 0x12
+	vkLWin    = 0x5B
+)
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetKeyboardLayout        = user32.NewProc("GetKeyboardLayout")
+	procVkKeyScanExW             = user32.NewProc("VkKeyScanExW")
+	procMapVirtualKeyExW         = user32.NewProc("MapVirtualKeyExW")
+	procLCIDToLocaleName         = kernel32.NewProc("LCIDToLocaleName")
+)
+
+// activeLayout returns the HKL of the foreground window's thread, which is
+// what the user is typing into. Windows layouts are per thread.
+func activeLayout() uintptr {
+	var tid uintptr
+	if hwnd, _, _ := procGetForegroundWindow.Call(); hwnd != 0 {
+		tid, _, _ = procGetWindowThreadProcessId.Call(hwnd, 0)
+	}
+	hkl, _, _ := procGetKeyboardLayout.Call(tid)
+	return hkl
+}
+
+// CurrentLayout reports the foreground keyboard layout.
+func CurrentLayout() (Layout, error) {
+	hkl := activeLayout()
+	if hkl == 0 {
+		return Layout{}, fmt.Errorf("no keyboard layout")
+	}
+	layout := Layout{ID: fmt.Sprintf("%08X", uint32(hkl))}
+	var name [85]uint16 // LOCALE_NAME_MAX_LENGTH
+	if n, _, _ := procLCIDToLocaleName.Call(hkl&0xFFFF, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name)), 0); n > 0 {
+		layout.Lang = syscall.UTF16ToString(name[:])
+	}
+	return layout, nil
+}
+
+// layoutKeyForChar finds the key producing r on layout hkl. Dead keys and
+// keys needing modifiers other than Shift and AltGr don't count: typing
+// them would not give r on its own.
+func layoutKeyForChar(r rune, hkl uintptr) (layoutKey, bool) {
+	if r > 0xFFFF {
+		return layoutKey{}, false
+	}
+	ret, _, _ := procVkKeyScanExW.Call(uintptr(r), hkl)
+	res := uint16(ret)
+	if res == 0xFFFF {
+		return layoutKey{}, false
+	}
+	vk, mods := res&0xFF, res>>8
+	shift, ctrl, alt := mods&1 != 0, mods&2 != 0, mods&4 != 0
+	if mods&^7 != 0 || ctrl != alt {
+		return layoutKey{}, false
+	}
+	if ch, _, _ := procMapVirtualKeyExW.Call(uintptr(vk), mapvkVKToChar, hkl); ch&0x80000000 != 0 {
+		return layoutKey{}, false // Dead key
+	}
+	scan, _, _ := procMapVirtualKeyExW.Call(uintptr(vk), mapvkVKToVSC, hkl)
+	return layoutKey{Code: vk, Scan: uint16(scan), Shift: shift, AltGr: ctrl && alt}, true
+}
+
+// SendKeyEvent injects a key from a layout-aware controller, choosing per
+// key between the physical scancode, the matching key on the foreground
+// layout and Unicode typing.
+func (k *KeyboardController) SendKeyEvent(ev KeyEvent) error {
+	hkl := activeLayout()
+	tk := k.translator.translate(ev, func(r rune) (layoutKey, bool) {
+		return layoutKeyForChar(r, hkl)
+	})
+
+	switch tk.route {
+	case routePhysical:
+		scan := ev.Scan
+		if scan == 0 {
+			scan = protocol.ScanCode(ev.Code)
+		}
+		if scan == 0 {
+			return k.SendKeyWithModifiers(ev.Code, ev.Down, ev.Ctrl, ev.Shift, ev.Alt, ev.Meta)
+		}
+		if !ev.Down {
+			return sendInputs(scanInput(scan, false))
+		}
+		return sendInputs(withMods(tk.have, tk.mods, scanInput(scan, true)))
+
+	case routeLayout:
+		key := makeKeyboardInput(tk.key.Code, tk.key.Scan, 0)
+		if !ev.Down {
+			key = makeKeyboardInput(tk.key.Code, tk.key.Scan, keyeventfKeyup)
+			return sendInputs(key[:])
+		}
+		return sendInputs(withMods(tk.have, tk.mods, key[:]))
+
+	case routeUnicode:
+		var text []byte
+		for _, r := range tk.text {
+			for _, u := range syscall.StringToUTF16(string(r)) {
+				if u == 0 {
+					continue
+				}
+				down := makeKeyboardInput(0, u, keyeventfUnicode)
+				up := makeKeyboardInput(0, u, keyeventfUnicode|keyeventfKeyup)
+				text = append(text, down[:]...)
+				text = append(text, up[:]...)
+			}
+		}
+		if len(text) == 0 {
+			return nil
+		}
+		return sendInputs(withMods(tk.have, modState{}, text))
+	}
+	return nil
+}
+
+// scanInput builds a scancode key event; the 0xE0 prefix becomes the
+// extended-key flag.
+func scanInput(scan uint16, down bool) []byte {
+	flags := uint32(keyeventfScancode)
+	if scan>>8 == 0xE0 {
+		flags |= keyeventfExtendedKey
+	}
+	if !down {
+		flags |= keyeventfKeyup
+	}
+	in := makeKeyboardInput(0, scan&0xFF, flags)
+	return in[:]
+}
+
+// withMods wraps inputs so they run with modifier state want instead of
+// have, restoring have afterwards. Everything goes in one SendInput call so
+// real keystrokes cannot interleave.
+func withMods(have, want modState, inputs []byte) []byte {
+	var before, after []byte
+	for _, m := range []struct {
+		vk         uint16
+		have, want bool
+	}{
+		{vkControl, have.Ctrl, want.Ctrl},
+		{vkMenu, have.Alt, want.Alt},
+		{vkShift, have.Shift, want.Shift},
+		{vkLWin, have.Meta, want.Meta},
+	} {
+		if m.have == m.want {
+			continue
+		}
+		press := makeKeyboardInput(m.vk, 0, 0)
+		release := makeKeyboardInput(m.vk, 0, keyeventfKeyup)
+		if m.want {
+			before = append(before, press[:]...)
+			after = append(release[:], after...)
+		} else {
+			before = append(before, release[:]...)
+			after = append(press[:], after...)
+		}
+	}
+	out := append(before, inputs...)
+	return append(out, after...)
+}
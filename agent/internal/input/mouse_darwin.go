@@ -78,6 +78,10 @@ type MouseController struct {
 	cursorHidden bool
 	lastX        float64
 	lastY        float64
+	wheel        wheelAccumulator // Scroll pixel remainders
+	touchPanning bool             // Two-finger pan in progress
+	touchPanX    float64
+	touchPanY    float64
 }
 
 func NewMouseController(width, height int) *MouseController {
@@ -104,6 +108,11 @@ func (m *MouseController) Move(x, y float64) error {
 	screenX = clampFloat(screenX, 0, float64(m.screenWidth-1))
 	screenY = clampFloat(screenY, 0, float64(m.screenHeight-1))
 
+	// Coordinates are relative to the captured display (or the spanning
+	// desktop), so shift them into global display space
+	screenX += float64(m.offsetX)
+	screenY += float64(m.offsetY)
+
 	m.lastX = screenX
 	m.lastY = screenY
 
@@ -28,6 +28,7 @@ type MouseController struct {
 	offsetX      int // Monitor X offset in virtual desktop
 	offsetY      int // Monitor Y offset in virtual desktop
 	cursorHidden bool
+	wheel        wheelAccumulator
 }
 
 func NewMouseController(width, height int) *MouseController {
@@ -49,6 +50,9 @@ func (m *MouseController) SetResolution(width, height int) {
 	m.screenHeight = height
 }
 
+// Move moves mouse to absolute pixel coordinates within the captured frame.
+// The frame may be a secondary monitor or the spanning virtual desktop, so
+// the virtual desktop offset is applied like in MoveRelative.
 func (m *MouseController) Move(x, y float64) error {
 	// Absolute coordinates
 	screenX := int(math.Round(x))
@@ -58,6 +62,10 @@ func (m *MouseController) Move(x, y float64) error {
 	screenX = clamp(screenX, 0, m.screenWidth-1)
 	screenY = clamp(screenY, 0, m.screenHeight-1)
 
+	// Apply virtual desktop offset for multi-monitor
+	screenX += m.offsetX
+	screenY += m.offsetY
+
 	// Use Windows API directly to avoid robotgo DPI scaling issues
 	m.setCursorPos(screenX, screenY)
 	return nil
@@ -127,15 +135,56 @@ func (m *MouseController) Click(button string, down bool) error {
 	return nil
 }
 
+// Scroll scrolls the vertical wheel by one notch in the direction of delta;
+// positive is up. Legacy controllers send raw DOM deltas here, so the
+// magnitude is ignored.
 func (m *MouseController) Scroll(delta int) error {
-	if delta > 0 {
-		robotgo.ScrollDir(1, "up")
-	} else if delta < 0 {
-		robotgo.ScrollDir(1, "down")
+	switch {
+	case delta > 0:
+		return InjectWheel(0, WheelDelta)
+	case delta < 0:
+		return InjectWheel(0, -WheelDelta)
 	}
 	return nil
 }
 
+// ScrollXY scrolls with high-resolution deltas in WheelDelta units:
+// positive dy is up, positive dx is right. Fractions carry over to the next
+// call.
+func (m *MouseController) ScrollXY(dx, dy float64) error {
+	wx, wy := m.wheel.add(dx, dy)
+	return InjectWheel(wx, wy)
+}
+
+// Touch injects a multi-touch frame. Coordinates are pixels in the captured
+// frame, or 0.0-1.0 when rel is set.
+func (m *MouseController) Touch(points []TouchPoint, rel bool) error {
+	mapped := make([]TouchPoint, len(points))
+	for i, p := range points {
+		p.X, p.Y = m.toScreen(p.X, p.Y, rel)
+		mapped[i] = p
+	}
+	return InjectTouch(mapped)
+}
+
+// Pen injects a pen sample with coordinates as for Touch.
+func (m *MouseController) Pen(p PenPoint, rel bool) error {
+	p.X, p.Y = m.toScreen(p.X, p.Y, rel)
+	return InjectPen(p)
+}
+
+// toScreen maps captured-frame coordinates to virtual desktop pixels, the
+// same way Move and MoveRelative do.
+func (m *MouseController) toScreen(x, y float64, rel bool) (float64, float64) {
+	if rel {
+		x *= float64(m.screenWidth)
+		y *= float64(m.screenHeight)
+	}
+	x = clampFloat(x, 0, float64(m.screenWidth-1))
+	y = clampFloat(y, 0, float64(m.screenHeight-1))
+	return x + float64(m.offsetX), y + float64(m.offsetY)
+}
+
 // HideCursor hides the local mouse cursor during remote session
 func (m *MouseController) HideCursor() {
 	if m.cursorHidden {
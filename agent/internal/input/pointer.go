@@ -0,0 +1,103 @@
+package input
+
+import "sync"
+
+// Pointer phases for touch contacts and pen samples (same strings as the
+// wire protocol).
+const (
+	PhaseHover  = "hover" // Pen in range, not touching
+	PhaseDown   = "down"
+	PhaseMove   = "move"
+	PhaseUp     = "up"
+	PhaseCancel = "cancel"
+)
+
+// WheelDelta is one notch of a classic mouse wheel. High-resolution
+// wheels and trackpads send fractions of it.
+const WheelDelta = 120
+
+// maxTouchContacts is how many fingers are injected at once.
+const maxTouchContacts = 10
+
+// TouchPoint is one contact of a touch frame. X/Y are pixels in the
+// captured frame for MouseController.Touch and screen pixels for the
+// low-level injectors.
+type TouchPoint struct {
+	ID       int
+	X, Y     float64
+	Phase    string
+	Pressure float64 // 0-1, 0 = not reported
+}
+
+// PenPoint is one pen sample, with coordinates as for TouchPoint.
+type PenPoint struct {
+	X, Y     float64
+	Phase    string
+	Pressure float64 // 0-1
+	TiltX    float64 // Degrees, -90..90
+	TiltY    float64 // Degrees, -90..90
+	Rotation float64 // Degrees, 0..359
+	Eraser   bool
+	Barrel   bool
+}
+
+// Ends reports whether the phase lifts the contact.
+func (p TouchPoint) Ends() bool {
+	return p.Phase == PhaseUp || p.Phase == PhaseCancel
+}
+
+// wheelAccumulator turns fractional wheel deltas into whole units and
+// carries the remainder, so slow trackpad scrolls are not lost to rounding.
+type wheelAccumulator struct {
+	mu   sync.Mutex
+	x, y float64
+}
+
+func (a *wheelAccumulator) add(dx, dy float64) (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.x += dx
+	a.y += dy
+	wx, wy := int(a.x), int(a.y) // Truncates toward zero, keeps the sign
+	a.x -= float64(wx)
+	a.y -= float64(wy)
+	return wx, wy
+}
+
+// touchSlots maps controller contact IDs (browser touch identifiers can be
+// large) onto the small 0..maxTouchContacts-1 range pointer injection
+// expects. A slot is taken on the first sample of a contact and freed when
+// it lifts.
+type touchSlots struct {
+	mu    sync.Mutex
+	slots map[int]int
+}
+
+// slot returns the slot for id, or false when all slots are in use.
+func (t *touchSlots) slot(p TouchPoint) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.slots == nil {
+		t.slots = make(map[int]int)
+	}
+	s, ok := t.slots[p.ID]
+	if !ok {
+		used := make(map[int]bool, len(t.slots))
+		for _, v := range t.slots {
+			used[v] = true
+		}
+		for s = 0; s < maxTouchContacts; s++ {
+			if !used[s] {
+				break
+			}
+		}
+		if s == maxTouchContacts {
+			return 0, false
+		}
+		t.slots[p.ID] = s
+	}
+	if p.Ends() {
+		delete(t.slots, p.ID)
+	}
+	return s, true
+}
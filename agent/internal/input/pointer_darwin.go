@@ -0,0 +1,146 @@
+//go:build darwin
+
+package input
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics -framework ApplicationServices
+#include <CoreGraphics/CoreGraphics.h>
+#include <ApplicationServices/ApplicationServices.h>
+
+// Pixel-precise two-axis scroll. macOS wheel2 is positive to the left.
+static void scrollPixels(int dy, int dx) {
+    CGEventRef event = CGEventCreateScrollWheelEvent(NULL, kCGScrollEventUnitPixel, 2, dy, -dx);
+    CGEventPost(kCGSessionEventTap, event);
+    CFRelease(event);
+}
+
+// Left-button event for touch emulation: 0 = down, 1 = drag, 2 = up.
+static void touchMouse(double x, double y, int phase) {
+    CGEventType type = kCGEventLeftMouseDragged;
+    if (phase == 0) {
+        type = kCGEventLeftMouseDown;
+    } else if (phase == 2) {
+        type = kCGEventLeftMouseUp;
+    }
+    CGEventRef event = CGEventCreateMouseEvent(NULL, type, CGPointMake(x, y), kCGMouseButtonLeft);
+    CGEventPost(kCGSessionEventTap, event);
+    CFRelease(event);
+}
+
+// Tablet point event: a mouse event carrying pressure and tilt, which
+// drawing apps read as pen input. phase: 0 = down, 1 = drag, 2 = up, 3 = hover.
+static void penEvent(double x, double y, int phase, double pressure,
+    double tiltX, double tiltY, double rotation) {
+    CGEventType type = kCGEventLeftMouseDragged;
+    switch (phase) {
+    case 0: type = kCGEventLeftMouseDown; break;
+    case 2: type = kCGEventLeftMouseUp; break;
+    case 3: type = kCGEventMouseMoved; break;
+    }
+    CGEventRef event = CGEventCreateMouseEvent(NULL, type, CGPointMake(x, y), kCGMouseButtonLeft);
+    CGEventSetIntegerValueField(event, kCGMouseEventSubtype, kCGEventMouseSubtypeTabletPoint);
+    CGEventSetDoubleValueField(event, kCGMouseEventPressure, pressure);
+    CGEventSetDoubleValueField(event, kCGTabletEventPointPressure, pressure);
+    CGEventSetDoubleValueField(event, kCGTabletEventTiltX, tiltX);
+    CGEventSetDoubleValueField(event, kCGTabletEventTiltY, tiltY);
+    CGEventSetDoubleValueField(event, kCGTabletEventRotation, rotation);
+    CGEventPost(kCGSessionEventTap, event);
+    CFRelease(event);
+}
+*/
+import "C"
+import "math"
+
+// macPixelsPerNotch converts WheelDelta units to scroll pixels; one line of
+// a classic wheel is about ten pixels on macOS.
+const macPixelsPerNotch = 10
+
+// ScrollXY scrolls with high-resolution deltas in WheelDelta units:
+// positive dy is up, positive dx is right. Fractions carry over.
+func (m *MouseController) ScrollXY(dx, dy float64) error {
+	px, py := m.wheel.add(dx*macPixelsPerNotch/WheelDelta, dy*macPixelsPerNotch/WheelDelta)
+	if px != 0 || py != 0 {
+		C.scrollPixels(C.int(py), C.int(px))
+	}
+	return nil
+}
+
+// Touch emulates touch input; macOS has no public API to inject touches.
+// One finger drives the left button, two or more fingers pan with pixel
+// scrolling. Pinch and rotate gestures are not available to inject.
+func (m *MouseController) Touch(points []TouchPoint, rel bool) error {
+	var active []TouchPoint
+	for _, p := range points {
+		if !p.Ends() {
+			active = append(active, p)
+		}
+	}
+
+	if len(points) == 1 && len(active) <= 1 {
+		p := points[0]
+		x, y := m.toScreen(p.X, p.Y, rel)
+		phase := 1
+		switch {
+		case p.Phase == PhaseDown:
+			phase = 0
+		case p.Ends():
+			phase = 2
+		}
+		m.lastX, m.lastY = x, y
+		C.touchMouse(C.double(x), C.double(y), C.int(phase))
+		m.touchPanning = false
+		return nil
+	}
+
+	if len(active) < 2 {
+		m.touchPanning = false
+		return nil
+	}
+	var cx, cy float64
+	for _, p := range active {
+		x, y := m.toScreen(p.X, p.Y, rel)
+		cx += x / float64(len(active))
+		cy += y / float64(len(active))
+	}
+	if m.touchPanning {
+		// Content follows the fingers: moving down scrolls up
+		dx, dy := m.wheel.add(cx-m.touchPanX, cy-m.touchPanY)
+		if dx != 0 || dy != 0 {
+			C.scrollPixels(C.int(dy), C.int(-dx))
+		}
+	}
+	m.touchPanning = true
+	m.touchPanX, m.touchPanY = cx, cy
+	return nil
+}
+
+// Pen posts tablet point events so pressure-aware apps see a stylus.
+func (m *MouseController) Pen(p PenPoint, rel bool) error {
+	x, y := m.toScreen(p.X, p.Y, rel)
+	phase := 1
+	switch p.Phase {
+	case PhaseDown:
+		phase = 0
+	case PhaseUp, PhaseCancel:
+		phase = 2
+	case PhaseHover:
+		phase = 3
+	}
+	m.lastX, m.lastY = x, y
+	C.penEvent(C.double(x), C.double(y), C.int(phase), C.double(clampFloat(p.Pressure, 0, 1)),
+		C.double(clampFloat(p.TiltX, -90, 90)/90), C.double(clampFloat(p.TiltY, -90, 90)/90),
+		C.double(math.Mod(p.Rotation, 360)))
+	return nil
+}
+
+// toScreen maps captured-frame coordinates to global display points, the
+// same way Move and MoveRelative do.
+func (m *MouseController) toScreen(x, y float64, rel bool) (float64, float64) {
+	if rel {
+		x *= float64(m.screenWidth)
+		y *= float64(m.screenHeight)
+	}
+	x = clampFloat(x, 0, float64(m.screenWidth-1))
+	y = clampFloat(y, 0, float64(m.screenHeight-1))
+	return x + float64(m.offsetX), y + float64(m.offsetY)
+}
@@ -0,0 +1,55 @@
+package input
+
+import "testing"
+
+func TestWheelAccumulator_CarriesRemainder(t *testing.T) {
+	var acc wheelAccumulator
+	// Trackpad-style slivers of 0.4 units add up to one unit on the third call
+	for i, want := range []int{0, 0, 1, 0, 1} {
+		_, got := acc.add(0, 0.4)
+		if got != want {
+			t.Fatalf("call %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestWheelAccumulator_Negative(t *testing.T) {
+	var acc wheelAccumulator
+	if x, y := acc.add(-130.5, -0.5); x != -130 || y != 0 {
+		t.Fatalf("add(-130.5, -0.5) = %d, %d, want -130, 0", x, y)
+	}
+	if x, y := acc.add(-0.5, -0.5); x != -1 || y != -1 {
+		t.Fatalf("remainders not carried: got %d, %d, want -1, -1", x, y)
+	}
+}
+
+func TestTouchSlots_ReuseAfterLift(t *testing.T) {
+	var slots touchSlots
+
+	a, _ := slots.slot(TouchPoint{ID: 1001, Phase: PhaseDown})
+	b, _ := slots.slot(TouchPoint{ID: 1002, Phase: PhaseDown})
+	if a != 0 || b != 1 {
+		t.Fatalf("first slots = %d, %d, want 0, 1", a, b)
+	}
+	if again, _ := slots.slot(TouchPoint{ID: 1001, Phase: PhaseMove}); again != a {
+		t.Fatalf("moving contact changed slot: %d, want %d", again, a)
+	}
+	if up, _ := slots.slot(TouchPoint{ID: 1001, Phase: PhaseUp}); up != a {
+		t.Fatalf("lifting contact changed slot: %d, want %d", up, a)
+	}
+	if c, _ := slots.slot(TouchPoint{ID: 1003, Phase: PhaseDown}); c != a {
+		t.Fatalf("freed slot not reused: got %d, want %d", c, a)
+	}
+}
+
+func TestTouchSlots_Full(t *testing.T) {
+	var slots touchSlots
+	for id := 0; id < maxTouchContacts; id++ {
+		if _, ok := slots.slot(TouchPoint{ID: id, Phase: PhaseDown}); !ok {
+			t.Fatalf("slot %d refused", id)
+		}
+	}
+	if _, ok := slots.slot(TouchPoint{ID: 99, Phase: PhaseDown}); ok {
+		t.Fatal("eleventh contact got a slot")
+	}
+}
@@ -0,0 +1,305 @@
+//go:build windows
+
+package input
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"unsafe"
+)
+
+// SendInput mouse wheel flags
+const (
+	inputMouse          = 0
+	mouseeventfWheel    = 0x0800
+	mouseeventfHWheel   = 0x1000
+	mouseeventfLeftDown = 0x0002
+	mouseeventfLeftUp   = 0x0004
+)
+
+// Pointer injection constants (winuser.h)
+const (
+	ptTouch = 2
+	ptPen   = 3
+
+	pointerFlagInRange     = 0x00000002
+	pointerFlagInContact   = 0x00000004
+	pointerFlagFirstButton = 0x00000010
+	pointerFlagPrimary     = 0x00002000
+	pointerFlagConfidence  = 0x00004000
+	pointerFlagCanceled    = 0x00008000
+	pointerFlagDown        = 0x00010000
+	pointerFlagUpdate      = 0x00020000
+	pointerFlagUp          = 0x00040000
+
+	pointerChangeFirstButtonDown = 1
+	pointerChangeFirstButtonUp   = 2
+
+	touchMaskContactArea = 0x1
+	touchMaskOrientation = 0x2
+	touchMaskPressure    = 0x4
+
+	penFlagBarrel   = 0x1
+	penFlagInverted = 0x2
+	penFlagEraser   = 0x4
+	penMaskPressure = 0x1
+	penMaskRotation = 0x2
+	penMaskTiltX    = 0x4
+	penMaskTiltY    = 0x8
+
+	touchFeedbackDefault   = 0x1
+	pointerFeedbackDefault = 1
+
+	// Struct sizes on 64-bit Windows
+	sizePointerInfo      = 96
+	sizePointerTouchInfo = 144
+	sizePointerTypeInfo  = 152 // type + padding + union of touch/pen info
+)
+
+var (
+	procInitializeTouchInjection     = user32.NewProc("InitializeTouchInjection")
+	procInjectTouchInput             = user32.NewProc("InjectTouchInput")
+	procCreateSyntheticPointerDevice = user32.NewProc("CreateSyntheticPointerDevice")
+	procInjectSyntheticPointerInput  = user32.NewProc("InjectSyntheticPointerInput")
+
+	touchInitOnce sync.Once
+	touchInitErr  error
+
+	penInitOnce sync.Once
+	penDevice   uintptr
+	penInitErr  error
+
+	injectSlots touchSlots
+)
+
+// InjectWheel sends one wheel event per axis at the cursor. Values are in
+// WheelDelta units; positive dy scrolls up and positive dx scrolls right.
+func InjectWheel(dx, dy int) error {
+	var inputs []byte
+	if dy != 0 {
+		in := makeMouseInputRaw(0, 0, uint32(int32(dy)), mouseeventfWheel)
+		inputs = append(inputs, in[:]...)
+	}
+	if dx != 0 {
+		in := makeMouseInputRaw(0, 0, uint32(int32(dx)), mouseeventfHWheel)
+		inputs = append(inputs, in[:]...)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	return sendInputs(inputs)
+}
+
+// InjectTouch injects one touch frame in screen pixels. The frame must list
+// every contact on the surface, as InjectTouchInput replaces the previous
+// frame rather than updating it.
+func InjectTouch(points []TouchPoint) error {
+	touchInitOnce.Do(func() {
+		if err := procInitializeTouchInjection.Find(); err != nil {
+			touchInitErr = fmt.Errorf("touch injection requires Windows 8 or later")
+			return
+		}
+		ret, _, err := procInitializeTouchInjection.Call(maxTouchContacts, touchFeedbackDefault)
+		if ret == 0 {
+			touchInitErr = fmt.Errorf("InitializeTouchInjection failed: %v", err)
+		}
+	})
+	if touchInitErr != nil {
+		return touchInitErr
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 0, len(points)*sizePointerTouchInfo)
+	for i, p := range points {
+		slot, ok := injectSlots.slot(p)
+		if !ok {
+			continue // More fingers than we inject; drop the extras
+		}
+		var info [sizePointerTouchInfo]byte
+		flags := phaseFlags(p.Phase, false) | pointerFlagConfidence
+		if i == 0 {
+			flags |= pointerFlagPrimary
+		}
+		putPointerInfo(info[:], ptTouch, uint32(slot), flags, p.X, p.Y, 0)
+
+		x, y := int32(math.Round(p.X)), int32(math.Round(p.Y))
+		binary.LittleEndian.PutUint32(info[100:104], touchMaskContactArea|touchMaskOrientation|touchMaskPressure)
+		putRect(info[104:120], x-2, y-2, x+2, y+2)                              // rcContact
+		binary.LittleEndian.PutUint32(info[136:140], 90)                        // orientation
+		binary.LittleEndian.PutUint32(info[140:144], pressureUnits(p.Pressure)) // pressure
+		buf = append(buf, info[:]...)
+	}
+	count := len(buf) / sizePointerTouchInfo
+	if count == 0 {
+		return fmt.Errorf("no free touch contact")
+	}
+	ret, _, err := procInjectTouchInput.Call(uintptr(count), uintptr(unsafe.Pointer(&buf[0])))
+	if ret == 0 {
+		return fmt.Errorf("InjectTouchInput failed: %v", err)
+	}
+	return nil
+}
+
+// InjectPen injects one pen sample in screen pixels through a synthetic
+// pen device (Windows 10 1809+). On older systems the pen falls back to the
+// left mouse button, which still draws and signs but without pressure.
+func InjectPen(p PenPoint) error {
+	penInitOnce.Do(func() {
+		if err := procCreateSyntheticPointerDevice.Find(); err != nil {
+			penInitErr = fmt.Errorf("synthetic pen requires Windows 10 1809 or later")
+			return
+		}
+		dev, _, err := procCreateSyntheticPointerDevice.Call(ptPen, 1, pointerFeedbackDefault)
+		if dev == 0 {
+			penInitErr = fmt.Errorf("CreateSyntheticPointerDevice failed: %v", err)
+			return
+		}
+		penDevice = dev
+	})
+	if penInitErr != nil {
+		return injectPenAsMouse(p)
+	}
+
+	var info [sizePointerTypeInfo]byte
+	binary.LittleEndian.PutUint32(info[0:4], ptPen)
+	pen := info[8:]
+
+	flags := phaseFlags(p.Phase, true) | pointerFlagPrimary
+	change := 0
+	switch p.Phase {
+	case PhaseDown:
+		change = pointerChangeFirstButtonDown
+	case PhaseUp:
+		change = pointerChangeFirstButtonUp
+	}
+	putPointerInfo(pen, ptPen, 0, flags, p.X, p.Y, change)
+
+	var penFlags uint32
+	if p.Barrel {
+		penFlags |= penFlagBarrel
+	}
+	if p.Eraser {
+		penFlags |= penFlagInverted
+		if flags&pointerFlagInContact != 0 {
+			penFlags |= penFlagEraser
+		}
+	}
+	binary.LittleEndian.PutUint32(pen[96:100], penFlags)
+	binary.LittleEndian.PutUint32(pen[100:104], penMaskPressure|penMaskRotation|penMaskTiltX|penMaskTiltY)
+	binary.LittleEndian.PutUint32(pen[104:108], pressureUnits(p.Pressure))
+	binary.LittleEndian.PutUint32(pen[108:112], uint32(int(math.Round(p.Rotation))%360))
+	binary.LittleEndian.PutUint32(pen[112:116], uint32(int32(clampFloat(p.TiltX, -90, 90))))
+	binary.LittleEndian.PutUint32(pen[116:120], uint32(int32(clampFloat(p.TiltY, -90, 90))))
+
+	ret, _, err := procInjectSyntheticPointerInput.Call(penDevice, uintptr(unsafe.Pointer(&info[0])), 1)
+	if ret == 0 {
+		return fmt.Errorf("InjectSyntheticPointerInput failed: %v", err)
+	}
+	return nil
+}
+
+// injectPenAsMouse draws with the left button where no synthetic pen exists.
+func injectPenAsMouse(p PenPoint) error {
+	procSetCursorPos.Call(uintptr(int(math.Round(p.X))), uintptr(int(math.Round(p.Y))))
+	var flags uint32
+	switch p.Phase {
+	case PhaseDown:
+		flags = mouseeventfLeftDown
+	case PhaseUp, PhaseCancel:
+		flags = mouseeventfLeftUp
+	default:
+		return nil
+	}
+	in := makeMouseInputRaw(0, 0, 0, flags)
+	return sendInputs(in[:])
+}
+
+// phaseFlags returns POINTER_FLAG_* for a phase. Pens report the first
+// button while in contact.
+func phaseFlags(phase string, pen bool) uint32 {
+	contact := uint32(pointerFlagInRange | pointerFlagInContact)
+	if pen {
+		contact |= pointerFlagFirstButton
+	}
+	switch phase {
+	case PhaseDown:
+		return contact | pointerFlagDown
+	case PhaseMove:
+		return contact | pointerFlagUpdate
+	case PhaseHover:
+		return pointerFlagInRange | pointerFlagUpdate
+	case PhaseCancel:
+		return pointerFlagUp | pointerFlagCanceled
+	default: // PhaseUp
+		if pen {
+			return pointerFlagInRange | pointerFlagUp
+		}
+		return pointerFlagUp
+	}
+}
+
+// putPointerInfo fills the POINTER_INFO header at the start of buf.
+func putPointerInfo(buf []byte, pointerType, id, flags uint32, x, y float64, buttonChange int) {
+	binary.LittleEndian.PutUint32(buf[0:4], pointerType)
+	binary.LittleEndian.PutUint32(buf[4:8], id)
+	binary.LittleEndian.PutUint32(buf[12:16], flags)
+	// buf[16:32] = sourceDevice, hwndTarget (zero)
+	binary.LittleEndian.PutUint32(buf[32:36], uint32(int32(math.Round(x)))) // ptPixelLocation
+	binary.LittleEndian.PutUint32(buf[36:40], uint32(int32(math.Round(y))))
+	binary.LittleEndian.PutUint32(buf[88:92], uint32(buttonChange))
+}
+
+func putRect(buf []byte, left, top, right, bottom int32) {
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(left))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(top))
+	binary.LittleEndian.PutUint32(buf[8:12], uint32(right))
+	binary.LittleEndian.PutUint32(buf[12:16], uint32(bottom))
+}
+
+// pressureUnits maps 0-1 to the 0-1024 pointer pressure range. Unreported
+// pressure (0) becomes a medium press so apps still register contact.
+func pressureUnits(p float64) uint32 {
+	if p <= 0 {
+		return 512
+	}
+	return uint32(math.Round(clampFloat(p, 0, 1) * 1024))
+}
+
+// makeMouseInputRaw builds a 40-byte INPUT struct for mouse events.
+// Layout mirrors makeKeyboardInput: MOUSEINPUT starts at offset 8.
+func makeMouseInputRaw(dx, dy int32, mouseData, flags uint32) [cbSize]byte {
+	var buf [cbSize]byte
+	binary.LittleEndian.PutUint32(buf[0:4], inputMouse)
+	binary.LittleEndian.PutUint32(buf[8:12], uint32(dx))
+	binary.LittleEndian.PutUint32(buf[12:16], uint32(dy))
+	binary.LittleEndian.PutUint32(buf[16:20], mouseData)
+	binary.LittleEndian.PutUint32(buf[20:24], flags)
+	return buf
+}
+
+func sendInputs(inputs []byte) error {
+	count := len(inputs) / cbSize
+	n, _, err := procSendInput.Call(
+		uintptr(count),
+		uintptr(unsafe.Pointer(&inputs[0])),
+		uintptr(cbSize),
+	)
+	if n == 0 {
+		return fmt.Errorf("SendInput failed: %v", err)
+	}
+	return nil
+}
+
+func clampFloat(val, min, max float64) float64 {
+	if val < min {
+		return min
+	}
+	if val > max {
+		return max
+	}
+	return val
+}
@@ -0,0 +1,34 @@
+// Package locallock blocks the local user's keyboard and mouse, and can
+// cover the physical monitors, while a remote session keeps controlling
+// the machine. Remote input is injected (SendInput) and passes; only
+// input from real devices is dropped. A lock always ends: at its
+// deadline, on Unlock, or when the local user presses EmergencyCombo.
+package locallock
+
+import (
+	"errors"
+	"time"
+)
+
+// EmergencyCombo releases a lock from the local keyboard.
+const EmergencyCombo = "Ctrl+Alt+Shift+Esc"
+
+// DefaultMessage is shown on covered monitors when no message is given.
+const DefaultMessage = "Vedligeholdelse i gang\nTastatur og mus er midlertidigt spærret af supporten."
+
+// Reasons passed to the release callback.
+const (
+	ReleasedController = "controller"
+	ReleasedTimeout    = "timeout"
+	ReleasedEmergency  = "emergency"
+)
+
+// ErrUnsupported is returned by Lock where locking is not implemented.
+var ErrUnsupported = errors.New("local lock is not supported on this platform")
+
+// Options configures a lock.
+type Options struct {
+	Blank   bool      // Cover the monitors (never hidden from capture)
+	Message string    // Text on the covered monitors
+	Until   time.Time // The lock ends by itself at this time
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package locallock
+
+// Supported reports whether Lock works on this platform.
+func Supported() bool { return false }
+
+// Lock is not implemented on this platform.
+func Lock(opts Options, onRelease func(reason string)) (blanked bool, err error) {
+	return false, ErrUnsupported
+}
+
+// Unlock is a no-op on this platform.
+func Unlock() {}
+
+// Active reports whether a lock is in force.
+func Active() bool { return false }
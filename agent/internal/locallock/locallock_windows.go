@@ -0,0 +1,488 @@
+//go:build windows
+
+package locallock
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32   = windows.NewLazySystemDLL("user32.dll")
+	gdi32    = windows.NewLazySystemDLL("gdi32.dll")
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procSetWindowsHookExW          = user32.NewProc("SetWindowsHookExW")
+	procUnhookWindowsHookEx        = user32.NewProc("UnhookWindowsHookEx")
+	procCallNextHookEx             = user32.NewProc("CallNextHookEx")
+	procGetMessageW                = user32.NewProc("GetMessageW")
+	procDispatchMessageW           = user32.NewProc("DispatchMessageW")
+	procPostThreadMessageW         = user32.NewProc("PostThreadMessageW")
+	procSetTimer                   = user32.NewProc("SetTimer")
+	procKillTimer                  = user32.NewProc("KillTimer")
+	procRegisterClassExW           = user32.NewProc("RegisterClassExW")
+	procCreateWindowExW            = user32.NewProc("CreateWindowExW")
+	procDestroyWindow              = user32.NewProc("DestroyWindow")
+	procDefWindowProcW             = user32.NewProc("DefWindowProcW")
+	procShowWindow                 = user32.NewProc("ShowWindow")
+	procSetWindowPos               = user32.NewProc("SetWindowPos")
+	procSetWindowDisplayAffinity   = user32.NewProc("SetWindowDisplayAffinity")
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes")
+	procEnumDisplayMonitors        = user32.NewProc("EnumDisplayMonitors")
+	procBeginPaint                 = user32.NewProc("BeginPaint")
+	procEndPaint                   = user32.NewProc("EndPaint")
+	procGetClientRect              = user32.NewProc("GetClientRect")
+	procFillRect                   = user32.NewProc("FillRect")
+	procDrawTextW                  = user32.NewProc("DrawTextW")
+	procOpenInputDesktop           = user32.NewProc("OpenInputDesktop")
+	procSetThreadDesktop           = user32.NewProc("SetThreadDesktop")
+	procCloseDesktop               = user32.NewProc("CloseDesktop")
+	procGetStockObject             = gdi32.NewProc("GetStockObject")
+	procSetTextColor               = gdi32.NewProc("SetTextColor")
+	procSetBkMode                  = gdi32.NewProc("SetBkMode")
+	procCreateFontW                = gdi32.NewProc("CreateFontW")
+	procSelectObject               = gdi32.NewProc("SelectObject")
+	procDeleteObject               = gdi32.NewProc("DeleteObject")
+	procGetModuleHandleW           = kernel32.NewProc("GetModuleHandleW")
+)
+
+const (
+	whKeyboardLL = 13
+	whMouseLL    = 14
+	hcAction     = 0
+
+	llkhfInjected = 0x10
+	llmhfInjected = 0x01
+
+	wmKeyDown    = 0x0100
+	wmSysKeyDown = 0x0104
+	wmPaint      = 0x000F
+	wmTimer      = 0x0113
+	wmMouseAct   = 0x0021
+	wmApp        = 0x8000
+	wmRelease    = wmApp + 1
+
+	maNoActivate = 3
+
+	wsPopup           = 0x80000000
+	wsExTopmost       = 0x00000008
+	wsExTransparent   = 0x00000020
+	wsExToolWindow    = 0x00000080
+	wsExLayered       = 0x00080000
+	wsExNoActivate    = 0x08000000
+	swShowNoActivate  = 4
+	lwaAlpha          = 0x2
+	hwndTopmost       = ^uintptr(0) // (HWND)-1
+	swpNoSize         = 0x0001
+	swpNoMove         = 0x0002
+	swpNoActivate     = 0x0010
+	wdaExcludeCapture = 0x00000011 // WDA_EXCLUDEFROMCAPTURE, Windows 10 2004+
+
+	blackBrush      = 4
+	bkTransparent   = 1
+	dtCenter        = 0x0001
+	dtWordBreak     = 0x0010
+	dtCalcRect      = 0x0400
+	dtNoPrefix      = 0x0800
+	fwSemiBold      = 600
+	clearTypeQ      = 5
+	genericAll      = 0x10000000
+	overlayClass    = "RemoteDesktopLocalLock"
+	keepAliveMillis = 1000
+)
+
+type point struct{ X, Y int32 }
+
+type rect struct{ Left, Top, Right, Bottom int32 }
+
+type msg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      point
+	private uint32
+}
+
+type kbdLLHookStruct struct {
+	VkCode    uint32
+	ScanCode  uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+type msLLHookStruct struct {
+	Pt        point
+	MouseData uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+type wndClassEx struct {
+	Size       uint32
+	Style      uint32
+	WndProc    uintptr
+	ClsExtra   int32
+	WndExtra   int32
+	Instance   uintptr
+	Icon       uintptr
+	Cursor     uintptr
+	Background uintptr
+	MenuName   *uint16
+	ClassName  *uint16
+	IconSm     uintptr
+}
+
+type paintStruct struct {
+	Hdc       uintptr
+	Erase     int32
+	Paint     rect
+	Restore   int32
+	IncUpdate int32
+	Reserved  [32]byte
+}
+
+// lock is one lock in force. Its hooks and windows live on one OS thread,
+// which runs the message loop until the lock is released.
+type lock struct {
+	opts      Options
+	onRelease func(reason string)
+	threadID  uint32
+	blanked   bool
+	started   chan error
+	done      chan struct{}
+
+	// Lock thread only
+	windows []uintptr
+	text    []uint16
+	font    uintptr
+	keys    keyState
+}
+
+var (
+	mu      sync.Mutex
+	current *lock
+
+	// hooked is the lock the hook and window callbacks serve. Those run
+	// on the lock's thread only.
+	hooked *lock
+
+	keyboardCallback = windows.NewCallback(keyboardHook)
+	mouseCallback    = windows.NewCallback(mouseHook)
+	overlayCallback  = windows.NewCallback(overlayProc)
+	monitorCallback  = windows.NewCallback(monitorEnum)
+	classOnce        sync.Once
+	classErr         error
+	monitorRects     []rect
+)
+
+// Supported reports whether Lock works on this platform.
+func Supported() bool { return true }
+
+// Lock blocks local input until opts.Until, Unlock or the emergency
+// combination, then calls onRelease with the reason. A lock already in
+// force is replaced without calling its callback. blanked reports whether
+// the monitors are covered; covering needs Windows 10 2004 or later,
+// which can keep the cover out of screen capture.
+func Lock(opts Options, onRelease func(reason string)) (blanked bool, err error) {
+	if opts.Message == "" {
+		opts.Message = DefaultMessage
+	}
+	mu.Lock()
+	old := current
+	current = nil
+	mu.Unlock()
+	if old != nil {
+		old.release(ReleasedController, false)
+	}
+
+	l := &lock{
+		opts:      opts,
+		onRelease: onRelease,
+		started:   make(chan error, 1),
+		done:      make(chan struct{}),
+	}
+	go l.run()
+	if err := <-l.started; err != nil {
+		return false, err
+	}
+	mu.Lock()
+	current = l
+	mu.Unlock()
+	return l.blanked, nil
+}
+
+// Unlock releases the lock in force, if any.
+func Unlock() {
+	mu.Lock()
+	l := current
+	current = nil
+	mu.Unlock()
+	if l != nil {
+		l.release(ReleasedController, true)
+	}
+}
+
+// Active reports whether a lock is in force.
+func Active() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return current != nil
+}
+
+// release ends the lock and waits for its thread to clean up.
+func (l *lock) release(reason string, notify bool) {
+	if !notify {
+		mu.Lock()
+		l.onRelease = nil
+		mu.Unlock()
+	}
+	procPostThreadMessageW.Call(uintptr(l.threadID), wmRelease, uintptr(reasonIndex(reason)), 0)
+	select {
+	case <-l.done:
+	case <-time.After(3 * time.Second):
+		log.Println("⚠️ Local lock thread did not stop in time")
+	}
+}
+
+func (l *lock) run() {
+	// The thread is left locked: it was moved to the input desktop, and
+	// Go retires a locked thread when its goroutine ends.
+	runtime.LockOSThread()
+	l.threadID = windows.GetCurrentThreadId()
+	attachInputDesktop()
+
+	hooked = l
+	module, _, _ := procGetModuleHandleW.Call(0)
+	kbd, _, err := procSetWindowsHookExW.Call(whKeyboardLL, keyboardCallback, module, 0)
+	if kbd == 0 {
+		hooked = nil
+		l.started <- fmt.Errorf("keyboard hook: %v", err)
+		close(l.done)
+		return
+	}
+	mouse, _, err := procSetWindowsHookExW.Call(whMouseLL, mouseCallback, module, 0)
+	if mouse == 0 {
+		procUnhookWindowsHookEx.Call(kbd)
+		hooked = nil
+		l.started <- fmt.Errorf("mouse hook: %v", err)
+		close(l.done)
+		return
+	}
+	if l.opts.Blank {
+		if err := l.cover(module); err != nil {
+			log.Printf("⚠️ Local lock: monitors not covered: %v", err)
+			l.uncover()
+		} else {
+			l.blanked = true
+		}
+	}
+	timer, _, _ := procSetTimer.Call(0, 0, keepAliveMillis, 0)
+	log.Printf("🔒 Local input locked until %s (blank=%v)", l.opts.Until.Format("15:04:05"), l.blanked)
+	l.started <- nil
+
+	reason := l.loop()
+
+	procKillTimer.Call(0, timer)
+	procUnhookWindowsHookEx.Call(mouse)
+	procUnhookWindowsHookEx.Call(kbd)
+	l.uncover()
+	hooked = nil
+	log.Printf("🔓 Local input unlocked (%s)", reason)
+
+	mu.Lock()
+	if current == l {
+		current = nil
+	}
+	callback := l.onRelease
+	mu.Unlock()
+	close(l.done)
+	if callback != nil {
+		callback(reason)
+	}
+}
+
+// loop pumps messages until a release is posted or the deadline passes.
+func (l *lock) loop() string {
+	var m msg
+	for {
+		ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+		if int32(ret) <= 0 {
+			return ReleasedController
+		}
+		if m.Hwnd == 0 {
+			switch m.Message {
+			case wmRelease:
+				return reasonAt(m.WParam)
+			case wmTimer:
+				if expired(l.opts, time.Now()) {
+					return ReleasedTimeout
+				}
+				// Stay above windows that made themselves topmost later
+				for _, hwnd := range l.windows {
+					procSetWindowPos.Call(hwnd, hwndTopmost, 0, 0, 0, 0, swpNoMove|swpNoSize|swpNoActivate)
+				}
+				continue
+			}
+		}
+		procDispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
+	}
+}
+
+// attachInputDesktop moves the thread to the desktop receiving input, so
+// the hooks and the cover land where the user is.
+func attachInputDesktop() {
+	desk, _, _ := procOpenInputDesktop.Call(0, 0, genericAll)
+	if desk == 0 {
+		return
+	}
+	if ret, _, _ := procSetThreadDesktop.Call(desk); ret == 0 {
+		procCloseDesktop.Call(desk)
+	}
+}
+
+// keyboardHook drops keys from real keyboards and watches them for the
+// emergency combination. Injected keys pass.
+func keyboardHook(code int32, wParam uintptr, k *kbdLLHookStruct) uintptr {
+	if code == hcAction && hooked != nil && k.Flags&llkhfInjected == 0 {
+		l := hooked
+		down := wParam == wmKeyDown || wParam == wmSysKeyDown
+		if l.keys.key(k.VkCode, down) {
+			procPostThreadMessageW.Call(uintptr(l.threadID), wmRelease, uintptr(reasonIndex(ReleasedEmergency)), 0)
+		}
+		return 1
+	}
+	ret, _, _ := procCallNextHookEx.Call(0, uintptr(code), wParam, uintptr(unsafe.Pointer(k)))
+	return ret
+}
+
+// mouseHook drops events from real mice and touchpads.
+func mouseHook(code int32, wParam uintptr, m *msLLHookStruct) uintptr {
+	if code == hcAction && hooked != nil && m.Flags&llmhfInjected == 0 {
+		return 1
+	}
+	ret, _, _ := procCallNextHookEx.Call(0, uintptr(code), wParam, uintptr(unsafe.Pointer(m)))
+	return ret
+}
+
+// cover opens a window over each monitor. The windows are click-through,
+// never take focus, and are excluded from capture, so the remote side
+// keeps seeing and using the desktop underneath.
+func (l *lock) cover(module uintptr) error {
+	classOnce.Do(func() {
+		name, _ := windows.UTF16PtrFromString(overlayClass)
+		wc := wndClassEx{
+			WndProc:   overlayCallback,
+			Instance:  module,
+			ClassName: name,
+		}
+		wc.Size = uint32(unsafe.Sizeof(wc))
+		if ret, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))); ret == 0 {
+			classErr = fmt.Errorf("RegisterClassEx: %v", err)
+		}
+	})
+	if classErr != nil {
+		return classErr
+	}
+
+	text := l.opts.Message + "\n\nNødfrigivelse: " + EmergencyCombo
+	l.text, _ = windows.UTF16FromString(strings.ReplaceAll(text, "\n", "\r\n"))
+	face, _ := windows.UTF16PtrFromString("Segoe UI")
+	l.font, _, _ = procCreateFontW.Call(^uintptr(35), 0, 0, 0, fwSemiBold, 0, 0, 0, 1, 0, 0, clearTypeQ, 0, uintptr(unsafe.Pointer(face)))
+
+	monitorRects = nil
+	procEnumDisplayMonitors.Call(0, 0, monitorCallback, 0)
+	if len(monitorRects) == 0 {
+		return fmt.Errorf("no monitors")
+	}
+	class, _ := windows.UTF16PtrFromString(overlayClass)
+	for _, r := range monitorRects {
+		hwnd, _, err := procCreateWindowExW.Call(
+			wsExTopmost|wsExToolWindow|wsExLayered|wsExTransparent|wsExNoActivate,
+			uintptr(unsafe.Pointer(class)), 0, wsPopup,
+			uintptr(r.Left), uintptr(r.Top), uintptr(r.Right-r.Left), uintptr(r.Bottom-r.Top),
+			0, 0, module, 0)
+		if hwnd == 0 {
+			return fmt.Errorf("CreateWindowEx: %v", err)
+		}
+		l.windows = append(l.windows, hwnd)
+		procSetLayeredWindowAttributes.Call(hwnd, 0, 255, lwaAlpha)
+		// Without capture exclusion the remote side would see the cover
+		// too, so no cover at all is better than a cover without it.
+		if ret, _, err := procSetWindowDisplayAffinity.Call(hwnd, wdaExcludeCapture); ret == 0 {
+			return fmt.Errorf("SetWindowDisplayAffinity: %v", err)
+		}
+		procShowWindow.Call(hwnd, swShowNoActivate)
+	}
+	return nil
+}
+
+func (l *lock) uncover() {
+	for _, hwnd := range l.windows {
+		procDestroyWindow.Call(hwnd)
+	}
+	l.windows = nil
+	if l.font != 0 {
+		procDeleteObject.Call(l.font)
+		l.font = 0
+	}
+}
+
+func monitorEnum(monitor, hdc uintptr, r *rect, data uintptr) uintptr {
+	monitorRects = append(monitorRects, *r)
+	return 1
+}
+
+func overlayProc(hwnd uintptr, message uint32, wParam, lParam uintptr) uintptr {
+	switch message {
+	case wmPaint:
+		paintCover(hwnd)
+		return 0
+	case wmMouseAct:
+		return maNoActivate
+	}
+	ret, _, _ := procDefWindowProcW.Call(hwnd, uintptr(message), wParam, lParam)
+	return ret
+}
+
+// paintCover fills the window black with the message centred on it.
+func paintCover(hwnd uintptr) {
+	var ps paintStruct
+	hdc, _, _ := procBeginPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+	defer procEndPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+
+	var client rect
+	procGetClientRect.Call(hwnd, uintptr(unsafe.Pointer(&client)))
+	brush, _, _ := procGetStockObject.Call(blackBrush)
+	procFillRect.Call(hdc, uintptr(unsafe.Pointer(&client)), brush)
+
+	l := hooked
+	if l == nil || len(l.text) == 0 {
+		return
+	}
+	if l.font != 0 {
+		procSelectObject.Call(hdc, l.font)
+	}
+	procSetBkMode.Call(hdc, bkTransparent)
+	procSetTextColor.Call(hdc, 0x00FFFFFF)
+
+	const flags = dtCenter | dtWordBreak | dtNoPrefix
+	text := uintptr(unsafe.Pointer(&l.text[0]))
+	box := rect{Left: client.Left + 40, Right: client.Right - 40}
+	procDrawTextW.Call(hdc, text, ^uintptr(0), uintptr(unsafe.Pointer(&box)), flags|dtCalcRect)
+	height := box.Bottom - box.Top
+	box.Left, box.Right = client.Left+40, client.Right-40
+	box.Top = client.Top + (client.Bottom-client.Top-height)/2
+	box.Bottom = box.Top + height
+	procDrawTextW.Call(hdc, text, ^uintptr(0), uintptr(unsafe.Pointer(&box)), flags)
+}
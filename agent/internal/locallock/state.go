@@ -0,0 +1,58 @@
+package locallock
+
+import "time"
+
+// Virtual-key codes watched for EmergencyCombo. Windows VKs, but kept here
+// so the combination logic is testable everywhere.
+const (
+	vkEscape   = 0x1B
+	vkLShift   = 0xA0
+	vkRShift   = 0xA1
+	vkLControl = 0xA2
+	vkRControl = 0xA3
+	vkLMenu    = 0xA4
+	vkRMenu    = 0xA5
+)
+
+// releaseReasons maps a release code (the pipe and window message value)
+// to a reason.
+var releaseReasons = []string{ReleasedController, ReleasedTimeout, ReleasedEmergency}
+
+func reasonIndex(reason string) int {
+	for i, r := range releaseReasons {
+		if r == reason {
+			return i
+		}
+	}
+	return 0
+}
+
+// reasonAt is the reason for a release code; unknown codes count as the
+// controller's.
+func reasonAt(code uintptr) string {
+	if code >= uintptr(len(releaseReasons)) {
+		return ReleasedController
+	}
+	return releaseReasons[code]
+}
+
+// keyState tracks which real keys are held, to spot EmergencyCombo.
+type keyState struct {
+	down [256]bool
+}
+
+// key records a real key event and reports whether it completes
+// EmergencyCombo: Esc pressed while Ctrl, Alt and Shift (either side) are
+// held.
+func (k *keyState) key(vk uint32, down bool) bool {
+	k.down[vk&0xFF] = down
+	return down && vk == vkEscape &&
+		(k.down[vkLControl] || k.down[vkRControl]) &&
+		(k.down[vkLMenu] || k.down[vkRMenu]) &&
+		(k.down[vkLShift] || k.down[vkRShift])
+}
+
+// expired reports whether a lock with opts has run out at now.
+func expired(opts Options, now time.Time) bool {
+	return now.After(opts.Until)
+}
@@ -0,0 +1,85 @@
+package locallock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmergencyCombo(t *testing.T) {
+	press := func(k *keyState, vks ...uint32) bool {
+		hit := false
+		for _, vk := range vks {
+			hit = k.key(vk, true)
+		}
+		return hit
+	}
+
+	for _, tc := range []struct {
+		name string
+		keys []uint32
+	}{
+		{"left modifiers", []uint32{vkLControl, vkLMenu, vkLShift, vkEscape}},
+		{"right modifiers", []uint32{vkRControl, vkRMenu, vkRShift, vkEscape}},
+		{"mixed sides", []uint32{vkRShift, vkLControl, vkRMenu, vkEscape}},
+	} {
+		var k keyState
+		if !press(&k, tc.keys...) {
+			t.Errorf("%s: combo not detected", tc.name)
+		}
+	}
+
+	var k keyState
+	if press(&k, vkEscape) {
+		t.Error("Esc alone released the lock")
+	}
+	if press(&k, vkLControl, vkLMenu, vkEscape) {
+		t.Error("Ctrl+Alt+Esc released the lock")
+	}
+
+	// Esc first, then the modifiers: only a new Esc press counts
+	k = keyState{}
+	if press(&k, vkEscape, vkLControl, vkLMenu, vkLShift) {
+		t.Error("modifiers after Esc released the lock")
+	}
+
+	// A released modifier breaks the combination
+	k = keyState{}
+	press(&k, vkLControl, vkLMenu, vkLShift)
+	k.key(vkLShift, false)
+	if k.key(vkEscape, true) {
+		t.Error("combo detected with Shift released")
+	}
+	if k.key(vkEscape, false) {
+		t.Error("Esc release counted as a press")
+	}
+}
+
+func TestReleaseReasons(t *testing.T) {
+	for _, reason := range []string{ReleasedController, ReleasedTimeout, ReleasedEmergency} {
+		if got := reasonAt(uintptr(reasonIndex(reason))); got != reason {
+			t.Errorf("reasonAt(reasonIndex(%q)) = %q", reason, got)
+		}
+	}
+	if got := reasonAt(uintptr(len(releaseReasons))); got != ReleasedController {
+		t.Errorf("out of range code = %q, want %q", got, ReleasedController)
+	}
+	if got := reasonIndex("bogus"); got != reasonIndex(ReleasedController) {
+		t.Errorf("unknown reason index = %d", got)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		until time.Time
+		want  bool
+	}{
+		{now.Add(time.Minute), false},
+		{now, false},
+		{now.Add(-time.Second), true},
+	} {
+		if got := expired(Options{Until: tc.until}, now); got != tc.want {
+			t.Errorf("expired(until %v) = %v, want %v", tc.until, got, tc.want)
+		}
+	}
+}
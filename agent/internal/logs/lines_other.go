@@ -0,0 +1,50 @@
+//go:build !windows
+
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runLines runs name with args and calls fn with each line of its output
+// until fn returns false, the command exits or ctx ends.
+func runLines(parent context.Context, fn func(line []byte) bool, name string, args ...string) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("run %s: %w", name, err)
+	}
+	sc := bufio.NewScanner(out)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	stopped := false
+	for sc.Scan() {
+		if !fn(sc.Bytes()) {
+			stopped = true
+			break
+		}
+	}
+	cancel()
+	err = cmd.Wait()
+	if stopped || parent.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package logs reads the device's system log for the process channel's
+// log_query op: the Windows Event Log, the macOS unified log (log show and
+// log stream) and the systemd journal (journalctl) on Linux.
+package logs
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Levels, most severe first, matching protocol.Log*.
+const (
+	LevelCritical = "critical"
+	LevelError    = "error"
+	LevelWarning  = "warning"
+	LevelInfo     = "info"
+	LevelDebug    = "debug"
+)
+
+// Limits, matching protocol.LogDefaultLimit, LogMaxLimit and
+// MaxLogMessageBytes.
+const (
+	DefaultLimit    = 100
+	MaxLimit        = 1000
+	maxMessageBytes = 4 * 1024
+)
+
+// maxScanned bounds how many entries a query with a text filter reads
+// before giving up on finding Limit matches.
+const maxScanned = 200000
+
+// queryTimeout bounds one Read.
+const queryTimeout = 30 * time.Second
+
+// Follow batching: new entries are sent at most every followFlush, and a
+// batch holds at most maxBatch; the rest are counted as dropped.
+const (
+	followFlush = 500 * time.Millisecond
+	maxBatch    = 500
+)
+
+// timeFormat is UTC with fixed microseconds, so times sort as strings.
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Entry is one log entry, matching protocol.LogEntry.
+type Entry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Source  string `json:"source,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	EventID int    `json:"event_id,omitempty"`
+	PID     int    `json:"pid,omitempty"`
+	Message string `json:"message"`
+}
+
+// Query selects entries. Channels are Windows event logs (default System
+// and Application). Source is the event provider on Windows, the subsystem
+// or process on macOS and the unit or syslog identifier on Linux. Level is
+// the least severe level wanted (default info). Text is a case-insensitive
+// substring of the message. Zero Since and Until leave the range open,
+// except on macOS where Since defaults to an hour ago.
+type Query struct {
+	Channels []string
+	Source   string
+	Level    string
+	Text     string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// ParseLevel normalizes a level name, accepting the usual aliases.
+func ParseLevel(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return LevelInfo, nil
+	case "critical", "crit", "fatal", "fault", "emerg", "alert":
+		return LevelCritical, nil
+	case "error", "err":
+		return LevelError, nil
+	case "warning", "warn":
+		return LevelWarning, nil
+	case "info", "information", "notice", "default":
+		return LevelInfo, nil
+	case "debug", "verbose", "trace":
+		return LevelDebug, nil
+	}
+	return "", fmt.Errorf("unknown log level %q (use critical, error, warning, info or debug)", s)
+}
+
+// rank orders levels: 1 is critical, 5 is debug.
+func rank(level string) int {
+	switch level {
+	case LevelCritical:
+		return 1
+	case LevelError:
+		return 2
+	case LevelWarning:
+		return 3
+	case LevelDebug:
+		return 5
+	}
+	return 4
+}
+
+// prepare validates q and fills in defaults.
+func (q *Query) prepare() error {
+	level, err := ParseLevel(q.Level)
+	if err != nil {
+		return err
+	}
+	q.Level = level
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if strings.ContainsAny(q.Source, `'"\`) {
+		return fmt.Errorf("invalid source %q", q.Source)
+	}
+	for i, c := range q.Channels {
+		q.Channels[i] = strings.TrimSpace(c)
+	}
+	return nil
+}
+
+// match reports whether e passes the filters every platform applies after
+// reading: level, text and time range.
+func (q *Query) match(e Entry) bool {
+	if rank(e.Level) > rank(q.Level) {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(q.Text)) {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time < formatTime(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time > formatTime(q.Until) {
+		return false
+	}
+	return true
+}
+
+func formatTime(t time.Time) string { return t.UTC().Format(timeFormat) }
+
+// finish trims the message and caps its length.
+func finish(e Entry) Entry {
+	e.Message = strings.TrimSpace(e.Message)
+	if len(e.Message) > maxMessageBytes {
+		n := maxMessageBytes
+		for n > 0 && !utf8.RuneStart(e.Message[n]) {
+			n--
+		}
+		e.Message = e.Message[:n] + "…"
+	}
+	return e
+}
+
+// Read returns the newest q.Limit entries matching q, oldest first.
+func Read(q Query) ([]Entry, error) {
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	var out []Entry
+	scanned := 0
+	perChannel := map[string]int{}
+	err := read(ctx, q, func(e Entry) bool {
+		scanned++
+		if q.match(e) {
+			out = append(out, finish(e))
+			// Sources read newest first stop at Limit; Windows reads each
+			// event log in turn, so the count is per log. Sources read
+			// oldest first keep going and are cut below.
+			key := ""
+			if runtime.GOOS == "windows" {
+				key = e.Channel
+			}
+			perChannel[key]++
+			if newestFirst && perChannel[key] >= q.Limit {
+				return false
+			}
+		}
+		return scanned < maxScanned
+	})
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time < out[j].Time })
+	if len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out, nil
+}
+
+// Follow calls emit with batches of new entries matching q until ctx ends
+// or the log cannot be read. dropped counts entries left out because more
+// than one batch arrived in a flush interval.
+func Follow(ctx context.Context, q Query, emit func(entries []Entry, dropped int) error) error {
+	if err := q.prepare(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entries := make(chan Entry, maxBatch)
+	done := make(chan error, 1)
+	go func() {
+		done <- follow(ctx, q, func(e Entry) {
+			if q.match(e) {
+				select {
+				case entries <- finish(e):
+				case <-ctx.Done():
+				}
+			}
+		})
+	}()
+
+	ticker := time.NewTicker(followFlush)
+	defer ticker.Stop()
+	var batch []Entry
+	dropped := 0
+	for {
+		select {
+		case e := <-entries:
+			if len(batch) < maxBatch {
+				batch = append(batch, e)
+			} else {
+				dropped++
+			}
+		case <-ticker.C:
+			if len(batch) == 0 && dropped == 0 {
+				continue
+			}
+			if err := emit(batch, dropped); err != nil {
+				return err
+			}
+			batch, dropped = nil, 0
+		case err := <-done:
+			if len(batch) > 0 || dropped > 0 {
+				_ = emit(batch, dropped)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("log reader exited")
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
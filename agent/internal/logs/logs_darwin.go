@@ -0,0 +1,85 @@
+//go:build darwin
+
+package logs
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// newestFirst: log show prints oldest first, so a query reads the whole
+// range and keeps the newest Limit; Since defaults to defaultSince.
+const newestFirst = false
+
+const defaultSince = time.Hour
+
+// predicate builds the log predicate for the filters log can apply
+// itself. The unified log has no warning level; warnings are errors.
+func predicate(q Query) string {
+	var parts []string
+	switch q.Level {
+	case LevelCritical:
+		parts = append(parts, "messageType == fault")
+	case LevelError, LevelWarning:
+		parts = append(parts, "(messageType == error OR messageType == fault)")
+	}
+	if q.Source != "" {
+		parts = append(parts, `(subsystem == "`+q.Source+`" OR process == "`+q.Source+`")`)
+	}
+	if q.Text != "" {
+		parts = append(parts, `eventMessage CONTAINS[c] "`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(q.Text)+`"`)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// levelFlags are the log show flags that add info and debug entries;
+// without them it shows default, error and fault entries.
+func levelFlags(q Query) []string {
+	switch q.Level {
+	case LevelInfo:
+		return []string{"--info"}
+	case LevelDebug:
+		return []string{"--info", "--debug"}
+	}
+	return nil
+}
+
+func read(ctx context.Context, q Query, fn func(Entry) bool) error {
+	since := q.Since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultSince)
+	}
+	const layout = "2006-01-02 15:04:05"
+	args := append([]string{"show", "--style", "ndjson", "--start", since.Local().Format(layout)}, levelFlags(q)...)
+	if !q.Until.IsZero() {
+		args = append(args, "--end", q.Until.Local().Add(time.Second).Format(layout))
+	}
+	if p := predicate(q); p != "" {
+		args = append(args, "--predicate", p)
+	}
+	return runLines(ctx, func(line []byte) bool {
+		e, ok := parseUnifiedLog(line)
+		return !ok || fn(e)
+	}, "/usr/bin/log", args...)
+}
+
+func follow(ctx context.Context, q Query, fn func(Entry)) error {
+	level := "default"
+	switch q.Level {
+	case LevelInfo:
+		level = "info"
+	case LevelDebug:
+		level = "debug"
+	}
+	args := []string{"stream", "--style", "ndjson", "--level", level}
+	if p := predicate(q); p != "" {
+		args = append(args, "--predicate", p)
+	}
+	return runLines(ctx, func(line []byte) bool {
+		if e, ok := parseUnifiedLog(line); ok {
+			fn(e)
+		}
+		return true
+	}, "/usr/bin/log", args...)
+}
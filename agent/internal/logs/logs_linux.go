@@ -0,0 +1,52 @@
+//go:build linux
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// newestFirst: journalctl -r reads backwards, so a query stops once it
+// has Limit matches.
+const newestFirst = true
+
+// journalArgs are the journalctl arguments shared by read and follow. A
+// source matches the unit ("cups" means cups.service) or the syslog
+// identifier.
+func journalArgs(q Query) []string {
+	args := []string{"-o", "json", "--no-pager", "-q", "-p", journalPriority(q.Level)}
+	if q.Source != "" {
+		unit := q.Source
+		if !strings.Contains(unit, ".") {
+			unit += ".service"
+		}
+		args = append(args, "_SYSTEMD_UNIT="+unit, "+", "SYSLOG_IDENTIFIER="+q.Source)
+	}
+	return args
+}
+
+func read(ctx context.Context, q Query, fn func(Entry) bool) error {
+	args := append(journalArgs(q), "-r")
+	if !q.Since.IsZero() {
+		args = append(args, fmt.Sprintf("--since=@%d", q.Since.Unix()))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, fmt.Sprintf("--until=@%d", q.Until.Unix()+1))
+	}
+	return runLines(ctx, func(line []byte) bool {
+		e, ok := parseJournal(line)
+		return !ok || fn(e)
+	}, "journalctl", args...)
+}
+
+func follow(ctx context.Context, q Query, fn func(Entry)) error {
+	args := append(journalArgs(q), "-f", "-n", "0")
+	return runLines(ctx, func(line []byte) bool {
+		if e, ok := parseJournal(line); ok {
+			fn(e)
+		}
+		return true
+	}, "journalctl", args...)
+}
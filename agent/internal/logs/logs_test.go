@@ -0,0 +1,92 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryMatch(t *testing.T) {
+	q := Query{Level: "warn", Text: "Disk", Since: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)}
+	if err := q.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Level != LevelWarning || q.Limit != DefaultLimit {
+		t.Fatalf("prepare gave level %q limit %d", q.Level, q.Limit)
+	}
+	e := Entry{Time: "2026-10-19T10:00:00.500000Z", Level: LevelError, Message: "disk full"}
+	if !q.match(e) {
+		t.Error("error entry with matching text rejected")
+	}
+	e.Level = LevelInfo
+	if q.match(e) {
+		t.Error("info entry passed a warning filter")
+	}
+	e.Level, e.Time = LevelCritical, "2026-10-19T09:59:59.999999Z"
+	if q.match(e) {
+		t.Error("entry before Since accepted")
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("unknown level accepted")
+	}
+	bad := Query{Source: `x' or '1`}
+	if err := bad.prepare(); err == nil {
+		t.Error("quoted source accepted")
+	}
+	if long := finish(Entry{Message: strings.Repeat("å", maxMessageBytes)}); len(long.Message) > maxMessageBytes+len("…") {
+		t.Errorf("message not capped: %d bytes", len(long.Message))
+	}
+}
+
+func TestParseEventXML(t *testing.T) {
+	const ev = `<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System>` +
+		`<Provider Name='Service Control Manager'/><EventID Qualifiers='49152'>7000</EventID><Level>2</Level>` +
+		`<TimeCreated SystemTime='2026-10-19T10:00:00.1234567Z'/><EventRecordID>4711</EventRecordID>` +
+		`<Execution ProcessID='712' ThreadID='1'/><Channel>System</Channel></System>` +
+		`<EventData><Data Name='param1'>Foo</Data><Data Name='param2'>%%2</Data></EventData></Event>`
+	e, id, err := parseEventXML(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{Time: "2026-10-19T10:00:00.123456Z", Level: LevelError, Source: "Service Control Manager",
+		Channel: "System", EventID: 7000, PID: 712, Message: "param1=Foo; param2=%%2"}
+	if e != want || id != 4711 {
+		t.Errorf("got %+v id %d", e, id)
+	}
+}
+
+func TestParseUnifiedLog(t *testing.T) {
+	line := `{"timestamp":"2026-10-19 12:00:00.250000+0200","eventType":"logEvent","messageType":"Fault",` +
+		`"eventMessage":"crashed","subsystem":"","category":"","processImagePath":"/usr/libexec/foo","processID":42}`
+	e, ok := parseUnifiedLog([]byte(line))
+	want := Entry{Time: "2026-10-19T10:00:00.250000Z", Level: LevelCritical, Source: "foo", PID: 42, Message: "crashed"}
+	if !ok || e != want {
+		t.Errorf("got %+v, %v", e, ok)
+	}
+	if _, ok := parseUnifiedLog([]byte(`{"count":12,"finished":1}`)); ok {
+		t.Error("trailer parsed as an entry")
+	}
+}
+
+func TestParseJournal(t *testing.T) {
+	line := `{"__REALTIME_TIMESTAMP":"1792404000000000","PRIORITY":"3","SYSLOG_IDENTIFIER":"sshd",` +
+		`"_SYSTEMD_UNIT":"ssh.service","_PID":"99","MESSAGE":[104,105,255]}`
+	e, ok := parseJournal([]byte(line))
+	want := Entry{Time: "2026-10-19T10:00:00.000000Z", Level: LevelError, Source: "sshd",
+		Channel: "ssh.service", PID: 99, Message: "hi�"}
+	if !ok || e != want {
+		t.Errorf("got %+v, %v", e, ok)
+	}
+}
+
+func TestEventQuery(t *testing.T) {
+	q := Query{Level: LevelError, Source: "Disk", Since: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)}
+	want := "*[System[Level>=1 and Level<=2 and Provider[@Name='Disk'] and " +
+		"TimeCreated[@SystemTime>='2026-10-19T10:00:00.000Z'] and EventRecordID>7]]"
+	if got := eventQuery(q, 7); got != want {
+		t.Errorf("got %s", got)
+	}
+	if got := eventQuery(Query{Level: LevelDebug}, 0); got != "*" {
+		t.Errorf("unfiltered query = %s", got)
+	}
+}
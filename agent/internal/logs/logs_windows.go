@@ -0,0 +1,215 @@
+//go:build windows
+
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// newestFirst: channels are queried in reverse, so a query stops reading
+// a channel once it has Limit matches from it.
+const newestFirst = true
+
+var defaultChannels = []string{"System", "Application"}
+
+var (
+	wevtapi                      = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtQuery                 = wevtapi.NewProc("EvtQuery")
+	procEvtNext                  = wevtapi.NewProc("EvtNext")
+	procEvtRender                = wevtapi.NewProc("EvtRender")
+	procEvtClose                 = wevtapi.NewProc("EvtClose")
+	procEvtOpenPublisherMetadata = wevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtFormatMessage         = wevtapi.NewProc("EvtFormatMessage")
+)
+
+const (
+	evtQueryChannelPath         = 0x1
+	evtQueryReverseDirection    = 0x200
+	evtQueryTolerateQueryErrors = 0x1000
+	evtRenderEventXML           = 1
+	evtFormatMessageEvent       = 1
+	evtInfinite                 = 0xFFFFFFFF
+
+	// eventBatch is how many events one EvtNext returns.
+	eventBatch = 64
+	// followPoll is how often a followed channel is queried for new events.
+	followPoll = 2 * time.Second
+)
+
+func channels(q Query) []string {
+	if len(q.Channels) == 0 {
+		return defaultChannels
+	}
+	return q.Channels
+}
+
+func read(ctx context.Context, q Query, fn func(Entry) bool) error {
+	query := eventQuery(q, 0)
+	for _, ch := range channels(q) {
+		err := queryChannel(ctx, ch, query, evtQueryReverseDirection, func(e Entry, _ uint64) bool {
+			return fn(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func follow(ctx context.Context, q Query, fn func(Entry)) error {
+	// Start after each channel's newest event, whatever it is.
+	last := map[string]uint64{}
+	for _, ch := range channels(q) {
+		err := queryChannel(ctx, ch, "*", evtQueryReverseDirection, func(_ Entry, id uint64) bool {
+			last[ch] = id
+			return false
+		})
+		if err != nil {
+			return err
+		}
+	}
+	ticker := time.NewTicker(followPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		for _, ch := range channels(q) {
+			err := queryChannel(ctx, ch, eventQuery(Query{Level: q.Level, Source: q.Source}, last[ch]), 0,
+				func(e Entry, id uint64) bool {
+					last[ch] = id
+					fn(e)
+					return true
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// queryChannel runs an XPath query against one event log and calls fn
+// with each event and its record ID until fn returns false.
+func queryChannel(ctx context.Context, channel, query string, flags uint32, fn func(Entry, uint64) bool) error {
+	path, err := windows.UTF16PtrFromString(channel)
+	if err != nil {
+		return err
+	}
+	q, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		return err
+	}
+	h, _, err := procEvtQuery.Call(0, uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(q)),
+		uintptr(flags|evtQueryChannelPath|evtQueryTolerateQueryErrors))
+	if h == 0 {
+		if errors.Is(err, windows.ERROR_EVT_CHANNEL_NOT_FOUND) {
+			return fmt.Errorf("event log %q not found", channel)
+		}
+		return fmt.Errorf("query %s log: %w", channel, err)
+	}
+	defer evtClose(h)
+
+	pubs := map[string]uintptr{}
+	defer func() {
+		for _, p := range pubs {
+			if p != 0 {
+				evtClose(p)
+			}
+		}
+	}()
+	events := make([]uintptr, eventBatch)
+	for ctx.Err() == nil {
+		var n uint32
+		ok, _, err := procEvtNext.Call(h, uintptr(len(events)), uintptr(unsafe.Pointer(&events[0])),
+			evtInfinite, 0, uintptr(unsafe.Pointer(&n)))
+		if ok == 0 {
+			if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+				return nil
+			}
+			return fmt.Errorf("read %s log: %w", channel, err)
+		}
+		more := true
+		for _, ev := range events[:n] {
+			if more {
+				if e, id, err := renderEvent(ev, pubs); err == nil {
+					more = fn(e, id)
+				}
+			}
+			evtClose(ev)
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// renderEvent renders ev as XML and formats its message with the
+// provider's message table, opening the provider's metadata into pubs.
+func renderEvent(ev uintptr, pubs map[string]uintptr) (Entry, uint64, error) {
+	buf := make([]uint16, 4096)
+	var used, props uint32
+	for {
+		ok, _, err := procEvtRender.Call(0, ev, evtRenderEventXML, uintptr(len(buf)*2),
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&props)))
+		if ok != 0 {
+			break
+		}
+		if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+			return Entry{}, 0, err
+		}
+		buf = make([]uint16, used/2+1)
+	}
+	e, id, err := parseEventXML(windows.UTF16ToString(buf[:used/2]))
+	if err != nil {
+		return Entry{}, 0, err
+	}
+	pub, seen := pubs[e.Source]
+	if !seen {
+		pub = openPublisher(e.Source)
+		pubs[e.Source] = pub
+	}
+	if pub != 0 {
+		if msg := formatMessage(pub, ev); msg != "" {
+			e.Message = msg
+		}
+	}
+	return e, id, nil
+}
+
+func openPublisher(name string) uintptr {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil || name == "" {
+		return 0
+	}
+	h, _, _ := procEvtOpenPublisherMetadata.Call(0, uintptr(unsafe.Pointer(p)), 0, 0, 0)
+	return h
+}
+
+// formatMessage returns ev's message, or "" if the provider has none.
+func formatMessage(pub, ev uintptr) string {
+	buf := make([]uint16, 1024)
+	var used uint32
+	for i := 0; i < 2; i++ {
+		ok, _, err := procEvtFormatMessage.Call(pub, ev, 0, 0, 0, evtFormatMessageEvent,
+			uintptr(len(buf)), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)))
+		if ok != 0 {
+			return windows.UTF16ToString(buf)
+		}
+		if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+			return ""
+		}
+		buf = make([]uint16, used)
+	}
+	return ""
+}
+
+func evtClose(h uintptr) { procEvtClose.Call(h) }
@@ -0,0 +1,239 @@
+package logs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventXML is the part of a rendered Windows event the agent uses.
+type eventXML struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		}
+		EventID     int
+		Level       int
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		}
+		EventRecordID uint64
+		Channel       string
+		Execution     struct {
+			ProcessID int `xml:"ProcessID,attr"`
+		}
+	}
+	EventData struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		}
+	}
+}
+
+// parseEventXML parses an event rendered by EvtRender as XML and returns
+// it with its record ID. The message is the event's data; the caller
+// replaces it with the formatted message where the provider has one.
+func parseEventXML(data string) (Entry, uint64, error) {
+	var ev eventXML
+	if err := xml.Unmarshal([]byte(data), &ev); err != nil {
+		return Entry{}, 0, err
+	}
+	e := Entry{
+		Level:   windowsLevel(ev.System.Level),
+		Source:  ev.System.Provider.Name,
+		Channel: ev.System.Channel,
+		EventID: ev.System.EventID,
+		PID:     ev.System.Execution.ProcessID,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ev.System.TimeCreated.SystemTime); err == nil {
+		e.Time = formatTime(t)
+	}
+	var parts []string
+	for _, d := range ev.EventData.Data {
+		v := strings.TrimSpace(d.Value)
+		switch {
+		case v == "":
+		case d.Name != "":
+			parts = append(parts, d.Name+"="+v)
+		default:
+			parts = append(parts, v)
+		}
+	}
+	e.Message = strings.Join(parts, "; ")
+	return e, ev.System.EventRecordID, nil
+}
+
+// windowsLevel maps an event's Level; 0 (LogAlways) is used by audit
+// events and counts as info.
+func windowsLevel(level int) string {
+	switch level {
+	case 1:
+		return LevelCritical
+	case 2:
+		return LevelError
+	case 3:
+		return LevelWarning
+	case 5:
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// unifiedLogEvent is one line of `log show --style ndjson`.
+type unifiedLogEvent struct {
+	Timestamp        string `json:"timestamp"`
+	EventType        string `json:"eventType"`
+	MessageType      string `json:"messageType"`
+	EventMessage     string `json:"eventMessage"`
+	Subsystem        string `json:"subsystem"`
+	Category         string `json:"category"`
+	ProcessImagePath string `json:"processImagePath"`
+	ProcessID        int    `json:"processID"`
+}
+
+// parseUnifiedLog parses one ndjson line of the macOS unified log. Lines
+// that are not log events (activities, the trailer) are skipped.
+func parseUnifiedLog(line []byte) (Entry, bool) {
+	var ev unifiedLogEvent
+	if err := json.Unmarshal(line, &ev); err != nil || ev.Timestamp == "" {
+		return Entry{}, false
+	}
+	if ev.EventType != "" && ev.EventType != "logEvent" {
+		return Entry{}, false
+	}
+	t, err := time.Parse("2006-01-02 15:04:05.000000-0700", ev.Timestamp)
+	if err != nil {
+		return Entry{}, false
+	}
+	e := Entry{
+		Time:    formatTime(t),
+		Source:  ev.Subsystem,
+		Channel: ev.Category,
+		PID:     ev.ProcessID,
+		Message: ev.EventMessage,
+	}
+	if e.Source == "" && ev.ProcessImagePath != "" {
+		e.Source = filepath.Base(ev.ProcessImagePath)
+	}
+	switch ev.MessageType {
+	case "Fault":
+		e.Level = LevelCritical
+	case "Error":
+		e.Level = LevelError
+	case "Debug":
+		e.Level = LevelDebug
+	default:
+		e.Level = LevelInfo
+	}
+	return e, true
+}
+
+// parseJournal parses one line of `journalctl -o json`. MESSAGE is a
+// string, or an array of bytes when it is not valid UTF-8.
+func parseJournal(line []byte) (Entry, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Entry{}, false
+	}
+	str := func(name string) string {
+		var s string
+		_ = json.Unmarshal(fields[name], &s)
+		return s
+	}
+	usec, err := strconv.ParseInt(str("__REALTIME_TIMESTAMP"), 10, 64)
+	if err != nil {
+		return Entry{}, false
+	}
+	e := Entry{
+		Time:    formatTime(time.UnixMicro(usec)),
+		Level:   LevelInfo,
+		Source:  str("SYSLOG_IDENTIFIER"),
+		Channel: str("_SYSTEMD_UNIT"),
+		Message: str("MESSAGE"),
+	}
+	if e.Source == "" {
+		e.Source = str("_COMM")
+	}
+	e.PID, _ = strconv.Atoi(str("_PID"))
+	if e.Message == "" {
+		var raw []byte
+		var ints []int
+		if json.Unmarshal(fields["MESSAGE"], &ints) == nil {
+			for _, b := range ints {
+				raw = append(raw, byte(b))
+			}
+			e.Message = strings.ToValidUTF8(string(raw), "�")
+		}
+	}
+	if p, err := strconv.Atoi(str("PRIORITY")); err == nil {
+		e.Level = journalLevel(p)
+	}
+	return e, true
+}
+
+// journalLevel maps a syslog priority: 0-2 (emerg, alert, crit) are
+// critical and 5-6 (notice, info) are info.
+func journalLevel(p int) string {
+	switch {
+	case p <= 2:
+		return LevelCritical
+	case p == 3:
+		return LevelError
+	case p == 4:
+		return LevelWarning
+	case p == 7:
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// journalPriority is the journalctl -p value for the least severe level
+// wanted.
+func journalPriority(level string) string {
+	switch level {
+	case LevelCritical:
+		return "2"
+	case LevelError:
+		return "3"
+	case LevelWarning:
+		return "4"
+	case LevelDebug:
+		return "7"
+	}
+	return "6"
+}
+
+// eventQuery is the structured XPath query for q's level, source and time
+// range, and for events after record ID after when it is not 0.
+func eventQuery(q Query, after uint64) string {
+	var conds []string
+	switch q.Level {
+	case LevelDebug:
+	case LevelInfo:
+		conds = append(conds, "Level<=4")
+	default:
+		conds = append(conds, fmt.Sprintf("Level>=1 and Level<=%d", rank(q.Level)))
+	}
+	if q.Source != "" {
+		conds = append(conds, "Provider[@Name='"+q.Source+"']")
+	}
+	const layout = "2006-01-02T15:04:05.000Z"
+	if !q.Since.IsZero() {
+		conds = append(conds, "TimeCreated[@SystemTime>='"+q.Since.UTC().Format(layout)+"']")
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "TimeCreated[@SystemTime<='"+q.Until.UTC().Format(layout)+"']")
+	}
+	if after > 0 {
+		conds = append(conds, fmt.Sprintf("EventRecordID>%d", after))
+	}
+	if len(conds) == 0 {
+		return "*"
+	}
+	return "*[System[" + strings.Join(conds, " and ") + "]]"
+}
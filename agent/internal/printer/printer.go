@@ -0,0 +1,221 @@
+// Package printer is the agent's virtual printer. Documents printed to it
+// are spooled as files (PDF from the Windows "Microsoft Print To PDF"
+// driver and the CUPS PDF filter) and held until a controller takes or
+// declines them over the print channel.
+package printer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Limits on what the spool holds. Jobs nobody claimed within jobTTL are
+// deleted, as are the oldest beyond maxJobs.
+const (
+	jobTTL      = 15 * time.Minute
+	maxJobs     = 20
+	maxJobBytes = 200 * 1024 * 1024
+)
+
+// Job is a captured print job.
+type Job struct {
+	ID      string
+	Title   string
+	User    string
+	Format  string
+	Size    int64
+	Created time.Time
+	path    string
+}
+
+// Message returns the print_job offer for the job.
+func (j Job) Message() protocol.PrintJob {
+	return protocol.PrintJob{
+		Op:      protocol.OpPrintJob,
+		ID:      j.ID,
+		Title:   j.Title,
+		User:    j.User,
+		Format:  j.Format,
+		Size:    j.Size,
+		Created: j.Created.Unix(),
+	}
+}
+
+// Spooler collects the jobs printed to the virtual printer.
+type Spooler struct {
+	dir   string
+	mu    sync.Mutex
+	jobs  []*Job
+	onJob func(Job)
+	stop  chan struct{}
+}
+
+// NewSpooler creates a spooler for the platform's spool directory.
+func NewSpooler() *Spooler {
+	return &Spooler{dir: spoolDir()}
+}
+
+// SetOnJob sets the callback for each newly captured job.
+func (s *Spooler) SetOnJob(callback func(Job)) {
+	s.onJob = callback
+}
+
+// Start clears jobs left from a previous run and begins capturing.
+func (s *Spooler) Start() error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("create spool directory: %w", err)
+	}
+	if entries, err := os.ReadDir(s.dir); err == nil {
+		for _, e := range entries {
+			_ = os.Remove(filepath.Join(s.dir, e.Name()))
+		}
+	}
+	s.stop = make(chan struct{})
+	if err := s.capture(); err != nil {
+		return err
+	}
+	go s.expireLoop()
+	log.Printf("🖨️ Virtual printer spool started: %s", s.dir)
+	return nil
+}
+
+// Stop ends capturing. Spooled jobs stay on disk until the next Start.
+func (s *Spooler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+		s.stopCapture()
+	}
+}
+
+// Jobs returns the jobs waiting to be claimed, oldest first.
+func (s *Spooler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// Open opens a job's data.
+func (s *Spooler) Open(id string) (*os.File, Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.ID == id {
+			f, err := os.Open(j.path)
+			return f, *j, err
+		}
+	}
+	return nil, Job{}, fmt.Errorf("no print job %s", id)
+}
+
+// Remove deletes a job.
+func (s *Spooler) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, j := range s.jobs {
+		if j.ID == id {
+			_ = os.Remove(j.path)
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// add takes a spooled file into the queue and announces it.
+func (s *Spooler) add(path, title, user string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.Size() == 0 || info.Size() > maxJobBytes {
+		log.Printf("⚠️ Print job %q dropped (%d bytes)", title, info.Size())
+		_ = os.Remove(path)
+		return
+	}
+	head := make([]byte, 8)
+	if f, err := os.Open(path); err == nil {
+		n, _ := f.Read(head)
+		head = head[:n]
+		f.Close()
+	}
+	job := &Job{
+		ID:      newJobID(),
+		Title:   title,
+		User:    user,
+		Format:  DetectFormat(head),
+		Size:    info.Size(),
+		Created: time.Now(),
+		path:    path,
+	}
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	for len(s.jobs) > maxJobs {
+		_ = os.Remove(s.jobs[0].path)
+		s.jobs = s.jobs[1:]
+	}
+	s.mu.Unlock()
+
+	log.Printf("🖨️ Print job captured: %q (%s, %d bytes)", job.Title, job.Format, job.Size)
+	if s.onJob != nil {
+		s.onJob(*job)
+	}
+}
+
+func (s *Spooler) expireLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	stop := s.stop
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		cutoff := time.Now().Add(-jobTTL)
+		s.mu.Lock()
+		kept := s.jobs[:0]
+		for _, j := range s.jobs {
+			if j.Created.Before(cutoff) {
+				log.Printf("🖨️ Print job %q expired unclaimed", j.Title)
+				_ = os.Remove(j.path)
+				continue
+			}
+			kept = append(kept, j)
+		}
+		s.jobs = kept
+		s.mu.Unlock()
+	}
+}
+
+// DetectFormat names the page description language of spooled data.
+func DetectFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF")):
+		return protocol.PrintFormatPDF
+	case bytes.HasPrefix(head, []byte("%!")):
+		return protocol.PrintFormatPS
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return protocol.PrintFormatXPS
+	}
+	return protocol.PrintFormatRaw
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+package printer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]string{
+		"%PDF-1.7\n":         protocol.PrintFormatPDF,
+		"%!PS-Adobe-3.0":     protocol.PrintFormatPS,
+		"PK\x03\x04\x14\x00": protocol.PrintFormatXPS,
+		"\x1b%-12345X@PJL":   protocol.PrintFormatRaw,
+		"":                   protocol.PrintFormatRaw,
+	}
+	for head, want := range cases {
+		if got := DetectFormat([]byte(head)); got != want {
+			t.Errorf("DetectFormat(%q) = %s, want %s", head, got, want)
+		}
+	}
+}
+
+func TestSpoolerJobs(t *testing.T) {
+	s := &Spooler{dir: t.TempDir()}
+	var offered []Job
+	s.SetOnJob(func(j Job) { offered = append(offered, j) })
+
+	path := filepath.Join(s.dir, "a.job")
+	if err := os.WriteFile(path, []byte("%PDF-1.4 test"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.add(path, "Invoice", "alice")
+	empty := filepath.Join(s.dir, "b.job")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.add(empty, "Empty", "alice")
+
+	jobs := s.Jobs()
+	if len(jobs) != 1 || len(offered) != 1 {
+		t.Fatalf("jobs %v offered %v, want the one non-empty job", jobs, offered)
+	}
+	job := jobs[0]
+	if job.Title != "Invoice" || job.User != "alice" || job.Format != protocol.PrintFormatPDF || job.Size != 13 {
+		t.Errorf("job = %+v", job)
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("empty job not deleted: %v", err)
+	}
+
+	f, _, err := s.Open(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "%PDF-1.4 test" {
+		t.Errorf("job data = %q", data)
+	}
+
+	s.Remove(job.ID)
+	if len(s.Jobs()) != 0 {
+		t.Error("job not removed")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("job file not deleted: %v", err)
+	}
+}
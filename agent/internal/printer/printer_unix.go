@@ -0,0 +1,144 @@
+//go:build !windows
+
+package printer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Name is the CUPS queue users pick in print dialogs; CUPS queue names
+// cannot contain spaces.
+const Name = "RemoteDesktop"
+
+// backendName is the CUPS backend, and the scheme of the queue's device URI.
+const backendName = "remotedesktop"
+
+// backendScript is the CUPS backend. It is installed 0700 so CUPS runs it
+// as root, and writes each job plus a user/title file into the spool,
+// renaming the job into place last so the agent never sees half a job.
+const backendScript = `#!/bin/sh
+# Remote Desktop virtual printer: hands each job to the agent.
+# CUPS runs backends as: job-id user title copies options [file]
+if [ $# -eq 0 ]; then
+	echo 'direct remotedesktop "Remote Desktop" "Remote Desktop virtual printer"'
+	exit 0
+fi
+umask 077
+job="%s/cups-$1"
+if [ -n "$6" ]; then
+	cat "$6" > "$job.tmp" || exit 1
+else
+	cat > "$job.tmp" || exit 1
+fi
+printf '%%s\n%%s\n' "$2" "$3" > "$job.meta"
+mv "$job.tmp" "$job.job"
+exit 0
+`
+
+// PPDs that make CUPS hand the backend PDF. Without one the queue is raw
+// and passes on what applications print, which on current desktops is PDF.
+var pdfPPDs = []string{
+	"/usr/share/ppd/cupsfilters/Generic-PDF_Printer-PDF.ppd",
+	"/usr/share/cups/model/Generic-PDF_Printer-PDF.ppd",
+}
+
+func spoolDir() string {
+	return "/var/spool/remote-desktop-print"
+}
+
+func backendPath() string {
+	if runtime.GOOS == "darwin" {
+		return filepath.Join("/usr/libexec/cups/backend", backendName)
+	}
+	return filepath.Join("/usr/lib/cups/backend", backendName)
+}
+
+// Install writes the backend and adds the queue. Requires root.
+func Install() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("installing the printer requires root")
+	}
+	if err := os.MkdirAll(spoolDir(), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(backendPath(), []byte(fmt.Sprintf(backendScript, spoolDir())), 0700); err != nil {
+		return fmt.Errorf("install CUPS backend: %w", err)
+	}
+	args := []string{"-p", Name, "-E", "-v", backendName + ":/", "-D", "Remote Desktop", "-o", "printer-is-shared=false"}
+	model := []string{"-m", "raw"}
+	for _, ppd := range pdfPPDs {
+		if _, err := os.Stat(ppd); err == nil {
+			model = []string{"-P", ppd}
+			break
+		}
+	}
+	if out, err := exec.Command("lpadmin", append(args, model...)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("lpadmin: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Uninstall removes the queue and the backend.
+func Uninstall() error {
+	_ = exec.Command("lpadmin", "-x", Name).Run()
+	if err := os.Remove(backendPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Installed reports whether the backend and queue exist.
+func Installed() bool {
+	if _, err := os.Stat(backendPath()); err != nil {
+		return false
+	}
+	return exec.Command("lpstat", "-p", Name).Run() == nil
+}
+
+// capture polls the spool for jobs the backend finished.
+func (s *Spooler) capture() error {
+	go func() {
+		stop := s.stop
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			s.collect()
+		}
+	}()
+	return nil
+}
+
+// collect takes every finished job. Its user/title file is consumed, so
+// a job is only taken once.
+func (s *Spooler) collect() {
+	metas, _ := filepath.Glob(filepath.Join(s.dir, "cups-*.meta"))
+	for _, meta := range metas {
+		path := strings.TrimSuffix(meta, ".meta") + ".job"
+		if _, err := os.Stat(path); err != nil {
+			continue // Still being written
+		}
+		var user, title string
+		if data, err := os.ReadFile(meta); err == nil {
+			user, title, _ = strings.Cut(strings.TrimRight(string(data), "\n"), "\n")
+		}
+		if err := os.Remove(meta); err != nil {
+			log.Printf("⚠️ Print spool: %v", err)
+			continue
+		}
+		s.add(path, title, user)
+	}
+}
+
+func (s *Spooler) stopCapture() {}
@@ -0,0 +1,255 @@
+//go:build windows
+
+package printer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Name is the printer users pick in print dialogs.
+const Name = "Remote Desktop"
+
+// pipeName is the printer's port. The built-in Local Port monitor writes
+// jobs to any path CreateFile opens, so a named pipe port hands each job
+// to the agent without a port monitor DLL of our own.
+const pipeName = `\\.\pipe\RemoteDesktopPrint`
+
+var (
+	winspool           = windows.NewLazySystemDLL("winspool.drv")
+	procOpenPrinterW   = winspool.NewProc("OpenPrinterW")
+	procClosePrinter   = winspool.NewProc("ClosePrinter")
+	procEnumJobsW      = winspool.NewProc("EnumJobsW")
+	jobStatusPrinting  = uint32(0x10) // JOB_STATUS_PRINTING
+	maxJobsEnumerated  = uint32(16)
+	printerInstallWait = 30 * time.Second
+)
+
+// jobInfo1 is JOB_INFO_1W.
+type jobInfo1 struct {
+	JobID        uint32
+	PrinterName  *uint16
+	MachineName  *uint16
+	UserName     *uint16
+	Document     *uint16
+	Datatype     *uint16
+	StatusText   *uint16
+	Status       uint32
+	Priority     uint32
+	Position     uint32
+	TotalPages   uint32
+	PagesPrinted uint32
+	Submitted    windows.Systemtime
+}
+
+func spoolDir() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "RemoteDesktopAgent", "print")
+}
+
+// Install adds the pipe port and a printer on it that uses the "Microsoft
+// Print To PDF" driver, so every job arrives as PDF. Requires admin.
+func Install() error {
+	script := fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+if (-not (Get-PrinterPort -Name '%[1]s' -ErrorAction SilentlyContinue)) { Add-PrinterPort -Name '%[1]s' }
+if (-not (Get-Printer -Name '%[2]s' -ErrorAction SilentlyContinue)) { Add-Printer -Name '%[2]s' -DriverName 'Microsoft Print To PDF' -PortName '%[1]s' }`,
+		pipeName, Name)
+	return powershell(script)
+}
+
+// Uninstall removes the printer and its port.
+func Uninstall() error {
+	script := fmt.Sprintf(`Remove-Printer -Name '%s' -ErrorAction SilentlyContinue
+Remove-PrinterPort -Name '%s' -ErrorAction SilentlyContinue`, Name, pipeName)
+	return powershell(script)
+}
+
+// Installed reports whether the printer exists.
+func Installed() bool {
+	h, err := openPrinter()
+	if err != nil {
+		return false
+	}
+	procClosePrinter.Call(uintptr(h))
+	return true
+}
+
+func powershell(script string) error {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	done := make(chan error, 1)
+	var out []byte
+	go func() {
+		var err error
+		out, err = cmd.CombinedOutput()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	case <-time.After(printerInstallWait):
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("powershell timed out")
+	}
+}
+
+func openPrinter() (windows.Handle, error) {
+	name, _ := windows.UTF16PtrFromString(Name)
+	var h windows.Handle
+	ret, _, err := procOpenPrinterW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&h)), 0)
+	if ret == 0 {
+		return 0, err
+	}
+	return h, nil
+}
+
+// currentJob returns the document name and owner of the job the spooler
+// is writing to the port.
+func currentJob() (title, user string) {
+	h, err := openPrinter()
+	if err != nil {
+		return "", ""
+	}
+	defer procClosePrinter.Call(uintptr(h))
+
+	var needed, returned uint32
+	procEnumJobsW.Call(uintptr(h), 0, uintptr(maxJobsEnumerated), 1, 0, 0,
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&returned)))
+	if needed == 0 {
+		return "", ""
+	}
+	buf := make([]byte, needed)
+	ret, _, _ := procEnumJobsW.Call(uintptr(h), 0, uintptr(maxJobsEnumerated), 1,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(needed),
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&returned)))
+	if ret == 0 || returned == 0 {
+		return "", ""
+	}
+	jobs := unsafe.Slice((*jobInfo1)(unsafe.Pointer(&buf[0])), returned)
+	pick := jobs[0]
+	for _, j := range jobs {
+		if j.Status&jobStatusPrinting != 0 {
+			pick = j
+			break
+		}
+	}
+	return windows.UTF16PtrToString(pick.Document), windows.UTF16PtrToString(pick.UserName)
+}
+
+// pipeSDDL denies network logons, then lets SYSTEM and interactive users
+// write. Depending on the port settings the spooler writes as SYSTEM or as
+// the printing user, who is logged on interactively.
+const pipeSDDL = "D:P(D;;GA;;;NU)(A;;GA;;;SY)(A;;GW;;;IU)"
+
+// createPipe makes the port's pipe.
+func createPipe() (windows.Handle, error) {
+	sd, err := windows.SecurityDescriptorFromString(pipeSDDL)
+	if err != nil {
+		return 0, fmt.Errorf("SecurityDescriptorFromString: %w", err)
+	}
+	sa := &windows.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+		SecurityDescriptor: sd,
+	}
+	name, _ := windows.UTF16PtrFromString(pipeName)
+	pipe, err := windows.CreateNamedPipe(name,
+		windows.PIPE_ACCESS_INBOUND,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_WAIT,
+		1, 0, 65536, 0, sa)
+	if err != nil {
+		return 0, fmt.Errorf("CreateNamedPipe: %w", err)
+	}
+	return pipe, nil
+}
+
+// capture serves the port: each connection of the spooler is one job.
+func (s *Spooler) capture() error {
+	pipe, err := createPipe()
+	if err != nil {
+		return err
+	}
+	go s.serve(pipe)
+	return nil
+}
+
+func (s *Spooler) serve(pipe windows.Handle) {
+	stop := s.stop
+	for n := 1; ; n++ {
+		err := windows.ConnectNamedPipe(pipe, nil)
+		select {
+		case <-stop:
+			windows.CloseHandle(pipe)
+			return
+		default:
+		}
+		if err != nil && err != windows.ERROR_PIPE_CONNECTED {
+			log.Printf("⚠️ Print port: ConnectNamedPipe: %v", err)
+			windows.CloseHandle(pipe)
+			time.Sleep(time.Second)
+			if pipe, err = createPipe(); err != nil {
+				log.Printf("❌ Print port closed: %v", err)
+				return
+			}
+			continue
+		}
+
+		title, user := currentJob()
+		path := filepath.Join(s.dir, fmt.Sprintf("win-%d.job", n))
+		ok := receive(pipe, path)
+		windows.DisconnectNamedPipe(pipe)
+		if ok {
+			s.add(path, title, user)
+		}
+	}
+}
+
+// receive copies one job to path. Data beyond maxJobBytes is drained
+// unwritten; add then drops the job.
+func receive(pipe windows.Handle, path string) bool {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Printf("❌ Print job not saved: %v", err)
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 64*1024)
+	var total int64
+	for {
+		var n uint32
+		err := windows.ReadFile(pipe, buf, &n, nil)
+		if n > 0 && total <= maxJobBytes {
+			if _, werr := f.Write(buf[:n]); werr != nil {
+				log.Printf("❌ Print job not saved: %v", werr)
+				return false
+			}
+		}
+		total += int64(n)
+		if err != nil {
+			return err == windows.ERROR_BROKEN_PIPE
+		}
+	}
+}
+
+// stopCapture wakes the pipe server so it sees the stop.
+func (s *Spooler) stopCapture() {
+	name, _ := windows.UTF16PtrFromString(pipeName)
+	h, err := windows.CreateFile(name, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err == nil {
+		windows.CloseHandle(h)
+	}
+}
@@ -0,0 +1,271 @@
+package registry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const regFileHeader = "Windows Registry Editor Version 5.00"
+
+// regKey is one [section] of a .reg file.
+type regKey struct {
+	Path    string // long hive form, e.g. HKEY_LOCAL_MACHINE\SOFTWARE\Vendor
+	Delete  bool   // [-path]: delete the key and its subkeys
+	Values  []rawValue
+	Removes []string // "name"=-
+}
+
+// formatRegFile writes keys in regedit's export format. Strings that
+// cannot be written literally fall back to hex(1), as regedit does.
+func formatRegFile(keys []regKey) string {
+	var b strings.Builder
+	b.WriteString(regFileHeader + "\r\n")
+	for _, k := range keys {
+		b.WriteString("\r\n[")
+		if k.Delete {
+			b.WriteString("-")
+		}
+		b.WriteString(k.Path + "]\r\n")
+		for _, name := range k.Removes {
+			b.WriteString(formatName(name) + "=-\r\n")
+		}
+		for _, v := range k.Values {
+			b.WriteString(formatValue(v))
+		}
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func formatName(name string) string {
+	if name == "" {
+		return "@"
+	}
+	return `"` + escapeRegString(name) + `"`
+}
+
+func escapeRegString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(v rawValue) string {
+	prefix := formatName(v.Name) + "="
+	switch v.Type {
+	case rawSZ:
+		s := utf16String(v.Data)
+		if len(v.Data)%2 == 0 && !strings.ContainsAny(s, "\x00\r\n") && string(utf16Bytes(s+"\x00")) == string(v.Data) {
+			return prefix + `"` + escapeRegString(s) + "\"\r\n"
+		}
+	case rawDWord:
+		if len(v.Data) == 4 {
+			return prefix + fmt.Sprintf("dword:%08x\r\n", binary.LittleEndian.Uint32(v.Data))
+		}
+	}
+	tag := "hex:"
+	if v.Type != rawBinary {
+		tag = fmt.Sprintf("hex(%x):", v.Type)
+	}
+	return wrapHex(prefix+tag, v.Data)
+}
+
+// wrapHex writes bytes as comma separated hex, continued with "\" at
+// about 80 columns like regedit.
+func wrapHex(prefix string, data []byte) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	col := len(prefix)
+	for i, c := range data {
+		item := fmt.Sprintf("%02x", c)
+		if i < len(data)-1 {
+			item += ","
+		}
+		if col+len(item) > 77 && i > 0 {
+			b.WriteString("\\\r\n  ")
+			col = 2
+		}
+		b.WriteString(item)
+		col += len(item)
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// parseRegFile reads a .reg file (version 5 or REGEDIT4, with or without a
+// BOM, already decoded to a Go string). Paths are normalized to the long
+// hive form.
+func parseRegFile(text string) ([]regKey, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Join "\" continuations of hex data
+	var logical []string
+	var lineNos []int
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		start := i
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) && isHexLine(line) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + strings.TrimSpace(lines[i])
+			line = strings.TrimRight(line, " \t\r")
+		}
+		logical = append(logical, line)
+		lineNos = append(lineNos, start+1)
+	}
+
+	var keys []regKey
+	headerSeen := false
+	for i, line := range logical {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		if !headerSeen {
+			if trimmed != regFileHeader && trimmed != "REGEDIT4" {
+				return nil, fmt.Errorf("not a .reg file (missing %q header)", regFileHeader)
+			}
+			headerSeen = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			if !strings.HasSuffix(trimmed, "]") {
+				return nil, fmt.Errorf("line %d: unterminated key", lineNos[i])
+			}
+			path := trimmed[1 : len(trimmed)-1]
+			k := regKey{}
+			if strings.HasPrefix(path, "-") {
+				k.Delete = true
+				path = path[1:]
+			}
+			hive, sub, err := splitPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNos[i], err)
+			}
+			k.Path = joinPath(hive, sub)
+			keys = append(keys, k)
+			continue
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("line %d: value outside of a [key] section", lineNos[i])
+		}
+		k := &keys[len(keys)-1]
+		if k.Delete {
+			return nil, fmt.Errorf("line %d: value under a deleted key", lineNos[i])
+		}
+		name, data, err := splitValueLine(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNos[i], err)
+		}
+		if data == "-" {
+			k.Removes = append(k.Removes, name)
+			continue
+		}
+		v, err := parseValueData(data)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNos[i], err)
+		}
+		v.Name = name
+		k.Values = append(k.Values, v)
+	}
+	if !headerSeen {
+		return nil, fmt.Errorf("empty .reg file")
+	}
+	return keys, nil
+}
+
+// isHexLine reports whether line is a hex value, the only kind that can
+// continue onto the next line.
+func isHexLine(line string) bool {
+	_, data, err := splitValueLine(strings.TrimSpace(line))
+	return err == nil && strings.HasPrefix(data, "hex")
+}
+
+// splitValueLine splits `"name"=data` or `@=data`.
+func splitValueLine(line string) (name, data string, err error) {
+	if strings.HasPrefix(line, "@=") {
+		return "", line[2:], nil
+	}
+	if !strings.HasPrefix(line, `"`) {
+		return "", "", fmt.Errorf("expected \"name\"=value")
+	}
+	name, rest, err := readQuoted(line)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.HasPrefix(rest, "=") {
+		return "", "", fmt.Errorf("expected = after value name")
+	}
+	return name, rest[1:], nil
+}
+
+// readQuoted reads a "..." string with \\ and \" escapes and returns the
+// rest of the line.
+func readQuoted(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+				continue
+			}
+		case '"':
+			return b.String(), s[i+1:], nil
+		}
+		b.WriteByte(s[i])
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+func parseValueData(data string) (rawValue, error) {
+	switch {
+	case strings.HasPrefix(data, `"`):
+		s, rest, err := readQuoted(data)
+		if err != nil {
+			return rawValue{}, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return rawValue{}, fmt.Errorf("unexpected text after string")
+		}
+		return rawValue{Type: rawSZ, Data: utf16Bytes(s + "\x00")}, nil
+	case strings.HasPrefix(data, "dword:"):
+		n, err := strconv.ParseUint(strings.TrimSpace(data[6:]), 16, 32)
+		if err != nil {
+			return rawValue{}, fmt.Errorf("bad dword %q", data[6:])
+		}
+		return rawValue{Type: rawDWord, Data: binary.LittleEndian.AppendUint32(nil, uint32(n))}, nil
+	case strings.HasPrefix(data, "hex"):
+		typ := uint32(rawBinary)
+		rest := data[3:]
+		if strings.HasPrefix(rest, "(") {
+			end := strings.Index(rest, ")")
+			if end < 0 {
+				return rawValue{}, fmt.Errorf("bad hex type")
+			}
+			n, err := strconv.ParseUint(rest[1:end], 16, 32)
+			if err != nil {
+				return rawValue{}, fmt.Errorf("bad hex type %q", rest[1:end])
+			}
+			typ, rest = uint32(n), rest[end+1:]
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return rawValue{}, fmt.Errorf("expected : after hex")
+		}
+		var b []byte
+		for _, part := range strings.Split(rest[1:], ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(part, 16, 8)
+			if err != nil {
+				return rawValue{}, fmt.Errorf("bad hex byte %q", part)
+			}
+			b = append(b, byte(n))
+		}
+		return rawValue{Type: typ, Data: b}, nil
+	}
+	return rawValue{}, fmt.Errorf("unsupported value %q", data)
+}
@@ -0,0 +1,333 @@
+// Package registry backs the process channel's reg_* ops: browsing and
+// editing the Windows registry, with .reg export and import. On macOS and
+// Linux it serves a read-only view of system configuration instead
+// (`defaults` domains and config files under /etc).
+package registry
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	// ErrReadOnly is returned for writes where the platform only has a
+	// read-only view.
+	ErrReadOnly = errors.New("configuration is read-only on this platform")
+	// ErrProtected is returned for keys the agent refuses to touch.
+	ErrProtected = errors.New("refusing to modify protected registry key")
+)
+
+// Value type names, matching protocol.Reg*.
+const (
+	TypeSZ       = "sz"
+	TypeExpandSZ = "expand_sz"
+	TypeMultiSZ  = "multi_sz"
+	TypeDWord    = "dword"
+	TypeQWord    = "qword"
+	TypeBinary   = "binary"
+	TypeText     = "text"
+)
+
+// MaxExportBytes bounds a .reg export so a mistaken export of a whole hive
+// does not stall the agent.
+const MaxExportBytes = 8 << 20
+
+// Value is one named value of a key. Name "" is the default value.
+type Value struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Raw value types (winnt.h), also the N in .reg hex(N): prefixes.
+const (
+	rawSZ       = 1
+	rawExpandSZ = 2
+	rawBinary   = 3
+	rawDWord    = 4
+	rawMultiSZ  = 7
+	rawQWord    = 11
+)
+
+// rawValue is a value as stored: type code and bytes.
+type rawValue struct {
+	Name string
+	Type uint32
+	Data []byte
+}
+
+var hiveNames = map[string]string{
+	"HKLM": "HKEY_LOCAL_MACHINE", "HKEY_LOCAL_MACHINE": "HKEY_LOCAL_MACHINE",
+	"HKCU": "HKEY_CURRENT_USER", "HKEY_CURRENT_USER": "HKEY_CURRENT_USER",
+	"HKCR": "HKEY_CLASSES_ROOT", "HKEY_CLASSES_ROOT": "HKEY_CLASSES_ROOT",
+	"HKU": "HKEY_USERS", "HKEY_USERS": "HKEY_USERS",
+	"HKCC": "HKEY_CURRENT_CONFIG", "HKEY_CURRENT_CONFIG": "HKEY_CURRENT_CONFIG",
+}
+
+// splitPath normalizes a regedit-style path to its long hive name and the
+// subkey below it: `hklm\Software\` → "HKEY_LOCAL_MACHINE", `Software`.
+func splitPath(path string) (hive, sub string, err error) {
+	p := strings.Trim(strings.ReplaceAll(path, "/", `\`), `\ `)
+	hive, sub, _ = strings.Cut(p, `\`)
+	long, ok := hiveNames[strings.ToUpper(hive)]
+	if !ok {
+		return "", "", fmt.Errorf("unknown registry hive %q (use HKLM, HKCU, HKCR, HKU or HKCC)", hive)
+	}
+	for strings.Contains(sub, `\\`) {
+		sub = strings.ReplaceAll(sub, `\\`, `\`)
+	}
+	return long, sub, nil
+}
+
+func joinPath(hive, sub string) string {
+	if sub == "" {
+		return hive
+	}
+	return hive + `\` + sub
+}
+
+var controlSetRe = regexp.MustCompile(`\\CONTROLSET\d{3}(\\|$)`)
+
+// protectedKeys are never written: credential stores, boot configuration,
+// security providers, services (use the service manager) and the
+// autostart/debugger hooks malware uses for persistence. Like
+// isProtectedPath for files, subkeys of a protected key are protected too.
+var protectedKeys = []string{
+	`HKEY_LOCAL_MACHINE\SAM`,
+	`HKEY_LOCAL_MACHINE\SECURITY`,
+	`HKEY_LOCAL_MACHINE\BCD00000000`,
+	`HKEY_LOCAL_MACHINE\SYSTEM\CURRENTCONTROLSET\CONTROL\LSA`,
+	`HKEY_LOCAL_MACHINE\SYSTEM\CURRENTCONTROLSET\CONTROL\SECURITYPROVIDERS`,
+	`HKEY_LOCAL_MACHINE\SYSTEM\CURRENTCONTROLSET\SERVICES`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\MICROSOFT\WINDOWS NT\CURRENTVERSION\WINLOGON`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\MICROSOFT\WINDOWS NT\CURRENTVERSION\IMAGE FILE EXECUTION OPTIONS`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\MICROSOFT\WINDOWS\CURRENTVERSION\RUN`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\MICROSOFT\WINDOWS\CURRENTVERSION\RUNONCE`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\WOW6432NODE\MICROSOFT\WINDOWS\CURRENTVERSION\RUN`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\WOW6432NODE\MICROSOFT\WINDOWS\CURRENTVERSION\RUNONCE`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\MICROSOFT\WINDOWS DEFENDER`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\POLICIES\MICROSOFT\WINDOWS DEFENDER`,
+	`HKEY_CURRENT_USER\SOFTWARE\MICROSOFT\WINDOWS\CURRENTVERSION\RUN`,
+	`HKEY_CURRENT_USER\SOFTWARE\MICROSOFT\WINDOWS\CURRENTVERSION\RUNONCE`,
+}
+
+// unreadableKeys hold password hashes and LSA secrets; the agent runs as
+// SYSTEM and could read them, so it refuses to.
+var unreadableKeys = []string{
+	`HKEY_LOCAL_MACHINE\SAM`,
+	`HKEY_LOCAL_MACHINE\SECURITY`,
+}
+
+// IsProtected reports whether path may not be written: hive roots, the keys
+// in protectedKeys and everything below them. HKU\<sid>\... is checked as
+// the matching HKCU path.
+func IsProtected(path string) bool {
+	p, ok := protectionPath(path)
+	return !ok || underAny(p, protectedKeys)
+}
+
+// IsDeleteProtected reports whether the key at path may not be deleted.
+// Beyond IsProtected, deleting a key takes its whole subtree with it, so a
+// key with a protected key anywhere below it is refused, as are the hives'
+// first-level children (HKLM\SOFTWARE, HKLM\SYSTEM, HKU\<sid>, ...).
+func IsDeleteProtected(path string) bool {
+	p, ok := protectionPath(path)
+	if !ok || underAny(p, protectedKeys) {
+		return true
+	}
+	_, sub, _ := splitPath(path)
+	if !strings.Contains(sub, `\`) {
+		return true
+	}
+	for _, k := range protectedKeys {
+		if strings.HasPrefix(k, p+`\`) {
+			return true
+		}
+	}
+	return false
+}
+
+// protectionPath normalizes path for matching against protectedKeys. ok is
+// false for unknown hives and hive roots.
+func protectionPath(path string) (p string, ok bool) {
+	hive, sub, err := splitPath(path)
+	if err != nil || sub == "" {
+		return "", false
+	}
+	p = strings.ToUpper(joinPath(hive, sub))
+	p = controlSetRe.ReplaceAllString(p, `\CURRENTCONTROLSET$1`)
+	if hive == "HKEY_USERS" {
+		if _, rest, ok := strings.Cut(strings.ToUpper(sub), `\`); ok {
+			p = `HKEY_CURRENT_USER\` + rest
+		}
+	}
+	return p, true
+}
+
+func isUnreadable(hive, sub string) bool {
+	return underAny(strings.ToUpper(joinPath(hive, sub)), unreadableKeys)
+}
+
+// lessFold orders names case-insensitively, as regedit lists them.
+func lessFold(a, b string) bool {
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+func underAny(p string, roots []string) bool {
+	for _, r := range roots {
+		if p == r || strings.HasPrefix(p, r+`\`) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeValue turns stored bytes into the JSON form sent to the controller.
+func decodeValue(r rawValue) Value {
+	v := Value{Name: r.Name}
+	switch r.Type {
+	case rawSZ, rawExpandSZ:
+		v.Type, v.Value = TypeSZ, utf16String(r.Data)
+		if r.Type == rawExpandSZ {
+			v.Type = TypeExpandSZ
+		}
+	case rawMultiSZ:
+		s := strings.TrimRight(utf16String(r.Data), "\x00")
+		list := []string{}
+		if s != "" {
+			list = strings.Split(s, "\x00")
+		}
+		v.Type, v.Value = TypeMultiSZ, list
+	case rawDWord:
+		if len(r.Data) == 4 {
+			v.Type, v.Value = TypeDWord, binary.LittleEndian.Uint32(r.Data)
+			return v
+		}
+		v.Type, v.Value = TypeBinary, hex.EncodeToString(r.Data)
+	case rawQWord:
+		if len(r.Data) == 8 {
+			v.Type, v.Value = TypeQWord, binary.LittleEndian.Uint64(r.Data)
+			return v
+		}
+		v.Type, v.Value = TypeBinary, hex.EncodeToString(r.Data)
+	default:
+		v.Type, v.Value = TypeBinary, hex.EncodeToString(r.Data)
+		if r.Type != rawBinary {
+			v.Type = fmt.Sprintf("hex(%x)", r.Type)
+		}
+	}
+	return v
+}
+
+// encodeValue converts a typed JSON value from the controller to the raw
+// type and bytes to store.
+func encodeValue(typ string, value interface{}) (uint32, []byte, error) {
+	switch typ {
+	case TypeSZ, TypeExpandSZ, "":
+		s, ok := value.(string)
+		if !ok {
+			return 0, nil, fmt.Errorf("%s value must be a string", typ)
+		}
+		if typ == TypeExpandSZ {
+			return rawExpandSZ, utf16Bytes(s + "\x00"), nil
+		}
+		return rawSZ, utf16Bytes(s + "\x00"), nil
+	case TypeMultiSZ:
+		var list []string
+		switch v := value.(type) {
+		case []string:
+			list = v
+		case []interface{}:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return 0, nil, fmt.Errorf("multi_sz value must be a list of strings")
+				}
+				list = append(list, s)
+			}
+		case string:
+			list = strings.Split(v, "\n")
+		default:
+			return 0, nil, fmt.Errorf("multi_sz value must be a list of strings")
+		}
+		var b strings.Builder
+		for _, s := range list {
+			if strings.Contains(s, "\x00") {
+				return 0, nil, fmt.Errorf("multi_sz entries cannot contain NUL")
+			}
+			b.WriteString(s + "\x00")
+		}
+		b.WriteString("\x00")
+		return rawMultiSZ, utf16Bytes(b.String()), nil
+	case TypeDWord, TypeQWord:
+		bits := 32
+		if typ == TypeQWord {
+			bits = 64
+		}
+		n, err := parseUint(value, bits)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s value: %w", typ, err)
+		}
+		if typ == TypeDWord {
+			return rawDWord, binary.LittleEndian.AppendUint32(nil, uint32(n)), nil
+		}
+		return rawQWord, binary.LittleEndian.AppendUint64(nil, n), nil
+	case TypeBinary:
+		s, ok := value.(string)
+		if !ok {
+			return 0, nil, fmt.Errorf("binary value must be a hex string")
+		}
+		b, err := hex.DecodeString(strings.NewReplacer(",", "", " ", "").Replace(s))
+		if err != nil {
+			return 0, nil, fmt.Errorf("binary value must be a hex string: %w", err)
+		}
+		return rawBinary, b, nil
+	}
+	return 0, nil, fmt.Errorf("unknown value type %q", typ)
+}
+
+// parseUint accepts a JSON number or a decimal / 0x-hex string, the latter
+// so qwords above 2^53 survive JSON.
+func parseUint(value interface{}, bits int) (uint64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 || v != float64(uint64(v)) || (bits == 32 && v > float64(^uint32(0))) {
+			return 0, fmt.Errorf("%v is not a %d-bit unsigned integer", v, bits)
+		}
+		return uint64(v), nil
+	case string:
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 0, bits)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a %d-bit unsigned integer", v, bits)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("expected a number")
+}
+
+func utf16Bytes(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// utf16String decodes UTF-16LE, dropping one trailing NUL terminator.
+func utf16String(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	if n := len(u); n > 0 && u[n-1] == 0 {
+		u = u[:n-1]
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,274 @@
+//go:build !windows
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// maxConfigFileBytes bounds a config file read from /etc.
+const maxConfigFileBytes = 256 << 10
+
+// Top-level entries of the read-only view.
+const (
+	defaultsRoot = "defaults"
+	etcRoot      = "/etc"
+)
+
+// unreadableFiles hold password hashes, sudo rules and host keys; the agent
+// runs as root and could read them, so it refuses to.
+var unreadableFiles = []string{
+	"/etc/shadow", "/etc/gshadow", "/etc/master.passwd", "/etc/sudoers",
+	"/etc/sudoers.d", "/etc/ssh", "/etc/krb5.keytab", "/etc/ssl/private",
+}
+
+// ReadOnly reports whether writes are unsupported on this platform.
+func ReadOnly() bool { return true }
+
+// List returns the entries under path: `defaults` domains and their keys on
+// macOS, or directories (as keys) and files (as values, without content)
+// under /etc. The root lists both.
+func List(path string) (string, []string, []Value, error) {
+	domain, file, err := splitConfigPath(path)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	switch {
+	case domain == "" && file == "":
+		roots := []string{etcRoot}
+		if runtime.GOOS == "darwin" {
+			roots = []string{defaultsRoot, etcRoot}
+		}
+		return "", roots, []Value{}, nil
+	case domain == defaultsRoot:
+		out, err := exec.Command("defaults", "domains").Output()
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("defaults domains: %w", err)
+		}
+		var domains []string
+		for _, d := range strings.Split(strings.TrimSpace(string(out)), ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				domains = append(domains, d)
+			}
+		}
+		domains = append(domains, "NSGlobalDomain")
+		sort.Slice(domains, func(i, j int) bool { return lessFold(domains[i], domains[j]) })
+		return defaultsRoot, domains, []Value{}, nil
+	case domain != "":
+		values, err := readDomain(domain)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return defaultsRoot + `\` + domain, []string{}, values, nil
+	}
+
+	entries, err := os.ReadDir(file)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	keys := []string{}
+	values := []Value{}
+	for _, e := range entries {
+		full := filepath.Join(file, e.Name())
+		if unreadableFile(full) {
+			continue
+		}
+		info, err := os.Stat(full) // follow symlinks
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			keys = append(keys, e.Name())
+		} else if info.Mode().IsRegular() {
+			values = append(values, Value{Name: e.Name(), Type: TypeText})
+		}
+	}
+	return file, keys, values, nil
+}
+
+// Get returns one `defaults` key or the content of a config file.
+func Get(path, name string) (string, Value, error) {
+	domain, file, err := splitConfigPath(path)
+	if err != nil {
+		return "", Value{}, err
+	}
+	if domain != "" && domain != defaultsRoot {
+		values, err := readDomain(domain)
+		if err != nil {
+			return "", Value{}, err
+		}
+		for _, v := range values {
+			if v.Name == name {
+				return defaultsRoot + `\` + domain, v, nil
+			}
+		}
+		return "", Value{}, fmt.Errorf("%s: key %q not found", domain, name)
+	}
+	if file == "" || name == "" || strings.ContainsAny(name, `/\`) {
+		return "", Value{}, fmt.Errorf("name a file under %s", etcRoot)
+	}
+	_, full, err := splitConfigPath(filepath.Join(file, name))
+	if err != nil {
+		return "", Value{}, err
+	}
+	text, err := readConfigFile(full)
+	if err != nil {
+		return "", Value{}, err
+	}
+	return file, Value{Name: name, Type: TypeText, Value: text}, nil
+}
+
+// Set is not supported; the view is read-only.
+func Set(path, name, typ string, value interface{}) error { return ErrReadOnly }
+
+// Delete is not supported; the view is read-only.
+func Delete(path, name string) error { return ErrReadOnly }
+
+// Import is not supported; the view is read-only.
+func Import(text string) (int, error) { return 0, ErrReadOnly }
+
+// Export returns a `defaults` domain as plist XML or a config file as text.
+func Export(path string) (string, error) {
+	domain, file, err := splitConfigPath(path)
+	if err != nil {
+		return "", err
+	}
+	if domain != "" && domain != defaultsRoot {
+		out, err := exec.Command("defaults", "export", domain, "-").Output()
+		if err != nil {
+			return "", fmt.Errorf("defaults export %s: %w", domain, err)
+		}
+		return string(out), nil
+	}
+	if file == "" {
+		return "", errors.New("export needs a defaults domain or a file under /etc")
+	}
+	return readConfigFile(file)
+}
+
+// splitConfigPath resolves path to either a `defaults` domain
+// ("defaults" itself for the domain list) or a file/directory under /etc.
+// Both empty means the root.
+func splitConfigPath(path string) (domain, file string, err error) {
+	p := strings.TrimSpace(path)
+	if p == "" || p == "/" || p == `\` {
+		return "", "", nil
+	}
+	if rest, ok := cutDefaults(p); ok {
+		if runtime.GOOS != "darwin" {
+			return "", "", errors.New("defaults domains are only available on macOS")
+		}
+		rest = strings.Trim(rest, `/\`)
+		if rest == "" {
+			return defaultsRoot, "", nil
+		}
+		if strings.ContainsAny(rest, `/\`) {
+			return "", "", fmt.Errorf("%q: defaults domains have no subkeys", path)
+		}
+		return rest, "", nil
+	}
+	clean := filepath.Clean(strings.ReplaceAll(p, `\`, "/"))
+	resolved, err := filepath.EvalSymlinks(clean)
+	if err != nil {
+		return "", "", err
+	}
+	if !underEtc(clean) || !underEtc(resolved) {
+		return "", "", fmt.Errorf("%q: only %s and defaults domains can be read on this platform", path, etcRoot)
+	}
+	if unreadableFile(clean) || unreadableFile(resolved) {
+		return "", "", fmt.Errorf("access to %s is not allowed", clean)
+	}
+	return "", clean, nil
+}
+
+func cutDefaults(p string) (string, bool) {
+	if len(p) < len(defaultsRoot) || !strings.EqualFold(p[:len(defaultsRoot)], defaultsRoot) {
+		return "", false
+	}
+	rest := p[len(defaultsRoot):]
+	if rest != "" && rest[0] != '/' && rest[0] != '\\' {
+		return "", false
+	}
+	return rest, true
+}
+
+// underEtc accepts /etc and, for macOS where /etc links to /private/etc,
+// /private/etc.
+func underEtc(p string) bool {
+	for _, root := range []string{etcRoot, "/private/etc"} {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func unreadableFile(p string) bool {
+	p = strings.TrimPrefix(p, "/private")
+	for _, f := range unreadableFiles {
+		if p == f || strings.HasPrefix(p, f+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfigFile reads a text config file. Binary plists are converted to
+// XML so they read like the rest.
+func readConfigFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxConfigFileBytes {
+		return "", fmt.Errorf("%s is larger than %d KB", path, maxConfigFileBytes>>10)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(data, []byte("bplist")) && runtime.GOOS == "darwin" {
+		if out, err := exec.Command("plutil", "-convert", "xml1", "-o", "-", path).Output(); err == nil {
+			return string(out), nil
+		}
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", fmt.Errorf("%s is a binary file", path)
+	}
+	return string(data), nil
+}
+
+// readDomain returns the top-level keys of a `defaults` domain. Values
+// plutil cannot express as JSON (dates, data) make it fall back to the
+// domain's plist XML as one value.
+func readDomain(domain string) ([]Value, error) {
+	plist, err := exec.Command("defaults", "export", domain, "-").Output()
+	if err != nil {
+		return nil, fmt.Errorf("defaults export %s: %w", domain, err)
+	}
+	conv := exec.Command("plutil", "-convert", "json", "-o", "-", "-")
+	conv.Stdin = bytes.NewReader(plist)
+	out, err := conv.Output()
+	var m map[string]interface{}
+	if err != nil || json.Unmarshal(out, &m) != nil {
+		return []Value{{Name: "", Type: TypeText, Value: string(plist)}}, nil
+	}
+	values := make([]Value, 0, len(m))
+	for k, v := range m {
+		values = append(values, Value{Name: k, Type: TypeText, Value: v})
+	}
+	sort.Slice(values, func(i, j int) bool { return lessFold(values[i].Name, values[j].Name) })
+	return values, nil
+}
@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsProtected(t *testing.T) {
+	cases := map[string]bool{
+		`HKLM`:                               true,
+		`HKLM\SAM\SAM\Domains`:               true,
+		`hklm\system\ControlSet001\Services`: true,
+		`HKLM\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`:      true,
+		`HKCU\Software\Microsoft\Windows\CurrentVersion\Run`:           true,
+		`HKU\S-1-5-21-1\Software\Microsoft\Windows\CurrentVersion\Run`: true,
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnceEx`:     false,
+		`HKLM\SOFTWARE\Vendor\App`:                                     false,
+		`HKCU/Software/Vendor`:                                         false,
+		`HKXX\Software`:                                                true,
+	}
+	for path, want := range cases {
+		if got := IsProtected(path); got != want {
+			t.Errorf("IsProtected(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestIsDeleteProtected(t *testing.T) {
+	cases := map[string]bool{
+		`HKLM`:                             true,
+		`HKLM\SOFTWARE`:                    true,
+		`HKLM\SYSTEM`:                      true,
+		`HKCU\Software`:                    true,
+		`HKU\S-1-5-21-1`:                   true,
+		`HKLM\SYSTEM\ControlSet001`:        true, // holds Services and Control\Lsa
+		`HKLM\SOFTWARE\Microsoft`:          true, // holds Winlogon and Run
+		`HKLM\SOFTWARE\Policies\Microsoft`: true,
+		`HKU\S-1-5-21-1\Software\Microsoft\Windows\CurrentVersion`: true,
+		`HKLM\SYSTEM\CurrentControlSet\Services\Tcpip`:             true,
+		`HKLM\SOFTWARE\Vendor\App`:                                 false,
+		`HKLM\SOFTWARE\Vendor`:                                     false,
+		`HKCU\Software\Vendor`:                                     false,
+		`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnceEx`: false,
+	}
+	for path, want := range cases {
+		if got := IsDeleteProtected(path); got != want {
+			t.Errorf("IsDeleteProtected(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	cases := []struct {
+		typ   string
+		in    interface{}
+		value interface{}
+	}{
+		{TypeSZ, "héllo", "héllo"},
+		{TypeExpandSZ, `%SystemRoot%\x`, `%SystemRoot%\x`},
+		{TypeMultiSZ, []interface{}{"a", "b"}, []string{"a", "b"}},
+		{TypeMultiSZ, []interface{}{}, []string{}},
+		{TypeDWord, float64(42), uint32(42)},
+		{TypeDWord, "0xffffffff", uint32(0xffffffff)},
+		{TypeQWord, "18446744073709551615", uint64(1<<64 - 1)},
+		{TypeBinary, "de ad,be ef", "deadbeef"},
+	}
+	for _, c := range cases {
+		raw, data, err := encodeValue(c.typ, c.in)
+		if err != nil {
+			t.Errorf("encodeValue(%s, %v): %v", c.typ, c.in, err)
+			continue
+		}
+		v := decodeValue(rawValue{Name: "n", Type: raw, Data: data})
+		if v.Type != c.typ || !reflect.DeepEqual(v.Value, c.value) {
+			t.Errorf("%s %v round trip = %s %#v, want %#v", c.typ, c.in, v.Type, v.Value, c.value)
+		}
+	}
+
+	for _, bad := range []struct {
+		typ string
+		in  interface{}
+	}{
+		{TypeDWord, float64(1 << 32)},
+		{TypeDWord, float64(-1)},
+		{TypeSZ, float64(1)},
+		{TypeBinary, "xyz"},
+		{"link", "x"},
+	} {
+		if _, _, err := encodeValue(bad.typ, bad.in); err == nil {
+			t.Errorf("encodeValue(%s, %v) accepted", bad.typ, bad.in)
+		}
+	}
+}
+
+func TestRegFileRoundTrip(t *testing.T) {
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	keys := []regKey{
+		{Path: `HKEY_CURRENT_USER\Software\Vendor`, Values: []rawValue{
+			{Name: "", Type: rawSZ, Data: utf16Bytes("default\x00")},
+			{Name: `Quote "and" \slash`, Type: rawSZ, Data: utf16Bytes(`C:\x "y"` + "\x00")},
+			{Name: "Multi", Type: rawSZ, Data: utf16Bytes("line1\r\nline2\x00")},
+			{Name: "Count", Type: rawDWord, Data: []byte{1, 2, 0, 0}},
+			{Name: "Big", Type: rawQWord, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			{Name: "Blob", Type: rawBinary, Data: long},
+			{Name: "Empty", Type: rawBinary, Data: nil},
+		}},
+		{Path: `HKEY_CURRENT_USER\Software\Vendor\Old`, Delete: true},
+		{Path: `HKEY_CURRENT_USER\Software\Vendor\Sub`, Removes: []string{"Gone"}},
+	}
+	text := formatRegFile(keys)
+	if !strings.HasPrefix(text, regFileHeader+"\r\n") || !strings.Contains(text, `"Count"=dword:00000201`) {
+		t.Fatalf("unexpected export:\n%s", text)
+	}
+	if !strings.Contains(text, `"Multi"=hex(1):`) {
+		t.Errorf("string with newlines not written as hex(1):\n%s", text)
+	}
+	for _, line := range strings.Split(text, "\r\n") {
+		if len(line) > 80 {
+			t.Errorf("line not wrapped (%d chars): %s", len(line), line)
+		}
+	}
+
+	got, err := parseRegFile("\ufeff" + text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(keys) {
+		t.Fatalf("parsed %d keys, want %d", len(got), len(keys))
+	}
+	for i := range keys {
+		if got[i].Path != keys[i].Path || got[i].Delete != keys[i].Delete || !reflect.DeepEqual(got[i].Removes, keys[i].Removes) {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], keys[i])
+		}
+		if len(got[i].Values) != len(keys[i].Values) {
+			t.Errorf("key %d has %d values, want %d", i, len(got[i].Values), len(keys[i].Values))
+			continue
+		}
+		for j, v := range keys[i].Values {
+			g := got[i].Values[j]
+			if g.Name != v.Name || g.Type != v.Type || string(g.Data) != string(v.Data) {
+				t.Errorf("value %q = %+v, want %+v", v.Name, g, v)
+			}
+		}
+	}
+}
+
+func TestParseRegFileErrors(t *testing.T) {
+	cases := map[string]string{
+		"no header":     "[HKEY_CURRENT_USER\\X]\r\n",
+		"bad hive":      regFileHeader + "\r\n[HKEY_NOPE\\X]\r\n",
+		"value first":   regFileHeader + "\r\n\"a\"=\"b\"\r\n",
+		"bad dword":     regFileHeader + "\r\n[HKCU\\X]\r\n\"a\"=dword:zz\r\n",
+		"unterminated":  regFileHeader + "\r\n[HKCU\\X]\r\n\"a=\"b\"\r\n",
+		"deleted value": regFileHeader + "\r\n[-HKCU\\X]\r\n\"a\"=\"b\"\r\n",
+	}
+	for name, text := range cases {
+		if _, err := parseRegFile(text); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+
+	keys, err := parseRegFile("REGEDIT4\n\n; comment\n[hkcu\\Software\\X]\n@=\"v\"\n")
+	if err != nil || len(keys) != 1 || keys[0].Path != `HKEY_CURRENT_USER\Software\X` {
+		t.Errorf("REGEDIT4 file = %+v, %v", keys, err)
+	}
+}
@@ -0,0 +1,319 @@
+//go:build windows
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var procRegSetValueExW = windows.NewLazySystemDLL("advapi32.dll").NewProc("RegSetValueExW")
+
+var hiveKeys = map[string]registry.Key{
+	"HKEY_LOCAL_MACHINE":  registry.LOCAL_MACHINE,
+	"HKEY_CURRENT_USER":   registry.CURRENT_USER,
+	"HKEY_CLASSES_ROOT":   registry.CLASSES_ROOT,
+	"HKEY_USERS":          registry.USERS,
+	"HKEY_CURRENT_CONFIG": registry.CURRENT_CONFIG,
+}
+
+// ReadOnly reports whether writes are unsupported on this platform.
+func ReadOnly() bool { return false }
+
+// open opens path for reading, refusing credential stores.
+func open(path string, access uint32) (registry.Key, string, error) {
+	hive, sub, err := splitPath(path)
+	if err != nil {
+		return 0, "", err
+	}
+	if isUnreadable(hive, sub) {
+		return 0, "", fmt.Errorf("access to %s is not allowed", joinPath(hive, sub))
+	}
+	k, err := registry.OpenKey(hiveKeys[hive], sub, access|registry.WOW64_64KEY)
+	if err != nil {
+		return 0, "", keyError(joinPath(hive, sub), err)
+	}
+	return k, joinPath(hive, sub), nil
+}
+
+var errKeyNotFound = errors.New("key not found")
+
+func keyError(path string, err error) error {
+	if errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("%s: %w", path, errKeyNotFound)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// List returns the subkeys and values of path. The canonical path (long
+// hive name) is returned alongside. An empty path lists the hives.
+func List(path string) (string, []string, []Value, error) {
+	if strings.Trim(path, `\/ `) == "" {
+		hives := make([]string, 0, len(hiveKeys))
+		for h := range hiveKeys {
+			hives = append(hives, h)
+		}
+		sort.Strings(hives)
+		return "", hives, []Value{}, nil
+	}
+	k, canonical, err := open(path, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	defer k.Close()
+
+	keys, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return "", nil, nil, keyError(canonical, err)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessFold(keys[i], keys[j]) })
+	raws, err := readValues(k)
+	if err != nil {
+		return "", nil, nil, keyError(canonical, err)
+	}
+	values := make([]Value, len(raws))
+	for i, r := range raws {
+		values[i] = decodeValue(r)
+	}
+	return canonical, keys, values, nil
+}
+
+// Get returns one value of path.
+func Get(path, name string) (string, Value, error) {
+	k, canonical, err := open(path, registry.QUERY_VALUE)
+	if err != nil {
+		return "", Value{}, err
+	}
+	defer k.Close()
+	r, err := readValue(k, name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", Value{}, fmt.Errorf("%s: value %q not found", canonical, name)
+		}
+		return "", Value{}, keyError(canonical, err)
+	}
+	return canonical, decodeValue(r), nil
+}
+
+// Set writes a typed value, creating the key if needed.
+func Set(path, name, typ string, value interface{}) error {
+	if IsProtected(path) {
+		return ErrProtected
+	}
+	rawType, data, err := encodeValue(typ, value)
+	if err != nil {
+		return err
+	}
+	return setRaw(path, rawValue{Name: name, Type: rawType, Data: data})
+}
+
+func setRaw(path string, v rawValue) error {
+	hive, sub, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(hiveKeys[hive], sub, registry.SET_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return keyError(joinPath(hive, sub), err)
+	}
+	defer k.Close()
+	pname, err := syscall.UTF16PtrFromString(v.Name)
+	if err != nil {
+		return err
+	}
+	var pdata *byte
+	if len(v.Data) > 0 {
+		pdata = &v.Data[0]
+	}
+	r, _, _ := procRegSetValueExW.Call(uintptr(k), uintptr(unsafe.Pointer(pname)), 0,
+		uintptr(v.Type), uintptr(unsafe.Pointer(pdata)), uintptr(len(v.Data)))
+	if r != 0 {
+		return fmt.Errorf("%s: set %q: %w", joinPath(hive, sub), v.Name, syscall.Errno(r))
+	}
+	return nil
+}
+
+// Delete removes the value name of path, or the key and all its subkeys
+// when name is empty.
+func Delete(path, name string) error {
+	if IsProtected(path) || name == "" && IsDeleteProtected(path) {
+		return ErrProtected
+	}
+	hive, sub, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		k, err := registry.OpenKey(hiveKeys[hive], sub, registry.SET_VALUE|registry.WOW64_64KEY)
+		if err != nil {
+			return keyError(joinPath(hive, sub), err)
+		}
+		defer k.Close()
+		if err := k.DeleteValue(name); err != nil {
+			if errors.Is(err, registry.ErrNotExist) {
+				return fmt.Errorf("%s: value %q not found", joinPath(hive, sub), name)
+			}
+			return keyError(joinPath(hive, sub), err)
+		}
+		return nil
+	}
+	return deleteTree(hiveKeys[hive], sub)
+}
+
+func deleteTree(root registry.Key, sub string) error {
+	k, err := registry.OpenKey(root, sub, registry.ENUMERATE_SUB_KEYS|registry.WOW64_64KEY)
+	if err != nil {
+		return keyError(sub, err)
+	}
+	children, err := k.ReadSubKeyNames(-1)
+	k.Close()
+	if err != nil {
+		return keyError(sub, err)
+	}
+	for _, c := range children {
+		if err := deleteTree(root, sub+`\`+c); err != nil {
+			return err
+		}
+	}
+	if err := registry.DeleteKey(root, sub); err != nil {
+		return keyError(sub, err)
+	}
+	return nil
+}
+
+// Export returns path and its subkeys as .reg text.
+func Export(path string) (string, error) {
+	k, canonical, err := open(path, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	k.Close()
+	var keys []regKey
+	size := 0
+	if err := exportTree(canonical, &keys, &size); err != nil {
+		return "", err
+	}
+	return formatRegFile(keys), nil
+}
+
+func exportTree(path string, keys *[]regKey, size *int) error {
+	k, canonical, err := open(path, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	values, err := readValues(k)
+	if err != nil {
+		return keyError(canonical, err)
+	}
+	for _, v := range values {
+		*size += len(v.Name) + 3*len(v.Data)
+	}
+	if *size > MaxExportBytes {
+		return fmt.Errorf("export is larger than %d MB; export a subkey instead", MaxExportBytes>>20)
+	}
+	*keys = append(*keys, regKey{Path: canonical, Values: values})
+
+	children, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return keyError(canonical, err)
+	}
+	sort.Slice(children, func(i, j int) bool { return lessFold(children[i], children[j]) })
+	hive, sub, _ := splitPath(canonical)
+	for _, c := range children {
+		if isUnreadable(hive, sub+`\`+c) {
+			continue
+		}
+		if err := exportTree(canonical+`\`+c, keys, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import applies .reg text. Every key is checked against the protected
+// list before anything is written, so a file touching one protected key
+// changes nothing. Returns the number of keys and values written.
+func Import(text string) (int, error) {
+	keys, err := parseRegFile(text)
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range keys {
+		if IsProtected(k.Path) || k.Delete && IsDeleteProtected(k.Path) {
+			return 0, fmt.Errorf("%w: %s", ErrProtected, k.Path)
+		}
+	}
+	count := 0
+	for _, k := range keys {
+		hive, sub, _ := splitPath(k.Path)
+		if k.Delete {
+			if err := deleteTree(hiveKeys[hive], sub); err != nil && !errors.Is(err, errKeyNotFound) {
+				return count, err
+			}
+			count++
+			continue
+		}
+		nk, _, err := registry.CreateKey(hiveKeys[hive], sub, registry.SET_VALUE|registry.WOW64_64KEY)
+		if err != nil {
+			return count, keyError(k.Path, err)
+		}
+		for _, name := range k.Removes {
+			if err := nk.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+				nk.Close()
+				return count, keyError(k.Path, err)
+			}
+			count++
+		}
+		nk.Close()
+		for _, v := range k.Values {
+			if err := setRaw(k.Path, v); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(k.Removes) == 0 && len(k.Values) == 0 {
+			count++ // created an empty key
+		}
+	}
+	return count, nil
+}
+
+func readValues(k registry.Key) ([]rawValue, error) {
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(names, func(i, j int) bool { return lessFold(names[i], names[j]) })
+	values := make([]rawValue, 0, len(names))
+	for _, name := range names {
+		v, err := readValue(k, name)
+		if err != nil {
+			continue // deleted meanwhile
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func readValue(k registry.Key, name string) (rawValue, error) {
+	n, typ, err := k.GetValue(name, nil)
+	if err != nil {
+		return rawValue{}, err
+	}
+	buf := make([]byte, n)
+	if n > 0 {
+		n, typ, err = k.GetValue(name, buf)
+		if err != nil {
+			return rawValue{}, err
+		}
+	}
+	return rawValue{Name: name, Type: typ, Data: buf[:n]}, nil
+}
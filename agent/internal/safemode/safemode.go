@@ -0,0 +1,66 @@
+// Package safemode restarts Windows into Safe Mode with Networking with the
+// agent service registered to start there, so the device comes back online
+// for malware cleanup and driver fixes. The boot flag is cleared as soon as
+// the agent starts in Safe Mode, and the registration is removed on the
+// next normal boot (see Reconcile).
+package safemode
+
+import "errors"
+
+// ErrUnsupported is returned where there is no Safe Mode to restart into.
+var ErrUnsupported = errors.New("safe mode restart is only supported on Windows")
+
+// marker is written when Safe Mode is armed and read by Reconcile.
+type marker struct {
+	Service  string `json:"service"`
+	ArmedAt  int64  `json:"armed_at"`
+	BootTime int64  `json:"boot_time"`         // Unix seconds of the boot that armed it
+	Cleared  bool   `json:"cleared,omitempty"` // boot flag cleared in Safe Mode
+}
+
+// step is one change Restart makes. undo reverts it and may be nil.
+type step struct {
+	do   func() error
+	undo func()
+}
+
+// runSteps runs steps in order. When one fails, the steps already done are
+// undone in reverse order and its error is returned.
+func runSteps(steps []step) error {
+	for i, s := range steps {
+		if err := s.do(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if steps[j].undo != nil {
+					steps[j].undo()
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileAction is what Reconcile does with a marker.
+type reconcileAction int
+
+const (
+	reconcileNone      reconcileAction = iota // nothing to do yet
+	reconcileClearFlag                        // in Safe Mode: clear the boot flag
+	reconcileRevert                           // back in normal mode: remove everything
+)
+
+// reconcileFor decides what Reconcile does with mk, given whether Windows
+// runs in Safe Mode and the Unix time of the current boot.
+func reconcileFor(mk marker, safeMode bool, bootTime int64) reconcileAction {
+	if safeMode {
+		if mk.Cleared {
+			return reconcileNone
+		}
+		return reconcileClearFlag
+	}
+	// Still the boot that armed it: the service restarted before Windows did
+	if d := bootTime - mk.BootTime; d > -120 && d < 120 {
+		return reconcileNone
+	}
+	return reconcileRevert
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package safemode
+
+import "time"
+
+// Supported reports whether Restart works on this platform.
+func Supported() bool { return false }
+
+// Active reports whether the system booted into Safe Mode.
+func Active() bool { return false }
+
+// Restart is not supported outside Windows.
+func Restart(delay time.Duration) error { return ErrUnsupported }
+
+// Reconcile has nothing to revert outside Windows.
+func Reconcile() {}
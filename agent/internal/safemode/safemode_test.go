@@ -0,0 +1,65 @@
+package safemode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRunStepsRollsBack(t *testing.T) {
+	var log []string
+	rec := func(name string, err error) step {
+		return step{
+			do:   func() error { log = append(log, "do "+name); return err },
+			undo: func() { log = append(log, "undo "+name) },
+		}
+	}
+	boom := errors.New("boom")
+
+	err := runSteps([]step{rec("register", nil), rec("marker", nil), {do: func() error { return nil }}, rec("bcdedit", boom), rec("shutdown", nil)})
+	if err != boom {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	want := []string{"do register", "do marker", "do bcdedit", "undo marker", "undo register"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("steps = %q, want %q", log, want)
+	}
+
+	log = nil
+	if err := runSteps([]step{rec("register", nil), rec("marker", nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"do register", "do marker"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("steps = %q, want %q", log, want)
+	}
+
+	log = nil
+	if err := runSteps([]step{rec("register", boom), rec("marker", nil)}); err != boom {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if want := []string{"do register"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("first step failing: steps = %q, want %q", log, want)
+	}
+}
+
+func TestReconcileFor(t *testing.T) {
+	const armed = 1_800_000_000
+	for _, tc := range []struct {
+		name     string
+		mk       marker
+		safeMode bool
+		boot     int64
+		want     reconcileAction
+	}{
+		{"safe mode, flag set", marker{BootTime: armed}, true, armed + 600, reconcileClearFlag},
+		{"safe mode, flag cleared", marker{BootTime: armed, Cleared: true}, true, armed + 600, reconcileNone},
+		{"same boot", marker{BootTime: armed}, false, armed, reconcileNone},
+		{"same boot, clock skew", marker{BootTime: armed}, false, armed - 119, reconcileNone},
+		{"next normal boot", marker{BootTime: armed, Cleared: true}, false, armed + 1200, reconcileRevert},
+		{"normal boot, never reached Safe Mode", marker{BootTime: armed}, false, armed + 120, reconcileRevert},
+	} {
+		if got := reconcileFor(tc.mk, tc.safeMode, tc.boot); got != tc.want {
+			t.Errorf("%s: reconcileFor = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+//go:build windows
+
+package safemode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	// serviceName is the agent's Windows service (remote-agent -install).
+	serviceName = "RemoteDesktopAgent"
+
+	// safeBootKey lists the services Safe Mode with Networking starts.
+	safeBootKey = `SYSTEM\CurrentControlSet\Control\SafeBoot\Network\`
+)
+
+// Supported reports whether Restart works on this platform.
+func Supported() bool { return true }
+
+// Active reports whether Windows booted into Safe Mode. The Option key
+// only exists while it is.
+func Active() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\SafeBoot\Option`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("OptionValue")
+	return err == nil && v != 0
+}
+
+// Restart registers the agent service to start in Safe Mode with
+// Networking, sets the boot flag and restarts Windows after delay.
+// Everything is undone when a step fails, so a failed attempt never leaves
+// the device booting into Safe Mode without the agent.
+func Restart(delay time.Duration) error {
+	service := serviceName
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("service manager: %w", err)
+	}
+	s, err := m.OpenService(service)
+	m.Disconnect()
+	if err != nil {
+		return fmt.Errorf("agent is not installed as service %s, so it would not start in Safe Mode: %w", service, err)
+	}
+	s.Close()
+
+	if err := runSteps([]step{
+		{
+			do: func() error {
+				k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, safeBootKey+service, registry.SET_VALUE)
+				if err != nil {
+					return fmt.Errorf("register service for Safe Mode: %w", err)
+				}
+				err = k.SetStringValue("", "Service")
+				k.Close()
+				if err != nil {
+					unregister(service)
+					return fmt.Errorf("register service for Safe Mode: %w", err)
+				}
+				return nil
+			},
+			undo: func() { unregister(service) },
+		},
+		{
+			do: func() error {
+				if err := writeMarker(marker{Service: service, ArmedAt: time.Now().Unix(), BootTime: bootTime().Unix()}); err != nil {
+					return fmt.Errorf("write marker: %w", err)
+				}
+				return nil
+			},
+			undo: func() { os.Remove(markerPath()) },
+		},
+		{
+			do:   func() error { return bcdedit("/set", "{current}", "safeboot", "network") },
+			undo: func() { _ = bcdedit("/deletevalue", "{current}", "safeboot") },
+		},
+		{
+			do: func() error {
+				cmd := exec.Command("shutdown", "/r", "/t", fmt.Sprint(int(delay.Seconds())), "/c", "Remote Desktop: Genstart i fejlsikret tilstand med netværk")
+				cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+				if out, err := cmd.CombinedOutput(); err != nil {
+					return fmt.Errorf("shutdown: %v: %s", err, strings.TrimSpace(string(out)))
+				}
+				return nil
+			},
+		},
+	}); err != nil {
+		return err
+	}
+	log.Printf("🛟 Restarting into Safe Mode with Networking in %s", delay)
+	return nil
+}
+
+// Reconcile runs when the agent service starts. In Safe Mode it clears the
+// boot flag, so the next restart is a normal one. After the next normal
+// boot it removes the Safe Mode registration.
+func Reconcile() {
+	data, err := os.ReadFile(markerPath())
+	if err != nil {
+		return
+	}
+	var mk marker
+	if err := json.Unmarshal(data, &mk); err != nil || mk.Service == "" {
+		log.Printf("⚠️ Safe Mode marker unreadable, removing it: %v", err)
+		os.Remove(markerPath())
+		return
+	}
+
+	switch reconcileFor(mk, Active(), bootTime().Unix()) {
+	case reconcileNone:
+		return
+	case reconcileClearFlag:
+		if err := bcdedit("/deletevalue", "{current}", "safeboot"); err != nil {
+			log.Printf("⚠️ Safe Mode: could not clear boot flag: %v", err)
+			return
+		}
+		mk.Cleared = true
+		if err := writeMarker(mk); err != nil {
+			log.Printf("⚠️ Safe Mode marker: %v", err)
+		}
+		log.Println("🛟 Running in Safe Mode with Networking — next restart returns to normal mode")
+		return
+	}
+
+	// Normally already cleared in Safe Mode; cleared here too in case
+	// Windows never got there
+	_ = bcdedit("/deletevalue", "{current}", "safeboot")
+	unregister(mk.Service)
+	os.Remove(markerPath())
+	log.Println("🛟 Back in normal mode — Safe Mode registration removed")
+}
+
+func unregister(service string) {
+	if err := registry.DeleteKey(registry.LOCAL_MACHINE, safeBootKey+service); err != nil && err != registry.ErrNotExist {
+		log.Printf("⚠️ Safe Mode: could not remove service registration: %v", err)
+	}
+}
+
+func bcdedit(args ...string) error {
+	cmd := exec.Command(filepath.Join(os.Getenv("SystemRoot"), "System32", "bcdedit.exe"), args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("bcdedit %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func bootTime() time.Time {
+	return time.Now().Add(-windows.DurationSinceBoot()).Truncate(time.Second)
+}
+
+func markerPath() string {
+	return filepath.Join(os.Getenv("ProgramData"), "RemoteDesktopAgent", "safemode.json")
+}
+
+func writeMarker(mk marker) error {
+	data, err := json.Marshal(mk)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(markerPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(markerPath(), data, 0644)
+}
@@ -149,9 +149,11 @@ import (
 	"image"
 	"log"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/nfnt/resize"
+	"github.com/stangtennis/remote-agent/internal/input"
 )
 
 type Capturer struct {
@@ -312,6 +314,13 @@ func (c *Capturer) Reinitialize(forceGDI bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.displayIndex == AllDisplays {
+		c.bounds = VirtualDesktop()
+		c.lastHash = nil
+		log.Printf("Reinitialized Quartz capturer (all displays): %dx%d", c.bounds.Dx(), c.bounds.Dy())
+		return nil
+	}
+
 	var x, y, w, h C.int
 	C.getDisplayBounds(C.int(c.displayIndex), &x, &y, &w, &h)
 
@@ -333,8 +342,20 @@ func (c *Capturer) SwitchDisplay(displayIndex int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if displayIndex == AllDisplays {
+		virtual := VirtualDesktop()
+		if virtual.Empty() {
+			return fmt.Errorf("failed to span displays: no displays found")
+		}
+		c.displayIndex = AllDisplays
+		c.bounds = virtual
+		c.lastHash = nil
+		log.Printf("Spanning all displays: %dx%d (origin %d,%d)", virtual.Dx(), virtual.Dy(), virtual.Min.X, virtual.Min.Y)
+		return nil
+	}
+
 	count := int(C.getDisplayCount())
-	if displayIndex >= count {
+	if displayIndex < 0 || displayIndex >= count {
 		return fmt.Errorf("display %d not found (only %d displays)", displayIndex, count)
 	}
 
@@ -371,6 +392,15 @@ func (c *Capturer) CaptureJPEGScaled(quality int, scale float64) ([]byte, int, i
 		return data, c.bounds.Dx(), c.bounds.Dy(), nil
 	}
 
+	// Spanning frames are composited in Go; scale the result the same way
+	if c.displayIndex == AllDisplays {
+		img, err := c.captureSpanInternal()
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to capture screen: %w", err)
+		}
+		return c.EncodeRGBAToJPEG(img, quality, scale)
+	}
+
 	// Get native display resolution for target calculation
 	var origW, origH C.int
 	var dummyBPR C.int
@@ -456,7 +486,21 @@ func (c *Capturer) ForwardScroll(delta, x, y int) error { return fmt.Errorf("not
 func (c *Capturer) ForwardKeyEvent(code string, down bool, ctrl, shift, alt, meta bool) error {
 	return fmt.Errorf("not supported on macOS")
 }
+func (c *Capturer) ForwardLayoutKey(ev input.KeyEvent) error {
+	return fmt.Errorf("not supported on macOS")
+}
 func (c *Capturer) ForwardUnicodeChar(char rune) error { return fmt.Errorf("not supported on macOS") }
+func (c *Capturer) ForwardWheel(dx, dy int) error      { return fmt.Errorf("not supported on macOS") }
+func (c *Capturer) ForwardTouch(points []input.TouchPoint) error {
+	return fmt.Errorf("not supported on macOS")
+}
+func (c *Capturer) ForwardPen(p input.PenPoint) error { return fmt.Errorf("not supported on macOS") }
+func (c *Capturer) ForwardLocalLock(enabled, blank bool, message string, until time.Time) (bool, error) {
+	return false, fmt.Errorf("not supported on macOS")
+}
+func (c *Capturer) ForwardLocalLockState() (bool, string, error) {
+	return false, "", fmt.Errorf("not supported on macOS")
+}
 
 func (c *Capturer) CaptureRGBA() (*image.RGBA, error) {
 	c.mu.Lock()
@@ -466,6 +510,10 @@ func (c *Capturer) CaptureRGBA() (*image.RGBA, error) {
 
 // captureRGBAInternal captures without locking (caller must hold lock)
 func (c *Capturer) captureRGBAInternal() (*image.RGBA, error) {
+	if c.displayIndex == AllDisplays {
+		return c.captureSpanInternal()
+	}
+
 	var width, height, bytesPerRow C.int
 	pixels := C.captureDisplay(C.int(c.displayIndex), &width, &height, &bytesPerRow)
 	if pixels == nil {
@@ -473,10 +521,49 @@ func (c *Capturer) captureRGBAInternal() (*image.RGBA, error) {
 	}
 	defer C.free(unsafe.Pointer(pixels))
 
-	w := int(width)
-	h := int(height)
-	bpr := int(bytesPerRow)
+	img := rgbaFromPixels(pixels, int(width), int(height), int(bytesPerRow))
+
+	// Update bounds
+	c.bounds = img.Bounds()
+
+	return img, nil
+}
+
+// captureSpanInternal composites every display into one frame of the
+// virtual desktop. Displays are captured at their size in points, not
+// Retina pixels, so mixed-DPI setups line up and mouse coordinates match
+// CGDisplayBounds (caller must hold lock).
+func (c *Capturer) captureSpanInternal() (*image.RGBA, error) {
+	monitors := EnumerateDisplays()
+	displays := displayRects(monitors)
+	virtual := VirtualBounds(displays)
+	if virtual.Empty() {
+		return nil, fmt.Errorf("no active displays found")
+	}
+
+	frames := make([]*image.RGBA, len(monitors))
+	captured := 0
+	for i, mon := range monitors {
+		var w, h, bpr C.int
+		pixels := C.captureDisplayScaled(C.int(mon.Index), C.int(mon.Width), C.int(mon.Height), &w, &h, &bpr)
+		if pixels == nil {
+			continue
+		}
+		frames[i] = rgbaFromPixels(pixels, int(w), int(h), int(bpr))
+		C.free(unsafe.Pointer(pixels))
+		captured++
+	}
+	if captured == 0 {
+		return nil, fmt.Errorf("CGDisplayCreateImage failed for all %d displays", len(monitors))
+	}
+
+	img := composeSpan(virtual, displays, frames)
+	c.bounds = virtual
+	return img, nil
+}
 
+// rgbaFromPixels copies a C RGBA buffer into a Go image.
+func rgbaFromPixels(pixels *C.uchar, w, h, bpr int) *image.RGBA {
 	// Zero-copy: create Go slice backed by C memory, copy directly into image
 	img := image.NewRGBA(image.Rect(0, 0, w, h))
 	src := unsafe.Slice((*byte)(unsafe.Pointer(pixels)), bpr*h)
@@ -490,9 +577,5 @@ func (c *Capturer) captureRGBAInternal() (*image.RGBA, error) {
 			copy(img.Pix[dstOff:dstOff+w*4], src[srcOff:srcOff+w*4])
 		}
 	}
-
-	// Update bounds
-	c.bounds = image.Rect(0, 0, w, h)
-
-	return img, nil
+	return img
 }
@@ -9,9 +9,11 @@ import (
 	"image"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/kbinani/screenshot"
 	"github.com/nfnt/resize"
+	"github.com/stangtennis/remote-agent/internal/input"
 )
 
 type Capturer struct {
@@ -21,6 +23,7 @@ type Capturer struct {
 	dxgiCapturer     *DXGICapturer         // DXGI capturer if available (works better with RDP)
 	gdiCapturer      *GDICapturer          // GDI capturer for Session 0 / login screen
 	session0Capturer *Session0PipeCapturer // Pipe-based capturer for Session 0 (helper in user session)
+	span             *spanCapturer         // Composited DXGI outputs in AllDisplays mode
 	useGDI           bool                  // Force GDI mode (for Session 0)
 	mu               sync.Mutex            // Protect capturer switching
 }
@@ -146,7 +149,7 @@ func (c *Capturer) CaptureJPEG(quality int) ([]byte, error) {
 	}
 
 	// Fallback to screenshot library
-	img, err := screenshot.CaptureRect(c.bounds)
+	img, err := c.captureFallbackRGBA()
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture screen: %w", err)
 	}
@@ -180,7 +183,7 @@ func (c *Capturer) CaptureJPEGIfChanged(quality int) ([]byte, error) {
 	}
 
 	// Fallback to screenshot library with change detection
-	img, err := screenshot.CaptureRect(c.bounds)
+	img, err := c.captureFallbackRGBA()
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture screen: %w", err)
 	}
@@ -242,6 +245,7 @@ func (c *Capturer) Close() error {
 		c.gdiCapturer.Close()
 		c.gdiCapturer = nil
 	}
+	c.closeSpan()
 	return nil
 }
 
@@ -266,6 +270,7 @@ func (c *Capturer) Reinitialize(forceGDI bool) error {
 		c.gdiCapturer.Close()
 		c.gdiCapturer = nil
 	}
+	c.closeSpan()
 
 	// If in GDI/Session0 mode, try Session 0 pipe capturer first (user may have logged in)
 	if wasSession0 || forceGDI || c.useGDI {
@@ -292,6 +297,14 @@ func (c *Capturer) Reinitialize(forceGDI bool) error {
 		return nil
 	}
 
+	// Stay in spanning mode if the controller selected it
+	if c.displayIndex == AllDisplays {
+		if err := c.spanDisplays(); err == nil {
+			return nil
+		}
+		c.displayIndex = 0
+	}
+
 	// Try DXGI first
 	dxgi, err := NewDXGICapturer()
 	if err == nil {
@@ -339,6 +352,13 @@ func (c *Capturer) SwitchDisplay(displayIndex int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if displayIndex == AllDisplays {
+		if c.session0Capturer != nil {
+			return fmt.Errorf("spanning all displays is not available on the login screen")
+		}
+		return c.spanDisplays()
+	}
+
 	log.Printf("🖥️ Switching to display %d...", displayIndex)
 
 	// Close existing capturer
@@ -346,6 +366,7 @@ func (c *Capturer) SwitchDisplay(displayIndex int) error {
 		c.dxgiCapturer.Close()
 		c.dxgiCapturer = nil
 	}
+	c.closeSpan()
 
 	// Create new DXGI capturer for the target output
 	dxgi, err := NewDXGICapturerForOutput(displayIndex)
@@ -363,6 +384,57 @@ func (c *Capturer) SwitchDisplay(displayIndex int) error {
 	return nil
 }
 
+// spanDisplays switches to AllDisplays. The DXGI outputs are composited
+// when all of them open; otherwise the screenshot library captures the
+// virtual desktop rectangle directly. Caller must hold c.mu.
+func (c *Capturer) spanDisplays() error {
+	monitors := EnumerateDisplays()
+	virtual := VirtualBounds(displayRects(monitors))
+	if virtual.Empty() {
+		return fmt.Errorf("failed to span displays: no displays found")
+	}
+	log.Printf("🖥️ Spanning %d displays...", len(monitors))
+
+	if c.dxgiCapturer != nil {
+		c.dxgiCapturer.Close()
+		c.dxgiCapturer = nil
+	}
+	c.closeSpan()
+
+	span, err := newSpanCapturer(monitors)
+	if err != nil {
+		log.Printf("⚠️ DXGI spanning not available: %v, using screenshot library", err)
+	} else {
+		c.span = span
+	}
+
+	// bounds keeps the virtual desktop position: the screenshot fallback
+	// captures it directly and input maps onto it via the monitor offset.
+	c.bounds = virtual
+	c.displayIndex = AllDisplays
+	c.useGDI = false
+	c.lastHash = nil
+
+	log.Printf("✅ Spanning all displays: %dx%d (origin %d,%d)", virtual.Dx(), virtual.Dy(), virtual.Min.X, virtual.Min.Y)
+	return nil
+}
+
+func (c *Capturer) closeSpan() {
+	if c.span != nil {
+		c.span.Close()
+		c.span = nil
+	}
+}
+
+// captureFallbackRGBA captures without a DXGI/GDI capturer: the composited
+// outputs in spanning mode, otherwise the screenshot library over c.bounds.
+func (c *Capturer) captureFallbackRGBA() (*image.RGBA, error) {
+	if c.span != nil {
+		return c.span.CaptureRGBA()
+	}
+	return screenshot.CaptureRect(c.bounds)
+}
+
 // GetDisplayIndex returns the current display index
 func (c *Capturer) GetDisplayIndex() int {
 	c.mu.Lock()
@@ -403,7 +475,7 @@ func (c *Capturer) CaptureJPEGScaled(quality int, scale float64) ([]byte, int, i
 	} else if c.dxgiCapturer != nil {
 		img, err = c.dxgiCapturer.CaptureRGBA()
 	} else {
-		img, err = screenshot.CaptureRect(c.bounds)
+		img, err = c.captureFallbackRGBA()
 	}
 
 	if err != nil {
@@ -490,6 +562,30 @@ func (c *Capturer) ForwardScroll(delta, x, y int) error {
 	return c.session0Capturer.SendScroll(delta, x, y)
 }
 
+// ForwardWheel forwards a high-resolution wheel event to the Session 0 helper.
+func (c *Capturer) ForwardWheel(dx, dy int) error {
+	if c.session0Capturer == nil {
+		return fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.SendWheel(dx, dy)
+}
+
+// ForwardTouch forwards a touch frame to the Session 0 helper.
+func (c *Capturer) ForwardTouch(points []input.TouchPoint) error {
+	if c.session0Capturer == nil {
+		return fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.SendTouch(points)
+}
+
+// ForwardPen forwards a pen sample to the Session 0 helper.
+func (c *Capturer) ForwardPen(p input.PenPoint) error {
+	if c.session0Capturer == nil {
+		return fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.SendPen(p)
+}
+
 // ForwardKeyEvent forwards a key event to the Session 0 helper.
 func (c *Capturer) ForwardKeyEvent(code string, down bool, ctrl, shift, alt, meta bool) error {
 	if c.session0Capturer == nil {
@@ -498,6 +594,14 @@ func (c *Capturer) ForwardKeyEvent(code string, down bool, ctrl, shift, alt, met
 	return c.session0Capturer.SendKeyEvent(code, down, ctrl, shift, alt, meta)
 }
 
+// ForwardLayoutKey forwards a layout-aware key event to the Session 0 helper.
+func (c *Capturer) ForwardLayoutKey(ev input.KeyEvent) error {
+	if c.session0Capturer == nil {
+		return fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.SendLayoutKey(ev)
+}
+
 // ForwardUnicodeChar forwards a unicode character to the Session 0 helper.
 func (c *Capturer) ForwardUnicodeChar(char rune) error {
 	if c.session0Capturer == nil {
@@ -506,6 +610,22 @@ func (c *Capturer) ForwardUnicodeChar(char rune) error {
 	return c.session0Capturer.SendUnicodeChar(char)
 }
 
+// ForwardLocalLock locks or unlocks local input through the Session 0 helper.
+func (c *Capturer) ForwardLocalLock(enabled, blank bool, message string, until time.Time) (bool, error) {
+	if c.session0Capturer == nil {
+		return false, fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.SendLocalLock(enabled, blank, message, until)
+}
+
+// ForwardLocalLockState reports the local lock held by the Session 0 helper.
+func (c *Capturer) ForwardLocalLockState() (bool, string, error) {
+	if c.session0Capturer == nil {
+		return false, "", fmt.Errorf("no session0 capturer")
+	}
+	return c.session0Capturer.LocalLockState()
+}
+
 // CaptureRGBA captures the screen as RGBA image (for dirty region detection)
 func (c *Capturer) CaptureRGBA() (*image.RGBA, error) {
 	c.mu.Lock()
@@ -534,7 +654,7 @@ func (c *Capturer) CaptureRGBA() (*image.RGBA, error) {
 	}
 
 	// Fallback to screenshot library
-	img, err := screenshot.CaptureRect(c.bounds)
+	img, err := c.captureFallbackRGBA()
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture screen: %w", err)
 	}
@@ -263,7 +263,8 @@ DXGICapture* InitDXGIForOutput(int outputIndex) {
     return cap;
 }
 
-int CaptureDXGI(DXGICapture* cap, unsigned char* buffer, int bufferSize) {
+// CaptureDXGITimeout waits up to timeoutMs for a new frame (0 = poll).
+int CaptureDXGITimeout(DXGICapture* cap, unsigned char* buffer, int bufferSize, int timeoutMs) {
     if (!cap || !buffer) {
         return -1;
     }
@@ -273,7 +274,7 @@ int CaptureDXGI(DXGICapture* cap, unsigned char* buffer, int bufferSize) {
     DXGI_OUTDUPL_FRAME_INFO frameInfo;
     
     // Acquire next frame
-    hr = cap->duplication->AcquireNextFrame(100, &frameInfo, &desktopResource);
+    hr = cap->duplication->AcquireNextFrame(timeoutMs, &frameInfo, &desktopResource);
     if (FAILED(hr)) {
         if (hr == DXGI_ERROR_WAIT_TIMEOUT) {
             return 1; // No new frame (timeout)
@@ -324,6 +325,10 @@ int CaptureDXGI(DXGICapture* cap, unsigned char* buffer, int bufferSize) {
     return 0;
 }
 
+int CaptureDXGI(DXGICapture* cap, unsigned char* buffer, int bufferSize) {
+    return CaptureDXGITimeout(cap, buffer, bufferSize, 100);
+}
+
 void CloseDXGI(DXGICapture* cap) {
     if (!cap) return;
     
@@ -32,6 +32,7 @@ DXGICapture* InitDXGI();
 DXGICapture* InitDXGIForOutput(int outputIndex);
 int EnumDXGIOutputs(MonitorInfoC* infos, int maxCount);
 int CaptureDXGI(DXGICapture* cap, unsigned char* buffer, int bufferSize);
+int CaptureDXGITimeout(DXGICapture* cap, unsigned char* buffer, int bufferSize, int timeoutMs);
 void CloseDXGI(DXGICapture* cap);
 */
 import "C"
@@ -172,12 +173,17 @@ func (c *DXGICapturer) CaptureJPEG(quality int) ([]byte, error) {
 
 // CaptureRGBA captures the screen as RGBA image (for dirty region detection)
 func (c *DXGICapturer) CaptureRGBA() (*image.RGBA, error) {
+	return c.captureRGBA(100)
+}
+
+// captureRGBA is CaptureRGBA waiting at most timeoutMs for a new frame.
+func (c *DXGICapturer) captureRGBA(timeoutMs int) (*image.RGBA, error) {
 	// Calculate buffer size for BGRA (4 bytes per pixel)
 	bufferSize := c.width * c.height * 4
 	buffer := make([]byte, bufferSize)
 
 	// Capture frame from DXGI
-	result := C.CaptureDXGI(c.handle, (*C.uchar)(unsafe.Pointer(&buffer[0])), C.int(bufferSize))
+	result := C.CaptureDXGITimeout(c.handle, (*C.uchar)(unsafe.Pointer(&buffer[0])), C.int(bufferSize), C.int(timeoutMs))
 	if result != 0 {
 		// Timeout (code 1) means no new frame - return cached frame if available
 		if result == 1 && c.lastFrame != nil {
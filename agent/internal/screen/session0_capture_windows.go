@@ -15,6 +15,8 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/stangtennis/remote-agent/internal/input"
+	"github.com/stangtennis/remote-agent/internal/locallock"
 	"golang.org/x/sys/windows"
 )
 
@@ -34,9 +36,64 @@ const (
 	cmdScroll     = 0x04
 	cmdKeyEvent   = 0x05
 	cmdUnicode    = 0x06
+	cmdWheel      = 0x07
+	cmdTouch      = 0x08
+	cmdPen        = 0x09
+	cmdLocalLock  = 0x0A
+	cmdLockState  = 0x0B
+	cmdLayoutKey  = 0x0C
 	cmdQuit       = 0xFF
 )
 
+// Release reasons on the pipe (cmdLockState); 0 means not released yet.
+var pipeLockReasons = []string{"", locallock.ReleasedController, locallock.ReleasedTimeout, locallock.ReleasedEmergency}
+
+func pipeLockReason(reason string) byte {
+	for i, r := range pipeLockReasons {
+		if r == reason {
+			return byte(i)
+		}
+	}
+	return 0
+}
+
+func pipeLockReasonName(b byte) string {
+	if int(b) < len(pipeLockReasons) {
+		return pipeLockReasons[b]
+	}
+	return ""
+}
+
+// Pointer phases on the pipe (cmdTouch / cmdPen)
+var pipePhases = []string{input.PhaseDown, input.PhaseMove, input.PhaseUp, input.PhaseHover, input.PhaseCancel}
+
+func pipePhase(phase string) byte {
+	for i, p := range pipePhases {
+		if p == phase {
+			return byte(i)
+		}
+	}
+	return 1 // move
+}
+
+func pipePhaseName(b byte) string {
+	if int(b) < len(pipePhases) {
+		return pipePhases[b]
+	}
+	return input.PhaseMove
+}
+
+// pipePressure scales 0-1 pressure to uint16 and back.
+func pipePressure(p float64) uint16 {
+	if p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return 0xFFFF
+	}
+	return uint16(p * 0xFFFF)
+}
+
 var (
 	modKernel32s = windows.NewLazySystemDLL("kernel32.dll")
 	modWtsapi32  = windows.NewLazySystemDLL("wtsapi32.dll")
@@ -383,6 +440,7 @@ type Session0PipeCapturer struct {
 	sessionID    uintptr // Current target session ID
 	sessionState int32
 	captureMode  string
+	localLock    *pipeLocalLock // re-sent to a relaunched helper
 	mu           sync.Mutex
 	stopCh       chan struct{} // For graceful shutdown of session monitor
 }
@@ -863,6 +921,74 @@ func (c *Session0PipeCapturer) SendScroll(delta, x, y int) error {
 	return err
 }
 
+// SendWheel sends a high-resolution wheel command (WheelDelta units) to the
+// helper process.
+func (c *Session0PipeCapturer) SendWheel(dx, dy int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return fmt.Errorf("pipe not connected")
+	}
+	var buf [5]byte
+	buf[0] = cmdWheel
+	binary.LittleEndian.PutUint16(buf[1:3], uint16(int16(dx)))
+	binary.LittleEndian.PutUint16(buf[3:5], uint16(int16(dy)))
+	_, err := c.pipe.Write(buf[:])
+	return err
+}
+
+// SendTouch sends a touch frame (screen pixels) to the helper process.
+// Each contact is id(4) phase(1) x(2) y(2) pressure(2).
+func (c *Session0PipeCapturer) SendTouch(points []input.TouchPoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return fmt.Errorf("pipe not connected")
+	}
+	if len(points) > 255 {
+		points = points[:255]
+	}
+	buf := make([]byte, 2+11*len(points))
+	buf[0] = cmdTouch
+	buf[1] = byte(len(points))
+	for i, p := range points {
+		off := 2 + 11*i
+		binary.LittleEndian.PutUint32(buf[off:off+4], uint32(p.ID))
+		buf[off+4] = pipePhase(p.Phase)
+		binary.LittleEndian.PutUint16(buf[off+5:off+7], uint16(p.X))
+		binary.LittleEndian.PutUint16(buf[off+7:off+9], uint16(p.Y))
+		binary.LittleEndian.PutUint16(buf[off+9:off+11], pipePressure(p.Pressure))
+	}
+	_, err := c.pipe.Write(buf)
+	return err
+}
+
+// SendPen sends a pen sample (screen pixels) to the helper process.
+func (c *Session0PipeCapturer) SendPen(p input.PenPoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return fmt.Errorf("pipe not connected")
+	}
+	var buf [13]byte
+	buf[0] = cmdPen
+	buf[1] = pipePhase(p.Phase)
+	if p.Eraser {
+		buf[2] |= 1
+	}
+	if p.Barrel {
+		buf[2] |= 2
+	}
+	binary.LittleEndian.PutUint16(buf[3:5], uint16(p.X))
+	binary.LittleEndian.PutUint16(buf[5:7], uint16(p.Y))
+	binary.LittleEndian.PutUint16(buf[7:9], pipePressure(p.Pressure))
+	buf[9] = byte(int8(p.TiltX))
+	buf[10] = byte(int8(p.TiltY))
+	binary.LittleEndian.PutUint16(buf[11:13], uint16(p.Rotation))
+	_, err := c.pipe.Write(buf[:])
+	return err
+}
+
 // SendKeyEvent sends a key event command to the helper process.
 func (c *Session0PipeCapturer) SendKeyEvent(code string, down bool, ctrl, shift, alt, meta bool) error {
 	c.mu.Lock()
@@ -897,6 +1023,38 @@ func (c *Session0PipeCapturer) SendKeyEvent(code string, down bool, ctrl, shift,
 	return err
 }
 
+// SendLayoutKey sends a key event from a layout-aware controller to the
+// helper, which translates it with the layout of the desktop it injects
+// into (the login screen and UAC prompts included).
+func (c *Session0PipeCapturer) SendLayoutKey(ev input.KeyEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return fmt.Errorf("pipe not connected")
+	}
+	code, key := []byte(ev.Code), []byte(ev.Key)
+	if len(code) > 255 {
+		code = code[:255]
+	}
+	if len(key) > 255 {
+		key = key[:255]
+	}
+	buf := make([]byte, 6, 6+len(code)+1+len(key))
+	buf[0] = cmdLayoutKey
+	for i, on := range []bool{ev.Down, ev.Ctrl, ev.Shift, ev.Alt, ev.Meta} {
+		if on {
+			buf[1] |= 1 << i
+		}
+	}
+	binary.LittleEndian.PutUint16(buf[2:4], ev.Scan)
+	buf[4] = byte(len(code))
+	buf = append(buf[:5], code...)
+	buf = append(buf, byte(len(key)))
+	buf = append(buf, key...)
+	_, err := c.pipe.Write(buf)
+	return err
+}
+
 // SendUnicodeChar sends a unicode character to the helper process.
 func (c *Session0PipeCapturer) SendUnicodeChar(char rune) error {
 	c.mu.Lock()
@@ -911,6 +1069,92 @@ func (c *Session0PipeCapturer) SendUnicodeChar(char rune) error {
 	return err
 }
 
+// pipeLocalLock is a local input lock held by the helper.
+type pipeLocalLock struct {
+	blank   bool
+	message string
+	until   time.Time
+}
+
+// SendLocalLock locks or unlocks local input in the user session. The
+// helper owns the hooks and the cover, since both must live on the
+// user's desktop; the lock follows the helper across relaunches.
+func (c *Session0PipeCapturer) SendLocalLock(enabled, blank bool, message string, until time.Time) (blanked bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return false, fmt.Errorf("pipe not connected")
+	}
+	c.localLock = nil
+	if !enabled {
+		_, err := c.writeLocalLock(nil)
+		return false, err
+	}
+	lock := &pipeLocalLock{blank: blank, message: message, until: until}
+	blanked, err = c.writeLocalLock(lock)
+	if err == nil {
+		c.localLock = lock
+	}
+	return blanked, err
+}
+
+// writeLocalLock sends a lock (nil unlocks) and reads the helper's status
+// byte: bit0 locked, bit1 blanked, 0x80 failed. Caller holds c.mu.
+func (c *Session0PipeCapturer) writeLocalLock(lock *pipeLocalLock) (blanked bool, err error) {
+	var msg []byte
+	buf := make([]byte, 8)
+	buf[0] = cmdLocalLock
+	if lock != nil {
+		buf[1] = 1
+		if lock.blank {
+			buf[1] |= 2
+		}
+		secs := time.Until(lock.until) / time.Second
+		if secs < 1 {
+			secs = 1
+		}
+		binary.LittleEndian.PutUint32(buf[2:6], uint32(secs))
+		msg = []byte(lock.message)
+		if len(msg) > 1024 {
+			msg = msg[:1024]
+		}
+		binary.LittleEndian.PutUint16(buf[6:8], uint16(len(msg)))
+	}
+	if _, err := c.pipe.Write(append(buf, msg...)); err != nil {
+		return false, err
+	}
+	var status [1]byte
+	if _, err := io.ReadFull(c.pipe, status[:]); err != nil {
+		return false, err
+	}
+	if status[0]&0x80 != 0 {
+		return false, fmt.Errorf("helper could not lock local input")
+	}
+	return status[0]&2 != 0, nil
+}
+
+// LocalLockState reports whether the helper still holds the lock and, once
+// it let go by itself, why (timeout or the emergency combination).
+func (c *Session0PipeCapturer) LocalLockState() (locked bool, released string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pipe == nil {
+		return false, "", fmt.Errorf("pipe not connected")
+	}
+	if _, err := c.pipe.Write([]byte{cmdLockState}); err != nil {
+		return false, "", err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(c.pipe, reply[:]); err != nil {
+		return false, "", err
+	}
+	locked = reply[0] != 0
+	if !locked {
+		c.localLock = nil
+	}
+	return locked, pipeLockReasonName(reply[1]), nil
+}
+
 // --- Session monitor ---
 
 // monitorSession polls the active console session and relaunches the helper if it changes.
@@ -1087,6 +1331,16 @@ func (c *Session0PipeCapturer) relaunchHelper(newSession uintptr, newSessionStat
 	c.width = w
 	c.height = h
 
+	if c.localLock != nil {
+		if time.Now().Before(c.localLock.until) {
+			if _, err := c.writeLocalLock(c.localLock); err != nil {
+				log.Printf("⚠️ relaunchHelper: local lock not restored: %v", err)
+			}
+		} else {
+			c.localLock = nil
+		}
+	}
+
 	log.Printf("✅ Helper relaunched in session %d (state=%d): %dx%d", c.sessionID, c.sessionState, c.width, c.height)
 }
 
@@ -11,10 +11,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
 
+	"github.com/stangtennis/remote-agent/internal/input"
+	"github.com/stangtennis/remote-agent/internal/locallock"
 	"golang.org/x/sys/windows"
 )
 
@@ -31,6 +34,7 @@ const (
 	_MOUSEEVENTF_MIDDLEDOWN = 0x0020
 	_MOUSEEVENTF_MIDDLEUP   = 0x0040
 	_MOUSEEVENTF_WHEEL      = 0x0800
+	_MOUSEEVENTF_HWHEEL     = 0x1000
 	_MOUSEEVENTF_ABSOLUTE   = 0x8000
 
 	_KEYEVENTF_EXTENDEDKEY = 0x0001
@@ -229,6 +233,134 @@ func handleScroll(pipe *pipeRW) error {
 	return helperSendInput(inp[:], 1)
 }
 
+// handleWheel handles high-resolution wheel commands from the service.
+func handleWheel(pipe *pipeRW) error {
+	var buf [4]byte
+	if _, err := io.ReadFull(pipe, buf[:]); err != nil {
+		return err
+	}
+	dx := int16(binary.LittleEndian.Uint16(buf[0:2]))
+	dy := int16(binary.LittleEndian.Uint16(buf[2:4]))
+
+	switchToInputDesktop()
+
+	var inputs []byte
+	if dy != 0 {
+		inp := makeMouseInput(0, 0, uint32(int32(dy)), _MOUSEEVENTF_WHEEL)
+		inputs = append(inputs, inp[:]...)
+	}
+	if dx != 0 {
+		inp := makeMouseInput(0, 0, uint32(int32(dx)), _MOUSEEVENTF_HWHEEL)
+		inputs = append(inputs, inp[:]...)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	return helperSendInput(inputs, len(inputs)/_cbSizeInput)
+}
+
+// handleTouch handles touch frame commands from the service.
+func handleTouch(pipe *pipeRW) error {
+	var count [1]byte
+	if _, err := io.ReadFull(pipe, count[:]); err != nil {
+		return err
+	}
+	buf := make([]byte, 11*int(count[0]))
+	if _, err := io.ReadFull(pipe, buf); err != nil {
+		return err
+	}
+	points := make([]input.TouchPoint, count[0])
+	for i := range points {
+		off := 11 * i
+		points[i] = input.TouchPoint{
+			ID:       int(binary.LittleEndian.Uint32(buf[off : off+4])),
+			Phase:    pipePhaseName(buf[off+4]),
+			X:        float64(binary.LittleEndian.Uint16(buf[off+5 : off+7])),
+			Y:        float64(binary.LittleEndian.Uint16(buf[off+7 : off+9])),
+			Pressure: float64(binary.LittleEndian.Uint16(buf[off+9:off+11])) / 0xFFFF,
+		}
+	}
+
+	switchToInputDesktop()
+	return input.InjectTouch(points)
+}
+
+// handlePen handles pen sample commands from the service.
+func handlePen(pipe *pipeRW) error {
+	var buf [12]byte
+	if _, err := io.ReadFull(pipe, buf[:]); err != nil {
+		return err
+	}
+	p := input.PenPoint{
+		Phase:    pipePhaseName(buf[0]),
+		Eraser:   buf[1]&1 != 0,
+		Barrel:   buf[1]&2 != 0,
+		X:        float64(binary.LittleEndian.Uint16(buf[2:4])),
+		Y:        float64(binary.LittleEndian.Uint16(buf[4:6])),
+		Pressure: float64(binary.LittleEndian.Uint16(buf[6:8])) / 0xFFFF,
+		TiltX:    float64(int8(buf[8])),
+		TiltY:    float64(int8(buf[9])),
+		Rotation: float64(binary.LittleEndian.Uint16(buf[10:12])),
+	}
+
+	switchToInputDesktop()
+	return input.InjectPen(p)
+}
+
+// lockReleased holds the pipe code of the reason the last local lock ended
+// by itself (see pipeLockReasons).
+var lockReleased atomic.Uint32
+
+// handleLocalLock locks or unlocks local input in this session and replies
+// with a status byte: bit0 locked, bit1 blanked, 0x80 failed.
+func handleLocalLock(pipe *pipeRW) error {
+	var hdr [7]byte
+	if _, err := io.ReadFull(pipe, hdr[:]); err != nil {
+		return err
+	}
+	msg := make([]byte, binary.LittleEndian.Uint16(hdr[5:7]))
+	if _, err := io.ReadFull(pipe, msg); err != nil {
+		return err
+	}
+
+	var status byte
+	if hdr[0]&1 == 0 {
+		locallock.Unlock()
+	} else {
+		lockReleased.Store(0)
+		opts := locallock.Options{
+			Blank:   hdr[0]&2 != 0,
+			Message: string(msg),
+			Until:   time.Now().Add(time.Duration(binary.LittleEndian.Uint32(hdr[1:5])) * time.Second),
+		}
+		blanked, err := locallock.Lock(opts, func(reason string) {
+			lockReleased.Store(uint32(pipeLockReason(reason)))
+		})
+		switch {
+		case err != nil:
+			log.Printf("Local lock error: %v", err)
+			status = 0x80
+		case blanked:
+			status = 3
+		default:
+			status = 1
+		}
+	}
+	_, err := pipe.Write([]byte{status})
+	return err
+}
+
+// handleLockState replies with [locked][release reason].
+func handleLockState(pipe *pipeRW) error {
+	var reply [2]byte
+	if locallock.Active() {
+		reply[0] = 1
+	}
+	reply[1] = byte(lockReleased.Load())
+	_, err := pipe.Write(reply[:])
+	return err
+}
+
 // handleKeyEvent handles key event commands from the service.
 func handleKeyEvent(pipe *pipeRW) error {
 	var hdr [6]byte
@@ -318,6 +450,59 @@ func handleKeyEvent(pipe *pipeRW) error {
 	return nil
 }
 
+// helperKeyboard injects layout-aware key events. It lives as long as the
+// helper so the modifier state it tracks spans events.
+var helperKeyboard = input.NewKeyboardController()
+
+// handleLayoutKey handles a key event from a layout-aware controller. The
+// key is translated here, with the layout of the input desktop, rather than
+// with the service's US table: the physical scancode when the layouts
+// agree, otherwise the key producing the same character or Unicode text.
+func handleLayoutKey(pipe *pipeRW) error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(pipe, hdr[:]); err != nil {
+		return err
+	}
+	code, err := readPipeString(pipe, hdr[3])
+	if err != nil {
+		return err
+	}
+	var keyLen [1]byte
+	if _, err := io.ReadFull(pipe, keyLen[:]); err != nil {
+		return err
+	}
+	key, err := readPipeString(pipe, keyLen[0])
+	if err != nil {
+		return err
+	}
+	flags := hdr[0]
+	ev := input.KeyEvent{
+		Code:  code,
+		Scan:  binary.LittleEndian.Uint16(hdr[1:3]),
+		Key:   key,
+		Down:  flags&1 != 0,
+		Ctrl:  flags&2 != 0,
+		Shift: flags&4 != 0,
+		Alt:   flags&8 != 0,
+		Meta:  flags&16 != 0,
+	}
+
+	switchToInputDesktop()
+	return helperKeyboard.SendKeyEvent(ev)
+}
+
+// readPipeString reads a length-prefixed string body of n bytes.
+func readPipeString(pipe *pipeRW, n byte) (string, error) {
+	if n == 0 {
+		return "", nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(pipe, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
 // handleUnicode handles unicode character commands from the service.
 // For ASCII characters, uses virtual key codes (VK) instead of KEYEVENTF_UNICODE
 // because VK_PACKET (generated by UNICODE mode) doesn't work reliably with
@@ -545,6 +730,7 @@ func RunCaptureHelper(pipeName string, captureMode string) error {
 
 	pipe := &pipeRW{handle: pipeHandle}
 	log.Println("Connected to capture pipe")
+	defer locallock.Unlock()
 
 	// Initialize GDI capturer (we're in the user's session, so GDI works!)
 	gdi, err := NewGDICapturer()
@@ -628,11 +814,41 @@ func RunCaptureHelper(pipeName string, captureMode string) error {
 				log.Printf("Key event error: %v", err)
 			}
 
+		case cmdLayoutKey:
+			if err := handleLayoutKey(pipe); err != nil {
+				log.Printf("Layout key error: %v", err)
+			}
+
 		case cmdUnicode:
 			if err := handleUnicode(pipe); err != nil {
 				log.Printf("Unicode error: %v", err)
 			}
 
+		case cmdWheel:
+			if err := handleWheel(pipe); err != nil {
+				log.Printf("Wheel error: %v", err)
+			}
+
+		case cmdTouch:
+			if err := handleTouch(pipe); err != nil {
+				log.Printf("Touch error: %v", err)
+			}
+
+		case cmdPen:
+			if err := handlePen(pipe); err != nil {
+				log.Printf("Pen error: %v", err)
+			}
+
+		case cmdLocalLock:
+			if err := handleLocalLock(pipe); err != nil {
+				return fmt.Errorf("pipe local lock: %w", err)
+			}
+
+		case cmdLockState:
+			if err := handleLockState(pipe); err != nil {
+				return fmt.Errorf("pipe lock state: %w", err)
+			}
+
 		case cmdQuit:
 			log.Printf("Quit command received (sent %d frames)", frameCount)
 			return nil
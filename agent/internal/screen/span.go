@@ -0,0 +1,38 @@
+package screen
+
+import (
+	"image"
+	"image/draw"
+)
+
+// AllDisplays is the display index for spanning mode: every monitor is
+// composited into one frame covering the whole virtual desktop.
+const AllDisplays = -1
+
+// VirtualBounds returns the smallest rectangle covering all displays, in
+// virtual desktop coordinates. Monitors left of or above the primary have
+// negative offsets, so Min is not necessarily 0,0.
+func VirtualBounds(displays []image.Rectangle) image.Rectangle {
+	var union image.Rectangle
+	for _, r := range displays {
+		union = union.Union(r)
+	}
+	return union
+}
+
+// composeSpan draws each display's frame at its place in the virtual
+// desktop. The result is 0-based: pixel (0,0) is virtual.Min. A nil frame
+// leaves its area black, as do gaps between monitors of different sizes.
+func composeSpan(virtual image.Rectangle, displays []image.Rectangle, frames []*image.RGBA) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, virtual.Dx(), virtual.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), image.Black, image.Point{}, draw.Src)
+	for i, frame := range frames {
+		if frame == nil || i >= len(displays) {
+			continue
+		}
+		at := displays[i].Min.Sub(virtual.Min)
+		dst := image.Rectangle{Min: at, Max: at.Add(frame.Bounds().Size())}
+		draw.Draw(canvas, dst, frame, frame.Bounds().Min, draw.Src)
+	}
+	return canvas
+}
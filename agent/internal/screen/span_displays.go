@@ -0,0 +1,25 @@
+//go:build windows || darwin
+
+package screen
+
+import "image"
+
+// Bounds returns the monitor's rectangle in virtual desktop coordinates.
+func (m MonitorInfo) Bounds() image.Rectangle {
+	return image.Rect(m.OffsetX, m.OffsetY, m.OffsetX+m.Width, m.OffsetY+m.Height)
+}
+
+// displayRects returns the virtual desktop rectangle of each monitor.
+func displayRects(monitors []MonitorInfo) []image.Rectangle {
+	rects := make([]image.Rectangle, len(monitors))
+	for i, mon := range monitors {
+		rects[i] = mon.Bounds()
+	}
+	return rects
+}
+
+// VirtualDesktop returns the bounds of the spanning desktop, or an empty
+// rectangle when no display is found.
+func VirtualDesktop() image.Rectangle {
+	return VirtualBounds(displayRects(EnumerateDisplays()))
+}
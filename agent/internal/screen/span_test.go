@@ -0,0 +1,77 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func solidRGBA(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestVirtualBounds_NegativeOffsets(t *testing.T) {
+	// Secondary monitor left of the primary, taller portrait monitor on the right.
+	displays := []image.Rectangle{
+		image.Rect(0, 0, 1920, 1080),
+		image.Rect(-1280, 0, 0, 1024),
+		image.Rect(1920, -200, 3000, 1720),
+	}
+	got := VirtualBounds(displays)
+	want := image.Rect(-1280, -200, 3000, 1720)
+	if got != want {
+		t.Fatalf("VirtualBounds = %v, want %v", got, want)
+	}
+}
+
+func TestVirtualBounds_Empty(t *testing.T) {
+	if got := VirtualBounds(nil); !got.Empty() {
+		t.Fatalf("VirtualBounds(nil) = %v, want empty", got)
+	}
+}
+
+func TestComposeSpan_PlacesFramesAtOffsets(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	displays := []image.Rectangle{
+		image.Rect(0, 0, 40, 30),
+		image.Rect(-20, 10, 0, 30),
+	}
+	virtual := VirtualBounds(displays)
+	frames := []*image.RGBA{solidRGBA(40, 30, red), solidRGBA(20, 20, blue)}
+
+	canvas := composeSpan(virtual, displays, frames)
+	if canvas.Bounds() != image.Rect(0, 0, 60, 30) {
+		t.Fatalf("canvas bounds = %v, want 60x30 at origin", canvas.Bounds())
+	}
+
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"primary top-left", 20, 0, red},
+		{"primary bottom-right", 59, 29, red},
+		{"secondary", 0, 10, blue},
+		{"secondary bottom-right", 19, 29, blue},
+		{"gap above secondary", 5, 5, color.RGBA{A: 255}},
+	}
+	for _, tt := range tests {
+		if got := canvas.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: pixel (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestComposeSpan_NilFrameStaysBlack(t *testing.T) {
+	displays := []image.Rectangle{image.Rect(0, 0, 10, 10), image.Rect(10, 0, 20, 10)}
+	frames := []*image.RGBA{solidRGBA(10, 10, color.RGBA{G: 255, A: 255}), nil}
+
+	canvas := composeSpan(VirtualBounds(displays), displays, frames)
+	if got := canvas.RGBAAt(15, 5); got != (color.RGBA{A: 255}) {
+		t.Fatalf("missing frame area = %v, want opaque black", got)
+	}
+}
@@ -0,0 +1,110 @@
+//go:build windows
+
+package screen
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sync"
+)
+
+// spanCapturer captures every DXGI output and composites the frames into
+// one image of the virtual desktop (Capturer in AllDisplays mode).
+type spanCapturer struct {
+	outputs  []*DXGICapturer
+	displays []image.Rectangle // Virtual desktop rectangle per output
+	virtual  image.Rectangle
+}
+
+// Span outputs are polled rather than waited on: an idle output would
+// otherwise hold every frame for the full DXGI timeout. Each output
+// returns its last frame until the desktop on it changes.
+const (
+	spanPollTimeoutMs  = 0
+	spanFirstTimeoutMs = 500
+	spanFirstAttempts  = 4
+)
+
+func newSpanCapturer(monitors []MonitorInfo) (*spanCapturer, error) {
+	if len(monitors) == 0 {
+		return nil, fmt.Errorf("no displays found")
+	}
+	displays := displayRects(monitors)
+	s := &spanCapturer{
+		displays: displays,
+		virtual:  VirtualBounds(displays),
+	}
+	for _, mon := range monitors {
+		dxgi, err := NewDXGICapturerForOutput(mon.Index)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.outputs = append(s.outputs, dxgi)
+	}
+	s.primeOutputs()
+	return s, nil
+}
+
+// primeOutputs waits for a first frame from every output so polling has a
+// frame to fall back on. DXGI delivers the current desktop as the first
+// frame of a new duplication, so this normally returns at once.
+func (s *spanCapturer) primeOutputs() {
+	var wg sync.WaitGroup
+	for i, out := range s.outputs {
+		wg.Add(1)
+		go func(i int, out *DXGICapturer) {
+			defer wg.Done()
+			var err error
+			for attempt := 0; attempt < spanFirstAttempts; attempt++ {
+				if _, err = out.captureRGBA(spanFirstTimeoutMs); err == nil {
+					return
+				}
+			}
+			log.Printf("⚠️ No first frame from display %d: %v", i, err)
+		}(i, out)
+	}
+	wg.Wait()
+}
+
+// CaptureRGBA returns the composited virtual desktop. Outputs are captured
+// concurrently; an output without a frame yet stays black and the capture
+// only fails when every output fails.
+func (s *spanCapturer) CaptureRGBA() (*image.RGBA, error) {
+	frames := make([]*image.RGBA, len(s.outputs))
+	errs := make([]error, len(s.outputs))
+	var wg sync.WaitGroup
+	for i, out := range s.outputs {
+		wg.Add(1)
+		go func(i int, out *DXGICapturer) {
+			defer wg.Done()
+			frames[i], errs[i] = out.captureRGBA(spanPollTimeoutMs)
+		}(i, out)
+	}
+	wg.Wait()
+
+	var firstErr error
+	captured := 0
+	for i := range frames {
+		if errs[i] != nil {
+			frames[i] = nil
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		captured++
+	}
+	if captured == 0 {
+		return nil, fmt.Errorf("spanning capture failed: %w", firstErr)
+	}
+	return composeSpan(s.virtual, s.displays, frames), nil
+}
+
+func (s *spanCapturer) Close() {
+	for _, out := range s.outputs {
+		out.Close()
+	}
+	s.outputs = nil
+}
@@ -0,0 +1,127 @@
+// Package services lists and controls the device's OS services for the
+// process channel's svc_* ops: the Service Control Manager on Windows,
+// launchd on macOS and systemd (over D-Bus) on Linux. Installing the agent
+// itself as a service is internal/service.
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrProtected is returned for services the agent refuses to stop, restart
+// or keep from starting at boot.
+var ErrProtected = errors.New("refusing to stop a protected service or take it off automatic startup")
+
+// Service states, matching protocol.Svc*.
+const (
+	StateRunning  = "running"
+	StateStopped  = "stopped"
+	StateStarting = "starting"
+	StateStopping = "stopping"
+	StatePaused   = "paused"
+	StateFailed   = "failed"
+	StateUnknown  = "unknown"
+)
+
+// Startup types, matching protocol.SvcStartup*. StartupDelayed is Windows
+// only.
+const (
+	StartupAuto     = "auto"
+	StartupDelayed  = "delayed"
+	StartupManual   = "manual"
+	StartupDisabled = "disabled"
+)
+
+// opTimeout bounds how long start/stop wait for the service to get there.
+const opTimeout = 30 * time.Second
+
+// Info describes one service.
+type Info struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	State       string `json:"state"`
+	Startup     string `json:"startup,omitempty"`
+	PID         int    `json:"pid,omitempty"`
+}
+
+// List returns every service, sorted by name.
+func List() ([]Info, error) {
+	list, err := list()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list, nil
+}
+
+// Start starts name and waits until it runs.
+func Start(name string) (Info, error) {
+	if name == "" {
+		return Info{}, errors.New("missing service name")
+	}
+	return start(name)
+}
+
+// Stop stops name and waits until it has stopped.
+func Stop(name string) (Info, error) {
+	if err := checkProtected(name); err != nil {
+		return Info{}, err
+	}
+	return stop(name)
+}
+
+// Restart stops name if it runs, then starts it.
+func Restart(name string) (Info, error) {
+	if err := checkProtected(name); err != nil {
+		return Info{}, err
+	}
+	if _, err := stop(name); err != nil {
+		return Info{}, err
+	}
+	return start(name)
+}
+
+// SetStartup changes how name starts at boot. Protected services may only
+// be switched between auto and delayed.
+func SetStartup(name, startup string) (Info, error) {
+	switch startup {
+	case StartupAuto, StartupDelayed:
+	case StartupManual, StartupDisabled:
+		if err := checkProtected(name); err != nil {
+			return Info{}, err
+		}
+	default:
+		return Info{}, fmt.Errorf("unknown startup type %q (use auto, delayed, manual or disabled)", startup)
+	}
+	if name == "" {
+		return Info{}, errors.New("missing service name")
+	}
+	return setStartup(name, startup)
+}
+
+// IsProtected reports whether name is the agent's own service or one the
+// OS cannot run without; those are never stopped remotely or kept from
+// starting at boot.
+func IsProtected(name string) bool {
+	name = normalize(name)
+	for _, p := range protected {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkProtected(name string) error {
+	if name == "" {
+		return errors.New("missing service name")
+	}
+	if IsProtected(name) {
+		return fmt.Errorf("%w: %s", ErrProtected, name)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+//go:build darwin
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// protected are the agent itself and the daemons a Mac needs to draw the
+// screen, log in and resolve names.
+var protected = []string{
+	"dk.hawkeye.remote-agent",
+	"com.apple.WindowServer", "com.apple.loginwindow", "com.apple.securityd",
+	"com.apple.opendirectoryd", "com.apple.logd", "com.apple.configd",
+	"com.apple.mDNSResponder", "com.apple.coreservicesd", "com.apple.notifyd",
+}
+
+func normalize(name string) string { return name }
+
+// domain is the launchd domain the agent manages: the system domain when
+// it runs as root, otherwise the logged-in user's GUI domain.
+func domain() string {
+	if uid := os.Geteuid(); uid != 0 {
+		return fmt.Sprintf("gui/%d", uid)
+	}
+	return "system"
+}
+
+func list() ([]Info, error) {
+	out, err := exec.Command("launchctl", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("launchctl list: %w", err)
+	}
+	disabled := disabledJobs()
+	var infos []Info
+	for i, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if i == 0 || len(fields) != 3 {
+			continue // header or blank
+		}
+		info := Info{Name: fields[2], State: StateStopped}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			info.State, info.PID = StateRunning, pid
+		} else if fields[1] != "0" {
+			info.State = StateFailed
+		}
+		if d, ok := disabled[info.Name]; ok {
+			info.Startup = StartupAuto
+			if d {
+				info.Startup = StartupDisabled
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+var printDisabledRe = regexp.MustCompile(`"([^"]+)"\s*=>\s*(\w+)`)
+
+// disabledJobs reads the domain's enable/disable overrides.
+func disabledJobs() map[string]bool {
+	out, err := exec.Command("launchctl", "print-disabled", domain()).Output()
+	if err != nil {
+		return nil
+	}
+	jobs := map[string]bool{}
+	for _, m := range printDisabledRe.FindAllStringSubmatch(string(out), -1) {
+		jobs[m[1]] = m[2] == "disabled" || m[2] == "true"
+	}
+	return jobs
+}
+
+var (
+	listPIDRe    = regexp.MustCompile(`"PID"\s*=\s*(\d+);`)
+	listStatusRe = regexp.MustCompile(`"LastExitStatus"\s*=\s*(-?\d+);`)
+)
+
+func describe(label string) (Info, error) {
+	out, err := exec.Command("launchctl", "list", label).Output()
+	if err != nil {
+		return Info{}, fmt.Errorf("service %q not found", label)
+	}
+	info := Info{Name: label, State: StateStopped}
+	if m := listPIDRe.FindSubmatch(out); m != nil {
+		info.PID, _ = strconv.Atoi(string(m[1]))
+		info.State = StateRunning
+	} else if m := listStatusRe.FindSubmatch(out); m != nil && string(m[1]) != "0" {
+		info.State = StateFailed
+	}
+	if d, ok := disabledJobs()[label]; ok {
+		info.Startup = StartupAuto
+		if d {
+			info.Startup = StartupDisabled
+		}
+	}
+	return info, nil
+}
+
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("launchctl %s: %s", args[0], msg)
+		}
+		return fmt.Errorf("launchctl %s: %w", args[0], err)
+	}
+	return nil
+}
+
+func start(label string) (Info, error) {
+	if _, err := describe(label); err != nil {
+		return Info{}, err
+	}
+	if err := launchctl("kickstart", domain()+"/"+label); err != nil {
+		return Info{}, err
+	}
+	deadline := time.Now().Add(opTimeout)
+	for {
+		info, err := describe(label)
+		if err != nil || info.State == StateRunning {
+			return info, err
+		}
+		if time.Now().After(deadline) {
+			// On-demand jobs may run and exit before we look.
+			return info, nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// stop sends SIGTERM. launchd restarts KeepAlive jobs right away; those
+// stay stopped only once disabled.
+func stop(label string) (Info, error) {
+	info, err := describe(label)
+	if err != nil || info.State != StateRunning {
+		return info, err
+	}
+	oldPID := info.PID
+	if err := launchctl("kill", "SIGTERM", domain()+"/"+label); err != nil {
+		return info, err
+	}
+	deadline := time.Now().Add(opTimeout)
+	for {
+		info, err = describe(label)
+		if err != nil || info.State != StateRunning {
+			return info, err
+		}
+		if info.PID != oldPID {
+			return info, errors.New(label + " was restarted by launchd (KeepAlive); set its startup to disabled to keep it stopped")
+		}
+		if time.Now().After(deadline) {
+			return info, fmt.Errorf("%s is still running after %s", label, opTimeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func setStartup(label, startup string) (Info, error) {
+	if _, err := describe(label); err != nil {
+		return Info{}, err
+	}
+	action := "enable"
+	switch startup {
+	case StartupDisabled:
+		action = "disable"
+	case StartupDelayed:
+		return Info{}, errors.New("delayed startup is Windows only")
+	}
+	if err := launchctl(action, domain()+"/"+label); err != nil {
+		return Info{}, err
+	}
+	return describe(label)
+}
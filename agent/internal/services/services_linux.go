@@ -0,0 +1,226 @@
+//go:build linux
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	systemdManager = "org.freedesktop.systemd1.Manager"
+	systemdUnit    = "org.freedesktop.systemd1.Unit"
+	systemdService = "org.freedesktop.systemd1.Service"
+)
+
+// protected are the units a systemd host needs for logins, logging and the
+// bus this package talks over.
+var protected = []string{
+	"dbus.service", "dbus-broker.service", "systemd-journald.service",
+	"systemd-logind.service", "systemd-udevd.service",
+}
+
+// normalize adds the .service suffix, so "cups" means cups.service.
+func normalize(name string) string {
+	if name != "" && !strings.Contains(name, ".") {
+		return name + ".service"
+	}
+	return name
+}
+
+// unitStatus is one entry of Manager.ListUnits.
+type unitStatus struct {
+	Name, Description, LoadState, ActiveState, SubState, Followed string
+	Path                                                          dbus.ObjectPath
+	JobID                                                         uint32
+	JobType                                                       string
+	JobPath                                                       dbus.ObjectPath
+}
+
+// unitFile is one entry of Manager.ListUnitFiles.
+type unitFile struct {
+	Path, State string
+}
+
+func connect() (*dbus.Conn, dbus.BusObject, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to systemd: %w", err)
+	}
+	return conn, conn.Object(systemdDest, systemdPath), nil
+}
+
+func list() ([]Info, error) {
+	conn, mgr, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var units []unitStatus
+	if err := mgr.Call(systemdManager+".ListUnits", 0).Store(&units); err != nil {
+		return nil, fmt.Errorf("list units: %w", err)
+	}
+	startup := map[string]string{}
+	var files []unitFile
+	if err := mgr.Call(systemdManager+".ListUnitFiles", 0).Store(&files); err == nil {
+		for _, f := range files {
+			startup[f.Path[strings.LastIndex(f.Path, "/")+1:]] = startupName(f.State)
+		}
+	}
+	var infos []Info
+	for _, u := range units {
+		if !strings.HasSuffix(u.Name, ".service") || u.LoadState == "not-found" {
+			continue
+		}
+		info := Info{Name: u.Name, DisplayName: u.Description, State: stateName(u.ActiveState), Startup: startup[u.Name]}
+		if info.State == StateRunning {
+			info.PID = mainPID(conn, u.Path)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// stateName maps a unit's ActiveState.
+func stateName(active string) string {
+	switch active {
+	case "active", "reloading":
+		return StateRunning
+	case "inactive":
+		return StateStopped
+	case "activating":
+		return StateStarting
+	case "deactivating":
+		return StateStopping
+	case "failed":
+		return StateFailed
+	}
+	return StateUnknown
+}
+
+// startupName maps a UnitFileState: enabled units start at boot, disabled
+// and static ones only when started or pulled in, masked ones never.
+func startupName(state string) string {
+	switch state {
+	case "enabled", "enabled-runtime", "alias":
+		return StartupAuto
+	case "disabled", "static", "indirect", "generated", "transient":
+		return StartupManual
+	case "masked", "masked-runtime":
+		return StartupDisabled
+	}
+	return ""
+}
+
+func mainPID(conn *dbus.Conn, path dbus.ObjectPath) int {
+	v, err := conn.Object(systemdDest, path).GetProperty(systemdService + ".MainPID")
+	if err != nil {
+		return 0
+	}
+	pid, _ := v.Value().(uint32)
+	return int(pid)
+}
+
+func describe(conn *dbus.Conn, mgr dbus.BusObject, name string) (Info, error) {
+	var path dbus.ObjectPath
+	if err := mgr.Call(systemdManager+".LoadUnit", 0, name).Store(&path); err != nil {
+		return Info{}, fmt.Errorf("load %s: %w", name, err)
+	}
+	unit := conn.Object(systemdDest, path)
+	prop := func(p string) string {
+		v, err := unit.GetProperty(systemdUnit + "." + p)
+		if err != nil {
+			return ""
+		}
+		s, _ := v.Value().(string)
+		return s
+	}
+	if prop("LoadState") == "not-found" {
+		return Info{}, fmt.Errorf("service %q not found", name)
+	}
+	info := Info{
+		Name: name, DisplayName: prop("Description"),
+		State: stateName(prop("ActiveState")), Startup: startupName(prop("UnitFileState")),
+	}
+	if info.State == StateRunning {
+		info.PID = mainPID(conn, path)
+	}
+	return info, nil
+}
+
+// control runs StartUnit or StopUnit and waits for the unit to settle.
+func control(name, method, want string) (Info, error) {
+	name = normalize(name)
+	conn, mgr, err := connect()
+	if err != nil {
+		return Info{}, err
+	}
+	defer conn.Close()
+	if _, err := describe(conn, mgr, name); err != nil {
+		return Info{}, err
+	}
+	if err := mgr.Call(systemdManager+"."+method, 0, name, "replace").Err; err != nil {
+		return Info{}, fmt.Errorf("%s %s: %w", strings.ToLower(strings.TrimSuffix(method, "Unit")), name, err)
+	}
+	began := time.Now()
+	for {
+		info, err := describe(conn, mgr, name)
+		if err != nil {
+			return info, err
+		}
+		switch {
+		case info.State == want:
+			return info, nil
+		case info.State == StateFailed:
+			return info, fmt.Errorf("%s failed", name)
+		case want == StateRunning && info.State == StateStopped && time.Since(began) > time.Second:
+			return info, nil // oneshot units run and exit
+		case time.Since(began) > opTimeout:
+			return info, fmt.Errorf("%s is still %s after %s", name, info.State, opTimeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func start(name string) (Info, error) { return control(name, "StartUnit", StateRunning) }
+
+func stop(name string) (Info, error) { return control(name, "StopUnit", StateStopped) }
+
+func setStartup(name, startup string) (Info, error) {
+	if startup == StartupDelayed {
+		return Info{}, errors.New("delayed startup is Windows only")
+	}
+	name = normalize(name)
+	conn, mgr, err := connect()
+	if err != nil {
+		return Info{}, err
+	}
+	defer conn.Close()
+	if _, err := describe(conn, mgr, name); err != nil {
+		return Info{}, err
+	}
+	// Each step is method + args; masking implies disabling, and enabling
+	// needs the unit unmasked first.
+	files := []string{name}
+	steps := [][]interface{}{{"UnmaskUnitFiles", files, false}, {"DisableUnitFiles", files, false}}
+	switch startup {
+	case StartupAuto:
+		steps[1] = []interface{}{"EnableUnitFiles", files, false, true}
+	case StartupDisabled:
+		steps = [][]interface{}{{"DisableUnitFiles", files, false}, {"MaskUnitFiles", files, false, true}}
+	}
+	steps = append(steps, []interface{}{"Reload"})
+	for _, step := range steps {
+		if err := mgr.Call(systemdManager+"."+step[0].(string), 0, step[1:]...).Err; err != nil {
+			return Info{}, fmt.Errorf("set startup of %s: %w", name, err)
+		}
+	}
+	return describe(conn, mgr, name)
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuards(t *testing.T) {
+	own := protected[0]
+	if !IsProtected(own) {
+		t.Fatalf("%s not protected", own)
+	}
+	if _, err := Stop(own); !errors.Is(err, ErrProtected) {
+		t.Errorf("Stop(%s) = %v, want ErrProtected", own, err)
+	}
+	if _, err := Restart(own); !errors.Is(err, ErrProtected) {
+		t.Errorf("Restart(%s) = %v, want ErrProtected", own, err)
+	}
+	for _, startup := range []string{StartupDisabled, StartupManual} {
+		if _, err := SetStartup(own, startup); !errors.Is(err, ErrProtected) {
+			t.Errorf("SetStartup(%s, %s) = %v, want ErrProtected", own, startup, err)
+		}
+	}
+	if _, err := SetStartup("anything", "sometimes"); err == nil {
+		t.Error("unknown startup type accepted")
+	}
+	if _, err := Start(""); err == nil {
+		t.Error("empty name accepted")
+	}
+	if IsProtected("Spooler") || IsProtected("cups") {
+		t.Error("ordinary service reported as protected")
+	}
+}
@@ -0,0 +1,196 @@
+//go:build windows
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// protected are the agent itself and services Windows needs to boot, log on
+// or stay reachable.
+var protected = []string{
+	"RemoteDesktopAgent",
+	"RpcSs", "RpcEptMapper", "DcomLaunch", "LSM", "SamSs", "PlugPlay", "Power",
+	"EventLog", "ProfSvc", "gpsvc", "BFE", "mpssvc", "WinDefend", "Dhcp", "Dnscache", "nsi",
+}
+
+func normalize(name string) string { return name }
+
+const queryAccess = windows.SERVICE_QUERY_STATUS | windows.SERVICE_QUERY_CONFIG
+
+func list() ([]Info, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	out := make([]Info, 0, len(names))
+	for _, name := range names {
+		s, err := openService(m, name, queryAccess)
+		if err != nil {
+			out = append(out, Info{Name: name, State: StateUnknown})
+			continue
+		}
+		out = append(out, describe(s))
+		s.Close()
+	}
+	return out, nil
+}
+
+// openService opens name with only the access the operation needs;
+// mgr.OpenService asks for SERVICE_ALL_ACCESS, which some services deny
+// even to SYSTEM.
+func openService(m *mgr.Mgr, name string, access uint32) (*mgr.Service, error) {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenService(m.Handle, p, access)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return nil, fmt.Errorf("service %q not found", name)
+		}
+		return nil, fmt.Errorf("open service %s: %w", name, err)
+	}
+	return &mgr.Service{Name: name, Handle: h}, nil
+}
+
+func describe(s *mgr.Service) Info {
+	info := Info{Name: s.Name, State: StateUnknown}
+	if st, err := s.Query(); err == nil {
+		info.State = stateName(st.State)
+		info.PID = int(st.ProcessId)
+	}
+	if c, err := s.Config(); err == nil {
+		info.DisplayName = c.DisplayName
+		switch c.StartType {
+		case mgr.StartAutomatic, windows.SERVICE_BOOT_START, windows.SERVICE_SYSTEM_START:
+			info.Startup = StartupAuto
+			if c.DelayedAutoStart {
+				info.Startup = StartupDelayed
+			}
+		case mgr.StartManual:
+			info.Startup = StartupManual
+		case mgr.StartDisabled:
+			info.Startup = StartupDisabled
+		}
+	}
+	return info
+}
+
+func stateName(s svc.State) string {
+	switch s {
+	case svc.Running:
+		return StateRunning
+	case svc.Stopped:
+		return StateStopped
+	case svc.StartPending, svc.ContinuePending:
+		return StateStarting
+	case svc.StopPending, svc.PausePending:
+		return StateStopping
+	case svc.Paused:
+		return StatePaused
+	}
+	return StateUnknown
+}
+
+// withService connects to the SCM and opens name for fn.
+func withService(name string, access uint32, fn func(s *mgr.Service) error) (Info, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return Info{}, fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := openService(m, name, access|queryAccess)
+	if err != nil {
+		return Info{}, err
+	}
+	defer s.Close()
+	if err := fn(s); err != nil {
+		return describe(s), err
+	}
+	return describe(s), nil
+}
+
+func start(name string) (Info, error) {
+	return withService(name, windows.SERVICE_START, func(s *mgr.Service) error {
+		if err := s.Start(); err != nil && !errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return fmt.Errorf("start %s: %w", name, err)
+		}
+		return waitState(s, svc.Running)
+	})
+}
+
+func stop(name string) (Info, error) {
+	return withService(name, windows.SERVICE_STOP, func(s *mgr.Service) error {
+		if _, err := s.Control(svc.Stop); err != nil && !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+			if errors.Is(err, windows.ERROR_DEPENDENT_SERVICES_RUNNING) {
+				return fmt.Errorf("stop %s: other running services depend on it; stop them first", name)
+			}
+			return fmt.Errorf("stop %s: %w", name, err)
+		}
+		return waitState(s, svc.Stopped)
+	})
+}
+
+func setStartup(name, startup string) (Info, error) {
+	startType := uint32(mgr.StartAutomatic)
+	switch startup {
+	case StartupManual:
+		startType = mgr.StartManual
+	case StartupDisabled:
+		startType = mgr.StartDisabled
+	}
+	return withService(name, windows.SERVICE_CHANGE_CONFIG, func(s *mgr.Service) error {
+		// Only the start type changes; mgr.UpdateConfig would rewrite the
+		// whole configuration.
+		err := windows.ChangeServiceConfig(s.Handle, windows.SERVICE_NO_CHANGE, startType,
+			windows.SERVICE_NO_CHANGE, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			return fmt.Errorf("set startup of %s: %w", name, err)
+		}
+		if startType != mgr.StartAutomatic {
+			return nil
+		}
+		var d windows.SERVICE_DELAYED_AUTO_START_INFO
+		if startup == StartupDelayed {
+			d.IsDelayedAutoStartUp = 1
+		}
+		if err := windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_DELAYED_AUTO_START_INFO,
+			(*byte)(unsafe.Pointer(&d))); err != nil {
+			return fmt.Errorf("set delayed start of %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func waitState(s *mgr.Service, want svc.State) error {
+	deadline := time.Now().Add(opTimeout)
+	for {
+		st, err := s.Query()
+		if err != nil {
+			return fmt.Errorf("query %s: %w", s.Name, err)
+		}
+		if st.State == want {
+			return nil
+		}
+		if want == svc.Running && st.State == svc.Stopped {
+			return fmt.Errorf("%s stopped right after starting (exit code %d)", s.Name, st.Win32ExitCode)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is still %s after %s", s.Name, stateName(st.State), opTimeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
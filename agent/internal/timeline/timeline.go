@@ -0,0 +1,188 @@
+// Package timeline records the stats timeline of a remote session: a
+// sample every stats tick plus change events. The agent returns it for
+// `remote-desktop-cli stats` and uploads it to session_stats when the
+// session ends.
+package timeline
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits. Samples arrive every 2s; once MaxSamples are stored, adjacent
+// pairs are merged and later samples are merged in twos, fours, ... so a
+// session of any length keeps its whole span at a coarser resolution.
+const (
+	MaxSamples = 1800
+	MaxEvents  = 500
+)
+
+// Sample is one periodic stats snapshot of the active session. A merged
+// sample starts at the first merged sample's At and carries the worst RTT,
+// jitter, loss and send buffer, the mean bitrate and the latest pair type,
+// encoder and mode.
+type Sample struct {
+	At          time.Time `json:"at"`
+	ConnType    string    `json:"conn_type"` // host | srflx | relay
+	RTTMs       float64   `json:"rtt_ms"`
+	JitterMs    float64   `json:"jitter_ms"`
+	LossPct     float64   `json:"loss_pct"`
+	BitrateKbps float64   `json:"bitrate_kbps"`
+	Encoder     string    `json:"encoder"` // jpeg, or the H.264 encoder name
+	Mode        string    `json:"mode"`
+	BufferedKB  uint64    `json:"buffered_kb"`
+	Count       int       `json:"count,omitempty"` // samples merged into this one; 0 = 1
+}
+
+// Event marks a discrete change during the session (mode switch, candidate
+// pair change, disconnect, ICE restart, buffer pressure).
+type Event struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Timeline is the JSON document returned to the CLI and uploaded when the
+// session ends.
+type Timeline struct {
+	SessionID string     `json:"session_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Samples   []Sample   `json:"samples"`
+	Events    []Event    `json:"events"`
+}
+
+// Recorder collects the timeline of one session. Safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	sessionID string
+	startedAt time.Time
+	mode      string // current streaming mode, stamped onto each sample
+	samples   []Sample
+	stride    int     // raw samples per stored sample
+	pending   *Sample // merging toward the next stored sample
+	events    []Event
+}
+
+// New starts a timeline for sessionID in streaming mode mode.
+func New(sessionID, mode string) *Recorder {
+	return &Recorder{sessionID: sessionID, startedAt: time.Now(), mode: mode, stride: 1}
+}
+
+// SessionID returns the session the timeline belongs to.
+func (r *Recorder) SessionID() string {
+	return r.sessionID
+}
+
+// AddSample records a stats tick.
+func (r *Recorder) AddSample(s Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.Mode = r.mode
+	if r.pending == nil {
+		r.pending = &s
+	} else {
+		merged := Merge(*r.pending, s)
+		r.pending = &merged
+	}
+	if weight(*r.pending) < r.stride {
+		return
+	}
+	r.samples = append(r.samples, *r.pending)
+	r.pending = nil
+	if len(r.samples) >= MaxSamples {
+		r.samples = Halve(r.samples)
+		r.stride *= 2
+	}
+}
+
+// AddEvent records a change. Only the last MaxEvents are kept.
+func (r *Recorder) AddEvent(kind, detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, Event{At: time.Now(), Kind: kind, Detail: detail})
+	if len(r.events) > MaxEvents {
+		r.events = r.events[len(r.events)-MaxEvents:]
+	}
+}
+
+// SetMode stamps later samples with mode to and records the switch.
+func (r *Recorder) SetMode(from, to string) {
+	r.mu.Lock()
+	r.mode = to
+	r.mu.Unlock()
+	r.AddEvent("mode_switch", from+" -> "+to)
+}
+
+// Snapshot copies samples and events newer than since (zero = everything),
+// including the sample still being merged.
+func (r *Recorder) Snapshot(since time.Time) Timeline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := Timeline{
+		SessionID: r.sessionID,
+		StartedAt: r.startedAt,
+		Samples:   []Sample{},
+		Events:    []Event{},
+	}
+	samples := r.samples
+	if r.pending != nil {
+		samples = append(samples[:len(samples):len(samples)], *r.pending)
+	}
+	for _, s := range samples {
+		if s.At.After(since) {
+			out.Samples = append(out.Samples, s)
+		}
+	}
+	for _, e := range r.events {
+		if e.At.After(since) {
+			out.Events = append(out.Events, e)
+		}
+	}
+	return out
+}
+
+// Merge combines two consecutive samples, a before b.
+func Merge(a, b Sample) Sample {
+	wa, wb := weight(a), weight(b)
+	m := b
+	m.At = a.At
+	m.RTTMs = max(a.RTTMs, b.RTTMs)
+	m.JitterMs = max(a.JitterMs, b.JitterMs)
+	m.LossPct = max(a.LossPct, b.LossPct)
+	m.BufferedKB = max(a.BufferedKB, b.BufferedKB)
+	m.BitrateKbps = (a.BitrateKbps*float64(wa) + b.BitrateKbps*float64(wb)) / float64(wa+wb)
+	m.Count = wa + wb
+	return m
+}
+
+// Halve merges adjacent pairs of samples; an odd last sample is kept as is.
+func Halve(samples []Sample) []Sample {
+	out := make([]Sample, 0, (len(samples)+1)/2)
+	for i := 0; i < len(samples); i += 2 {
+		if i+1 < len(samples) {
+			out = append(out, Merge(samples[i], samples[i+1]))
+		} else {
+			out = append(out, samples[i])
+		}
+	}
+	return out
+}
+
+// UploadRow is the session_stats row for a finished timeline.
+func UploadRow(tl Timeline, deviceID string) map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": tl.SessionID,
+		"device_id":  deviceID,
+		"started_at": tl.StartedAt,
+		"ended_at":   tl.EndedAt,
+		"timeline":   tl,
+	}
+}
+
+func weight(s Sample) int {
+	if s.Count > 0 {
+		return s.Count
+	}
+	return 1
+}
@@ -0,0 +1,124 @@
+package timeline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecorderKeepsWholeSession(t *testing.T) {
+	r := New("sess-1", "h264")
+	start := time.Now()
+	// Three hours of 2s ticks with one lag spike in the first minute.
+	const ticks = 3 * 3600 / 2
+	for i := 0; i < ticks; i++ {
+		s := Sample{At: start.Add(time.Duration(i) * 2 * time.Second), RTTMs: 20, BitrateKbps: 1000}
+		if i == 10 {
+			s.RTTMs = 900
+			s.LossPct = 12
+		}
+		r.AddSample(s)
+	}
+
+	tl := r.Snapshot(time.Time{})
+	if n := len(tl.Samples); n == 0 || n > MaxSamples {
+		t.Fatalf("%d samples stored, want 1..%d", n, MaxSamples)
+	}
+	if !tl.Samples[0].At.Equal(start) {
+		t.Errorf("first sample at %v, want the session start %v", tl.Samples[0].At, start)
+	}
+	last := tl.Samples[len(tl.Samples)-1]
+	if want := start.Add((ticks - 1) * 2 * time.Second); last.At.After(want) || want.Sub(last.At) > 10*time.Second {
+		t.Errorf("last sample at %v, want close to %v", last.At, want)
+	}
+	total, worstRTT, worstLoss := 0, 0.0, 0.0
+	for _, s := range tl.Samples {
+		total += weight(s)
+		worstRTT = max(worstRTT, s.RTTMs)
+		worstLoss = max(worstLoss, s.LossPct)
+		if s.BitrateKbps != 1000 {
+			t.Fatalf("merged bitrate %v, want the mean 1000", s.BitrateKbps)
+		}
+	}
+	if total != ticks {
+		t.Errorf("samples cover %d ticks, want %d", total, ticks)
+	}
+	if worstRTT != 900 || worstLoss != 12 {
+		t.Errorf("lag spike lost: worst RTT %v, loss %v", worstRTT, worstLoss)
+	}
+}
+
+func TestRecorderModeAndSince(t *testing.T) {
+	r := New("sess-1", "jpeg")
+	now := time.Now()
+	r.AddSample(Sample{At: now.Add(-time.Minute)})
+	r.SetMode("jpeg", "h264")
+	r.AddSample(Sample{At: now})
+
+	tl := r.Snapshot(now.Add(-time.Second))
+	if len(tl.Samples) != 1 || tl.Samples[0].Mode != "h264" {
+		t.Errorf("since filter / mode stamp: %+v", tl.Samples)
+	}
+	if len(tl.Events) != 1 || tl.Events[0].Kind != "mode_switch" || tl.Events[0].Detail != "jpeg -> h264" {
+		t.Errorf("events = %+v", tl.Events)
+	}
+	if all := r.Snapshot(time.Time{}); all.Samples[0].Mode != "jpeg" {
+		t.Errorf("earlier sample restamped: %+v", all.Samples[0])
+	}
+}
+
+func TestRecorderEventLimit(t *testing.T) {
+	r := New("s", "jpeg")
+	for i := 0; i < MaxEvents+10; i++ {
+		r.AddEvent("conn_type", "")
+	}
+	if n := len(r.Snapshot(time.Time{}).Events); n != MaxEvents {
+		t.Errorf("%d events kept, want %d", n, MaxEvents)
+	}
+}
+
+func TestMergeAndHalve(t *testing.T) {
+	at := time.Now()
+	a := Sample{At: at, ConnType: "host", RTTMs: 10, JitterMs: 5, LossPct: 0, BitrateKbps: 1000, Encoder: "jpeg", BufferedKB: 10}
+	b := Sample{At: at.Add(2 * time.Second), ConnType: "relay", RTTMs: 80, JitterMs: 1, LossPct: 3, BitrateKbps: 2000, Encoder: "openh264", BufferedKB: 4, Count: 3}
+	m := Merge(a, b)
+	want := Sample{At: at, ConnType: "relay", RTTMs: 80, JitterMs: 5, LossPct: 3, BitrateKbps: 1750, Encoder: "openh264", BufferedKB: 10, Count: 4}
+	if m != want {
+		t.Errorf("Merge = %+v\nwant   %+v", m, want)
+	}
+
+	h := Halve([]Sample{a, a, b})
+	if len(h) != 2 || h[0].Count != 2 || h[1] != b {
+		t.Errorf("Halve = %+v", h)
+	}
+}
+
+func TestUploadRow(t *testing.T) {
+	r := New("sess-9", "jpeg")
+	r.AddSample(Sample{At: time.Now(), RTTMs: 42})
+	r.AddEvent("ended", "peer closed")
+	tl := r.Snapshot(time.Time{})
+	ended := time.Now()
+	tl.EndedAt = &ended
+
+	data, err := json.Marshal(UploadRow(tl, "dev-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row struct {
+		SessionID string     `json:"session_id"`
+		DeviceID  string     `json:"device_id"`
+		StartedAt time.Time  `json:"started_at"`
+		EndedAt   *time.Time `json:"ended_at"`
+		Timeline  Timeline   `json:"timeline"`
+	}
+	if err := json.Unmarshal(data, &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.SessionID != "sess-9" || row.DeviceID != "dev-1" || row.EndedAt == nil || row.StartedAt.IsZero() {
+		t.Errorf("row = %+v", row)
+	}
+	if len(row.Timeline.Samples) != 1 || row.Timeline.Samples[0].RTTMs != 42 || len(row.Timeline.Events) != 1 {
+		t.Errorf("timeline = %+v", row.Timeline)
+	}
+}
@@ -0,0 +1,222 @@
+package updater
+
+// Delta updates. version.json may list patches from recent versions to the
+// release (agent_deltas / agent_deltas_macos). When one starts from the
+// running binary, the updater downloads the patch instead of the whole
+// EXE, applies it and checks the result exactly like a full download:
+// SHA-256 from the manifest and the release signature. Anything that does
+// not match falls back to the full download.
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// DeltaZstd is a zstd --patch-from patch (the old binary is the dictionary)
+	DeltaZstd = "zstd"
+	// DeltaBsdiff is a classic BSDIFF40 patch
+	DeltaBsdiff = "bsdiff"
+
+	maxPatchedSize = 512 << 20
+)
+
+// Delta is a patch from an older version to the release.
+type Delta struct {
+	From       string `json:"from"`
+	Format     string `json:"format"`
+	URL        string `json:"url"`
+	Size       int64  `json:"size,omitempty"`
+	FromSHA256 string `json:"from_sha256"` // the binary the patch applies to
+}
+
+// pickDelta returns the delta that starts from currentVersion, if any.
+func pickDelta(deltas []Delta, currentVersion string) *Delta {
+	for i := range deltas {
+		d := &deltas[i]
+		if !SameVersion(d.From, currentVersion) || d.FromSHA256 == "" {
+			continue
+		}
+		if d.Format == DeltaZstd || d.Format == DeltaBsdiff {
+			return d
+		}
+	}
+	return nil
+}
+
+// downloadDelta tries to build the update at exePath from a delta against
+// the running binary. It reports whether exePath now holds the verified
+// release; on false the caller downloads the full binary.
+func (u *Updater) downloadDelta(info *UpdateInfo, exePath string) bool {
+	d := pickDelta(info.Deltas, u.currentVersion)
+	if d == nil || info.SHA256Hash == "" {
+		return false
+	}
+	currentExe, err := os.Executable()
+	if err == nil {
+		currentExe, err = filepath.EvalSymlinks(currentExe)
+	}
+	if err != nil {
+		return false
+	}
+	old, err := os.ReadFile(currentExe)
+	if err != nil {
+		log.Printf("⚠️ Delta: kan ikke læse %s: %v", currentExe, err)
+		return false
+	}
+	sum := sha256.Sum256(old)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), d.FromSHA256) {
+		// Another build of the same version (fx console vs. GUI)
+		log.Printf("ℹ️ Delta fra %s passer ikke til den kørende binær — henter fuld version", d.From)
+		return false
+	}
+
+	patchPath := exePath + ".patch"
+	defer os.Remove(patchPath)
+	log.Printf("📥 Downloading %s delta %s (%d bytes)", d.Format, d.URL, d.Size)
+	if err := u.downloader.DownloadFile(d.URL, patchPath, d.Size); err != nil {
+		log.Printf("⚠️ Delta download fejlede: %v — henter fuld version", err)
+		return false
+	}
+
+	if err := applyDeltaFile(d.Format, old, patchPath, exePath); err != nil {
+		log.Printf("⚠️ Delta kunne ikke anvendes: %v — henter fuld version", err)
+		os.Remove(exePath)
+		return false
+	}
+	if err := VerifySHA256(exePath, strings.ToLower(info.SHA256Hash)); err != nil {
+		log.Printf("⚠️ Delta-resultat: %v — henter fuld version", err)
+		os.Remove(exePath)
+		return false
+	}
+	if err := u.downloader.VerifySignature(exePath, info.ExeURL); err != nil {
+		log.Printf("⚠️ Delta-resultat: %v — henter fuld version", err)
+		os.Remove(exePath)
+		return false
+	}
+	log.Printf("✅ Opdatering bygget fra delta (%s)", d.Format)
+	return true
+}
+
+func applyDeltaFile(format string, old []byte, patchPath, outPath string) error {
+	patch, err := os.Open(patchPath)
+	if err != nil {
+		return err
+	}
+	defer patch.Close()
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	err = ApplyDelta(format, old, patch, out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ApplyDelta writes the result of applying patch (in format) to old.
+func ApplyDelta(format string, old []byte, patch io.Reader, out io.Writer) error {
+	switch format {
+	case DeltaZstd:
+		dec, err := zstd.NewReader(patch, zstd.WithDecoderDictRaw(0, old), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer dec.Close()
+		n, err := io.Copy(out, io.LimitReader(dec, maxPatchedSize+1))
+		if err != nil {
+			return fmt.Errorf("zstd patch: %w", err)
+		}
+		if n > maxPatchedSize {
+			return errors.New("zstd patch: result too large")
+		}
+		return nil
+	case DeltaBsdiff:
+		data, err := io.ReadAll(io.LimitReader(patch, maxPatchedSize))
+		if err != nil {
+			return err
+		}
+		result, err := bspatch(old, data)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(result)
+		return err
+	default:
+		return fmt.Errorf("unknown delta format %q", format)
+	}
+}
+
+// bspatch applies a BSDIFF40 patch: a 32-byte header (magic, control and
+// diff block lengths, new size) followed by three bzip2 streams holding
+// the control triples, the diff bytes added to old, and the extra bytes.
+func bspatch(old, patch []byte) ([]byte, error) {
+	if len(patch) < 32 || string(patch[:8]) != "BSDIFF40" {
+		return nil, errors.New("bsdiff: bad header")
+	}
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || newSize > maxPatchedSize ||
+		ctrlLen > int64(len(patch))-32 || diffLen > int64(len(patch))-32-ctrlLen {
+		return nil, errors.New("bsdiff: corrupt header")
+	}
+	body := patch[32:]
+	ctrl := bzip2.NewReader(bytes.NewReader(body[:ctrlLen]))
+	diff := bzip2.NewReader(bytes.NewReader(body[ctrlLen : ctrlLen+diffLen]))
+	extra := bzip2.NewReader(bytes.NewReader(body[ctrlLen+diffLen:]))
+
+	out := make([]byte, newSize)
+	var newPos, oldPos int64
+	var triple [24]byte
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, triple[:]); err != nil {
+			return nil, fmt.Errorf("bsdiff: control block: %w", err)
+		}
+		addLen, copyLen, seek := offtin(triple[0:8]), offtin(triple[8:16]), offtin(triple[16:24])
+		if addLen < 0 || copyLen < 0 || addLen > newSize-newPos || copyLen > newSize-newPos-addLen {
+			return nil, errors.New("bsdiff: corrupt control block")
+		}
+
+		// Diff bytes are added to the old bytes at the same offset
+		if _, err := io.ReadFull(diff, out[newPos:newPos+addLen]); err != nil {
+			return nil, fmt.Errorf("bsdiff: diff block: %w", err)
+		}
+		for i := int64(0); i < addLen; i++ {
+			if p := oldPos + i; p >= 0 && p < int64(len(old)) {
+				out[newPos+i] += old[p]
+			}
+		}
+		newPos += addLen
+		oldPos += addLen
+
+		if _, err := io.ReadFull(extra, out[newPos:newPos+copyLen]); err != nil {
+			return nil, fmt.Errorf("bsdiff: extra block: %w", err)
+		}
+		newPos += copyLen
+		oldPos += seek
+	}
+	return out, nil
+}
+
+// offtin decodes bsdiff's sign-magnitude little-endian int64.
+func offtin(b []byte) int64 {
+	v := int64(binary.LittleEndian.Uint64(b) &^ (1 << 63))
+	if b[7]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestApplyDeltaZstd(t *testing.T) {
+	old := bytes.Repeat([]byte("remote desktop agent v3.1.120 "), 2000)
+	want := append(bytes.Replace(old, []byte("v3.1.120"), []byte("v3.1.121"), 3), "tail"...)
+
+	// Same shape as zstd --patch-from: the old binary is a raw dictionary
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDictRaw(0, old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := enc.EncodeAll(want, nil)
+	enc.Close()
+
+	var out bytes.Buffer
+	if err := ApplyDelta(DeltaZstd, old, bytes.NewReader(patch), &out); err != nil {
+		t.Fatalf("ApplyDelta: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatal("patched output differs")
+	}
+}
+
+func TestApplyDeltaBsdiff(t *testing.T) {
+	old := []byte("remote desktop agent v3.1.120 build")
+	want := []byte("remote desktop agent v3.1.121 build + delta")
+	// BSDIFF40 patch from old to want
+	patch, _ := hex.DecodeString("42534449464634302b000000000000002b000000000000002b00000000000000" +
+		"425a68393141592653597ca6a1e2000005d0004848080020002186819a0c56c9b8bb9229c28483e5350f10" +
+		"425a6839314159265359a3bd7781000000e00070000040200030cd341268369327177245385090a3bd7781" +
+		"425a6839314159265359ad79733400000011804008260404002000220f49821800277b945dc914e14242b5e5ccd0")
+
+	var out bytes.Buffer
+	if err := ApplyDelta(DeltaBsdiff, old, bytes.NewReader(patch), &out); err != nil {
+		t.Fatalf("ApplyDelta: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("got %q, want %q", out.Bytes(), want)
+	}
+
+	// A header claiming more than the patch holds is refused
+	bad := append([]byte{}, patch...)
+	bad[8] = 0xff
+	if err := ApplyDelta(DeltaBsdiff, old, bytes.NewReader(bad), &out); err == nil {
+		t.Fatal("corrupt patch accepted")
+	}
+}
+
+func TestPickDelta(t *testing.T) {
+	deltas := []Delta{
+		{From: "v3.1.119", Format: DeltaZstd, FromSHA256: "aa"},
+		{From: "v3.1.120", Format: "xdelta", FromSHA256: "bb"},
+		{From: "v3.1.120", Format: DeltaBsdiff, FromSHA256: "cc"},
+	}
+	if d := pickDelta(deltas, "3.1.120"); d == nil || d.FromSHA256 != "cc" {
+		t.Fatalf("pickDelta = %+v, want the bsdiff delta", d)
+	}
+	if d := pickDelta(deltas, "v3.1.118"); d != nil {
+		t.Fatalf("pickDelta = %+v, want none", d)
+	}
+}
@@ -47,6 +47,8 @@ type UpdateInfo struct {
 	SHA256URL    string // Deprecated: brug SHA256Hash i stedet
 	SHA256Hash   string // Inline SHA256 hash fra version.json
 	IsPrerelease bool
+	Deltas       []Delta  // Patches fra tidligere versioner (se delta.go)
+	Rollout      *Rollout // Udrulningsringe fra version.json (nil = alle)
 }
 
 // GitHubClient handles GitHub API requests
@@ -137,13 +139,16 @@ func (c *GitHubClient) fetchRelease(url string) (*Release, error) {
 
 // VersionInfo represents version information from Caddy server
 type VersionInfo struct {
-	AgentVersion      string `json:"agent_version"`
-	ControllerVersion string `json:"controller_version"`
-	AgentURL          string `json:"agent_url"`
-	ControllerURL     string `json:"controller_url"`
-	AgentSHA256       string `json:"agent_sha256,omitempty"`
-	AgentURLMacOS     string `json:"agent_url_macos,omitempty"`
-	AgentSHA256MacOS  string `json:"agent_sha256_macos,omitempty"`
+	AgentVersion      string   `json:"agent_version"`
+	ControllerVersion string   `json:"controller_version"`
+	AgentURL          string   `json:"agent_url"`
+	ControllerURL     string   `json:"controller_url"`
+	AgentSHA256       string   `json:"agent_sha256,omitempty"`
+	AgentURLMacOS     string   `json:"agent_url_macos,omitempty"`
+	AgentSHA256MacOS  string   `json:"agent_sha256_macos,omitempty"`
+	AgentDeltas       []Delta  `json:"agent_deltas,omitempty"`
+	AgentDeltasMacOS  []Delta  `json:"agent_deltas_macos,omitempty"`
+	Rollout           *Rollout `json:"rollout,omitempty"`
 }
 
 // CheckForUpdate checks if an update is available for the agent
@@ -154,27 +159,11 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, channel string) (*U
 		current = Version{Major: 0, Minor: 0, Patch: 0, Raw: currentVersion}
 	}
 
-	// Fetch version info from Caddy downloads server
-	req, err := http.NewRequest("GET", VersionCheckURL, nil)
+	// Fetch version info from Caddy downloads server; refused unless signed
+	versionInfo, err := c.fetchSignedManifest()
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", c.userAgent)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch version info: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("version check returned status %d", resp.StatusCode)
-	}
-
-	var versionInfo VersionInfo
-	if err := json.NewDecoder(resp.Body).Decode(&versionInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse version info: %w", err)
-	}
 
 	remoteVersion, err := ParseVersion(versionInfo.AgentVersion)
 	if err != nil {
@@ -188,9 +177,11 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, channel string) (*U
 	// Pick platform-specific URL and hash
 	agentURL := versionInfo.AgentURL
 	agentHash := versionInfo.AgentSHA256
+	agentDeltas := versionInfo.AgentDeltas
 	if runtime.GOOS == "darwin" && versionInfo.AgentURLMacOS != "" {
 		agentURL = versionInfo.AgentURLMacOS
 		agentHash = versionInfo.AgentSHA256MacOS
+		agentDeltas = versionInfo.AgentDeltasMacOS
 	}
 
 	info := &UpdateInfo{
@@ -200,6 +191,8 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, channel string) (*U
 		ExeSize:      0, // Size will be determined during download
 		SHA256Hash:   agentHash,
 		IsPrerelease: false,
+		Deltas:       agentDeltas,
+		Rollout:      versionInfo.Rollout,
 	}
 
 	return info, nil
@@ -208,28 +201,7 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, channel string) (*U
 // FetchVersionInfo fetches version info from the update server
 // Returns the full VersionInfo so callers can display both agent and controller versions
 func (c *GitHubClient) FetchVersionInfo() (*VersionInfo, error) {
-	req, err := http.NewRequest("GET", VersionCheckURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", c.userAgent)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch version info: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("version check returned status %d", resp.StatusCode)
-	}
-
-	var versionInfo VersionInfo
-	if err := json.NewDecoder(resp.Body).Decode(&versionInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse version info: %w", err)
-	}
-
-	return &versionInfo, nil
+	return c.fetchSignedManifest()
 }
 
 // DownloadSHA256 downloads and parses a SHA256 checksum file
@@ -0,0 +1,186 @@
+package updater
+
+// Post-update health check. When a new version is put in place the
+// replaced binaries are kept and a pending_update.json marker is written
+// next to update_state.json. The new version has to reconnect within the
+// release's health timeout (see Rollout) — the device package watches for
+// that — or RollBack puts the old binaries back.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxUpdateStarts is how often the new version may start without becoming
+// healthy before it is treated as crash-looping and rolled back.
+const MaxUpdateStarts = 3
+
+// ReplacedFile is a binary replaced by an update and the copy of the old one.
+type ReplacedFile struct {
+	Path   string `json:"path"`
+	Backup string `json:"backup"`
+}
+
+// PendingUpdate is an installed update that has not proven itself yet.
+type PendingUpdate struct {
+	FromVersion string         `json:"from_version"`
+	ToVersion   string         `json:"to_version"`
+	Files       []ReplacedFile `json:"files"`
+	InstalledAt int64          `json:"installed_at"`
+	TimeoutMin  int            `json:"timeout_min"`
+	Starts      int            `json:"starts"`
+	RolledBack  bool           `json:"rolled_back,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+}
+
+func pendingUpdatePath() (string, error) {
+	dir, err := GetUpdateDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pending_update.json"), nil
+}
+
+// MarkInstalled records the downloaded update as installed over files, so
+// the next start verifies it. Call it once the new binaries are in place.
+func (u *Updater) MarkInstalled(files ...ReplacedFile) error {
+	if u.state.DownloadedVersion == "" {
+		return errors.New("no update downloaded")
+	}
+	timeout := DefaultHealthTimeoutMin
+	if u.availableUpdate != nil {
+		timeout = u.availableUpdate.Rollout.HealthTimeout()
+	}
+	p := &PendingUpdate{
+		FromVersion: u.currentVersion,
+		ToVersion:   u.state.DownloadedVersion,
+		Files:       files,
+		InstalledAt: time.Now().Unix(),
+		TimeoutMin:  timeout,
+	}
+	return p.Save()
+}
+
+// LoadPendingUpdate returns the update awaiting its health check, or nil.
+func LoadPendingUpdate() (*PendingUpdate, error) {
+	path, err := pendingUpdatePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p PendingUpdate
+	if err := json.Unmarshal(data, &p); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("corrupt pending update marker: %w", err)
+	}
+	return &p, nil
+}
+
+// UpdatePending reports whether an installed update is still being
+// verified; its backups must be kept and no other update installed.
+func UpdatePending() bool {
+	p, err := LoadPendingUpdate()
+	return err == nil && p != nil && !p.RolledBack
+}
+
+// Save writes the marker.
+func (p *PendingUpdate) Save() error {
+	path, err := pendingUpdatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// SameVersion compares versions with or without the "v" prefix.
+func SameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// Done removes the marker and, best effort, the backups of a healthy update.
+func (p *PendingUpdate) Done() {
+	for _, f := range p.Files {
+		os.Remove(f.Backup)
+	}
+	ClearPendingUpdate()
+}
+
+// ClearPendingUpdate removes the marker.
+func ClearPendingUpdate() {
+	if path, err := pendingUpdatePath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// RollBack puts the replaced binaries back (the failed ones are kept as
+// .bad) and marks the update rolled back, so the old version reports it
+// when it starts. The caller exits so the service manager restarts it.
+func (p *PendingUpdate) RollBack(reason string) error {
+	for i, f := range p.Files {
+		if _, err := os.Stat(f.Backup); err != nil {
+			if i == 0 {
+				return fmt.Errorf("backup %s missing: %w", f.Backup, err)
+			}
+			continue
+		}
+		bad := f.Path + ".bad"
+		os.Remove(bad)
+		if err := os.Rename(f.Path, bad); err != nil && !os.IsNotExist(err) {
+			if i == 0 {
+				return fmt.Errorf("move failed binary aside: %w", err)
+			}
+			log.Printf("⚠️ Rollback: %s: %v", f.Path, err)
+			continue
+		}
+		if err := os.Rename(f.Backup, f.Path); err != nil {
+			os.Rename(bad, f.Path)
+			if i == 0 {
+				return fmt.Errorf("restore %s: %w", f.Path, err)
+			}
+			log.Printf("⚠️ Rollback: %s: %v", f.Path, err)
+		}
+	}
+	p.RolledBack = true
+	p.Reason = reason
+	return p.Save()
+}
+
+// IgnoreVersion stops automatic updates to version, e.g. after it was
+// rolled back. A newer release replaces the ignore.
+func IgnoreVersion(version string) error {
+	u, err := NewUpdater(version)
+	if err != nil {
+		return err
+	}
+	u.state.IgnoredVersion = version
+	return u.saveState()
+}
+
+// ServerReachable reports whether the update server answers, which tells a
+// broken release apart from a device that is simply offline.
+func ServerReachable() bool {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Head(UpdatesBaseURL)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultHealthTimeoutMin is how long a new version gets to reconnect
+// before it is rolled back, when the manifest does not say.
+const DefaultHealthTimeoutMin = 10
+
+// Rollout is the "rollout" block of version.json. Without it every device
+// takes the release, as before rollouts existed.
+type Rollout struct {
+	// Percent of devices that take the release (0-100). Which devices is
+	// decided per release from a hash of device ID and version, so raising
+	// the percentage only adds devices.
+	Percent int `json:"percent"`
+	// Tags (device_tags) whose devices take the release regardless of Percent
+	Tags []string `json:"tags,omitempty"`
+	// HealthTimeoutMin is how long the new version has to reconnect
+	HealthTimeoutMin int `json:"health_timeout_min,omitempty"`
+}
+
+// RolloutTarget describes this device for rollout decisions. The device
+// package keeps it current from the heartbeat.
+type RolloutTarget struct {
+	DeviceID string
+	Tags     []string
+	Pin      string // remote_devices.update_pin: stay on this version
+}
+
+var (
+	targetMu sync.Mutex
+	target   RolloutTarget
+)
+
+// SetRolloutTarget records this device's ID, tags and version pin.
+func SetRolloutTarget(t RolloutTarget) {
+	targetMu.Lock()
+	target = t
+	targetMu.Unlock()
+}
+
+func currentTarget() RolloutTarget {
+	targetMu.Lock()
+	defer targetMu.Unlock()
+	return target
+}
+
+// Eligible reports whether the device t takes release version, and why
+// not when it does not. A pin overrides the rollout: a pinned device takes
+// exactly the pinned version and nothing else.
+func (r *Rollout) Eligible(t RolloutTarget, version string) (bool, string) {
+	if t.Pin != "" {
+		if SameVersion(t.Pin, version) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("pinned to %s", t.Pin)
+	}
+	if r == nil || r.Percent >= 100 {
+		return true, ""
+	}
+	for _, want := range r.Tags {
+		for _, have := range t.Tags {
+			if strings.EqualFold(want, have) {
+				return true, ""
+			}
+		}
+	}
+	if t.DeviceID == "" {
+		return false, "device not known yet"
+	}
+	if r.Percent > 0 && rolloutBucket(t.DeviceID, version) < r.Percent {
+		return true, ""
+	}
+	return false, fmt.Sprintf("not in rollout (%d%%, tags %s)", r.Percent, strings.Join(r.Tags, ","))
+}
+
+// HealthTimeout returns the reconnect deadline for the release in minutes.
+func (r *Rollout) HealthTimeout() int {
+	if r == nil || r.HealthTimeoutMin <= 0 {
+		return DefaultHealthTimeoutMin
+	}
+	return r.HealthTimeoutMin
+}
+
+// rolloutBucket places a device in 0-99 for a release.
+func rolloutBucket(deviceID, version string) int {
+	sum := sha256.Sum256([]byte(deviceID + "/" + strings.TrimPrefix(version, "v")))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
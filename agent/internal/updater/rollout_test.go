@@ -0,0 +1,55 @@
+package updater
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRolloutEligible(t *testing.T) {
+	canary := &Rollout{Percent: 0, Tags: []string{"canary"}}
+	tests := []struct {
+		name    string
+		rollout *Rollout
+		target  RolloutTarget
+		version string
+		want    bool
+	}{
+		{"no rollout block", nil, RolloutTarget{}, "v3.2.0", true},
+		{"full rollout", &Rollout{Percent: 100}, RolloutTarget{}, "v3.2.0", true},
+		{"tagged device", canary, RolloutTarget{DeviceID: "dev-1", Tags: []string{"Canary"}}, "v3.2.0", true},
+		{"untagged device", canary, RolloutTarget{DeviceID: "dev-1", Tags: []string{"office"}}, "v3.2.0", false},
+		{"unknown device", &Rollout{Percent: 50}, RolloutTarget{}, "v3.2.0", false},
+		{"pinned to release", canary, RolloutTarget{DeviceID: "dev-1", Pin: "3.2.0"}, "v3.2.0", true},
+		{"pinned elsewhere", nil, RolloutTarget{DeviceID: "dev-1", Pin: "v3.1.9"}, "v3.2.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := tt.rollout.Eligible(tt.target, tt.version); got != tt.want {
+				t.Errorf("Eligible() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutPercentGrows(t *testing.T) {
+	// Raising the percentage must keep every device already in the rollout
+	in := map[string]bool{}
+	for _, pct := range []int{5, 25, 50, 100} {
+		r := &Rollout{Percent: pct}
+		n := 0
+		for i := 0; i < 1000; i++ {
+			id := fmt.Sprintf("device-%d", i)
+			ok, _ := r.Eligible(RolloutTarget{DeviceID: id}, "v3.2.0")
+			if in[id] && !ok {
+				t.Fatalf("%s dropped out when rollout grew to %d%%", id, pct)
+			}
+			if ok {
+				in[id] = true
+				n++
+			}
+		}
+		if pct < 100 && (n < pct*10-60 || n > pct*10+60) {
+			t.Errorf("%d%% rollout picked %d of 1000 devices", pct, n)
+		}
+	}
+}
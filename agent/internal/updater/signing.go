@@ -0,0 +1,32 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stangtennis/Remote/releasesig"
+)
+
+// VerifySignature checks the file at filePath, downloaded from fileURL,
+// against its release signature (see releasesig). It fails when no release
+// key is built in.
+func (d *Downloader) VerifySignature(filePath, fileURL string) error {
+	f := releasesig.Fetcher{Client: d.httpClient, UserAgent: d.userAgent}
+	return f.VerifyDownload(filePath, fileURL, releasesig.BuiltinKeys())
+}
+
+// fetchSignedManifest downloads version.json and refuses it unless it is
+// signed by a trusted release key.
+func (c *GitHubClient) fetchSignedManifest() (*VersionInfo, error) {
+	f := releasesig.Fetcher{Client: c.httpClient, UserAgent: c.userAgent}
+	data, err := f.FetchVerified(VersionCheckURL, releasesig.BuiltinKeys())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch version info: %w", err)
+	}
+
+	var versionInfo VersionInfo
+	if err := json.Unmarshal(data, &versionInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse version info: %w", err)
+	}
+	return &versionInfo, nil
+}
@@ -182,6 +182,12 @@ func (u *Updater) CheckForUpdate() error {
 
 	log.Printf("🔍 Checking for updates (channel: %s, current: %s)", u.state.Channel, u.currentVersion)
 
+	if UpdatePending() {
+		log.Println("⏭️ Forrige opdatering verificeres stadig — venter")
+		u.setStatus(StatusUpToDate)
+		return nil
+	}
+
 	info, err := u.github.CheckForUpdate(u.currentVersion, u.state.Channel)
 	if err != nil {
 		u.lastError = err
@@ -205,6 +211,12 @@ func (u *Updater) CheckForUpdate() error {
 		return nil
 	}
 
+	if ok, reason := info.Rollout.Eligible(currentTarget(), info.TagName); !ok {
+		log.Printf("⏸️ Version %s holdes tilbage: %s", info.TagName, reason)
+		u.setStatus(StatusUpToDate)
+		return nil
+	}
+
 	log.Printf("🆕 Update available: %s", info.TagName)
 	u.availableUpdate = info
 	u.setStatus(StatusUpdateAvailable)
@@ -241,12 +253,14 @@ func (u *Updater) DownloadUpdate() error {
 		ext = ""
 	}
 	exePath := filepath.Join(versionDir, fmt.Sprintf("remote-agent-%s%s", info.TagName, ext))
-	log.Printf("📥 Downloading %s to %s", info.ExeURL, exePath)
-
-	if err := u.downloader.DownloadFile(info.ExeURL, exePath, info.ExeSize); err != nil {
-		u.lastError = err
-		u.setStatus(StatusError)
-		return err
+	// Delta mod den kørende binær hvis manifestet har en — ellers fuld download
+	if !u.downloadDelta(info, exePath) {
+		log.Printf("📥 Downloading %s to %s", info.ExeURL, exePath)
+		if err := u.downloader.DownloadFile(info.ExeURL, exePath, info.ExeSize); err != nil {
+			u.lastError = err
+			u.setStatus(StatusError)
+			return err
+		}
 	}
 
 	// Verificer SHA256 — inline hash fra version.json har forrang
@@ -279,6 +293,14 @@ func (u *Updater) DownloadUpdate() error {
 		return err
 	}
 
+	log.Printf("🔐 Verificerer signatur...")
+	if err := u.downloader.VerifySignature(exePath, info.ExeURL); err != nil {
+		u.lastError = err
+		u.setStatus(StatusError)
+		os.Remove(exePath)
+		return err
+	}
+
 	u.state.DownloadedVersion = info.TagName
 	u.state.DownloadPath = exePath
 	u.saveState()
@@ -359,8 +381,11 @@ func (u *Updater) installMacOS(currentExe string) error {
 		return u.lastError
 	}
 
-	// Clean up backup
-	os.Remove(backupPath)
+	// Behold backup til den nye version har meldt sig (se health.go)
+	if err := u.MarkInstalled(ReplacedFile{Path: currentExe, Backup: backupPath}); err != nil {
+		log.Printf("⚠️ Kunne ikke markere opdatering til health check: %v", err)
+		os.Remove(backupPath)
+	}
 
 	u.state.DownloadedVersion = ""
 	u.state.DownloadPath = ""
@@ -0,0 +1,115 @@
+package webrtc
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/clipboard"
+	"github.com/stangtennis/remote-agent/internal/filetransfer"
+)
+
+// setClipboardFileSource tells the file channel where the clipboard's
+// file list comes from ("clip" op), or that there is none.
+func (m *Manager) setClipboardFileSource(source func() []string) {
+	if m.fileTransferHandler == nil {
+		return
+	}
+	if source == nil {
+		m.fileTransferHandler.SetClipboardSource(nil)
+		return
+	}
+	// The size limit was applied when the files were announced
+	m.fileTransferHandler.SetClipboardSource(func() []string {
+		if !m.clipboardPolicyInForce().Allows(protocol.ClipboardFormatFiles, false, 0) {
+			return nil
+		}
+		return source()
+	})
+}
+
+// announceClipboardFiles tells the controller which files were copied
+// here. Only names and sizes go out; the controller pulls the content over
+// the file channel when the user pastes. paths is nil when the clipboard
+// no longer holds files, which clears the controller's copy.
+func (m *Manager) announceClipboardFiles(paths []string) {
+	if !m.protoSession().Has(protocol.CapClipboardFiles) {
+		return
+	}
+	if m.supportIsActive() && !m.supportAllows("files") {
+		return
+	}
+
+	msg := protocol.ClipboardFiles{Type: protocol.TypeClipboardFiles, Files: []protocol.ClipboardFile{}}
+	files, total, err := filetransfer.ExpandClipboard(paths)
+	if err != nil {
+		msg.Error = err.Error()
+		log.Printf("📋 Copied files not offered to the controller: %v", err)
+		m.sendControl(msg)
+		return
+	}
+
+	// One entry per copied item; folder sizes add up their files
+	index := make(map[string]int)
+	for _, f := range files {
+		top := strings.SplitN(f.Rel, "/", 2)
+		i, ok := index[top[0]]
+		if !ok {
+			root := f.Path
+			if len(top) > 1 {
+				root = filepath.Clean(strings.TrimSuffix(f.Path, filepath.FromSlash(top[1])))
+			}
+			i = len(msg.Files)
+			index[top[0]] = i
+			msg.Files = append(msg.Files, protocol.ClipboardFile{Name: top[0], Path: root, IsDir: len(top) > 1})
+		}
+		msg.Files[i].Size += f.Size
+	}
+	msg.Count = len(files)
+	msg.Total = total
+	if len(files) > 0 && !m.clipboardAllowed(protocol.ClipboardFormatFiles, false, total) {
+		msg = protocol.ClipboardFiles{Type: protocol.TypeClipboardFiles, Files: []protocol.ClipboardFile{}, Error: "blocked by clipboard policy"}
+	}
+	m.sendControl(msg)
+}
+
+// handleClipboardFiles puts files the controller uploaded with
+// "clip_stage"/"put" on the local clipboard, so a paste in Explorer or
+// Finder copies them where the user wants.
+func (m *Manager) handleClipboardFiles(data []byte) {
+	var msg protocol.ClipboardFiles
+	if err := protocol.Decode(data, &msg); err != nil || m.fileTransferHandler == nil {
+		return
+	}
+	if !m.clipboardAllowed(protocol.ClipboardFormatFiles, true, msg.Total) {
+		return
+	}
+	paths := make([]string, 0, len(msg.Files))
+	for _, f := range msg.Files {
+		paths = append(paths, f.Path)
+	}
+	paths, err := m.fileTransferHandler.StagedClipboardPaths(paths)
+	if err != nil {
+		log.Printf("❌ Refused clipboard files from controller: %v", err)
+		return
+	}
+
+	if m.clipboardSessionHelper != nil {
+		if err := m.clipboardSessionHelper.SetFiles(paths); err != nil {
+			log.Printf("❌ Helper SetFiles failed: %v", err)
+		}
+		return
+	}
+	if m.clipboardReceiver == nil {
+		m.clipboardReceiver = clipboard.NewReceiver()
+	}
+	if err := m.clipboardReceiver.SetFiles(paths); err != nil {
+		log.Printf("❌ Failed to set clipboard files on agent: %v", err)
+		return
+	}
+	log.Printf("✅ Clipboard set to %d file(s) from controller", len(paths))
+	if m.clipboardMonitor != nil {
+		m.clipboardMonitor.RememberFiles(paths)
+	}
+}
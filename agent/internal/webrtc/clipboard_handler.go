@@ -6,6 +6,7 @@ import (
 	"log"
 
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
 	"github.com/stangtennis/remote-agent/internal/clipboard"
 )
 
@@ -13,6 +14,9 @@ import (
 // On Windows when running as a service we route writes through the
 // user-session helper so the user's clipboard is updated (per-session).
 func (m *Manager) handleClipboardText(content string) {
+	if !m.clipboardAllowed(protocol.ClipboardFormatText, true, int64(len(content))) {
+		return
+	}
 	if m.clipboardSessionHelper != nil {
 		if err := m.clipboardSessionHelper.SetText(content); err != nil {
 			log.Printf("❌ Helper SetText failed: %v", err)
@@ -41,6 +45,9 @@ func (m *Manager) handleClipboardImage(contentB64 string) {
 		log.Printf("❌ Failed to decode clipboard image: %v", err)
 		return
 	}
+	if !m.clipboardAllowed(protocol.ClipboardFormatImage, true, int64(len(imageData))) {
+		return
+	}
 	if m.clipboardSessionHelper != nil {
 		if err := m.clipboardSessionHelper.SetImage(imageData); err != nil {
 			log.Printf("❌ Helper SetImage failed: %v", err)
@@ -62,6 +69,52 @@ func (m *Manager) handleClipboardImage(contentB64 string) {
 	}
 }
 
+// handleClipboardRich handles incoming HTML or RTF from the controller,
+// set together with its plain-text alternative.
+func (m *Manager) handleClipboardRich(data []byte) {
+	var msg protocol.Clipboard
+	if err := protocol.Decode(data, &msg); err != nil || msg.Content == "" {
+		return
+	}
+	format := protocol.ClipboardFormatOf(msg.Type)
+	if !m.clipboardAllowed(format, true, int64(len(msg.Content)+len(msg.Text))) {
+		return
+	}
+	if m.clipboardSessionHelper != nil {
+		if err := m.clipboardSessionHelper.SetRich(format, msg.Content, msg.Text); err != nil {
+			log.Printf("❌ Helper SetRich failed: %v", err)
+		}
+		return
+	}
+	if m.clipboardReceiver == nil {
+		m.clipboardReceiver = clipboard.NewReceiver()
+	}
+	if err := m.clipboardReceiver.SetRich(format, msg.Content, msg.Text); err != nil {
+		log.Printf("❌ Failed to set clipboard %s on agent: %v", format, err)
+		return
+	}
+	log.Printf("✅ Clipboard %s set on agent", format)
+	if m.clipboardMonitor != nil {
+		m.clipboardMonitor.RememberRich(format, msg.Content)
+		if msg.Text != "" {
+			m.clipboardMonitor.RememberText(msg.Text)
+		}
+	}
+}
+
+// sendClipboardRich offers a formatted copy to the controller: HTML when
+// the policy allows it, else RTF. It follows the clipboard_text the text
+// watcher sent, so peers that predate clipboard_html still paste text.
+func (m *Manager) sendClipboardRich(send func(interface{}), rich clipboard.Rich) {
+	size := int64(len(rich.Text))
+	switch {
+	case rich.HTML != "" && m.clipboardAllowed(protocol.ClipboardFormatHTML, false, size+int64(len(rich.HTML))):
+		send(protocol.Clipboard{Type: protocol.TypeClipboardHTML, Content: rich.HTML, Text: rich.Text})
+	case rich.RTF != "" && m.clipboardAllowed(protocol.ClipboardFormatRTF, false, size+int64(len(rich.RTF))):
+		send(protocol.Clipboard{Type: protocol.TypeClipboardRTF, Content: rich.RTF, Text: rich.Text})
+	}
+}
+
 // startClipboardMonitoring initializes and starts clipboard monitoring.
 //
 // On Windows when running as a service in Session 0, the OS clipboard is
@@ -85,7 +138,7 @@ func (m *Manager) startClipboardMonitoring() {
 		m.clipboardMu.Unlock()
 	}()
 
-	send := func(msg map[string]interface{}) {
+	send := func(msg interface{}) {
 		if m.dataChannel == nil || m.dataChannel.ReadyState() != pionwebrtc.DataChannelStateOpen {
 			return
 		}
@@ -103,23 +156,32 @@ func (m *Manager) startClipboardMonitoring() {
 		log.Println("📋 Spawning clipboard helper in user session (Session 0 service can't see user's clipboard directly)")
 		helper := clipboard.NewSessionHelper()
 		helper.SetOnTextChange(func(text string) {
-			send(map[string]interface{}{"type": "clipboard_text", "content": text})
+			if m.clipboardAllowed(protocol.ClipboardFormatText, false, int64(len(text))) {
+				send(map[string]interface{}{"type": "clipboard_text", "content": text})
+			}
 		})
 		helper.SetOnImageChange(func(imageData []byte) {
-			send(map[string]interface{}{"type": "clipboard_image", "content": base64.StdEncoding.EncodeToString(imageData)})
+			if m.clipboardAllowed(protocol.ClipboardFormatImage, false, int64(len(imageData))) {
+				send(map[string]interface{}{"type": "clipboard_image", "content": base64.StdEncoding.EncodeToString(imageData)})
+			}
 		})
+		helper.SetOnRichChange(func(rich clipboard.Rich) {
+			m.sendClipboardRich(send, rich)
+		})
+		helper.SetOnFilesChange(m.announceClipboardFiles)
 		if err := helper.Start(); err != nil {
 			log.Printf("⚠️  Clipboard session helper failed to start: %v — falling back to in-process monitor", err)
 		} else {
 			m.clipboardMu.Lock()
 			m.clipboardSessionHelper = helper
 			m.clipboardMu.Unlock()
+			m.setClipboardFileSource(helper.Files)
 			return
 		}
 	}
 
 	// In-process monitor (used when agent runs in user session: console
-	// mode, --as-user, macOS).
+	// mode, --as-user, macOS, Linux).
 	monitor := clipboard.NewMonitor()
 
 	// Set up text clipboard callback
@@ -127,6 +189,9 @@ func (m *Manager) startClipboardMonitoring() {
 		if m.dataChannel == nil || m.dataChannel.ReadyState() != pionwebrtc.DataChannelStateOpen {
 			return
 		}
+		if !m.clipboardAllowed(protocol.ClipboardFormatText, false, int64(len(text))) {
+			return
+		}
 
 		// Send text clipboard to controller
 		msg := map[string]interface{}{
@@ -150,6 +215,9 @@ func (m *Manager) startClipboardMonitoring() {
 		if m.dataChannel == nil || m.dataChannel.ReadyState() != pionwebrtc.DataChannelStateOpen {
 			return
 		}
+		if !m.clipboardAllowed(protocol.ClipboardFormatImage, false, int64(len(imageData))) {
+			return
+		}
 
 		// Encode image to base64 for JSON transmission
 		imageB64 := base64.StdEncoding.EncodeToString(imageData)
@@ -171,6 +239,11 @@ func (m *Manager) startClipboardMonitoring() {
 		}
 	})
 
+	monitor.SetOnRichChange(func(rich clipboard.Rich) {
+		m.sendClipboardRich(send, rich)
+	})
+	monitor.SetOnFilesChange(m.announceClipboardFiles)
+
 	// Start monitoring
 	if err := monitor.Start(); err != nil {
 		log.Printf("❌ Failed to start clipboard monitor: %v", err)
@@ -180,6 +253,7 @@ func (m *Manager) startClipboardMonitoring() {
 	m.clipboardMu.Lock()
 	m.clipboardMonitor = monitor
 	m.clipboardMu.Unlock()
+	m.setClipboardFileSource(monitor.Files)
 }
 
 func (m *Manager) stopClipboardMonitoring() {
@@ -197,4 +271,5 @@ func (m *Manager) stopClipboardMonitoring() {
 	if helper != nil {
 		helper.Stop()
 	}
+	m.setClipboardFileSource(nil)
 }
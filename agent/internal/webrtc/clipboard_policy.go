@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// clipboardPolicyInForce returns the policy clipboard sync follows: the
+// controller's, narrowed by the support session's when one is active.
+func (m *Manager) clipboardPolicyInForce() protocol.ClipboardPolicy {
+	var policy protocol.ClipboardPolicy
+	if p := m.clipboardPolicy.Load(); p != nil {
+		policy = *p
+	}
+	if m.supportIsActive() {
+		m.supportAuthMu.RLock()
+		policy = policy.Narrow(m.supportClipboard)
+		m.supportAuthMu.RUnlock()
+	}
+	return policy
+}
+
+// clipboardAllowed checks one clipboard sync against the policy in force.
+// toAgent is true for content from the controller. In support sessions
+// every sync, allowed or not, is audited.
+func (m *Manager) clipboardAllowed(format string, toAgent bool, size int64) bool {
+	allowed := m.clipboardPolicyInForce().Allows(format, toAgent, size)
+	if !allowed {
+		log.Printf("🚫 Clipboard %s (%d bytes) blocked by clipboard policy", format, size)
+	}
+	if m.supportIsActive() {
+		actionType, summary := "CLIPBOARD_FROM_AGENT", "Clipboard sent to support"
+		if toAgent {
+			actionType, summary = "CLIPBOARD_TO_AGENT", "Clipboard received from support"
+		}
+		status, details := "succeeded", map[string]interface{}{"operation": format, "bytes": size}
+		if !allowed {
+			status, details["reason"] = "cancelled", "clipboard policy"
+		}
+		go func() {
+			_ = m.recordSupportAction(actionType, status, summary, "clipboard", details)
+		}()
+	}
+	return allowed
+}
+
+// handleClipboardPolicy sets the controller's clipboard policy for this
+// session and answers with the policy in force.
+func (m *Manager) handleClipboardPolicy(data []byte) {
+	var req protocol.ClipboardPolicy
+	err := protocol.Decode(data, &req)
+	if err == nil {
+		err = req.Validate()
+	}
+	if err != nil {
+		m.sendClipboardPolicy(fmt.Sprintf("invalid clipboard policy: %v", err))
+		return
+	}
+	req.Type = ""
+	m.clipboardPolicy.Store(&req)
+
+	policy := m.clipboardPolicyInForce()
+	log.Printf("📋 Clipboard policy: direction=%q max=%d formats=%v", policy.Direction, policy.MaxBytes, policy.Formats)
+	if m.supportIsActive() {
+		go func() {
+			_ = m.recordSupportAction("CLIPBOARD_POLICY", "succeeded", "Clipboard policy set", "clipboard", map[string]interface{}{
+				"operation": policy.Direction,
+				"bytes":     policy.MaxBytes,
+				"scope":     strings.Join(policy.Formats, ","),
+			})
+		}()
+	}
+	m.sendClipboardPolicy("")
+}
+
+// sendClipboardPolicy tells a CapClipboardPolicy controller the policy in
+// force, with errMsg when its request was rejected.
+func (m *Manager) sendClipboardPolicy(errMsg string) {
+	if !m.protoSession().Has(protocol.CapClipboardPolicy) {
+		return
+	}
+	policy := m.clipboardPolicyInForce()
+	policy.Type = protocol.TypeClipboardPolicy
+	policy.Error = errMsg
+	m.sendControl(policy)
+}
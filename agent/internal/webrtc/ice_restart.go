@@ -0,0 +1,213 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// iceRestartGracePeriod is how long the disconnect grace period is extended
+// after an ICE restart has been answered. Covers TURN allocation on the new
+// network plus connectivity checks.
+const iceRestartGracePeriod = 30 * time.Second
+
+// restartSignal is a restart_offer row from session_signaling.
+type restartSignal struct {
+	ID      int `json:"id"`
+	Payload struct {
+		RestartID string `json:"restart_id"`
+		SDP       string `json:"sdp"`
+	} `json:"payload"`
+}
+
+// answerICERestart applies a controller's ICE restart offer to the existing
+// peer connection and returns the answer SDP. Data channels, tracks, the
+// terminal and shell exec registry stay intact — only the ICE transport
+// gathers new candidates for the new network path.
+func (m *Manager) answerICERestart(restartID, offerSDP string) (string, error) {
+	m.iceRestartMu.Lock()
+	defer m.iceRestartMu.Unlock()
+
+	// The controller sends the same offer over both control channel and
+	// signaling; answer once and replay the cached answer for the other path.
+	if answer, ok := m.iceRestarts[restartID]; ok {
+		return answer, nil
+	}
+
+	m.mu.Lock()
+	pc := m.peerConnection
+	m.mu.Unlock()
+	if pc == nil {
+		return "", fmt.Errorf("no active peer connection")
+	}
+
+	log.Printf("🧊 ICE restart requested by controller (%s)", restartID)
+	m.lastICERestart.Store(time.Now().UnixNano())
+	m.recordTimelineEvent("ice_restart", restartID)
+
+	offer := pionwebrtc.SessionDescription{Type: pionwebrtc.SDPTypeOffer, SDP: offerSDP}
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		return "", fmt.Errorf("set restart offer: %w", err)
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", fmt.Errorf("create restart answer: %w", err)
+	}
+	gatherComplete := pionwebrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return "", fmt.Errorf("set restart answer: %w", err)
+	}
+	select {
+	case <-gatherComplete:
+	case <-time.After(5 * time.Second):
+		log.Println("⚡ ICE restart: proceeding with gathered candidates (5s timeout)")
+	}
+
+	sdp := pc.LocalDescription().SDP
+	if m.iceRestarts == nil {
+		m.iceRestarts = make(map[string]string)
+	}
+	m.iceRestarts[restartID] = sdp
+	// Restart the clock from when the answer is ready, not when the offer arrived
+	m.lastICERestart.Store(time.Now().UnixNano())
+	log.Printf("🧊 ICE restart answered (%s)", restartID)
+	return sdp, nil
+}
+
+// iceRestartInProgress reports whether an ICE restart was answered recently
+// enough that the connection should be given time to come back.
+func (m *Manager) iceRestartInProgress() bool {
+	last := m.lastICERestart.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < iceRestartGracePeriod
+}
+
+// handleICERestartOffer answers an ICE restart offer received on the control
+// channel. Used while SCTP still has a path (e.g. during Wi-Fi → LTE handover
+// before the old interface goes down).
+func (m *Manager) handleICERestartOffer(dc *pionwebrtc.DataChannel, data []byte) {
+	var offer protocol.ICERestart
+	if err := protocol.Decode(data, &offer); err != nil || offer.RestartID == "" || offer.SDP == "" {
+		return
+	}
+	restartID := offer.RestartID
+	answer, err := m.answerICERestart(restartID, offer.SDP)
+	if err != nil {
+		log.Printf("❌ ICE restart failed: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(protocol.ICERestart{
+		Type:      protocol.TypeICERestartAnswer,
+		RestartID: restartID,
+		SDP:       answer,
+	})
+	if err := dc.SendText(string(resp)); err != nil {
+		log.Printf("⚠️ Could not send ICE restart answer on control channel: %v", err)
+	}
+}
+
+// pollICERestartOffers checks session_signaling for restart offers. Called
+// from the disconnect grace period, when the data channels cannot carry the
+// offer any more. Support sessions use their own signaling and only get
+// restarts over the control channel.
+func (m *Manager) pollICERestartOffers() {
+	sessionID := m.sessionID
+	if sessionID == "" || m.supportIsActive() {
+		return
+	}
+
+	url := m.cfg.SupabaseURL + "/rest/v1/session_signaling"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return
+	}
+	if err := m.setAuthHeaders(req); err != nil {
+		return
+	}
+	q := req.URL.Query()
+	q.Add("session_id", "eq."+sessionID)
+	q.Add("from_side", "eq.dashboard")
+	q.Add("msg_type", "eq.restart_offer")
+	q.Add("select", "id,payload")
+	q.Add("order", "created_at.desc")
+	q.Add("limit", "1") // Only the newest offer matters; older ones timed out on the controller
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		io.Copy(io.Discard, resp.Body)
+		return
+	}
+
+	var signals []restartSignal
+	if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil || len(signals) == 0 {
+		return
+	}
+	sig := signals[0]
+	if sig.Payload.RestartID == "" || sig.Payload.SDP == "" {
+		return
+	}
+
+	m.iceRestartMu.Lock()
+	_, answered := m.iceRestarts[sig.Payload.RestartID]
+	m.iceRestartMu.Unlock()
+	if answered {
+		return
+	}
+
+	answer, err := m.answerICERestart(sig.Payload.RestartID, sig.Payload.SDP)
+	if err != nil {
+		log.Printf("❌ ICE restart failed: %v", err)
+		return
+	}
+	m.sendRestartAnswer(sessionID, sig.Payload.RestartID, answer)
+}
+
+// sendRestartAnswer posts the restart answer to session_signaling.
+func (m *Manager) sendRestartAnswer(sessionID, restartID, sdp string) {
+	payload := map[string]interface{}{
+		"session_id": sessionID,
+		"from_side":  "agent",
+		"msg_type":   "restart_answer",
+		"payload": map[string]interface{}{
+			"restart_id": restartID,
+			"sdp":        sdp,
+		},
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, err := http.NewRequest("POST", m.cfg.SupabaseURL+"/rest/v1/session_signaling", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("❌ Failed to create restart answer request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := m.setAuthHeaders(req); err != nil {
+		log.Printf("❌ Failed to set auth headers for restart answer: %v", err)
+		return
+	}
+	req.Header.Set("Prefer", "return=minimal")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		log.Printf("❌ Failed to send restart answer: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("❌ Failed to send restart answer: HTTP %d - %s", resp.StatusCode, string(body))
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+}
@@ -4,18 +4,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"time"
 
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
 	"github.com/stangtennis/remote-agent/internal/desktop"
+	"github.com/stangtennis/remote-agent/internal/input"
 	"github.com/stangtennis/remote-agent/internal/screen"
 )
 
-// handleInputEvent handles input events (mouse, keyboard) with priority
-func (m *Manager) handleInputEvent(event map[string]interface{}) {
-	eventType, ok := event["t"].(string)
-	if !ok {
+// handleInputEvent handles input events (mouse, keyboard) with priority.
+// eventType is the event's "t"; data is the raw message, decoded once into
+// the event's protocol type.
+func (m *Manager) handleInputEvent(eventType string, data []byte) {
+	switch eventType {
+	case "mouse_move", "mouse_click", "mouse_scroll", "key", "ping", protocol.TypeTouch, protocol.TypePen:
+	default:
+		m.replyUnsupported(eventType, "unknown input event")
 		return
 	}
 	if m.supportIsActive() && eventType != "ping" && !m.supportAllows("input") {
@@ -29,7 +36,7 @@ func (m *Manager) handleInputEvent(event map[string]interface{}) {
 	}
 
 	switch eventType {
-	case "mouse_move", "mouse_click", "mouse_scroll", "key":
+	case "mouse_move", "mouse_click", "mouse_scroll", "key", protocol.TypeTouch, protocol.TypePen:
 		m.noteInputPriority(eventType)
 		m.inputEvents.Add(1)
 	}
@@ -49,11 +56,9 @@ func (m *Manager) handleInputEvent(event map[string]interface{}) {
 
 	// Handle ping/pong for RTT measurement
 	if eventType == "ping" {
-		ts, _ := event["ts"].(float64)
-		pong := map[string]interface{}{
-			"t":  "pong",
-			"ts": ts,
-		}
+		var ping protocol.Ping
+		_ = protocol.Decode(data, &ping)
+		pong := protocol.Ping{T: protocol.TypePong, TS: ping.TS}
 		if data, err := json.Marshal(pong); err == nil {
 			// Send pong on control channel for accurate RTT
 			if m.controlChannel != nil && m.controlChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
@@ -79,8 +84,7 @@ func (m *Manager) handleInputEvent(event map[string]interface{}) {
 	if m.isSession0 && m.screenCapturer != nil && m.screenCapturer.HasInputForwarder() {
 		var forwardErr error
 		// Helper: convert relative (0.0-1.0) coordinates to absolute pixel coordinates
-		resolveCoords := func(x, y float64) (int, int) {
-			isRelative, _ := event["rel"].(bool)
+		resolveCoords := func(x, y float64, isRelative bool) (int, int) {
 			if isRelative {
 				x = clampf(x, 0, 1)
 				y = clampf(y, 0, 1)
@@ -99,54 +103,93 @@ func (m *Manager) handleInputEvent(event map[string]interface{}) {
 
 		switch eventType {
 		case "mouse_move":
-			x, _ := event["x"].(float64)
-			y, _ := event["y"].(float64)
-			absX, absY := resolveCoords(x, y)
+			var msg protocol.MouseMove
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
+			absX, absY := resolveCoords(msg.X, msg.Y, msg.Rel)
 			forwardErr = m.screenCapturer.ForwardMouseMove(absX, absY)
 
 		case "mouse_click":
-			button, _ := event["button"].(string)
-			down, _ := event["down"].(bool)
-			x, _ := event["x"].(float64)
-			y, _ := event["y"].(float64)
+			var msg protocol.MouseClick
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
 			btnCode := 0 // left
-			if button == "right" {
+			if msg.Button == "right" {
 				btnCode = 1
-			} else if button == "middle" {
+			} else if msg.Button == "middle" {
 				btnCode = 2
 			}
 			downVal := 0
-			if down {
+			if msg.Down {
 				downVal = 1
 			}
-			absX, absY := resolveCoords(x, y)
+			var x, y float64
+			if msg.X != nil && msg.Y != nil {
+				x, y = *msg.X, *msg.Y
+			}
+			absX, absY := resolveCoords(x, y, msg.Rel)
 			forwardErr = m.screenCapturer.ForwardMouseClick(btnCode, downVal, absX, absY)
 
 		case "mouse_scroll":
-			delta, _ := event["delta"].(float64)
-			forwardErr = m.screenCapturer.ForwardScroll(int(clampf(delta, -1000, 1000)), 0, 0)
+			var msg protocol.MouseScroll
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
+			if dx, dy, ok := wheelDeltas(msg); ok {
+				forwardErr = m.screenCapturer.ForwardWheel(int(dx), int(dy))
+			} else {
+				forwardErr = m.screenCapturer.ForwardScroll(int(clampf(msg.Delta, -1000, 1000)), 0, 0)
+			}
+
+		case protocol.TypeTouch:
+			var msg protocol.Touch
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
+			points := touchPoints(msg)
+			for i := range points {
+				x, y := resolveCoords(points[i].X, points[i].Y, msg.Rel)
+				points[i].X, points[i].Y = float64(x), float64(y)
+			}
+			forwardErr = m.screenCapturer.ForwardTouch(points)
+
+		case protocol.TypePen:
+			var msg protocol.Pen
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
+			p := penPoint(msg)
+			x, y := resolveCoords(p.X, p.Y, msg.Rel)
+			p.X, p.Y = float64(x), float64(y)
+			forwardErr = m.screenCapturer.ForwardPen(p)
 
 		case "key":
-			code, _ := event["code"].(string)
-			down, _ := event["down"].(bool)
-			ctrl, _ := event["ctrl"].(bool)
-			shift, _ := event["shift"].(bool)
-			alt, _ := event["alt"].(bool)
-			meta, _ := event["meta"].(bool)
+			var msg protocol.Key
+			if forwardErr = protocol.Decode(data, &msg); forwardErr != nil {
+				break
+			}
+			// Layout-aware keys are translated by the helper, with the
+			// layout of the desktop it injects into.
+			if ev, ok := keyEvent(msg); ok {
+				forwardErr = m.screenCapturer.ForwardLayoutKey(ev)
+				break
+			}
 
 			// For type_text: send each character via ForwardUnicodeChar.
 			// The helper's handleUnicode uses charToVK() for ASCII chars
 			// (VK codes work with admin windows) and falls back to
 			// KEYEVENTF_UNICODE for non-ASCII chars.
-			if charStr, ok := event["char"].(string); ok && charStr != "" && down {
-				for _, ch := range charStr {
+			if msg.Char != "" && msg.Down {
+				for _, ch := range msg.Char {
 					if err := m.screenCapturer.ForwardUnicodeChar(ch); err != nil {
 						forwardErr = err
 						break
 					}
 				}
 			} else {
-				forwardErr = m.screenCapturer.ForwardKeyEvent(code, down, ctrl, shift, alt, meta)
+				forwardErr = m.screenCapturer.ForwardKeyEvent(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta)
 			}
 		}
 		if forwardErr != nil {
@@ -167,58 +210,142 @@ func (m *Manager) handleInputEvent(event map[string]interface{}) {
 	// Handle input events (direct — not Session 0 or no pipe capturer)
 	switch eventType {
 	case "mouse_move":
-		x, _ := event["x"].(float64)
-		y, _ := event["y"].(float64)
-		isRelative, _ := event["rel"].(bool)
-		if isRelative {
-			m.mouseController.MoveRelative(clampf(x, 0, 1), clampf(y, 0, 1))
+		var msg protocol.MouseMove
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("⚠️ Invalid mouse_move: %v", err)
+			return
+		}
+		if msg.Rel {
+			m.mouseController.MoveRelative(clampf(msg.X, 0, 1), clampf(msg.Y, 0, 1))
 		} else {
-			ax, ay := m.clampAbsolute(x, y)
+			ax, ay := m.clampAbsolute(msg.X, msg.Y)
 			m.mouseController.Move(ax, ay)
 		}
 
 	case "mouse_click":
-		button, _ := event["button"].(string)
-		down, _ := event["down"].(bool)
-		x, hasX := event["x"].(float64)
-		y, hasY := event["y"].(float64)
-		isRelative, _ := event["rel"].(bool)
-		if hasX && hasY {
-			if isRelative {
-				m.mouseController.MoveRelative(clampf(x, 0, 1), clampf(y, 0, 1))
+		var msg protocol.MouseClick
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("⚠️ Invalid mouse_click: %v", err)
+			return
+		}
+		if msg.X != nil && msg.Y != nil {
+			if msg.Rel {
+				m.mouseController.MoveRelative(clampf(*msg.X, 0, 1), clampf(*msg.Y, 0, 1))
 			} else {
-				ax, ay := m.clampAbsolute(x, y)
+				ax, ay := m.clampAbsolute(*msg.X, *msg.Y)
 				m.mouseController.Move(ax, ay)
 			}
 		}
-		m.mouseController.Click(button, down)
+		m.mouseController.Click(msg.Button, msg.Down)
 
 	case "mouse_scroll":
-		delta, _ := event["delta"].(float64)
-		m.mouseController.Scroll(int(clampf(delta, -1000, 1000)))
+		var msg protocol.MouseScroll
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("⚠️ Invalid mouse_scroll: %v", err)
+			return
+		}
+		if dx, dy, ok := wheelDeltas(msg); ok {
+			m.mouseController.ScrollXY(dx, dy)
+		} else {
+			m.mouseController.Scroll(int(clampf(msg.Delta, -1000, 1000)))
+		}
+
+	case protocol.TypeTouch:
+		var msg protocol.Touch
+		err := protocol.Decode(data, &msg)
+		if err == nil {
+			err = m.mouseController.Touch(touchPoints(msg), msg.Rel)
+		}
+		if err != nil {
+			log.Printf("⚠️ Touch input failed: %v", err)
+		}
+
+	case protocol.TypePen:
+		var msg protocol.Pen
+		err := protocol.Decode(data, &msg)
+		if err == nil {
+			err = m.mouseController.Pen(penPoint(msg), msg.Rel)
+		}
+		if err != nil {
+			log.Printf("⚠️ Pen input failed: %v", err)
+		}
 
 	case "key":
-		code, _ := event["code"].(string)
-		down, _ := event["down"].(bool)
-		ctrl, _ := event["ctrl"].(bool)
-		shift, _ := event["shift"].(bool)
-		alt, _ := event["alt"].(bool)
-		meta, _ := event["meta"].(bool)
+		var msg protocol.Key
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("⚠️ Invalid key event: %v", err)
+			return
+		}
+		// Layout-aware controllers send scan/key; the agent picks the
+		// route per key
+		if ev, ok := keyEvent(msg); ok && m.keyController != nil {
+			if ev.Down {
+				m.reportKeyboardLayout(false)
+			}
+			if err := m.keyController.SendKeyEvent(ev); err != nil {
+				log.Printf("⚠️ Key event failed (%s): %v", ev.Code, err)
+			}
+			break
+		}
 
 		// If "char" field is present, use Unicode input (bypasses keyboard layout)
-		if charStr, ok := event["char"].(string); ok && charStr != "" && down {
-			for _, ch := range charStr {
+		if msg.Char != "" && msg.Down {
+			for _, ch := range msg.Char {
 				if err := m.keyController.SendUnicodeChar(ch); err != nil {
 					// Fallback to key code approach
-					m.keyController.SendKeyWithModifiers(code, down, ctrl, shift, alt, meta)
+					m.keyController.SendKeyWithModifiers(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta)
 				}
 			}
 		} else if m.keyController != nil {
-			m.keyController.SendKeyWithModifiers(code, down, ctrl, shift, alt, meta)
+			m.keyController.SendKeyWithModifiers(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta)
 		}
 	}
 }
 
+// maxWheelUnits bounds one wheel event (100 notches), like the legacy
+// delta clamp.
+const maxWheelUnits = 100 * protocol.WheelDelta
+
+// wheelDeltas returns the high-resolution wheel deltas of a mouse_scroll
+// event, or false when the controller only sent the legacy delta.
+func wheelDeltas(msg protocol.MouseScroll) (float64, float64, bool) {
+	if msg.DX == 0 && msg.DY == 0 {
+		return 0, 0, false
+	}
+	return clampf(msg.DX, -maxWheelUnits, maxWheelUnits), clampf(msg.DY, -maxWheelUnits, maxWheelUnits), true
+}
+
+// touchPoints converts a touch frame. Coordinates are left as sent; the
+// caller resolves them against the captured frame.
+func touchPoints(msg protocol.Touch) []input.TouchPoint {
+	points := make([]input.TouchPoint, 0, len(msg.Contacts))
+	for _, c := range msg.Contacts {
+		points = append(points, input.TouchPoint{
+			ID:       c.ID,
+			X:        c.X,
+			Y:        c.Y,
+			Phase:    c.Phase,
+			Pressure: clampf(c.Pressure, 0, 1),
+		})
+	}
+	return points
+}
+
+// penPoint converts a pen sample.
+func penPoint(msg protocol.Pen) input.PenPoint {
+	return input.PenPoint{
+		X:        msg.X,
+		Y:        msg.Y,
+		Phase:    msg.Phase,
+		Pressure: clampf(msg.Pressure, 0, 1),
+		TiltX:    clampf(msg.TiltX, -90, 90),
+		TiltY:    clampf(msg.TiltY, -90, 90),
+		Rotation: clampf(msg.Rotation, 0, 359),
+		Eraser:   msg.Eraser,
+		Barrel:   msg.Barrel,
+	}
+}
+
 func (m *Manager) sendInputStatus(eventType, route, errMsg string, force bool) {
 	now := time.Now()
 	last := time.Unix(0, m.lastInputStatusAt.Load())
@@ -227,29 +354,18 @@ func (m *Manager) sendInputStatus(eventType, route, errMsg string, force bool) {
 	}
 	m.lastInputStatusAt.Store(now.UnixNano())
 
-	status := map[string]interface{}{
-		"type":         "input_status",
-		"event":        eventType,
-		"route":        route,
-		"session0":     m.isSession0,
-		"forwarder":    m.screenCapturer != nil && m.screenCapturer.HasInputForwarder(),
-		"login_screen": m.currentDesktop == desktop.DesktopWinlogon,
-		"events":       m.inputEvents.Load(),
-		"forwarded":    m.inputForwarded.Load(),
-		"errors":       m.inputForwardErrors.Load(),
-		"error":        errMsg,
-	}
-	data, jsonErr := json.Marshal(status)
-	if jsonErr != nil {
-		return
-	}
-	if m.controlChannel != nil && m.controlChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
-		_ = m.controlChannel.Send(data)
-		return
-	}
-	if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
-		_ = m.dataChannel.Send(data)
-	}
+	m.sendControl(protocol.InputStatus{
+		Type:        protocol.TypeInputStatus,
+		Event:       eventType,
+		Route:       route,
+		Session0:    m.isSession0,
+		Forwarder:   m.screenCapturer != nil && m.screenCapturer.HasInputForwarder(),
+		LoginScreen: m.currentDesktop == desktop.DesktopWinlogon,
+		Events:      int64(m.inputEvents.Load()),
+		Forwarded:   int64(m.inputForwarded.Load()),
+		Errors:      int64(m.inputForwardErrors.Load()),
+		Error:       errMsg,
+	})
 }
 
 func (m *Manager) noteInputPriority(eventType string) {
@@ -337,48 +453,42 @@ func (m *Manager) scheduleH264InputRefresh(eventType string) {
 	}()
 }
 
-// handleControlEvent handles control events from the dashboard data channel
-func (m *Manager) handleControlEvent(event map[string]interface{}) {
-	getMsgType := func(ev map[string]interface{}) string {
-		if v, ok := ev["type"].(string); ok && v != "" {
-			return v
-		}
-		if v, ok := ev["t"].(string); ok && v != "" {
-			return v
-		}
-		return ""
-	}
+// handleControlEvent handles control events from the dashboard data channel.
+// msgType is protocol.MsgType of the raw message data.
+func (m *Manager) handleControlEvent(msgType string, data []byte) {
 	sendCodecStatus := func(requested string, active string, accepted bool, reason string) {
-		status := map[string]interface{}{
-			"type":      "codec_status",
-			"requested": requested,
-			"active":    active,
-			"accepted":  accepted,
-			"reason":    reason,
-		}
-		if data, err := json.Marshal(status); err == nil {
-			if m.controlChannel != nil && m.controlChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
-				_ = m.controlChannel.Send(data)
-				return
-			}
-			if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
-				_ = m.dataChannel.Send(data)
-			}
-		}
+		m.sendControl(protocol.CodecStatus{
+			Type:      protocol.TypeCodecStatus,
+			Requested: requested,
+			Active:    active,
+			Accepted:  accepted,
+			Reason:    reason,
+		})
+	}
+
+	// The handshake is not scope-gated: it carries no data and support
+	// sessions need it like any other.
+	if msgType == protocol.TypeHello {
+		m.handleHello(data)
+		return
 	}
 
 	if m.supportIsActive() {
-		msgType := getMsgType(event)
 		switch msgType {
-		case "set_mode", "stream_pause", "stream_resume":
+		case "set_mode", "set_stream_params", "stream_pause", "stream_resume":
 			if !m.supportAllows("screen") {
 				return
 			}
-		case "switch_monitor", "clipboard_text", "clipboard_image", "release_all_keys":
+		case "switch_monitor", "clipboard_text", "clipboard_image", "release_all_keys",
+			protocol.TypeClipboardHTML, protocol.TypeClipboardRTF:
 			if !m.supportAllows("input") {
 				return
 			}
-		case "remote_login", "force_update":
+		case protocol.TypeClipboardFiles:
+			if !m.supportAllows("files") {
+				return
+			}
+		case "remote_login", "force_update", protocol.TypeLocalLock, protocol.TypeRestartSafeMode:
 			if !m.supportAllows("admin") {
 				return
 			}
@@ -390,10 +500,15 @@ func (m *Manager) handleControlEvent(event map[string]interface{}) {
 	}
 
 	// Handle streaming mode changes
-	if msgType := getMsgType(event); msgType == "set_mode" {
-		if mode, ok := event["mode"].(string); ok {
-			log.Printf("🎛️ Received set_mode request: mode=%s bitrate=%v", mode, event["bitrate"])
-			switch mode {
+	if msgType == "set_mode" {
+		var msg protocol.SetMode
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("⚠️ Invalid set_mode: %v", err)
+			return
+		}
+		if msg.Mode != "" {
+			log.Printf("🎛️ Received set_mode request: mode=%s bitrate=%d", msg.Mode, msg.Bitrate)
+			switch msg.Mode {
 			case "h264":
 				if m.SetH264Mode(true) {
 					log.Println("🎬 Switched to H.264 mode")
@@ -416,8 +531,8 @@ func (m *Manager) handleControlEvent(event map[string]interface{}) {
 				}
 			}
 		}
-		if bitrate, ok := event["bitrate"].(float64); ok && bitrate > 0 {
-			kbps := int(bitrate)
+		if msg.Bitrate > 0 {
+			kbps := msg.Bitrate
 			if kbps > 50000 {
 				kbps = 50000
 			}
@@ -427,24 +542,46 @@ func (m *Manager) handleControlEvent(event map[string]interface{}) {
 	}
 
 	// Handle switch_monitor
-	if msgType := getMsgType(event); msgType == "switch_monitor" {
-		m.handleSwitchMonitor(event)
+	if msgType == "switch_monitor" {
+		m.handleSwitchMonitor(data)
+		return
+	}
+
+	if msgType == "set_stream_params" {
+		m.handleSetStreamParams(data)
 		return
 	}
 
 	// Clipboard messages (controller -> agent)
-	if msgType := getMsgType(event); msgType != "" {
+	if msgType != "" {
 		switch msgType {
-		case "clipboard_text":
-			if content, ok := event["content"].(string); ok {
-				m.handleClipboardText(content)
+		case "clipboard_text", "clipboard_image":
+			var msg protocol.Clipboard
+			if err := protocol.Decode(data, &msg); err != nil || msg.Content == "" {
+				return
 			}
-			return
-		case "clipboard_image":
-			if contentB64, ok := event["content"].(string); ok {
-				m.handleClipboardImage(contentB64)
+			if msgType == "clipboard_text" {
+				m.handleClipboardText(msg.Content)
+			} else {
+				m.handleClipboardImage(msg.Content)
 			}
 			return
+		case protocol.TypeClipboardFiles:
+			m.handleClipboardFiles(data)
+			return
+		case protocol.TypeClipboardHTML, protocol.TypeClipboardRTF:
+			m.handleClipboardRich(data)
+			return
+		case protocol.TypeLocalLock:
+			m.handleLocalLock(data)
+			return
+		case protocol.TypeRestartSafeMode:
+			go m.handleRestartSafeMode(data)
+			return
+		case protocol.TypeClipboardPolicy:
+			// Not scope-gated: it can only narrow the session's policy
+			m.handleClipboardPolicy(data)
+			return
 		case "stream_pause":
 			// Controller signalled user-idle — stop sending frames until
 			// stream_resume. Connection + heartbeat stay alive.
@@ -467,63 +604,68 @@ func (m *Manager) handleControlEvent(event map[string]interface{}) {
 			m.releaseAllKeys()
 			return
 		case "remote_login":
-			m.handleRemoteLogin(event)
+			m.handleRemoteLogin(data)
 			return
 		}
 	}
 
 	// Handle force update from dashboard
-	if msgType, ok := event["type"].(string); ok && msgType == "force_update" {
+	if msgType == "force_update" {
 		log.Println("🔄 Force update requested from dashboard")
 		go m.handleForceUpdate()
 		return
 	}
 
-	// Check if this is a file transfer or file browser message
-	if msgType, ok := event["type"].(string); ok {
-		switch msgType {
-		case "file_transfer_start", "file_chunk", "file_transfer_complete", "file_transfer_error":
-			if m.fileTransferHandler != nil {
-				data, _ := json.Marshal(event)
-				if err := m.fileTransferHandler.HandleIncomingData(data); err != nil {
-					log.Printf("File transfer error: %v", err)
-				}
+	// File transfer and file browser messages; input events sent on the
+	// data channel fall through to the legacy handling below.
+	switch msgType {
+	case "file_transfer_start", "file_chunk", "file_transfer_complete", "file_transfer_error":
+		if m.fileTransferHandler != nil {
+			if err := m.fileTransferHandler.HandleIncomingData(data); err != nil {
+				log.Printf("File transfer error: %v", err)
 			}
-			return
+		}
+		return
 
-		case "dir_list":
-			// Handle directory listing request
-			path, _ := event["path"].(string)
-			m.handleDirListRequest(path)
-			return
+	case "dir_list":
+		// Handle directory listing request
+		var msg protocol.DirList
+		_ = protocol.Decode(data, &msg)
+		m.handleDirListRequest(msg.Path)
+		return
 
-		case "drives_list":
-			// Handle drives listing request
-			m.handleDrivesListRequest()
-			return
+	case "drives_list":
+		// Handle drives listing request
+		m.handleDrivesListRequest()
+		return
 
-		case "file_request":
-			// Handle file download request from controller
-			remotePath, _ := event["remotePath"].(string)
-			m.handleFileRequest(remotePath)
-			return
-		}
-	}
+	case "file_request":
+		// Handle file download request from controller
+		var msg protocol.ControlFileRequest
+		_ = protocol.Decode(data, &msg)
+		m.handleFileRequest(msg.RemotePath)
+		return
 
-	// Handle input events
-	eventType, ok := event["t"].(string)
-	if !ok {
+	case "ping", "mouse_move", "mouse_click", "mouse_scroll", "key":
+		// Legacy input on the data channel, handled below
+
+	case "":
+		return
+
+	default:
+		m.replyUnsupported(msgType, "unknown control message")
 		return
 	}
 
+	// Handle input events
+	eventType := msgType
+
 	// Handle ping/pong for RTT measurement
 	if eventType == "ping" {
-		// Respond with pong immediately
-		ts, _ := event["ts"].(float64)
-		pong := map[string]interface{}{
-			"t":  "pong",
-			"ts": ts, // Echo back the timestamp
-		}
+		// Respond with pong immediately, echoing the timestamp
+		var ping protocol.Ping
+		_ = protocol.Decode(data, &ping)
+		pong := protocol.Ping{T: protocol.TypePong, TS: ping.TS}
 		if data, err := json.Marshal(pong); err == nil {
 			if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
 				m.dataChannel.Send(data)
@@ -544,85 +686,90 @@ func (m *Manager) handleControlEvent(event map[string]interface{}) {
 
 	switch eventType {
 	case "mouse_move":
-		x, _ := event["x"].(float64)
-		y, _ := event["y"].(float64)
-		isRelative, _ := event["rel"].(bool)
+		var msg protocol.MouseMove
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("❌ Invalid mouse_move: %v", err)
+			return
+		}
 
 		// Use rel flag to determine coordinate type
-		if isRelative {
-			if err := m.mouseController.MoveRelative(x, y); err != nil {
+		if msg.Rel {
+			if err := m.mouseController.MoveRelative(msg.X, msg.Y); err != nil {
 				log.Printf("❌ Mouse move error: %v", err)
 			}
 		} else {
-			if err := m.mouseController.Move(x, y); err != nil {
+			if err := m.mouseController.Move(msg.X, msg.Y); err != nil {
 				log.Printf("❌ Mouse move error: %v", err)
 			}
 		}
 
 	case "mouse_click":
-		button, _ := event["button"].(string)
-		down, _ := event["down"].(bool)
-		x, hasX := event["x"].(float64)
-		y, hasY := event["y"].(float64)
-		isRelative, _ := event["rel"].(bool)
+		var msg protocol.MouseClick
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("❌ Invalid mouse_click: %v", err)
+			return
+		}
 
 		// Move mouse to click position if coordinates are provided
-		if hasX && hasY {
-			if isRelative {
-				m.mouseController.MoveRelative(x, y)
+		if msg.X != nil && msg.Y != nil {
+			if msg.Rel {
+				m.mouseController.MoveRelative(*msg.X, *msg.Y)
 			} else {
-				m.mouseController.Move(x, y)
+				m.mouseController.Move(*msg.X, *msg.Y)
 			}
 		}
 
-		if err := m.mouseController.Click(button, down); err != nil {
+		if err := m.mouseController.Click(msg.Button, msg.Down); err != nil {
 			log.Printf("❌ Mouse click error: %v", err)
 		}
 
 	case "mouse_scroll":
-		delta, _ := event["delta"].(float64)
-		if err := m.mouseController.Scroll(int(delta)); err != nil {
+		var msg protocol.MouseScroll
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("Invalid mouse_scroll: %v", err)
+			return
+		}
+		if err := m.mouseController.Scroll(int(msg.Delta)); err != nil {
 			log.Printf("Mouse scroll error: %v", err)
 		}
 
 	case "key":
-		code, _ := event["code"].(string)
-		down, _ := event["down"].(bool)
-		ctrl, _ := event["ctrl"].(bool)
-		shift, _ := event["shift"].(bool)
-		alt, _ := event["alt"].(bool)
-		meta, _ := event["meta"].(bool)
+		var msg protocol.Key
+		if err := protocol.Decode(data, &msg); err != nil {
+			log.Printf("Invalid key event: %v", err)
+			return
+		}
 
 		// If "char" field is present, use Unicode input (bypasses keyboard layout)
-		if charStr, ok := event["char"].(string); ok && charStr != "" && down {
-			for _, ch := range charStr {
+		if msg.Char != "" && msg.Down {
+			for _, ch := range msg.Char {
 				if err := m.keyController.SendUnicodeChar(ch); err != nil {
 					// Fallback to key code approach
-					if err2 := m.keyController.SendKeyWithModifiers(code, down, ctrl, shift, alt, meta); err2 != nil {
+					if err2 := m.keyController.SendKeyWithModifiers(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta); err2 != nil {
 						log.Printf("Key event error: %v", err2)
 					}
 				}
 			}
 		} else {
 			// Send key with modifiers
-			if err := m.keyController.SendKeyWithModifiers(code, down, ctrl, shift, alt, meta); err != nil {
+			if err := m.keyController.SendKeyWithModifiers(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta); err != nil {
 				log.Printf("Key event error: %v", err)
 			}
 		}
 	}
 }
 
-func (m *Manager) handleRemoteLogin(event map[string]interface{}) {
-	username, _ := event["username"].(string)
-	password, _ := event["password"].(string)
-	domain, _ := event["domain"].(string)
-	sendUsername := true
-	if v, ok := event["send_username"].(bool); ok {
-		sendUsername = v
+func (m *Manager) handleRemoteLogin(data []byte) {
+	var msg protocol.RemoteLogin
+	if err := protocol.Decode(data, &msg); err != nil {
+		log.Printf("⚠️ remote_login ignored: %v", err)
+		return
 	}
+	password := msg.Password
+	sendUsername := msg.SendUsername == nil || *msg.SendUsername
 
-	username = strings.TrimSpace(username)
-	domain = strings.TrimSpace(domain)
+	username := strings.TrimSpace(msg.Username)
+	domain := strings.TrimSpace(msg.Domain)
 
 	if username == "" && password == "" {
 		log.Println("⚠️ remote_login ignored: no username/password provided")
@@ -750,15 +897,20 @@ func (m *Manager) remoteTapKey(code string) error {
 }
 
 // handleSwitchMonitor handles monitor switching requests
-func (m *Manager) handleSwitchMonitor(event map[string]interface{}) {
-	indexF, ok := event["index"].(float64)
-	if !ok {
+func (m *Manager) handleSwitchMonitor(data []byte) {
+	// Index is required: one Decode leaves untouched is missing
+	msg := protocol.SwitchMonitor{Index: math.MinInt32}
+	if err := protocol.Decode(data, &msg); err != nil {
+		log.Printf("⚠️ switch_monitor: %v", err)
+		return
+	}
+	if msg.Index == math.MinInt32 {
 		log.Println("⚠️ switch_monitor: missing index")
 		return
 	}
-	index := int(indexF)
-	if index < 0 || index > 15 {
-		log.Printf("⚠️ switch_monitor: invalid index %d (must be 0-15)", index)
+	index := msg.Index
+	if index < screen.AllDisplays || index > 15 {
+		log.Printf("⚠️ switch_monitor: invalid index %d (must be 0-15, or -1 for all)", index)
 		return
 	}
 	log.Printf("🖥️ Switching to monitor %d...", index)
@@ -769,15 +921,20 @@ func (m *Manager) handleSwitchMonitor(event map[string]interface{}) {
 			return
 		}
 
-		// Update mouse controller with new resolution + offset
+		// Update mouse controller with new resolution + offset. In spanning
+		// mode the frame starts at the virtual desktop's top-left corner.
 		width, height := m.screenCapturer.GetResolution()
-		monitors := screen.EnumerateDisplays()
 		var offsetX, offsetY int
-		for _, mon := range monitors {
-			if mon.Index == index {
-				offsetX = mon.OffsetX
-				offsetY = mon.OffsetY
-				break
+		if index == screen.AllDisplays {
+			origin := screen.VirtualDesktop().Min
+			offsetX, offsetY = origin.X, origin.Y
+		} else {
+			for _, mon := range screen.EnumerateDisplays() {
+				if mon.Index == index {
+					offsetX = mon.OffsetX
+					offsetY = mon.OffsetY
+					break
+				}
 			}
 		}
 
@@ -791,12 +948,29 @@ func (m *Manager) handleSwitchMonitor(event map[string]interface{}) {
 			m.dirtyDetector = nil // Will be recreated on next frame
 		}
 
+		// A wide spanning desktop may exceed what the H.264 encoder takes
+		if m.useH264.Load() && !m.canUseH264Mode() {
+			m.SetH264Mode(false)
+			log.Printf("🎬 %dx%d is too large for H.264 - switched to JPEG tiles", width, height)
+			m.sendControl(protocol.CodecStatus{
+				Type:      protocol.TypeCodecStatus,
+				Requested: "h264",
+				Active:    "jpeg",
+				Accepted:  false,
+				Reason:    "h264_unavailable_for_span",
+			})
+		}
+
 		// Send confirmation
-		confirmation := map[string]interface{}{
-			"type":   "monitor_switched",
-			"index":  index,
-			"width":  width,
-			"height": height,
+		confirmation := protocol.MonitorSwitched{
+			Type:   protocol.TypeMonitorSwitched,
+			Index:  index,
+			Width:  width,
+			Height: height,
+		}
+		if index == screen.AllDisplays {
+			confirmation.OffsetX = offsetX
+			confirmation.OffsetY = offsetY
 		}
 		if data, err := json.Marshal(confirmation); err == nil {
 			if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
@@ -809,9 +983,14 @@ func (m *Manager) handleSwitchMonitor(event map[string]interface{}) {
 }
 
 // handleSetStreamParams handles stream parameter updates from controller
-func (m *Manager) handleSetStreamParams(event map[string]interface{}) {
-	if maxQuality, ok := event["max_quality"].(float64); ok {
-		q := int(maxQuality)
+func (m *Manager) handleSetStreamParams(data []byte) {
+	var msg protocol.SetStreamParams
+	if err := protocol.Decode(data, &msg); err != nil {
+		log.Printf("⚠️ set_stream_params: %v", err)
+		return
+	}
+	if msg.MaxQuality != 0 {
+		q := msg.MaxQuality
 		if q < 10 {
 			q = 10
 		} else if q > 100 {
@@ -819,8 +998,8 @@ func (m *Manager) handleSetStreamParams(event map[string]interface{}) {
 		}
 		m.streamMaxQuality = q
 	}
-	if maxFPS, ok := event["max_fps"].(float64); ok {
-		fps := int(maxFPS)
+	if msg.MaxFPS != 0 {
+		fps := msg.MaxFPS
 		if fps < 1 {
 			fps = 1
 		} else if fps > 60 {
@@ -828,13 +1007,11 @@ func (m *Manager) handleSetStreamParams(event map[string]interface{}) {
 		}
 		m.streamMaxFPS = fps
 	}
-	if maxScale, ok := event["max_scale"].(float64); ok {
-		if maxScale >= 0.25 && maxScale <= 1.0 {
-			m.streamMaxScale = maxScale
-		}
+	if msg.MaxScale >= 0.25 && msg.MaxScale <= 1.0 {
+		m.streamMaxScale = msg.MaxScale
 	}
-	if h264Kbps, ok := event["h264_bitrate_kbps"].(float64); ok {
-		kbps := int(h264Kbps)
+	if msg.H264BitrateKbps != 0 {
+		kbps := msg.H264BitrateKbps
 		if kbps < 100 {
 			kbps = 100
 		} else if kbps > 50000 {
@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"log"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/input"
+)
+
+// layoutCheckInterval limits how often key presses look for a changed
+// keyboard layout.
+const layoutCheckInterval = 2 * time.Second
+
+// reportKeyboardLayout sends the active keyboard layout to a CapKeyLayout
+// controller when it changed since the last report. Without force the check
+// runs at most once per layoutCheckInterval.
+func (m *Manager) reportKeyboardLayout(force bool) {
+	if !m.protoSession().Has(protocol.CapKeyLayout) {
+		return
+	}
+	now := time.Now().UnixNano()
+	if !force && now-m.layoutCheckedAt.Load() < int64(layoutCheckInterval) {
+		return
+	}
+	m.layoutCheckedAt.Store(now)
+
+	layout, err := input.CurrentLayout()
+	if err != nil {
+		return
+	}
+	if prev := m.keyboardLayout.Load(); prev != nil && *prev == layout {
+		return
+	}
+	m.keyboardLayout.Store(&layout)
+	log.Printf("⌨️ Keyboard layout: %s (%s)", layout.ID, layout.Lang)
+	m.sendControl(protocol.KeyboardLayout{Type: protocol.TypeKeyboardLayout, ID: layout.ID, Lang: layout.Lang})
+}
+
+// keyEvent builds a layout-aware key event, or false when the controller
+// sent neither scan nor key and the legacy code/char handling applies.
+func keyEvent(msg protocol.Key) (input.KeyEvent, bool) {
+	if msg.Scan == 0 && msg.Key == "" {
+		return input.KeyEvent{}, false
+	}
+	return input.KeyEvent{
+		Code:  msg.Code,
+		Scan:  msg.Scan,
+		Key:   msg.Key,
+		Down:  msg.Down,
+		Ctrl:  msg.Ctrl,
+		Shift: msg.Shift,
+		Alt:   msg.Alt,
+		Meta:  msg.Meta,
+	}, true
+}
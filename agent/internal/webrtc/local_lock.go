@@ -0,0 +1,214 @@
+package webrtc
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/locallock"
+)
+
+// activeLocalLock is the local input lock the controller asked for.
+type activeLocalLock struct {
+	until     time.Time
+	blanked   bool
+	forwarded bool // held by the Session 0 capture helper
+	stop      chan struct{}
+}
+
+// localLockForwarded reports whether the lock must be taken by the capture
+// helper: a service in Session 0 cannot hook the user's input or cover the
+// user's monitors itself.
+func (m *Manager) localLockForwarded() bool {
+	return m.isSession0 && m.screenCapturer != nil && m.screenCapturer.HasInputForwarder()
+}
+
+// handleLocalLock locks or unlocks the local keyboard and mouse (see
+// protocol.LocalLock) and answers with the lock state.
+func (m *Manager) handleLocalLock(data []byte) {
+	var req protocol.LocalLock
+	if err := protocol.Decode(data, &req); err != nil {
+		m.sendControl(protocol.LocalLockState{Type: protocol.TypeLocalLockState, Error: fmt.Sprintf("invalid local lock: %v", err)})
+		return
+	}
+
+	if !req.Enabled {
+		released := m.releaseLocalLock()
+		log.Printf("🔓 Local lock released by controller (was locked: %v)", released)
+		if released && m.supportIsActive() {
+			go func() {
+				_ = m.recordSupportAction("ADMIN_LOCAL_UNLOCK", "succeeded", "Local input unlocked", "local_input", map[string]interface{}{
+					"reason": protocol.LockReleasedController,
+				})
+			}()
+		}
+		m.sendControl(protocol.LocalLockState{Type: protocol.TypeLocalLockState, Released: protocol.LockReleasedController})
+		return
+	}
+
+	timeout := req.LockTimeout()
+	operation := "lock"
+	if req.Blank {
+		operation = "lock_blank"
+	}
+	if m.supportIsActive() {
+		if err := m.recordSupportAction("ADMIN_LOCAL_LOCK", "succeeded", "Local input locked", "local_input", map[string]interface{}{
+			"operation":   operation,
+			"duration_ms": timeout * 1000,
+		}); err != nil {
+			m.sendControl(protocol.LocalLockState{Type: protocol.TypeLocalLockState, Error: err.Error()})
+			return
+		}
+	}
+
+	l, err := m.startLocalLock(req.Blank, req.Message, time.Now().Add(time.Duration(timeout)*time.Second))
+	if err != nil {
+		log.Printf("⚠️ Local lock failed: %v", err)
+		m.sendControl(protocol.LocalLockState{Type: protocol.TypeLocalLockState, Error: err.Error()})
+		return
+	}
+	if req.Blank && !l.blanked {
+		log.Println("⚠️ Local lock: monitors could not be covered without hiding them from capture")
+	}
+	if m.StatusCallback != nil {
+		m.StatusCallback("🔒 Lokal input spærret — " + locallock.EmergencyCombo + " frigiver")
+	}
+	m.sendControl(protocol.LocalLockState{
+		Type:    protocol.TypeLocalLockState,
+		Enabled: true,
+		Blanked: l.blanked,
+		Until:   l.until.Unix(),
+		Combo:   locallock.EmergencyCombo,
+	})
+}
+
+// startLocalLock takes the lock, replacing one already in force, and
+// watches it until it ends.
+func (m *Manager) startLocalLock(blank bool, message string, until time.Time) (*activeLocalLock, error) {
+	if !locallock.Supported() {
+		return nil, locallock.ErrUnsupported
+	}
+	m.releaseLocalLock()
+
+	l := &activeLocalLock{until: until, forwarded: m.localLockForwarded(), stop: make(chan struct{})}
+	var err error
+	if l.forwarded {
+		l.blanked, err = m.screenCapturer.ForwardLocalLock(true, blank, message, until)
+	} else {
+		l.blanked, err = locallock.Lock(locallock.Options{Blank: blank, Message: message, Until: until}, func(reason string) {
+			m.localLockEnded(l, reason)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.localLockMu.Lock()
+	m.localLock = l
+	m.localLockMu.Unlock()
+	go m.watchLocalLock(l)
+	return l, nil
+}
+
+// watchLocalLock notices a lock the helper let go by itself, and releases
+// a lock that outlived its deadline in case the lock thread missed it.
+func (m *Manager) watchLocalLock(l *activeLocalLock) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		if l.forwarded {
+			locked, reason, err := m.screenCapturer.ForwardLocalLockState()
+			if err == nil && !locked {
+				if reason == "" {
+					// The helper was relaunched too late to restore the lock
+					reason = protocol.LockReleasedTimeout
+				}
+				m.localLockEnded(l, reason)
+				return
+			}
+		}
+		if time.Now().After(l.until.Add(5 * time.Second)) {
+			if m.takeLocalLock(l) {
+				m.unlockLocal(l)
+				m.notifyLocalLockEnded(protocol.LockReleasedTimeout)
+			}
+			return
+		}
+	}
+}
+
+// localLockEnded handles a lock that ended without the controller asking:
+// on timeout or the local user's emergency combination.
+func (m *Manager) localLockEnded(l *activeLocalLock, reason string) {
+	if m.takeLocalLock(l) {
+		m.notifyLocalLockEnded(reason)
+	}
+}
+
+// takeLocalLock clears l if it is still the lock in force.
+func (m *Manager) takeLocalLock(l *activeLocalLock) bool {
+	m.localLockMu.Lock()
+	defer m.localLockMu.Unlock()
+	if m.localLock != l {
+		return false
+	}
+	m.localLock = nil
+	close(l.stop)
+	return true
+}
+
+func (m *Manager) notifyLocalLockEnded(reason string) {
+	log.Printf("🔓 Local lock ended (%s)", reason)
+	if m.StatusCallback != nil && m.isStreaming.Load() {
+		m.StatusCallback("Forbundet")
+	}
+	if m.supportIsActive() {
+		go func() {
+			_ = m.recordSupportAction("ADMIN_LOCAL_UNLOCK", "succeeded", "Local input unlocked", "local_input", map[string]interface{}{
+				"reason": reason,
+			})
+		}()
+	}
+	if m.protoSession().Has(protocol.CapLocalLock) {
+		m.sendControl(protocol.LocalLockState{Type: protocol.TypeLocalLockState, Released: reason})
+	}
+}
+
+// releaseLocalLock lets go of the lock in force, if any, and reports
+// whether there was one. The tray is restored; the controller is not told.
+func (m *Manager) releaseLocalLock() bool {
+	m.localLockMu.Lock()
+	l := m.localLock
+	m.localLock = nil
+	if l != nil {
+		close(l.stop)
+	}
+	m.localLockMu.Unlock()
+	if l == nil {
+		return false
+	}
+	m.unlockLocal(l)
+	if m.StatusCallback != nil && m.isStreaming.Load() {
+		m.StatusCallback("Forbundet")
+	}
+	return true
+}
+
+func (m *Manager) unlockLocal(l *activeLocalLock) {
+	if !l.forwarded {
+		locallock.Unlock()
+		return
+	}
+	if m.screenCapturer == nil {
+		return
+	}
+	if _, err := m.screenCapturer.ForwardLocalLock(false, false, "", time.Time{}); err != nil {
+		log.Printf("⚠️ Local unlock via helper failed: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/logs"
+)
+
+// maxLogFollows bounds the followed log queries on one process channel.
+const maxLogFollows = 4
+
+// logFollows tracks the followed log queries of one process channel by ID.
+type logFollows struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func (lf *logFollows) start(id string, cancel context.CancelFunc) error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if lf.cancels == nil {
+		lf.cancels = map[string]context.CancelFunc{}
+	}
+	if _, ok := lf.cancels[id]; ok {
+		return fmt.Errorf("log follow %s is already running", id)
+	}
+	if len(lf.cancels) >= maxLogFollows {
+		return fmt.Errorf("at most %d log follows per connection", maxLogFollows)
+	}
+	lf.cancels[id] = cancel
+	return nil
+}
+
+func (lf *logFollows) stop(id string) bool {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	cancel, ok := lf.cancels[id]
+	if ok {
+		cancel()
+		delete(lf.cancels, id)
+	}
+	return ok
+}
+
+func (lf *logFollows) stopAll() {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	for id, cancel := range lf.cancels {
+		cancel()
+		delete(lf.cancels, id)
+	}
+}
+
+// sendLogError is sendProcessError with the request's ID, so the reply
+// reaches the caller waiting on it.
+func sendLogError(dc *pionwebrtc.DataChannel, id, msg string) {
+	_ = sendProcessReply(dc, map[string]interface{}{"op": "error", "id": id, "error": msg})
+	log.Printf("⚠️ Process error: %s", msg)
+}
+
+func logQuery(req protocol.LogRequest) logs.Query {
+	q := logs.Query{Source: req.Source, Level: req.Level, Text: req.Text, Limit: req.Limit}
+	if req.Channel != "" {
+		q.Channels = strings.Split(req.Channel, ",")
+	}
+	now := time.Now()
+	if req.SinceSec > 0 {
+		q.Since = now.Add(-time.Duration(req.SinceSec * float64(time.Second)))
+	}
+	if req.UntilSec > 0 {
+		q.Until = now.Add(-time.Duration(req.UntilSec * float64(time.Second)))
+	}
+	return q
+}
+
+func toProtocolEntries(entries []logs.Entry) []protocol.LogEntry {
+	out := make([]protocol.LogEntry, len(entries))
+	for i, e := range entries {
+		out[i] = protocol.LogEntry(e)
+	}
+	return out
+}
+
+// handleLogQuery answers a log_query with the newest matching entries and,
+// with Follow set, keeps sending new ones from a goroutine until log_stop
+// or the channel closes.
+func (m *Manager) handleLogQuery(dc *pionwebrtc.DataChannel, req protocol.LogRequest, follows *logFollows) error {
+	if req.Follow && req.ID == "" {
+		sendProcessError(dc, "log follow needs an id")
+		return fmt.Errorf("log follow without id")
+	}
+	q := logQuery(req)
+	entries, err := logs.Read(q)
+	if err != nil {
+		sendLogError(dc, req.ID, err.Error())
+		return err
+	}
+	runs := protocol.SplitLogEntries(toProtocolEntries(entries))
+	for i, run := range runs {
+		if err := sendProcessReply(dc, protocol.LogQueryResult{
+			Op: protocol.OpLogQueryResult, ID: req.ID, Entries: run, More: i < len(runs)-1,
+		}); err != nil {
+			return err
+		}
+	}
+	if !req.Follow {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := follows.start(req.ID, cancel); err != nil {
+		cancel()
+		sendLogError(dc, req.ID, err.Error())
+		return err
+	}
+	log.Printf("📜 Following system log (%s)", req.ID)
+	go func() {
+		defer follows.stop(req.ID)
+		// New entries are newer than the backlog; only the filters carry over.
+		q.Since, q.Until = time.Time{}, time.Time{}
+		err := logs.Follow(ctx, q, func(entries []logs.Entry, dropped int) error {
+			runs := protocol.SplitLogEntries(toProtocolEntries(entries))
+			for i, run := range runs {
+				msg := protocol.LogEntries{Op: protocol.OpLogEntries, ID: req.ID, Entries: run}
+				if i == len(runs)-1 {
+					msg.Dropped = dropped
+				}
+				if err := sendProcessReply(dc, msg); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		done := protocol.LogEntries{Op: protocol.OpLogEntries, ID: req.ID, Done: true}
+		if err != nil {
+			done.Error = err.Error()
+			log.Printf("⚠️ System log follow %s ended: %v", req.ID, err)
+		}
+		_ = sendProcessReply(dc, done)
+		log.Printf("📜 Stopped following system log (%s)", req.ID)
+	}()
+	return nil
+}
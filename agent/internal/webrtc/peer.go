@@ -17,6 +17,8 @@ import (
 	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/Remote/scriptlib"
 	"github.com/stangtennis/remote-agent/internal/audio"
 	"github.com/stangtennis/remote-agent/internal/auth"
 	"github.com/stangtennis/remote-agent/internal/clipboard"
@@ -27,8 +29,10 @@ import (
 	"github.com/stangtennis/remote-agent/internal/input"
 	"github.com/stangtennis/remote-agent/internal/metrics"
 	"github.com/stangtennis/remote-agent/internal/monitor"
+	"github.com/stangtennis/remote-agent/internal/printer"
 	"github.com/stangtennis/remote-agent/internal/screen"
 	"github.com/stangtennis/remote-agent/internal/terminal"
+	"github.com/stangtennis/remote-agent/internal/timeline"
 	"github.com/stangtennis/remote-agent/internal/updater"
 	"github.com/stangtennis/remote-agent/internal/version"
 	"github.com/stangtennis/remote-agent/internal/video"
@@ -79,6 +83,25 @@ type Manager struct {
 	pendingCandidates []*pionwebrtc.ICECandidate // Buffer ICE candidates until answer is sent
 	answerSent        bool                       // Flag to track if answer has been sent
 	iceStopCh         chan struct{}              // Closed to stop ICE polling goroutine
+	iceRestartMu      sync.Mutex                 // Serialises ICE restart renegotiation
+	iceRestarts       map[string]string          // restart_id -> answer SDP (offer may arrive via control channel and signaling)
+	lastICERestart    atomic.Int64               // UnixNano of last answered ICE restart; extends the disconnect grace period
+
+	// Stats timeline for the current session (nil when idle)
+	timeline atomic.Pointer[timeline.Recorder]
+
+	// Negotiated wire protocol for the current session (nil until the peer
+	// sends hello; a peer that never does is treated as legacy)
+	peerProto atomic.Pointer[protocol.Session]
+
+	// Keyboard layout last reported to a CapKeyLayout controller, and when
+	// it was last checked for changes
+	keyboardLayout  atomic.Pointer[input.Layout]
+	layoutCheckedAt atomic.Int64
+
+	// Clipboard policy the controller set for the current session (nil
+	// allows everything; see clipboard_policy.go)
+	clipboardPolicy atomic.Pointer[protocol.ClipboardPolicy]
 
 	// RTT measurement (protected by statsMu)
 	lastRTT       time.Duration // Last measured round-trip time
@@ -117,6 +140,15 @@ type Manager struct {
 	shellOnce    sync.Once
 	shellSt      *shellState
 
+	// Virtual printer (nil unless installed; see print_handler.go)
+	printSpooler *printer.Spooler
+	printChannel *pionwebrtc.DataChannel
+	printEnabled atomic.Bool
+
+	// Local input lock (see local_lock.go)
+	localLock   *activeLocalLock
+	localLockMu sync.Mutex
+
 	// System monitoring
 	cpuMonitor *monitor.CPUMonitor
 
@@ -153,6 +185,7 @@ type Manager struct {
 	supportGrant     string
 	supportScopes    map[string]bool
 	supportExpiresAt time.Time
+	supportClipboard protocol.ClipboardPolicy
 	supportAuthMu    sync.RWMutex
 
 	// Shared HTTP client with connection pooling (reused across all requests)
@@ -244,6 +277,11 @@ func New(cfg *config.Config, dev *device.Device, tokenProvider *auth.TokenProvid
 	fileTransferHandler := filetransfer.NewHandler(downloadDir)
 	log.Printf("✅ File transfer handler initialized: %s", downloadDir)
 
+	if len(scriptlib.AgentTrustedKeys()) == 0 {
+		log.Println("⚠️  No script signing keys trusted — library scripts and queued run_script jobs will be refused")
+		log.Println("   Set RD_SCRIPT_SIGNING_KEYS (public key from `remote-desktop-cli scripts keygen`)")
+	}
+
 	// Initialize video encoder
 	videoEncoder := encoder.NewManager()
 	if err := videoEncoder.Init(encoder.Config{
@@ -299,6 +337,8 @@ func New(cfg *config.Config, dev *device.Device, tokenProvider *auth.TokenProvid
 	// Start desktop monitoring to handle login/logout transitions
 	go mgr.monitorDesktopChanges()
 
+	mgr.startPrintSpooler()
+
 	return mgr, nil
 }
 
@@ -417,8 +457,11 @@ func (m *Manager) CreatePeerConnection(iceServers []pionwebrtc.ICEServer) error
 	m.videoChannel = nil
 	m.fileChannel = nil
 	m.terminalChannel = nil
+	m.printChannel = nil
 	m.mu.Unlock()
+	m.printEnabled.Store(false)
 	m.peerConnection = pc
+	m.peerProto.Store(nil)
 
 	// Always add video track (even if not using H.264 yet)
 	// This allows mode switching without renegotiation
@@ -503,6 +546,8 @@ func (m *Manager) CreatePeerConnection(iceServers []pionwebrtc.ICEServer) error
 			}
 			m.connCtx, m.connCancel = context.WithCancel(context.Background())
 			m.isStreaming.Store(true)
+			m.ensureTimeline()
+			m.recordTimelineEvent("connected", "")
 			// Hide local cursor during remote session
 			if m.mouseController != nil {
 				m.mouseController.HideCursor()
@@ -533,6 +578,7 @@ func (m *Manager) CreatePeerConnection(iceServers []pionwebrtc.ICEServer) error
 				m.StatusCallback("Afbrudt — venter på genforbindelse...")
 			}
 			m.isStreaming.Store(false) // Stop sending frames during recovery
+			m.recordTimelineEvent("disconnected", "")
 			// Cancel previous streaming, start grace period with new context
 			if m.connCancel != nil {
 				m.connCancel()
@@ -548,6 +594,11 @@ func (m *Manager) CreatePeerConnection(iceServers []pionwebrtc.ICEServer) error
 				m.handleDisconnectGracePeriod(m.connCtx)
 			}()
 		case pionwebrtc.PeerConnectionStateFailed:
+			if isCurrentPC && m.iceRestartInProgress() {
+				// The grace period goroutine owns cleanup while a restart is negotiating
+				log.Println("⚠️  WebRTC FAILED during ICE restart - waiting for new candidates...")
+				return
+			}
 			log.Println("❌ WebRTC CONNECTION FAILED")
 			if m.StatusCallback != nil {
 				m.StatusCallback("Status: Online (ingen forbindelse)")
@@ -632,6 +683,12 @@ func (m *Manager) CreatePeerConnection(iceServers []pionwebrtc.ICEServer) error
 			log.Println("🐚 Shell channel ready")
 			m.shellChannel = dc
 			m.setupShellChannelHandlers(dc)
+		case "print":
+			log.Println("🖨️ Print channel ready")
+			m.mu.Lock()
+			m.printChannel = dc
+			m.mu.Unlock()
+			m.setupPrintChannelHandlers(dc)
 		case "chat":
 			log.Println("💬 Chat channel ready")
 			dc.OnOpen(func() {
@@ -691,13 +748,12 @@ func (m *Manager) setupDataChannelHandlers(dc *pionwebrtc.DataChannel) {
 
 	dc.OnMessage(func(msg pionwebrtc.DataChannelMessage) {
 		// Handle control events from dashboard
-		var event map[string]interface{}
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			log.Printf("Failed to parse control event: %v", err)
+		if !json.Valid(msg.Data) {
+			log.Printf("Failed to parse control event (%d bytes)", len(msg.Data))
 			return
 		}
 
-		m.handleControlEvent(event)
+		m.handleControlEvent(protocol.MsgType(msg.Data), msg.Data)
 	})
 }
 
@@ -845,70 +901,78 @@ func (m *Manager) setupControlChannelHandlers(dc *pionwebrtc.DataChannel) {
 	})
 
 	dc.OnMessage(func(msg pionwebrtc.DataChannelMessage) {
-		// Handle input events with priority
-		var event map[string]interface{}
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
+		// Input events go first, with priority; everything else is a
+		// control message from the controller.
+		msgType := protocol.MsgType(msg.Data)
+		switch msgType {
+		case "":
 			return
-		}
-
-		msgType := ""
-		if v, ok := event["type"].(string); ok && v != "" {
-			msgType = v
-		} else if v, ok := event["t"].(string); ok && v != "" {
-			msgType = v
-		}
-
-		// Check for control messages from controller
-		if msgType != "" {
-			switch msgType {
-			case "clipboard_text":
-				if m.supportIsActive() && !m.supportAllows("input") {
-					return
-				}
-				if content, ok := event["content"].(string); ok {
-					log.Printf("📋 Received clipboard text from controller (%d bytes)", len(content))
-					m.handleClipboardText(content)
-				}
+		case "mouse_move", "mouse_click", "mouse_scroll", "key", "ping", protocol.TypeTouch, protocol.TypePen:
+			m.handleInputEvent(msgType, msg.Data)
+		case "clipboard_text":
+			if m.supportIsActive() && !m.supportAllows("input") {
 				return
-			case "clipboard_image":
-				if m.supportIsActive() && !m.supportAllows("input") {
-					return
-				}
-				if contentB64, ok := event["content"].(string); ok {
-					log.Printf("📋 Received clipboard image from controller")
-					m.handleClipboardImage(contentB64)
-				}
+			}
+			var clip protocol.Clipboard
+			if protocol.Decode(msg.Data, &clip) == nil && clip.Content != "" {
+				log.Printf("📋 Received clipboard text from controller (%d bytes)", len(clip.Content))
+				m.handleClipboardText(clip.Content)
+			}
+		case "clipboard_image":
+			if m.supportIsActive() && !m.supportAllows("input") {
 				return
-			case "set_stream_params":
-				if m.supportIsActive() && !m.supportAllows("screen") {
-					return
-				}
-				m.handleSetStreamParams(event)
+			}
+			var clip protocol.Clipboard
+			if protocol.Decode(msg.Data, &clip) == nil && clip.Content != "" {
+				log.Printf("📋 Received clipboard image from controller")
+				m.handleClipboardImage(clip.Content)
+			}
+		case protocol.TypeClipboardFiles:
+			if m.supportIsActive() && !m.supportAllows("files") {
 				return
-			case "set_mode", "switch_monitor", "force_update", "remote_login", "release_all_keys":
-				// Control-plane events: route via handleControlEvent.
-				// set_mode aktiverer H.264-streaming. v3.1.13 routede dette
-				// men H.264-frames decodede ikke i WebView2 → black screen.
-				// v3.1.20 fixer NVENC til at repeat SPS+PPS ved hver
-				// keyframe (-bsf:v dump_extra=freq=keyframe) så browser-
-				// decoder altid kan initialiseres. Re-enabled nu.
-				m.handleControlEvent(event)
+			}
+			log.Printf("📋 Received clipboard files from controller")
+			m.handleClipboardFiles(msg.Data)
+		case protocol.TypeClipboardHTML, protocol.TypeClipboardRTF:
+			if m.supportIsActive() && !m.supportAllows("input") {
+				return
+			}
+			log.Printf("📋 Received clipboard %s from controller", msgType)
+			m.handleClipboardRich(msg.Data)
+		case "set_stream_params":
+			if m.supportIsActive() && !m.supportAllows("screen") {
 				return
 			}
+			m.handleSetStreamParams(msg.Data)
+		case "ice_restart_offer":
+			// Answering waits for ICE gathering — keep the channel read loop free
+			go m.handleICERestartOffer(dc, msg.Data)
+		default:
+			// Every other control-plane event (hello, set_mode,
+			// stream_pause, ...) goes via handleControlEvent, which
+			// answers unknown types with "unsupported" instead of
+			// dropping them.
+			// set_mode aktiverer H.264-streaming. v3.1.13 routede dette
+			// men H.264-frames decodede ikke i WebView2 → black screen.
+			// v3.1.20 fixer NVENC til at repeat SPS+PPS ved hver
+			// keyframe (-bsf:v dump_extra=freq=keyframe) så browser-
+			// decoder altid kan initialiseres. Re-enabled nu.
+			m.handleControlEvent(msgType, msg.Data)
 		}
-
-		// Handle input events on control channel
-		m.handleInputEvent(event)
 	})
 }
 
-// handleDisconnectGracePeriod waits up to 8 seconds for ICE to self-recover
-// before cleaning up the connection. Checks every 500ms.
+// handleDisconnectGracePeriod waits up to 12 seconds for ICE to self-recover
+// before cleaning up the connection. Checks every 500ms and polls signaling
+// for ICE restart offers from the controller; once a restart is answered the
+// deadline is extended so a network handover (Wi-Fi → LTE) keeps the session,
+// its terminal, shell execs and file transfers.
 // The context is cancelled when a new connection state change supersedes this grace period.
 func (m *Manager) handleDisconnectGracePeriod(ctx context.Context) {
-	const gracePeriod = 8 * time.Second
+	const gracePeriod = 12 * time.Second
 	const checkInterval = 500 * time.Millisecond
 	deadline := time.Now().Add(gracePeriod)
+	checks := 0
 
 	for time.Now().Before(deadline) {
 		select {
@@ -927,6 +991,18 @@ func (m *Manager) handleDisconnectGracePeriod(ctx context.Context) {
 			break
 		}
 
+		// Poll for restart offers once a second
+		checks++
+		if checks%2 == 0 {
+			m.pollICERestartOffers()
+		}
+		if last := m.lastICERestart.Load(); last != 0 {
+			if extended := time.Unix(0, last).Add(iceRestartGracePeriod); extended.After(deadline) {
+				log.Println("🧊 ICE restart in progress - extending grace period")
+				deadline = extended
+			}
+		}
+
 		state := pc.ConnectionState()
 		switch state {
 		case pionwebrtc.PeerConnectionStateConnected:
@@ -936,6 +1012,9 @@ func (m *Manager) handleDisconnectGracePeriod(ctx context.Context) {
 			log.Println("✅ ICE recovered during grace period (handler will start streaming)")
 			return
 		case pionwebrtc.PeerConnectionStateFailed, pionwebrtc.PeerConnectionStateClosed:
+			if state == pionwebrtc.PeerConnectionStateFailed && m.iceRestartInProgress() {
+				continue // restarted ICE agent may still connect
+			}
 			log.Printf("❌ Connection state became %s during grace period", state.String())
 			if m.mouseController != nil {
 				m.mouseController.ShowCursor()
@@ -946,7 +1025,7 @@ func (m *Manager) handleDisconnectGracePeriod(ctx context.Context) {
 		// Still disconnected - keep waiting
 	}
 
-	log.Println("⏰ Grace period expired (8s) - cleaning up connection")
+	log.Println("⏰ Grace period expired - cleaning up connection")
 	if m.mouseController != nil {
 		m.mouseController.ShowCursor()
 	}
@@ -977,6 +1056,7 @@ func (m *Manager) cleanupConnection(reason string) {
 	if m.sessionID != "" {
 		m.updateSessionStatus("ended")
 	}
+	m.finishTimeline(reason)
 
 	// Stop ICE polling goroutine from previous session
 	m.closeIceStopCh()
@@ -1003,11 +1083,19 @@ func (m *Manager) cleanupConnection(reason string) {
 		m.terminalChannel.Close()
 		m.terminalChannel = nil
 	}
+	if m.printChannel != nil {
+		m.printChannel.Close()
+		m.printChannel = nil
+	}
+	m.printEnabled.Store(false)
 
 	pc := m.peerConnection
 	m.peerConnection = nil
 	m.mu.Unlock()
 
+	// Never leave the local user locked out after the session
+	m.releaseLocalLock()
+
 	// Close terminal session if active
 	if m.terminal != nil {
 		m.terminal.Close()
@@ -1022,6 +1110,11 @@ func (m *Manager) cleanupConnection(reason string) {
 	// Reset session ID for next connection
 	m.sessionID = ""
 
+	m.iceRestartMu.Lock()
+	m.iceRestarts = nil
+	m.iceRestartMu.Unlock()
+	m.lastICERestart.Store(0)
+
 	log.Println("✅ Connection cleaned up - ready for new connections")
 }
 
@@ -1137,10 +1230,10 @@ func (m *Manager) Close() {
 func (m *Manager) handleForceUpdate() {
 	// Send status back to dashboard
 	sendStatus := func(status, message string) {
-		msg := map[string]interface{}{
-			"type":    "update_status",
-			"status":  status,
-			"message": message,
+		msg := protocol.UpdateStatus{
+			Type:    protocol.TypeUpdateStatus,
+			Status:  status,
+			Message: message,
 		}
 		if data, err := json.Marshal(msg); err == nil {
 			if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
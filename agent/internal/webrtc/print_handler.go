@@ -0,0 +1,202 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/printer"
+)
+
+// printBufferLimit pauses sending a job while this much is queued on the
+// print channel, so a large job does not swamp the connection.
+const printBufferLimit = 4 * 1024 * 1024
+
+// startPrintSpooler captures jobs from the virtual printer when it is
+// installed (remote-agent -install-printer).
+func (m *Manager) startPrintSpooler() {
+	if !printer.Installed() {
+		return
+	}
+	spooler := printer.NewSpooler()
+	spooler.SetOnJob(m.offerPrintJob)
+	if err := spooler.Start(); err != nil {
+		log.Printf("⚠️ Virtual printer not available: %v", err)
+		return
+	}
+	m.printSpooler = spooler
+}
+
+// setupPrintChannelHandlers wires the "print" data channel. Jobs are only
+// offered after the controller sends print_enable; each one is sent only
+// when the controller accepts it (see protocol/print.go).
+func (m *Manager) setupPrintChannelHandlers(dc *pionwebrtc.DataChannel) {
+	dc.OnOpen(func() {
+		log.Println("🖨️ Print channel open")
+	})
+
+	dc.OnClose(func() {
+		log.Println("🖨️ Print channel closed")
+		m.printEnabled.Store(false)
+	})
+
+	dc.OnMessage(func(msg pionwebrtc.DataChannelMessage) {
+		if m.supportIsActive() && !m.supportAllows("print") {
+			sendPrintError(dc, "", "print scope denied")
+			return
+		}
+		var req protocol.PrintRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			log.Printf("⚠️ Invalid print message: %v", err)
+			return
+		}
+
+		switch req.Op {
+		case protocol.OpPrintEnable:
+			m.handlePrintEnable(dc, true)
+		case protocol.OpPrintDisable:
+			m.handlePrintEnable(dc, false)
+		case protocol.OpPrintAccept:
+			go m.sendPrintJob(dc, req.ID)
+		case protocol.OpPrintDecline:
+			m.finishPrintJob(req.ID, "cancelled", "declined")
+		case protocol.OpPrintDone:
+			if req.Error != "" {
+				m.finishPrintJob(req.ID, "failed", req.Error)
+			} else {
+				m.finishPrintJob(req.ID, "succeeded", "")
+			}
+		default:
+			sendPrintError(dc, req.ID, "unknown op: "+req.Op)
+		}
+	})
+}
+
+// handlePrintEnable turns job forwarding on or off for this session and
+// offers the jobs already waiting.
+func (m *Manager) handlePrintEnable(dc *pionwebrtc.DataChannel, enable bool) {
+	state := protocol.PrintState{
+		Op:        protocol.OpPrintState,
+		Installed: m.printSpooler != nil,
+		Printer:   printer.Name,
+	}
+	if m.printSpooler == nil {
+		if enable {
+			state.Error = "virtual printer is not installed on this device"
+		}
+		sendPrintMsg(dc, state)
+		return
+	}
+	if enable && m.supportIsActive() {
+		if err := m.recordSupportAction("PRINT_ENABLE", "succeeded", "Print forwarding enabled", printer.Name, map[string]interface{}{}); err != nil {
+			state.Error = err.Error()
+			sendPrintMsg(dc, state)
+			return
+		}
+	}
+	m.printEnabled.Store(enable)
+	state.Enabled = enable
+	log.Printf("🖨️ Print forwarding enabled: %v", enable)
+	sendPrintMsg(dc, state)
+
+	if enable {
+		for _, job := range m.printSpooler.Jobs() {
+			sendPrintMsg(dc, job.Message())
+		}
+	}
+}
+
+// offerPrintJob offers a newly captured job to the controller, if it
+// asked for jobs. Otherwise the job waits in the spool.
+func (m *Manager) offerPrintJob(job printer.Job) {
+	if !m.printEnabled.Load() {
+		return
+	}
+	m.mu.Lock()
+	dc := m.printChannel
+	m.mu.Unlock()
+	if dc != nil {
+		sendPrintMsg(dc, job.Message())
+	}
+}
+
+// sendPrintJob streams an accepted job in print_data chunks.
+func (m *Manager) sendPrintJob(dc *pionwebrtc.DataChannel, id string) {
+	if !m.printEnabled.Load() || m.printSpooler == nil {
+		sendPrintError(dc, id, "print forwarding is not enabled")
+		return
+	}
+	f, job, err := m.printSpooler.Open(id)
+	if err != nil {
+		sendPrintError(dc, id, err.Error())
+		return
+	}
+	defer f.Close()
+
+	total := int((job.Size + protocol.PrintChunkSize - 1) / protocol.PrintChunkSize)
+	buf := make([]byte, protocol.PrintChunkSize)
+	for c := 0; c < total; c++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			sendPrintError(dc, id, err.Error())
+			return
+		}
+		for dc.BufferedAmount() > printBufferLimit {
+			if dc.ReadyState() != pionwebrtc.DataChannelStateOpen {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		sendPrintMsg(dc, protocol.PrintChunk{Op: protocol.OpPrintData, ID: id, C: c, T: total, Data: buf[:n]})
+	}
+	log.Printf("🖨️ Print job sent: %q (%d bytes)", job.Title, job.Size)
+}
+
+// finishPrintJob drops a job the controller is done with, and audits the
+// outcome in support sessions.
+func (m *Manager) finishPrintJob(id, status, reason string) {
+	if m.printSpooler == nil {
+		return
+	}
+	var job printer.Job
+	for _, j := range m.printSpooler.Jobs() {
+		if j.ID == id {
+			job = j
+		}
+	}
+	if job.ID == "" {
+		return
+	}
+	m.printSpooler.Remove(id)
+	log.Printf("🖨️ Print job %q %s %s", job.Title, status, reason)
+	if m.supportIsActive() {
+		details := map[string]interface{}{"bytes": job.Size, "operation": job.Format}
+		switch {
+		case status == "failed":
+			details["error"] = reason
+		case reason != "":
+			details["reason"] = reason
+		}
+		go func() {
+			_ = m.recordSupportAction("PRINT_JOB", status, "Print job forwarded to support", job.Title, details)
+		}()
+	}
+}
+
+func sendPrintError(dc *pionwebrtc.DataChannel, id, errMsg string) {
+	sendPrintMsg(dc, map[string]interface{}{"op": "error", "id": id, "error": errMsg})
+}
+
+func sendPrintMsg(dc *pionwebrtc.DataChannel, msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("⚠️ print: marshal error: %v", err)
+		return
+	}
+	if err := dc.Send(data); err != nil {
+		log.Printf("⚠️ print: send error: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
 	"github.com/stangtennis/remote-agent/internal/process"
 	"github.com/stangtennis/remote-agent/internal/sysinfo"
 )
@@ -18,6 +19,11 @@ func (m *Manager) setupProcessChannelHandlers(dc *pionwebrtc.DataChannel) {
 	dc.OnOpen(func() {
 		log.Println("⚙️ Process channel open")
 	})
+	var regImp regImport
+	var follows logFollows
+	dc.OnClose(func() {
+		follows.stopAll()
+	})
 
 	dc.OnMessage(func(msg pionwebrtc.DataChannelMessage) {
 		if m.supportIsActive() && !m.supportAllows("process") {
@@ -31,10 +37,70 @@ func (m *Manager) setupProcessChannelHandlers(dc *pionwebrtc.DataChannel) {
 		}
 
 		op, _ := message["op"].(string)
+		details := map[string]interface{}{}
+		var regReq protocol.RegRequest
+		var importText string
+		if strings.HasPrefix(op, "reg_") {
+			if err := protocol.Decode(msg.Data, &regReq); err != nil {
+				sendProcessError(dc, "invalid registry request: "+err.Error())
+				return
+			}
+			if (op == protocol.OpRegSet || op == protocol.OpRegDelete || op == protocol.OpRegImport) &&
+				m.supportIsActive() && !m.supportAllows("admin") {
+				sendProcessError(dc, "admin scope required to change the registry")
+				return
+			}
+			if op == protocol.OpRegImport {
+				text, done, err := regImp.add(regReq)
+				if err != nil {
+					sendProcessError(dc, err.Error())
+					return
+				}
+				if !done {
+					return // wait for the rest of the .reg file
+				}
+				importText = text
+			}
+			if regReq.Path != "" {
+				details["path"] = regReq.Path
+			}
+		}
+		var svcReq protocol.SvcRequest
+		if strings.HasPrefix(op, "svc_") {
+			if err := protocol.Decode(msg.Data, &svcReq); err != nil {
+				sendProcessError(dc, "invalid service request: "+err.Error())
+				return
+			}
+			if op != protocol.OpSvcList && m.supportIsActive() && !m.supportAllows("admin") {
+				sendProcessError(dc, "admin scope required to control services")
+				return
+			}
+			if svcReq.Name != "" {
+				details["service"] = svcReq.Name
+			}
+			if svcReq.Startup != "" {
+				details["operation"] = svcReq.Startup
+			}
+		}
+		var logReq protocol.LogRequest
+		if strings.HasPrefix(op, "log_") {
+			if err := protocol.Decode(msg.Data, &logReq); err != nil {
+				sendProcessError(dc, "invalid log request: "+err.Error())
+				return
+			}
+			switch {
+			case op == protocol.OpLogStop:
+				details["operation"] = "stop"
+			case logReq.Follow:
+				details["operation"] = "follow"
+			default:
+				details["operation"] = "query"
+			}
+		}
 		actionType := "PROCESS_" + strings.ToUpper(op)
 		var opErr error
 		if m.supportIsActive() {
-			if err := m.recordSupportAction(actionType, "started", "Started process operation", op, map[string]interface{}{}); err != nil {
+			if err := m.recordSupportAction(actionType, "started", "Started process operation", op, details); err != nil {
 				sendProcessError(dc, err.Error())
 				return
 			}
@@ -51,6 +117,23 @@ func (m *Manager) setupProcessChannelHandlers(dc *pionwebrtc.DataChannel) {
 			opErr = m.handleProcessKill(dc, int(pidVal))
 		case "sysinfo":
 			opErr = m.handleSysinfo(dc)
+		case "stats":
+			sinceSec, _ := message["since_sec"].(float64)
+			opErr = m.handleSessionStats(dc, sinceSec)
+		case protocol.OpRegList, protocol.OpRegGet, protocol.OpRegSet, protocol.OpRegDelete,
+			protocol.OpRegExport, protocol.OpRegImport:
+			opErr = m.handleRegistry(dc, regReq, importText)
+		case protocol.OpSvcList:
+			opErr = m.handleServiceList(dc)
+		case protocol.OpSvcStart, protocol.OpSvcStop, protocol.OpSvcRestart, protocol.OpSvcSetStartup:
+			opErr = m.handleServiceControl(dc, svcReq)
+		case protocol.OpLogQuery:
+			opErr = m.handleLogQuery(dc, logReq, &follows)
+		case protocol.OpLogStop:
+			if !follows.stop(logReq.ID) {
+				opErr = fmt.Errorf("no log follow %s", logReq.ID)
+				sendLogError(dc, logReq.ID, opErr.Error())
+			}
 		default:
 			opErr = fmt.Errorf("unknown op: %s", op)
 			sendProcessError(dc, opErr.Error())
@@ -60,7 +143,7 @@ func (m *Manager) setupProcessChannelHandlers(dc *pionwebrtc.DataChannel) {
 			if opErr != nil {
 				status = "failed"
 			}
-			_ = m.recordSupportAction(actionType, status, "Finished process operation", op, map[string]interface{}{})
+			_ = m.recordSupportAction(actionType, status, "Finished process operation", op, details)
 		}
 	})
 }
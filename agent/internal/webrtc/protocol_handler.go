@@ -0,0 +1,118 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"log"
+	"runtime"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/locallock"
+	"github.com/stangtennis/remote-agent/internal/safemode"
+	"github.com/stangtennis/remote-agent/internal/version"
+)
+
+// agentCapabilities lists the protocol features this agent build implements.
+func agentCapabilities() []string {
+	caps := []string{
+		protocol.CapInput,
+		protocol.CapInputChar,
+		protocol.CapInputWheel,
+		protocol.CapInputTouch,
+		protocol.CapInputPen,
+		protocol.CapKeyLayout,
+		protocol.CapClipboardText,
+		protocol.CapClipboardImage,
+		protocol.CapClipboardFiles,
+		protocol.CapClipboardRich,
+		protocol.CapClipboardPolicy,
+		protocol.CapH264,
+		protocol.CapStreamParams,
+		protocol.CapStreamPause,
+		protocol.CapMonitors,
+		protocol.CapMonitorSpan,
+		protocol.CapRemoteLogin,
+		protocol.CapForceUpdate,
+		protocol.CapICERestart,
+		protocol.CapFiles,
+		protocol.CapShell,
+		protocol.CapScripts,
+		protocol.CapProcess,
+		protocol.CapStats,
+		protocol.CapRegistry,
+		protocol.CapServices,
+		protocol.CapLogs,
+		protocol.CapPrint,
+	}
+	if locallock.Supported() {
+		caps = append(caps, protocol.CapLocalLock)
+	}
+	if safemode.Supported() {
+		caps = append(caps, protocol.CapSafeModeRestart)
+	}
+	return caps
+}
+
+// handleHello answers the controller's hello with our own and records the
+// negotiated session. A version mismatch is logged and the session falls
+// back to the legacy feature set rather than dropping the connection.
+func (m *Manager) handleHello(data []byte) {
+	var peer protocol.Hello
+	if err := protocol.Decode(data, &peer); err != nil {
+		log.Printf("⚠️ Invalid hello: %v", err)
+		return
+	}
+
+	local := protocol.NewHello(protocol.RoleAgent, version.Version, runtime.GOOS, agentCapabilities())
+	session, err := protocol.Negotiate(local, peer)
+	if err != nil {
+		log.Printf("⚠️ %v — using basic feature set", err)
+	}
+	m.peerProto.Store(&session)
+	log.Printf("🤝 Protocol v%d with %s %s (%d shared capabilities)",
+		session.Version, peer.Role, peer.AppVersion, len(session.Capabilities()))
+
+	m.sendControl(local)
+
+	m.keyboardLayout.Store(nil)
+	m.reportKeyboardLayout(true)
+
+	m.clipboardPolicy.Store(nil)
+	m.sendClipboardPolicy("")
+
+	m.releaseLocalLock()
+}
+
+// protoSession returns the negotiated session, or the legacy session when
+// the peer has not sent hello.
+func (m *Manager) protoSession() protocol.Session {
+	if s := m.peerProto.Load(); s != nil {
+		return *s
+	}
+	return protocol.LegacySession()
+}
+
+// replyUnsupported tells a handshaken peer that msgType is not handled
+// here. Legacy peers would not understand the reply, so they get nothing.
+func (m *Manager) replyUnsupported(msgType, reason string) {
+	if msgType == "" || !m.protoSession().Handshaken() {
+		return
+	}
+	log.Printf("⚠️ Unsupported message type from controller: %s", msgType)
+	m.sendControl(protocol.NewUnsupported(msgType, reason))
+}
+
+// sendControl sends a control-plane message, preferring the control channel.
+func (m *Manager) sendControl(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if m.controlChannel != nil && m.controlChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
+		_ = m.controlChannel.Send(data)
+		return
+	}
+	if m.dataChannel != nil && m.dataChannel.ReadyState() == pionwebrtc.DataChannelStateOpen {
+		_ = m.dataChannel.Send(data)
+	}
+}
@@ -0,0 +1,157 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/registry"
+)
+
+// maxRegValueBytes is the largest value sent inline in reg_list_result or
+// reg_get_result; larger ones are left to reg_export.
+const maxRegValueBytes = 16 * 1024
+
+// regImport collects a chunked reg_import until its last message.
+type regImport struct {
+	data []byte
+}
+
+// add appends one chunk and reports whether the import is complete.
+func (ri *regImport) add(req protocol.RegRequest) (string, bool, error) {
+	if len(ri.data)+len(req.Data) > registry.MaxExportBytes {
+		ri.data = nil
+		return "", false, fmt.Errorf("import is larger than %d MB", registry.MaxExportBytes>>20)
+	}
+	ri.data = append(ri.data, req.Data...)
+	if req.More {
+		return "", false, nil
+	}
+	text := string(ri.data)
+	ri.data = nil
+	return text, true, nil
+}
+
+func (m *Manager) handleRegistry(dc *pionwebrtc.DataChannel, req protocol.RegRequest, importText string) error {
+	switch req.Op {
+	case protocol.OpRegList:
+		path, keys, values, err := registry.List(req.Path)
+		if err != nil {
+			sendProcessError(dc, err.Error())
+			return err
+		}
+		return sendRegList(dc, path, keys, values)
+
+	case protocol.OpRegGet:
+		path, v, err := registry.Get(req.Path, req.Name)
+		if err != nil {
+			sendProcessError(dc, err.Error())
+			return err
+		}
+		data, _ := json.Marshal(v.Value)
+		if len(data) > maxRegValueBytes {
+			err := fmt.Errorf("value %q is %d KB; use reg export to read it", req.Name, len(data)>>10)
+			sendProcessError(dc, err.Error())
+			return err
+		}
+		return sendProcessReply(dc, protocol.RegGetResult{
+			Op: protocol.OpRegGetResult, Path: path, Value: protocol.RegValue(v),
+		})
+
+	case protocol.OpRegExport:
+		text, err := registry.Export(req.Path)
+		if err != nil {
+			sendProcessError(dc, err.Error())
+			return err
+		}
+		chunks := protocol.SplitRegChunks(text)
+		for i, c := range chunks {
+			if err := sendProcessReply(dc, protocol.RegExportResult{
+				Op: protocol.OpRegExportResult, Path: req.Path, Data: c, More: i < len(chunks)-1,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Writes
+	res := protocol.RegResult{Op: req.Op + "_result", Path: req.Path}
+	var err error
+	switch req.Op {
+	case protocol.OpRegSet:
+		log.Printf("🗝️ Registry set %s\\%s", req.Path, req.Name)
+		err = registry.Set(req.Path, req.Name, req.Type, req.Value)
+	case protocol.OpRegDelete:
+		log.Printf("🗝️ Registry delete %s (value %q)", req.Path, req.Name)
+		err = registry.Delete(req.Path, req.Name)
+	case protocol.OpRegImport:
+		log.Printf("🗝️ Registry import (%d bytes)", len(importText))
+		res.Count, err = registry.Import(importText)
+	default:
+		err = fmt.Errorf("unknown op: %s", req.Op)
+		sendProcessError(dc, err.Error())
+		return err
+	}
+	res.OK = err == nil
+	if err != nil {
+		res.Error = err.Error()
+	}
+	sendProcessReply(dc, res)
+	return err
+}
+
+// sendRegList sends a listing in as many reg_list_result messages as it
+// takes; the controller merges them until one arrives without More.
+func sendRegList(dc *pionwebrtc.DataChannel, path string, keys []string, values []registry.Value) error {
+	res := protocol.RegListResult{
+		Op: protocol.OpRegListResult, Path: path, ReadOnly: registry.ReadOnly(),
+		Keys: []string{}, Values: []protocol.RegValue{},
+	}
+	size := 0
+	flush := func() error {
+		res.More = true
+		if err := sendProcessReply(dc, res); err != nil {
+			return err
+		}
+		res.Keys, res.Values, size = []string{}, []protocol.RegValue{}, 0
+		return nil
+	}
+	for _, k := range keys {
+		if size+len(k)+4 > protocol.RegChunkBytes {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		res.Keys = append(res.Keys, k)
+		size += len(k) + 4
+	}
+	for _, v := range values {
+		rv := protocol.RegValue(v)
+		data, _ := json.Marshal(rv)
+		if len(data) > maxRegValueBytes {
+			rv.Value = nil
+			data, _ = json.Marshal(rv)
+		}
+		if size+len(data) > protocol.RegChunkBytes {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		res.Values = append(res.Values, rv)
+		size += len(data)
+	}
+	res.More = false
+	return sendProcessReply(dc, res)
+}
+
+func sendProcessReply(dc *pionwebrtc.DataChannel, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		sendProcessError(dc, err.Error())
+		return err
+	}
+	return dc.Send(data)
+}
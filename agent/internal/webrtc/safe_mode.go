@@ -0,0 +1,68 @@
+package webrtc
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/device"
+	"github.com/stangtennis/remote-agent/internal/safemode"
+)
+
+// safeModeMaxDelay caps the restart delay a controller can ask for.
+const safeModeMaxDelay = 10 * 60
+
+// handleRestartSafeMode restarts the device into Safe Mode with Networking
+// (see protocol.RestartSafeMode). The session drops with the restart; the
+// agent service starts again in Safe Mode and the controller reconnects.
+func (m *Manager) handleRestartSafeMode(data []byte) {
+	reply := func(status, message string) {
+		m.sendControl(protocol.RestartStatus{
+			Type:     protocol.TypeRestartStatus,
+			Status:   status,
+			SafeMode: true,
+			Message:  message,
+		})
+	}
+
+	var req protocol.RestartSafeMode
+	if err := protocol.Decode(data, &req); err != nil {
+		reply("error", fmt.Sprintf("invalid restart_safe_mode: %v", err))
+		return
+	}
+	delay := req.DelaySec
+	if delay <= 0 {
+		delay = protocol.RestartSafeModeDefaultDelay
+	}
+	if delay > safeModeMaxDelay {
+		delay = safeModeMaxDelay
+	}
+
+	if m.supportIsActive() {
+		if err := m.recordSupportAction("ADMIN_RESTART_SAFE_MODE", "succeeded", "Restart into Safe Mode requested", "device", map[string]interface{}{
+			"delay_sec": delay,
+		}); err != nil {
+			reply("error", err.Error())
+			return
+		}
+	}
+
+	log.Printf("🛟 Restart into Safe Mode requested (delay %ds)", delay)
+	err := safemode.Restart(time.Duration(delay) * time.Second)
+	if m.device != nil {
+		details := map[string]interface{}{"source": "control", "delay_sec": delay}
+		severity := "warning"
+		if err != nil {
+			details["error"] = err.Error()
+			severity = "error"
+		}
+		m.device.WriteAudit(device.AuditEvent{Event: "RESTART_SAFE_MODE", Severity: severity, Details: details})
+	}
+	if err != nil {
+		log.Printf("❌ Safe Mode restart failed: %v", err)
+		reply("error", "Genstart i fejlsikret tilstand fejlede: "+err.Error())
+		return
+	}
+	reply("restarting", fmt.Sprintf("Genstarter i fejlsikret tilstand med netværk om %d sek...", delay))
+}
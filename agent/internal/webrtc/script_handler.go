@@ -0,0 +1,102 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+
+	"github.com/stangtennis/Remote/scriptlib"
+	"github.com/stangtennis/remote-agent/internal/device"
+	"github.com/stangtennis/remote-agent/internal/shell"
+)
+
+// scriptRun is a verified library script rendered for this device.
+type scriptRun struct {
+	script *scriptlib.Script
+	params map[string]string
+	cmd    string
+}
+
+// prepareScriptRun verifies the signed script in a run_script request and
+// renders it with the supplied parameters. Nothing runs unless the signature
+// checks out against a trusted key.
+func prepareScriptRun(req map[string]interface{}) (*scriptRun, error) {
+	raw, err := json.Marshal(req["script"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid script")
+	}
+	var s scriptlib.Script
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid script: %w", err)
+	}
+	if err := s.Verify(scriptlib.AgentTrustedKeys()); err != nil {
+		return nil, fmt.Errorf("script %s v%d rejected: %w", s.Name, s.Version, err)
+	}
+
+	params := map[string]string{}
+	if p, ok := req["params"].(map[string]interface{}); ok {
+		for k, v := range p {
+			switch val := v.(type) {
+			case string:
+				params[k] = val
+			case bool, float64:
+				params[k] = fmt.Sprint(val)
+			default:
+				return nil, fmt.Errorf("parameter %q has unsupported type", k)
+			}
+		}
+	}
+
+	cmd, err := s.Render(runtime.GOOS, params)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptRun{script: &s, params: params, cmd: cmd}, nil
+}
+
+// addAuditDetails adds the script reference to audit details. Safe on nil.
+func (r *scriptRun) addAuditDetails(details map[string]interface{}) {
+	if r == nil {
+		return
+	}
+	details["script_id"] = r.script.ID
+	details["script_name"] = r.script.Name
+	details["script_version"] = r.script.Version
+	details["script_key_id"] = r.script.KeyID
+	names := make([]string, 0, len(r.params))
+	for k := range r.params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	details["params"] = names
+}
+
+// auditScriptRun records a library script run. Parameter values are left
+// out (they may hold hostnames, user names or paths); the script id and
+// version identify exactly what ran.
+func (m *Manager) auditScriptRun(run *scriptRun, asUser bool, res shell.Result) {
+	log.Printf("🛡️ AUDIT script %s v%d exit=%d duration=%dms as_user=%v",
+		run.script.Name, run.script.Version, res.ExitCode, res.DurationMs, asUser)
+
+	severity := "info"
+	if res.ExitCode != 0 || res.Err != nil {
+		severity = "warning"
+	}
+	details := map[string]interface{}{
+		"as_user":     asUser,
+		"exit_code":   res.ExitCode,
+		"duration_ms": res.DurationMs,
+		"pid":         res.PID,
+	}
+	run.addAuditDetails(details)
+	if res.Err != nil {
+		details["error"] = res.Err.Error()
+	}
+	m.device.WriteAudit(device.AuditEvent{
+		Event:    "SCRIPT_RUN",
+		Severity: severity,
+		Details:  details,
+	})
+}
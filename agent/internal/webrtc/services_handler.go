@@ -0,0 +1,73 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/remote-agent/internal/services"
+)
+
+func (m *Manager) handleServiceList(dc *pionwebrtc.DataChannel) error {
+	list, err := services.List()
+	if err != nil {
+		sendProcessError(dc, err.Error())
+		return err
+	}
+	res := protocol.SvcListResult{Op: protocol.OpSvcListResult, Services: make([]protocol.Service, len(list)), Count: len(list)}
+	for i, s := range list {
+		res.Services[i] = protocol.Service(s)
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		sendProcessError(dc, err.Error())
+		return err
+	}
+	// Windows has a few hundred services; if their display names push the
+	// reply past one SCTP message, send the list without them.
+	if len(data) > maxStatsReplyBytes {
+		for i := range res.Services {
+			res.Services[i].DisplayName = ""
+		}
+		data, _ = json.Marshal(res)
+	}
+	dc.Send(data)
+	return nil
+}
+
+func (m *Manager) handleServiceControl(dc *pionwebrtc.DataChannel, req protocol.SvcRequest) error {
+	var info services.Info
+	var err error
+	switch req.Op {
+	case protocol.OpSvcStart:
+		log.Printf("⚙️ Starting service %s", req.Name)
+		info, err = services.Start(req.Name)
+	case protocol.OpSvcStop:
+		log.Printf("⚙️ Stopping service %s", req.Name)
+		info, err = services.Stop(req.Name)
+	case protocol.OpSvcRestart:
+		log.Printf("⚙️ Restarting service %s", req.Name)
+		info, err = services.Restart(req.Name)
+	case protocol.OpSvcSetStartup:
+		log.Printf("⚙️ Setting startup of service %s to %s", req.Name, req.Startup)
+		info, err = services.SetStartup(req.Name, req.Startup)
+	default:
+		err = fmt.Errorf("unknown op: %s", req.Op)
+		sendProcessError(dc, err.Error())
+		return err
+	}
+
+	res := protocol.SvcResult{Op: req.Op + "_result", Name: req.Name, OK: err == nil}
+	if info.Name != "" {
+		svc := protocol.Service(info)
+		res.Service = &svc
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	data, _ := json.Marshal(res)
+	dc.Send(data)
+	return err
+}
@@ -41,6 +41,8 @@ func (m *Manager) ensureShellState() *shellState {
 //	← {"op":"stderr","id":"...","data":"<base64>"}
 //	← {"op":"exit","id":"...","code":0,"duration_ms":1234}
 //	→ {"op":"kill","id":"..."}
+//	→ {"op":"run_script","id":"...","script":{...signed...},"params":{"k":"v"},"as_user":bool,"timeout_sec":int}
+//	  (same started/stdout/stderr/exit replies as exec)
 func (m *Manager) setupShellChannelHandlers(dc *pionwebrtc.DataChannel) {
 	state := m.ensureShellState()
 
@@ -76,7 +78,17 @@ func (m *Manager) setupShellChannelHandlers(dc *pionwebrtc.DataChannel) {
 			cmdStr, _ := req["cmd"].(string)
 			asUser, _ := req["as_user"].(bool)
 			timeoutF, _ := req["timeout_sec"].(float64)
-			go m.handleShellExec(dc, id, cmdStr, asUser, int(timeoutF))
+			go m.handleShellExec(dc, id, cmdStr, asUser, int(timeoutF), nil)
+		case "run_script":
+			asUser, _ := req["as_user"].(bool)
+			timeoutF, _ := req["timeout_sec"].(float64)
+			run, err := prepareScriptRun(req)
+			if err != nil {
+				log.Printf("🛡️ shell: refused script id=%s: %v", id, err)
+				sendShellMsg(dc, map[string]interface{}{"op": "error", "id": id, "error": err.Error()})
+				return
+			}
+			go m.handleShellExec(dc, id, run.cmd, asUser, int(timeoutF), run)
 		case "kill":
 			state.mu.Lock()
 			ex, ok := state.inflight[id]
@@ -95,7 +107,10 @@ func (m *Manager) setupShellChannelHandlers(dc *pionwebrtc.DataChannel) {
 	})
 }
 
-func (m *Manager) handleShellExec(dc *pionwebrtc.DataChannel, id, cmdStr string, asUser bool, timeoutSec int) {
+// handleShellExec runs cmdStr and streams its output. run is set when the
+// command was rendered from a signed library script; it is audited by script
+// id and version instead of by command hash.
+func (m *Manager) handleShellExec(dc *pionwebrtc.DataChannel, id, cmdStr string, asUser bool, timeoutSec int, run *scriptRun) {
 	if id == "" || cmdStr == "" {
 		sendShellMsg(dc, map[string]interface{}{
 			"op": "error", "id": id, "error": "missing id or cmd",
@@ -107,10 +122,12 @@ func (m *Manager) handleShellExec(dc *pionwebrtc.DataChannel, id, cmdStr string,
 			sendShellMsg(dc, map[string]interface{}{"op": "error", "id": id, "error": "admin scope required for elevated shell"})
 			return
 		}
-		if err := m.recordSupportAction("SHELL_EXEC", "started", "Started shell command", "shell", map[string]interface{}{
+		details := map[string]interface{}{
 			"action_id": id,
 			"as_user":   asUser,
-		}); err != nil {
+		}
+		run.addAuditDetails(details)
+		if err := m.recordSupportAction("SHELL_EXEC", "started", "Started shell command", "shell", details); err != nil {
 			sendShellMsg(dc, map[string]interface{}{"op": "error", "id": id, "error": err.Error()})
 			return
 		}
@@ -174,17 +191,23 @@ func (m *Manager) handleShellExec(dc *pionwebrtc.DataChannel, id, cmdStr string,
 		if res.ExitCode != 0 || res.Err != nil {
 			status = "failed"
 		}
-		_ = m.recordSupportAction("SHELL_EXEC", status, "Finished shell command", "shell", map[string]interface{}{
+		details := map[string]interface{}{
 			"action_id":   id,
 			"exit_code":   res.ExitCode,
 			"duration_ms": res.DurationMs,
 			"as_user":     asUser,
-		})
+		}
+		run.addAuditDetails(details)
+		_ = m.recordSupportAction("SHELL_EXEC", status, "Finished shell command", "shell", details)
 	}
 
 	// Best-effort audit log (async, fire-and-forget)
 	if m.device != nil && !m.supportIsActive() {
-		go m.auditShellCommand(cmdStr, asUser, res)
+		if run != nil {
+			go m.auditScriptRun(run, asUser, res)
+		} else {
+			go m.auditShellCommand(cmdStr, asUser, res)
+		}
 	}
 }
 
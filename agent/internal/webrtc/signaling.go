@@ -84,9 +84,16 @@ func fetchTurnCredentials(supabaseURL, anonKey, authToken string, client *http.C
 
 // getICEServers returns the ICE server configuration with STUN and optional TURN
 func (m *Manager) getICEServers() []webrtc.ICEServer {
-	// Try fetching from Edge Function first
 	authToken, _ := m.tokenProvider.GetToken()
-	if servers := fetchTurnCredentials(m.cfg.SupabaseURL, m.cfg.SupabaseAnonKey, authToken, m.httpClient); len(servers) > 0 {
+	return ICEServers(m.cfg.SupabaseURL, m.cfg.SupabaseAnonKey, authToken, m.httpClient)
+}
+
+// ICEServers returns the ICE servers a session would use: TURN credentials
+// from the Edge Function, falling back to public STUN plus TURN_* env vars.
+// Exported for the --diagnose network check.
+func ICEServers(supabaseURL, anonKey, authToken string, client *http.Client) []webrtc.ICEServer {
+	// Try fetching from Edge Function first
+	if servers := fetchTurnCredentials(supabaseURL, anonKey, authToken, client); len(servers) > 0 {
 		return servers
 	}
 
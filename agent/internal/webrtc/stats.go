@@ -7,6 +7,7 @@ import (
 
 	pionwebrtc "github.com/pion/webrtc/v3"
 	"github.com/stangtennis/remote-agent/internal/metrics"
+	"github.com/stangtennis/remote-agent/internal/timeline"
 )
 
 // sendStats sends streaming stats to controller
@@ -42,6 +43,10 @@ func (m *Manager) collectStats(ctx context.Context) {
 	defer ticker.Stop()
 
 	var prevPacketsSent, prevPacketsReceived uint32
+	var prevBytesSent uint64
+	prevSampleAt := time.Now()
+	prevConnType := ""
+	bufferPressure := false
 
 	for {
 		select {
@@ -61,10 +66,15 @@ func (m *Manager) collectStats(ctx context.Context) {
 		stats := pc.GetStats()
 		// Collect candidate type lookup table
 		localCandidates := map[string]string{} // id → candidateType
+		jitterMs := 0.0
 		for _, stat := range stats {
 			if cs, ok := stat.(pionwebrtc.ICECandidateStats); ok {
 				localCandidates[cs.ID] = cs.CandidateType.String()
 			}
+			// Jitter as reported back by the controller via RTCP (H.264 track only)
+			if rs, ok := stat.(pionwebrtc.RemoteInboundRTPStreamStats); ok && rs.Kind == "video" {
+				jitterMs = rs.Jitter * 1000
+			}
 		}
 
 		for _, stat := range stats {
@@ -116,8 +126,10 @@ func (m *Manager) collectStats(ctx context.Context) {
 		}
 
 		// Fallback: also check buffer as secondary congestion signal
+		var bufferedBytes uint64
 		if m.dataChannel != nil {
-			buffered := float64(m.dataChannel.BufferedAmount())
+			bufferedBytes = m.dataChannel.BufferedAmount()
+			buffered := float64(bufferedBytes)
 			m.statsMu.Lock()
 			if buffered > 4*1024*1024 && m.lossPct < 1 {
 				// Buffer is very high but ICE says no loss — report minor congestion
@@ -128,5 +140,44 @@ func (m *Manager) collectStats(ctx context.Context) {
 			}
 			m.statsMu.Unlock()
 		}
+
+		// Session timeline: one sample per tick plus change events
+		connType := m.getConnectionType()
+		if connType != prevConnType && connType != "" {
+			if prevConnType != "" {
+				m.recordTimelineEvent("conn_type", prevConnType+" -> "+connType)
+			}
+			prevConnType = connType
+		}
+		if pressured := bufferedBytes > 2*1024*1024; pressured != bufferPressure {
+			bufferPressure = pressured
+			if pressured {
+				m.recordTimelineEvent("buffer_pressure", "send buffer above 2MB")
+			} else {
+				m.recordTimelineEvent("buffer_pressure", "send buffer recovered")
+			}
+		}
+		bytesSent := m.getTotalBytesSent()
+		now := time.Now()
+		bitrateKbps := 0.0
+		if elapsed := now.Sub(prevSampleAt).Seconds(); elapsed > 0 && prevBytesSent > 0 && bytesSent >= prevBytesSent {
+			bitrateKbps = float64(bytesSent-prevBytesSent) * 8 / 1000 / elapsed
+		}
+		prevBytesSent = bytesSent
+		prevSampleAt = now
+		encoderName := "jpeg"
+		if m.useH264.Load() && m.videoEncoder != nil {
+			encoderName = m.videoEncoder.GetEncoderName()
+		}
+		m.recordTimelineSample(timeline.Sample{
+			At:          now,
+			ConnType:    connType,
+			RTTMs:       float64(m.getLastRTT().Microseconds()) / 1000,
+			JitterMs:    jitterMs,
+			LossPct:     m.getLossPct(),
+			BitrateKbps: bitrateKbps,
+			Encoder:     encoderName,
+			BufferedKB:  bufferedBytes / 1024,
+		})
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
 	"github.com/stangtennis/remote-agent/internal/desktop"
 	"github.com/stangtennis/remote-agent/internal/input"
 	"github.com/stangtennis/remote-agent/internal/metrics"
@@ -49,7 +50,19 @@ type ModeState struct {
 	switchHistory []time.Time   // Recent switches for flapping detection
 }
 
+// maxH264SpanSize is the largest frame side the H.264 path accepts in
+// spanning mode (NVENC and OpenH264 level limits both sit around 4096).
+const maxH264SpanSize = 4096
+
 func (m *Manager) canUseH264Mode() bool {
+	// Spanning several monitors can produce frames wider than H.264
+	// encoders accept; those stay on JPEG tiles whatever the encoder.
+	if m.screenCapturer != nil && m.screenCapturer.GetDisplayIndex() == screen.AllDisplays {
+		if w, h := m.screenCapturer.GetResolution(); w > maxH264SpanSize || h > maxH264SpanSize {
+			return false
+		}
+	}
+
 	// Allow H.264 if using hardware encoder (NVENC) — even in Session 0.
 	// Hardware encoding runs on GPU and doesn't compete with capture CPU.
 	if m.videoEncoder != nil {
@@ -202,6 +215,8 @@ func (m *Manager) switchMode(newMode StreamMode, fps *int, quality *int, scale *
 	m.modeState.current = newMode
 	m.modeState.lastSwitch = time.Now()
 
+	m.recordModeSwitch(oldMode, newMode)
+
 	// Track switch history for flapping detection
 	m.modeState.switchHistory = append(m.modeState.switchHistory, time.Now())
 	if len(m.modeState.switchHistory) > 10 {
@@ -987,19 +1002,9 @@ func (m *Manager) sendMonitorList() {
 		activeIndex = m.screenCapturer.GetDisplayIndex()
 	}
 
-	type monitorMsg struct {
-		Index   int    `json:"index"`
-		Name    string `json:"name"`
-		Width   int    `json:"width"`
-		Height  int    `json:"height"`
-		Primary bool   `json:"primary"`
-		OffsetX int    `json:"offsetX"`
-		OffsetY int    `json:"offsetY"`
-	}
-
-	var monList []monitorMsg
+	var monList []protocol.Monitor
 	for _, mon := range monitors {
-		monList = append(monList, monitorMsg{
+		monList = append(monList, protocol.Monitor{
 			Index:   mon.Index,
 			Name:    mon.Name,
 			Width:   mon.Width,
@@ -1010,10 +1015,11 @@ func (m *Manager) sendMonitorList() {
 		})
 	}
 
-	msg := map[string]interface{}{
-		"type":     "monitor_list",
-		"monitors": monList,
-		"active":   activeIndex,
+	msg := protocol.MonitorList{
+		Type:     protocol.TypeMonitorList,
+		Monitors: monList,
+		Active:   activeIndex,
+		Span:     len(monitors) > 1 && !m.isSession0,
 	}
 
 	if data, err := json.Marshal(msg); err == nil {
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
 	"github.com/stangtennis/remote-agent/internal/config"
 	"github.com/stangtennis/remote-agent/internal/device"
 )
@@ -20,13 +21,14 @@ import (
 type SupportConsentFunc func(scopes []string) bool
 
 type supportSessionResponse struct {
-	SessionID       string   `json:"session_id"`
-	Token           string   `json:"token"`
-	ClientGrant     string   `json:"client_grant_token"`
-	SupportMode     string   `json:"support_mode"`
-	RequestedScopes []string `json:"requested_scopes"`
-	RequiresConsent bool     `json:"requires_consent"`
-	ExpiresAt       string   `json:"expires_at"`
+	SessionID       string                   `json:"session_id"`
+	Token           string                   `json:"token"`
+	ClientGrant     string                   `json:"client_grant_token"`
+	SupportMode     string                   `json:"support_mode"`
+	RequestedScopes []string                 `json:"requested_scopes"`
+	ClipboardPolicy protocol.ClipboardPolicy `json:"clipboard_policy"`
+	RequiresConsent bool                     `json:"requires_consent"`
+	ExpiresAt       string                   `json:"expires_at"`
 }
 
 // RunPortableSupport runs a temporary, non-installed support agent. It never
@@ -91,7 +93,10 @@ func RunPortableSupport(cfg *config.Config, dev *device.Device, pin string, cons
 	if err != nil {
 		return fmt.Errorf("invalid support expiry: %w", err)
 	}
-	m.enableSupportAuthorization(session.SessionID, grant, effectiveScopes, expiresAt)
+	if err := session.ClipboardPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid support clipboard policy: %w", err)
+	}
+	m.enableSupportAuthorization(session.SessionID, grant, effectiveScopes, session.ClipboardPolicy, expiresAt)
 	m.sessionID = session.SessionID
 	defer func() {
 		if m.peerConnection != nil {
@@ -4,22 +4,27 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/stangtennis/Remote/protocol"
 )
 
-// enableSupportAuthorization installs the server-returned scope set before a
-// support peer connection is created. WebRTC payloads never get to choose it.
-func (m *Manager) enableSupportAuthorization(sessionID, grant string, scopes []string, expiresAt time.Time) {
+// enableSupportAuthorization installs the server-returned scope set and
+// clipboard policy before a support peer connection is created. WebRTC
+// payloads never get to choose them; a clipboard_policy from the controller
+// can only narrow the policy.
+func (m *Manager) enableSupportAuthorization(sessionID, grant string, scopes []string, clipboard protocol.ClipboardPolicy, expiresAt time.Time) {
 	m.supportAuthMu.Lock()
 	defer m.supportAuthMu.Unlock()
 	m.supportMode = true
 	m.supportSessionID = sessionID
 	m.supportGrant = grant
 	m.supportExpiresAt = expiresAt
+	m.supportClipboard = clipboard
 	m.supportScopes = make(map[string]bool, len(scopes))
 	for _, scope := range scopes {
 		m.supportScopes[scope] = true
 	}
-	log.Printf("Support authorization enabled for %s with scopes=%v", sessionID, scopes)
+	log.Printf("Support authorization enabled for %s with scopes=%v clipboard=%+v", sessionID, scopes, clipboard)
 }
 
 func (m *Manager) supportAllows(scope string) bool {
@@ -54,6 +59,8 @@ func (m *Manager) supportChannelAllowed(label string) bool {
 		return m.supportAllows("terminal")
 	case "process":
 		return m.supportAllows("process")
+	case "print":
+		return m.supportAllows("print")
 	case "control":
 		return m.supportAllows("screen")
 	default:
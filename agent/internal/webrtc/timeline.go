@@ -0,0 +1,128 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	pionwebrtc "github.com/pion/webrtc/v3"
+	"github.com/stangtennis/remote-agent/internal/timeline"
+)
+
+// maxStatsReplyBytes keeps stats_result below the default SCTP max message size.
+const maxStatsReplyBytes = 60 * 1024
+
+// ensureTimeline starts a timeline for the current session. An ICE restart
+// reconnects within the same session and keeps the existing timeline.
+func (m *Manager) ensureTimeline() {
+	if t := m.timeline.Load(); t != nil && t.SessionID() == m.sessionID {
+		return
+	}
+	m.timeline.Store(timeline.New(m.sessionID, m.modeState.current.String()))
+}
+
+// recordTimelineEvent adds an event to the active session timeline, if any.
+func (m *Manager) recordTimelineEvent(kind, detail string) {
+	if t := m.timeline.Load(); t != nil {
+		t.AddEvent(kind, detail)
+	}
+}
+
+// recordModeSwitch notes a streaming mode change from switchMode.
+func (m *Manager) recordModeSwitch(from, to StreamMode) {
+	if t := m.timeline.Load(); t != nil {
+		t.SetMode(from.String(), to.String())
+	}
+}
+
+// recordTimelineSample adds a stats sample to the active session timeline.
+func (m *Manager) recordTimelineSample(s timeline.Sample) {
+	if t := m.timeline.Load(); t != nil {
+		t.AddSample(s)
+	}
+}
+
+// finishTimeline detaches the session timeline and uploads it in the
+// background. Called from cleanupConnection before the session ID is reset.
+func (m *Manager) finishTimeline(reason string) {
+	t := m.timeline.Swap(nil)
+	if t == nil {
+		return
+	}
+	t.AddEvent("ended", reason)
+	tl := t.Snapshot(time.Time{})
+	ended := time.Now()
+	tl.EndedAt = &ended
+	if tl.SessionID == "" || len(tl.Samples) == 0 {
+		return
+	}
+	go m.uploadTimeline(tl)
+}
+
+// uploadTimeline posts the finished timeline to session_stats.
+func (m *Manager) uploadTimeline(tl timeline.Timeline) {
+	jsonData, _ := json.Marshal(timeline.UploadRow(tl, m.device.ID))
+	req, err := http.NewRequest("POST", m.cfg.SupabaseURL+"/rest/v1/session_stats", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := m.setAuthHeaders(req); err != nil {
+		log.Printf("⚠️ Session stats upload skipped: %v", err)
+		return
+	}
+	req.Header.Set("Prefer", "return=minimal")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		log.Printf("⚠️ Session stats upload failed: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("⚠️ Session stats upload failed: HTTP %d - %s", resp.StatusCode, string(body))
+		return
+	}
+	log.Printf("📊 Session stats uploaded (%d samples, %d events)", len(tl.Samples), len(tl.Events))
+}
+
+// handleSessionStats returns the current session timeline on the process
+// channel. sinceSec > 0 limits the result to the last sinceSec seconds.
+func (m *Manager) handleSessionStats(dc *pionwebrtc.DataChannel, sinceSec float64) error {
+	t := m.timeline.Load()
+	if t == nil {
+		sendProcessError(dc, "no active session timeline")
+		return fmt.Errorf("no active session timeline")
+	}
+	var since time.Time
+	if sinceSec > 0 {
+		since = time.Now().Add(-time.Duration(sinceSec * float64(time.Second)))
+	}
+	tl := t.Snapshot(since)
+
+	// Keep the reply within a single SCTP message; long sessions are merged
+	// in twos, fours, ... rather than truncated.
+	step := 1
+	for {
+		data, err := json.Marshal(map[string]interface{}{
+			"op":          "stats_result",
+			"timeline":    tl,
+			"sample_step": step,
+		})
+		if err != nil {
+			sendProcessError(dc, err.Error())
+			return err
+		}
+		if len(data) <= maxStatsReplyBytes || len(tl.Samples) < 2 {
+			dc.Send(data)
+			return nil
+		}
+		tl.Samples = timeline.Halve(tl.Samples)
+		step *= 2
+	}
+}
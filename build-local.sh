@@ -11,6 +11,12 @@ TIMEOUT=300  # 5 minute timeout per build
 echo "🔨 Building Remote Desktop $VERSION (date: $BUILD_DATE)"
 echo "=================================="
 
+# Release builds must carry a release signing key, or they refuse every update
+if [ "$VERSION" != "dev" ] && ! grep -qv '^[[:space:]]*\(#\|$\)' releasesig/keys.txt; then
+    echo "❌ No release signing key in releasesig/keys.txt — the build could never update (see controller/cmd/sign-release)"
+    exit 1
+fi
+
 # Create output directory
 mkdir -p builds
 
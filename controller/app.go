@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/stangtennis/Remote/controller/internal/config"
 	"github.com/stangtennis/Remote/controller/internal/credentials"
 	"github.com/stangtennis/Remote/controller/internal/logger"
+	"github.com/stangtennis/Remote/controller/internal/macro"
 	"github.com/stangtennis/Remote/controller/internal/settings"
 	"github.com/stangtennis/Remote/controller/internal/supabase"
 	"github.com/stangtennis/Remote/controller/internal/updater"
@@ -243,6 +245,7 @@ type DeviceInfo struct {
 	MemoryTotalMB int     `json:"memory_total_mb"`
 	DiskUsedGB    int     `json:"disk_used_gb"`
 	DiskTotalGB   int     `json:"disk_total_gb"`
+	UpdatePin     string  `json:"update_pin"`
 }
 
 func deviceToInfo(d supabase.Device) DeviceInfo {
@@ -257,6 +260,7 @@ func deviceToInfo(d supabase.Device) DeviceInfo {
 		MemoryTotalMB: d.MemoryTotalMB,
 		DiskUsedGB:    d.DiskUsedGB,
 		DiskTotalGB:   d.DiskTotalGB,
+		UpdatePin:     d.UpdatePin,
 	}
 
 	if d.LastSeen.IsZero() {
@@ -618,6 +622,38 @@ func (a *App) GetLogContent(lines int) (string, error) {
 	return logger.ReadLog(lines)
 }
 
+// ==================== MACROS ====================
+
+// SaveMacro compacts the input events recorded in the viewer into a macro
+// and asks where to save it. Returns the saved path, or "" if cancelled.
+func (a *App) SaveMacro(deviceName, eventsJSON string) (string, error) {
+	var events []macro.Event
+	if err := json.Unmarshal([]byte(eventsJSON), &events); err != nil {
+		return "", fmt.Errorf("invalid recording: %w", err)
+	}
+	m := macro.FromEvents(events)
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	m.Name = fmt.Sprintf("%s %s", deviceName, time.Now().Format("2006-01-02 15:04"))
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Gem makro",
+		DefaultFilename: "macro-" + time.Now().Format("20060102-150405") + ".yaml",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Makro (*.yaml, *.json)", Pattern: "*.yaml;*.yml;*.json"},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := m.Save(path); err != nil {
+		return "", err
+	}
+	logger.Info("Saved macro with %d steps to %s", len(m.Steps), path)
+	return path, nil
+}
+
 // ==================== ADMIN ====================
 
 // AdminUserInfo represents a user for the admin panel
@@ -698,6 +734,23 @@ func (a *App) ForceUpdateAllDevices() error {
 	return a.supabase.ForceUpdateAllDevices()
 }
 
+// SetDeviceUpdatePin holds a device on an agent version ("" releases the pin)
+func (a *App) SetDeviceUpdatePin(deviceID, version string) error {
+	if a.currentUser == nil || a.supabase == nil {
+		return fmt.Errorf("not logged in")
+	}
+	version = strings.TrimSpace(version)
+	if version != "" {
+		if _, err := updater.ParseVersion(version); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+	}
+	return a.supabase.SetDeviceUpdatePin(deviceID, version)
+}
+
 // ==================== DEVICE REFRESH ====================
 
 func (a *App) startDeviceRefresh() {
@@ -72,7 +72,12 @@ func cmdExec() {
 		fmt.Fprintf(os.Stderr, "Error: send request: %v\n", err)
 		os.Exit(1)
 	}
+	followExecStream(conn)
+}
 
+// followExecStream prints a streamed exec from the daemon and exits the
+// process with the remote exit code.
+func followExecStream(conn net.Conn) {
 	dec := json.NewDecoder(conn)
 	exitCode := 0
 	for {
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const clipboardUsage = `Usage:
+  remote-desktop-cli clipboard [files]            Files copied on the remote device
+  remote-desktop-cli clipboard paste <local-dir>  Download them into local-dir
+  remote-desktop-cli clipboard copy <path>...     Upload files and put them on the remote clipboard
+  remote-desktop-cli clipboard policy [--direction both|to_agent|from_agent|off]
+                                      [--max-size <n>[K|M|G]] [--formats text,image,html,rtf,files]
+                                                  Show or limit clipboard sync for this session`
+
+// clipFileTimeout bounds one file of a clipboard paste or copy.
+const clipFileTimeout = 10 * time.Minute
+
+// requestClipListing sends a "clip" or "clip_stage" op on the file channel
+// and waits for its reply.
+func requestClipListing(conn *DeviceConnection, op string, timeout time.Duration) (*protocol.ClipListing, error) {
+	fid := nextFileID()
+	sub := conn.fileRouter.Subscribe(fid)
+	defer conn.fileRouter.Unsubscribe(fid)
+
+	req, _ := json.Marshal(protocol.FileRequest{Op: op, Fid: int(fid)})
+	if err := conn.SendFile(req); err != nil {
+		return nil, fmt.Errorf("send %s: %w", op, err)
+	}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				return nil, fmt.Errorf("channel closed unexpectedly")
+			}
+			switch msg["op"] {
+			case op:
+				var listing protocol.ClipListing
+				if err := protocol.FromMap(msg, &listing); err != nil {
+					return nil, err
+				}
+				return &listing, nil
+			case protocol.OpErr:
+				errStr, _ := msg["error"].(string)
+				return nil, fmt.Errorf("agent error: %s", errStr)
+			}
+		case <-deadline:
+			return nil, fmt.Errorf("no %s reply within %s", op, timeout)
+		}
+	}
+}
+
+// pasteClipboardFiles downloads the files copied on the agent into dir,
+// keeping the copied folder structure. Existing files are never
+// overwritten. progress is called before each file.
+func pasteClipboardFiles(conn *DeviceConnection, dir string, progress func(rel string, done, total int64)) (int64, error) {
+	listing, err := requestClipListing(conn, protocol.OpClip, 30*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	if len(listing.Files) > protocol.MaxClipboardFiles || listing.Total > protocol.MaxClipboardBytes {
+		return 0, fmt.Errorf("copied files exceed the clipboard limits")
+	}
+
+	// Check every target first so a clash doesn't leave half a paste
+	targets := make([]string, len(listing.Files))
+	for i, f := range listing.Files {
+		rel := filepath.FromSlash(f.Rel)
+		if !filepath.IsLocal(rel) {
+			return 0, fmt.Errorf("refusing unsafe path from agent: %q", f.Rel)
+		}
+		targets[i] = filepath.Join(dir, rel)
+		if _, err := os.Lstat(targets[i]); err == nil {
+			return 0, fmt.Errorf("%s already exists", targets[i])
+		}
+	}
+
+	var done int64
+	for i, f := range listing.Files {
+		progress(f.Rel, done, listing.Total)
+		n, err := downloadRemoteFile(conn, f.Path, targets[i], clipFileTimeout)
+		done += n
+		if err != nil {
+			return done, fmt.Errorf("%s: %w", f.Rel, err)
+		}
+	}
+	return done, nil
+}
+
+// localClipFile is a local file for a clipboard copy; rel uses forward
+// slashes and starts with the copied item's name.
+type localClipFile struct {
+	rel, path string
+	size      int64
+}
+
+// expandLocalFiles flattens paths like the agent's ExpandClipboard,
+// enforcing the same limits. Empty files are left out: the put op has no
+// way to create them.
+func expandLocalFiles(paths []string) ([]localClipFile, []protocol.ClipboardFile, int64, error) {
+	var files []localClipFile
+	var items []protocol.ClipboardFile
+	var total int64
+	for _, root := range paths {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		item := protocol.ClipboardFile{Name: filepath.Base(root), IsDir: info.IsDir()}
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil || fi.Size() == 0 {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			files = append(files, localClipFile{rel: path.Join(item.Name, filepath.ToSlash(rel)), path: p, size: fi.Size()})
+			item.Size += fi.Size()
+			total += fi.Size()
+			if len(files) > protocol.MaxClipboardFiles || total > protocol.MaxClipboardBytes {
+				return fmt.Errorf("more than %d files or %d MB", protocol.MaxClipboardFiles, protocol.MaxClipboardBytes>>20)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		items = append(items, item)
+	}
+	if len(files) == 0 {
+		return nil, nil, 0, fmt.Errorf("nothing to copy")
+	}
+	return files, items, total, nil
+}
+
+// copyClipboardFiles uploads local files into a fresh stage folder on the
+// agent and puts them on its clipboard, ready for a paste there.
+func copyClipboardFiles(conn *DeviceConnection, paths []string, progress func(rel string, done, total int64)) (int64, error) {
+	if err := conn.Require(protocol.CapClipboardFiles); err != nil {
+		return 0, err
+	}
+	files, items, total, err := expandLocalFiles(paths)
+	if err != nil {
+		return 0, err
+	}
+	stage, err := requestClipListing(conn, protocol.OpStage, 30*time.Second)
+	if err != nil {
+		return 0, err
+	}
+
+	// Remote paths use the agent's separator
+	sep := "/"
+	if strings.Contains(stage.Path, `\`) {
+		sep = `\`
+	}
+	remote := func(rel string) string {
+		return stage.Path + sep + strings.ReplaceAll(rel, "/", sep)
+	}
+
+	var done int64
+	for _, f := range files {
+		progress(f.rel, done, total)
+		n, err := uploadLocalFile(conn, f.path, remote(f.rel), clipFileTimeout)
+		done += n
+		if err != nil {
+			return done, fmt.Errorf("%s: %w", f.rel, err)
+		}
+	}
+	for i := range items {
+		items[i].Path = remote(items[i].Name)
+	}
+	err = conn.client.SetClipboardFiles(protocol.ClipboardFiles{Files: items, Count: len(files), Total: total})
+	return done, err
+}
+
+// handleClipboardFiles reports the files last copied on the agent.
+func handleClipboardFiles(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.Require(protocol.CapClipboardFiles); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	files, ok := conn.client.ClipboardFiles()
+	if !ok {
+		return daemonResponse{OK: true, Data: map[string]interface{}{"files": []protocol.ClipboardFile{}}}
+	}
+	return daemonResponse{OK: true, Data: map[string]interface{}{
+		"files": files.Files,
+		"count": files.Count,
+		"total": files.Total,
+		"error": files.Error,
+	}}
+}
+
+// handleClipboardStream runs a clipboard paste or copy with progress.
+func handleClipboardStream(conn net.Conn, req daemonRequest, connMgr *ConnectionManager, deviceID string) error {
+	sw := newStreamWriter(conn)
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+	progress := func(rel string, done, total int64) {
+		sw.Send(streamMsg{Type: "progress", Data: rel, Bytes: done, Total: total})
+	}
+
+	var n int64
+	switch req.Cmd {
+	case "clipboard_paste":
+		dir, _ := req.Args["dir"].(string)
+		if dir == "" {
+			err = fmt.Errorf("missing local folder")
+			break
+		}
+		n, err = pasteClipboardFiles(deviceConn, dir, progress)
+	case "clipboard_copy":
+		var paths []string
+		if list, ok := req.Args["paths"].([]interface{}); ok {
+			for _, p := range list {
+				if s, ok := p.(string); ok {
+					paths = append(paths, s)
+				}
+			}
+		}
+		n, err = copyClipboardFiles(deviceConn, paths, progress)
+	}
+	msg := streamMsg{Type: "end", Bytes: n}
+	if err != nil {
+		msg.Error = err.Error()
+	}
+	sw.Send(msg)
+	return err
+}
+
+func cmdClipboard() {
+	sub := "files"
+	if len(os.Args) > 2 {
+		sub = os.Args[2]
+	}
+	switch sub {
+	case "files":
+		cmdClipboardFiles()
+	case "paste":
+		if len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr, clipboardUsage)
+			os.Exit(2)
+		}
+		dir, err := filepath.Abs(os.Args[3])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		streamClipboard("clipboard_paste", map[string]interface{}{"dir": dir})
+	case "policy":
+		cmdClipboardPolicy()
+	case "copy":
+		if len(os.Args) < 4 {
+			fmt.Fprintln(os.Stderr, clipboardUsage)
+			os.Exit(2)
+		}
+		var paths []string
+		for _, p := range os.Args[3:] {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			paths = append(paths, p)
+		}
+		streamClipboard("clipboard_copy", map[string]interface{}{"paths": paths})
+	default:
+		fmt.Fprintln(os.Stderr, clipboardUsage)
+		os.Exit(2)
+	}
+}
+
+func cmdClipboardFiles() {
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "clipboard_files"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	if msg, _ := resp.Data["error"].(string); msg != "" {
+		fmt.Printf("Files were copied but cannot be transferred: %s\n", msg)
+		return
+	}
+	files, _ := resp.Data["files"].([]interface{})
+	if len(files) == 0 {
+		fmt.Println("(no files on the remote clipboard)")
+		return
+	}
+	for _, raw := range files {
+		f, _ := raw.(map[string]interface{})
+		name, _ := f["name"].(string)
+		if dir, _ := f["dir"].(bool); dir {
+			name += string(filepath.Separator)
+		}
+		fmt.Printf("%10s  %s\n", formatBytes(int64(numFloat(f["size"]))), name)
+	}
+	fmt.Printf("%d file(s), %s — paste with: remote-desktop-cli clipboard paste <dir>\n",
+		int(numFloat(resp.Data["count"])), formatBytes(int64(numFloat(resp.Data["total"]))))
+}
+
+func streamClipboard(cmd string, args map[string]interface{}) {
+	conn, err := streamingDial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Cmd: cmd, Args: args}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		var m streamMsg
+		if err := dec.Decode(&m); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading from daemon: %v\n", err)
+			os.Exit(1)
+		}
+		switch m.Type {
+		case "progress":
+			fmt.Fprintf(os.Stderr, "  %s (%s of %s)\n", m.Data, formatBytes(m.Bytes), formatBytes(m.Total))
+		case "end":
+			if m.Error != "" {
+				fmt.Fprintf(os.Stderr, "Failed: %s (%d bytes transferred)\n", m.Error, m.Bytes)
+				os.Exit(1)
+			}
+			if cmd == "clipboard_copy" {
+				fmt.Printf("OK: %d bytes uploaded and put on the remote clipboard\n", m.Bytes)
+			} else {
+				fmt.Printf("OK: %d bytes pasted\n", m.Bytes)
+			}
+			return
+		case "error":
+			fmt.Fprintf(os.Stderr, "Error: %s\n", m.Error)
+			os.Exit(1)
+		}
+	}
+}
+
+// formatBytes renders a size as B, KB, MB or GB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// handleClipboardPolicy sets the session's clipboard policy when any of
+// direction, max_bytes or formats is given, and reports the policy the
+// agent has in force.
+func handleClipboardPolicy(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.Require(protocol.CapClipboardPolicy); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	if len(req.Args) > 0 {
+		policy := protocol.ClipboardPolicy{
+			Direction: getStringArg(req.Args, "direction", ""),
+			MaxBytes:  int64(numFloat(req.Args["max_bytes"])),
+		}
+		if list, ok := req.Args["formats"].([]interface{}); ok {
+			for _, f := range list {
+				if s, ok := f.(string); ok {
+					policy.Formats = append(policy.Formats, s)
+				}
+			}
+		}
+		if err := conn.client.SetClipboardPolicy(policy); err != nil {
+			return daemonResponse{OK: false, Error: err.Error()}
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if policy, ok := conn.client.ClipboardPolicy(); ok {
+			if policy.Error != "" {
+				return daemonResponse{OK: false, Error: policy.Error}
+			}
+			return daemonResponse{OK: true, Data: map[string]interface{}{
+				"direction": policy.Direction,
+				"max_bytes": policy.MaxBytes,
+				"formats":   policy.Formats,
+			}}
+		}
+		if time.Now().After(deadline) {
+			return daemonResponse{OK: false, Error: "agent has not reported a clipboard policy"}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// parseSize reads a byte count with an optional K, M or G suffix.
+func parseSize(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.ToUpper(s), "B")
+	mult := int64(1)
+	if i := len(num) - 1; i > 0 {
+		switch num[i] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			num = num[:i]
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func cmdClipboardPolicy() {
+	args := map[string]interface{}{}
+	rest := os.Args[3:]
+	for i := 0; i < len(rest); i++ {
+		if i+1 >= len(rest) {
+			fmt.Fprintln(os.Stderr, clipboardUsage)
+			os.Exit(2)
+		}
+		switch rest[i] {
+		case "--direction":
+			args["direction"] = rest[i+1]
+		case "--max-size":
+			n, err := parseSize(rest[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+			args["max_bytes"] = n
+		case "--formats":
+			args["formats"] = strings.Split(rest[i+1], ",")
+		default:
+			fmt.Fprintln(os.Stderr, clipboardUsage)
+			os.Exit(2)
+		}
+		i++
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "clipboard_policy", Args: args})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	direction, _ := resp.Data["direction"].(string)
+	if direction == "" {
+		direction = protocol.ClipboardBoth
+	}
+	size := "any"
+	if n := int64(numFloat(resp.Data["max_bytes"])); n > 0 {
+		size = formatBytes(n)
+	}
+	formats := "all"
+	if list, _ := resp.Data["formats"].([]interface{}); len(list) > 0 {
+		names := make([]string, 0, len(list))
+		for _, f := range list {
+			if s, ok := f.(string); ok {
+				names = append(names, s)
+			}
+		}
+		formats = strings.Join(names, ",")
+	}
+	fmt.Printf("Direction: %s\nMax size:  %s\nFormats:   %s\n", direction, size, formats)
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"os"
 	"time"
+	"unicode/utf8"
+
+	"github.com/stangtennis/Remote/protocol"
 )
 
 func handleStatus(connMgr *ConnectionManager, deviceID, deviceName string, startTime time.Time) daemonResponse {
@@ -27,6 +30,10 @@ func handleStatus(connMgr *ConnectionManager, deviceID, deviceName string, start
 	if frame != nil {
 		data["frame_age"] = time.Since(frameTime).Round(time.Millisecond).String()
 	}
+	if layout, ok := conn.client.KeyboardLayout(); ok {
+		data["keyboard_layout"] = layout.ID
+		data["keyboard_lang"] = layout.Lang
+	}
 
 	return daemonResponse{OK: true, Data: data}
 }
@@ -82,13 +89,12 @@ func handleClick(req daemonRequest, connMgr *ConnectionManager, deviceID string)
 		return daemonResponse{OK: false, Error: err.Error()}
 	}
 
-	x := getIntArg(req.Args, "x", 0)
-	y := getIntArg(req.Args, "y", 0)
+	x, y, rel := pointerArgs(req.Args)
 	button := getStringArg(req.Args, "button", "left")
 	doubleClick := getBoolArg(req.Args, "double_click", false)
 
 	// Move mouse first
-	if err := conn.SendInput(buildMouseMove(x, y)); err != nil {
+	if err := conn.SendInput(buildMouseMove(x, y, rel)); err != nil {
 		return daemonResponse{OK: false, Error: fmt.Sprintf("failed to move mouse: %v", err)}
 	}
 	time.Sleep(10 * time.Millisecond)
@@ -96,9 +102,9 @@ func handleClick(req daemonRequest, connMgr *ConnectionManager, deviceID string)
 	// Click
 	var events []string
 	if doubleClick {
-		events = buildMouseDoubleClick(x, y, button)
+		events = buildMouseDoubleClick(x, y, rel, button)
 	} else {
-		events = buildMouseClick(x, y, button)
+		events = buildMouseClick(x, y, rel, button)
 	}
 
 	for _, evt := range events {
@@ -151,7 +157,11 @@ func handleKey(req daemonRequest, connMgr *ConnectionManager, deviceID string) d
 	meta := getBoolArg(req.Args, "meta", false)
 
 	keyCode := parseKeyName(key)
-	events := buildKeyPress(keyCode, ctrl, shift, alt, meta)
+	char := ""
+	if utf8.RuneCountInString(key) == 1 {
+		char = key
+	}
+	events := buildKeyPress(keyCode, char, ctrl, shift, alt, meta)
 
 	for _, evt := range events {
 		if err := conn.SendInput(evt); err != nil {
@@ -169,25 +179,63 @@ func handleScroll(req daemonRequest, connMgr *ConnectionManager, deviceID string
 		return daemonResponse{OK: false, Error: err.Error()}
 	}
 
+	// CLI delta is in notches (positive = right with horizontal)
 	delta := getIntArg(req.Args, "delta", 0)
-	x := getIntArg(req.Args, "x", -1)
-	y := getIntArg(req.Args, "y", -1)
+	horizontal := getBoolArg(req.Args, "horizontal", false)
+	x, y, rel := pointerArgs(req.Args)
+	_, hasX := req.Args["x"]
+	_, hasY := req.Args["y"]
+
+	event := buildScroll(delta)
+	if horizontal {
+		if err := conn.Require(protocol.CapInputWheel); err != nil {
+			return daemonResponse{OK: false, Error: err.Error()}
+		}
+		event = buildWheel(delta*protocol.WheelDelta, 0)
+	}
 
 	// Move mouse first if coordinates provided
-	if x >= 0 && y >= 0 {
-		if err := conn.SendInput(buildMouseMove(x, y)); err != nil {
+	if hasX && hasY && x >= 0 && y >= 0 {
+		if err := conn.SendInput(buildMouseMove(x, y, rel)); err != nil {
 			return daemonResponse{OK: false, Error: fmt.Sprintf("failed to move mouse: %v", err)}
 		}
 		time.Sleep(10 * time.Millisecond)
 	}
 
-	if err := conn.SendInput(buildScroll(delta)); err != nil {
+	if err := conn.SendInput(event); err != nil {
 		return daemonResponse{OK: false, Error: fmt.Sprintf("failed to scroll: %v", err)}
 	}
 
 	return daemonResponse{OK: true}
 }
 
+// handleMouse sends a single pointer move, button down or button up, for
+// drags and hovers that a click cannot express.
+func handleMouse(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	x, y, rel := pointerArgs(req.Args)
+	button := getStringArg(req.Args, "button", "left")
+
+	var event string
+	switch action := getStringArg(req.Args, "action", "move"); action {
+	case "move":
+		event = buildMouseMove(x, y, rel)
+	case "down", "up":
+		event = buildMouseButton(x, y, rel, button, action == "down")
+	default:
+		return daemonResponse{OK: false, Error: fmt.Sprintf("unknown mouse action %q (use move, down or up)", action)}
+	}
+
+	if err := conn.SendInput(event); err != nil {
+		return daemonResponse{OK: false, Error: fmt.Sprintf("failed to send mouse event: %v", err)}
+	}
+	return daemonResponse{OK: true}
+}
+
 func handleDisconnect(connMgr *ConnectionManager, deviceID string) daemonResponse {
 	if err := connMgr.Disconnect(deviceID); err != nil {
 		return daemonResponse{OK: false, Error: err.Error()}
@@ -197,6 +245,16 @@ func handleDisconnect(connMgr *ConnectionManager, deviceID string) daemonRespons
 
 // --- Arg helpers ---
 
+// pointerArgs reads the x, y target of a pointer command. With rel set they
+// are normalized 0..1 of the screen (recorded macros), otherwise pixels.
+func pointerArgs(args map[string]interface{}) (x, y float64, rel bool) {
+	rel = getBoolArg(args, "rel", false)
+	if rel {
+		return numFloat(args["x"]), numFloat(args["y"]), true
+	}
+	return float64(getIntArg(args, "x", 0)), float64(getIntArg(args, "y", 0)), false
+}
+
 func getIntArg(args map[string]interface{}, key string, def int) int {
 	if args == nil {
 		return def
@@ -10,11 +10,14 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/webrtc/v3"
 	"github.com/stangtennis/Remote/controller/internal/config"
+	"github.com/stangtennis/Remote/controller/internal/reconnection"
 	rtc "github.com/stangtennis/Remote/controller/internal/webrtc"
+	"github.com/stangtennis/Remote/protocol"
 )
 
 const idleTimeout = 5 * time.Minute
@@ -23,7 +26,7 @@ const idleTimeout = 5 * time.Minute
 type DeviceConnection struct {
 	client      *rtc.Client
 	signaling   *rtc.SignalingClient
-	sessionID   string
+	sessionID   string // guarded by mu; use session()
 	deviceID    string
 	deviceName  string
 	lastFrame   []byte
@@ -38,6 +41,12 @@ type DeviceConnection struct {
 	shellRouter   *channelRouter
 	processRouter *channelRouter
 	fileRouter    *fileTransferRouter
+
+	// ICE restart state. Answers received on the control channel are
+	// delivered to restartAnswers; recovering guards against overlapping
+	// restarts when Disconnected is followed by Failed.
+	restartAnswers chan restartAnswer
+	recovering     atomic.Bool
 }
 
 // channelRouter dispatches incoming JSON-with-"id" messages to per-id subscribers.
@@ -97,18 +106,20 @@ func (r *channelRouter) Dispatch(data []byte) {
 
 // ConnectionManager manages a pool of WebRTC connections
 type ConnectionManager struct {
-	connections map[string]*DeviceConnection // device_id -> connection
-	cfg         *config.Config
-	auth        *authInfo
-	mu          sync.RWMutex
+	connections  map[string]*DeviceConnection     // device_id -> connection
+	reconnectors map[string]*reconnection.Manager // device_id -> last-resort reconnect in progress
+	cfg          *config.Config
+	auth         *authInfo
+	mu           sync.RWMutex
 }
 
 // NewConnectionManager creates a new connection manager
 func NewConnectionManager(cfg *config.Config, auth *authInfo) *ConnectionManager {
 	return &ConnectionManager{
-		connections: make(map[string]*DeviceConnection),
-		cfg:         cfg,
-		auth:        auth,
+		connections:  make(map[string]*DeviceConnection),
+		reconnectors: make(map[string]*reconnection.Manager),
+		cfg:          cfg,
+		auth:         auth,
 	}
 }
 
@@ -130,15 +141,17 @@ func (cm *ConnectionManager) Connect(deviceID, deviceName string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create WebRTC client: %w", err)
 	}
+	client.SetProtocolIdentity(protocol.RoleCLI, "")
 
 	conn := &DeviceConnection{
-		client:        client,
-		deviceID:      deviceID,
-		deviceName:    deviceName,
-		lastUsedAt:    time.Now(),
-		shellRouter:   newChannelRouter(),
-		processRouter: newChannelRouter(),
-		fileRouter:    newFileTransferRouter(),
+		client:         client,
+		deviceID:       deviceID,
+		deviceName:     deviceName,
+		lastUsedAt:     time.Now(),
+		shellRouter:    newChannelRouter(),
+		processRouter:  newChannelRouter(),
+		fileRouter:     newFileTransferRouter(),
+		restartAnswers: make(chan restartAnswer, 4),
 	}
 
 	client.SetOnFrame(func(frameData []byte) {
@@ -158,6 +171,7 @@ func (cm *ConnectionManager) Connect(deviceID, deviceName string) error {
 	client.SetOnFileMessage(func(data []byte) {
 		conn.fileRouter.Dispatch(data)
 	})
+	client.SetOnRestartAnswer(conn.deliverRestartAnswer)
 
 	connectedCh := make(chan bool, 1)
 	client.SetOnConnected(func() {
@@ -176,6 +190,7 @@ func (cm *ConnectionManager) Connect(deviceID, deviceName string) error {
 		conn.mu.Lock()
 		conn.connected = false
 		conn.mu.Unlock()
+		go cm.recoverConnection(conn)
 	})
 
 	token := cm.auth.GetToken()
@@ -198,7 +213,7 @@ func (cm *ConnectionManager) Connect(deviceID, deviceName string) error {
 		client.Close()
 		return fmt.Errorf("failed to create session: %w", err)
 	}
-	conn.sessionID = session.SessionID
+	conn.setSession(session.SessionID)
 
 	offerJSON, err := client.CreateOffer()
 	if err != nil {
@@ -258,24 +273,28 @@ func (cm *ConnectionManager) ConnectSupport(supportSessionID string) error {
 	if err != nil {
 		return err
 	}
+	client.SetProtocolIdentity(protocol.RoleCLI, "")
 	conn := &DeviceConnection{
-		client:        client,
-		deviceID:      deviceKey,
-		deviceName:    "AI Support",
-		lastUsedAt:    time.Now(),
-		shellRouter:   newChannelRouter(),
-		processRouter: newChannelRouter(),
-		fileRouter:    newFileTransferRouter(),
+		client:         client,
+		deviceID:       deviceKey,
+		deviceName:     "AI Support",
+		lastUsedAt:     time.Now(),
+		shellRouter:    newChannelRouter(),
+		processRouter:  newChannelRouter(),
+		fileRouter:     newFileTransferRouter(),
+		restartAnswers: make(chan restartAnswer, 4),
 	}
 	client.SetOnFrame(func(frameData []byte) {
 		conn.mu.Lock()
-		conn.lastFrame = append(conn.lastFrame[:0], frameData...)
+		// A fresh slice: GetLastFrame callers keep decoding the old one
+		conn.lastFrame = append([]byte(nil), frameData...)
 		conn.lastFrameAt = time.Now()
 		conn.mu.Unlock()
 	})
 	client.SetOnShellMessage(func(data []byte) { conn.shellRouter.Dispatch(data) })
 	client.SetOnProcessMessage(func(data []byte) { conn.processRouter.Dispatch(data) })
 	client.SetOnFileMessage(func(data []byte) { conn.fileRouter.Dispatch(data) })
+	client.SetOnRestartAnswer(conn.deliverRestartAnswer)
 	connectedCh := make(chan bool, 1)
 	client.SetOnConnected(func() {
 		conn.mu.Lock()
@@ -290,6 +309,7 @@ func (cm *ConnectionManager) ConnectSupport(supportSessionID string) error {
 		conn.mu.Lock()
 		conn.connected = false
 		conn.mu.Unlock()
+		go cm.recoverConnection(conn)
 	})
 
 	token := cm.auth.GetToken()
@@ -320,7 +340,7 @@ func (cm *ConnectionManager) ConnectSupport(supportSessionID string) error {
 		client.Close()
 		return err
 	}
-	conn.sessionID = supportSessionID
+	conn.setSession(supportSessionID)
 	deadline := time.Now().Add(60 * time.Second)
 	answerReceived := false
 	processedSignals := make(map[int]bool)
@@ -433,6 +453,10 @@ func forceRelayEnabled() bool {
 // Disconnect closes a WebRTC connection
 func (cm *ConnectionManager) Disconnect(deviceID string) error {
 	cm.mu.Lock()
+	if r, ok := cm.reconnectors[deviceID]; ok {
+		r.Cancel()
+		delete(cm.reconnectors, deviceID)
+	}
 	conn, exists := cm.connections[deviceID]
 	if !exists {
 		cm.mu.Unlock()
@@ -441,8 +465,8 @@ func (cm *ConnectionManager) Disconnect(deviceID string) error {
 	delete(cm.connections, deviceID)
 	cm.mu.Unlock()
 
-	if conn.signaling != nil && conn.sessionID != "" {
-		conn.signaling.DeleteSession(conn.sessionID)
+	if sessionID := conn.session(); conn.signaling != nil && sessionID != "" {
+		conn.signaling.DeleteSession(sessionID)
 	}
 	return conn.client.Close()
 }
@@ -468,6 +492,20 @@ func (cm *ConnectionManager) GetConnection(deviceID string) (*DeviceConnection,
 	return conn, nil
 }
 
+// session returns the signaling session ID. Connection-state callbacks
+// start recovery while Connect may still be setting it.
+func (dc *DeviceConnection) session() string {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+	return dc.sessionID
+}
+
+func (dc *DeviceConnection) setSession(id string) {
+	dc.mu.Lock()
+	dc.sessionID = id
+	dc.mu.Unlock()
+}
+
 // GetLastFrame returns the cached last frame
 func (dc *DeviceConnection) GetLastFrame() ([]byte, time.Time) {
 	dc.mu.RLock()
@@ -501,6 +539,17 @@ func (dc *DeviceConnection) ShellReady() bool { return dc.client.ShellChannelRea
 // ProcessReady reports whether the process channel is open.
 func (dc *DeviceConnection) ProcessReady() bool { return dc.client.ProcessChannelReady() }
 
+// protocolWait bounds how long a command waits for the agent's hello. The
+// agent answers as soon as the control channel opens, so this only matters
+// for agents that predate the handshake.
+const protocolWait = 3 * time.Second
+
+// Require fails with a readable error when the agent lacks capability c,
+// instead of sending a request an older agent would silently drop.
+func (dc *DeviceConnection) Require(c string) error {
+	return dc.client.WaitProtocol(protocolWait).Require(c)
+}
+
 // StartIdleChecker starts a goroutine that disconnects idle connections
 func (cm *ConnectionManager) StartIdleChecker() {
 	go func() {
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -194,7 +195,7 @@ func runDaemon(deviceID, deviceName string) {
 			activityMu.Lock()
 			idle := time.Since(lastActivity)
 			activityMu.Unlock()
-			if idle > daemonIdleTimeout {
+			if idle > daemonIdleTimeout && daemonStreams.Load() == 0 {
 				log.Printf("[daemon] Idle timeout (%s), shutting down", idle.Round(time.Second))
 				connMgr.Disconnect(deviceID)
 				listener.Close()
@@ -260,6 +261,17 @@ func handleDaemonConnection(conn net.Conn, connMgr *ConnectionManager, deviceID,
 			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
 		}
 		return
+	case "run_script":
+		conn.SetDeadline(time.Now().Add(15 * time.Minute))
+		streamErr := handleScriptStream(conn, req, connMgr, deviceID)
+		if audit {
+			status := "succeeded"
+			if streamErr != nil {
+				status = "failed"
+			}
+			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
+		}
+		return
 	case "upload", "download":
 		conn.SetDeadline(time.Now().Add(15 * time.Minute))
 		streamErr := handleFileStream(conn, req, connMgr, deviceID)
@@ -271,6 +283,45 @@ func handleDaemonConnection(conn net.Conn, connMgr *ConnectionManager, deviceID,
 			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
 		}
 		return
+	case "watch":
+		conn.SetDeadline(time.Time{})
+		streamErr := handleWatchStream(conn, req, connMgr, deviceID)
+		if audit {
+			status := "succeeded"
+			if streamErr != nil {
+				status = "failed"
+			}
+			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
+		}
+		return
+	case "logs":
+		if !getBoolArg(req.Args, "follow", false) {
+			conn.SetDeadline(time.Now().Add(logsTimeout + 10*time.Second))
+			break
+		}
+		conn.SetDeadline(time.Time{})
+		streamErr := handleLogsStream(conn, req, connMgr, deviceID)
+		if audit {
+			status := "succeeded"
+			if streamErr != nil {
+				status = "failed"
+			}
+			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
+		}
+		return
+	case "clipboard_paste", "clipboard_copy":
+		conn.SetDeadline(time.Now().Add(15 * time.Minute))
+		streamErr := handleClipboardStream(conn, req, connMgr, deviceID)
+		if audit {
+			status := "succeeded"
+			if streamErr != nil {
+				status = "failed"
+			}
+			_ = connMgr.AuditSupportAction(deviceID, actionType, status, summary, target, details)
+		}
+		return
+	case "wait_stable", "wait_change", "find_image":
+		conn.SetDeadline(time.Now().Add(waitMaxTimeout + 30*time.Second))
 	}
 
 	resp := handleCommand(req, connMgr, deviceID, deviceName, startTime)
@@ -296,6 +347,9 @@ func handleDaemonConnection(conn net.Conn, connMgr *ConnectionManager, deviceID,
 
 func daemonAuditInfo(req daemonRequest) (string, string, string, map[string]interface{}, bool) {
 	details := map[string]interface{}{}
+	if via, ok := req.Args["via"].(string); ok && via != "" {
+		details["via"] = via
+	}
 	switch req.Cmd {
 	case "screenshot":
 		return "SCREEN_SCREENSHOT", "AI requested a screenshot", "screen", details, true
@@ -310,22 +364,129 @@ func daemonAuditInfo(req daemonRequest) (string, string, string, map[string]inte
 		return "INPUT_KEY", "AI pressed a key", "keyboard", details, true
 	case "scroll":
 		return "INPUT_SCROLL", "AI scrolled the remote desktop", "screen", details, true
+	case "mouse":
+		// Only the press is audited; moves and releases would flood the log.
+		if action, _ := req.Args["action"].(string); action != "down" {
+			return "", "", "", nil, false
+		}
+		details["operation"] = "drag"
+		return "INPUT_CLICK", "AI pressed a mouse button on the remote desktop", "screen", details, true
+	case "monitor":
+		if _, ok := req.Args["index"]; !ok {
+			return "", "", "", nil, false
+		}
+		details["index"] = getIntArg(req.Args, "index", 0)
+		return "SCREEN_MONITOR", "AI switched the captured monitor", "screen", details, true
 	case "exec":
 		if command, ok := req.Args["cmd"].(string); ok {
 			details["command_length"] = len(command)
 			details["command_sha256"] = fmt.Sprintf("%x", sha256.Sum256([]byte(command)))
 		}
 		return "SHELL_EXEC", "AI requested a remote shell command", "shell", details, true
+	case "run_script":
+		details["script_name"], _ = req.Args["script"].(string)
+		if v, ok := req.Args["version"].(float64); ok && v > 0 {
+			details["script_version"] = int(v)
+		}
+		if params, ok := req.Args["params"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(params))
+			for k := range params {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			details["params"] = names
+		}
+		return "SHELL_SCRIPT", "AI ran a library script", "shell", details, true
 	case "upload":
 		return "FILE_UPLOAD", "AI uploaded a file", "file", details, true
 	case "download":
 		return "FILE_DOWNLOAD", "AI downloaded a file", "file", details, true
+	case "clipboard_policy":
+		if len(req.Args) == 0 {
+			return "", "", "", nil, false
+		}
+		details["operation"], _ = req.Args["direction"].(string)
+		details["bytes"] = int64(numFloat(req.Args["max_bytes"]))
+		return "CLIPBOARD_POLICY", "AI changed the clipboard policy", "clipboard", details, true
+	case "print":
+		switch action, _ := req.Args["action"].(string); action {
+		case "on":
+			details["operation"] = "print"
+			if dir, ok := req.Args["save"].(string); ok && dir != "" {
+				details["operation"], details["path"] = "save", dir
+			}
+			return "PRINT_ENABLE", "AI enabled print forwarding", "printer", details, true
+		case "off":
+			return "PRINT_DISABLE", "AI disabled print forwarding", "printer", details, true
+		}
+		return "", "", "", nil, false
+	case "lock":
+		switch action, _ := req.Args["action"].(string); action {
+		case "on":
+			details["operation"] = "lock"
+			if blank, _ := req.Args["blank"].(bool); blank {
+				details["operation"] = "lock_blank"
+			}
+			return "ADMIN_LOCAL_LOCK", "AI locked local keyboard and mouse", "local_input", details, true
+		case "off":
+			return "ADMIN_LOCAL_UNLOCK", "AI unlocked local keyboard and mouse", "local_input", details, true
+		}
+		return "", "", "", nil, false
+	case "safemode":
+		details["delay_sec"] = int(numFloat(req.Args["delay_sec"]))
+		return "ADMIN_RESTART_SAFE_MODE", "AI restarted the device into Safe Mode", "device", details, true
+	case "clipboard_paste":
+		return "FILE_CLIPBOARD_PASTE", "AI pasted files copied on the remote desktop", "file", details, true
+	case "clipboard_copy":
+		if paths, ok := req.Args["paths"].([]interface{}); ok {
+			details["items"] = len(paths)
+		}
+		return "FILE_UPLOAD", "AI copied files to the remote clipboard", "file", details, true
 	case "ps":
 		return "PROCESS_PS", "AI requested a process list", "process", details, true
 	case "kill":
 		return "PROCESS_KILL", "AI requested a process termination", "process", details, true
 	case "sysinfo":
 		return "PROCESS_SYSINFO", "AI requested system information", "system", details, true
+	case "stats":
+		return "PROCESS_STATS", "AI requested the session stats timeline", "session", details, true
+	case "reg":
+		action, _ := req.Args["action"].(string)
+		switch action {
+		case "list", "get", "set", "delete", "export", "import":
+		default:
+			return "", "", "", nil, false
+		}
+		details["path"], _ = req.Args["path"].(string)
+		details["operation"] = action
+		return "PROCESS_REG_" + strings.ToUpper(action), "AI used the registry editor (" + action + ")", "registry", details, true
+	case "svc":
+		action, _ := req.Args["action"].(string)
+		switch action {
+		case "list":
+			return "PROCESS_SVC_LIST", "AI listed services", "service", details, true
+		case "start", "stop", "restart", "set-startup":
+		default:
+			return "", "", "", nil, false
+		}
+		details["service"], _ = req.Args["name"].(string)
+		details["operation"] = action
+		if startup, ok := req.Args["startup"].(string); ok {
+			details["operation"] = action + ":" + startup
+		}
+		actionType := "PROCESS_SVC_" + strings.ToUpper(strings.ReplaceAll(action, "-", "_"))
+		return actionType, "AI used the service manager (" + action + ")", "service", details, true
+	case "logs":
+		details["operation"] = "query"
+		if follow, _ := req.Args["follow"].(bool); follow {
+			details["operation"] = "follow"
+		}
+		return "PROCESS_LOG_QUERY", "AI read the system log", "system_log", details, true
+	case "find_image":
+		return "SCREEN_SCREENSHOT", "AI searched the screen for an image", "screen", details, true
+	case "watch":
+		details["operation"] = "watch"
+		return "SCREEN_SCREENSHOT", "AI watched the live screen", "screen", details, true
 	default:
 		return "", "", "", nil, false
 	}
@@ -349,6 +510,10 @@ func handleCommand(req daemonRequest, connMgr *ConnectionManager, deviceID, devi
 		return handleKey(req, connMgr, deviceID)
 	case "scroll":
 		return handleScroll(req, connMgr, deviceID)
+	case "mouse":
+		return handleMouse(req, connMgr, deviceID)
+	case "monitor":
+		return handleMonitor(req, connMgr, deviceID)
 	case "disconnect":
 		return handleDisconnect(connMgr, deviceID)
 	case "ps":
@@ -357,6 +522,30 @@ func handleCommand(req daemonRequest, connMgr *ConnectionManager, deviceID, devi
 		return handleKill(req, connMgr, deviceID)
 	case "sysinfo":
 		return handleSysinfo(req, connMgr, deviceID)
+	case "stats":
+		return handleStats(req, connMgr, deviceID)
+	case "reg":
+		return handleReg(req, connMgr, deviceID)
+	case "svc":
+		return handleSvc(req, connMgr, deviceID)
+	case "logs":
+		return handleLogs(req, connMgr, deviceID)
+	case "clipboard_files":
+		return handleClipboardFiles(req, connMgr, deviceID)
+	case "clipboard_policy":
+		return handleClipboardPolicy(req, connMgr, deviceID)
+	case "print":
+		return handlePrint(req, connMgr, deviceID)
+	case "lock":
+		return handleLock(req, connMgr, deviceID)
+	case "safemode":
+		return handleSafeMode(req, connMgr, deviceID)
+	case "wait_stable":
+		return handleWaitStable(req, connMgr, deviceID)
+	case "wait_change":
+		return handleWaitChange(req, connMgr, deviceID)
+	case "find_image":
+		return handleFindImage(req, connMgr, deviceID)
 	default:
 		return daemonResponse{OK: false, Error: fmt.Sprintf("unknown command: %s", req.Cmd)}
 	}
@@ -11,7 +11,7 @@ import (
 // streamMsg is the wire format used by streaming daemon → CLI commands. The
 // CLI reads JSON messages in a loop and stops once it sees Type=="end".
 type streamMsg struct {
-	Type    string  `json:"type"` // "started" | "stdout" | "stderr" | "exit" | "progress" | "end" | "error"
+	Type    string  `json:"type"` // "started" | "stdout" | "stderr" | "exit" | "progress" | "frame" | "logs" | "keepalive" | "end" | "error"
 	PID     int     `json:"pid,omitempty"`
 	Code    int     `json:"code"` // populated on "exit"
 	Data    string  `json:"data,omitempty"`
@@ -19,6 +19,8 @@ type streamMsg struct {
 	Total   int64   `json:"total,omitempty"`
 	Error   string  `json:"error,omitempty"`
 	Elapsed float64 `json:"elapsed_ms,omitempty"`
+	Width   int     `json:"width,omitempty"` // "frame": JPEG size in Data (base64)
+	Height  int     `json:"height,omitempty"`
 }
 
 // streamWriter serializes writes to a net.Conn from multiple goroutines.
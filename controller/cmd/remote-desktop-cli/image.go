@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -11,13 +12,34 @@ import (
 	"github.com/nfnt/resize"
 )
 
+// errRegionOutside is returned when a crop region misses the frame entirely.
+var errRegionOutside = errors.New("region is outside the screen")
+
 // downscaleJPEG takes a JPEG byte slice and returns a downscaled version
 func downscaleJPEG(jpegData []byte, maxWidth int, quality int) ([]byte, int, int, error) {
+	return cropScaleJPEG(jpegData, image.Rectangle{}, maxWidth, quality)
+}
+
+// cropScaleJPEG is downscaleJPEG that first crops to region (frame pixels)
+// when it is not empty.
+func cropScaleJPEG(jpegData []byte, region image.Rectangle, maxWidth int, quality int) ([]byte, int, int, error) {
 	img, _, err := image.Decode(bytes.NewReader(jpegData))
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("failed to decode JPEG: %w", err)
 	}
 
+	if !region.Empty() {
+		region = region.Intersect(img.Bounds())
+		if region.Empty() {
+			return nil, 0, 0, errRegionOutside
+		}
+		if sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			img = sub.SubImage(region)
+		}
+	}
+
 	bounds := img.Bounds()
 	origW := bounds.Dx()
 
@@ -1,83 +1,65 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/stangtennis/Remote/protocol"
 )
 
-// buildMouseMove creates a mouse_move input event
-func buildMouseMove(x, y int) string {
-	msg := map[string]interface{}{
-		"t": "mouse_move",
-		"x": x,
-		"y": y,
-	}
-	data, _ := json.Marshal(msg)
+// encodeEvent marshals one input event to the JSON text sent on the
+// control channel.
+func encodeEvent(ev interface{}) string {
+	data, _ := protocol.Encode(ev)
 	return string(data)
 }
 
+// buildMouseMove creates a mouse_move input event. x, y are screen pixels,
+// or normalized 0..1 of the screen when rel is set.
+func buildMouseMove(x, y float64, rel bool) string {
+	return encodeEvent(protocol.NewMouseMove(x, y, rel))
+}
+
+// buildMouseButton creates a single button down or up event
+func buildMouseButton(x, y float64, rel bool, button string, down bool) string {
+	return encodeEvent(protocol.NewMouseClick(button, down, x, y, rel))
+}
+
 // buildMouseClick creates mouse click events (down + up)
-func buildMouseClick(x, y int, button string) []string {
-	down := map[string]interface{}{
-		"t":      "mouse_click",
-		"button": button,
-		"down":   true,
-		"x":      x,
-		"y":      y,
+func buildMouseClick(x, y float64, rel bool, button string) []string {
+	return []string{
+		buildMouseButton(x, y, rel, button, true),
+		buildMouseButton(x, y, rel, button, false),
 	}
-	up := map[string]interface{}{
-		"t":      "mouse_click",
-		"button": button,
-		"down":   false,
-		"x":      x,
-		"y":      y,
-	}
-	downJSON, _ := json.Marshal(down)
-	upJSON, _ := json.Marshal(up)
-	return []string{string(downJSON), string(upJSON)}
 }
 
 // buildMouseDoubleClick creates double-click events
-func buildMouseDoubleClick(x, y int, button string) []string {
-	events := buildMouseClick(x, y, button)
-	events = append(events, buildMouseClick(x, y, button)...)
+func buildMouseDoubleClick(x, y float64, rel bool, button string) []string {
+	events := buildMouseClick(x, y, rel, button)
+	events = append(events, buildMouseClick(x, y, rel, button)...)
 	return events
 }
 
-// buildScroll creates a mouse_scroll event
+// buildScroll creates a mouse_scroll event (delta in notches, positive = up)
 func buildScroll(delta int) string {
-	msg := map[string]interface{}{
-		"t":     "mouse_scroll",
-		"delta": delta,
-	}
-	data, _ := json.Marshal(msg)
-	return string(data)
+	return encodeEvent(protocol.NewMouseScroll(float64(delta)))
 }
 
-// buildKeyPress creates key down + up events
-func buildKeyPress(code string, ctrl, shift, alt, meta bool) []string {
-	down := map[string]interface{}{
-		"t":     "key",
-		"code":  code,
-		"down":  true,
-		"ctrl":  ctrl,
-		"shift": shift,
-		"alt":   alt,
-		"meta":  meta,
-	}
-	up := map[string]interface{}{
-		"t":     "key",
-		"code":  code,
-		"down":  false,
-		"ctrl":  ctrl,
-		"shift": shift,
-		"alt":   alt,
-		"meta":  meta,
-	}
-	downJSON, _ := json.Marshal(down)
-	upJSON, _ := json.Marshal(up)
-	return []string{string(downJSON), string(upJSON)}
+// buildWheel creates a high-resolution mouse_scroll event in
+// protocol.WheelDelta units (positive dx = right, positive dy = up)
+func buildWheel(dx, dy int) string {
+	return encodeEvent(protocol.NewWheel(float64(dx), float64(dy)))
+}
+
+// buildKeyPress creates key down + up events. key is the character the key
+// types ("z" for Ctrl+Z) so layout-aware agents hit the right key on their
+// layout; leave it empty for named keys.
+func buildKeyPress(code, key string, ctrl, shift, alt, meta bool) []string {
+	down := protocol.NewKey(code, true, ctrl, shift, alt, meta)
+	down.Key = key
+	up := protocol.NewKey(code, false, ctrl, shift, alt, meta)
+	up.Key = key
+	return []string{encodeEvent(down), encodeEvent(up)}
 }
 
 // charToKeyCode converts a character to its key code
@@ -169,38 +151,23 @@ func charToKeyCode(c rune) (string, bool) {
 // buildTypeText generates key events for typing a string
 // buildTypeText generates key events for typing a string.
 // Uses "char" field for Unicode input — bypasses keyboard layout issues on non-US keyboards.
+// Layout-aware agents use "key" instead and press the matching key on their
+// own layout, so no modifier flags are set: nothing is physically held.
 func buildTypeText(text string) []string {
 	var events []string
 	for _, c := range text {
-		code, needShift := charToKeyCode(c)
+		code, _ := charToKeyCode(c)
 		if code == "" {
 			code = "Unidentified" // Use Unicode fallback for unknown chars
 		}
 		// Include "char" field so agent can use Unicode input (SendInput KEYEVENTF_UNICODE)
-		charStr := string(c)
-		down := map[string]interface{}{
-			"t":     "key",
-			"code":  code,
-			"down":  true,
-			"ctrl":  false,
-			"shift": needShift,
-			"alt":   false,
-			"meta":  false,
-			"char":  charStr,
-		}
-		up := map[string]interface{}{
-			"t":     "key",
-			"code":  code,
-			"down":  false,
-			"ctrl":  false,
-			"shift": needShift,
-			"alt":   false,
-			"meta":  false,
-			"char":  charStr,
-		}
-		downJSON, _ := json.Marshal(down)
-		upJSON, _ := json.Marshal(up)
-		events = append(events, string(downJSON), string(upJSON))
+		down := protocol.NewKey(code, true, false, false, false, false)
+		down.Char = string(c)
+		down.Key = string(c)
+		up := protocol.NewKey(code, false, false, false, false, false)
+		up.Char = string(c)
+		up.Key = string(c)
+		events = append(events, encodeEvent(down), encodeEvent(up))
 	}
 	return events
 }
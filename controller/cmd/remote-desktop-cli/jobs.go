@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const jobsUsage = `Usage:
+  remote-desktop-cli jobs list <device> [--all]
+  remote-desktop-cli jobs show <job_id>
+  remote-desktop-cli jobs cancel <job_id>
+  remote-desktop-cli jobs add <device> script <name>[@version] [--param k=v ...] [--as-user] [--timeout N] [options]
+  remote-desktop-cli jobs add <device> copy-file <bucket/path> <dest> --sha256 hex [options]
+  remote-desktop-cli jobs add <device> update [options]
+  remote-desktop-cli jobs add <device> reboot [--delay N] [options]
+
+Options:
+  --at <time>          Run at RFC3339 time or +duration (default: now)
+  --expires <dur>      Give up if not started within dur of run time (default 24h)
+  --retries <n>        Extra attempts after a failure (default 0)
+  --retry-delay <dur>  Wait between attempts (default 5m)`
+
+// deviceJob is one row of device_jobs.
+type deviceJob struct {
+	ID          string          `json:"id"`
+	DeviceID    string          `json:"device_id"`
+	JobType     string          `json:"job_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	RunAt       time.Time       `json:"run_at"`
+	ExpiresAt   *time.Time      `json:"expires_at"`
+	MaxAttempts int             `json:"max_attempts"`
+	Attempts    int             `json:"attempts"`
+	ExitCode    *int            `json:"exit_code"`
+	OutputTail  *string         `json:"output_tail"`
+	Error       *string         `json:"error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// cmdJobs manages the device_jobs queue. Jobs are picked up by the agent on
+// its next heartbeat, so the device does not need to be online (and no
+// daemon connection is needed).
+func cmdJobs() {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, jobsUsage)
+		os.Exit(2)
+	}
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	api := &jobsAPI{supabaseURL: cfg.SupabaseURL, anonKey: cfg.SupabaseAnonKey, auth: auth}
+
+	switch os.Args[2] {
+	case "list":
+		err = jobsList(api, os.Args[3:])
+	case "show":
+		err = jobsShow(api, os.Args[3])
+	case "cancel":
+		err = jobsCancel(api, os.Args[3])
+	case "add":
+		err = jobsAdd(api, os.Args[3:])
+	default:
+		fmt.Fprintln(os.Stderr, jobsUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func jobsList(api *jobsAPI, args []string) error {
+	all := false
+	for _, a := range args[1:] {
+		if a == "--all" {
+			all = true
+		}
+	}
+	dev, err := api.resolveDevice(args[0])
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("device_id", "eq."+dev.DeviceID)
+	q.Set("order", "created_at.desc")
+	if !all {
+		q.Set("limit", "20")
+	}
+	var jobs []deviceJob
+	if err := api.do("GET", "/rest/v1/device_jobs", q, nil, &jobs); err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Printf("No jobs for %s.\n", dev.DeviceName)
+		return nil
+	}
+
+	fmt.Printf("%-36s  %-14s  %-9s  %-16s  %-7s  %s\n", "ID", "TYPE", "STATUS", "RUN AT", "TRIES", "RESULT")
+	for _, j := range jobs {
+		result := ""
+		switch {
+		case j.Error != nil && *j.Error != "":
+			result = *j.Error
+		case j.ExitCode != nil:
+			result = fmt.Sprintf("exit %d", *j.ExitCode)
+		}
+		if len(result) > 50 {
+			result = result[:47] + "..."
+		}
+		fmt.Printf("%-36s  %-14s  %-9s  %-16s  %d/%-5d  %s\n",
+			j.ID, j.JobType, j.Status, j.RunAt.Local().Format("2006-01-02 15:04"), j.Attempts, j.MaxAttempts, result)
+	}
+	return nil
+}
+
+func jobsShow(api *jobsAPI, id string) error {
+	job, err := api.getJob(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Job:       %s\n", job.ID)
+	fmt.Printf("Device:    %s\n", job.DeviceID)
+	fmt.Printf("Type:      %s\n", job.JobType)
+	fmt.Printf("Status:    %s (attempt %d of %d)\n", job.Status, job.Attempts, job.MaxAttempts)
+	fmt.Printf("Run at:    %s\n", job.RunAt.Local().Format(time.RFC3339))
+	if job.ExpiresAt != nil {
+		fmt.Printf("Expires:   %s\n", job.ExpiresAt.Local().Format(time.RFC3339))
+	}
+	if job.StartedAt != nil {
+		fmt.Printf("Started:   %s\n", job.StartedAt.Local().Format(time.RFC3339))
+	}
+	if job.FinishedAt != nil {
+		fmt.Printf("Finished:  %s\n", job.FinishedAt.Local().Format(time.RFC3339))
+	}
+	if job.ExitCode != nil {
+		fmt.Printf("Exit code: %d\n", *job.ExitCode)
+	}
+	if job.Error != nil && *job.Error != "" {
+		fmt.Printf("Error:     %s\n", *job.Error)
+	}
+	if job.OutputTail != nil && *job.OutputTail != "" {
+		fmt.Println("Output (tail):")
+		fmt.Println(strings.TrimRight(*job.OutputTail, "\n"))
+	}
+	return nil
+}
+
+func jobsCancel(api *jobsAPI, id string) error {
+	q := url.Values{}
+	q.Set("id", "eq."+id)
+	q.Set("status", "eq.queued")
+	var rows []deviceJob
+	if err := api.do("PATCH", "/rest/v1/device_jobs", q, map[string]interface{}{
+		"status":      "cancelled",
+		"finished_at": time.Now().UTC(),
+	}, &rows); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		job, err := api.getJob(id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("job is %s, only queued jobs can be cancelled", job.Status)
+	}
+	fmt.Printf("Cancelled %s\n", id)
+	return nil
+}
+
+func jobsAdd(api *jobsAPI, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("missing device or job type\n%s", jobsUsage)
+	}
+	dev, err := api.resolveDevice(args[0])
+	if err != nil {
+		return err
+	}
+	kind := args[1]
+
+	runAt := time.Now()
+	expires := 24 * time.Hour
+	retries := 0
+	retryDelay := 5 * time.Minute
+	payload := map[string]interface{}{}
+	params := map[string]string{}
+	var positional []string
+
+	for i := 2; i < len(args); i++ {
+		a := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s needs a value", a)
+			}
+			i++
+			return args[i], nil
+		}
+		switch a {
+		case "--at":
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			if runAt, err = parseRunAt(v); err != nil {
+				return err
+			}
+		case "--expires", "--retry-delay":
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid duration for %s: %s", a, v)
+			}
+			if a == "--expires" {
+				expires = d
+			} else {
+				retryDelay = d
+			}
+		case "--retries", "--timeout", "--delay":
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid number for %s: %s", a, v)
+			}
+			switch a {
+			case "--retries":
+				retries = n
+			case "--timeout":
+				payload["timeout_sec"] = n
+			case "--delay":
+				payload["delay_sec"] = n
+			}
+		case "--param":
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			k, val, ok := strings.Cut(v, "=")
+			if !ok || k == "" {
+				return fmt.Errorf("--param wants k=v, got %q", v)
+			}
+			params[k] = val
+		case "--as-user":
+			payload["as_user"] = true
+		case "--sha256":
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			payload["sha256"] = strings.ToLower(v)
+		default:
+			positional = append(positional, a)
+		}
+	}
+
+	var jobType string
+	switch kind {
+	case "script":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: jobs add <device> script <name>[@version] [--param k=v ...] [--as-user] [--timeout N]")
+		}
+		name, version, err := parseScriptRef(positional[0])
+		if err != nil {
+			return err
+		}
+		// The job carries the signed script row; the agent verifies it
+		// before running, exactly like a live `run`.
+		script, err := fetchScript(api.supabaseURL, api.anonKey, api.auth.GetToken(), name, version)
+		if err != nil {
+			return err
+		}
+		if _, err := script.ResolveParams(params); err != nil {
+			return err
+		}
+		jobType = "run_script"
+		payload["script"] = script
+		payload["params"] = params
+	case "copy-file":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: jobs add <device> copy-file <bucket/path> <dest> --sha256 hex")
+		}
+		// The agent refuses a download it cannot check.
+		if sum, _ := payload["sha256"].(string); len(sum) != 64 || !isHex(sum) {
+			return fmt.Errorf("copy-file needs --sha256 with the file's 64-digit SHA-256")
+		}
+		bucket, path, ok := strings.Cut(positional[0], "/")
+		if !ok || bucket == "" || path == "" {
+			return fmt.Errorf("source must be <bucket>/<path>")
+		}
+		// The agent has no user session, so hand it a signed URL that stays
+		// valid for as long as the job can still run.
+		signed, err := api.signStorageURL(bucket, path, time.Until(runAt)+expires)
+		if err != nil {
+			return err
+		}
+		jobType = "copy_file"
+		payload["url"] = signed
+		payload["bucket"] = bucket
+		payload["path"] = path
+		payload["dest"] = positional[1]
+	case "update":
+		jobType = "install_update"
+	case "reboot":
+		jobType = "reboot"
+	default:
+		return fmt.Errorf("unknown job type %q (script, copy-file, update, reboot)", kind)
+	}
+
+	row := map[string]interface{}{
+		"device_id":       dev.DeviceID,
+		"created_by":      api.auth.userID,
+		"job_type":        jobType,
+		"payload":         payload,
+		"run_at":          runAt.UTC(),
+		"expires_at":      runAt.Add(expires).UTC(),
+		"max_attempts":    retries + 1,
+		"retry_delay_sec": int(retryDelay.Seconds()),
+	}
+	var created []deviceJob
+	if err := api.do("POST", "/rest/v1/device_jobs", nil, row, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("job was not created")
+	}
+	when := "on next heartbeat"
+	if time.Until(runAt) > time.Minute {
+		when = "at " + runAt.Local().Format("2006-01-02 15:04")
+	}
+	fmt.Printf("Queued %s job %s for %s (runs %s)\n", jobType, created[0].ID, dev.DeviceName, when)
+	return nil
+}
+
+// parseRunAt accepts an RFC3339 timestamp or a +duration relative to now.
+func parseRunAt(v string) (time.Time, error) {
+	if strings.HasPrefix(v, "+") {
+		d, err := time.ParseDuration(v[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --at duration: %s", v)
+		}
+		return time.Now().Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at time (want RFC3339 or +30m): %s", v)
+	}
+	return t, nil
+}
+
+// jobsAPI is a thin PostgREST/Storage client for the jobs command.
+type jobsAPI struct {
+	supabaseURL string
+	anonKey     string
+	auth        *authInfo
+}
+
+func (a *jobsAPI) do(method, path string, query url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	u := a.supabaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", a.anonKey)
+	req.Header.Set("Authorization", "Bearer "+a.auth.GetToken())
+	req.Header.Set("Content-Type", "application/json")
+	if method != "GET" {
+		req.Header.Set("Prefer", "return=representation")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (a *jobsAPI) getJob(id string) (*deviceJob, error) {
+	q := url.Values{}
+	q.Set("id", "eq."+id)
+	var rows []deviceJob
+	if err := a.do("GET", "/rest/v1/device_jobs", q, nil, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	return &rows[0], nil
+}
+
+// resolveDevice finds an owned device by device_id or name. Offline devices
+// are fine — that is what the queue is for.
+func (a *jobsAPI) resolveDevice(arg string) (*device, error) {
+	devices, err := fetchDevices(a.supabaseURL, a.anonKey, a.auth)
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		if devices[i].DeviceID == arg || strings.EqualFold(devices[i].DeviceName, arg) {
+			return &devices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("device '%s' not found", arg)
+}
+
+// signStorageURL creates a signed download URL for a storage object.
+func (a *jobsAPI) signStorageURL(bucket, path string, valid time.Duration) (string, error) {
+	var out struct {
+		SignedURL string `json:"signedURL"`
+	}
+	body := map[string]interface{}{"expiresIn": int(valid.Seconds())}
+	if err := a.do("POST", "/storage/v1/object/sign/"+bucket+"/"+path, nil, body, &out); err != nil {
+		return "", fmt.Errorf("sign storage URL: %w", err)
+	}
+	if out.SignedURL == "" {
+		return "", fmt.Errorf("sign storage URL: empty response")
+	}
+	return a.supabaseURL + "/storage/v1" + out.SignedURL, nil
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const lockUsageText = `Usage:
+  remote-desktop-cli lock                       Show whether local input is locked
+  remote-desktop-cli lock on [--blank] [--message <text>] [--timeout <duration>]
+                                                Block the remote device's own keyboard and mouse;
+                                                --blank covers the monitors with the message
+                                                (default timeout 10m, at most 4h)
+  remote-desktop-cli lock off                   Give keyboard and mouse back`
+
+// handleLock locks or unlocks local input on the agent when action is
+// given, and reports the lock state.
+func handleLock(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.Require(protocol.CapLocalLock); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	action := getStringArg(req.Args, "action", "")
+	switch action {
+	case "on":
+		blank, _ := req.Args["blank"].(bool)
+		err = conn.client.SetLocalLock(protocol.LocalLock{
+			Enabled:    true,
+			Blank:      blank,
+			Message:    getStringArg(req.Args, "message", ""),
+			TimeoutSec: int(numFloat(req.Args["timeout_sec"])),
+		})
+	case "off":
+		err = conn.client.SetLocalLock(protocol.LocalLock{})
+	case "":
+	default:
+		return daemonResponse{OK: false, Error: "unknown lock action: " + action}
+	}
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if state, ok := conn.client.LocalLockState(); ok {
+			if state.Error != "" {
+				return daemonResponse{OK: false, Error: state.Error}
+			}
+			return daemonResponse{OK: true, Data: map[string]interface{}{
+				"enabled":  state.Enabled,
+				"blanked":  state.Blanked,
+				"until":    state.Until,
+				"combo":    state.Combo,
+				"released": state.Released,
+			}}
+		}
+		// Nothing to wait for unless the lock was switched
+		if action == "" {
+			return daemonResponse{OK: true, Data: map[string]interface{}{"enabled": false}}
+		}
+		if time.Now().After(deadline) {
+			return daemonResponse{OK: false, Error: "agent did not answer the lock request"}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func cmdLock() {
+	args := map[string]interface{}{}
+	rest := os.Args[2:]
+	if len(rest) > 0 {
+		switch rest[0] {
+		case "on", "off":
+			args["action"] = rest[0]
+		default:
+			fmt.Fprintln(os.Stderr, lockUsageText)
+			os.Exit(2)
+		}
+		rest = rest[1:]
+	}
+	for i := 0; i < len(rest); i++ {
+		if args["action"] != "on" {
+			fmt.Fprintln(os.Stderr, lockUsageText)
+			os.Exit(2)
+		}
+		if rest[i] == "--blank" {
+			args["blank"] = true
+			continue
+		}
+		if i+1 >= len(rest) {
+			fmt.Fprintln(os.Stderr, lockUsageText)
+			os.Exit(2)
+		}
+		switch rest[i] {
+		case "--message":
+			args["message"] = rest[i+1]
+		case "--timeout":
+			d, err := time.ParseDuration(rest[i+1])
+			if err != nil || d < time.Second {
+				fmt.Fprintf(os.Stderr, "Error: invalid timeout %q\n", rest[i+1])
+				os.Exit(2)
+			}
+			args["timeout_sec"] = int(d / time.Second)
+		default:
+			fmt.Fprintln(os.Stderr, lockUsageText)
+			os.Exit(2)
+		}
+		i++
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "lock", Args: args})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+
+	if enabled, _ := resp.Data["enabled"].(bool); !enabled {
+		if released, _ := resp.Data["released"].(string); released != "" && released != protocol.LockReleasedController {
+			fmt.Printf("Local input: unlocked (%s)\n", released)
+			return
+		}
+		fmt.Println("Local input: unlocked")
+		return
+	}
+	until := time.Unix(int64(numFloat(resp.Data["until"])), 0)
+	fmt.Printf("Local input: locked until %s\n", until.Format("15:04:05"))
+	if blanked, _ := resp.Data["blanked"].(bool); blanked {
+		fmt.Println("Screen:      covered")
+	} else if args["blank"] == true {
+		fmt.Println("Screen:      not covered (not supported on this Windows version)")
+	}
+	if combo, _ := resp.Data["combo"].(string); combo != "" {
+		fmt.Printf("Release key: %s (on the remote device)\n", combo)
+	}
+}
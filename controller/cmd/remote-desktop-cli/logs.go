@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const logsUsageText = `Usage: remote-desktop-cli logs [--follow] [--since 1h] [--until 10m] [--level error]
+                               [--source <name>] [--channel <log>] [--grep <text>] [--limit N] [--json]
+
+Reads the device's system log: the Windows Event Log, the macOS unified log
+or the systemd journal on Linux. Shows the newest --limit entries (default
+100, at most 1000), oldest first; --follow then keeps printing new entries
+until Ctrl+C.
+
+  --since, --until  a duration ago (30m, 1h, 2d) or a time (2026-10-19T08:00)
+  --level           least severe level: critical, error, warning, info (default), debug
+  --source          Windows event provider, macOS subsystem or process,
+                    systemd unit or syslog identifier ("sshd")
+  --channel         Windows event logs, comma separated (default System,Application)
+  --grep            case-insensitive text in the message
+  --json            one JSON object per entry
+
+On macOS --since defaults to 1h; the unified log has no warning level.`
+
+// logsTimeout covers the agent's 30s query limit.
+const logsTimeout = 45 * time.Second
+
+// logsKeepalive is how often a quiet follow checks that the CLI is still
+// listening.
+const logsKeepalive = 5 * time.Second
+
+// logRequestFromArgs builds the agent request from daemon args.
+func logRequestFromArgs(args map[string]interface{}, id string) protocol.LogRequest {
+	return protocol.LogRequest{
+		Op:       protocol.OpLogQuery,
+		ID:       id,
+		Channel:  getStringArg(args, "channel", ""),
+		Source:   getStringArg(args, "source", ""),
+		Level:    getStringArg(args, "level", ""),
+		Text:     getStringArg(args, "text", ""),
+		SinceSec: numFloat(args["since_sec"]),
+		UntilSec: numFloat(args["until_sec"]),
+		Limit:    getIntArg(args, "limit", 0),
+		Follow:   getBoolArg(args, "follow", false),
+	}
+}
+
+// sendLogQuery checks the channel and sends req; replies arrive on the
+// subscription for req.ID.
+func sendLogQuery(conn *DeviceConnection, req protocol.LogRequest) (<-chan []byte, error) {
+	if !conn.ProcessReady() {
+		return nil, fmt.Errorf("process channel not open (agent likely older than v3.0.2)")
+	}
+	if err := conn.Require(protocol.CapLogs); err != nil {
+		return nil, err
+	}
+	sub := conn.processRouter.Subscribe(req.ID)
+	data, _ := protocol.Encode(req)
+	if err := conn.SendProcess(data); err != nil {
+		conn.processRouter.Unsubscribe(req.ID)
+		return nil, fmt.Errorf("send log_query: %w", err)
+	}
+	return sub, nil
+}
+
+// requestLogs runs a log_query and returns the entries of all its
+// log_query_result messages.
+func requestLogs(conn *DeviceConnection, req protocol.LogRequest, timeout time.Duration) ([]interface{}, error) {
+	sub, err := sendLogQuery(conn, req)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.processRouter.Unsubscribe(req.ID)
+
+	entries := []interface{}{}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				return nil, fmt.Errorf("process channel closed unexpectedly")
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(msg, &parsed); err != nil {
+				continue
+			}
+			switch parsed["op"] {
+			case protocol.OpLogQueryResult:
+				more, _ := parsed["more"].(bool)
+				batch, _ := parsed["entries"].([]interface{})
+				entries = append(entries, batch...)
+				if !more {
+					return entries, nil
+				}
+			case "error":
+				errStr, _ := parsed["error"].(string)
+				return nil, fmt.Errorf("agent error: %s", errStr)
+			}
+		case <-deadline:
+			return nil, fmt.Errorf("log_query timeout after %s", timeout)
+		}
+	}
+}
+
+func handleLogs(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	lr := logRequestFromArgs(req.Args, newExecID())
+	lr.Follow = false
+	entries, err := requestLogs(deviceConn, lr, logsTimeout)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	return daemonResponse{OK: true, Data: map[string]interface{}{"entries": entries, "count": len(entries)}}
+}
+
+// handleLogsStream follows the device's log for the CLI: the backlog and
+// then each new batch go out as "logs" messages whose Data is
+// {"entries":[...],"dropped":N}. It stops the follow on the agent when the
+// CLI goes away.
+func handleLogsStream(conn net.Conn, req daemonRequest, connMgr *ConnectionManager, deviceID string) error {
+	sw := newStreamWriter(conn)
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+	lr := logRequestFromArgs(req.Args, newExecID())
+	sub, err := sendLogQuery(deviceConn, lr)
+	if err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+	defer deviceConn.processRouter.Unsubscribe(lr.ID)
+	running := true
+	defer func() {
+		if running {
+			data, _ := protocol.Encode(protocol.LogRequest{Op: protocol.OpLogStop, ID: lr.ID})
+			_ = deviceConn.SendProcess(data)
+		}
+	}()
+
+	daemonStreams.Add(1)
+	defer daemonStreams.Add(-1)
+
+	keepalive := time.NewTicker(logsKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				running = false
+				sw.Send(streamMsg{Type: "error", Error: "process channel closed"})
+				return errors.New("process channel closed")
+			}
+			var parsed protocol.LogEntries
+			if err := json.Unmarshal(msg, &parsed); err != nil {
+				continue
+			}
+			switch parsed.Op {
+			case protocol.OpLogQueryResult, protocol.OpLogEntries:
+				if len(parsed.Entries) > 0 || parsed.Dropped > 0 {
+					data, _ := json.Marshal(map[string]interface{}{"entries": parsed.Entries, "dropped": parsed.Dropped})
+					if err := sw.Send(streamMsg{Type: "logs", Data: string(data)}); err != nil {
+						return nil // CLI stopped following
+					}
+				}
+				if parsed.Done {
+					running = false
+					if parsed.Error != "" {
+						sw.Send(streamMsg{Type: "error", Error: parsed.Error})
+						return errors.New(parsed.Error)
+					}
+					sw.Send(streamMsg{Type: "end"})
+					return nil
+				}
+			case "error":
+				running = false
+				var e struct{ Error string }
+				_ = json.Unmarshal(msg, &e)
+				sw.Send(streamMsg{Type: "error", Error: "agent error: " + e.Error})
+				return errors.New(e.Error)
+			}
+		case <-keepalive.C:
+			if err := sw.Send(streamMsg{Type: "keepalive"}); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// parseAgo turns "30m", "2d" or a local time into seconds before now.
+func parseAgo(s string) (float64, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.ParseFloat(days, 64); err == nil && n > 0 {
+			return n * 86400, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d.Seconds(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return max(time.Since(t).Seconds(), 1), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return max(time.Since(t).Seconds(), 1), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q (use a duration like 1h or a time like 2026-10-19T08:00)", s)
+}
+
+// printLogEntry prints one entry as a line, or as JSON with --json.
+func printLogEntry(raw interface{}, jsonOut bool) {
+	e, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if jsonOut {
+		out, _ := json.Marshal(e)
+		fmt.Println(string(out))
+		return
+	}
+	ts := stringOr(e["time"], "")
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		ts = t.Local().Format("2006-01-02 15:04:05")
+	}
+	source := stringOr(e["source"], stringOr(e["channel"], "-"))
+	if id := int(numFloat(e["event_id"])); id > 0 {
+		source += fmt.Sprintf(" (%d)", id)
+	} else if pid := int(numFloat(e["pid"])); pid > 0 {
+		source += fmt.Sprintf("[%d]", pid)
+	}
+	msg := strings.ReplaceAll(strings.ReplaceAll(stringOr(e["message"], ""), "\r\n", "\n"), "\n", "\n    ")
+	fmt.Printf("%s  %-8s  %s: %s\n", ts, strings.ToUpper(stringOr(e["level"], "-")), source, msg)
+}
+
+func cmdLogs() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, logsUsageText)
+		os.Exit(2)
+	}
+	args := map[string]interface{}{}
+	follow, jsonOut := false, false
+	for i := 2; i < len(os.Args); i++ {
+		a := os.Args[i]
+		switch a {
+		case "--follow", "-f":
+			follow = true
+		case "--json":
+			jsonOut = true
+		case "--since", "--until", "--level", "--source", "--channel", "--grep", "--limit":
+			if i+1 >= len(os.Args) {
+				usage()
+			}
+			i++
+			v := os.Args[i]
+			switch a {
+			case "--since", "--until":
+				ago, err := parseAgo(v)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(2)
+				}
+				args[strings.TrimPrefix(a, "--")+"_sec"] = ago
+			case "--grep":
+				args["text"] = v
+			case "--limit":
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 || n > protocol.LogMaxLimit {
+					fmt.Fprintf(os.Stderr, "Invalid limit: %s (1-%d)\n", v, protocol.LogMaxLimit)
+					os.Exit(2)
+				}
+				args["limit"] = n
+			default:
+				args[strings.TrimPrefix(a, "--")] = v
+			}
+		default:
+			usage()
+		}
+	}
+
+	if !follow {
+		resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: "logs", Args: args}, logsTimeout+10*time.Second)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !resp.OK {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+			os.Exit(1)
+		}
+		entries, _ := resp.Data["entries"].([]interface{})
+		for _, e := range entries {
+			printLogEntry(e, jsonOut)
+		}
+		if !jsonOut && len(entries) == 0 {
+			fmt.Fprintln(os.Stderr, "No matching log entries")
+		}
+		return
+	}
+
+	args["follow"] = true
+	conn, err := streamingDial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Time{}) // runs until Ctrl+C or the device disconnects
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Cmd: "logs", Args: args}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: send request: %v\n", err)
+		os.Exit(1)
+	}
+	dec := json.NewDecoder(conn)
+	for {
+		var m streamMsg
+		if err := dec.Decode(&m); err != nil {
+			fmt.Fprintf(os.Stderr, "Stream ended: daemon connection lost: %v\n", err)
+			os.Exit(1)
+		}
+		switch m.Type {
+		case "logs":
+			var batch struct {
+				Entries []interface{} `json:"entries"`
+				Dropped int           `json:"dropped"`
+			}
+			if err := json.Unmarshal([]byte(m.Data), &batch); err != nil {
+				continue
+			}
+			for _, e := range batch.Entries {
+				printLogEntry(e, jsonOut)
+			}
+			if batch.Dropped > 0 {
+				fmt.Fprintf(os.Stderr, "(%d entries dropped; narrow the filter)\n", batch.Dropped)
+			}
+		case "end":
+			return
+		case "error":
+			fmt.Fprintf(os.Stderr, "Stream ended: %s\n", m.Error)
+			os.Exit(1)
+		}
+	}
+}
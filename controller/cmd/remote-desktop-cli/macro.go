@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/controller/internal/macro"
+)
+
+const macroUsageText = `Usage: remote-desktop-cli macro run <file.yaml|file.json> [--var name=value ...] [--speed 2] [--dry-run]
+
+Replays a recorded input macro on the connected device. Record one from the
+controller viewer (Optag makro), or write it by hand:
+
+  name: login
+  variables: {user: admin}
+  steps:
+    - {action: wait_image, image: login-button.png, timeout_ms: 10000, click: true}
+    - {action: type, text: "${user}", delay_ms: 300}
+    - {action: key, key: Tab}
+    - {action: type, text: "${password}"}
+    - {action: key, key: Enter}
+
+Coordinates are 0..1 of the remote screen; images are relative to the macro
+file. ${name} in typed text is replaced by --var name=value or the macro's
+variables.`
+
+// macroDefaultWaitMs is the wait timeout for steps that do not set one.
+const macroDefaultWaitMs = 10000
+
+func cmdMacro() {
+	if len(os.Args) < 4 || os.Args[2] != "run" {
+		fmt.Fprintln(os.Stderr, macroUsageText)
+		os.Exit(2)
+	}
+
+	var file string
+	vars := map[string]string{}
+	speed := 1.0
+	dryRun := false
+	rest := os.Args[3:]
+	for i := 0; i < len(rest); i++ {
+		switch a := rest[i]; a {
+		case "--dry-run":
+			dryRun = true
+		case "--var", "--speed":
+			if i+1 >= len(rest) {
+				fmt.Fprintln(os.Stderr, macroUsageText)
+				os.Exit(2)
+			}
+			i++
+			if a == "--speed" {
+				s, err := strconv.ParseFloat(rest[i], 64)
+				if err != nil || s <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid speed %q\n", rest[i])
+					os.Exit(2)
+				}
+				speed = s
+				continue
+			}
+			k, v, ok := strings.Cut(rest[i], "=")
+			if !ok || k == "" {
+				fmt.Fprintf(os.Stderr, "Error: --var expects name=value, got %q\n", rest[i])
+				os.Exit(2)
+			}
+			vars[k] = v
+		default:
+			if strings.HasPrefix(a, "-") || file != "" {
+				fmt.Fprintln(os.Stderr, macroUsageText)
+				os.Exit(2)
+			}
+			file = a
+		}
+	}
+	if file == "" {
+		fmt.Fprintln(os.Stderr, macroUsageText)
+		os.Exit(2)
+	}
+
+	m, err := macro.Load(file)
+	if err == nil {
+		m, err = m.Expand(vars)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	abs, _ := filepath.Abs(file)
+	dir := filepath.Dir(abs)
+
+	name := m.Name
+	if name == "" {
+		name = filepath.Base(file)
+	}
+	fmt.Printf("Running macro %s: %d steps, ~%s\n", name, len(m.Steps),
+		(time.Duration(float64(m.Duration())/speed) * time.Millisecond).Round(100*time.Millisecond))
+
+	start := time.Now()
+	for i, s := range m.Steps {
+		if s.DelayMs > 0 && !dryRun {
+			time.Sleep(time.Duration(float64(s.DelayMs)/speed) * time.Millisecond)
+		}
+		fmt.Printf("[%d/%d] %s\n", i+1, len(m.Steps), macroStepSummary(s))
+		if dryRun {
+			continue
+		}
+		if err := runMacroStep(s, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", i+1, s.Action, err)
+			os.Exit(1)
+		}
+	}
+	if !dryRun {
+		fmt.Printf("Done in %s\n", time.Since(start).Round(100*time.Millisecond))
+	}
+}
+
+// runMacroStep replays one step through the daemon, so every step goes
+// through the same audit path as the matching CLI command.
+func runMacroStep(s macro.Step, dir string) error {
+	button := s.Button
+	if button == "" {
+		button = "left"
+	}
+	switch s.Action {
+	case macro.ActionMove, macro.ActionDown, macro.ActionUp:
+		_, err := macroRequest("mouse", map[string]interface{}{
+			"action": s.Action, "x": s.X, "y": s.Y, "rel": true, "button": button,
+		}, 0)
+		return err
+	case macro.ActionClick:
+		_, err := macroRequest("click", map[string]interface{}{
+			"x": s.X, "y": s.Y, "rel": true, "button": button, "double_click": s.Double,
+		}, 0)
+		return err
+	case macro.ActionType:
+		_, err := macroRequest("type", map[string]interface{}{"text": s.Text}, 0)
+		return err
+	case macro.ActionKey:
+		_, err := macroRequest("key", map[string]interface{}{
+			"key": macroKeyName(s.Key), "ctrl": s.Ctrl, "shift": s.Shift, "alt": s.Alt, "meta": s.Meta,
+		}, 0)
+		return err
+	case macro.ActionScroll:
+		_, err := macroRequest("scroll", map[string]interface{}{
+			"delta": s.Delta, "horizontal": s.Horizontal,
+		}, 0)
+		return err
+	case macro.ActionWaitStable:
+		timeout := macroWaitMs(s)
+		_, err := macroRequest("wait_stable", map[string]interface{}{"timeout_ms": timeout}, timeout)
+		return err
+	case macro.ActionWaitImage:
+		image := s.Image
+		if !filepath.IsAbs(image) {
+			image = filepath.Join(dir, image)
+		}
+		args := map[string]interface{}{"template": image, "timeout_ms": macroWaitMs(s)}
+		if s.Threshold > 0 {
+			args["threshold"] = s.Threshold
+		}
+		data, err := macroRequest("find_image", args, macroWaitMs(s))
+		if err != nil || !s.Click {
+			return err
+		}
+		_, err = macroRequest("click", map[string]interface{}{
+			"x": numFloat(data["center_x"]), "y": numFloat(data["center_y"]), "button": button, "double_click": s.Double,
+		}, 0)
+		return err
+	}
+	return fmt.Errorf("unknown action")
+}
+
+func macroRequest(cmd string, args map[string]interface{}, waitMs int) (map[string]interface{}, error) {
+	args["via"] = "macro"
+	resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: cmd, Args: args},
+		time.Duration(waitMs)*time.Millisecond+30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return resp.Data, nil
+}
+
+func macroWaitMs(s macro.Step) int {
+	if s.TimeoutMs > 0 {
+		return min(s.TimeoutMs, int(waitMaxTimeout/time.Millisecond))
+	}
+	return macroDefaultWaitMs
+}
+
+// macroKeyName maps recorded KeyboardEvent codes for letters and digits to
+// the character, so shortcuts like Ctrl+KeyC land on the remote layout's C.
+func macroKeyName(code string) string {
+	if len(code) == 4 && strings.HasPrefix(code, "Key") {
+		return strings.ToLower(code[3:])
+	}
+	if len(code) == 6 && strings.HasPrefix(code, "Digit") {
+		return code[5:]
+	}
+	return code
+}
+
+func macroStepSummary(s macro.Step) string {
+	switch s.Action {
+	case macro.ActionMove, macro.ActionDown, macro.ActionUp:
+		return fmt.Sprintf("%s %.3f,%.3f", s.Action, s.X, s.Y)
+	case macro.ActionClick:
+		kind := "click"
+		if s.Double {
+			kind = "double-click"
+		}
+		if s.Button != "" {
+			kind = s.Button + " " + kind
+		}
+		return fmt.Sprintf("%s %.3f,%.3f", kind, s.X, s.Y)
+	case macro.ActionType:
+		// Typed text may hold substituted secrets, so only the length is shown
+		return fmt.Sprintf("type %d chars", len([]rune(s.Text)))
+	case macro.ActionKey:
+		var mods []string
+		for _, m := range []struct {
+			on   bool
+			name string
+		}{{s.Ctrl, "Ctrl"}, {s.Alt, "Alt"}, {s.Shift, "Shift"}, {s.Meta, "Meta"}} {
+			if m.on {
+				mods = append(mods, m.name)
+			}
+		}
+		return "key " + strings.Join(append(mods, s.Key), "+")
+	case macro.ActionScroll:
+		return fmt.Sprintf("scroll %d", s.Delta)
+	case macro.ActionWaitImage:
+		if s.Click {
+			return "wait for " + s.Image + " and click it"
+		}
+		return "wait for " + s.Image
+	}
+	return s.Action
+}
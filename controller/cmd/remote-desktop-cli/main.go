@@ -41,10 +41,24 @@ func main() {
 		cmdKey()
 	case "scroll":
 		cmdScroll()
+	case "monitor":
+		cmdMonitor()
 	case "status":
 		cmdStatus()
 	case "exec":
 		cmdExec()
+	case "run":
+		cmdRun()
+	case "scripts":
+		cmdScripts()
+	case "clipboard":
+		cmdClipboard()
+	case "print":
+		cmdPrint()
+	case "lock":
+		cmdLock()
+	case "safemode":
+		cmdSafeMode()
 	case "upload":
 		cmdUpload()
 	case "download":
@@ -55,6 +69,32 @@ func main() {
 		cmdKill()
 	case "sysinfo":
 		cmdSysinfo()
+	case "stats":
+		cmdStats()
+	case "reg":
+		cmdReg()
+	case "svc":
+		cmdSvc()
+	case "logs":
+		cmdLogs()
+	case "netcheck":
+		cmdNetcheck()
+	case "jobs":
+		cmdJobs()
+	case "mcp":
+		cmdMCP()
+	case "wait-stable":
+		cmdWaitStable()
+	case "wait-change":
+		cmdWaitChange()
+	case "find-image":
+		cmdFindImage()
+	case "diff":
+		cmdDiff()
+	case "macro":
+		cmdMacro()
+	case "watch":
+		cmdWatch()
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -75,19 +115,50 @@ Commands:
   support-list                      List AI clients and their short keys
   disconnect                        Disconnect and stop daemon
   screenshot [-o file.jpg]          Take screenshot and save to file
+  watch [--listen addr] [--fps 5] [--max-width 1280] [--region x,y,w,h] [-o dir]  Live MJPEG stream on localhost (or image sequence)
   click <x> <y> [--right|--double]  Click at coordinates
   type "text"                       Type text
   key <key> [--ctrl] [--shift] [--alt]  Press a key
-  scroll <delta> [--at x,y]        Scroll (positive=down, negative=up)
+  scroll <delta> [--at x,y] [--horizontal]  Scroll (positive=down/right, negative=up/left)
+  monitor [list|<index>|all]        List monitors or switch capture (all = span every display)
   status                            Show connection status
+  wait-stable [--timeout 10s] [--quiet 500ms] [--region x,y,w,h]  Wait until the screen stops changing
+  wait-change [--region x,y,w,h] [--timeout 10s]  Wait until the screen (or region) changes
+  find-image <template.png> [--threshold 0.9] [--timeout 5s]  Locate an image on the screen
+  diff <a.jpg> <b.jpg> [-o diff.png]  Compare two screenshots (exit 1 if they differ)
+  macro run <file> [--var k=v] [--speed 2] [--dry-run]  Replay a recorded input macro
+  netcheck [--json]                 Check NAT type, TURN reachability, MTU and clock skew
+  mcp                               Serve the commands below as MCP tools over stdio
 
 Remote admin (v3.0.2+ agent):
   exec [--as-user] [--timeout=N] "<cmd>"  Run PowerShell (Windows) / bash (macOS)
+  run <script>[@ver] [--param k=v ...]    Run a signed library script (see: scripts list)
   upload <local> <remote>                 Upload local file to remote path
   download <remote> <local>               Download remote file to local path
+  clipboard [files|paste <dir>|copy <path>...]  Files copied on the remote / paste them here / copy files there
+  clipboard policy [--direction d] [--max-size n] [--formats f,...]  Show or limit clipboard sync
+  print [on [--printer name|--save dir]|off]  Print jobs from the remote virtual printer here
+  lock [on [--blank] [--message t] [--timeout 10m]|off]  Block the remote device's own keyboard and mouse
+  safemode [--delay 10s]                  Restart into Safe Mode with Networking (Windows); agent reconnects
   ps                                      List running processes
   kill <pid>                              Terminate a process by PID
   sysinfo                                 OS / CPU / RAM / disk / installed apps
+  stats [--since 10m] [--json]            Session timeline: RTT, loss, bitrate, mode switches
+  stats --device <d> [--since 24h] | --session <id>  Uploaded timelines of finished sessions
+  reg list|get|set|delete|export|import ...  Registry editor (read-only defaults and /etc on macOS)
+  svc list|start|stop|restart|set-startup ...  Services (SCM / launchd / systemd)
+  logs [--follow] [--since 1h] [--level error] [--json] ...  System log (Event Log / unified log / journald)
+
+Script library:
+  scripts list | show <name>[@ver]        Browse published scripts
+  scripts keygen [--out signing.key]      Create an ed25519 signing key pair
+  scripts publish <script.json> --key <k> Sign and publish a script version
+
+Queued jobs (run on next heartbeat, device may be offline):
+  jobs list <device> [--all]              List queued and finished jobs
+  jobs add <device> script <file|->       Queue a script (also: copy-file, update, reboot)
+  jobs show <job_id>                      Job details with exit code and output tail
+  jobs cancel <job_id>                    Cancel a queued job
 
 Environment:
   RD_EMAIL      Supabase email (required for list/connect)
@@ -96,6 +167,12 @@ Environment:
 
 // sendDaemonRequest sends a JSON request to the daemon and returns the response
 func sendDaemonRequest(req daemonRequest) (*daemonResponse, error) {
+	return sendDaemonRequestTimeout(req, 30*time.Second)
+}
+
+// sendDaemonRequestTimeout is sendDaemonRequest for commands that may take
+// longer than 30 seconds to answer.
+func sendDaemonRequestTimeout(req daemonRequest, timeout time.Duration) (*daemonResponse, error) {
 	socketPath := getSocketPath()
 	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
 	if err != nil {
@@ -103,7 +180,7 @@ func sendDaemonRequest(req daemonRequest) (*daemonResponse, error) {
 	}
 	defer conn.Close()
 
-	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	conn.SetDeadline(time.Now().Add(timeout))
 
 	encoder := json.NewEncoder(conn)
 	if err := encoder.Encode(req); err != nil {
@@ -148,20 +225,26 @@ func cmdConnect() {
 		fmt.Fprintln(os.Stderr, "Usage: remote-desktop-cli connect <device_id_or_name>")
 		os.Exit(1)
 	}
-	deviceArg := os.Args[2]
-
-	// Start daemon
-	auth, cfg, err := getAuthAndConfig()
+	msg, err := connectDevice(os.Args[2])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Println(msg)
+}
+
+// connectDevice resolves deviceArg by device_id or device_name and starts a
+// daemon connected to it, reusing one that is already connected.
+func connectDevice(deviceArg string) (string, error) {
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return "", err
+	}
 
 	// Fetch devices and resolve by device_id or device_name
 	devices, err := fetchDevices(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching devices: %v\n", err)
-		os.Exit(1)
+		return "", fmt.Errorf("fetching devices: %w", err)
 	}
 
 	var found *device
@@ -172,12 +255,10 @@ func cmdConnect() {
 		}
 	}
 	if found == nil {
-		fmt.Fprintf(os.Stderr, "Error: device '%s' not found\n", deviceArg)
-		os.Exit(1)
+		return "", fmt.Errorf("device '%s' not found", deviceArg)
 	}
 	if !found.isOnline() {
-		fmt.Fprintf(os.Stderr, "Error: device '%s' is offline (last seen: %s)\n", found.DeviceName, found.LastSeen.Format(time.RFC3339))
-		os.Exit(1)
+		return "", fmt.Errorf("device '%s' is offline (last seen: %s)", found.DeviceName, found.LastSeen.Format(time.RFC3339))
 	}
 
 	deviceID := found.DeviceID
@@ -186,8 +267,7 @@ func cmdConnect() {
 	// Check if daemon is already running and connected to this device
 	if resp, err := sendDaemonRequest(daemonRequest{Cmd: "status"}); err == nil && resp.OK {
 		if connID, ok := resp.Data["device_id"].(string); ok && connID == deviceID {
-			fmt.Printf("Already connected to %s (%s)\n", deviceName, deviceID)
-			return
+			return fmt.Sprintf("Already connected to %s (%s)", deviceName, deviceID), nil
 		}
 		// Connected to different device — disconnect first
 		sendDaemonRequest(daemonRequest{Cmd: "disconnect"})
@@ -196,11 +276,10 @@ func cmdConnect() {
 
 	pid, err := startDaemon(cfg, auth, deviceID, deviceName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return "", err
 	}
 
-	fmt.Printf("Connected to %s (%s). Daemon running (PID %d).\n", deviceName, deviceID, pid)
+	return fmt.Sprintf("Connected to %s (%s). Daemon running (PID %d).", deviceName, deviceID, pid), nil
 }
 
 func cmdSupportConnect() {
@@ -588,7 +667,7 @@ func cmdKey() {
 
 func cmdScroll() {
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: remote-desktop-cli scroll <delta> [--at x,y]")
+		fmt.Fprintln(os.Stderr, "Usage: remote-desktop-cli scroll <delta> [--at x,y] [--horizontal]")
 		os.Exit(1)
 	}
 
@@ -600,8 +679,11 @@ func cmdScroll() {
 
 	x := -1
 	y := -1
+	horizontal := false
 	for i := 3; i < len(os.Args); i++ {
-		if os.Args[i] == "--at" && i+1 < len(os.Args) {
+		if os.Args[i] == "--horizontal" {
+			horizontal = true
+		} else if os.Args[i] == "--at" && i+1 < len(os.Args) {
 			parts := strings.Split(os.Args[i+1], ",")
 			if len(parts) == 2 {
 				x, _ = strconv.Atoi(strings.TrimSpace(parts[0]))
@@ -614,9 +696,10 @@ func cmdScroll() {
 	resp, err := sendDaemonRequest(daemonRequest{
 		Cmd: "scroll",
 		Args: map[string]interface{}{
-			"delta": delta,
-			"x":     x,
-			"y":     y,
+			"delta":      delta,
+			"horizontal": horizontal,
+			"x":          x,
+			"y":          y,
 		},
 	})
 	if err != nil {
@@ -632,6 +715,12 @@ func cmdScroll() {
 	if delta < 0 {
 		direction = "up"
 	}
+	if horizontal {
+		direction = "right"
+		if delta < 0 {
+			direction = "left"
+		}
+	}
 	if delta < 0 {
 		delta = -delta
 	}
@@ -654,6 +743,8 @@ func cmdStatus() {
 	connected, _ := resp.Data["connected"].(bool)
 	uptime, _ := resp.Data["uptime"].(string)
 	frameAge, _ := resp.Data["frame_age"].(string)
+	layout, _ := resp.Data["keyboard_layout"].(string)
+	lang, _ := resp.Data["keyboard_lang"].(string)
 	pid, _ := resp.Data["pid"].(float64)
 
 	if connected {
@@ -663,6 +754,9 @@ func cmdStatus() {
 		if frameAge != "" {
 			fmt.Printf("Frame age:    %s\n", frameAge)
 		}
+		if layout != "" {
+			fmt.Printf("Keyboard:     %s (%s)\n", lang, layout)
+		}
 	} else {
 		fmt.Println("Not connected")
 	}
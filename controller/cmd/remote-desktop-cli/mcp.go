@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// mcpMaxOutput caps stdout and stderr of an exec tool call so one chatty
+// command cannot flood the assistant's context.
+const mcpMaxOutput = 256 << 10
+
+const mcpInstructions = `Controls one remote desktop at a time through the remote-desktop-cli daemon.
+Call devices to find a device and connect to attach to it; every other tool acts on the connected device.
+Click and scroll coordinates are remote screen pixels. screenshot returns the width and height of the
+image it sends; pass a larger max_width if you need to map pixels exactly.
+Every action is recorded in the session's audit log.`
+
+// cmdMCP serves the daemon commands as MCP tools over stdio. Tool calls go
+// through the same daemon socket as the CLI, so they are audited by
+// daemonAuditInfo exactly like their shell counterparts (tagged via=mcp).
+func cmdMCP() {
+	s := server.NewMCPServer("remote-desktop-cli", "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithInstructions(mcpInstructions),
+		server.WithRecovery(),
+	)
+	addMCPTools(s)
+	if err := server.ServeStdio(s); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func addMCPTools(s *server.MCPServer) {
+	s.AddTool(mcp.NewTool("devices",
+		mcp.WithDescription("List the account's devices with platform and online status"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpDevices)
+
+	s.AddTool(mcp.NewTool("connect",
+		mcp.WithDescription("Connect to a device by ID or name; replaces any current connection"),
+		mcp.WithString("device", mcp.Required(), mcp.Description("device_id or device_name from devices")),
+	), mcpConnect)
+
+	s.AddTool(mcp.NewTool("screenshot",
+		mcp.WithDescription("Capture the remote screen as a JPEG image"),
+		mcp.WithNumber("max_width", mcp.DefaultNumber(1280), mcp.Min(160), mcp.Max(3840), mcp.Description("Downscale to at most this width")),
+		mcp.WithNumber("quality", mcp.DefaultNumber(60), mcp.Min(10), mcp.Max(95), mcp.Description("JPEG quality")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpScreenshot)
+
+	s.AddTool(mcp.NewTool("click",
+		mcp.WithDescription("Move the mouse to (x, y) and click"),
+		mcp.WithNumber("x", mcp.Required(), mcp.Description("Remote screen X in pixels")),
+		mcp.WithNumber("y", mcp.Required(), mcp.Description("Remote screen Y in pixels")),
+		mcp.WithString("button", mcp.Enum("left", "right", "middle"), mcp.DefaultString("left")),
+		mcp.WithBoolean("double", mcp.DefaultBool(false), mcp.Description("Double-click")),
+	), mcpClick)
+
+	s.AddTool(mcp.NewTool("type_text",
+		mcp.WithDescription("Type text on the remote keyboard"),
+		mcp.WithString("text", mcp.Required()),
+	), mcpType)
+
+	s.AddTool(mcp.NewTool("key",
+		mcp.WithDescription("Press a key, optionally with modifiers (e.g. enter, tab, escape, f5, a)"),
+		mcp.WithString("key", mcp.Required()),
+		mcp.WithBoolean("ctrl", mcp.DefaultBool(false)),
+		mcp.WithBoolean("shift", mcp.DefaultBool(false)),
+		mcp.WithBoolean("alt", mcp.DefaultBool(false)),
+		mcp.WithBoolean("meta", mcp.DefaultBool(false), mcp.Description("Windows / Command / Super key")),
+	), mcpKey)
+
+	s.AddTool(mcp.NewTool("scroll",
+		mcp.WithDescription("Scroll the mouse wheel; positive is down/right, negative up/left"),
+		mcp.WithNumber("delta", mcp.Required()),
+		mcp.WithNumber("x", mcp.Description("Scroll at this X (default: current position)")),
+		mcp.WithNumber("y", mcp.Description("Scroll at this Y (default: current position)")),
+		mcp.WithBoolean("horizontal", mcp.DefaultBool(false)),
+	), mcpScroll)
+
+	s.AddTool(mcp.NewTool("wait_stable",
+		mcp.WithDescription("Wait until the screen stops changing, e.g. after a click; use instead of sleeping"),
+		mcp.WithNumber("timeout_ms", mcp.DefaultNumber(10000), mcp.Min(0), mcp.Max(600000)),
+		mcp.WithNumber("quiet_ms", mcp.DefaultNumber(500), mcp.Min(0), mcp.Description("How long nothing may change")),
+		mcp.WithString("region", mcp.Description("Only watch x,y,w,h")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpWait("wait_stable"))
+
+	s.AddTool(mcp.NewTool("wait_change",
+		mcp.WithDescription("Wait until the screen (or a region) differs from how it looks now"),
+		mcp.WithNumber("timeout_ms", mcp.DefaultNumber(10000), mcp.Min(0), mcp.Max(600000)),
+		mcp.WithString("region", mcp.Description("Only watch x,y,w,h")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpWait("wait_change"))
+
+	s.AddTool(mcp.NewTool("find_image",
+		mcp.WithDescription("Locate a template image (local PNG/JPEG file) on the remote screen; returns its position and center"),
+		mcp.WithString("template", mcp.Required(), mcp.Description("Local path of the template image")),
+		mcp.WithNumber("threshold", mcp.DefaultNumber(0.9), mcp.Min(0.1), mcp.Max(1)),
+		mcp.WithNumber("timeout_ms", mcp.DefaultNumber(0), mcp.Min(0), mcp.Max(600000), mcp.Description("Keep looking until it appears")),
+		mcp.WithString("region", mcp.Description("Only search x,y,w,h")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpWait("find_image"))
+
+	s.AddTool(mcp.NewTool("exec",
+		mcp.WithDescription("Run a command on the remote device (PowerShell on Windows, bash on macOS/Linux) and return its output"),
+		mcp.WithString("command", mcp.Required()),
+		mcp.WithBoolean("as_user", mcp.DefaultBool(false), mcp.Description("Run in the logged-in user's session instead of as the service")),
+		mcp.WithNumber("timeout_sec", mcp.DefaultNumber(300), mcp.Min(1), mcp.Max(900)),
+		mcp.WithDestructiveHintAnnotation(true),
+	), mcpExec)
+
+	s.AddTool(mcp.NewTool("upload",
+		mcp.WithDescription("Copy a local file to a path on the remote device"),
+		mcp.WithString("local", mcp.Required(), mcp.Description("Local file path")),
+		mcp.WithString("remote", mcp.Required(), mcp.Description("Destination path on the remote device")),
+		mcp.WithDestructiveHintAnnotation(true),
+	), mcpTransfer("upload"))
+
+	s.AddTool(mcp.NewTool("download",
+		mcp.WithDescription("Copy a file from the remote device to a local path"),
+		mcp.WithString("remote", mcp.Required(), mcp.Description("File path on the remote device")),
+		mcp.WithString("local", mcp.Required(), mcp.Description("Local destination path")),
+	), mcpTransfer("download"))
+
+	s.AddTool(mcp.NewTool("ps",
+		mcp.WithDescription("List running processes (pid, name, memory_mb, cpu, user)"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpProcessList)
+
+	s.AddTool(mcp.NewTool("kill",
+		mcp.WithDescription("Terminate a process by PID"),
+		mcp.WithNumber("pid", mcp.Required(), mcp.Min(1)),
+		mcp.WithDestructiveHintAnnotation(true),
+	), mcpKill)
+
+	s.AddTool(mcp.NewTool("sysinfo",
+		mcp.WithDescription("OS, CPU, RAM, disks, uptime and installed apps"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), mcpSysinfo)
+}
+
+// mcpDaemonRequest sends one request to the daemon. via=mcp lands in the
+// audit details so support sessions show where an action came from.
+func mcpDaemonRequest(cmd string, args map[string]interface{}) (map[string]interface{}, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	args["via"] = "mcp"
+	timeout := 30 * time.Second
+	if ms := getIntArg(args, "timeout_ms", 0); ms > 0 {
+		timeout += min(time.Duration(ms)*time.Millisecond, waitMaxTimeout)
+	}
+	resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: cmd, Args: args}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Data, nil
+}
+
+func mcpDevices(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	devices, err := fetchDevices(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	connected := ""
+	if resp, err := sendDaemonRequest(daemonRequest{Cmd: "status"}); err == nil && resp.OK {
+		connected, _ = resp.Data["device_id"].(string)
+	}
+	type entry struct {
+		DeviceID   string    `json:"device_id"`
+		DeviceName string    `json:"device_name"`
+		Platform   string    `json:"platform"`
+		Online     bool      `json:"online"`
+		Connected  bool      `json:"connected"`
+		LastSeen   time.Time `json:"last_seen"`
+	}
+	out := make([]entry, 0, len(devices))
+	for _, d := range devices {
+		out = append(out, entry{d.DeviceID, d.DeviceName, d.Platform, d.isOnline(), d.DeviceID == connected, d.LastSeen})
+	}
+	return mcp.NewToolResultJSON(map[string]interface{}{"devices": out})
+}
+
+func mcpConnect(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	deviceArg, err := req.RequireString("device")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	msg, err := connectDevice(deviceArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+func mcpScreenshot(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// The daemon writes the JPEG to a file; it runs on this machine
+	file := filepath.Join(os.TempDir(), fmt.Sprintf("rd-mcp-%d.jpg", time.Now().UnixNano()))
+	defer os.Remove(file)
+	data, err := mcpDaemonRequest("screenshot", map[string]interface{}{
+		"max_width": req.GetInt("max_width", 1280),
+		"quality":   req.GetInt("quality", 60),
+		"file":      file,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	img, err := os.ReadFile(file)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	text := fmt.Sprintf("Screenshot %.0fx%.0f", numFloat(data["width"]), numFloat(data["height"]))
+	return mcp.NewToolResultImage(text, base64.StdEncoding.EncodeToString(img), "image/jpeg"), nil
+}
+
+func mcpClick(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	x, errX := req.RequireInt("x")
+	y, errY := req.RequireInt("y")
+	if err := errors.Join(errX, errY); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	button := req.GetString("button", "left")
+	if _, err := mcpDaemonRequest("click", map[string]interface{}{
+		"x":            x,
+		"y":            y,
+		"button":       button,
+		"double_click": req.GetBool("double", false),
+	}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Clicked at (%d, %d) with %s button", x, y, button)), nil
+}
+
+func mcpType(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text, err := req.RequireString("text")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if _, err := mcpDaemonRequest("type", map[string]interface{}{"text": text}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Typed %d characters", len(text))), nil
+}
+
+func mcpKey(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	key, err := req.RequireString("key")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if _, err := mcpDaemonRequest("key", map[string]interface{}{
+		"key":   key,
+		"ctrl":  req.GetBool("ctrl", false),
+		"shift": req.GetBool("shift", false),
+		"alt":   req.GetBool("alt", false),
+		"meta":  req.GetBool("meta", false),
+	}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText("Pressed " + key), nil
+}
+
+func mcpScroll(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	delta, err := req.RequireInt("delta")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if _, err := mcpDaemonRequest("scroll", map[string]interface{}{
+		"delta":      delta,
+		"horizontal": req.GetBool("horizontal", false),
+		"x":          req.GetInt("x", -1),
+		"y":          req.GetInt("y", -1),
+	}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Scrolled by %d", delta)), nil
+}
+
+// mcpWait returns the handler for wait_stable, wait_change and find_image.
+func mcpWait(cmd string) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := map[string]interface{}{}
+		for k, v := range req.GetArguments() {
+			args[k] = v
+		}
+		delete(args, "region")
+		if region := req.GetString("region", ""); region != "" {
+			r, err := parseRegion(region)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			for k, v := range regionArgs(r) {
+				args[k] = v
+			}
+		}
+		if tpl, ok := args["template"].(string); ok {
+			if abs, err := filepath.Abs(tpl); err == nil {
+				args["template"] = abs
+			}
+		}
+		data, err := mcpDaemonRequest(cmd, args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultJSON(data)
+	}
+}
+
+// mcpExecResult is the structured result of the exec tool.
+type mcpExecResult struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func mcpExec(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	command, err := req.RequireString("command")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	conn, err := streamingDial()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	defer conn.Close()
+	// A cancelled tool call drops the stream; the daemon then stops waiting
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	started := time.Now()
+	if err := json.NewEncoder(conn).Encode(daemonRequest{
+		Cmd: "exec",
+		Args: map[string]interface{}{
+			"cmd":         command,
+			"as_user":     req.GetBool("as_user", false),
+			"timeout_sec": req.GetInt("timeout_sec", 300),
+			"via":         "mcp",
+		},
+	}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var res mcpExecResult
+	var stdout, stderr strings.Builder
+	appendCapped := func(b *strings.Builder, s string) {
+		if room := mcpMaxOutput - b.Len(); len(s) > room {
+			s, res.Truncated = s[:max(room, 0)], true
+		}
+		b.WriteString(s)
+	}
+	dec := json.NewDecoder(conn)
+	for done := false; !done; {
+		var m streamMsg
+		if err := dec.Decode(&m); err != nil {
+			return mcp.NewToolResultErrorf("reading from daemon: %v", err), nil
+		}
+		switch m.Type {
+		case "stdout":
+			appendCapped(&stdout, m.Data)
+		case "stderr":
+			appendCapped(&stderr, m.Data)
+		case "exit":
+			res.ExitCode, res.Error, done = m.Code, m.Error, true
+		case "error":
+			return mcp.NewToolResultError(m.Error), nil
+		}
+	}
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	res.DurationMs = time.Since(started).Milliseconds()
+
+	text := res.Stdout
+	if res.Stderr != "" {
+		text += "\n[stderr]\n" + res.Stderr
+	}
+	text += fmt.Sprintf("\n[exit %d]", res.ExitCode)
+	result := mcp.NewToolResultStructured(res, text)
+	result.IsError = res.ExitCode != 0 || res.Error != ""
+	return result, nil
+}
+
+// mcpTransfer returns the handler for the upload or download tool.
+func mcpTransfer(kind string) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		local, errL := req.RequireString("local")
+		remote, errR := req.RequireString("remote")
+		if err := errors.Join(errL, errR); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if abs, err := filepath.Abs(local); err == nil {
+			local = abs
+		}
+		conn, err := streamingDial()
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer conn.Close()
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+
+		if err := json.NewEncoder(conn).Encode(daemonRequest{
+			Cmd:  kind,
+			Args: map[string]interface{}{"local": local, "remote": remote, "via": "mcp"},
+		}); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		dec := json.NewDecoder(conn)
+		for {
+			var m streamMsg
+			if err := dec.Decode(&m); err != nil {
+				return mcp.NewToolResultErrorf("reading from daemon: %v", err), nil
+			}
+			switch m.Type {
+			case "end":
+				if m.Error != "" {
+					return mcp.NewToolResultErrorf("%s failed: %s (%d bytes transferred)", kind, m.Error, m.Bytes), nil
+				}
+				if kind == "upload" {
+					return mcp.NewToolResultText(fmt.Sprintf("Uploaded %d bytes to %s", m.Bytes, remote)), nil
+				}
+				return mcp.NewToolResultText(fmt.Sprintf("Downloaded %d bytes to %s", m.Bytes, local)), nil
+			case "error":
+				return mcp.NewToolResultError(m.Error), nil
+			}
+		}
+	}
+}
+
+func mcpProcessList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := mcpDaemonRequest("ps", nil)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultJSON(data)
+}
+
+func mcpKill(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pid, err := req.RequireInt("pid")
+	if err != nil || pid <= 0 {
+		return mcp.NewToolResultError("pid must be a positive integer"), nil
+	}
+	if _, err := mcpDaemonRequest("kill", map[string]interface{}{"pid": pid}); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Killed PID %d", pid)), nil
+}
+
+func mcpSysinfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := mcpDaemonRequest("sysinfo", nil)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultJSON(data)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeDaemon listens on the daemon socket under a temporary HOME and
+// answers every request with reply, recording what it received.
+type fakeDaemon struct {
+	mu    sync.Mutex
+	reqs  []daemonRequest
+	reply func(req daemonRequest, enc *json.Encoder)
+}
+
+func startFakeDaemon(t *testing.T, reply func(req daemonRequest, enc *json.Encoder)) *fakeDaemon {
+	t.Helper()
+	// Short path: unix socket names are limited to ~100 bytes
+	home, err := os.MkdirTemp("", "rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(home) })
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(getDaemonDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("unix", getSocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	d := &fakeDaemon{reply: reply}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var req daemonRequest
+				if json.NewDecoder(conn).Decode(&req) != nil {
+					return
+				}
+				d.mu.Lock()
+				d.reqs = append(d.reqs, req)
+				d.mu.Unlock()
+				d.reply(req, json.NewEncoder(conn))
+			}()
+		}
+	}()
+	return d
+}
+
+func (d *fakeDaemon) requests() []daemonRequest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.reqs)
+}
+
+func newMCPTestServer() *server.MCPServer {
+	s := server.NewMCPServer("remote-desktop-cli", "test", server.WithToolCapabilities(false))
+	addMCPTools(s)
+	return s
+}
+
+// callTool sends a tools/call through the MCP server, like a client would.
+func callTool(t *testing.T, s *server.MCPServer, name string, args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	msg, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": args},
+	})
+	resp := s.HandleMessage(context.Background(), msg)
+	r, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("%s: response %#v", name, resp)
+	}
+	res, ok := r.Result.(*mcp.CallToolResult)
+	if !ok {
+		t.Fatalf("%s: result %#v", name, r.Result)
+	}
+	return res
+}
+
+func resultText(res *mcp.CallToolResult) string {
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
+
+func TestMCPToolSchemas(t *testing.T) {
+	tools := newMCPTestServer().ListTools()
+
+	type schema struct {
+		InputSchema struct {
+			Properties map[string]struct {
+				Type string   `json:"type"`
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+			Required []string `json:"required"`
+		} `json:"inputSchema"`
+		Annotations struct {
+			ReadOnly    *bool `json:"readOnlyHint"`
+			Destructive *bool `json:"destructiveHint"`
+		} `json:"annotations"`
+	}
+	tests := []struct {
+		name        string
+		required    []string
+		readOnly    bool
+		destructive bool
+	}{
+		{"devices", nil, true, false},
+		{"connect", []string{"device"}, false, false},
+		{"screenshot", nil, true, false},
+		{"click", []string{"x", "y"}, false, false},
+		{"type_text", []string{"text"}, false, false},
+		{"key", []string{"key"}, false, false},
+		{"scroll", []string{"delta"}, false, false},
+		{"wait_stable", nil, true, false},
+		{"wait_change", nil, true, false},
+		{"find_image", []string{"template"}, true, false},
+		{"exec", []string{"command"}, false, true},
+		{"upload", []string{"local", "remote"}, false, true},
+		{"download", []string{"remote", "local"}, false, false},
+		{"ps", nil, true, false},
+		{"kill", []string{"pid"}, false, true},
+		{"sysinfo", nil, true, false},
+	}
+	if len(tools) != len(tests) {
+		t.Errorf("%d tools registered, want %d", len(tools), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := tools[tt.name]
+			if !ok {
+				t.Fatal("not registered")
+			}
+			data, err := json.Marshal(st.Tool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got schema
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			req := slices.Clone(got.InputSchema.Required)
+			want := slices.Clone(tt.required)
+			slices.Sort(req)
+			slices.Sort(want)
+			if !slices.Equal(req, want) {
+				t.Errorf("required = %v, want %v", got.InputSchema.Required, tt.required)
+			}
+			for _, r := range tt.required {
+				if _, ok := got.InputSchema.Properties[r]; !ok {
+					t.Errorf("required %q has no property", r)
+				}
+			}
+			if ro := got.Annotations.ReadOnly != nil && *got.Annotations.ReadOnly; ro != tt.readOnly {
+				t.Errorf("readOnlyHint = %v, want %v", ro, tt.readOnly)
+			}
+			if tt.destructive && (got.Annotations.Destructive == nil || !*got.Annotations.Destructive) {
+				t.Error("destructiveHint not set")
+			}
+		})
+	}
+
+	data, _ := json.Marshal(tools["click"].Tool)
+	var click schema
+	json.Unmarshal(data, &click)
+	if b := click.InputSchema.Properties["button"]; !slices.Equal(b.Enum, []string{"left", "right", "middle"}) {
+		t.Errorf("click button enum = %v", b.Enum)
+	}
+	if x := click.InputSchema.Properties["x"]; x.Type != "number" {
+		t.Errorf("click x type = %q", x.Type)
+	}
+}
+
+func TestMCPArgumentValidation(t *testing.T) {
+	d := startFakeDaemon(t, func(req daemonRequest, enc *json.Encoder) {
+		enc.Encode(daemonResponse{OK: true})
+	})
+	s := newMCPTestServer()
+
+	tests := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"connect", map[string]interface{}{}},
+		{"click", map[string]interface{}{"x": 10}},
+		{"type_text", map[string]interface{}{}},
+		{"key", map[string]interface{}{"ctrl": true}},
+		{"scroll", map[string]interface{}{"x": 5, "y": 5}},
+		{"exec", map[string]interface{}{"as_user": true}},
+		{"upload", map[string]interface{}{"local": "a.txt"}},
+		{"download", map[string]interface{}{"local": "a.txt"}},
+		{"kill", map[string]interface{}{"pid": 0}},
+		{"kill", map[string]interface{}{}},
+		{"wait_stable", map[string]interface{}{"region": "1,2,3"}},
+	}
+	for _, tt := range tests {
+		if res := callTool(t, s, tt.tool, tt.args); !res.IsError {
+			t.Errorf("%s(%v) accepted: %s", tt.tool, tt.args, resultText(res))
+		}
+	}
+	if reqs := d.requests(); len(reqs) != 0 {
+		t.Errorf("invalid calls reached the daemon: %+v", reqs)
+	}
+}
+
+func TestMCPDispatch(t *testing.T) {
+	d := startFakeDaemon(t, func(req daemonRequest, enc *json.Encoder) {
+		switch req.Cmd {
+		case "ps":
+			enc.Encode(daemonResponse{OK: true, Data: map[string]interface{}{"processes": []interface{}{}}})
+		case "kill":
+			enc.Encode(daemonResponse{OK: false, Error: "access denied"})
+		case "exec":
+			enc.Encode(streamMsg{Type: "started", PID: 42})
+			enc.Encode(streamMsg{Type: "stdout", Data: "hello\n"})
+			enc.Encode(streamMsg{Type: "stderr", Data: "warn\n"})
+			enc.Encode(streamMsg{Type: "exit", Code: 3})
+		default:
+			enc.Encode(daemonResponse{OK: true})
+		}
+	})
+	s := newMCPTestServer()
+
+	tests := []struct {
+		tool     string
+		args     map[string]interface{}
+		wantCmd  string
+		wantArgs map[string]interface{}
+		wantErr  bool
+		wantText string
+	}{
+		{"click", map[string]interface{}{"x": 100, "y": 200, "button": "right"}, "click",
+			map[string]interface{}{"x": 100.0, "y": 200.0, "button": "right", "double_click": false}, false, "Clicked at (100, 200) with right button"},
+		{"type_text", map[string]interface{}{"text": "héllo"}, "type",
+			map[string]interface{}{"text": "héllo"}, false, ""},
+		{"key", map[string]interface{}{"key": "f5", "ctrl": true}, "key",
+			map[string]interface{}{"key": "f5", "ctrl": true, "shift": false}, false, "Pressed f5"},
+		{"scroll", map[string]interface{}{"delta": -3, "horizontal": true}, "scroll",
+			map[string]interface{}{"delta": -3.0, "horizontal": true, "x": -1.0, "y": -1.0}, false, "Scrolled by -3"},
+		{"wait_stable", map[string]interface{}{"region": "10,20,30,40", "quiet_ms": 200}, "wait_stable",
+			map[string]interface{}{"region_x": 10.0, "region_y": 20.0, "region_w": 30.0, "region_h": 40.0, "quiet_ms": 200.0, "region": nil}, false, ""},
+		{"ps", map[string]interface{}{}, "ps", map[string]interface{}{}, false, ""},
+		{"kill", map[string]interface{}{"pid": 1234}, "kill",
+			map[string]interface{}{"pid": 1234.0}, true, "access denied"},
+		{"exec", map[string]interface{}{"command": "Get-Date"}, "exec",
+			map[string]interface{}{"cmd": "Get-Date", "as_user": false, "timeout_sec": 300.0}, true, "hello\n\n[stderr]\nwarn\n\n[exit 3]"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			res := callTool(t, s, tt.tool, tt.args)
+			if res.IsError != tt.wantErr {
+				t.Errorf("IsError = %v, want %v (%s)", res.IsError, tt.wantErr, resultText(res))
+			}
+			if tt.wantText != "" && resultText(res) != tt.wantText {
+				t.Errorf("text = %q, want %q", resultText(res), tt.wantText)
+			}
+			reqs := d.requests()
+			if len(reqs) != i+1 {
+				t.Fatalf("%d daemon requests, want %d", len(reqs), i+1)
+			}
+			got := reqs[i]
+			if got.Cmd != tt.wantCmd {
+				t.Errorf("cmd = %q, want %q", got.Cmd, tt.wantCmd)
+			}
+			for k, v := range tt.wantArgs {
+				if fmt.Sprint(got.Args[k]) != fmt.Sprint(v) {
+					t.Errorf("args[%s] = %v, want %v", k, got.Args[k], v)
+				}
+			}
+			if got.Args["via"] != "mcp" {
+				t.Errorf("via = %v, want mcp", got.Args["via"])
+			}
+			// The daemon audits the request with its origin
+			if _, _, _, details, audit := daemonAuditInfo(got); audit && details["via"] != "mcp" {
+				t.Errorf("audit details = %v, want via=mcp", details)
+			}
+		})
+	}
+
+	res := callTool(t, s, "exec", map[string]interface{}{"command": "x"})
+	var structured mcpExecResult
+	data, _ := json.Marshal(res.StructuredContent)
+	if err := json.Unmarshal(data, &structured); err != nil || structured.ExitCode != 3 || structured.Stdout != "hello\n" || structured.Stderr != "warn\n" {
+		t.Errorf("exec structured result = %+v (%v)", structured, err)
+	}
+}
+
+func TestMCPAuditTagging(t *testing.T) {
+	for _, cmd := range []string{"screenshot", "click", "type", "key", "scroll", "exec", "upload", "download"} {
+		action, _, _, details, audit := daemonAuditInfo(daemonRequest{Cmd: cmd, Args: map[string]interface{}{"via": "mcp"}})
+		if !audit || action == "" {
+			t.Errorf("%s not audited", cmd)
+			continue
+		}
+		if details["via"] != "mcp" {
+			t.Errorf("%s audit details = %v, want via=mcp", cmd, details)
+		}
+	}
+	if _, _, _, details, _ := daemonAuditInfo(daemonRequest{Cmd: "click", Args: map[string]interface{}{}}); details["via"] != nil {
+		t.Errorf("shell click tagged via=%v", details["via"])
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// switchMonitor selects a monitor (or protocol.AllMonitors) and waits for
+// the agent's monitor_switched confirmation.
+func switchMonitor(conn *DeviceConnection, index int, timeout time.Duration) error {
+	if err := conn.client.SwitchMonitor(index); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if list, ok := conn.client.Monitors(); ok && list.Active == index {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("agent did not confirm monitor %d within %s", index, timeout)
+}
+
+func handleMonitor(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	if _, ok := req.Args["index"]; ok {
+		index := getIntArg(req.Args, "index", 0)
+		if err := switchMonitor(conn, index, 5*time.Second); err != nil {
+			return daemonResponse{OK: false, Error: err.Error()}
+		}
+	}
+
+	list, ok := conn.client.Monitors()
+	if !ok {
+		return daemonResponse{OK: false, Error: "agent has not sent a monitor list"}
+	}
+	return daemonResponse{OK: true, Data: map[string]interface{}{
+		"monitors": list.Monitors,
+		"active":   list.Active,
+		"span":     list.Span,
+	}}
+}
+
+func cmdMonitor() {
+	usage := "Usage: remote-desktop-cli monitor [list | <index> | all]"
+	args := map[string]interface{}{}
+	if len(os.Args) > 3 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if len(os.Args) == 3 && os.Args[2] != "list" {
+		if os.Args[2] == "all" {
+			args["index"] = protocol.AllMonitors
+		} else {
+			index, err := strconv.Atoi(os.Args[2])
+			if err != nil || index < 0 {
+				fmt.Fprintln(os.Stderr, usage)
+				os.Exit(2)
+			}
+			args["index"] = index
+		}
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "monitor", Args: args})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+
+	active := int(numFloat(resp.Data["active"]))
+	monitors, _ := resp.Data["monitors"].([]interface{})
+	for _, raw := range monitors {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := int(numFloat(m["index"]))
+		marker := " "
+		if index == active {
+			marker = "*"
+		}
+		primary := ""
+		if p, _ := m["primary"].(bool); p {
+			primary = " (primary)"
+		}
+		fmt.Printf("%s %d  %-20s %dx%d at %d,%d%s\n", marker, index, m["name"],
+			int(numFloat(m["width"])), int(numFloat(m["height"])),
+			int(numFloat(m["offsetX"])), int(numFloat(m["offsetY"])), primary)
+	}
+	if span, _ := resp.Data["span"].(bool); span {
+		marker := " "
+		if active == protocol.AllMonitors {
+			marker = "*"
+		}
+		fmt.Printf("%s all  All displays as one desktop\n", marker)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stangtennis/Remote/controller/internal/config"
+	"github.com/stangtennis/Remote/netcheck"
+)
+
+// cmdNetcheck runs the network diagnostics from this machine. It does not
+// need the daemon; TURN credentials are fetched with the usual RD_EMAIL login
+// and the check falls back to STUN only when login is unavailable.
+func cmdNetcheck() {
+	jsonOut := false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--json":
+			jsonOut = true
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: remote-desktop-cli netcheck [--json]")
+			os.Exit(2)
+		}
+	}
+
+	var supabaseURL, token, anonKey string
+	if auth, cfg, err := getAuthAndConfig(); err == nil {
+		supabaseURL, anonKey, token = cfg.SupabaseURL, cfg.SupabaseAnonKey, auth.accessToken
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: %v — testing STUN only\n", err)
+		if cfg, err := config.Load(); err == nil {
+			supabaseURL, anonKey = cfg.SupabaseURL, cfg.SupabaseAnonKey
+		}
+	}
+
+	servers := fetchICEServers(supabaseURL, anonKey, token)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report := netcheck.Run(ctx, netcheck.Options{
+		ICEServers:  servers,
+		SupabaseURL: supabaseURL,
+	})
+
+	if jsonOut {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	report.WriteText(os.Stdout)
+}
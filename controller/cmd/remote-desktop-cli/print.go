@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stangtennis/Remote/controller/internal/printing"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const printUsageText = `Usage:
+  remote-desktop-cli print                          Show whether print jobs are forwarded
+  remote-desktop-cli print on [--printer <name>]    Print jobs from the remote virtual printer here
+                                                    (default printer unless --printer is given)
+  remote-desktop-cli print on --save <local-dir>    Save them in local-dir instead
+  remote-desktop-cli print off                      Stop forwarding (jobs wait on the remote device)`
+
+// printTarget is where the daemon delivers forwarded jobs.
+var printTarget struct {
+	mu        sync.Mutex
+	printer   string
+	saveDir   string
+	delivered int
+	lastError string
+}
+
+// deliverPrintJob prints or saves one job received from the agent.
+func deliverPrintJob(job protocol.PrintJob, path string) error {
+	printTarget.mu.Lock()
+	printer, saveDir := printTarget.printer, printTarget.saveDir
+	printTarget.mu.Unlock()
+
+	var err error
+	if saveDir != "" {
+		var saved string
+		if saved, err = printing.Save(saveDir, job, path); err == nil {
+			log.Printf("🖨️ Saved print job %q to %s", job.Title, saved)
+		}
+	} else {
+		err = printing.Print(job, path, printer)
+	}
+
+	printTarget.mu.Lock()
+	if err != nil {
+		printTarget.lastError = err.Error()
+	} else {
+		printTarget.delivered++
+	}
+	printTarget.mu.Unlock()
+	return err
+}
+
+// handlePrint turns print forwarding on or off when action is given, and
+// reports the agent's print state.
+func handlePrint(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.Require(protocol.CapPrint); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	switch action := getStringArg(req.Args, "action", ""); action {
+	case "on":
+		printTarget.mu.Lock()
+		printTarget.printer = getStringArg(req.Args, "printer", "")
+		printTarget.saveDir = getStringArg(req.Args, "save", "")
+		printTarget.mu.Unlock()
+		err = conn.client.EnablePrinting(deliverPrintJob)
+	case "off":
+		err = conn.client.DisablePrinting()
+	case "":
+	default:
+		return daemonResponse{OK: false, Error: "unknown print action: " + action}
+	}
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	printTarget.mu.Lock()
+	data := map[string]interface{}{
+		"enabled":    false,
+		"printer":    printTarget.printer,
+		"save":       printTarget.saveDir,
+		"delivered":  printTarget.delivered,
+		"last_error": printTarget.lastError,
+	}
+	printTarget.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if state, ok := conn.client.PrintState(); ok {
+			if state.Error != "" {
+				return daemonResponse{OK: false, Error: state.Error}
+			}
+			data["enabled"] = state.Enabled
+			data["installed"] = state.Installed
+			data["remote_printer"] = state.Printer
+			return daemonResponse{OK: true, Data: data}
+		}
+		// Nothing to wait for until forwarding has been switched
+		if getStringArg(req.Args, "action", "") == "" || time.Now().After(deadline) {
+			return daemonResponse{OK: true, Data: data}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func cmdPrint() {
+	args := map[string]interface{}{}
+	rest := os.Args[2:]
+	if len(rest) > 0 {
+		switch rest[0] {
+		case "on", "off":
+			args["action"] = rest[0]
+		default:
+			fmt.Fprintln(os.Stderr, printUsageText)
+			os.Exit(2)
+		}
+		rest = rest[1:]
+	}
+	for i := 0; i < len(rest); i++ {
+		if i+1 >= len(rest) || args["action"] != "on" {
+			fmt.Fprintln(os.Stderr, printUsageText)
+			os.Exit(2)
+		}
+		switch rest[i] {
+		case "--printer":
+			args["printer"] = rest[i+1]
+		case "--save":
+			// The daemon runs in another directory
+			dir, err := filepath.Abs(rest[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			args["save"] = dir
+		default:
+			fmt.Fprintln(os.Stderr, printUsageText)
+			os.Exit(2)
+		}
+		i++
+	}
+	if args["printer"] != nil && args["save"] != nil {
+		fmt.Fprintln(os.Stderr, "Error: use either --printer or --save")
+		os.Exit(2)
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "print", Args: args})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+
+	if enabled, _ := resp.Data["enabled"].(bool); !enabled {
+		fmt.Println("Print forwarding: off")
+		return
+	}
+	target := "default printer"
+	if p, _ := resp.Data["printer"].(string); p != "" {
+		target = "printer " + p
+	}
+	if dir, _ := resp.Data["save"].(string); dir != "" {
+		target = "saved in " + dir
+	}
+	remote, _ := resp.Data["remote_printer"].(string)
+	fmt.Printf("Print forwarding: on (%s)\n", target)
+	fmt.Printf("Remote printer:   %s\n", remote)
+	fmt.Printf("Jobs delivered:   %d\n", int(numFloat(resp.Data["delivered"])))
+	if last, _ := resp.Data["last_error"].(string); last != "" {
+		fmt.Printf("Last error:       %s\n", last)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/stangtennis/Remote/protocol"
 )
 
 // requestProcessList asks the agent for the running process list. The agent
@@ -17,7 +19,7 @@ func requestProcessList(conn *DeviceConnection, timeout time.Duration) ([]map[st
 	sub := conn.processRouter.Subscribe("")
 	defer conn.processRouter.Unsubscribe("")
 
-	req, _ := json.Marshal(map[string]interface{}{"op": "ps"})
+	req, _ := protocol.Encode(protocol.ProcessRequest{Op: protocol.OpPs})
 	if err := conn.SendProcess(req); err != nil {
 		return nil, fmt.Errorf("send ps: %w", err)
 	}
@@ -61,7 +63,7 @@ func requestProcessKill(conn *DeviceConnection, pid int, timeout time.Duration)
 	sub := conn.processRouter.Subscribe("")
 	defer conn.processRouter.Unsubscribe("")
 
-	req, _ := json.Marshal(map[string]interface{}{"op": "kill", "pid": pid})
+	req, _ := protocol.Encode(protocol.ProcessRequest{Op: protocol.OpKill, PID: pid})
 	if err := conn.SendProcess(req); err != nil {
 		return fmt.Errorf("send kill: %w", err)
 	}
@@ -102,7 +104,7 @@ func requestSysinfo(conn *DeviceConnection, timeout time.Duration) (map[string]i
 	sub := conn.processRouter.Subscribe("")
 	defer conn.processRouter.Unsubscribe("")
 
-	req, _ := json.Marshal(map[string]interface{}{"op": "sysinfo"})
+	req, _ := protocol.Encode(protocol.ProcessRequest{Op: protocol.OpSysinfo})
 	if err := conn.SendProcess(req); err != nil {
 		return nil, fmt.Errorf("send sysinfo: %w", err)
 	}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/controller/internal/reconnection"
+	rtc "github.com/stangtennis/Remote/controller/internal/webrtc"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const (
+	iceRestartAttempts       = 3
+	iceRestartAnswerTimeout  = 10 * time.Second
+	iceRestartConnectTimeout = 10 * time.Second
+)
+
+// restartAnswer is an ICE restart answer received from the agent.
+type restartAnswer struct {
+	id  string
+	sdp string
+}
+
+func (dc *DeviceConnection) deliverRestartAnswer(restartID, sdp string) {
+	select {
+	case dc.restartAnswers <- restartAnswer{id: restartID, sdp: sdp}:
+	default:
+	}
+}
+
+// restartPeer is the part of the WebRTC client an ICE restart drives.
+type restartPeer interface {
+	CreateRestartOffer() (string, error)
+	SendRestartOffer(restartID, sdp string) error
+	ApplyRestartAnswer(sdp string) error
+	IsConnected() bool
+}
+
+// restartSignaling carries restart offers and answers over session_signaling.
+type restartSignaling interface {
+	SendRestartOffer(sessionID, restartID, sdp string) error
+	GetRestartAnswer(sessionID, restartID string) (string, error)
+}
+
+// recoveryResult is how an ICE recovery ended.
+type recoveryResult int
+
+const (
+	recoveryRestarted  recoveryResult = iota // an ICE restart brought the connection back
+	recoverySelfHealed                       // ICE recovered without a restart
+	recoveryAbandoned                        // the connection was closed or replaced meanwhile
+	recoveryGaveUp                           // every restart failed; a full reconnect is needed
+)
+
+// iceRecovery is the ICE restart state machine for one dropped connection.
+type iceRecovery struct {
+	peer      restartPeer
+	signaling restartSignaling // nil when there is no session to signal over
+	sessionID string
+	answers   chan restartAnswer
+	current   func() bool // false once the connection was closed or replaced
+
+	attempts       int
+	answerTimeout  time.Duration
+	connectTimeout time.Duration
+	poll           time.Duration
+}
+
+// recoverConnection tries to bring a dropped connection back without
+// tearing down the peer connection. An ICE restart keeps the data channels,
+// so running shell execs, terminal sessions and file transfers survive a
+// network change (e.g. Wi-Fi → LTE). Only if every restart fails do we fall
+// back to a full re-signal via the reconnection manager.
+func (cm *ConnectionManager) recoverConnection(conn *DeviceConnection) {
+	if !conn.recovering.CompareAndSwap(false, true) {
+		return
+	}
+	defer conn.recovering.Store(false)
+
+	if !conn.client.Protocol().Has(protocol.CapICERestart) {
+		log.Printf("[cli] Agent does not support ICE restart — full reconnect to %s", conn.deviceName)
+		cm.fullReconnect(conn)
+		return
+	}
+
+	r := &iceRecovery{
+		peer:           conn.client,
+		sessionID:      conn.session(),
+		answers:        conn.restartAnswers,
+		current:        func() bool { return cm.isCurrent(conn) },
+		attempts:       iceRestartAttempts,
+		answerTimeout:  iceRestartAnswerTimeout,
+		connectTimeout: iceRestartConnectTimeout,
+		poll:           500 * time.Millisecond,
+	}
+	// Support sessions have their own signaling and only take restarts
+	// over the control channel.
+	if !strings.HasPrefix(conn.deviceID, "support:") && r.sessionID != "" {
+		r.signaling = rtc.NewSignalingClient(cm.cfg.SupabaseURL, cm.cfg.SupabaseAnonKey, cm.auth.GetToken())
+	}
+	switch r.run(conn.deviceName) {
+	case recoveryRestarted:
+		log.Printf("[cli] ICE restart succeeded — session to %s kept", conn.deviceName)
+	case recoveryGaveUp:
+		log.Printf("[cli] ICE restart gave up — falling back to full reconnect to %s", conn.deviceName)
+		cm.fullReconnect(conn)
+	}
+}
+
+// run attempts ICE restarts until one succeeds, ICE recovers on its own or
+// the connection stops being current.
+func (r *iceRecovery) run(name string) recoveryResult {
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if !r.current() {
+			return recoveryAbandoned // disconnected by the user or replaced meanwhile
+		}
+		if r.peer.IsConnected() {
+			return recoverySelfHealed
+		}
+
+		log.Printf("[cli] ICE restart attempt %d/%d for %s", attempt, r.attempts, name)
+		if err := r.restart(); err != nil {
+			log.Printf("[cli] ICE restart failed: %v", err)
+			continue
+		}
+		return recoveryRestarted
+	}
+	if !r.current() {
+		return recoveryAbandoned
+	}
+	return recoveryGaveUp
+}
+
+// restart performs one ICE restart round trip. The offer is sent over the
+// control channel (works while the old path is still alive) and, for regular
+// sessions, over session_signaling (works when the old path is already gone).
+func (r *iceRecovery) restart() error {
+	restartID := newExecID()
+	sdp, err := r.peer.CreateRestartOffer()
+	if err != nil {
+		return err
+	}
+
+	// Drain answers left over from earlier attempts
+	for len(r.answers) > 0 {
+		<-r.answers
+	}
+
+	sentControl := r.peer.SendRestartOffer(restartID, sdp) == nil
+
+	signaling := r.signaling
+	if signaling != nil {
+		if err := signaling.SendRestartOffer(r.sessionID, restartID, sdp); err != nil {
+			log.Printf("[cli] Restart offer via signaling failed: %v", err)
+			signaling = nil
+		}
+	}
+	if !sentControl && signaling == nil {
+		return fmt.Errorf("no path to deliver restart offer")
+	}
+
+	answer := ""
+	deadline := time.Now().Add(r.answerTimeout)
+	for answer == "" && time.Now().Before(deadline) {
+		select {
+		case a := <-r.answers:
+			if a.id == restartID {
+				answer = a.sdp
+			}
+		case <-time.After(r.poll):
+			if signaling != nil {
+				if sdp, err := signaling.GetRestartAnswer(r.sessionID, restartID); err == nil && sdp != "" {
+					answer = sdp
+				}
+			}
+		}
+	}
+	if answer == "" {
+		return fmt.Errorf("timeout waiting for restart answer")
+	}
+
+	if err := r.peer.ApplyRestartAnswer(answer); err != nil {
+		return err
+	}
+
+	deadline = time.Now().Add(r.connectTimeout)
+	for time.Now().Before(deadline) {
+		if r.peer.IsConnected() {
+			return nil
+		}
+		time.Sleep(r.poll / 2)
+	}
+	return fmt.Errorf("timeout waiting for ICE to reconnect")
+}
+
+// fullReconnect drops the old peer connection and re-signals from scratch
+// with exponential backoff. In-flight execs and transfers are lost.
+func (cm *ConnectionManager) fullReconnect(conn *DeviceConnection) {
+	r := reconnection.NewManager()
+	r.SetReconnectFunc(func() error {
+		if strings.HasPrefix(conn.deviceID, "support:") {
+			return cm.ConnectSupport(strings.TrimPrefix(conn.deviceID, "support:"))
+		}
+		return cm.Connect(conn.deviceID, conn.deviceName)
+	})
+	r.SetOnReconnected(func() {
+		log.Printf("[cli] Reconnected to %s", conn.deviceName)
+		cm.clearReconnector(conn.deviceID, r)
+	})
+	r.SetOnReconnectFailed(func() {
+		log.Printf("[cli] Giving up on %s", conn.deviceName)
+		cm.clearReconnector(conn.deviceID, r)
+	})
+
+	cm.mu.Lock()
+	if cm.connections[conn.deviceID] != conn {
+		cm.mu.Unlock()
+		return
+	}
+	delete(cm.connections, conn.deviceID)
+	cm.reconnectors[conn.deviceID] = r
+	cm.mu.Unlock()
+
+	if sessionID := conn.session(); conn.signaling != nil && sessionID != "" {
+		conn.signaling.DeleteSession(sessionID)
+	}
+	conn.client.Close()
+
+	r.StartReconnection()
+}
+
+func (cm *ConnectionManager) isCurrent(conn *DeviceConnection) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.connections[conn.deviceID] == conn
+}
+
+func (cm *ConnectionManager) clearReconnector(deviceID string, r *reconnection.Manager) {
+	cm.mu.Lock()
+	if cm.reconnectors[deviceID] == r {
+		delete(cm.reconnectors, deviceID)
+	}
+	cm.mu.Unlock()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer answers restart offers like an agent would: over the control
+// channel when control is up, never when it is down.
+type fakePeer struct {
+	mu        sync.Mutex
+	control   bool // control channel still carries offers
+	connected bool
+	answers   chan restartAnswer
+	offers    int
+	applied   []string
+}
+
+func (p *fakePeer) CreateRestartOffer() (string, error) { return "offer-sdp", nil }
+
+func (p *fakePeer) SendRestartOffer(restartID, sdp string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offers++
+	if !p.control {
+		return errors.New("control channel closed")
+	}
+	go func() { p.answers <- restartAnswer{id: restartID, sdp: "answer-via-control"} }()
+	return nil
+}
+
+func (p *fakePeer) ApplyRestartAnswer(sdp string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied = append(p.applied, sdp)
+	p.connected = true
+	return nil
+}
+
+func (p *fakePeer) IsConnected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connected
+}
+
+// fakeSignaling stores restart offers and answers them when answer is set.
+type fakeSignaling struct {
+	mu     sync.Mutex
+	offers map[string]string // restart ID → session ID
+	answer bool
+}
+
+func (s *fakeSignaling) SendRestartOffer(sessionID, restartID, sdp string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offers == nil {
+		s.offers = map[string]string{}
+	}
+	s.offers[restartID] = sessionID
+	return nil
+}
+
+func (s *fakeSignaling) GetRestartAnswer(sessionID, restartID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.answer || s.offers[restartID] != sessionID {
+		return "", nil
+	}
+	return "answer-via-signaling", nil
+}
+
+func newTestRecovery(p *fakePeer, sig restartSignaling) *iceRecovery {
+	return &iceRecovery{
+		peer:           p,
+		signaling:      sig,
+		sessionID:      "session-1",
+		answers:        p.answers,
+		current:        func() bool { return true },
+		attempts:       3,
+		answerTimeout:  100 * time.Millisecond,
+		connectTimeout: 100 * time.Millisecond,
+		poll:           5 * time.Millisecond,
+	}
+}
+
+func TestICERecovery(t *testing.T) {
+	cases := []struct {
+		name      string
+		control   bool
+		signaling *fakeSignaling
+		want      recoveryResult
+		applied   string
+		offers    int
+	}{
+		{"answer over control channel", true, nil, recoveryRestarted, "answer-via-control", 1},
+		{"answer over signaling", false, &fakeSignaling{answer: true}, recoveryRestarted, "answer-via-signaling", 1},
+		{"no answer falls back to full reconnect", false, &fakeSignaling{}, recoveryGaveUp, "", 3},
+		{"no path falls back to full reconnect", false, nil, recoveryGaveUp, "", 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &fakePeer{control: tc.control, answers: make(chan restartAnswer, 1)}
+			var sig restartSignaling
+			if tc.signaling != nil {
+				sig = tc.signaling
+			}
+			if got := newTestRecovery(p, sig).run("test"); got != tc.want {
+				t.Fatalf("run = %d, want %d", got, tc.want)
+			}
+			if p.offers != tc.offers {
+				t.Errorf("%d offers sent, want %d", p.offers, tc.offers)
+			}
+			if tc.applied == "" && len(p.applied) > 0 || tc.applied != "" && (len(p.applied) != 1 || p.applied[0] != tc.applied) {
+				t.Errorf("applied answers %v, want %q", p.applied, tc.applied)
+			}
+		})
+	}
+}
+
+func TestICERecoveryStops(t *testing.T) {
+	p := &fakePeer{connected: true, answers: make(chan restartAnswer, 1)}
+	if got := newTestRecovery(p, nil).run("test"); got != recoverySelfHealed || p.offers != 0 {
+		t.Errorf("connected peer: run = %d after %d offers", got, p.offers)
+	}
+
+	p = &fakePeer{answers: make(chan restartAnswer, 1)}
+	r := newTestRecovery(p, nil)
+	r.current = func() bool { return false }
+	if got := r.run("test"); got != recoveryAbandoned || p.offers != 0 {
+		t.Errorf("replaced connection: run = %d after %d offers", got, p.offers)
+	}
+
+	// A stale answer from an earlier attempt is not applied.
+	p = &fakePeer{answers: make(chan restartAnswer, 1)}
+	p.answers <- restartAnswer{id: "old", sdp: "stale"}
+	r = newTestRecovery(p, nil)
+	r.attempts = 1
+	if got := r.run("test"); got != recoveryGaveUp || len(p.applied) != 0 {
+		t.Errorf("stale answer: run = %d, applied %v", got, p.applied)
+	}
+}
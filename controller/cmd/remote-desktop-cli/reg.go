@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const regUsageText = `Usage: remote-desktop-cli reg list [<path>] [--json]
+       remote-desktop-cli reg get <path> [<name>] [--json]
+       remote-desktop-cli reg set <path> <name> <value>... [--type sz|expand_sz|multi_sz|dword|qword|binary]
+       remote-desktop-cli reg delete <path> (<name> | --key)
+       remote-desktop-cli reg export <path> [-o file.reg]
+       remote-desktop-cli reg import <file.reg>
+
+Paths use the regedit form, e.g. HKLM\SOFTWARE\Vendor (HKLM, HKCU, HKCR, HKU,
+HKCC). The name @ is the key's default value. multi_sz takes one argument per
+string; binary takes hex. delete --key removes the key and all its subkeys.
+
+Credential stores cannot be read, and services, Winlogon, Run keys, Defender
+and the other protected keys cannot be changed. On macOS and Linux the view
+is read-only: defaults\<domain> for preferences and /etc/... for config files.`
+
+// regTimeout covers a large export or import.
+const regTimeout = 2 * time.Minute
+
+// requestRegistry runs one reg_* op on the agent. Chunked replies are merged:
+// a listing's keys and values, or an export's .reg text.
+func requestRegistry(conn *DeviceConnection, req protocol.RegRequest, timeout time.Duration) (map[string]interface{}, error) {
+	if !conn.ProcessReady() {
+		return nil, fmt.Errorf("process channel not open (agent likely older than v3.0.2)")
+	}
+	if err := conn.Require(protocol.CapRegistry); err != nil {
+		return nil, err
+	}
+	sub := conn.processRouter.Subscribe("")
+	defer conn.processRouter.Unsubscribe("")
+
+	if req.Op == protocol.OpRegImport {
+		chunks := protocol.SplitRegChunks(req.Data)
+		for i, c := range chunks {
+			part := req
+			part.Data, part.More = c, i < len(chunks)-1
+			data, _ := protocol.Encode(part)
+			if err := conn.SendProcess(data); err != nil {
+				return nil, fmt.Errorf("send %s: %w", req.Op, err)
+			}
+		}
+	} else {
+		data, _ := protocol.Encode(req)
+		if err := conn.SendProcess(data); err != nil {
+			return nil, fmt.Errorf("send %s: %w", req.Op, err)
+		}
+	}
+
+	want := req.Op + "_result"
+	var merged map[string]interface{}
+	var export strings.Builder
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				return nil, fmt.Errorf("process channel closed unexpectedly")
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(msg, &parsed); err != nil {
+				continue
+			}
+			switch parsed["op"] {
+			case want:
+			case "error":
+				errStr, _ := parsed["error"].(string)
+				return nil, fmt.Errorf("agent error: %s", errStr)
+			default:
+				continue
+			}
+			more, _ := parsed["more"].(bool)
+			delete(parsed, "more")
+			switch req.Op {
+			case protocol.OpRegList:
+				if merged == nil {
+					merged = parsed
+				} else {
+					for _, field := range []string{"keys", "values"} {
+						prev, _ := merged[field].([]interface{})
+						next, _ := parsed[field].([]interface{})
+						merged[field] = append(prev, next...)
+					}
+				}
+			case protocol.OpRegExport:
+				s, _ := parsed["data"].(string)
+				export.WriteString(s)
+				parsed["data"] = export.String()
+				merged = parsed
+			default:
+				merged = parsed
+			}
+			if !more {
+				return merged, nil
+			}
+		case <-deadline:
+			return nil, fmt.Errorf("%s timeout after %s", req.Op, timeout)
+		}
+	}
+}
+
+func handleReg(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	action := getStringArg(req.Args, "action", "")
+	switch action {
+	case "list", "get", "set", "delete", "export", "import":
+	default:
+		return daemonResponse{OK: false, Error: fmt.Sprintf("unknown reg action: %q", action)}
+	}
+	result, err := requestRegistry(deviceConn, protocol.RegRequest{
+		Op:    "reg_" + action,
+		Path:  getStringArg(req.Args, "path", ""),
+		Name:  getStringArg(req.Args, "name", ""),
+		Type:  getStringArg(req.Args, "type", ""),
+		Value: req.Args["value"],
+		Data:  getStringArg(req.Args, "data", ""),
+	}, regTimeout)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if ok, isWrite := result["ok"].(bool); isWrite && !ok {
+		errStr, _ := result["error"].(string)
+		return daemonResponse{OK: false, Error: errStr}
+	}
+	return daemonResponse{OK: true, Data: result}
+}
+
+func cmdReg() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, regUsageText)
+		os.Exit(2)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+	action := os.Args[2]
+	args := map[string]interface{}{"action": action}
+	jsonOut := false
+	typ := ""
+	outFile := ""
+	deleteKey := false
+	var pos []string
+	rest := os.Args[3:]
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case "--json":
+			jsonOut = true
+		case "--key":
+			deleteKey = true
+		case "--type", "-o":
+			if i+1 >= len(rest) {
+				usage()
+			}
+			if rest[i] == "--type" {
+				typ = rest[i+1]
+			} else {
+				outFile = rest[i+1]
+			}
+			i++
+		default:
+			pos = append(pos, rest[i])
+		}
+	}
+	valueName := func(s string) string {
+		if s == "@" {
+			return ""
+		}
+		return s
+	}
+
+	switch action {
+	case "list":
+		if len(pos) > 1 {
+			usage()
+		}
+		args["path"] = strings.Join(pos, "") // no path lists the roots
+	case "export":
+		if len(pos) != 1 {
+			usage()
+		}
+		args["path"] = pos[0]
+	case "get":
+		if len(pos) < 1 || len(pos) > 2 {
+			usage()
+		}
+		args["path"] = pos[0]
+		if len(pos) == 2 {
+			args["name"] = valueName(pos[1])
+		}
+	case "set":
+		if len(pos) < 3 {
+			usage()
+		}
+		if typ == "" {
+			typ = protocol.RegSZ
+		}
+		args["path"], args["name"], args["type"] = pos[0], valueName(pos[1]), typ
+		if typ == protocol.RegMultiSZ {
+			args["value"] = pos[2:]
+		} else if len(pos) == 3 {
+			args["value"] = pos[2] // numbers go as strings so qwords keep all 64 bits
+		} else {
+			fmt.Fprintln(os.Stderr, "Error: only multi_sz takes more than one value (quote values with spaces)")
+			os.Exit(2)
+		}
+	case "delete":
+		if len(pos) == 1 && deleteKey {
+			args["path"] = pos[0]
+		} else if len(pos) == 2 && !deleteKey {
+			args["path"], args["name"] = pos[0], valueName(pos[1])
+		} else {
+			fmt.Fprintln(os.Stderr, "Error: delete takes a value name, or --key to delete the whole key")
+			os.Exit(2)
+		}
+	case "import":
+		if len(pos) != 1 {
+			usage()
+		}
+		raw, err := os.ReadFile(pos[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		args["data"] = decodeRegFile(raw)
+	default:
+		usage()
+	}
+
+	resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: "reg", Args: args}, regTimeout+10*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	if jsonOut {
+		out, _ := json.MarshalIndent(resp.Data, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	switch action {
+	case "list":
+		path, _ := resp.Data["path"].(string)
+		if path == "" {
+			path = "(root)"
+		}
+		fmt.Println(path)
+		if ro, _ := resp.Data["read_only"].(bool); ro {
+			fmt.Println("(read-only)")
+		}
+		keys, _ := resp.Data["keys"].([]interface{})
+		for _, k := range keys {
+			fmt.Printf("  [%s]\n", k)
+		}
+		values, _ := resp.Data["values"].([]interface{})
+		for _, raw := range values {
+			if v, ok := raw.(map[string]interface{}); ok {
+				printRegValue(v)
+			}
+		}
+		if len(keys) == 0 && len(values) == 0 {
+			fmt.Println("  (empty)")
+		}
+	case "get":
+		v, _ := resp.Data["value"].(map[string]interface{})
+		if s, ok := v["value"].(string); ok && v["type"] == protocol.RegText {
+			fmt.Print(s)
+			if !strings.HasSuffix(s, "\n") {
+				fmt.Println()
+			}
+			return
+		}
+		printRegValue(v)
+	case "export":
+		data, _ := resp.Data["data"].(string)
+		if outFile == "" {
+			fmt.Print(data)
+			return
+		}
+		if err := os.WriteFile(outFile, encodeRegFile(data), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %s to %s\n", args["path"], outFile)
+	case "import":
+		fmt.Printf("Imported %s: %d keys and values written\n", pos[0], int(numFloat(resp.Data["count"])))
+	default:
+		fmt.Println("OK")
+	}
+}
+
+func printRegValue(v map[string]interface{}) {
+	name, _ := v["name"].(string)
+	if name == "" {
+		name = "@"
+	}
+	var shown string
+	switch val := v["value"].(type) {
+	case nil:
+		shown = "(too large to show, use reg export)"
+		if v["type"] == protocol.RegText {
+			shown = "(use reg get to read)"
+		}
+	case string:
+		shown = val
+		if len(shown) > 200 {
+			shown = shown[:200] + "..."
+		}
+		shown = strings.ReplaceAll(shown, "\n", `\n`)
+	case float64:
+		shown = fmt.Sprintf("%.0f (0x%x)", val, uint64(val))
+	default:
+		out, _ := json.Marshal(val)
+		shown = string(out)
+	}
+	fmt.Printf("  %-32s %-10s %s\n", name, v["type"], shown)
+}
+
+// encodeRegFile writes .reg text as regedit does: UTF-16LE with a BOM.
+// Anything else (plists, config files from macOS/Linux) stays UTF-8.
+func encodeRegFile(text string) []byte {
+	if !strings.HasPrefix(text, "Windows Registry Editor") {
+		return []byte(text)
+	}
+	u := utf16.Encode([]rune(text))
+	b := make([]byte, 2+2*len(u))
+	b[0], b[1] = 0xff, 0xfe
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2+2*i:], c)
+	}
+	return b
+}
+
+// decodeRegFile accepts regedit's UTF-16LE export as well as UTF-8 files.
+func decodeRegFile(raw []byte) string {
+	if bytes.HasPrefix(raw, []byte{0xff, 0xfe}) {
+		raw = raw[2:]
+		u := make([]uint16, len(raw)/2)
+		for i := range u {
+			u[i] = binary.LittleEndian.Uint16(raw[2*i:])
+		}
+		return string(utf16.Decode(u))
+	}
+	return string(bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf")))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const safeModeUsageText = `Usage:
+  remote-desktop-cli safemode [--delay <duration>]
+        Restart the remote Windows device into Safe Mode with Networking
+        (default delay 10s). The agent comes back online in Safe Mode and the
+        next restart is a normal one.`
+
+// handleSafeMode asks the agent to restart into Safe Mode and waits for its
+// answer.
+func handleSafeMode(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.Require(protocol.CapSafeModeRestart); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if err := conn.client.RestartSafeMode(int(numFloat(req.Args["delay_sec"]))); err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+
+	// bcdedit and shutdown take a moment on the agent
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		if status, ok := conn.client.RestartStatus(); ok {
+			if status.Status == "error" {
+				return daemonResponse{OK: false, Error: status.Message}
+			}
+			return daemonResponse{OK: true, Data: map[string]interface{}{
+				"status":  status.Status,
+				"message": status.Message,
+			}}
+		}
+		if time.Now().After(deadline) {
+			return daemonResponse{OK: false, Error: "agent did not answer the restart request"}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func cmdSafeMode() {
+	args := map[string]interface{}{}
+	rest := os.Args[2:]
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != "--delay" || i+1 >= len(rest) {
+			fmt.Fprintln(os.Stderr, safeModeUsageText)
+			os.Exit(2)
+		}
+		d, err := time.ParseDuration(rest[i+1])
+		if err != nil || d < time.Second {
+			fmt.Fprintf(os.Stderr, "Error: invalid delay %q\n", rest[i+1])
+			os.Exit(2)
+		}
+		args["delay_sec"] = int(d / time.Second)
+		i++
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "safemode", Args: args})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	msg, _ := resp.Data["message"].(string)
+	fmt.Printf("OK: %s\n", msg)
+	fmt.Println("The connection drops now; reconnect once the device is back online in Safe Mode.")
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stangtennis/Remote/scriptlib"
+)
+
+const scriptsUsage = `Usage:
+  remote-desktop-cli scripts list
+  remote-desktop-cli scripts show <name>[@version]
+  remote-desktop-cli scripts keygen [--out signing.key]
+  remote-desktop-cli scripts publish <script.json> --key <signing.key>
+
+script.json:
+  {"name": "clear-temp", "version": 1, "platform": "windows",
+   "description": "...", "params": [{"name": "Days", "type": "int", "default": "7"}],
+   "body_file": "clear-temp.ps1"}`
+
+// fetchScript loads a library script. version <= 0 means the newest version.
+func fetchScript(supabaseURL, anonKey, token, name string, version int) (*scriptlib.Script, error) {
+	q := url.Values{}
+	q.Set("name", "eq."+name)
+	q.Set("order", "version.desc")
+	q.Set("limit", "1")
+	if version > 0 {
+		q.Set("version", "eq."+strconv.Itoa(version))
+	}
+	var rows []scriptlib.Script
+	if err := scriptsRequest(supabaseURL, anonKey, token, "GET", q, nil, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		if version > 0 {
+			return nil, fmt.Errorf("script %s@%d not found", name, version)
+		}
+		return nil, fmt.Errorf("script %s not found", name)
+	}
+	return &rows[0], nil
+}
+
+func scriptsRequest(supabaseURL, anonKey, token, method string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = strings.NewReader(string(data))
+	}
+	u := supabaseURL + "/rest/v1/scripts"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", anonKey)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	if method == "POST" {
+		req.Header.Set("Prefer", "return=representation")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// parseScriptRef splits "name@version".
+func parseScriptRef(ref string) (string, int, error) {
+	name, ver, found := strings.Cut(ref, "@")
+	if !found {
+		return name, 0, nil
+	}
+	v, err := strconv.Atoi(ver)
+	if err != nil || v < 1 {
+		return "", 0, fmt.Errorf("invalid script version in %q", ref)
+	}
+	return name, v, nil
+}
+
+// handleScriptStream resolves a script reference and runs it on the agent.
+func handleScriptStream(conn net.Conn, req daemonRequest, connMgr *ConnectionManager, deviceID string) error {
+	sw := newStreamWriter(conn)
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+
+	name, _ := req.Args["script"].(string)
+	versionF, _ := req.Args["version"].(float64)
+	asUser, _ := req.Args["as_user"].(bool)
+	timeoutF, _ := req.Args["timeout_sec"].(float64)
+	params := map[string]string{}
+	if p, ok := req.Args["params"].(map[string]interface{}); ok {
+		for k, v := range p {
+			params[k], _ = v.(string)
+		}
+	}
+
+	script, err := fetchScript(connMgr.cfg.SupabaseURL, connMgr.cfg.SupabaseAnonKey, connMgr.auth.GetToken(), name, int(versionF))
+	if err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+	// Catch bad parameters here for a clear error; the agent checks again.
+	if _, err := script.ResolveParams(params); err != nil {
+		sw.Send(streamMsg{Type: "error", Error: err.Error()})
+		return err
+	}
+
+	exitCode, durationMs, runErr := execRemoteScript(deviceConn, script, params, asUser, int(timeoutF),
+		func(pid int) { sw.Send(streamMsg{Type: "started", PID: pid}) },
+		func(out string) { sw.Send(streamMsg{Type: "stdout", Data: out}) },
+		func(out string) { sw.Send(streamMsg{Type: "stderr", Data: out}) },
+	)
+	exitMsg := streamMsg{Type: "exit", Code: exitCode, Elapsed: float64(durationMs)}
+	if runErr != nil {
+		exitMsg.Error = runErr.Error()
+	}
+	sw.Send(exitMsg)
+	return runErr
+}
+
+// cmdRun runs a library script on the connected device.
+func cmdRun() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: remote-desktop-cli run <script>[@version] [--param k=v ...] [--as-user] [--timeout N]")
+		os.Exit(2)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+	name, version, err := parseScriptRef(os.Args[2])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	params := map[string]interface{}{}
+	asUser := false
+	timeoutSec := 300
+	for i := 3; i < len(os.Args); i++ {
+		a := os.Args[i]
+		switch {
+		case a == "--param" && i+1 < len(os.Args):
+			i++
+			k, v, ok := strings.Cut(os.Args[i], "=")
+			if !ok || k == "" {
+				usage()
+			}
+			params[k] = v
+		case strings.HasPrefix(a, "--param="):
+			k, v, ok := strings.Cut(strings.TrimPrefix(a, "--param="), "=")
+			if !ok || k == "" {
+				usage()
+			}
+			params[k] = v
+		case a == "--as-user":
+			asUser = true
+		case a == "--timeout" && i+1 < len(os.Args):
+			i++
+			n, err := strconv.Atoi(os.Args[i])
+			if err != nil {
+				usage()
+			}
+			timeoutSec = n
+		default:
+			usage()
+		}
+	}
+
+	conn, err := streamingDial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(daemonRequest{
+		Cmd: "run_script",
+		Args: map[string]interface{}{
+			"script":      name,
+			"version":     version,
+			"params":      params,
+			"as_user":     asUser,
+			"timeout_sec": timeoutSec,
+		},
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: send request: %v\n", err)
+		os.Exit(1)
+	}
+	followExecStream(conn)
+}
+
+// cmdScripts manages the script library. Needs no daemon.
+func cmdScripts() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, scriptsUsage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[2] {
+	case "list":
+		err = scriptsList()
+	case "show":
+		if len(os.Args) < 4 {
+			fmt.Fprintln(os.Stderr, scriptsUsage)
+			os.Exit(2)
+		}
+		err = scriptsShow(os.Args[3])
+	case "keygen":
+		err = scriptsKeygen(os.Args[3:])
+	case "publish":
+		err = scriptsPublish(os.Args[3:])
+	default:
+		fmt.Fprintln(os.Stderr, scriptsUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func scriptsList() error {
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	q.Set("select", "id,name,version,platform,description,params,key_id")
+	q.Set("order", "name.asc,version.desc")
+	var rows []scriptlib.Script
+	if err := scriptsRequest(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth.GetToken(), "GET", q, nil, &rows); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		fmt.Println("No scripts published.")
+		return nil
+	}
+	fmt.Printf("%-24s  %-4s  %-8s  %-24s  %s\n", "NAME", "VER", "PLATFORM", "PARAMS", "DESCRIPTION")
+	last := ""
+	for _, s := range rows {
+		if s.Name == last {
+			continue // newest version only
+		}
+		last = s.Name
+		names := make([]string, 0, len(s.Params))
+		for _, p := range s.Params {
+			names = append(names, p.Name)
+		}
+		fmt.Printf("%-24s  %-4d  %-8s  %-24s  %s\n", s.Name, s.Version, s.Platform, strings.Join(names, ","), s.Description)
+	}
+	return nil
+}
+
+func scriptsShow(ref string) error {
+	name, version, err := parseScriptRef(ref)
+	if err != nil {
+		return err
+	}
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return err
+	}
+	s, err := fetchScript(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth.GetToken(), name, version)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Script:    %s@%d (%s)\n", s.Name, s.Version, s.Platform)
+	fmt.Printf("ID:        %s\n", s.ID)
+	fmt.Printf("Signed by: %s\n", s.KeyID)
+	if s.Description != "" {
+		fmt.Printf("About:     %s\n", s.Description)
+	}
+	if len(s.Params) > 0 {
+		fmt.Println("Params:")
+		for _, p := range s.Params {
+			line := fmt.Sprintf("  %-16s %s", p.Name, p.Type)
+			if p.Type == "enum" {
+				line += " (" + strings.Join(p.Values, "|") + ")"
+			}
+			if p.Required {
+				line += ", required"
+			} else if p.Default != "" {
+				line += ", default " + p.Default
+			}
+			if p.Description != "" {
+				line += " — " + p.Description
+			}
+			fmt.Println(line)
+		}
+	}
+	fmt.Println("---")
+	fmt.Println(strings.TrimRight(s.Body, "\n"))
+	return nil
+}
+
+func scriptsKeygen(args []string) error {
+	out := ""
+	if len(args) == 2 && args[0] == "--out" {
+		out = args[1]
+	} else if len(args) != 0 {
+		return fmt.Errorf("usage: scripts keygen [--out signing.key]")
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	seed := base64.StdEncoding.EncodeToString(priv.Seed())
+	if out != "" {
+		if err := os.WriteFile(out, []byte(seed+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Private key written to %s — keep it offline.\n", out)
+	} else {
+		fmt.Printf("Private key: %s\n", seed)
+	}
+	fmt.Printf("Public key:  %s\n", base64.StdEncoding.EncodeToString(pub))
+	fmt.Printf("Key ID:      %s\n", scriptlib.KeyID(pub))
+	fmt.Println("Trust it on agents via RD_SCRIPT_SIGNING_KEYS or the agent's built-in key list.")
+	return nil
+}
+
+func scriptsPublish(args []string) error {
+	var file, keyFile string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--key" && i+1 < len(args):
+			i++
+			keyFile = args[i]
+		case file == "":
+			file = args[i]
+		default:
+			return fmt.Errorf("usage: scripts publish <script.json> --key <signing.key>")
+		}
+	}
+	if file == "" || keyFile == "" {
+		return fmt.Errorf("usage: scripts publish <script.json> --key <signing.key>")
+	}
+
+	var def struct {
+		scriptlib.Script
+		BodyFile string `json:"body_file"`
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &def); err != nil {
+		return fmt.Errorf("parse %s: %w", file, err)
+	}
+	s := def.Script
+	if def.BodyFile != "" {
+		path := def.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.Body = string(body)
+	}
+	if s.Params == nil {
+		s.Params = []scriptlib.Param{}
+	}
+
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	priv, err := scriptlib.ParsePrivateKey(string(keyData))
+	if err != nil {
+		return err
+	}
+	if err := s.Sign(priv); err != nil {
+		return err
+	}
+
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return err
+	}
+	row := map[string]interface{}{
+		"name":        s.Name,
+		"version":     s.Version,
+		"platform":    s.Platform,
+		"description": s.Description,
+		"params":      s.Params,
+		"body":        s.Body,
+		"key_id":      s.KeyID,
+		"signature":   s.Signature,
+		"created_by":  auth.userID,
+	}
+	var created []scriptlib.Script
+	if err := scriptsRequest(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth.GetToken(), "POST", nil, row, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("script was not created")
+	}
+	fmt.Printf("Published %s@%d (%s), signed by %s\n", s.Name, s.Version, created[0].ID, s.KeyID)
+	return nil
+}
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+	"github.com/stangtennis/Remote/scriptlib"
 )
 
 // execRemoteCommand sends an exec request over the shell channel and streams
@@ -19,19 +22,54 @@ func execRemoteCommand(conn *DeviceConnection, cmd string, asUser bool, timeoutS
 	}
 
 	id := newExecID()
+	return streamShellRequest(conn, id, protocol.ShellExec{
+		Op:         protocol.OpExec,
+		ID:         id,
+		Cmd:        cmd,
+		AsUser:     asUser,
+		TimeoutSec: timeoutSec,
+	}, timeoutSec, onStarted, onOut, onErr)
+}
+
+// execRemoteScript runs a signed library script over the shell channel. The
+// agent verifies the signature and binds params itself; output streams back
+// exactly like execRemoteCommand.
+func execRemoteScript(conn *DeviceConnection, script *scriptlib.Script, params map[string]string, asUser bool, timeoutSec int,
+	onStarted func(pid int), onOut func(string), onErr func(string)) (exitCode int, durationMs int64, runErr error) {
+
+	if !conn.ShellReady() {
+		return -1, 0, fmt.Errorf("shell channel not open (agent likely older than v3.0.2)")
+	}
+	if err := conn.Require(protocol.CapScripts); err != nil {
+		return -1, 0, err
+	}
+
+	reqParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		reqParams[k] = v
+	}
+	id := newExecID()
+	return streamShellRequest(conn, id, protocol.RunScript{
+		Op:         protocol.OpRunScript,
+		ID:         id,
+		Script:     script,
+		Params:     reqParams,
+		AsUser:     asUser,
+		TimeoutSec: timeoutSec,
+	}, timeoutSec, onStarted, onOut, onErr)
+}
+
+// streamShellRequest sends req on the shell channel and relays the
+// started/stdout/stderr/exit messages for id.
+func streamShellRequest(conn *DeviceConnection, id string, req interface{}, timeoutSec int,
+	onStarted func(pid int), onOut func(string), onErr func(string)) (exitCode int, durationMs int64, runErr error) {
+
 	sub := conn.shellRouter.Subscribe(id)
 	defer conn.shellRouter.Unsubscribe(id)
 
-	req := map[string]interface{}{
-		"op":          "exec",
-		"id":          id,
-		"cmd":         cmd,
-		"as_user":     asUser,
-		"timeout_sec": timeoutSec,
-	}
-	data, _ := json.Marshal(req)
+	data, _ := protocol.Encode(req)
 	if err := conn.SendShell(data); err != nil {
-		return -1, 0, fmt.Errorf("send exec: %w", err)
+		return -1, 0, fmt.Errorf("send %s: %w", protocol.MsgType(data), err)
 	}
 
 	// Use a generous outer deadline (2x command timeout) so we still bail out
@@ -91,7 +129,7 @@ func execRemoteCommand(conn *DeviceConnection, cmd string, asUser bool, timeoutS
 			}
 		case <-deadline:
 			// Best-effort kill before bailing
-			killMsg, _ := json.Marshal(map[string]interface{}{"op": "kill", "id": id})
+			killMsg, _ := protocol.Encode(protocol.ShellKill{Op: protocol.OpKill, ID: id})
 			_ = conn.SendShell(killMsg)
 			return -1, 0, fmt.Errorf("exec timeout after %s", outerTimeout)
 		}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// requestSessionStats asks the agent for the current session's stats
+// timeline. sinceSec > 0 limits the result to the last sinceSec seconds.
+func requestSessionStats(conn *DeviceConnection, sinceSec float64, timeout time.Duration) (map[string]interface{}, error) {
+	if !conn.ProcessReady() {
+		return nil, fmt.Errorf("process channel not open (agent likely older than v3.0.2)")
+	}
+	if err := conn.Require(protocol.CapStats); err != nil {
+		return nil, err
+	}
+	sub := conn.processRouter.Subscribe("")
+	defer conn.processRouter.Unsubscribe("")
+
+	req, _ := protocol.Encode(protocol.ProcessRequest{Op: protocol.OpStats, SinceSec: sinceSec})
+	if err := conn.SendProcess(req); err != nil {
+		return nil, fmt.Errorf("send stats: %w", err)
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				return nil, fmt.Errorf("process channel closed unexpectedly")
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(msg, &parsed); err != nil {
+				continue
+			}
+			switch parsed["op"] {
+			case "stats_result":
+				return parsed, nil
+			case "error":
+				errStr, _ := parsed["error"].(string)
+				return nil, fmt.Errorf("agent error: %s", errStr)
+			}
+		case <-deadline:
+			return nil, fmt.Errorf("stats timeout after %s", timeout)
+		}
+	}
+}
+
+func handleStats(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	sinceSec, _ := req.Args["since_sec"].(float64)
+	result, err := requestSessionStats(deviceConn, sinceSec, 15*time.Second)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	return daemonResponse{OK: true, Data: result}
+}
+
+const statsUsage = `Usage:
+  remote-desktop-cli stats [--since 10m] [--json]                 Live timeline of the connected session
+  remote-desktop-cli stats --device <device> [--since 24h] [--json]  Uploaded sessions of a device
+  remote-desktop-cli stats --session <session_id> [--json]          Uploaded timeline of a finished session`
+
+// statsTimeline is the agent's session timeline (internal/timeline), live
+// from stats_result or stored in session_stats.timeline.
+type statsTimeline struct {
+	SessionID string        `json:"session_id"`
+	StartedAt time.Time     `json:"started_at"`
+	EndedAt   *time.Time    `json:"ended_at,omitempty"`
+	Samples   []statsSample `json:"samples"`
+	Events    []statsEvent  `json:"events"`
+}
+
+type statsSample struct {
+	At          time.Time `json:"at"`
+	ConnType    string    `json:"conn_type"`
+	RTTMs       float64   `json:"rtt_ms"`
+	JitterMs    float64   `json:"jitter_ms"`
+	LossPct     float64   `json:"loss_pct"`
+	BitrateKbps float64   `json:"bitrate_kbps"`
+	Encoder     string    `json:"encoder"`
+	Mode        string    `json:"mode"`
+	BufferedKB  uint64    `json:"buffered_kb"`
+	Count       int       `json:"count,omitempty"`
+}
+
+type statsEvent struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// sessionStatsRow is one row of session_stats. Timeline is only selected
+// for --session.
+type sessionStatsRow struct {
+	SessionID string         `json:"session_id"`
+	DeviceID  string         `json:"device_id"`
+	StartedAt time.Time      `json:"started_at"`
+	EndedAt   *time.Time     `json:"ended_at"`
+	Timeline  *statsTimeline `json:"timeline,omitempty"`
+}
+
+func cmdStats() {
+	var since time.Duration
+	var deviceArg, sessionID string
+	jsonOut := false
+	usage := func() {
+		fmt.Fprintln(os.Stderr, statsUsage)
+		os.Exit(2)
+	}
+	for i := 2; i < len(os.Args); i++ {
+		switch os.Args[i] {
+		case "--since", "--device", "--session":
+			if i+1 >= len(os.Args) {
+				usage()
+			}
+			i++
+			switch os.Args[i-1] {
+			case "--device":
+				deviceArg = os.Args[i]
+			case "--session":
+				sessionID = os.Args[i]
+			default:
+				d, err := time.ParseDuration(os.Args[i])
+				if err != nil || d <= 0 {
+					fmt.Fprintf(os.Stderr, "Invalid duration: %s\n", os.Args[i])
+					os.Exit(2)
+				}
+				since = d
+			}
+		case "--json":
+			jsonOut = true
+		default:
+			usage()
+		}
+	}
+	if sessionID != "" && (deviceArg != "" || since > 0) {
+		usage()
+	}
+
+	if deviceArg != "" || sessionID != "" {
+		if err := storedStats(deviceArg, sessionID, since, jsonOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	resp, err := sendDaemonRequest(daemonRequest{Cmd: "stats", Args: map[string]interface{}{"since_sec": since.Seconds()}})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		out, _ := json.MarshalIndent(resp.Data["timeline"], "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	var tl statsTimeline
+	raw, _ := json.Marshal(resp.Data["timeline"])
+	if err := json.Unmarshal(raw, &tl); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid timeline: %v\n", err)
+		os.Exit(1)
+	}
+	renderTimeline(os.Stdout, tl, int(numFloat(resp.Data["sample_step"])))
+}
+
+// storedStats lists a device's uploaded sessions (newest first, started
+// within since, default 24h) or shows one uploaded timeline.
+func storedStats(deviceArg, sessionID string, since time.Duration, jsonOut bool) error {
+	auth, cfg, err := getAuthAndConfig()
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	q.Set("order", "started_at.desc")
+	if sessionID != "" {
+		q.Set("session_id", "eq."+sessionID)
+		q.Set("select", "session_id,device_id,started_at,ended_at,timeline")
+		q.Set("limit", "1")
+	} else {
+		devices, err := fetchDevices(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth)
+		if err != nil {
+			return err
+		}
+		var dev *device
+		for i := range devices {
+			if devices[i].DeviceID == deviceArg || strings.EqualFold(devices[i].DeviceName, deviceArg) {
+				dev = &devices[i]
+				break
+			}
+		}
+		if dev == nil {
+			return fmt.Errorf("device '%s' not found", deviceArg)
+		}
+		if since <= 0 {
+			since = 24 * time.Hour
+		}
+		q.Set("device_id", "eq."+dev.DeviceID)
+		q.Set("started_at", "gte."+time.Now().Add(-since).UTC().Format(time.RFC3339))
+		q.Set("select", "session_id,device_id,started_at,ended_at")
+	}
+	rows, err := fetchSessionStats(cfg.SupabaseURL, cfg.SupabaseAnonKey, auth.GetToken(), q)
+	if err != nil {
+		return err
+	}
+
+	if sessionID != "" {
+		if len(rows) == 0 || rows[0].Timeline == nil {
+			return fmt.Errorf("no uploaded stats for session %s (uploaded when the session ends, kept 30 days)", sessionID)
+		}
+		if jsonOut {
+			out, _ := json.MarshalIndent(rows[0].Timeline, "", "  ")
+			fmt.Println(string(out))
+			return nil
+		}
+		renderTimeline(os.Stdout, *rows[0].Timeline, 1)
+		return nil
+	}
+	if jsonOut {
+		out, _ := json.MarshalIndent(rows, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+	renderSessionList(os.Stdout, rows)
+	return nil
+}
+
+func fetchSessionStats(supabaseURL, anonKey, token string, q url.Values) ([]sessionStatsRow, error) {
+	req, err := http.NewRequest("GET", supabaseURL+"/rest/v1/session_stats?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", anonKey)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	var rows []sessionStatsRow
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// renderSessionList prints one line per uploaded session.
+func renderSessionList(w io.Writer, rows []sessionStatsRow) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "(no uploaded sessions in that window)")
+		return
+	}
+	fmt.Fprintf(w, "%-36s  %-19s  %9s\n", "SESSION", "STARTED", "DURATION")
+	for _, r := range rows {
+		dur := "-"
+		if r.EndedAt != nil {
+			dur = r.EndedAt.Sub(r.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%-36s  %-19s  %9s\n", r.SessionID, r.StartedAt.Local().Format("2006-01-02 15:04:05"), dur)
+	}
+	fmt.Fprintln(w, "Show one with: remote-desktop-cli stats --session <id>")
+}
+
+// renderTimeline prints a timeline as events plus a sample table. step > 1
+// means the agent merged samples to fit its reply.
+func renderTimeline(w io.Writer, tl statsTimeline, step int) {
+	fmt.Fprintf(w, "Session:  %s\n", tl.SessionID)
+	fmt.Fprintf(w, "Started:  %s\n", tl.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	if tl.EndedAt != nil {
+		fmt.Fprintf(w, "Ended:    %s\n", tl.EndedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if step > 1 {
+		fmt.Fprintf(w, "Samples:  merged %d to 1 to fit the reply (use --since to narrow)\n", step)
+	}
+
+	if len(tl.Events) > 0 {
+		fmt.Fprintln(w, "Events:")
+		for _, e := range tl.Events {
+			fmt.Fprintf(w, "  %s  %-16s %s\n", e.At.Local().Format("15:04:05"), e.Kind, e.Detail)
+		}
+	}
+
+	if len(tl.Samples) == 0 {
+		fmt.Fprintln(w, "(no samples)")
+		return
+	}
+	// Merged samples (long sessions) show the worst RTT, jitter, loss and
+	// buffer over their span and the mean bitrate.
+	fmt.Fprintf(w, "%-8s  %-6s  %7s  %7s  %6s  %9s  %-8s  %-13s  %7s  %5s\n",
+		"TIME", "PAIR", "RTT(ms)", "JIT(ms)", "LOSS%", "KBIT/S", "ENCODER", "MODE", "BUF(KB)", "MERGE")
+	for _, s := range tl.Samples {
+		merged := "-"
+		if s.Count > 1 {
+			merged = fmt.Sprintf("%dx", s.Count)
+		}
+		fmt.Fprintf(w, "%-8s  %-6s  %7.1f  %7.1f  %6.1f  %9.0f  %-8s  %-13s  %7d  %5s\n",
+			s.At.Local().Format("15:04:05"), stringOr(s.ConnType, "-"), s.RTTMs, s.JitterMs,
+			s.LossPct, s.BitrateKbps, stringOr(s.Encoder, "-"), stringOr(s.Mode, "-"), s.BufferedKB, merged)
+	}
+}
+
+func stringOr(v interface{}, fallback string) string {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
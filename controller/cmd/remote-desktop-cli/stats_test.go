@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTimeline(t *testing.T) {
+	start := time.Date(2026, 10, 18, 14, 0, 0, 0, time.Local)
+	ended := start.Add(90 * time.Minute)
+	tl := statsTimeline{
+		SessionID: "sess-1",
+		StartedAt: start,
+		EndedAt:   &ended,
+		Events: []statsEvent{
+			{At: start.Add(time.Minute), Kind: "conn_type", Detail: "host -> relay"},
+		},
+		Samples: []statsSample{
+			{At: start, ConnType: "host", RTTMs: 12.34, JitterMs: 1, BitrateKbps: 4000, Encoder: "openh264", Mode: "h264", BufferedKB: 3},
+			{At: start.Add(4 * time.Second), ConnType: "relay", RTTMs: 480, LossPct: 7.5, BitrateKbps: 900, Mode: "jpeg", Count: 2},
+		},
+	}
+
+	var buf bytes.Buffer
+	renderTimeline(&buf, tl, 4)
+	out := buf.String()
+	for _, want := range []string{
+		"Session:  sess-1",
+		"Started:  2026-10-18 14:00:00",
+		"Ended:    2026-10-18 15:30:00",
+		"merged 4 to 1",
+		"14:01:00  conn_type        host -> relay",
+		"14:00:00  host       12.3      1.0     0.0       4000  openh264  h264                 3      -",
+		"14:00:04  relay     480.0      0.0     7.5        900  -         jpeg                 0     2x",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	renderTimeline(&buf, statsTimeline{SessionID: "s"}, 1)
+	if out := buf.String(); !strings.Contains(out, "(no samples)") || strings.Contains(out, "merged") || strings.Contains(out, "Ended") {
+		t.Errorf("empty timeline:\n%s", out)
+	}
+}
+
+func TestRenderSessionList(t *testing.T) {
+	start := time.Date(2026, 10, 18, 14, 0, 0, 0, time.Local)
+	ended := start.Add(42*time.Minute + 7*time.Second)
+	var buf bytes.Buffer
+	renderSessionList(&buf, []sessionStatsRow{
+		{SessionID: "sess-2", StartedAt: start, EndedAt: &ended},
+		{SessionID: "sess-1", StartedAt: start.Add(-time.Hour)},
+	})
+	out := buf.String()
+	for _, want := range []string{"sess-2", "2026-10-18 14:00:00", "42m7s", "2026-10-18 13:00:00", "stats --session"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	renderSessionList(&buf, nil)
+	if !strings.Contains(buf.String(), "no uploaded sessions") {
+		t.Errorf("empty list: %q", buf.String())
+	}
+}
+
+func TestFetchSessionStats(t *testing.T) {
+	var gotQuery url.Values
+	var gotAuth, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/v1/session_stats" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery, gotAuth, gotKey = r.URL.Query(), r.Header.Get("Authorization"), r.Header.Get("apikey")
+		json.NewEncoder(w).Encode([]map[string]interface{}{{
+			"session_id": "sess-1",
+			"device_id":  "dev-1",
+			"started_at": "2026-10-18T12:00:00Z",
+			"ended_at":   "2026-10-18T13:00:00Z",
+			"timeline": map[string]interface{}{
+				"session_id": "sess-1",
+				"started_at": "2026-10-18T12:00:00Z",
+				"samples":    []map[string]interface{}{{"at": "2026-10-18T12:00:02Z", "rtt_ms": 35.5, "count": 4}},
+				"events":     []map[string]interface{}{{"at": "2026-10-18T12:30:00Z", "kind": "ice_restart"}},
+			},
+		}})
+	}))
+	defer srv.Close()
+
+	q := url.Values{}
+	q.Set("session_id", "eq.sess-1")
+	rows, err := fetchSessionStats(srv.URL, "anon", "tok", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery.Get("session_id") != "eq.sess-1" || gotAuth != "Bearer tok" || gotKey != "anon" {
+		t.Errorf("request query %v, auth %q, apikey %q", gotQuery, gotAuth, gotKey)
+	}
+	if len(rows) != 1 || rows[0].Timeline == nil || rows[0].EndedAt == nil {
+		t.Fatalf("rows = %+v", rows)
+	}
+	tl := rows[0].Timeline
+	if len(tl.Samples) != 1 || tl.Samples[0].RTTMs != 35.5 || tl.Samples[0].Count != 4 || len(tl.Events) != 1 {
+		t.Errorf("timeline = %+v", tl)
+	}
+
+	if _, err := fetchSessionStats(srv.URL+"/missing", "anon", "tok", q); err == nil {
+		t.Error("HTTP error not reported")
+	}
+}
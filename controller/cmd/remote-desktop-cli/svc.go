@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+const svcUsageText = `Usage: remote-desktop-cli svc list [<filter>] [--running] [--json]
+       remote-desktop-cli svc start|stop|restart <name> [--json]
+       remote-desktop-cli svc set-startup <name> auto|delayed|manual|disabled [--json]
+
+Manages the device's services: the Service Control Manager on Windows
+(names like Spooler), launchd on macOS (labels) and systemd on Linux (units;
+"cups" means cups.service). list filters by a case-insensitive substring of
+the name or display name. The agent itself and services the OS needs to
+boot and log in cannot be stopped or set to manual or disabled.`
+
+// svcTimeout covers a restart: the agent waits up to 30s for the stop and
+// 30s for the start.
+const svcTimeout = 75 * time.Second
+
+// requestService runs one svc_* op on the agent.
+func requestService(conn *DeviceConnection, req protocol.SvcRequest, timeout time.Duration) (map[string]interface{}, error) {
+	if !conn.ProcessReady() {
+		return nil, fmt.Errorf("process channel not open (agent likely older than v3.0.2)")
+	}
+	if err := conn.Require(protocol.CapServices); err != nil {
+		return nil, err
+	}
+	sub := conn.processRouter.Subscribe("")
+	defer conn.processRouter.Unsubscribe("")
+
+	data, _ := protocol.Encode(req)
+	if err := conn.SendProcess(data); err != nil {
+		return nil, fmt.Errorf("send %s: %w", req.Op, err)
+	}
+
+	want := req.Op + "_result"
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-sub:
+			if !ok {
+				return nil, fmt.Errorf("process channel closed unexpectedly")
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(msg, &parsed); err != nil {
+				continue
+			}
+			switch parsed["op"] {
+			case want:
+				return parsed, nil
+			case "error":
+				errStr, _ := parsed["error"].(string)
+				return nil, fmt.Errorf("agent error: %s", errStr)
+			}
+		case <-deadline:
+			return nil, fmt.Errorf("%s timeout after %s", req.Op, timeout)
+		}
+	}
+}
+
+func handleSvc(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	deviceConn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	action := getStringArg(req.Args, "action", "")
+	switch action {
+	case "list", "start", "stop", "restart", "set-startup":
+	default:
+		return daemonResponse{OK: false, Error: fmt.Sprintf("unknown svc action: %q", action)}
+	}
+	result, err := requestService(deviceConn, protocol.SvcRequest{
+		Op:      "svc_" + strings.ReplaceAll(action, "-", "_"),
+		Name:    getStringArg(req.Args, "name", ""),
+		Startup: getStringArg(req.Args, "startup", ""),
+	}, svcTimeout)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	if ok, isControl := result["ok"].(bool); isControl && !ok {
+		errStr, _ := result["error"].(string)
+		return daemonResponse{OK: false, Error: errStr, Data: result}
+	}
+	return daemonResponse{OK: true, Data: result}
+}
+
+func cmdSvc() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, svcUsageText)
+		os.Exit(2)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+	action := os.Args[2]
+	args := map[string]interface{}{"action": action}
+	jsonOut := false
+	runningOnly := false
+	var pos []string
+	for _, a := range os.Args[3:] {
+		switch a {
+		case "--json":
+			jsonOut = true
+		case "--running":
+			runningOnly = true
+		default:
+			pos = append(pos, a)
+		}
+	}
+	switch action {
+	case "list":
+		if len(pos) > 1 {
+			usage()
+		}
+	case "start", "stop", "restart":
+		if len(pos) != 1 {
+			usage()
+		}
+		args["name"] = pos[0]
+	case "set-startup":
+		if len(pos) != 2 {
+			usage()
+		}
+		args["name"], args["startup"] = pos[0], pos[1]
+	default:
+		usage()
+	}
+
+	resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: "svc", Args: args}, svcTimeout+10*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOut && resp.Data != nil {
+		out, _ := json.MarshalIndent(resp.Data, "", "  ")
+		fmt.Println(string(out))
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	if jsonOut {
+		return
+	}
+
+	if action != "list" {
+		s, _ := resp.Data["service"].(map[string]interface{})
+		fmt.Printf("%s: %s", args["name"], stringOr(s["state"], "unknown"))
+		if startup := stringOr(s["startup"], ""); startup != "" {
+			fmt.Printf(" (startup %s)", startup)
+		}
+		if pid := int(numFloat(s["pid"])); pid > 0 {
+			fmt.Printf(", PID %d", pid)
+		}
+		fmt.Println()
+		return
+	}
+
+	filter := ""
+	if len(pos) == 1 {
+		filter = strings.ToLower(pos[0])
+	}
+	list, _ := resp.Data["services"].([]interface{})
+	fmt.Printf("%-40s  %-9s  %-8s  %7s  %s\n", "NAME", "STATE", "STARTUP", "PID", "DISPLAY NAME")
+	shown := 0
+	for _, raw := range list {
+		s, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, display := stringOr(s["name"], ""), stringOr(s["display_name"], "")
+		if filter != "" && !strings.Contains(strings.ToLower(name+" "+display), filter) {
+			continue
+		}
+		if runningOnly && s["state"] != protocol.SvcRunning {
+			continue
+		}
+		pid := "-"
+		if p := int(numFloat(s["pid"])); p > 0 {
+			pid = fmt.Sprint(p)
+		}
+		fmt.Printf("%-40s  %-9s  %-8s  %7s  %s\n", name, stringOr(s["state"], "-"), stringOr(s["startup"], "-"), pid, display)
+		shown++
+	}
+	fmt.Printf("\n%d of %d services\n", shown, len(list))
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	_ "image/png"
+)
+
+// Pure-Go image helpers for wait-stable, wait-change, find-image and diff.
+// Everything works on luma only: frames arrive as JPEG, whose Y plane is
+// used directly, and colour adds little when comparing UI states.
+
+const (
+	// tileSize is the edge of a change-detection tile in frame pixels
+	tileSize = 32
+	// tileThreshold is the mean absolute luma difference that makes a tile
+	// dirty; JPEG re-encoding noise stays well below it
+	tileThreshold = 3.0
+	// pixelThreshold is the luma difference that counts a pixel as changed
+	// in diff
+	pixelThreshold = 24
+)
+
+// grayImage is a luma copy of an image, optionally box-downscaled.
+type grayImage struct {
+	w, h  int
+	scale int // frame pixels per grayImage pixel
+	pix   []uint8
+}
+
+func (g *grayImage) at(x, y int) uint8 { return g.pix[y*g.w+x] }
+
+// decodeGray decodes JPEG or PNG data into luma downscaled by scale.
+func decodeGray(data []byte, scale int) (*grayImage, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return toGray(img, scale), nil
+}
+
+func loadGray(path string, scale int) (*grayImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeGray(data, scale)
+}
+
+func toGray(img image.Image, scale int) *grayImage {
+	if scale < 1 {
+		scale = 1
+	}
+	b := img.Bounds()
+	g := &grayImage{w: b.Dx() / scale, h: b.Dy() / scale, scale: scale}
+	g.pix = make([]uint8, g.w*g.h)
+
+	luma := func(x, y int) uint32 { return uint32(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y) }
+	switch src := img.(type) {
+	case *image.YCbCr:
+		luma = func(x, y int) uint32 { return uint32(src.Y[src.YOffset(x, y)]) }
+	case *image.Gray:
+		luma = func(x, y int) uint32 { return uint32(src.Pix[src.PixOffset(x, y)]) }
+	}
+	n := uint32(scale * scale)
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			var sum uint32
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					sum += luma(b.Min.X+x*scale+dx, b.Min.Y+y*scale+dy)
+				}
+			}
+			g.pix[y*g.w+x] = uint8(sum / n)
+		}
+	}
+	return g
+}
+
+// parseRegion parses "x,y,w,h" in frame pixels.
+func parseRegion(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("region must be x,y,w,h: %q", s)
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 {
+			return image.Rectangle{}, fmt.Errorf("region must be x,y,w,h: %q", s)
+		}
+		v[i] = n
+	}
+	if v[2] == 0 || v[3] == 0 {
+		return image.Rectangle{}, fmt.Errorf("region %q is empty", s)
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
+
+// regionArg reads the optional region (x, y, w, h) sent by the CLI.
+func regionArg(args map[string]interface{}) (image.Rectangle, bool) {
+	w, h := getIntArg(args, "region_w", 0), getIntArg(args, "region_h", 0)
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}, false
+	}
+	x, y := getIntArg(args, "region_x", 0), getIntArg(args, "region_y", 0)
+	return image.Rect(x, y, x+w, y+h), true
+}
+
+func regionArgs(r image.Rectangle) map[string]interface{} {
+	return map[string]interface{}{
+		"region_x": r.Min.X, "region_y": r.Min.Y,
+		"region_w": r.Dx(), "region_h": r.Dy(),
+	}
+}
+
+// dirtyTiles compares two luma frames of the same scale tile by tile and
+// returns the changed tiles in frame pixels, limited to region when it is
+// not empty. Frames of different size are entirely dirty.
+func dirtyTiles(a, b *grayImage, region image.Rectangle) []image.Rectangle {
+	full := image.Rect(0, 0, b.w*b.scale, b.h*b.scale)
+	if region.Empty() {
+		region = full
+	}
+	region = region.Intersect(full)
+	if a == nil || a.w != b.w || a.h != b.h || a.scale != b.scale {
+		return []image.Rectangle{region}
+	}
+
+	step := max(tileSize/b.scale, 1)
+	var dirty []image.Rectangle
+	for ty := region.Min.Y / b.scale; ty < (region.Max.Y+b.scale-1)/b.scale && ty < b.h; ty += step {
+		for tx := region.Min.X / b.scale; tx < (region.Max.X+b.scale-1)/b.scale && tx < b.w; tx += step {
+			var sum, n int
+			for y := ty; y < min(ty+step, b.h); y++ {
+				for x := tx; x < min(tx+step, b.w); x++ {
+					d := int(a.at(x, y)) - int(b.at(x, y))
+					if d < 0 {
+						d = -d
+					}
+					sum += d
+					n++
+				}
+			}
+			if n > 0 && float64(sum)/float64(n) > tileThreshold {
+				tile := image.Rect(tx*b.scale, ty*b.scale, (tx+step)*b.scale, (ty+step)*b.scale)
+				dirty = append(dirty, tile.Intersect(region))
+			}
+		}
+	}
+	return dirty
+}
+
+// boundsOf returns the smallest rectangle covering rects.
+func boundsOf(rects []image.Rectangle) image.Rectangle {
+	var r image.Rectangle
+	for _, t := range rects {
+		r = r.Union(t)
+	}
+	return r
+}
+
+// imageMatch is a template match in frame pixels.
+type imageMatch struct {
+	X, Y, W, H int
+	Score      float64 // normalized cross-correlation, 1 = identical
+}
+
+// findTemplate locates tpl in img (both scale 1) by normalized
+// cross-correlation. It searches a downscaled copy first and refines the
+// best candidates at full resolution, so a 1080p frame takes well under a
+// second. region limits the search when it is not empty.
+func findTemplate(img, tpl *grayImage, region image.Rectangle) (imageMatch, bool) {
+	if tpl.w > img.w || tpl.h > img.h || tpl.w == 0 || tpl.h == 0 {
+		return imageMatch{}, false
+	}
+	full := image.Rect(0, 0, img.w, img.h)
+	if region.Empty() {
+		region = full
+	}
+	region = region.Intersect(full)
+	// The check comes first: image.Rect would swap the corners of an
+	// inverted search rectangle instead of leaving it empty.
+	if region.Dx() < tpl.w || region.Dy() < tpl.h {
+		return imageMatch{}, false
+	}
+	// Positions whose template window fits inside the region
+	search := image.Rect(region.Min.X, region.Min.Y, region.Max.X-tpl.w+1, region.Max.Y-tpl.h+1)
+
+	scale := 1
+	for _, s := range []int{4, 2} {
+		if tpl.w/s >= 8 && tpl.h/s >= 8 {
+			scale = s
+			break
+		}
+	}
+
+	type candidate struct {
+		x, y  int
+		score float64
+	}
+	var cands []candidate
+	if scale == 1 {
+		cands = []candidate{{search.Min.X, search.Min.Y, -2}}
+		for y := search.Min.Y; y < search.Max.Y; y++ {
+			for x := search.Min.X; x < search.Max.X; x++ {
+				if s := ncc(img, tpl, x, y); s > cands[0].score {
+					cands[0] = candidate{x, y, s}
+				}
+			}
+		}
+	} else {
+		small := downscaleGray(img, scale)
+		smallTpl := downscaleGray(tpl, scale)
+		const keep = 5
+		for y := search.Min.Y / scale; y <= (search.Max.Y-1)/scale && y+smallTpl.h <= small.h; y++ {
+			for x := search.Min.X / scale; x <= (search.Max.X-1)/scale && x+smallTpl.w <= small.w; x++ {
+				s := ncc(small, smallTpl, x, y)
+				if len(cands) == keep && s <= cands[keep-1].score {
+					continue
+				}
+				c := candidate{x * scale, y * scale, s}
+				i := len(cands)
+				if i < keep {
+					cands = append(cands, c)
+				} else {
+					i = keep - 1
+					cands[i] = c
+				}
+				for ; i > 0 && cands[i].score > cands[i-1].score; i-- {
+					cands[i], cands[i-1] = cands[i-1], cands[i]
+				}
+			}
+		}
+		// Refine around each coarse hit at full resolution
+		best := candidate{score: -2}
+		for _, c := range cands {
+			for y := max(c.y-scale, search.Min.Y); y <= min(c.y+scale, search.Max.Y-1); y++ {
+				for x := max(c.x-scale, search.Min.X); x <= min(c.x+scale, search.Max.X-1); x++ {
+					if s := ncc(img, tpl, x, y); s > best.score {
+						best = candidate{x, y, s}
+					}
+				}
+			}
+		}
+		cands = []candidate{best}
+	}
+	if len(cands) == 0 || cands[0].score < -1 {
+		return imageMatch{}, false
+	}
+	return imageMatch{X: cands[0].x, Y: cands[0].y, W: tpl.w, H: tpl.h, Score: cands[0].score}, true
+}
+
+// downscaleGray box-averages a scale-1 luma image by factor.
+func downscaleGray(g *grayImage, factor int) *grayImage {
+	out := &grayImage{w: g.w / factor, h: g.h / factor, scale: g.scale * factor}
+	out.pix = make([]uint8, out.w*out.h)
+	n := factor * factor
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			sum := 0
+			for dy := 0; dy < factor; dy++ {
+				row := (y*factor + dy) * g.w
+				for dx := 0; dx < factor; dx++ {
+					sum += int(g.pix[row+x*factor+dx])
+				}
+			}
+			out.pix[y*out.w+x] = uint8(sum / n)
+		}
+	}
+	return out
+}
+
+// ncc is the normalized cross-correlation of tpl with img at (x, y).
+// Flat areas have no variance to correlate, so they score by how close
+// their brightness is instead.
+func ncc(img, tpl *grayImage, x, y int) float64 {
+	var sumI, sumT, sumII, sumTT, sumIT float64
+	for ty := 0; ty < tpl.h; ty++ {
+		row := (y+ty)*img.w + x
+		trow := ty * tpl.w
+		for tx := 0; tx < tpl.w; tx++ {
+			i := float64(img.pix[row+tx])
+			t := float64(tpl.pix[trow+tx])
+			sumI += i
+			sumT += t
+			sumII += i * i
+			sumTT += t * t
+			sumIT += i * t
+		}
+	}
+	n := float64(tpl.w * tpl.h)
+	varI := sumII - sumI*sumI/n
+	varT := sumTT - sumT*sumT/n
+	if varI < n || varT < n { // standard deviation below one luma step
+		if varI < n && varT < n {
+			return 1 - math.Abs(sumI-sumT)/n/255
+		}
+		return 0
+	}
+	return (sumIT - sumI*sumT/n) / math.Sqrt(varI*varT)
+}
+
+// imageDiff summarizes how b differs from a.
+type imageDiff struct {
+	Width, Height  int
+	ChangedPixels  int
+	ChangedPercent float64
+	Bounds         image.Rectangle // of changed pixels, empty when identical
+	DirtyTiles     int
+}
+
+// diffImages compares two images of the same size pixel by pixel. When
+// highlight is set it returns b with the changed pixels tinted red.
+func diffImages(a, b image.Image, highlight bool) (imageDiff, *image.RGBA, error) {
+	ga, gb := toGray(a, 1), toGray(b, 1)
+	if ga.w != gb.w || ga.h != gb.h {
+		return imageDiff{}, nil, fmt.Errorf("images differ in size: %dx%d vs %dx%d", ga.w, ga.h, gb.w, gb.h)
+	}
+	d := imageDiff{Width: gb.w, Height: gb.h}
+	var out *image.RGBA
+	if highlight {
+		out = image.NewRGBA(image.Rect(0, 0, gb.w, gb.h))
+	}
+	bMin := b.Bounds().Min
+	for y := 0; y < gb.h; y++ {
+		for x := 0; x < gb.w; x++ {
+			delta := int(ga.at(x, y)) - int(gb.at(x, y))
+			changed := delta > pixelThreshold || delta < -pixelThreshold
+			if changed {
+				d.ChangedPixels++
+				d.Bounds = d.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+			if out != nil {
+				r, g, bl, _ := b.At(bMin.X+x, bMin.Y+y).RGBA()
+				c := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8), 255}
+				if changed {
+					c = color.RGBA{255, c.G / 3, c.B / 3, 255}
+				} else {
+					// Fade unchanged pixels so changes stand out
+					c = color.RGBA{c.R/2 + 64, c.G/2 + 64, c.B/2 + 64, 255}
+				}
+				out.SetRGBA(x, y, c)
+			}
+		}
+	}
+	if n := gb.w * gb.h; n > 0 {
+		d.ChangedPercent = 100 * float64(d.ChangedPixels) / float64(n)
+	}
+	d.DirtyTiles = len(dirtyTiles(ga, gb, image.Rectangle{}))
+	return d, out, nil
+}
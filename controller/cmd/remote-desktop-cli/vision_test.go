@@ -0,0 +1,216 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// noiseGray returns a w×h luma image of deterministic noise, which has one
+// clear best match for any template cut from it.
+func noiseGray(w, h int, seed int64) *grayImage {
+	r := rand.New(rand.NewSource(seed))
+	g := &grayImage{w: w, h: h, scale: 1, pix: make([]uint8, w*h)}
+	for i := range g.pix {
+		g.pix[i] = uint8(r.Intn(256))
+	}
+	return g
+}
+
+func flatGray(w, h int, v uint8) *grayImage {
+	g := &grayImage{w: w, h: h, scale: 1, pix: make([]uint8, w*h)}
+	for i := range g.pix {
+		g.pix[i] = v
+	}
+	return g
+}
+
+// crop copies r out of g.
+func crop(g *grayImage, r image.Rectangle) *grayImage {
+	out := &grayImage{w: r.Dx(), h: r.Dy(), scale: g.scale, pix: make([]uint8, r.Dx()*r.Dy())}
+	for y := 0; y < out.h; y++ {
+		copy(out.pix[y*out.w:(y+1)*out.w], g.pix[(r.Min.Y+y)*g.w+r.Min.X:])
+	}
+	return out
+}
+
+// paste copies src into g at (x, y).
+func paste(g, src *grayImage, x, y int) {
+	for sy := 0; sy < src.h; sy++ {
+		copy(g.pix[(y+sy)*g.w+x:], src.pix[sy*src.w:(sy+1)*src.w])
+	}
+}
+
+func TestNCC(t *testing.T) {
+	tpl := noiseGray(16, 16, 1)
+	inverted := &grayImage{w: 16, h: 16, scale: 1, pix: make([]uint8, 256)}
+	for i, v := range tpl.pix {
+		inverted.pix[i] = 255 - v
+	}
+
+	tests := []struct {
+		name     string
+		img, tpl *grayImage
+		want     float64
+	}{
+		{"identical", tpl, tpl, 1},
+		{"inverted", inverted, tpl, -1},
+		{"flat, same brightness", flatGray(16, 16, 200), flatGray(16, 16, 200), 1},
+		{"flat, other brightness", flatGray(16, 16, 0), flatGray(16, 16, 255), 0},
+		{"flat image, textured template", flatGray(16, 16, 128), tpl, 0},
+		{"textured image, flat template", tpl, flatGray(16, 16, 128), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ncc(tt.img, tt.tpl, 0, 0); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ncc = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	img := noiseGray(200, 120, 2)
+	small := crop(img, image.Rect(50, 30, 62, 42))    // 12×12: searched at full resolution
+	large := crop(img, image.Rect(100, 60, 140, 100)) // 40×40: coarse pass, then refined
+
+	// A flat button on a flat background, next to noise
+	flat := flatGray(200, 120, 40)
+	paste(flat, flatGray(20, 10, 220), 150, 90)
+	paste(flat, crop(img, image.Rect(0, 0, 60, 60)), 0, 0)
+
+	tests := []struct {
+		name   string
+		img    *grayImage
+		tpl    *grayImage
+		region image.Rectangle
+		want   image.Point
+		found  bool
+	}{
+		{"exact match, small template", img, small, image.Rectangle{}, image.Pt(50, 30), true},
+		{"exact match, large template", img, large, image.Rectangle{}, image.Pt(100, 60), true},
+		{"match inside region", img, small, image.Rect(40, 20, 80, 60), image.Pt(50, 30), true},
+		{"region clipped to the image", img, large, image.Rect(90, 50, 500, 500), image.Pt(100, 60), true},
+		{"flat template on flat region", flat, flatGray(20, 10, 220), image.Rect(100, 60, 200, 120), image.Pt(150, 90), true},
+		{"region smaller than template", img, large, image.Rect(100, 60, 120, 80), image.Point{}, false},
+		{"region outside the image", img, small, image.Rect(300, 300, 400, 400), image.Point{}, false},
+		{"template larger than image", small, img, image.Rectangle{}, image.Point{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := findTemplate(tt.img, tt.tpl, tt.region)
+			if ok != tt.found {
+				t.Fatalf("found = %v, want %v (%+v)", ok, tt.found, m)
+			}
+			if !ok {
+				return
+			}
+			if image.Pt(m.X, m.Y) != tt.want || m.W != tt.tpl.w || m.H != tt.tpl.h {
+				t.Errorf("match = %+v, want %v", m, tt.want)
+			}
+			if m.Score < 0.99 {
+				t.Errorf("score = %v, want ~1", m.Score)
+			}
+		})
+	}
+
+	// A region that excludes the only exact match finds something weaker.
+	if m, ok := findTemplate(img, small, image.Rect(120, 0, 200, 60)); ok && m.Score > 0.9 {
+		t.Errorf("match outside the region: %+v", m)
+	}
+}
+
+func TestDirtyTiles(t *testing.T) {
+	a := noiseGray(128, 96, 3) // 4×3 tiles
+	same := crop(a, image.Rect(0, 0, 128, 96))
+	changed := crop(a, image.Rect(0, 0, 128, 96))
+	paste(changed, flatGray(10, 10, 0), 40, 40) // inside tile (1, 1)
+	half := &grayImage{w: 64, h: 48, scale: 2, pix: make([]uint8, 64*48)}
+
+	tests := []struct {
+		name   string
+		a, b   *grayImage
+		region image.Rectangle
+		want   []image.Rectangle
+	}{
+		{"identical", a, same, image.Rectangle{}, nil},
+		{"one tile changed", a, changed, image.Rectangle{}, []image.Rectangle{image.Rect(32, 32, 64, 64)}},
+		{"change outside region", a, changed, image.Rect(64, 0, 128, 96), nil},
+		{"tile clipped to region", a, changed, image.Rect(40, 40, 50, 50), []image.Rectangle{image.Rect(40, 40, 50, 50)}},
+		{"region clipped to frame", a, changed, image.Rect(32, 32, 1000, 1000), []image.Rectangle{image.Rect(32, 32, 64, 64)}},
+		{"no previous frame", nil, a, image.Rectangle{}, []image.Rectangle{image.Rect(0, 0, 128, 96)}},
+		{"size mismatch", a, noiseGray(96, 96, 3), image.Rectangle{}, []image.Rectangle{image.Rect(0, 0, 96, 96)}},
+		{"scale mismatch", half, &grayImage{w: 64, h: 48, scale: 1, pix: half.pix}, image.Rect(0, 0, 10, 10), []image.Rectangle{image.Rect(0, 0, 10, 10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dirtyTiles(tt.a, tt.b, tt.region)
+			if len(got) != len(tt.want) {
+				t.Fatalf("dirtyTiles = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("tile %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	gray := func(w, h int, v uint8) *image.Gray {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for i := range img.Pix {
+			img.Pix[i] = v
+		}
+		return img
+	}
+	base := gray(64, 64, 100)
+	changed := gray(64, 64, 100)
+	for y := 10; y < 20; y++ {
+		for x := 5; x < 15; x++ {
+			changed.SetGray(x, y, color.Gray{Y: 250})
+		}
+	}
+	noise := gray(64, 64, 100)
+	noise.SetGray(0, 0, color.Gray{Y: 100 + pixelThreshold}) // At the threshold: unchanged
+
+	tests := []struct {
+		name    string
+		a, b    image.Image
+		want    imageDiff
+		wantErr bool
+	}{
+		{"identical", base, base, imageDiff{Width: 64, Height: 64}, false},
+		{"below threshold", base, noise, imageDiff{Width: 64, Height: 64}, false},
+		{"changed block", base, changed, imageDiff{
+			Width: 64, Height: 64, ChangedPixels: 100, ChangedPercent: 100 * 100.0 / 4096,
+			Bounds: image.Rect(5, 10, 15, 20), DirtyTiles: 1,
+		}, false},
+		{"frame size mismatch", base, gray(32, 64, 100), imageDiff{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, out, err := diffImages(tt.a, tt.b, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("diff = %+v, want %+v", got, tt.want)
+			}
+			if out == nil || out.Bounds() != tt.b.Bounds() {
+				t.Fatalf("highlight image bounds = %v", out)
+			}
+			if got.ChangedPixels > 0 {
+				if c := out.RGBAAt(got.Bounds.Min.X, got.Bounds.Min.Y); c.R != 255 {
+					t.Errorf("changed pixel not tinted red: %v", c)
+				}
+			}
+		})
+	}
+}
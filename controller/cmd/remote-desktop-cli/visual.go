@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// waitScale downsamples frames for change detection (a 32px tile is 8x8)
+	waitScale = 4
+	// waitMaxTimeout bounds wait-stable, wait-change and find-image --timeout
+	waitMaxTimeout = 10 * time.Minute
+	// waitPoll is how often the frame cache is checked for a new frame
+	waitPoll = 50 * time.Millisecond
+)
+
+const visualUsageText = `Usage:
+  remote-desktop-cli wait-stable [--timeout 10s] [--quiet 500ms] [--region x,y,w,h] [--max-tiles 0]
+        Wait until no 32px tile changes for --quiet; --max-tiles 2 ignores a blinking caret
+  remote-desktop-cli wait-change [--region x,y,w,h] [--timeout 10s]
+        Wait until the screen (or region) differs from how it looks now
+  remote-desktop-cli find-image <template.png> [--threshold 0.9] [--region x,y,w,h] [--timeout 0] [--json]
+        Locate a template on the screen; --timeout keeps looking until it appears
+  remote-desktop-cli diff <a.jpg> <b.jpg> [-o diff.png] [--json]
+        Compare two screenshots (local files)
+Coordinates are frame pixels, the same as a full-size screenshot.`
+
+// latestGray decodes the newest cached frame if it is newer than since.
+func latestGray(conn *DeviceConnection, since time.Time, scale int) (*grayImage, time.Time, bool) {
+	frame, at := conn.GetLastFrame()
+	if frame == nil || !at.After(since) {
+		return nil, since, false
+	}
+	g, err := decodeGray(frame, scale)
+	if err != nil {
+		return nil, since, false
+	}
+	return g, at, true
+}
+
+func waitTimeoutArg(args map[string]interface{}, def time.Duration) time.Duration {
+	d := time.Duration(getIntArg(args, "timeout_ms", int(def/time.Millisecond))) * time.Millisecond
+	return min(d, waitMaxTimeout)
+}
+
+// handleWaitStable returns once no more than max_tiles tiles have changed
+// between consecutive frames for quiet_ms. Frames only arrive when the
+// screen changes, so a quiet frame cache counts as stable.
+func handleWaitStable(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	timeout := waitTimeoutArg(req.Args, 10*time.Second)
+	quiet := time.Duration(getIntArg(req.Args, "quiet_ms", 500)) * time.Millisecond
+	maxTiles := getIntArg(req.Args, "max_tiles", 0)
+	region, _ := regionArg(req.Args)
+
+	start := time.Now()
+	lastChange := start
+	var prev *grayImage
+	var prevAt time.Time
+	for {
+		if cur, at, ok := latestGray(conn, prevAt, waitScale); ok {
+			if prev != nil && len(dirtyTiles(prev, cur, region)) > maxTiles {
+				lastChange = time.Now()
+			}
+			prev, prevAt = cur, at
+		}
+		if prev != nil && time.Since(lastChange) >= quiet {
+			return daemonResponse{OK: true, Data: map[string]interface{}{
+				"waited_ms": time.Since(start).Milliseconds(),
+			}}
+		}
+		if time.Since(start) > timeout {
+			if prev == nil {
+				return daemonResponse{OK: false, Error: "no video frame received"}
+			}
+			return daemonResponse{OK: false, Error: fmt.Sprintf("screen did not settle within %s", timeout)}
+		}
+		time.Sleep(waitPoll)
+	}
+}
+
+// handleWaitChange returns once a tile in the region differs from the
+// frame that was current when the request arrived.
+func handleWaitChange(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	timeout := waitTimeoutArg(req.Args, 10*time.Second)
+	region, _ := regionArg(req.Args)
+
+	start := time.Now()
+	var base *grayImage
+	var seen time.Time
+	for {
+		if cur, at, ok := latestGray(conn, seen, waitScale); ok {
+			seen = at
+			if base == nil {
+				base = cur
+			} else if tiles := dirtyTiles(base, cur, region); len(tiles) > 0 {
+				b := boundsOf(tiles)
+				return daemonResponse{OK: true, Data: map[string]interface{}{
+					"waited_ms": time.Since(start).Milliseconds(),
+					"tiles":     len(tiles),
+					"x":         b.Min.X, "y": b.Min.Y, "w": b.Dx(), "h": b.Dy(),
+				}}
+			}
+		}
+		if time.Since(start) > timeout {
+			if base == nil {
+				return daemonResponse{OK: false, Error: "no video frame received"}
+			}
+			return daemonResponse{OK: false, Error: fmt.Sprintf("no change within %s", timeout)}
+		}
+		time.Sleep(waitPoll)
+	}
+}
+
+// handleFindImage matches a template file (on this machine) against the
+// current frame, retrying on new frames until timeout_ms.
+func handleFindImage(req daemonRequest, connMgr *ConnectionManager, deviceID string) daemonResponse {
+	conn, err := connMgr.GetConnection(deviceID)
+	if err != nil {
+		return daemonResponse{OK: false, Error: err.Error()}
+	}
+	tpl, err := loadGray(getStringArg(req.Args, "template", ""), 1)
+	if err != nil {
+		return daemonResponse{OK: false, Error: fmt.Sprintf("template: %v", err)}
+	}
+	threshold := 0.9
+	if v, ok := req.Args["threshold"].(float64); ok && v > 0 {
+		threshold = v
+	}
+	timeout := waitTimeoutArg(req.Args, 0)
+	region, _ := regionArg(req.Args)
+
+	start := time.Now()
+	var seen time.Time
+	var best imageMatch
+	for {
+		if img, at, ok := latestGray(conn, seen, 1); ok {
+			seen = at
+			m, found := findTemplate(img, tpl, region)
+			if found && m.Score >= threshold {
+				return daemonResponse{OK: true, Data: map[string]interface{}{
+					"x": m.X, "y": m.Y, "w": m.W, "h": m.H,
+					"center_x": m.X + m.W/2, "center_y": m.Y + m.H/2,
+					"score":     m.Score,
+					"waited_ms": time.Since(start).Milliseconds(),
+				}}
+			}
+			if found && m.Score > best.Score {
+				best = m
+			}
+		}
+		// Like screenshot, give the first frame up to 10s to arrive
+		if time.Since(start) >= timeout && (!seen.IsZero() || time.Since(start) > 10*time.Second) {
+			if seen.IsZero() {
+				return daemonResponse{OK: false, Error: "no video frame received"}
+			}
+			return daemonResponse{OK: false, Error: fmt.Sprintf("image not found (best score %.2f at %d,%d, threshold %.2f)",
+				best.Score, best.X, best.Y, threshold)}
+		}
+		time.Sleep(waitPoll)
+	}
+}
+
+// parseVisualFlags reads the shared flags of the visual commands into
+// daemon args. Positional arguments are returned in order.
+func parseVisualFlags(rest []string) (map[string]interface{}, []string, bool) {
+	args := map[string]interface{}{}
+	var positional []string
+	asJSON := false
+	for i := 0; i < len(rest); i++ {
+		a := rest[i]
+		if a == "--json" {
+			asJSON = true
+			continue
+		}
+		if !strings.HasPrefix(a, "-") {
+			positional = append(positional, a)
+			continue
+		}
+		if i+1 >= len(rest) {
+			fmt.Fprintln(os.Stderr, visualUsageText)
+			os.Exit(2)
+		}
+		v := rest[i+1]
+		i++
+		switch a {
+		case "--timeout", "--quiet":
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid duration %q\n", v)
+				os.Exit(2)
+			}
+			args[strings.TrimPrefix(a, "--")+"_ms"] = int(d / time.Millisecond)
+		case "--region":
+			r, err := parseRegion(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+			for k, rv := range regionArgs(r) {
+				args[k] = rv
+			}
+		case "--max-tiles":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-tiles %q\n", v)
+				os.Exit(2)
+			}
+			args["max_tiles"] = n
+		case "--threshold":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 || f > 1 {
+				fmt.Fprintf(os.Stderr, "Error: threshold must be in (0, 1]: %q\n", v)
+				os.Exit(2)
+			}
+			args["threshold"] = f
+		case "-o", "--output":
+			args["output"] = v
+		default:
+			fmt.Fprintln(os.Stderr, visualUsageText)
+			os.Exit(2)
+		}
+	}
+	return args, positional, asJSON
+}
+
+// visualRequest sends a wait/find request with a socket deadline that
+// outlasts its timeout.
+func visualRequest(cmd string, args map[string]interface{}) map[string]interface{} {
+	timeout := time.Duration(getIntArg(args, "timeout_ms", 10000)) * time.Millisecond
+	resp, err := sendDaemonRequestTimeout(daemonRequest{Cmd: cmd, Args: args}, min(timeout, waitMaxTimeout)+30*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !resp.OK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Error)
+		os.Exit(1)
+	}
+	return resp.Data
+}
+
+func printVisualJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func cmdWaitStable() {
+	args, positional, asJSON := parseVisualFlags(os.Args[2:])
+	if len(positional) > 0 {
+		fmt.Fprintln(os.Stderr, visualUsageText)
+		os.Exit(2)
+	}
+	data := visualRequest("wait_stable", args)
+	if asJSON {
+		printVisualJSON(data)
+		return
+	}
+	fmt.Printf("Screen stable after %s\n", time.Duration(numFloat(data["waited_ms"]))*time.Millisecond)
+}
+
+func cmdWaitChange() {
+	args, positional, asJSON := parseVisualFlags(os.Args[2:])
+	if len(positional) > 0 {
+		fmt.Fprintln(os.Stderr, visualUsageText)
+		os.Exit(2)
+	}
+	data := visualRequest("wait_change", args)
+	if asJSON {
+		printVisualJSON(data)
+		return
+	}
+	fmt.Printf("Changed after %s at %.0f,%.0f,%.0f,%.0f\n",
+		time.Duration(numFloat(data["waited_ms"]))*time.Millisecond,
+		numFloat(data["x"]), numFloat(data["y"]), numFloat(data["w"]), numFloat(data["h"]))
+}
+
+func cmdFindImage() {
+	args, positional, asJSON := parseVisualFlags(os.Args[2:])
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, visualUsageText)
+		os.Exit(2)
+	}
+	// The daemon reads the template itself
+	tpl, err := filepath.Abs(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	args["template"] = tpl
+	if _, ok := args["timeout_ms"]; !ok {
+		args["timeout_ms"] = 0
+	}
+	data := visualRequest("find_image", args)
+	if asJSON {
+		printVisualJSON(data)
+		return
+	}
+	fmt.Printf("Found at %.0f,%.0f (%.0fx%.0f), center %.0f,%.0f, score %.3f\n",
+		numFloat(data["x"]), numFloat(data["y"]), numFloat(data["w"]), numFloat(data["h"]),
+		numFloat(data["center_x"]), numFloat(data["center_y"]), numFloat(data["score"]))
+}
+
+func cmdDiff() {
+	args, positional, asJSON := parseVisualFlags(os.Args[2:])
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, visualUsageText)
+		os.Exit(2)
+	}
+	var imgs [2]image.Image
+	for i, path := range positional {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		imgs[i], _, err = image.Decode(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+	output := getStringArg(args, "output", "")
+	d, highlighted, err := diffImages(imgs[0], imgs[1], output != "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if output != "" {
+		if err := writeImageFile(output, highlighted); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if asJSON {
+		printVisualJSON(map[string]interface{}{
+			"width": d.Width, "height": d.Height,
+			"changed_pixels": d.ChangedPixels, "changed_percent": d.ChangedPercent,
+			"dirty_tiles": d.DirtyTiles,
+			"x":           d.Bounds.Min.X, "y": d.Bounds.Min.Y, "w": d.Bounds.Dx(), "h": d.Bounds.Dy(),
+		})
+	} else if d.ChangedPixels == 0 {
+		fmt.Println("Images are identical")
+	} else {
+		fmt.Printf("%.2f%% changed (%d pixels, %d tiles) within %d,%d,%d,%d\n",
+			d.ChangedPercent, d.ChangedPixels, d.DirtyTiles,
+			d.Bounds.Min.X, d.Bounds.Min.Y, d.Bounds.Dx(), d.Bounds.Dy())
+	}
+	// Exit status like cmp: 0 identical, 1 different
+	if d.ChangedPixels > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeImageFile writes PNG, or JPEG for .jpg/.jpeg paths.
+func writeImageFile(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	default:
+		err = png.Encode(f, img)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
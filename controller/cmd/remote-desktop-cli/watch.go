@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	watchDefaultFPS = 5
+	watchMaxFPS     = 15
+	// watchKeepalive is how often an idle stream (static screen) checks that
+	// the CLI is still listening.
+	watchKeepalive = 5 * time.Second
+)
+
+const watchUsageText = `Usage: remote-desktop-cli watch [--listen 127.0.0.1:8090] [--fps 5] [--max-width 1280] [--quality 60] [--region x,y,w,h]
+       remote-desktop-cli watch -o <dir> [--count N] [--fps 5] ...
+
+Streams the live screen of the connected device. By default it serves MJPEG
+over HTTP on localhost under a random token printed at start: open
+http://127.0.0.1:8090/<token>/ in a browser or point a dashboard <img> at
+it; /<token>/frame.jpg returns the latest single frame. With -o it writes
+frame-000001.jpg, frame-000002.jpg, ... to <dir> instead.
+
+Frames are only sent when the screen changed, at most --fps per second. The
+stream ends when the device disconnects.`
+
+// daemonStreams counts open watch streams; the daemon does not idle out
+// while someone is watching.
+var daemonStreams atomic.Int32
+
+// handleWatchStream sends the live frame cache to the CLI as "frame"
+// messages until the device disconnects or the CLI goes away.
+func handleWatchStream(conn net.Conn, req daemonRequest, connMgr *ConnectionManager, deviceID string) error {
+	sw := newStreamWriter(conn)
+	fps := min(max(getIntArg(req.Args, "fps", watchDefaultFPS), 1), watchMaxFPS)
+	maxWidth := getIntArg(req.Args, "max_width", 1280)
+	quality := getIntArg(req.Args, "quality", 60)
+	count := getIntArg(req.Args, "count", 0)
+	region, _ := regionArg(req.Args)
+
+	daemonStreams.Add(1)
+	defer daemonStreams.Add(-1)
+
+	ticker := time.NewTicker(time.Second / time.Duration(fps))
+	defer ticker.Stop()
+	var lastFrameAt time.Time
+	lastSent := time.Now()
+	sent := 0
+	for range ticker.C {
+		dc, err := connMgr.GetConnection(deviceID)
+		if err != nil {
+			sw.Send(streamMsg{Type: "error", Error: "device disconnected: " + err.Error()})
+			return err
+		}
+		frame, frameAt := dc.GetLastFrame()
+		if frame == nil || !frameAt.After(lastFrameAt) {
+			if time.Since(lastSent) >= watchKeepalive {
+				if err := sw.Send(streamMsg{Type: "keepalive"}); err != nil {
+					return nil // CLI stopped watching
+				}
+				lastSent = time.Now()
+			}
+			continue
+		}
+		lastFrameAt = frameAt
+
+		out, w, h, err := cropScaleJPEG(frame, region, maxWidth, quality)
+		if errors.Is(err, errRegionOutside) {
+			sw.Send(streamMsg{Type: "error", Error: err.Error()})
+			return err
+		}
+		if err != nil {
+			log.Printf("[daemon] watch: skipping frame: %v", err)
+			continue
+		}
+		if err := sw.Send(streamMsg{
+			Type: "frame", Data: base64.StdEncoding.EncodeToString(out),
+			Bytes: int64(len(out)), Width: w, Height: h,
+		}); err != nil {
+			return nil
+		}
+		lastSent = time.Now()
+		sent++
+		if count > 0 && sent >= count {
+			sw.Send(streamMsg{Type: "end"})
+			return nil
+		}
+	}
+	return nil
+}
+
+// frameHub fans the latest frame out to HTTP clients. Slow clients skip
+// frames instead of buffering them.
+type frameHub struct {
+	mu      sync.Mutex
+	latest  []byte
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+func newFrameHub() *frameHub {
+	return &frameHub{clients: map[chan []byte]struct{}{}}
+}
+
+func (h *frameHub) publish(frame []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latest = frame
+	for ch := range h.clients {
+		select {
+		case <-ch: // drop the frame the client has not picked up yet
+		default:
+		}
+		ch <- frame
+	}
+}
+
+func (h *frameHub) subscribe() (chan []byte, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, false
+	}
+	ch := make(chan []byte, 1)
+	if h.latest != nil {
+		ch <- h.latest
+	}
+	h.clients[ch] = struct{}{}
+	return ch, true
+}
+
+func (h *frameHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[ch]; ok {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+// close ends every client stream.
+func (h *frameHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.clients {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+func (h *frameHub) serveMJPEG(w http.ResponseWriter, r *http.Request) {
+	ch, ok := h.subscribe()
+	if !ok {
+		http.Error(w, "stream ended", http.StatusGone)
+		return
+	}
+	defer h.unsubscribe(ch)
+
+	const boundary = "frame"
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+boundary)
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush() // send the headers before the first frame
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", boundary, len(frame)); err != nil {
+				return
+			}
+			if _, err := w.Write(frame); err != nil {
+				return
+			}
+			if _, err := io.WriteString(w, "\r\n"); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func (h *frameHub) serveFrame(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	frame := h.latest
+	h.mu.Unlock()
+	if frame == nil {
+		http.Error(w, "no frame yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.Write(frame)
+}
+
+// isLoopbackHost reports whether host (with or without a port) is
+// localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newWatchToken returns the random path segment the stream is served under.
+func newWatchToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// watchGuard only passes requests addressed to a loopback host and carrying
+// the stream token. The Host check defeats DNS rebinding (a web page on a
+// name that resolves to 127.0.0.1 still sends its own name); the token
+// keeps out other local users and pages that guess the port.
+func watchGuard(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.PathValue("token")), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func cmdWatch() {
+	args := map[string]interface{}{}
+	listen := "127.0.0.1:8090"
+	outDir := ""
+	rest := os.Args[2:]
+	for i := 0; i < len(rest); i++ {
+		if i+1 >= len(rest) {
+			fmt.Fprintln(os.Stderr, watchUsageText)
+			os.Exit(2)
+		}
+		a, v := rest[i], rest[i+1]
+		i++
+		switch a {
+		case "--listen":
+			listen = v
+		case "-o", "--output":
+			outDir = v
+		case "--region":
+			r, err := parseRegion(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+			for k, rv := range regionArgs(r) {
+				args[k] = rv
+			}
+		case "--fps", "--max-width", "--quality", "--count":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: %s expects a positive number, got %q\n", a, v)
+				os.Exit(2)
+			}
+			args[map[string]string{"--fps": "fps", "--max-width": "max_width", "--quality": "quality", "--count": "count"}[a]] = n
+		default:
+			fmt.Fprintln(os.Stderr, watchUsageText)
+			os.Exit(2)
+		}
+	}
+	if getIntArg(args, "fps", watchDefaultFPS) > watchMaxFPS {
+		fmt.Fprintf(os.Stderr, "Note: --fps capped at %d\n", watchMaxFPS)
+	}
+
+	// Frames go to local files or a loopback listener only; the stream is
+	// the remote screen and must not be reachable from the network.
+	var hub *frameHub
+	var server *http.Server
+	if outDir == "" {
+		if _, _, err := net.SplitHostPort(listen); err != nil || !isLoopbackHost(listen) {
+			fmt.Fprintf(os.Stderr, "Error: --listen must be a localhost address, got %q\n", listen)
+			os.Exit(2)
+		}
+		token, err := newWatchToken()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		hub = newFrameHub()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /{token}/{$}", watchGuard(token, hub.serveMJPEG))
+		mux.HandleFunc("GET /{token}/frame.jpg", watchGuard(token, hub.serveFrame))
+		server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go server.Serve(ln)
+		fmt.Printf("Streaming on http://%s/%s/ (latest frame: /%s/frame.jpg), Ctrl+C to stop\n", ln.Addr(), token, token)
+	} else {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Writing frames to %s, Ctrl+C to stop\n", outDir)
+	}
+
+	conn, err := streamingDial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Time{}) // runs until the device disconnects
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Cmd: "watch", Args: args}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: send request: %v\n", err)
+		os.Exit(1)
+	}
+
+	streamErr := followWatchStream(conn, func(n int, frame []byte) error {
+		if hub != nil {
+			hub.publish(frame)
+			return nil
+		}
+		return os.WriteFile(filepath.Join(outDir, fmt.Sprintf("frame-%06d.jpg", n)), frame, 0644)
+	})
+	if hub != nil {
+		hub.close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		server.Shutdown(ctx)
+		cancel()
+	}
+	if streamErr != nil {
+		fmt.Fprintf(os.Stderr, "Stream ended: %v\n", streamErr)
+		os.Exit(1)
+	}
+}
+
+// followWatchStream passes each streamed frame to onFrame until the daemon
+// ends the stream. It returns nil for a normal end (--count reached).
+func followWatchStream(conn net.Conn, onFrame func(n int, frame []byte) error) error {
+	dec := json.NewDecoder(conn)
+	n := 0
+	for {
+		var m streamMsg
+		if err := dec.Decode(&m); err != nil {
+			return fmt.Errorf("daemon connection lost: %v", err)
+		}
+		switch m.Type {
+		case "frame":
+			frame, err := base64.StdEncoding.DecodeString(m.Data)
+			if err != nil {
+				return fmt.Errorf("bad frame from daemon: %v", err)
+			}
+			n++
+			if err := onFrame(n, frame); err != nil {
+				return err
+			}
+		case "end":
+			return nil
+		case "error":
+			return errors.New(m.Error)
+		}
+	}
+}
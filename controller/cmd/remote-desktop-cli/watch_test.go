@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWatchGuard(t *testing.T) {
+	const token = "s3cret-token"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{token}/frame.jpg", watchGuard(token, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("jpeg"))
+	}))
+
+	tests := []struct {
+		name, host, path string
+		want             int
+	}{
+		{"ipv4 loopback", "127.0.0.1:8090", "/" + token + "/frame.jpg", http.StatusOK},
+		{"localhost", "LocalHost:8090", "/" + token + "/frame.jpg", http.StatusOK},
+		{"ipv6 loopback", "[::1]:8090", "/" + token + "/frame.jpg", http.StatusOK},
+		{"no port", "127.0.0.1", "/" + token + "/frame.jpg", http.StatusOK},
+		{"rebound name", "attacker.example:8090", "/" + token + "/frame.jpg", http.StatusForbidden},
+		{"lan address", "192.168.1.5:8090", "/" + token + "/frame.jpg", http.StatusForbidden},
+		{"localhost suffix", "localhost.attacker.example", "/" + token + "/frame.jpg", http.StatusForbidden},
+		{"wrong token", "127.0.0.1:8090", "/guess/frame.jpg", http.StatusNotFound},
+		{"no token", "127.0.0.1:8090", "/frame.jpg", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// sign-release writes the detached ed25519 signatures the agent and
+// controller updaters require: run it at release time over version.json
+// and every binary it points to, then publish each <file>.sig next to its
+// file.
+//
+//	sign-release -keygen [-out release.key]
+//	sign-release -key release.key[,next.key] version.json remote-agent-v1.2.3.exe ...
+//
+// Several keys sign every file while keys are rotated.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/stangtennis/Remote/releasesig"
+)
+
+var (
+	keygen   bool
+	keyFiles string
+	outFile  string
+)
+
+func init() {
+	flag.BoolVar(&keygen, "keygen", false, "Create a new release signing key pair")
+	flag.StringVar(&outFile, "out", "", "With -keygen: write the private key to this file")
+	flag.StringVar(&keyFiles, "key", "", "Private key file(s) to sign with, comma-separated")
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	if keygen {
+		if err := generateKey(outFile); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	if keyFiles == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: sign-release -key release.key[,next.key] <file>...")
+		fmt.Fprintln(os.Stderr, "       sign-release -keygen [-out release.key]")
+		os.Exit(2)
+	}
+
+	var keys []ed25519.PrivateKey
+	for _, path := range strings.Split(keyFiles, ",") {
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			log.Fatalf("❌ Read key: %v", err)
+		}
+		priv, err := releasesig.ParsePrivateKey(string(data))
+		if err != nil {
+			log.Fatalf("❌ %s: %v", path, err)
+		}
+		keys = append(keys, priv)
+	}
+
+	for _, file := range flag.Args() {
+		sig, err := releasesig.SignFile(file, keys)
+		if err != nil {
+			log.Fatalf("❌ Sign %s: %v", file, err)
+		}
+		if err := os.WriteFile(file+releasesig.SignatureSuffix, append(sig, '\n'), 0644); err != nil {
+			log.Fatalf("❌ Write signature: %v", err)
+		}
+		log.Printf("✅ Signed %s", file)
+	}
+}
+
+func generateKey(out string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	seed := base64.StdEncoding.EncodeToString(priv.Seed())
+	if out != "" {
+		if err := os.WriteFile(out, []byte(seed+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Private key written to %s — keep it offline.\n", out)
+	} else {
+		fmt.Printf("Private key: %s\n", seed)
+	}
+	fmt.Printf("Public key:  %s\n", base64.StdEncoding.EncodeToString(pub))
+	fmt.Printf("Key ID:      %s\n", releasesig.KeyID(pub))
+	fmt.Println("Add the public key to releasesig/keys.txt and rebuild the agent and controller.")
+	return nil
+}
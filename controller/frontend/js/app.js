@@ -290,6 +290,7 @@ const App = {
         <div class="device-meta">
           <span><i class="fas fa-${d.platform === 'darwin' ? 'apple' : 'windows'}"></i> ${this.esc(d.platform)}</span>
           ${d.agent_version ? `<span><i class="fas fa-code-branch"></i> ${this.esc(d.agent_version)}</span>` : ''}
+          ${d.update_pin ? `<span title="Fastlåst version"><i class="fas fa-thumbtack"></i> ${this.esc(d.update_pin)}</span>` : ''}
           <span><i class="fas fa-clock"></i> ${this.esc(d.time_since)}</span>
         </div>
         ${d.is_online ? `<div class="device-metrics">
@@ -325,8 +326,10 @@ const App = {
             </button>
             <div class="dropdown-menu">
               <button onclick="App.sendDeviceCommand('${d.device_id}', '${this.jsEsc(d.device_name)}', 'force_update')"><i class="fas fa-sync-alt"></i> Opdater agent</button>
+              <button onclick="App.setUpdatePin('${d.device_id}', '${this.jsEsc(d.device_name)}', '${this.jsEsc(d.update_pin || '')}')"><i class="fas fa-thumbtack"></i> Fastlås version</button>
               <button onclick="App.sendDeviceCommand('${d.device_id}', '${this.jsEsc(d.device_name)}', 'lock')"><i class="fas fa-lock"></i> Lås skærm</button>
               <button onclick="App.sendDeviceCommand('${d.device_id}', '${this.jsEsc(d.device_name)}', 'restart')"><i class="fas fa-redo"></i> Genstart</button>
+              ${d.platform === 'windows' ? `<button onclick="App.sendDeviceCommand('${d.device_id}', '${this.jsEsc(d.device_name)}', 'restart_safe_mode')"><i class="fas fa-life-ring"></i> Genstart i fejlsikret tilstand</button>` : ''}
               <button onclick="App.sendDeviceCommand('${d.device_id}', '${this.jsEsc(d.device_name)}', 'shutdown')"><i class="fas fa-power-off"></i> Luk ned</button>
             </div>
           </div>
@@ -992,12 +995,15 @@ const App = {
     // Close any open dropdowns
     document.querySelectorAll('.dropdown-menu.show').forEach(m => m.classList.remove('show'));
 
-    const labels = { force_update: 'Opdater', lock: 'Lås', restart: 'Genstart', shutdown: 'Luk ned' };
+    const labels = { force_update: 'Opdater', lock: 'Lås', restart: 'Genstart', restart_safe_mode: 'Fejlsikret genstart', shutdown: 'Luk ned' };
     const label = labels[command] || command;
 
     if (command === 'restart' || command === 'shutdown') {
       if (!confirm(`${label} '${deviceName}'?\n\nDette kan ikke fortrydes!`)) return;
     }
+    if (command === 'restart_safe_mode') {
+      if (!confirm(`Genstart '${deviceName}' i fejlsikret tilstand med netværk?\n\nAgenten starter igen i fejlsikret tilstand, og næste genstart er normal.`)) return;
+    }
 
     showToast(`Sender ${label}-kommando til ${deviceName}...`, 'info');
     try {
@@ -1032,6 +1038,19 @@ const App = {
     }
   },
 
+  async setUpdatePin(deviceId, deviceName, currentPin) {
+    document.querySelectorAll('.dropdown-menu.show').forEach(m => m.classList.remove('show'));
+    const version = prompt(`Fastlås '${deviceName}' på agent-version (fx v3.1.120).\nTom = følg udrulningen i version.json.`, currentPin || '');
+    if (version === null) return;
+    try {
+      await window.go.main.App.SetDeviceUpdatePin(deviceId, version.trim());
+      showToast(version.trim() ? `${deviceName} fastlåst på ${version.trim()}` : `${deviceName} følger udrulningen igen`, 'success');
+      this.loadDevices();
+    } catch (err) {
+      showToast(`Fejl: ${err?.message || err}`, 'error');
+    }
+  },
+
   async forceUpdateDevice(deviceId, deviceName) {
     this.sendDeviceCommand(deviceId, deviceName, 'force_update');
   },
@@ -1169,7 +1188,7 @@ const App = {
   },
 
   async forceUpdateAllDevices() {
-    if (!confirm('Send opdateringskommando til ALLE online enheder?')) return;
+    if (!confirm('Send opdateringskommando til ALLE online enheder?\n\nHver agent installerer kun, hvis dens udrulningsring (procent, tags eller fastlåst version) tillader det.')) return;
     try {
       await window.go.main.App.ForceUpdateAllDevices();
       showToast('Opdateringskommando sendt til alle enheder', 'success');
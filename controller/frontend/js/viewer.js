@@ -40,7 +40,7 @@ const ClipboardBroker = {
 
   hash(kind, payload) {
     let h = 0;
-    const s = kind + ':' + payload;
+    const s = kind + ':' + (typeof payload === 'string' ? payload : JSON.stringify(payload));
     for (let i = 0; i < s.length; i++) {
       h = ((h << 5) - h + s.charCodeAt(i)) | 0;
     }
@@ -100,6 +100,16 @@ const ClipboardBroker = {
       });
       return;
     }
+    if (kind === 'html') {
+      // payload is { html, text }; the text keeps plain pastes working
+      navigator.clipboard.write([new ClipboardItem({
+        'text/html': new Blob([payload.html], { type: 'text/html' }),
+        'text/plain': new Blob([payload.text || ''], { type: 'text/plain' }),
+      })]).catch(err => {
+        console.warn('clipboard.write html failed:', err);
+      });
+      return;
+    }
     if (kind === 'image') {
       try {
         const binary = atob(payload);
@@ -118,9 +128,14 @@ const ClipboardBroker = {
   _sendToSession(session, kind, payload) {
     const dc = session && session.dataChannel;
     if (!dc || dc.readyState !== 'open') return;
-    const msgType = kind === 'text' ? 'clipboard_text' : 'clipboard_image';
+    let msg;
+    if (kind === 'html') {
+      msg = { type: 'clipboard_html', content: payload.html, text: payload.text };
+    } else {
+      msg = { type: kind === 'text' ? 'clipboard_text' : 'clipboard_image', content: payload };
+    }
     try {
-      dc.send(JSON.stringify({ type: msgType, content: payload }));
+      dc.send(JSON.stringify(msg));
     } catch (e) {
       console.warn('Failed to forward clipboard to session', session.id, e);
     }
@@ -245,6 +260,7 @@ class ViewerSession {
           <button class="btn btn-sm btn-icon session-details-btn" title="Forbindelsesdetaljer"><i class="fas fa-info-circle"></i></button>
           <button class="btn btn-sm btn-icon session-update-btn" title="Opdater agent"><i class="fas fa-sync-alt"></i></button>
           <button class="btn btn-sm btn-icon session-screenshot-btn" title="Tag screenshot"><i class="fas fa-camera"></i></button>
+          <button class="btn btn-sm btn-icon session-macro-btn" title="Optag makro"><i class="fas fa-circle-dot"></i></button>
           <button class="btn btn-sm btn-icon session-terminal-btn" title="Terminal"><i class="fas fa-terminal"></i></button>
           <button class="btn btn-sm btn-icon session-login-btn" title="Login som RDP"><i class="fas fa-right-to-bracket"></i></button>
           <button class="btn btn-sm session-codec-btn" title="Skift codec (H.264 ⇄ JPEG)"><i class="fas fa-film"></i><span>JPEG</span></button>
@@ -741,7 +757,7 @@ class ViewerSession {
     if (msg.type === 'screen_info' || msg.type === 'frame_meta') {
       this.updateResolution(msg.width, msg.height);
     } else if (msg.type === 'monitor_list') {
-      this.updateMonitorList(msg.monitors || [], msg.active || 0);
+      this.updateMonitorList(msg.monitors || [], msg.active || 0, !!msg.span);
     } else if (msg.type === 'update_status') {
       const type = msg.status === 'error' ? 'error' : msg.status === 'up_to_date' ? 'success' : 'info';
       showToast(msg.message || msg.status, type);
@@ -767,6 +783,12 @@ class ViewerSession {
       if (msg.content) {
         ClipboardBroker.spread('image', msg.content, this.id);
       }
+    } else if (msg.type === 'clipboard_html') {
+      // Formatted copy, sent after its clipboard_text. clipboard_rtf is
+      // left to that text: the browser clipboard can't hold RTF.
+      if (msg.content) {
+        ClipboardBroker.spread('html', { html: msg.content, text: msg.text || '' }, this.id);
+      }
     }
     return true;
   }
@@ -808,6 +830,7 @@ class ViewerSession {
       const text = await navigator.clipboard.readText();
       if (text) {
         ClipboardBroker.broadcast('text', text, null);
+        await this._sendClipboardHTML(text);
         return;
       }
     } catch (_) { /* fall through to image */ }
@@ -827,7 +850,22 @@ class ViewerSession {
     }
   }
 
-  updateMonitorList(monitors, activeIndex) {
+  // _sendClipboardHTML follows the text with its HTML rendering when the
+  // copy was formatted. Agents that predate clipboard_html ignore it.
+  async _sendClipboardHTML(text) {
+    try {
+      const items = await navigator.clipboard.read();
+      for (const item of items) {
+        if (item.types.includes('text/html')) {
+          const html = await (await item.getType('text/html')).text();
+          if (html) ClipboardBroker.broadcast('html', { html, text }, null);
+          return;
+        }
+      }
+    } catch (_) { /* the text alone was sent */ }
+  }
+
+  updateMonitorList(monitors, activeIndex, span) {
     const select = this.wrapper.querySelector('.session-monitor-select');
     if (!select || monitors.length <= 1) return;
 
@@ -840,6 +878,15 @@ class ViewerSession {
       if (i === activeIndex) opt.selected = true;
       select.appendChild(opt);
     });
+
+    // Agent can composite all monitors into one frame (index -1)
+    if (span) {
+      const opt = document.createElement('option');
+      opt.value = -1;
+      opt.textContent = 'Alle skærme';
+      if (activeIndex === -1) opt.selected = true;
+      select.appendChild(opt);
+    }
   }
 
   switchMonitor(index) {
@@ -1856,6 +1903,7 @@ class ViewerSession {
       if (panel) panel.classList.toggle('visible');
     });
     this.wrapper.querySelector('.session-screenshot-btn').addEventListener('click', () => this.takeScreenshot());
+    this.wrapper.querySelector('.session-macro-btn').addEventListener('click', () => this.toggleMacroRecording());
     const logBtn = this.wrapper.querySelector('.session-log-btn');
     if (logBtn) logBtn.addEventListener('click', () => this.showSessionLog());
     const codecBtn = this.wrapper.querySelector('.session-codec-btn');
@@ -2465,6 +2513,28 @@ class ViewerSession {
     showToast('Screenshot gemt!', 'success');
   }
 
+  // Macro recording captures the input payloads as they are sent, so the
+  // macro replays exactly what reached the agent (normalized coordinates).
+  // The Go side compacts them into click/type/key steps and saves the file.
+  toggleMacroRecording() {
+    const btn = this.wrapper.querySelector('.session-macro-btn');
+    if (!this.macroRecording) {
+      this.macroRecording = { start: Date.now(), events: [] };
+      btn.classList.add('btn-danger');
+      btn.title = 'Stop optagelse og gem makro';
+      showToast('Optager makro — klik igen for at stoppe', 'info');
+      return;
+    }
+    const { events } = this.macroRecording;
+    this.macroRecording = null;
+    btn.classList.remove('btn-danger');
+    btn.title = 'Optag makro';
+    if (events.length === 0) { showToast('Ingen input optaget', 'warning'); return; }
+    window.go.main.App.SaveMacro(this.deviceName, JSON.stringify(events))
+      .then(path => { if (path) showToast('Makro gemt!', 'success'); })
+      .catch(err => showToast(`Kunne ikke gemme makro: ${err}`, 'error'));
+  }
+
   enableH264Mode() {
     // Request H.264 streaming mode for better performance on large screen changes
     if (!this.dataChannel) return;
@@ -2605,10 +2675,18 @@ class ViewerSession {
   sendWheelEvent(e) {
     if (!this.dataChannel || this.dataChannel.readyState !== 'open') return;
     this.inputStats.wheel++;
-    // Agent expects: t=mouse_scroll, delta (positive=up, negative=down)
+    // Agent expects: t=mouse_scroll, delta (positive=up, negative=down).
+    // dx/dy carry high-resolution wheel units (120 = one notch) for
+    // trackpads and horizontal scrolling; older agents ignore them.
+    const scale = e.deltaMode === 1 ? 40 : e.deltaMode === 2 ? 360 : 1.2;
+    const dx = Math.round(e.deltaX * scale);
+    const dy = Math.round(-e.deltaY * scale);
+    if (dx === 0 && dy === 0) return;
     this._sendInputPayload({
       t: 'mouse_scroll',
-      delta: e.deltaY > 0 ? -1 : 1
+      delta: Math.sign(dy),
+      dx,
+      dy
     });
   }
 
@@ -2633,9 +2711,13 @@ class ViewerSession {
       }
     }
 
+    // IME keystrokes are composed locally and not forwarded
+    if (e.isComposing || e.keyCode === 229) return;
+
     const evt = {
       t: 'key',
       code: e.code,
+      key: e.key,
       down: type === 'keydown',
       shift: e.shiftKey,
       ctrl: e.ctrlKey,
@@ -2669,6 +2751,10 @@ class ViewerSession {
         throw new Error(`control channel ${this.dataChannel ? this.dataChannel.readyState : 'missing'}`);
       }
       this.dataChannel.send(JSON.stringify(payload));
+      if (this.macroRecording) {
+        this.macroRecording.events.push({ at: Date.now() - this.macroRecording.start, e: payload });
+        if (this.macroRecording.events.length >= 100000) this.toggleMacroRecording();
+      }
       if (this.inputStats) {
         this.inputStats.sent++;
         this.inputStats.lastType = payload.t || payload.type || '';
@@ -8,16 +8,16 @@ import (
 	"image/png"
 	"log"
 	"time"
-
-	"golang.design/x/clipboard"
 )
 
 // Monitor watches the system clipboard for changes (controller side).
 type Monitor struct {
 	lastTextHash  string
 	lastImageHash string
+	lastRichHash  string
 	onTextChange  func(text string)
 	onImageChange func(imageData []byte)
+	onRichChange  func(rich Rich)
 	stopChan      chan bool
 	running       bool
 }
@@ -36,12 +36,18 @@ func (m *Monitor) SetOnImageChange(callback func(imageData []byte)) {
 	m.onImageChange = callback
 }
 
+// SetOnRichChange sets the callback for HTML/RTF copies. It fires after
+// the text callback for the same copy.
+func (m *Monitor) SetOnRichChange(callback func(rich Rich)) {
+	m.onRichChange = callback
+}
+
 func (m *Monitor) Start() error {
 	if m.running {
 		return nil
 	}
 
-	if err := clipboard.Init(); err != nil {
+	if err := initClipboard(); err != nil {
 		return err
 	}
 
@@ -63,11 +69,18 @@ func (m *Monitor) monitorLoop() {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
+	var lastCount uint64
 	for {
 		select {
 		case <-m.stopChan:
 			return
 		case <-ticker.C:
+			if count, ok := changeCount(); ok {
+				if count == lastCount {
+					continue
+				}
+				lastCount = count
+			}
 			m.checkClipboard()
 		}
 	}
@@ -75,7 +88,7 @@ func (m *Monitor) monitorLoop() {
 
 func (m *Monitor) checkClipboard() {
 	// Text
-	textData := clipboard.Read(clipboard.FmtText)
+	textData := readText()
 	if len(textData) > 0 {
 		text := string(textData)
 		hash := hashString(text)
@@ -90,11 +103,12 @@ func (m *Monitor) checkClipboard() {
 
 			log.Printf("?? Local clipboard text changed (%d bytes)", len(text))
 			m.onTextChange(text)
+			m.checkRich()
 		}
 	}
 
 	// Image
-	imageData := clipboard.Read(clipboard.FmtImage)
+	imageData := readImage()
 	if len(imageData) > 0 {
 		hash := hashBytes(imageData)
 
@@ -118,6 +132,28 @@ func (m *Monitor) checkClipboard() {
 	}
 }
 
+// checkRich reports the formatted rendering of a new copy. Formatted
+// copies always carry text, so this only runs when the text changed.
+func (m *Monitor) checkRich() {
+	if m.onRichChange == nil {
+		return
+	}
+	rich, _ := readRich()
+	format, content, ok := rich.Preferred()
+	hash := hashRich(format, content)
+	if !ok || hash == m.lastRichHash {
+		return
+	}
+	m.lastRichHash = hash
+	log.Printf("?? Local clipboard %s changed (%d bytes)", format, len(content))
+	m.onRichChange(rich)
+}
+
+// RememberRich marks HTML or RTF as already seen to prevent echo loops.
+func (m *Monitor) RememberRich(format, content string) {
+	m.lastRichHash = hashRich(format, content)
+}
+
 // RememberText marks the provided text as already seen to prevent echo loops.
 func (m *Monitor) RememberText(text string) {
 	m.lastTextHash = hashString(text)
@@ -5,8 +5,6 @@ import (
 	"image"
 	"image/png"
 	"log"
-
-	"golang.design/x/clipboard"
 )
 
 // Receiver handles incoming clipboard data from the remote agent
@@ -22,7 +20,7 @@ func NewReceiver() *Receiver {
 // Initialize initializes the clipboard system
 func (r *Receiver) Initialize() error {
 	if !r.initialized {
-		err := clipboard.Init()
+		err := initClipboard()
 		if err != nil {
 			return err
 		}
@@ -40,7 +38,9 @@ func (r *Receiver) SetText(text string) error {
 		}
 	}
 
-	clipboard.Write(clipboard.FmtText, []byte(text))
+	if err := writeText(text); err != nil {
+		return err
+	}
 	log.Printf("📋 Clipboard updated with text (%d bytes)", len(text))
 	return nil
 }
@@ -68,7 +68,9 @@ func (r *Receiver) SetImage(imageData []byte) error {
 		return err
 	}
 
-	clipboard.Write(clipboard.FmtImage, buf.Bytes())
+	if err := writeImage(buf.Bytes()); err != nil {
+		return err
+	}
 	log.Printf("📋 Clipboard updated with image (%d bytes)", buf.Len())
 	return nil
 }
@@ -81,7 +83,9 @@ func (r *Receiver) SetImageRaw(imageData []byte) error {
 		}
 	}
 
-	clipboard.Write(clipboard.FmtImage, imageData)
+	if err := writeImage(imageData); err != nil {
+		return err
+	}
 	log.Printf("📋 Clipboard updated with raw image (%d bytes)", len(imageData))
 	return nil
 }
@@ -94,7 +98,7 @@ func (r *Receiver) GetText() (string, error) {
 		}
 	}
 
-	data := clipboard.Read(clipboard.FmtText)
+	data := readText()
 	return string(data), nil
 }
 
@@ -106,7 +110,7 @@ func (r *Receiver) GetImage() (image.Image, error) {
 		}
 	}
 
-	data := clipboard.Read(clipboard.FmtImage)
+	data := readImage()
 	if len(data) == 0 {
 		return nil, nil
 	}
@@ -0,0 +1,21 @@
+//go:build !windows && !darwin
+
+package clipboard
+
+import "github.com/stangtennis/Remote/richclip"
+
+func readRich() (Rich, bool) {
+	conn, err := selection()
+	if err != nil {
+		return Rich{}, false
+	}
+	return richclip.ReadSelection(conn)
+}
+
+func writeRich(format, content, text string) error {
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	return richclip.WriteSelection(conn, format, content, text)
+}
@@ -0,0 +1,194 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/stangtennis/Remote/richclip"
+	"golang.org/x/sys/windows"
+)
+
+// golang.design/x/clipboard only knows text and images, so rich text goes
+// through the Win32 clipboard directly.
+
+const (
+	cfUnicodeText = 13
+	gMemMoveable  = 0x0002
+)
+
+var (
+	user32                         = windows.NewLazySystemDLL("user32.dll")
+	kernel32                       = windows.NewLazySystemDLL("kernel32.dll")
+	procOpenClipboard              = user32.NewProc("OpenClipboard")
+	procCloseClipboard             = user32.NewProc("CloseClipboard")
+	procEmptyClipboard             = user32.NewProc("EmptyClipboard")
+	procGetClipboardData           = user32.NewProc("GetClipboardData")
+	procSetClipboardData           = user32.NewProc("SetClipboardData")
+	procIsClipboardFormatAvailable = user32.NewProc("IsClipboardFormatAvailable")
+	procRegisterClipboardFormatW   = user32.NewProc("RegisterClipboardFormatW")
+	procGlobalAlloc                = kernel32.NewProc("GlobalAlloc")
+	procGlobalLock                 = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock               = kernel32.NewProc("GlobalUnlock")
+	procGlobalSize                 = kernel32.NewProc("GlobalSize")
+	procGlobalFree                 = kernel32.NewProc("GlobalFree")
+
+	clipboardWriteMu sync.Mutex
+)
+
+// Registered clipboard formats for rich text; zero until registered.
+var (
+	richFormatsOnce sync.Once
+	cfHTML, cfRTF   uintptr
+)
+
+// rawOpen opens the clipboard, retrying while another process holds it.
+func rawOpen(maxAttempts int) bool {
+	for i := 0; i < maxAttempts; i++ {
+		if ret, _, _ := procOpenClipboard.Call(0); ret != 0 {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func rawClose() {
+	procCloseClipboard.Call()
+}
+
+// setClipboardBytes copies data into global memory and hands it to the
+// open clipboard, which then owns it.
+func setClipboardBytes(format uintptr, data []byte) error {
+	r, _, _ := procGlobalAlloc.Call(gMemMoveable, uintptr(len(data)))
+	hMem := windows.Handle(r)
+	if hMem == 0 {
+		return fmt.Errorf("GlobalAlloc failed")
+	}
+	ptr, _, _ := procGlobalLock.Call(uintptr(hMem))
+	if ptr == 0 {
+		procGlobalFree.Call(uintptr(hMem))
+		return fmt.Errorf("GlobalLock failed")
+	}
+	basePtr := unsafe.Pointer(ptr)
+	for i, b := range data {
+		*(*byte)(unsafe.Add(basePtr, i)) = b
+	}
+	procGlobalUnlock.Call(uintptr(hMem))
+
+	if r, _, _ := procSetClipboardData.Call(format, uintptr(hMem)); r == 0 {
+		procGlobalFree.Call(uintptr(hMem)) // Still ours: ownership only passes on success
+		return fmt.Errorf("SetClipboardData failed")
+	}
+	return nil
+}
+
+func richFormats() (html, rtf uintptr) {
+	richFormatsOnce.Do(func() {
+		cfHTML = registerFormat("HTML Format")
+		cfRTF = registerFormat("Rich Text Format")
+	})
+	return cfHTML, cfRTF
+}
+
+func registerFormat(name string) uintptr {
+	p, _ := windows.UTF16PtrFromString(name)
+	format, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(p)))
+	return format
+}
+
+// readRich reads "HTML Format" and "Rich Text Format" plus the plain text
+// copied with them, in one clipboard open.
+func readRich() (Rich, bool) {
+	htmlFormat, rtfFormat := richFormats()
+	available := func(format uintptr) bool {
+		ret, _, _ := procIsClipboardFormatAvailable.Call(format)
+		return format != 0 && ret != 0
+	}
+	if !available(htmlFormat) && !available(rtfFormat) {
+		return Rich{}, false // No need to open the clipboard
+	}
+	if !rawOpen(5) {
+		return Rich{}, false
+	}
+	defer rawClose()
+
+	var r Rich
+	if data := clipboardBytes(htmlFormat); len(data) > 0 {
+		r.HTML = richclip.ParseCFHTML(bytes.TrimRight(data, "\x00"))
+	}
+	if data := clipboardBytes(rtfFormat); len(data) > 0 {
+		r.RTF = string(bytes.TrimRight(data, "\x00"))
+	}
+	if data := clipboardBytes(cfUnicodeText); len(data) >= 2 {
+		u16 := unsafe.Slice((*uint16)(unsafe.Pointer(&data[0])), len(data)/2)
+		r.Text = windows.UTF16ToString(u16)
+	}
+	return r, r.HTML != "" || r.RTF != ""
+}
+
+// clipboardBytes copies one format off the open clipboard, capped at
+// richclip.MaxBytes.
+func clipboardBytes(format uintptr) []byte {
+	if format == 0 {
+		return nil
+	}
+	hData, _, _ := procGetClipboardData.Call(format)
+	if hData == 0 {
+		return nil
+	}
+	size, _, _ := procGlobalSize.Call(hData)
+	if size == 0 || size > richclip.MaxBytes {
+		return nil
+	}
+	ptr, _, _ := procGlobalLock.Call(hData)
+	if ptr == 0 {
+		return nil
+	}
+	defer procGlobalUnlock.Call(hData)
+	data := make([]byte, size)
+	basePtr := unsafe.Pointer(ptr)
+	for i := range data {
+		data[i] = *(*byte)(unsafe.Add(basePtr, i))
+	}
+	return data
+}
+
+// writeRich sets HTML (as CF_HTML) or RTF together with CF_UNICODETEXT, so
+// both formatted and plain pastes work.
+func writeRich(format, content, text string) error {
+	htmlFormat, rtfFormat := richFormats()
+	var cf uintptr
+	var data []byte
+	switch format {
+	case FormatHTML:
+		cf, data = htmlFormat, append(richclip.BuildCFHTML(content), 0)
+	case FormatRTF:
+		cf, data = rtfFormat, append([]byte(content), 0)
+	}
+	if cf == 0 {
+		return fmt.Errorf("clipboard format %s not registered", format)
+	}
+
+	clipboardWriteMu.Lock()
+	defer clipboardWriteMu.Unlock()
+	if !rawOpen(20) {
+		return fmt.Errorf("OpenClipboard failed after retries")
+	}
+	defer rawClose()
+	procEmptyClipboard.Call()
+
+	if err := setClipboardBytes(cf, data); err != nil {
+		return err
+	}
+	if text != "" {
+		u16 := utf16.Encode([]rune(text + "\x00"))
+		_ = setClipboardBytes(cfUnicodeText, unsafe.Slice((*byte)(unsafe.Pointer(&u16[0])), 2*len(u16)))
+	}
+	return nil
+}
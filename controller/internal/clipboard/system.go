@@ -0,0 +1,34 @@
+//go:build windows || darwin
+
+package clipboard
+
+import "golang.design/x/clipboard"
+
+// initClipboard prepares golang.design/x/clipboard, which covers text and
+// images natively here.
+func initClipboard() error {
+	return clipboard.Init()
+}
+
+func readText() []byte {
+	return clipboard.Read(clipboard.FmtText)
+}
+
+func readImage() []byte {
+	return clipboard.Read(clipboard.FmtImage)
+}
+
+func writeText(text string) error {
+	clipboard.Write(clipboard.FmtText, []byte(text))
+	return nil
+}
+
+func writeImage(data []byte) error {
+	clipboard.Write(clipboard.FmtImage, data)
+	return nil
+}
+
+// changeCount is not exposed here; the clipboard is read on every poll.
+func changeCount() (uint64, bool) {
+	return 0, false
+}
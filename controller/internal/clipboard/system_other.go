@@ -0,0 +1,115 @@
+//go:build !windows && !darwin
+
+package clipboard
+
+import (
+	"context"
+	_ "image/gif" // Copied images are sent as PNG via convertImageToPNG
+	_ "image/jpeg"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stangtennis/Remote/linuxclip"
+	_ "golang.org/x/image/bmp"
+)
+
+// The session's clipboard, shared by monitor and receiver. On X11 what
+// the controller copies is served from this connection until another
+// application copies, so it is kept open.
+var (
+	selectionMu   sync.Mutex
+	selectionConn linuxclip.Conn
+)
+
+func selection() (linuxclip.Conn, error) {
+	selectionMu.Lock()
+	defer selectionMu.Unlock()
+	if selectionConn != nil {
+		return selectionConn, nil
+	}
+	conn, err := linuxclip.Open()
+	if err != nil {
+		return nil, err
+	}
+	selectionConn = conn
+	return conn, nil
+}
+
+func initClipboard() error {
+	_, err := selection()
+	return err
+}
+
+func readFirst(types []string) []byte {
+	conn, err := selection()
+	if err != nil {
+		return nil
+	}
+	data, _, err := linuxclip.ReadFirst(conn, linuxclip.Clipboard, types)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func readText() []byte {
+	return readFirst(linuxclip.TextTypes)
+}
+
+// readImage returns the first image type on offer, PNG preferred.
+func readImage() []byte {
+	return readFirst(linuxclip.ImageTypes)
+}
+
+// writeText sets CLIPBOARD and PRIMARY, so both Ctrl+V and middle-click
+// paste it.
+func writeText(text string) error {
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	items := []linuxclip.Item{{Type: linuxclip.TextType, Data: []byte(text)}}
+	if err := conn.Write(linuxclip.Clipboard, items); err != nil {
+		return err
+	}
+	return conn.Write(linuxclip.Primary, items)
+}
+
+func writeImage(data []byte) error {
+	conn, err := selection()
+	if err != nil {
+		return err
+	}
+	return conn.Write(linuxclip.Clipboard, []linuxclip.Item{{Type: "image/png", Data: data}})
+}
+
+// Clipboard changes counted by a watch started on first use.
+var (
+	changeWatchOnce sync.Once
+	changes         atomic.Uint64
+	changesWatched  atomic.Bool
+)
+
+// changeCount counts CLIPBOARD changes, so the monitor only reads the
+// clipboard after one.
+func changeCount() (uint64, bool) {
+	changeWatchOnce.Do(func() {
+		conn, err := selection()
+		if err != nil {
+			return
+		}
+		ch, err := conn.Watch(context.Background(), linuxclip.Clipboard)
+		if err != nil {
+			return
+		}
+		changes.Add(1) // Read what is already there
+		changesWatched.Store(true)
+		go func() {
+			for range ch {
+				changes.Add(1)
+			}
+			changesWatched.Store(false)
+		}()
+	})
+	return changes.Load(), changesWatched.Load()
+}
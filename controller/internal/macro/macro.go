@@ -0,0 +1,209 @@
+// Package macro implements recorded input macros.
+//
+// A macro is a list of steps with relative timing. Pointer positions are
+// normalized (0..1 of the remote screen) so a macro recorded on one
+// resolution replays on another. Typed text may reference variables as
+// ${name}; they are substituted when the macro runs, so one recording can
+// be replayed on many machines with different values. Macros are stored as
+// JSON or YAML, picked by file extension.
+package macro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Step actions.
+const (
+	ActionMove       = "move"        // pointer to x, y
+	ActionClick      = "click"       // click (or double) at x, y
+	ActionDown       = "down"        // press button at x, y (drag start)
+	ActionUp         = "up"          // release button at x, y (drag end)
+	ActionType       = "type"        // type text, ${vars} substituted
+	ActionKey        = "key"         // press key (KeyboardEvent.code) with modifiers
+	ActionScroll     = "scroll"      // wheel notches, positive = down/right
+	ActionWaitImage  = "wait_image"  // wait until image appears, optionally click it
+	ActionWaitStable = "wait_stable" // wait until the screen stops changing
+)
+
+// MaxSteps bounds a macro so a runaway recording stays replayable.
+const MaxSteps = 10000
+
+var varRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Macro is a recorded or hand-written input sequence.
+type Macro struct {
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Variables   map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"` // defaults
+	Steps       []Step            `json:"steps" yaml:"steps"`
+}
+
+// Step is one macro action. Only the fields of its action are used.
+type Step struct {
+	DelayMs int    `json:"delay_ms,omitempty" yaml:"delay_ms,omitempty"` // after the previous step
+	Action  string `json:"action" yaml:"action"`
+
+	X      float64 `json:"x,omitempty" yaml:"x,omitempty"` // 0..1 of screen width
+	Y      float64 `json:"y,omitempty" yaml:"y,omitempty"` // 0..1 of screen height
+	Button string  `json:"button,omitempty" yaml:"button,omitempty"`
+	Double bool    `json:"double,omitempty" yaml:"double,omitempty"`
+
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+	Key   string `json:"key,omitempty" yaml:"key,omitempty"`
+	Ctrl  bool   `json:"ctrl,omitempty" yaml:"ctrl,omitempty"`
+	Shift bool   `json:"shift,omitempty" yaml:"shift,omitempty"`
+	Alt   bool   `json:"alt,omitempty" yaml:"alt,omitempty"`
+	Meta  bool   `json:"meta,omitempty" yaml:"meta,omitempty"`
+
+	Delta      int  `json:"delta,omitempty" yaml:"delta,omitempty"`
+	Horizontal bool `json:"horizontal,omitempty" yaml:"horizontal,omitempty"`
+
+	Image     string  `json:"image,omitempty" yaml:"image,omitempty"` // relative to the macro file
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	TimeoutMs int     `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+	Click     bool    `json:"click,omitempty" yaml:"click,omitempty"` // click the found image
+}
+
+// Load reads a macro from a .json, .yaml or .yml file and validates it.
+func Load(path string) (*Macro, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Macro
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &m)
+	} else {
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &m, nil
+}
+
+// Save writes the macro as YAML or JSON depending on the extension.
+func (m *Macro) Save(path string) error {
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(m)
+	} else {
+		data, err = json.MarshalIndent(m, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Validate checks every step for the fields its action needs.
+func (m *Macro) Validate() error {
+	if len(m.Steps) == 0 {
+		return errors.New("macro has no steps")
+	}
+	if len(m.Steps) > MaxSteps {
+		return fmt.Errorf("macro has %d steps (max %d)", len(m.Steps), MaxSteps)
+	}
+	for i, s := range m.Steps {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, s.Action, err)
+		}
+	}
+	return nil
+}
+
+func (s Step) validate() error {
+	if s.DelayMs < 0 {
+		return errors.New("delay_ms is negative")
+	}
+	switch s.Action {
+	case ActionMove, ActionClick, ActionDown, ActionUp:
+		if s.X < 0 || s.X > 1 || s.Y < 0 || s.Y > 1 {
+			return fmt.Errorf("x, y must be between 0 and 1 (got %g, %g)", s.X, s.Y)
+		}
+		switch s.Button {
+		case "", "left", "right", "middle":
+		default:
+			return fmt.Errorf("unknown button %q", s.Button)
+		}
+	case ActionType:
+		if s.Text == "" {
+			return errors.New("text is empty")
+		}
+	case ActionKey:
+		if s.Key == "" {
+			return errors.New("key is empty")
+		}
+	case ActionScroll:
+		if s.Delta == 0 {
+			return errors.New("delta is zero")
+		}
+	case ActionWaitImage:
+		if s.Image == "" {
+			return errors.New("image is empty")
+		}
+		if s.Threshold < 0 || s.Threshold > 1 {
+			return fmt.Errorf("threshold must be between 0 and 1 (got %g)", s.Threshold)
+		}
+	case ActionWaitStable:
+	default:
+		return errors.New("unknown action")
+	}
+	return nil
+}
+
+// Expand returns a copy with ${name} in typed text replaced from vars,
+// falling back to the macro's defaults. A variable without a value is an
+// error rather than being typed literally.
+func (m *Macro) Expand(vars map[string]string) (*Macro, error) {
+	out := *m
+	out.Steps = make([]Step, len(m.Steps))
+	var missing []string
+	for i, s := range m.Steps {
+		if s.Action == ActionType {
+			s.Text = varRe.ReplaceAllStringFunc(s.Text, func(ref string) string {
+				name := varRe.FindStringSubmatch(ref)[1]
+				if v, ok := vars[name]; ok {
+					return v
+				}
+				if v, ok := m.Variables[name]; ok {
+					return v
+				}
+				missing = append(missing, name)
+				return ref
+			})
+		}
+		out.Steps[i] = s
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no value for variable(s): %s (use --var name=value)", strings.Join(missing, ", "))
+	}
+	return &out, nil
+}
+
+// Duration is the total of the step delays in milliseconds.
+func (m *Macro) Duration() int {
+	total := 0
+	for _, s := range m.Steps {
+		total += s.DelayMs
+	}
+	return total
+}
@@ -0,0 +1,156 @@
+package macro
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func events(t *testing.T, raw ...any) []Event {
+	t.Helper()
+	var out []Event
+	for i := 0; i < len(raw); i += 2 {
+		b, err := json.Marshal(raw[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, Event{AtMs: raw[i].(int), Payload: b})
+	}
+	return out
+}
+
+type m = map[string]any
+
+func TestFromEventsClickAndType(t *testing.T) {
+	got := FromEvents(events(t,
+		0, m{"t": "mouse_move", "x": 0.1, "y": 0.1, "rel": true},
+		20, m{"t": "mouse_move", "x": 0.5, "y": 0.5, "rel": true},
+		100, m{"t": "mouse_click", "x": 0.5, "y": 0.5, "button": "left", "down": true},
+		180, m{"t": "mouse_click", "x": 0.5, "y": 0.5, "button": "left", "down": false},
+		1000, m{"t": "key", "code": "ShiftLeft", "key": "Shift", "down": true, "shift": true},
+		1010, m{"t": "key", "code": "KeyH", "key": "H", "char": "H", "down": true, "shift": true},
+		1100, m{"t": "key", "code": "KeyH", "key": "H", "down": false},
+		1200, m{"t": "key", "code": "KeyI", "key": "i", "char": "i", "down": true},
+		1300, m{"t": "key", "code": "Space", "key": " ", "down": true},
+		1400, m{"t": "key", "code": "KeyA", "key": "a", "down": true, "ctrl": true},
+		1500, m{"t": "key", "code": "Enter", "key": "Enter", "down": true},
+	)).Steps
+
+	want := []Step{
+		{DelayMs: 100, Action: ActionClick, X: 0.5, Y: 0.5},
+		{DelayMs: 910, Action: ActionType, Text: "Hi "},
+		{DelayMs: 390, Action: ActionKey, Key: "KeyA", Ctrl: true},
+		{DelayMs: 100, Action: ActionKey, Key: "Enter"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d steps %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFromEventsDoubleClickDragScroll(t *testing.T) {
+	got := FromEvents(events(t,
+		0, m{"t": "mouse_click", "x": 0.2, "y": 0.2, "button": "left", "down": true},
+		50, m{"t": "mouse_click", "x": 0.2, "y": 0.2, "button": "left", "down": false},
+		150, m{"t": "mouse_click", "x": 0.2, "y": 0.2, "button": "left", "down": true},
+		200, m{"t": "mouse_click", "x": 0.2, "y": 0.2, "button": "left", "down": false},
+		1000, m{"t": "mouse_click", "x": 0.3, "y": 0.3, "button": "left", "down": true},
+		1050, m{"t": "mouse_move", "x": 0.35, "y": 0.3},
+		1080, m{"t": "mouse_move", "x": 0.4, "y": 0.3},
+		1200, m{"t": "mouse_move", "x": 0.6, "y": 0.3},
+		1250, m{"t": "mouse_click", "x": 0.6, "y": 0.3, "button": "left", "down": false},
+		2000, m{"t": "mouse_scroll", "delta": -1, "dx": 0, "dy": -120},
+		2050, m{"t": "mouse_scroll", "delta": -1, "dx": 0, "dy": -240},
+	)).Steps
+
+	want := []Step{
+		{DelayMs: 0, Action: ActionClick, X: 0.2, Y: 0.2, Double: true},
+		{DelayMs: 850, Action: ActionDown, X: 0.3, Y: 0.3},
+		{DelayMs: 200, Action: ActionMove, X: 0.6, Y: 0.3},
+		{DelayMs: 50, Action: ActionUp, X: 0.6, Y: 0.3},
+		{DelayMs: 750, Action: ActionScroll, Delta: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d steps %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	mac := &Macro{
+		Variables: map[string]string{"user": "admin"},
+		Steps: []Step{
+			{Action: ActionType, Text: "${user}:${password}"},
+			{Action: ActionKey, Key: "Enter"},
+		},
+	}
+	got, err := mac.Expand(map[string]string{"password": "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Steps[0].Text != "admin:s3cret" {
+		t.Errorf("text = %q", got.Steps[0].Text)
+	}
+	if mac.Steps[0].Text != "${user}:${password}" {
+		t.Errorf("Expand modified the original macro")
+	}
+	if _, err := mac.Expand(nil); err == nil {
+		t.Error("expected error for missing variable")
+	}
+}
+
+func TestLoadSaveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mac := &Macro{
+		Name: "login",
+		Steps: []Step{
+			{Action: ActionWaitImage, Image: "button.png", Threshold: 0.9, TimeoutMs: 5000, Click: true},
+			{DelayMs: 250, Action: ActionType, Text: "${user}"},
+		},
+		Variables: map[string]string{"user": "bob"},
+	}
+	for _, name := range []string{"m.yaml", "m.json"} {
+		path := filepath.Join(dir, name)
+		if err := mac.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.Name != mac.Name || len(got.Steps) != 2 || got.Steps[0] != mac.Steps[0] || got.Steps[1] != mac.Steps[1] {
+			t.Errorf("%s: round trip = %+v", name, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Step{
+		{Action: ActionClick, X: 1.5},
+		{Action: ActionClick, Button: "side"},
+		{Action: ActionType},
+		{Action: ActionScroll},
+		{Action: ActionWaitImage},
+		{Action: "teleport"},
+		{Action: ActionKey, Key: "Enter", DelayMs: -1},
+	}
+	for _, s := range bad {
+		if err := (&Macro{Steps: []Step{s}}).Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", s)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte(`{"steps":[{"action":"type"}]}`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("Load accepted an invalid macro")
+	}
+}
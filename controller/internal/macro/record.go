@@ -0,0 +1,254 @@
+package macro
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Compaction thresholds for recordings.
+const (
+	clickMaxMs      = 600   // down→up within this and in place is a click
+	doubleClickMs   = 400   // two clicks this close become a double click
+	clickSlop       = 0.005 // normalized distance still counted as "in place"
+	dragMoveEveryMs = 100   // keep one move per interval while dragging
+	hoverMs         = 500   // a pointer resting this long keeps its move
+)
+
+// Event is one payload from the controller's outgoing input stream, with the
+// time it was sent in milliseconds since recording started.
+type Event struct {
+	AtMs    int             `json:"at"`
+	Payload json.RawMessage `json:"e"`
+}
+
+// payload is the subset of viewer input messages a recording understands.
+type payload struct {
+	T      string  `json:"t"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Button string  `json:"button"`
+	Down   bool    `json:"down"`
+	DX     int     `json:"dx"`
+	DY     int     `json:"dy"`
+	Delta  int     `json:"delta"`
+	Code   string  `json:"code"`
+	Key    string  `json:"key"`
+	Char   string  `json:"char"`
+	Shift  bool    `json:"shift"`
+	Ctrl   bool    `json:"ctrl"`
+	Alt    bool    `json:"alt"`
+}
+
+// FromEvents turns a raw input recording into a compact macro: button
+// down/up pairs become clicks, runs of printable keys become one type step,
+// and pointer moves are kept only while dragging or when the pointer rests
+// (hover menus). Unknown payloads are skipped.
+func FromEvents(events []Event) *Macro {
+	r := recorder{}
+	for _, ev := range events {
+		var p payload
+		if json.Unmarshal(ev.Payload, &p) != nil {
+			continue
+		}
+		r.add(ev.AtMs, p)
+	}
+	r.flushMove()
+	r.flushText()
+	return &Macro{Steps: r.steps}
+}
+
+type recorder struct {
+	steps  []Step
+	lastAt int // time of the last emitted step
+
+	// Pending pointer move, emitted if the pointer rests on it.
+	move   *payload
+	moveAt int
+
+	// Button held down, waiting to see if it becomes a click or a drag.
+	down     *payload
+	downAt   int
+	dragging bool
+	dragAt   int
+
+	// Typed text being accumulated.
+	text   []rune
+	textAt int
+}
+
+func (r *recorder) emit(at int, s Step) {
+	s.DelayMs = at - r.lastAt
+	if s.DelayMs < 0 {
+		s.DelayMs = 0
+	}
+	r.lastAt = at
+	r.steps = append(r.steps, s)
+}
+
+func (r *recorder) add(at int, p payload) {
+	switch p.T {
+	case "mouse_move":
+		if r.down != nil {
+			if !r.dragging && (at-r.downAt > clickMaxMs || !near(r.down, &p)) {
+				r.dragging = true
+				r.emit(r.downAt, pointer(ActionDown, r.down))
+				r.dragAt = r.downAt
+			}
+			if r.dragging && at-r.dragAt >= dragMoveEveryMs {
+				r.emit(at, pointer(ActionMove, &p))
+				r.dragAt = at
+			}
+			return
+		}
+		if r.move != nil && at-r.moveAt >= hoverMs {
+			r.flushMove()
+		}
+		pp := p
+		r.move, r.moveAt = &pp, at
+	case "mouse_click":
+		r.flushText()
+		if p.Down {
+			if r.move != nil && at-r.moveAt >= hoverMs {
+				r.flushMove()
+			}
+			r.move = nil
+			pp := p
+			r.down, r.downAt, r.dragging = &pp, at, false
+			return
+		}
+		if r.down == nil {
+			return
+		}
+		if r.dragging {
+			r.emit(at, pointer(ActionUp, &p))
+		} else if at-r.downAt <= clickMaxMs && near(r.down, &p) {
+			r.click(r.downAt, r.down)
+		} else {
+			r.emit(r.downAt, pointer(ActionDown, r.down))
+			r.emit(at, pointer(ActionUp, &p))
+		}
+		r.down = nil
+	case "mouse_scroll":
+		r.flushMove()
+		r.flushText()
+		// Viewer deltas are positive up in 120ths of a notch; macros use
+		// CLI notches where positive scrolls down.
+		s := Step{Action: ActionScroll}
+		if p.DX != 0 && p.DY == 0 {
+			s.Horizontal = true
+			s.Delta = notches(p.DX)
+		} else if p.DY != 0 {
+			s.Delta = -notches(p.DY)
+		} else {
+			s.Delta = -p.Delta
+		}
+		if s.Delta == 0 {
+			return
+		}
+		if n := len(r.steps); n > 0 && r.steps[n-1].Action == ActionScroll &&
+			r.steps[n-1].Horizontal == s.Horizontal && at-r.lastAt < hoverMs {
+			r.steps[n-1].Delta += s.Delta
+			r.lastAt = at
+			return
+		}
+		r.emit(at, s)
+	case "key":
+		if !p.Down || isModifier(p.Code) {
+			return
+		}
+		r.flushMove()
+		if ch := typedChar(&p); ch != "" {
+			if len(r.text) == 0 {
+				r.textAt = at
+			}
+			r.text = append(r.text, []rune(ch)...)
+			return
+		}
+		r.flushText()
+		r.emit(at, Step{Action: ActionKey, Key: p.Code, Ctrl: p.Ctrl, Shift: p.Shift, Alt: p.Alt})
+	}
+}
+
+// click emits a click, merging it into the previous one as a double click.
+func (r *recorder) click(at int, p *payload) {
+	if n := len(r.steps); n > 0 {
+		prev := &r.steps[n-1]
+		if prev.Action == ActionClick && !prev.Double && at-r.lastAt <= doubleClickMs &&
+			prev.Button == button(p) && math.Abs(prev.X-p.X) <= clickSlop && math.Abs(prev.Y-p.Y) <= clickSlop {
+			prev.Double = true
+			r.lastAt = at
+			return
+		}
+	}
+	r.emit(at, pointer(ActionClick, p))
+}
+
+func (r *recorder) flushMove() {
+	if r.move != nil {
+		r.emit(r.moveAt, pointer(ActionMove, r.move))
+		r.move = nil
+	}
+}
+
+func (r *recorder) flushText() {
+	if len(r.text) > 0 {
+		r.emit(r.textAt, Step{Action: ActionType, Text: string(r.text)})
+		r.text = nil
+	}
+}
+
+func pointer(action string, p *payload) Step {
+	s := Step{Action: action, X: p.X, Y: p.Y}
+	if action != ActionMove {
+		s.Button = button(p)
+	}
+	return s
+}
+
+func button(p *payload) string {
+	if p.Button == "" || p.Button == "left" {
+		return ""
+	}
+	return p.Button
+}
+
+func near(a, b *payload) bool {
+	return math.Abs(a.X-b.X) <= clickSlop && math.Abs(a.Y-b.Y) <= clickSlop
+}
+
+// notches converts 120ths of a wheel notch, rounding away from zero so a
+// small trackpad flick still scrolls.
+func notches(v int) int {
+	n := v / 120
+	if n == 0 {
+		if v > 0 {
+			return 1
+		}
+		return -1
+	}
+	return n
+}
+
+// typedChar returns the text a key press produces, or "" if it is a
+// shortcut or a non-printing key. Ctrl+Alt is AltGr on Windows layouts.
+func typedChar(p *payload) string {
+	if p.Ctrl && !p.Alt {
+		return ""
+	}
+	if p.Char != "" {
+		return p.Char
+	}
+	if p.Code == "Space" && !p.Ctrl && !p.Alt {
+		return " "
+	}
+	return ""
+}
+
+func isModifier(code string) bool {
+	switch code {
+	case "ShiftLeft", "ShiftRight", "ControlLeft", "ControlRight",
+		"AltLeft", "AltRight", "MetaLeft", "MetaRight", "CapsLock":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package printing
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Print sends a job to printer, or the default printer when printer is
+// empty, with CUPS lp. CUPS converts PDF and PostScript for the printer;
+// raw jobs are passed through unfiltered.
+func Print(job protocol.PrintJob, path, printer string) error {
+	if job.Format == protocol.PrintFormatXPS {
+		return fmt.Errorf("cannot print XPS jobs with CUPS; save the job instead")
+	}
+	args := []string{"-t", job.Title}
+	if printer != "" {
+		args = append(args, "-d", printer)
+	}
+	if job.Format == protocol.PrintFormatRaw {
+		args = append(args, "-o", "raw")
+	}
+	args = append(args, "--", path)
+	out, err := exec.Command("lp", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("lp: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package printing
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Print sends a job to printer, or the default printer when printer is
+// empty, through the print verb of the application registered for the
+// format. That application reads the file after Print returns, so the job
+// is first copied to a folder that is cleared of old jobs.
+func Print(job protocol.PrintJob, path, printer string) error {
+	if job.Format != protocol.PrintFormatPDF && job.Format != protocol.PrintFormatXPS {
+		return fmt.Errorf("cannot print %s jobs on Windows; save the job instead", job.Format)
+	}
+	dir := filepath.Join(os.TempDir(), "RemoteDesktopPrint")
+	clearOld(dir)
+	file, err := Save(dir, job, path)
+	if err != nil {
+		return err
+	}
+
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	script := "Start-Process -FilePath " + quote(file) + " -Verb Print"
+	if printer != "" {
+		script = "Start-Process -FilePath " + quote(file) + " -Verb PrintTo -ArgumentList " + quote(`"`+printer+`"`)
+	}
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("print: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// clearOld removes jobs printed more than an hour ago.
+func clearOld(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > time.Hour {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
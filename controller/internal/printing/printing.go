@@ -0,0 +1,88 @@
+// Package printing delivers print jobs received from an agent's virtual
+// printer: to a local printer, or as a file.
+package printing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Extension returns the file extension for a job format.
+func Extension(format string) string {
+	switch format {
+	case protocol.PrintFormatPDF:
+		return ".pdf"
+	case protocol.PrintFormatPS:
+		return ".ps"
+	case protocol.PrintFormatXPS:
+		return ".xps"
+	}
+	return ".prn"
+}
+
+// Save copies a received job into dir, named after its title, and returns
+// the file's path. Existing files are not overwritten.
+func Save(dir string, job protocol.PrintJob, path string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := fileName(job.Title)
+	ext := Extension(job.Format)
+	for n := 1; ; n++ {
+		name := base + ext
+		if n > 1 {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		dst := filepath.Join(dir, name)
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		err = copyInto(out, path)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+			return "", err
+		}
+		return dst, nil
+	}
+}
+
+func copyInto(out *os.File, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// fileName makes a job title safe to use as a file name.
+func fileName(title string) string {
+	title = strings.TrimSuffix(strings.TrimSpace(title), filepath.Ext(title))
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, title)
+	name = strings.Trim(name, " .")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	if name == "" {
+		return "print-job"
+	}
+	return name
+}
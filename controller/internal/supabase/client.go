@@ -57,6 +57,7 @@ type Device struct {
 	MemoryTotalMB int       `json:"memory_total_mb"`
 	DiskUsedGB    int       `json:"disk_used_gb"`
 	DiskTotalGB   int       `json:"disk_total_gb"`
+	UpdatePin     string    `json:"update_pin"`
 }
 
 // NewClient creates a new Supabase client
@@ -914,7 +915,37 @@ func (c *Client) IsAdmin(userID string) bool {
 	return c.isAdmin(userID)
 }
 
-// ForceUpdateAllDevices sends force_update command to all online devices
+// SetDeviceUpdatePin holds a device on an agent version; an empty version
+// lets it follow the rollout in version.json again.
+func (c *Client) SetDeviceUpdatePin(deviceID, version string) error {
+	c.ensureValidToken()
+	url := fmt.Sprintf("%s/rest/v1/remote_devices?device_id=eq.%s", c.URL, deviceID)
+	var pin interface{}
+	if version != "" {
+		pin = version
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"update_pin": pin})
+	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", c.AnonKey)
+	req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed: %s", string(body))
+	}
+	return nil
+}
+
+// ForceUpdateAllDevices sends force_update command to all online devices.
+// Each agent still only installs the release if its rollout ring allows it.
 func (c *Client) ForceUpdateAllDevices() error {
 	c.ensureValidToken()
 	url := fmt.Sprintf("%s/rest/v1/remote_devices?is_online=eq.true", c.URL)
@@ -178,27 +178,11 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, appType string, cha
 		current = Version{Major: 0, Minor: 0, Patch: 0, Raw: currentVersion}
 	}
 
-	// Fetch version info from Caddy downloads server
-	req, err := http.NewRequest("GET", VersionCheckURL, nil)
+	// Fetch version info from Caddy downloads server; refused unless signed
+	versionInfo, err := c.fetchSignedManifest()
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", c.userAgent)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch version info: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("version check returned status %d", resp.StatusCode)
-	}
-
-	var versionInfo VersionInfo
-	if err := json.NewDecoder(resp.Body).Decode(&versionInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse version info: %w", err)
-	}
 
 	// Get version and URL based on app type
 	var remoteVersionStr, downloadURL, expectedHash string
@@ -244,28 +228,7 @@ func (c *GitHubClient) CheckForUpdate(currentVersion string, appType string, cha
 
 // FetchVersionInfo fetches version info from the update server
 func (c *GitHubClient) FetchVersionInfo() (*VersionInfo, error) {
-	req, err := http.NewRequest("GET", VersionCheckURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", c.userAgent)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch version info: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("version check returned status %d", resp.StatusCode)
-	}
-
-	var versionInfo VersionInfo
-	if err := json.NewDecoder(resp.Body).Decode(&versionInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse version info: %w", err)
-	}
-
-	return &versionInfo, nil
+	return c.fetchSignedManifest()
 }
 
 // DownloadSHA256 downloads and parses a SHA256 checksum file
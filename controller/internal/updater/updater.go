@@ -244,6 +244,14 @@ func (u *Updater) DownloadUpdate() error {
 		return err
 	}
 
+	log.Printf("🔐 Verifying signature...")
+	if err := u.downloader.VerifySignature(exePath, info.ExeURL); err != nil {
+		u.lastError = err
+		u.setStatus(StatusError)
+		os.Remove(exePath)
+		return err
+	}
+
 	// Save state
 	u.state.SetDownloadedVersion(info.TagName, exePath)
 
@@ -13,6 +13,7 @@ import (
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/stangtennis/Remote/protocol"
 )
 
 // Client represents a WebRTC client for the controller
@@ -23,6 +24,7 @@ type Client struct {
 	fileChannel          *webrtc.DataChannel // Reliable channel for file transfer
 	shellChannel         *webrtc.DataChannel // Reliable channel for remote shell exec
 	processChannel       *webrtc.DataChannel // Reliable channel for ps/kill/sysinfo
+	printChannel         *webrtc.DataChannel // Reliable channel for virtual printer jobs
 	videoTrack           *webrtc.TrackRemote
 	onFrame              func([]byte)
 	onH264Frame          func([]byte) // Callback for decoded H.264 frames
@@ -32,9 +34,40 @@ type Client struct {
 	onFileMessage        func([]byte) // Callback for file transfer messages
 	onShellMessage       func([]byte) // Callback for shell channel messages
 	onProcessMessage     func([]byte) // Callback for process/sysinfo channel messages
+	onRestartAnswer      func(restartID, sdp string)
+	onUnsupported        func(msgType, reason string)
 	mu                   sync.Mutex
 	connected            bool
 
+	// Wire protocol handshake (see protocol.go)
+	helloRole    string
+	helloVersion string
+	proto        *protocol.Session
+	protoReady   chan struct{}
+
+	// Last monitor_list from the agent (see monitors.go)
+	monitors *protocol.MonitorList
+
+	// Last keyboard_layout from the agent (see keyboard.go)
+	keyboardLayout *protocol.KeyboardLayout
+
+	// Files last copied on the agent (see clipboard_files.go)
+	clipboardFiles *protocol.ClipboardFiles
+
+	// Clipboard policy the agent reported in force (see clipboard_policy.go)
+	clipboardPolicy *protocol.ClipboardPolicy
+
+	// Local lock state the agent last reported (see local_lock.go)
+	localLockState *protocol.LocalLockState
+
+	// Answer to a Safe Mode restart (see safe_mode.go)
+	restartStatus *protocol.RestartStatus
+
+	// Print forwarding (see printing.go)
+	printHandler   PrintHandler
+	printState     *protocol.PrintState
+	printDownloads map[string]*printDownload
+
 	// Frame reassembly with timeout tracking and backlog limit
 	frameChunks      map[int][][]byte  // frameID -> chunk data
 	frameFirstSeen   map[int]time.Time // frameID -> first chunk arrival time
@@ -59,6 +92,7 @@ func NewClient() (*Client, error) {
 		frameChunks:      make(map[int][][]byte),
 		frameFirstSeen:   make(map[int]time.Time),
 		maxPendingFrames: 100,
+		protoReady:       make(chan struct{}),
 	}, nil
 }
 
@@ -115,6 +149,7 @@ func (c *Client) CreatePeerConnectionWithPolicy(iceServers []webrtc.ICEServer, i
 	}
 
 	c.peerConnection = pc
+	c.printChannel = nil
 
 	// Handle connection state changes
 	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
@@ -230,6 +265,7 @@ func (c *Client) CreateOffer() (string, error) {
 		c.controlChannel = cc
 		cc.OnOpen(func() {
 			log.Println("🎮 Control channel OPENED (ordered, reliable)")
+			c.sendHello(cc)
 		})
 		cc.OnMessage(func(msg webrtc.DataChannelMessage) {
 			c.handleDataChannelMessage(msg.Data)
@@ -424,12 +460,10 @@ func (c *Client) SendData(data []byte) error {
 func (c *Client) SetStreamingMode(mode string, bitrate int) error {
 	log.Printf("🎬 SetStreamingMode called: mode=%s, bitrate=%d", mode, bitrate)
 
-	msg := map[string]interface{}{
-		"type": "set_mode",
-		"mode": mode,
-	}
-	if bitrate > 0 {
-		msg["bitrate"] = float64(bitrate)
+	msg := protocol.SetMode{
+		Type:    protocol.TypeSetMode,
+		Mode:    mode,
+		Bitrate: bitrate,
 	}
 
 	data, err := json.Marshal(msg)
@@ -730,6 +764,35 @@ func (c *Client) handleDataChannelMessage(data []byte) {
 			return
 		}
 
+		// Protocol handshake
+		switch jsonMsg["type"] {
+		case protocol.TypeHello:
+			c.handleHello(data)
+			return
+		case protocol.TypeUnsupported:
+			c.handleUnsupported(data)
+			return
+		}
+
+		// ICE restart answers arriving over the control channel
+		if msgType, ok := jsonMsg["type"].(string); ok && msgType == "ice_restart_answer" {
+			restartID, _ := jsonMsg["restart_id"].(string)
+			sdp, _ := jsonMsg["sdp"].(string)
+			if c.onRestartAnswer != nil && sdp != "" {
+				c.onRestartAnswer(restartID, sdp)
+			}
+			return
+		}
+
+		if msgType, ok := jsonMsg["type"].(string); ok {
+			c.trackMonitors(msgType, data)
+			c.trackKeyboardLayout(msgType, data)
+			c.trackClipboardFiles(msgType, data)
+			c.trackClipboardPolicy(msgType, data)
+			c.trackLocalLock(msgType, data)
+			c.trackRestartStatus(msgType, data)
+		}
+
 		// It's a JSON message (clipboard, file transfer, etc.)
 		if c.onDataChannelMessage != nil {
 			c.onDataChannelMessage(data)
@@ -765,9 +828,9 @@ func (c *Client) StartPingLoop() {
 // SendPing sends a ping message to measure RTT via control channel
 func (c *Client) SendPing() {
 	c.lastPingTime = time.Now()
-	ping := map[string]interface{}{
-		"t":  "ping",
-		"ts": float64(c.lastPingTime.UnixNano()) / 1e6, // ms timestamp
+	ping := protocol.Ping{
+		T:  protocol.TypePing,
+		TS: float64(c.lastPingTime.UnixNano()) / 1e6, // ms timestamp
 	}
 
 	data, err := json.Marshal(ping)
@@ -0,0 +1,53 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// ClipboardFiles returns the files last copied on the agent. ok is false
+// when the agent's clipboard holds no files.
+func (c *Client) ClipboardFiles() (files protocol.ClipboardFiles, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clipboardFiles == nil {
+		return protocol.ClipboardFiles{}, false
+	}
+	return *c.clipboardFiles, true
+}
+
+// SetClipboardFiles asks the agent to put files on its clipboard. The
+// paths must be files uploaded into a "clip_stage" folder; the agent
+// refuses anything else.
+func (c *Client) SetClipboardFiles(msg protocol.ClipboardFiles) error {
+	if err := c.Protocol().Require(protocol.CapClipboardFiles); err != nil {
+		return err
+	}
+	msg.Type = protocol.TypeClipboardFiles
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.SendInput(string(data))
+}
+
+// trackClipboardFiles records clipboard_files. An announcement without
+// files clears the record. The message is still passed on to
+// onDataChannelMessage.
+func (c *Client) trackClipboardFiles(msgType string, data []byte) {
+	if msgType != protocol.TypeClipboardFiles {
+		return
+	}
+	var files protocol.ClipboardFiles
+	if err := json.Unmarshal(data, &files); err != nil {
+		return
+	}
+	c.mu.Lock()
+	if len(files.Files) == 0 && files.Error == "" {
+		c.clipboardFiles = nil
+	} else {
+		c.clipboardFiles = &files
+	}
+	c.mu.Unlock()
+}
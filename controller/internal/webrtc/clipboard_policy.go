@@ -0,0 +1,57 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// ClipboardPolicy returns the clipboard policy the agent last reported in
+// force. ok is false until the agent has reported one.
+func (c *Client) ClipboardPolicy() (policy protocol.ClipboardPolicy, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clipboardPolicy == nil {
+		return protocol.ClipboardPolicy{}, false
+	}
+	return *c.clipboardPolicy, true
+}
+
+// SetClipboardPolicy asks the agent to limit clipboard sync for this
+// session. The agent answers with the policy in force, which a support
+// session may have narrowed further; ClipboardPolicy reports it once it
+// arrives.
+func (c *Client) SetClipboardPolicy(policy protocol.ClipboardPolicy) error {
+	if err := c.Protocol().Require(protocol.CapClipboardPolicy); err != nil {
+		return err
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	policy.Type = protocol.TypeClipboardPolicy
+	policy.Error = ""
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	// Forget the old reply so callers can wait for the answer
+	c.mu.Lock()
+	c.clipboardPolicy = nil
+	c.mu.Unlock()
+	return c.SendInput(string(data))
+}
+
+// trackClipboardPolicy records clipboard_policy replies. The message is
+// still passed on to onDataChannelMessage.
+func (c *Client) trackClipboardPolicy(msgType string, data []byte) {
+	if msgType != protocol.TypeClipboardPolicy {
+		return
+	}
+	var policy protocol.ClipboardPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.clipboardPolicy = &policy
+	c.mu.Unlock()
+}
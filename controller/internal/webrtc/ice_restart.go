@@ -0,0 +1,79 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// CreateRestartOffer renegotiates ICE on the existing peer connection.
+// Data channels, tracks and DTLS state are kept; only the ICE credentials
+// change so the agent gathers fresh candidates for the new network path.
+// Returns the raw SDP of the restart offer.
+func (c *Client) CreateRestartOffer() (string, error) {
+	if c.peerConnection == nil {
+		return "", fmt.Errorf("peer connection not initialized")
+	}
+
+	offer, err := c.peerConnection.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to create restart offer: %w", err)
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(c.peerConnection)
+	if err := c.peerConnection.SetLocalDescription(offer); err != nil {
+		return "", fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	// Same semi-trickle budget as the initial offer
+	select {
+	case <-gatherComplete:
+	case <-time.After(5 * time.Second):
+		log.Println("⚡ ICE restart: proceeding with gathered candidates (5s timeout)")
+	}
+
+	return c.peerConnection.LocalDescription().SDP, nil
+}
+
+// ApplyRestartAnswer sets the agent's answer to a restart offer.
+func (c *Client) ApplyRestartAnswer(sdp string) error {
+	if c.peerConnection == nil {
+		return fmt.Errorf("peer connection not initialized")
+	}
+	if c.peerConnection.SignalingState() != webrtc.SignalingStateHaveLocalOffer {
+		return fmt.Errorf("no restart offer pending (signaling state %s)", c.peerConnection.SignalingState())
+	}
+	answer := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: sdp}
+	if err := c.peerConnection.SetRemoteDescription(answer); err != nil {
+		return fmt.Errorf("failed to set restart answer: %w", err)
+	}
+	return nil
+}
+
+// SendRestartOffer delivers a restart offer over the control channel. This
+// only succeeds while SCTP still has a working path (e.g. the old interface
+// is still up during a handover); callers should also use signaling.
+func (c *Client) SendRestartOffer(restartID, sdp string) error {
+	if c.controlChannel == nil || c.controlChannel.ReadyState() != webrtc.DataChannelStateOpen {
+		return fmt.Errorf("control channel not ready")
+	}
+	data, err := json.Marshal(protocol.ICERestart{
+		Type:      protocol.TypeICERestartOffer,
+		RestartID: restartID,
+		SDP:       sdp,
+	})
+	if err != nil {
+		return err
+	}
+	return c.controlChannel.SendText(string(data))
+}
+
+// SetOnRestartAnswer registers the callback for restart answers received on
+// the control channel.
+func (c *Client) SetOnRestartAnswer(callback func(restartID, sdp string)) {
+	c.onRestartAnswer = callback
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// KeyboardLayout returns the agent's active keyboard layout. ok is false
+// until an agent with CapKeyLayout has reported one.
+func (c *Client) KeyboardLayout() (layout protocol.KeyboardLayout, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keyboardLayout == nil {
+		return protocol.KeyboardLayout{}, false
+	}
+	return *c.keyboardLayout, true
+}
+
+// trackKeyboardLayout records keyboard_layout. The message is still passed
+// on to onDataChannelMessage.
+func (c *Client) trackKeyboardLayout(msgType string, data []byte) {
+	if msgType != protocol.TypeKeyboardLayout {
+		return
+	}
+	var layout protocol.KeyboardLayout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.keyboardLayout = &layout
+	c.mu.Unlock()
+}
@@ -0,0 +1,52 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// LocalLockState returns the local lock state the agent last reported.
+// ok is false until the agent has answered SetLocalLock.
+func (c *Client) LocalLockState() (state protocol.LocalLockState, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.localLockState == nil {
+		return protocol.LocalLockState{}, false
+	}
+	return *c.localLockState, true
+}
+
+// SetLocalLock asks the agent to block (or unblock) its local keyboard and
+// mouse. LocalLockState reports the answer once it arrives, and later how
+// the lock ended when the agent let go of it by itself.
+func (c *Client) SetLocalLock(lock protocol.LocalLock) error {
+	if err := c.Protocol().Require(protocol.CapLocalLock); err != nil {
+		return err
+	}
+	lock.Type = protocol.TypeLocalLock
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	// Forget the old reply so callers can wait for the answer
+	c.mu.Lock()
+	c.localLockState = nil
+	c.mu.Unlock()
+	return c.SendInput(string(data))
+}
+
+// trackLocalLock records local_lock_state messages. The message is still
+// passed on to onDataChannelMessage.
+func (c *Client) trackLocalLock(msgType string, data []byte) {
+	if msgType != protocol.TypeLocalLockState {
+		return
+	}
+	var state protocol.LocalLockState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.localLockState = &state
+	c.mu.Unlock()
+}
@@ -0,0 +1,62 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// Monitors returns the agent's monitor list with the active index kept up
+// to date by monitor_switched. ok is false until the agent has sent one.
+func (c *Client) Monitors() (list protocol.MonitorList, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.monitors == nil {
+		return protocol.MonitorList{}, false
+	}
+	return *c.monitors, true
+}
+
+// SwitchMonitor asks the agent to capture another monitor, or
+// protocol.AllMonitors for one frame spanning every display. The agent
+// confirms with monitor_switched.
+func (c *Client) SwitchMonitor(index int) error {
+	if index == protocol.AllMonitors {
+		if err := c.Protocol().Require(protocol.CapMonitorSpan); err != nil {
+			return err
+		}
+	} else if index < 0 {
+		return fmt.Errorf("invalid monitor index %d", index)
+	}
+	data, err := json.Marshal(protocol.SwitchMonitor{Type: protocol.TypeSwitchMonitor, Index: index})
+	if err != nil {
+		return err
+	}
+	return c.SendInput(string(data))
+}
+
+// trackMonitors records monitor_list and monitor_switched. The messages are
+// still passed on to onDataChannelMessage.
+func (c *Client) trackMonitors(msgType string, data []byte) {
+	switch msgType {
+	case protocol.TypeMonitorList:
+		var list protocol.MonitorList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.monitors = &list
+		c.mu.Unlock()
+	case protocol.TypeMonitorSwitched:
+		var switched protocol.MonitorSwitched
+		if err := json.Unmarshal(data, &switched); err != nil {
+			return
+		}
+		c.mu.Lock()
+		if c.monitors != nil {
+			c.monitors.Active = switched.Index
+		}
+		c.mu.Unlock()
+	}
+}
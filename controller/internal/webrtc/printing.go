@@ -0,0 +1,239 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// PrintHandler delivers a job received from the agent's virtual printer.
+// path is a temporary file holding the job, removed when the handler
+// returns. An error is reported back to the agent.
+type PrintHandler func(job protocol.PrintJob, path string) error
+
+// printDownload is an accepted job being received.
+type printDownload struct {
+	job  protocol.PrintJob
+	file *os.File
+	next int
+}
+
+// EnablePrinting asks the agent to forward jobs from its virtual printer.
+// Every job offered is accepted and handed to handler. The agent answers
+// with its print state; PrintState reports it once it arrives.
+func (c *Client) EnablePrinting(handler PrintHandler) error {
+	if err := c.Protocol().Require(protocol.CapPrint); err != nil {
+		return err
+	}
+	if err := c.openPrintChannel(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.printHandler = handler
+	c.printState = nil
+	c.mu.Unlock()
+	return c.sendPrint(protocol.PrintRequest{Op: protocol.OpPrintEnable})
+}
+
+// DisablePrinting stops job forwarding. Jobs stay in the agent's spool.
+func (c *Client) DisablePrinting() error {
+	if err := c.Protocol().Require(protocol.CapPrint); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.printHandler = nil
+	c.printState = nil
+	c.mu.Unlock()
+	return c.sendPrint(protocol.PrintRequest{Op: protocol.OpPrintDisable})
+}
+
+// PrintState returns the print state the agent last reported. ok is false
+// until it has reported one.
+func (c *Client) PrintState() (state protocol.PrintState, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.printState == nil {
+		return protocol.PrintState{}, false
+	}
+	return *c.printState, true
+}
+
+// openPrintChannel opens the print channel on first use. Unlike the other
+// channels it is not created with the offer: agents from before CapPrint
+// take unknown channels for the frame channel.
+func (c *Client) openPrintChannel() error {
+	if c.printChannel != nil {
+		return nil
+	}
+	if c.peerConnection == nil {
+		return fmt.Errorf("not connected")
+	}
+	ordered := true
+	dc, err := c.peerConnection.CreateDataChannel("print", &webrtc.DataChannelInit{Ordered: &ordered})
+	if err != nil {
+		return fmt.Errorf("create print channel: %w", err)
+	}
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		log.Println("🖨️ Print channel OPENED (reliable, ordered)")
+		close(opened)
+	})
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		c.handlePrintMessage(msg.Data)
+	})
+	select {
+	case <-opened:
+	case <-time.After(10 * time.Second):
+		dc.Close()
+		return fmt.Errorf("print channel did not open")
+	}
+	c.printChannel = dc
+	return nil
+}
+
+func (c *Client) sendPrint(req protocol.PrintRequest) error {
+	if c.printChannel == nil || c.printChannel.ReadyState() != webrtc.DataChannelStateOpen {
+		return fmt.Errorf("print channel not ready")
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.printChannel.Send(data)
+}
+
+// handlePrintMessage handles messages on the print channel. Chunks arrive
+// in order (the channel is ordered), so a job is written straight to disk.
+func (c *Client) handlePrintMessage(data []byte) {
+	var head struct {
+		Op    string `json:"op"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		log.Printf("⚠️ Invalid print message: %v", err)
+		return
+	}
+
+	switch head.Op {
+	case protocol.OpPrintState:
+		var state protocol.PrintState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.printState = &state
+		c.mu.Unlock()
+	case protocol.OpPrintJob:
+		var job protocol.PrintJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return
+		}
+		c.acceptPrintJob(job)
+	case protocol.OpPrintData:
+		var chunk protocol.PrintChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return
+		}
+		c.receivePrintChunk(chunk)
+	case "error":
+		log.Printf("⚠️ Print error (job %s): %s", head.ID, head.Error)
+		c.dropPrintDownload(head.ID)
+	}
+}
+
+// acceptPrintJob accepts an offered job when printing is enabled.
+func (c *Client) acceptPrintJob(job protocol.PrintJob) {
+	c.mu.Lock()
+	enabled := c.printHandler != nil
+	_, busy := c.printDownloads[job.ID]
+	c.mu.Unlock()
+	if !enabled || busy {
+		return
+	}
+
+	f, err := os.CreateTemp("", "remote-print-*")
+	if err != nil {
+		log.Printf("⚠️ Print job %q not accepted: %v", job.Title, err)
+		return
+	}
+	c.mu.Lock()
+	if c.printDownloads == nil {
+		c.printDownloads = make(map[string]*printDownload)
+	}
+	c.printDownloads[job.ID] = &printDownload{job: job, file: f}
+	c.mu.Unlock()
+
+	log.Printf("🖨️ Receiving print job %q (%s, %d bytes)", job.Title, job.Format, job.Size)
+	if err := c.sendPrint(protocol.PrintRequest{Op: protocol.OpPrintAccept, ID: job.ID}); err != nil {
+		c.dropPrintDownload(job.ID)
+	}
+}
+
+func (c *Client) receivePrintChunk(chunk protocol.PrintChunk) {
+	c.mu.Lock()
+	dl := c.printDownloads[chunk.ID]
+	c.mu.Unlock()
+	if dl == nil {
+		return
+	}
+	if chunk.C != dl.next {
+		c.finishPrintJob(dl, fmt.Errorf("chunk %d arrived out of order", chunk.C))
+		return
+	}
+	if _, err := dl.file.Write(chunk.Data); err != nil {
+		c.finishPrintJob(dl, err)
+		return
+	}
+	dl.next++
+	if dl.next == chunk.T {
+		c.finishPrintJob(dl, nil)
+	}
+}
+
+// finishPrintJob hands a received job to the handler and tells the agent
+// the outcome, which also lets it drop the job.
+func (c *Client) finishPrintJob(dl *printDownload, err error) {
+	c.mu.Lock()
+	delete(c.printDownloads, dl.job.ID)
+	handler := c.printHandler
+	c.mu.Unlock()
+
+	path := dl.file.Name()
+	if cerr := dl.file.Close(); err == nil {
+		err = cerr
+	}
+	go func() {
+		defer os.Remove(path)
+		if err == nil && handler == nil {
+			err = fmt.Errorf("printing was disabled")
+		}
+		if err == nil {
+			err = handler(dl.job, path)
+		}
+		done := protocol.PrintRequest{Op: protocol.OpPrintDone, ID: dl.job.ID}
+		if err != nil {
+			log.Printf("⚠️ Print job %q failed: %v", dl.job.Title, err)
+			done.Error = err.Error()
+		} else {
+			log.Printf("🖨️ Print job %q delivered", dl.job.Title)
+		}
+		_ = c.sendPrint(done)
+	}()
+}
+
+func (c *Client) dropPrintDownload(id string) {
+	c.mu.Lock()
+	dl := c.printDownloads[id]
+	delete(c.printDownloads, id)
+	c.mu.Unlock()
+	if dl != nil {
+		dl.file.Close()
+		os.Remove(dl.file.Name())
+	}
+}
@@ -0,0 +1,144 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// controllerCapabilities lists the protocol features the controller can use.
+var controllerCapabilities = []string{
+	protocol.CapInput,
+	protocol.CapInputChar,
+	protocol.CapInputWheel,
+	protocol.CapInputTouch,
+	protocol.CapInputPen,
+	protocol.CapKeyLayout,
+	protocol.CapClipboardText,
+	protocol.CapClipboardImage,
+	protocol.CapClipboardFiles,
+	protocol.CapClipboardRich,
+	protocol.CapClipboardPolicy,
+	protocol.CapH264,
+	protocol.CapStreamParams,
+	protocol.CapStreamPause,
+	protocol.CapMonitors,
+	protocol.CapMonitorSpan,
+	protocol.CapRemoteLogin,
+	protocol.CapForceUpdate,
+	protocol.CapSafeModeRestart,
+	protocol.CapICERestart,
+	protocol.CapFiles,
+	protocol.CapShell,
+	protocol.CapScripts,
+	protocol.CapProcess,
+	protocol.CapStats,
+	protocol.CapRegistry,
+	protocol.CapServices,
+	protocol.CapLogs,
+	protocol.CapPrint,
+	protocol.CapLocalLock,
+}
+
+// SetProtocolIdentity sets the role and version announced in hello.
+// Call before CreateOffer.
+func (c *Client) SetProtocolIdentity(role, appVersion string) {
+	c.mu.Lock()
+	c.helloRole = role
+	c.helloVersion = appVersion
+	c.mu.Unlock()
+}
+
+// SetOnUnsupported registers a callback for "unsupported" replies, sent by
+// the agent for message types it does not handle.
+func (c *Client) SetOnUnsupported(callback func(msgType, reason string)) {
+	c.onUnsupported = callback
+}
+
+// localHello builds the hello sent when the control channel opens.
+func (c *Client) localHello() protocol.Hello {
+	c.mu.Lock()
+	role, appVersion := c.helloRole, c.helloVersion
+	c.mu.Unlock()
+	if role == "" {
+		role = protocol.RoleController
+	}
+	return protocol.NewHello(role, appVersion, runtime.GOOS, controllerCapabilities)
+}
+
+// sendHello opens the handshake on the control channel.
+func (c *Client) sendHello(dc *webrtc.DataChannel) {
+	data, err := json.Marshal(c.localHello())
+	if err != nil {
+		return
+	}
+	if err := dc.SendText(string(data)); err != nil {
+		log.Printf("⚠️ Failed to send hello: %v", err)
+	}
+}
+
+// handleHello records the agent's answer to our hello.
+func (c *Client) handleHello(data []byte) {
+	var peer protocol.Hello
+	if err := json.Unmarshal(data, &peer); err != nil {
+		log.Printf("⚠️ Invalid hello from agent: %v", err)
+		return
+	}
+	session, err := protocol.Negotiate(c.localHello(), peer)
+	if err != nil {
+		log.Printf("⚠️ %v — using basic feature set", err)
+	}
+	log.Printf("🤝 Protocol v%d with agent %s (%d shared capabilities)",
+		session.Version, peer.AppVersion, len(session.Capabilities()))
+	c.setProtocol(session)
+}
+
+// setProtocol stores the session once; later calls are ignored so a late
+// hello cannot change capabilities under a running command.
+func (c *Client) setProtocol(s protocol.Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.proto != nil {
+		return
+	}
+	c.proto = &s
+	close(c.protoReady)
+}
+
+// Protocol returns the negotiated session, or the legacy session if the
+// agent has not answered hello (yet).
+func (c *Client) Protocol() protocol.Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.proto != nil {
+		return *c.proto
+	}
+	return protocol.LegacySession()
+}
+
+// WaitProtocol waits up to timeout for the agent's hello. An agent that
+// stays silent predates the handshake and is settled as legacy.
+func (c *Client) WaitProtocol(timeout time.Duration) protocol.Session {
+	select {
+	case <-c.protoReady:
+	case <-time.After(timeout):
+		c.setProtocol(protocol.LegacySession())
+	}
+	return c.Protocol()
+}
+
+// handleUnsupported reports an "unsupported" reply from the agent.
+func (c *Client) handleUnsupported(data []byte) {
+	var msg protocol.Unsupported
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	log.Printf("⚠️ Agent does not support %q: %s", msg.MsgType, msg.Reason)
+	if c.onUnsupported != nil {
+		c.onUnsupported(msg.MsgType, msg.Reason)
+	}
+}
@@ -0,0 +1,52 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/stangtennis/Remote/protocol"
+)
+
+// RestartStatus returns the agent's answer to RestartSafeMode. ok is false
+// until it arrives.
+func (c *Client) RestartStatus() (status protocol.RestartStatus, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.restartStatus == nil {
+		return protocol.RestartStatus{}, false
+	}
+	return *c.restartStatus, true
+}
+
+// RestartSafeMode asks the agent to restart its device into Safe Mode with
+// Networking after delaySec (0 = protocol.RestartSafeModeDefaultDelay).
+// The connection drops with the restart; the agent comes back online in
+// Safe Mode, and its next restart is a normal one.
+func (c *Client) RestartSafeMode(delaySec int) error {
+	if err := c.Protocol().Require(protocol.CapSafeModeRestart); err != nil {
+		return err
+	}
+	data, err := json.Marshal(protocol.RestartSafeMode{Type: protocol.TypeRestartSafeMode, DelaySec: delaySec})
+	if err != nil {
+		return err
+	}
+	// Forget the old reply so callers can wait for the answer
+	c.mu.Lock()
+	c.restartStatus = nil
+	c.mu.Unlock()
+	return c.SendInput(string(data))
+}
+
+// trackRestartStatus records restart_status replies. The message is still
+// passed on to onDataChannelMessage.
+func (c *Client) trackRestartStatus(msgType string, data []byte) {
+	if msgType != protocol.TypeRestartStatus {
+		return
+	}
+	var status protocol.RestartStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.restartStatus = &status
+	c.mu.Unlock()
+}
@@ -336,3 +336,72 @@ func (s *SignalingClient) GetSupportSignals(sessionID string) ([]SupportSignal,
 	}
 	return signals, nil
 }
+
+// SendRestartOffer posts an ICE restart offer for an established session.
+// The agent only polls for these while its connection is disconnected.
+func (s *SignalingClient) SendRestartOffer(sessionID, restartID, sdp string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"session_id": sessionID,
+		"from_side":  "dashboard",
+		"msg_type":   "restart_offer",
+		"payload": map[string]string{
+			"restart_id": restartID,
+			"sdp":        sdp,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.supabaseURL+"/rest/v1/session_signaling", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", s.anonKey)
+	req.Header.Set("Authorization", "Bearer "+s.authToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=minimal")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("restart offer failed (%d): %s", resp.StatusCode, string(data))
+	}
+	return nil
+}
+
+// GetRestartAnswer returns the agent's answer SDP for restartID, or "" if the
+// agent has not answered yet.
+func (s *SignalingClient) GetRestartAnswer(sessionID, restartID string) (string, error) {
+	url := fmt.Sprintf("%s/rest/v1/session_signaling?session_id=eq.%s&from_side=eq.agent&msg_type=eq.restart_answer&order=created_at.desc&limit=10", s.supabaseURL, sessionID)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("apikey", s.anonKey)
+	req.Header.Set("Authorization", "Bearer "+s.authToken)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("restart answer read failed (%d): %s", resp.StatusCode, string(data))
+	}
+	var signals []SupportSignal
+	if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil {
+		return "", err
+	}
+	for _, signal := range signals {
+		if id, _ := signal.Payload["restart_id"].(string); id != restartID {
+			continue
+		}
+		if sdp, _ := signal.Payload["sdp"].(string); sdp != "" {
+			return sdp, nil
+		}
+	}
+	return "", nil
+}
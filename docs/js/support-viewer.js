@@ -28,6 +28,14 @@ async function createSupportSession() {
     const supportMode = modeSelect?.value === 'screen' ? 'screen' : 'ai';
     const requestedScopes = [...document.querySelectorAll('[data-support-scope]:checked')]
       .map((input) => input.dataset.supportScope);
+    // Enforced by the agent for the whole session; the admin's viewer can
+    // only narrow it further.
+    const clipboardPolicy = {
+      direction: document.getElementById('supportClipboardDirection')?.value || 'both',
+      max_bytes: parseInt(document.getElementById('supportClipboardMax')?.value || '0', 10),
+      formats: [...document.querySelectorAll('[data-clipboard-format]:checked')]
+        .map((input) => input.dataset.clipboardFormat),
+    };
 
     const response = await fetch(`${SUPABASE_CONFIG.url}/functions/v1/create-support-session`, {
       method: 'POST',
@@ -38,6 +46,7 @@ async function createSupportSession() {
       body: JSON.stringify({
         support_mode: supportMode,
         requested_scopes: supportMode === 'ai' ? requestedScopes : ['screen'],
+        clipboard_policy: supportMode === 'ai' ? clipboardPolicy : undefined,
       }),
     });
 
@@ -1208,22 +1208,29 @@ function setupInputCapture() {
     document.body.appendChild(mobileInput);
 
     // Capture input and send as keystrokes
-    mobileInput.addEventListener('input', (e) => {
+    const sendMobileText = (text) => {
       const dc = getActiveDataChannel();
       if (!dc || dc.readyState !== 'open') return;
-      const text = e.data || '';
       for (const char of text) {
-        sendControlEvent({ t: 'key', key: char, down: true });
+        sendControlEvent({ t: 'key', key: char, char, down: true });
         sendControlEvent({ t: 'key', key: char, down: false });
       }
       mobileInput.value = '';
+    };
+    mobileInput.addEventListener('input', (e) => {
+      // While an IME composes, wait for the final text on compositionend
+      if (e.isComposing) return;
+      sendMobileText(e.data || '');
+    });
+    mobileInput.addEventListener('compositionend', (e) => {
+      sendMobileText(e.data || '');
     });
     mobileInput.addEventListener('keydown', (e) => {
       const dc = getActiveDataChannel();
       if (!dc || dc.readyState !== 'open') return;
       if (e.key === 'Backspace' || e.key === 'Enter' || e.key === 'Tab' || e.key === 'Escape') {
-        sendControlEvent({ t: 'key', key: e.key, down: true });
-        sendControlEvent({ t: 'key', key: e.key, down: false });
+        sendControlEvent({ t: 'key', code: e.key, key: e.key, down: true });
+        sendControlEvent({ t: 'key', code: e.key, key: e.key, down: false });
         e.preventDefault();
       }
     });
@@ -1259,11 +1266,19 @@ function setupInputCapture() {
     const dc = getActiveDataChannel();
     if (!dc || dc.readyState !== 'open') return;
 
+    // dx/dy are high-resolution wheel units (120 = one notch, positive dy =
+    // up); delta is kept for agents that only understand whole notches
+    const scale = e.deltaMode === 1 ? 40 : e.deltaMode === 2 ? 360 : 1.2;
+    const dx = Math.round(e.deltaX * scale);
+    const dy = Math.round(-e.deltaY * scale);
+    e.preventDefault();
+    if (dx === 0 && dy === 0) return;
     sendControlEvent({
       t: 'mouse_scroll',
-      delta: e.deltaY > 0 ? -1 : 1
+      delta: Math.sign(dy),
+      dx,
+      dy
     }, { priority: true });
-    e.preventDefault();
   };
   target.addEventListener('wheel', wheelHandler);
   inputEventHandlers.wheel = wheelHandler;
@@ -1293,6 +1308,8 @@ function setupInputCapture() {
     if (shouldIgnoreRemoteInputEvent(e)) return;
     const dc = getActiveDataChannel();
     if (!dc || dc.readyState !== 'open') return;
+    // IME keystrokes are composed locally; the text follows on compositionend
+    if (e.isComposing || e.keyCode === 229) return;
 
     if (e.ctrlKey && e.code === 'KeyV') {
       // First sync the local clipboard to the remote PC (writes to its
@@ -1310,6 +1327,7 @@ function setupInputCapture() {
     const evt = {
       t: 'key',
       code: e.code,
+      key: e.key,
       down: true,
       ctrl: e.ctrlKey,
       shift: e.shiftKey,
@@ -1333,11 +1351,13 @@ function setupInputCapture() {
     const dc = getActiveDataChannel();
     if (!dc || dc.readyState !== 'open') return;
 
+    if (e.isComposing) return;
     pressedKeys.delete(e.code);
 
     sendControlEvent({
       t: 'key',
       code: e.code,
+      key: e.key,
       down: false
     }, { priority: true });
     e.preventDefault();
@@ -1955,14 +1975,22 @@ document.addEventListener('DOMContentLoaded', () => {
 // the user's next interaction with the dashboard makes the clipboard
 // land. This also handles the "copy on remote, immediately Alt-Tab to
 // local app to paste" timing where the write would otherwise race.
-let _pendingClipboard = null; // { kind: 'text'|'image', content: string }
+let _pendingClipboard = null; // { kind: 'text'|'image'|'html', content: string, text?: string }
 
-async function _tryWriteClipboard(kind, content) {
+async function _tryWriteClipboard(kind, content, text) {
   try {
     if (kind === 'text') {
       await navigator.clipboard.writeText(content);
       return true;
     }
+    if (kind === 'html') {
+      // Keep the plain-text alternative so pastes into plain editors work
+      await navigator.clipboard.write([new ClipboardItem({
+        'text/html': new Blob([content], { type: 'text/html' }),
+        'text/plain': new Blob([text || ''], { type: 'text/plain' }),
+      })]);
+      return true;
+    }
     if (kind === 'image') {
       const binary = atob(content);
       const bytes = new Uint8Array(binary.length);
@@ -1977,8 +2005,8 @@ async function _tryWriteClipboard(kind, content) {
   return false;
 }
 
-async function _writeClipboardOrQueue(kind, content) {
-  const ok = await _tryWriteClipboard(kind, content);
+async function _writeClipboardOrQueue(kind, content, text) {
+  const ok = await _tryWriteClipboard(kind, content, text);
   if (ok) {
     debug('📋 Clipboard from agent written (' + kind + ', ' + content.length + ' bytes)');
     _pendingClipboard = null;
@@ -1987,15 +2015,15 @@ async function _writeClipboardOrQueue(kind, content) {
   // Could not write right now — usually because document.hasFocus() is
   // false. Queue the content; the listeners below flush on next focus /
   // user gesture and the show-toast indicator tells the user it's there.
-  _pendingClipboard = { kind, content };
+  _pendingClipboard = { kind, content, text };
   debug('📋 Clipboard queued (waiting for focus); ' + kind + ' ' + content.length + ' bytes');
   showToast('Clipboard fra remote venter — klik på dashboard én gang så vi kan skrive den', 'info');
 }
 
 async function _flushPendingClipboard() {
   if (!_pendingClipboard) return;
-  const { kind, content } = _pendingClipboard;
-  if (await _tryWriteClipboard(kind, content)) {
+  const { kind, content, text } = _pendingClipboard;
+  if (await _tryWriteClipboard(kind, content, text)) {
     debug('📋 Pending clipboard flushed on focus/gesture (' + kind + ')');
     _pendingClipboard = null;
   }
@@ -2050,6 +2078,14 @@ function handleAgentMessage(msg) {
         _writeClipboardOrQueue('image', msg.content);
       }
       break;
+
+    // Formatted copies arrive after their clipboard_text. Browsers can't
+    // write RTF, so clipboard_rtf is covered by that text alone.
+    case 'clipboard_html':
+      if (msg.content) {
+        _writeClipboardOrQueue('html', msg.content, msg.text);
+      }
+      break;
   }
 }
 
@@ -2066,6 +2102,7 @@ async function sendClipboardToAgent() {
         content: text
       });
       debug('📋 Clipboard sent to agent (text:', text.length, 'bytes)');
+      await sendClipboardHTML(text);
       return;
     }
   } catch (e) {
@@ -2092,6 +2129,26 @@ async function sendClipboardToAgent() {
   }
 }
 
+// Follow the text with its HTML rendering, if the copy was formatted.
+// Agents that predate clipboard_html ignore it and keep the text.
+async function sendClipboardHTML(text) {
+  try {
+    const items = await navigator.clipboard.read();
+    for (const item of items) {
+      if (item.types.includes('text/html')) {
+        const html = await (await item.getType('text/html')).text();
+        if (html) {
+          sendControlEvent({ type: 'clipboard_html', content: html, text });
+          debug('📋 Clipboard sent to agent (html:', html.length, 'bytes)');
+        }
+        return;
+      }
+    }
+  } catch (e) {
+    // Reading HTML can need a permission the text read didn't; the text alone was sent
+  }
+}
+
 // ==================== MULTI-MONITOR ====================
 
 function handleMonitorList(msg) {
@@ -2121,6 +2178,15 @@ function handleMonitorList(msg) {
     select.appendChild(opt);
   });
 
+  // Agent can composite all monitors into one frame (index -1)
+  if (msg.span && monitors.length > 1) {
+    const opt = document.createElement('option');
+    opt.value = -1;
+    opt.textContent = 'Alle skærme';
+    if (active === -1) opt.selected = true;
+    select.appendChild(opt);
+  }
+
   // Show/hide selector based on monitor count
   const container = document.getElementById('monitorSelectContainer');
   if (container) {
@@ -2157,7 +2223,8 @@ function handleMonitorSwitched(msg) {
   const select = document.getElementById('monitorSelect');
   if (select) select.value = index;
 
-  showToast(`Skiftet til monitor ${index + 1} (${width}x${height})`, 'success');
+  const label = index === -1 ? 'alle skærme' : `monitor ${index + 1}`;
+  showToast(`Skiftet til ${label} (${width}x${height})`, 'success');
 }
 
 // ==================== FILE CHANNEL ====================
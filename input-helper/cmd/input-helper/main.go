@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/stangtennis/Remote/protocol"
 )
 
 const (
@@ -45,57 +46,6 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// InputEvent represents an input event from the browser
-type InputEvent struct {
-	Type      string  `json:"type"`
-	T         string  `json:"t,omitempty"` // alias from dashboard shorthand
-	Seq       int64   `json:"seq,omitempty"`
-	Timestamp int64   `json:"ts,omitempty"`
-
-	// Auth
-	Token     string `json:"token,omitempty"`
-	DeviceID  string `json:"device_id,omitempty"`
-	SessionID string `json:"session_id,omitempty"`
-
-	// Mouse move (relative)
-	DX int `json:"dx,omitempty"`
-	DY int `json:"dy,omitempty"`
-
-	// Mouse move (absolute, normalized 0-1)
-	X float64 `json:"x,omitempty"`
-	Y float64 `json:"y,omitempty"`
-
-	// Mouse button
-	Button string `json:"button,omitempty"`
-	Down   bool   `json:"down,omitempty"`
-
-	// Key
-	Code  string `json:"code,omitempty"`
-	Key   string `json:"key,omitempty"`
-	Char  string `json:"char,omitempty"` // Unicode character to type
-	Ctrl  bool   `json:"ctrl,omitempty"`
-	Shift bool   `json:"shift,omitempty"`
-	Alt   bool   `json:"alt,omitempty"`
-	Meta  bool   `json:"meta,omitempty"`
-
-	// Clipboard
-	Direction string `json:"direction,omitempty"`
-	Content   string `json:"content,omitempty"`
-
-	// Control
-	Action string `json:"action,omitempty"`
-	Scope  string `json:"scope,omitempty"`
-}
-
-// Response sent back to browser
-type Response struct {
-	Type    string `json:"type"`
-	Seq     int64  `json:"seq,omitempty"`
-	OK      bool   `json:"ok"`
-	Error   string `json:"error,omitempty"`
-	Content string `json:"content,omitempty"`
-}
-
 // Session state
 type Session struct {
 	authenticated bool
@@ -158,13 +108,12 @@ func (h *InputHelper) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 
-		var event InputEvent
-		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("Invalid JSON: %v", err)
+		if !json.Valid(message) {
+			log.Printf("Invalid JSON (%d bytes)", len(message))
 			continue
 		}
 
-		response := h.handleEvent(conn, session, &event)
+		response := h.handleEvent(session, message)
 		if response != nil {
 			responseJSON, _ := json.Marshal(response)
 			conn.WriteMessage(websocket.TextMessage, responseJSON)
@@ -174,139 +123,163 @@ func (h *InputHelper) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔌 WebSocket connection closed")
 }
 
-func (h *InputHelper) handleEvent(conn *websocket.Conn, session *Session, event *InputEvent) *Response {
+// seqOf returns the sequence number of a raw message, for acks.
+func seqOf(message []byte) int64 {
+	var hdr struct {
+		Seq int64 `json:"seq"`
+	}
+	_ = json.Unmarshal(message, &hdr)
+	return hdr.Seq
+}
+
+func ack(seq int64, errMsg string) *protocol.HelperResponse {
+	return &protocol.HelperResponse{Type: protocol.TypeHelperAck, Seq: seq, OK: errMsg == "", Error: errMsg}
+}
+
+func (h *InputHelper) handleEvent(session *Session, message []byte) *protocol.HelperResponse {
 	session.mu.Lock()
 	session.lastActivity = time.Now()
+	authenticated := session.authenticated
+	mouseEnabled := session.mouseEnabled
+	keyEnabled := session.keyEnabled
+	clipEnabled := session.clipEnabled
 	session.mu.Unlock()
 
-	// Accept "t" as alias for "type" (dashboard shorthand)
-	if event.Type == "" && event.T != "" {
-		event.Type = event.T
+	// MsgType accepts "t" as an alias for "type" (dashboard shorthand)
+	msgType := protocol.MsgType(message)
+	seq := seqOf(message)
+	if msgType == protocol.TypeHelperAuth {
+		var msg protocol.HelperAuth
+		if err := protocol.Decode(message, &msg); err != nil {
+			return ack(seq, "invalid auth message")
+		}
+		return h.handleAuth(session, msg)
 	}
-
-	switch event.Type {
-	case "auth":
-		return h.handleAuth(session, event)
-
-	case "mouse_move":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
+	if !authenticated {
+		switch msgType {
+		case protocol.TypeMouseMove, protocol.TypeHelperMouseAbs, protocol.TypeHelperMouseButton,
+			protocol.TypeHelperWheel, protocol.TypeKey, protocol.TypeHelperClipboard, protocol.TypeHelperControl:
+			return ack(seq, "not authenticated")
 		}
-		if !session.mouseEnabled {
+	}
+
+	switch msgType {
+	case protocol.TypeMouseMove:
+		var msg protocol.HelperMouseMove
+		if !mouseEnabled || protocol.Decode(message, &msg) != nil {
 			return nil // Silently ignore
 		}
-		h.injector.MouseMoveRelative(event.DX, event.DY)
+		h.injector.MouseMoveRelative(msg.DX, msg.DY)
 		return nil // Don't ack every mouse move (too noisy)
 
-	case "mouse_abs":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
-		}
-		if !session.mouseEnabled {
+	case protocol.TypeHelperMouseAbs:
+		var msg protocol.HelperMouseAbs
+		if !mouseEnabled || protocol.Decode(message, &msg) != nil {
 			return nil
 		}
-		h.injector.MouseMoveAbsolute(event.X, event.Y)
+		h.injector.MouseMoveAbsolute(msg.X, msg.Y)
 		return nil
 
-	case "mouse_button":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
-		}
-		if !session.mouseEnabled {
+	case protocol.TypeHelperMouseButton:
+		var msg protocol.HelperMouseButton
+		if !mouseEnabled {
 			return nil
 		}
-		h.injector.MouseButton(event.Button, event.Down)
-		return &Response{Type: "ack", Seq: event.Seq, OK: true}
-
-	case "wheel":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
+		if err := protocol.Decode(message, &msg); err != nil {
+			return ack(seq, "invalid mouse_button message")
 		}
-		if !session.mouseEnabled {
+		h.injector.MouseButton(msg.Button, msg.Down)
+		return ack(seq, "")
+
+	case protocol.TypeHelperWheel:
+		var msg protocol.HelperWheel
+		if !mouseEnabled || protocol.Decode(message, &msg) != nil {
 			return nil
 		}
-		h.injector.MouseWheel(event.DX, event.DY)
+		h.injector.MouseWheel(msg.DX, msg.DY)
 		return nil
 
-	case "key":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
+	case protocol.TypeKey:
+		if !keyEnabled {
+			return ack(seq, "keyboard not enabled")
 		}
-		if !session.keyEnabled {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "keyboard not enabled"}
+		var msg protocol.HelperKey
+		if err := protocol.Decode(message, &msg); err != nil {
+			return ack(seq, "invalid key message")
 		}
 		// If char is provided, use Unicode input (handles æøå, @, #, emoji, AltGr, etc.)
-		if event.Char != "" {
-			runes := []rune(event.Char)
-			for _, r := range runes {
+		if msg.Char != "" {
+			for _, r := range msg.Char {
 				h.injector.TypeUnicode(uint16(r))
 			}
-		} else if event.Code != "" {
-			h.injector.KeyEvent(event.Code, event.Down, event.Ctrl, event.Shift, event.Alt, event.Meta)
+		} else if msg.Code != "" {
+			h.injector.KeyEvent(msg.Code, msg.Down, msg.Ctrl, msg.Shift, msg.Alt, msg.Meta)
 		}
-		return &Response{Type: "ack", Seq: event.Seq, OK: true}
+		return ack(seq, "")
 
-	case "clipboard":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
+	case protocol.TypeHelperClipboard:
+		if !clipEnabled {
+			return ack(seq, "clipboard not enabled")
 		}
-		if !session.clipEnabled {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "clipboard not enabled"}
+		var msg protocol.HelperClipboard
+		if err := protocol.Decode(message, &msg); err != nil {
+			return ack(seq, "invalid clipboard message")
 		}
-		if event.Direction == "to_system" {
-			h.injector.SetClipboard(event.Content)
-			return &Response{Type: "ack", Seq: event.Seq, OK: true}
-		} else if event.Direction == "from_system" {
+		if msg.Direction == "to_system" {
+			h.injector.SetClipboard(msg.Content)
+			return ack(seq, "")
+		} else if msg.Direction == "from_system" {
 			content := h.injector.GetClipboard()
-			return &Response{Type: "clipboard_content", Seq: event.Seq, OK: true, Content: content}
+			return &protocol.HelperResponse{Type: protocol.TypeHelperClipboardContent, Seq: seq, OK: true, Content: content}
 		}
 
-	case "control":
-		if !session.authenticated {
-			return &Response{Type: "ack", Seq: event.Seq, OK: false, Error: "not authenticated"}
+	case protocol.TypeHelperControl:
+		var msg protocol.HelperControl
+		if err := protocol.Decode(message, &msg); err != nil {
+			return ack(seq, "invalid control message")
 		}
-		return h.handleControl(session, event)
+		return h.handleControl(session, msg)
 
 	default:
-		log.Printf("Unknown event type: %s", event.Type)
+		log.Printf("Unknown event type: %s", msgType)
 	}
 
 	return nil
 }
 
-func (h *InputHelper) handleAuth(session *Session, event *InputEvent) *Response {
+func (h *InputHelper) handleAuth(session *Session, msg protocol.HelperAuth) *protocol.HelperResponse {
 	// Validate required fields and minimum token length
-	if event.Token == "" || event.DeviceID == "" {
-		return &Response{Type: "ack", OK: false, Error: "missing token or device_id"}
+	if msg.Token == "" || msg.DeviceID == "" {
+		return ack(0, "missing token or device_id")
 	}
-	if len(event.Token) < 8 {
-		return &Response{Type: "ack", OK: false, Error: "invalid token"}
+	if len(msg.Token) < 8 {
+		return ack(0, "invalid token")
 	}
-	if event.SessionID == "" {
-		return &Response{Type: "ack", OK: false, Error: "missing session_id"}
+	if msg.SessionID == "" {
+		return ack(0, "missing session_id")
 	}
 
 	session.mu.Lock()
 	session.authenticated = true
-	session.deviceID = event.DeviceID
-	session.sessionID = event.SessionID
+	session.deviceID = msg.DeviceID
+	session.sessionID = msg.SessionID
 	session.mu.Unlock()
 
-	log.Printf("✅ Authenticated: device=%s session=%s", event.DeviceID, event.SessionID)
+	log.Printf("✅ Authenticated: device=%s session=%s", msg.DeviceID, msg.SessionID)
 
-	return &Response{
-		Type: "status",
+	return &protocol.HelperResponse{
+		Type: protocol.TypeHelperStatus,
 		OK:   true,
 	}
 }
 
-func (h *InputHelper) handleControl(session *Session, event *InputEvent) *Response {
+func (h *InputHelper) handleControl(session *Session, msg protocol.HelperControl) *protocol.HelperResponse {
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
-	switch event.Action {
+	switch msg.Action {
 	case "enable":
-		switch event.Scope {
+		switch msg.Scope {
 		case "mouse":
 			session.mouseEnabled = true
 		case "keyboard":
@@ -318,10 +291,10 @@ func (h *InputHelper) handleControl(session *Session, event *InputEvent) *Respon
 			session.keyEnabled = true
 			session.clipEnabled = true
 		}
-		log.Printf("🎮 Enabled: %s", event.Scope)
+		log.Printf("🎮 Enabled: %s", msg.Scope)
 
 	case "disable":
-		switch event.Scope {
+		switch msg.Scope {
 		case "mouse":
 			session.mouseEnabled = false
 		case "keyboard":
@@ -333,7 +306,7 @@ func (h *InputHelper) handleControl(session *Session, event *InputEvent) *Respon
 			session.keyEnabled = false
 			session.clipEnabled = false
 		}
-		log.Printf("🎮 Disabled: %s", event.Scope)
+		log.Printf("🎮 Disabled: %s", msg.Scope)
 
 	case "pause":
 		session.mouseEnabled = false
@@ -347,7 +320,7 @@ func (h *InputHelper) handleControl(session *Session, event *InputEvent) *Respon
 		log.Printf("▶️ Resumed mouse input")
 	}
 
-	return &Response{Type: "ack", Seq: event.Seq, OK: true}
+	return ack(msg.Seq, "")
 }
 
 func (h *InputHelper) handleStatus(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,5 @@
+module github.com/stangtennis/Remote/linuxclip
+
+go 1.24.0
+
+require github.com/jezek/xgb v1.1.1
@@ -0,0 +1,104 @@
+// Package linuxclip reads and writes the Linux desktop clipboard without
+// cgo: X11 selections through the X protocol itself, and Wayland through
+// wl-clipboard, which speaks the data-control protocols compositors offer
+// to clipboard managers.
+//
+// Both CLIPBOARD (Ctrl+C / Ctrl+V) and PRIMARY (select / middle-click) are
+// supported. Content is addressed by MIME type; the X11 names for plain
+// text (UTF8_STRING, STRING, TEXT) are served and read as TextType.
+package linuxclip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Selection names a clipboard.
+type Selection string
+
+const (
+	// Clipboard is the explicit copy/paste clipboard.
+	Clipboard Selection = "CLIPBOARD"
+	// Primary holds the current mouse selection, pasted with the middle
+	// button.
+	Primary Selection = "PRIMARY"
+)
+
+// TextType is the MIME type plain text is offered and read as.
+const TextType = "text/plain;charset=utf-8"
+
+// MaxBytes caps a single read.
+const MaxBytes = 64 * 1024 * 1024
+
+// Types to try, in order, for each kind of content.
+var (
+	TextTypes  = []string{TextType, "UTF8_STRING", "text/plain", "STRING", "TEXT"}
+	ImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/bmp", "image/x-bmp"}
+)
+
+// ErrNoContent is returned when the selection has no owner or the owner
+// does not offer the requested type.
+var ErrNoContent = errors.New("no such clipboard content")
+
+// Item is one representation of the clipboard content.
+type Item struct {
+	Type string
+	Data []byte
+}
+
+// Conn is a connection to the desktop clipboard.
+type Conn interface {
+	// Targets lists the types the selection's owner offers.
+	Targets(sel Selection) ([]string, error)
+	// Read returns the selection's content as the given type.
+	Read(sel Selection, mimeType string) ([]byte, error)
+	// Write takes ownership of the selection, offering every item, and
+	// serves it until another client takes over. Items are in order of
+	// preference; where only one type can be offered it is the first.
+	Write(sel Selection, items []Item) error
+	// Watch signals each change of the selection's owner or content until
+	// ctx is done.
+	Watch(ctx context.Context, sel Selection) (<-chan struct{}, error)
+	Close() error
+}
+
+// Open connects to the session's clipboard: Wayland when WAYLAND_DISPLAY
+// is set and wl-clipboard is installed, X11 (including XWayland) otherwise.
+func Open() (Conn, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if c, err := openWayland(); err == nil {
+			return c, nil
+		}
+	}
+	if os.Getenv("DISPLAY") == "" {
+		return nil, fmt.Errorf("no X11 or Wayland display")
+	}
+	return OpenX11("")
+}
+
+// ReadFirst reads the first of types the owner offers and returns it with
+// the type it was read as.
+func ReadFirst(c Conn, sel Selection, types []string) ([]byte, string, error) {
+	targets, err := c.Targets(sel)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, t := range types {
+		if slices.Contains(targets, t) {
+			data, err := c.Read(sel, t)
+			return data, t, err
+		}
+	}
+	return nil, "", ErrNoContent
+}
+
+// textAliases returns the names a type is offered under.
+func textAliases(mimeType string) []string {
+	if mimeType == TextType {
+		return TextTypes
+	}
+	return []string{mimeType}
+}
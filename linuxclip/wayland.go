@@ -0,0 +1,105 @@
+package linuxclip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// wayland runs wl-paste and wl-copy. Wayland only lets the focused client
+// touch the clipboard, unless the compositor offers a data-control
+// protocol, which wl-clipboard uses when available. wl-copy offers a single
+// type, so Write offers only its first item.
+type wayland struct{}
+
+func openWayland() (Conn, error) {
+	for _, tool := range []string{"wl-paste", "wl-copy"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("wl-clipboard not installed: %w", err)
+		}
+	}
+	return wayland{}, nil
+}
+
+// selectionArgs prepends --primary for the PRIMARY selection.
+func selectionArgs(sel Selection, args ...string) []string {
+	if sel == Primary {
+		return append([]string{"--primary"}, args...)
+	}
+	return args
+}
+
+// paste runs wl-paste. It exits non-zero when nothing is copied or the
+// type is not offered.
+func paste(sel Selection, args ...string) ([]byte, error) {
+	out, err := exec.Command("wl-paste", selectionArgs(sel, args...)...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, ErrNoContent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxBytes {
+		return nil, fmt.Errorf("selection larger than %d bytes", MaxBytes)
+	}
+	return out, nil
+}
+
+func (wayland) Targets(sel Selection) ([]string, error) {
+	out, err := paste(sel, "--list-types")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (wayland) Read(sel Selection, mimeType string) ([]byte, error) {
+	return paste(sel, "--no-newline", "--type", mimeType)
+}
+
+func (wayland) Write(sel Selection, items []Item) error {
+	if len(items) == 0 {
+		return fmt.Errorf("nothing to write")
+	}
+	cmd := exec.Command("wl-copy", selectionArgs(sel, "--type", items[0].Type)...)
+	cmd.Stdin = bytes.NewReader(items[0].Data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("wl-copy: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Watch runs "wl-paste --watch", which starts a command on every change;
+// echo turns each into a line.
+func (wayland) Watch(ctx context.Context, sel Selection) (<-chan struct{}, error) {
+	cmd := exec.CommandContext(ctx, "wl-paste", selectionArgs(sel, "--watch", "echo")...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("wl-paste --watch: %w", err)
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		_ = cmd.Wait()
+	}()
+	return ch, nil
+}
+
+func (wayland) Close() error {
+	return nil
+}
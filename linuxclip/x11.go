@@ -0,0 +1,542 @@
+package linuxclip
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xfixes"
+	"github.com/jezek/xgb/xproto"
+)
+
+// readTimeout bounds how long a selection owner gets to answer.
+const readTimeout = 2 * time.Second
+
+// x11 owns a hidden window that requests selections and, after Write,
+// serves them. Large transfers in both directions use the ICCCM INCR
+// protocol.
+type x11 struct {
+	conn  *xgb.Conn
+	win   xproto.Window
+	prop  xproto.Atom // property on win that selections are converted into
+	chunk int         // largest property written in one request
+	fixes bool        // XFixes selection events are available
+
+	atomMu sync.Mutex
+	atoms  map[string]xproto.Atom
+	names  map[xproto.Atom]string
+
+	readMu  sync.Mutex // one conversion into prop at a time
+	replies chan xproto.SelectionNotifyEvent
+	values  chan struct{} // prop got a new value
+
+	mu       sync.Mutex
+	owned    map[xproto.Atom][]Item
+	sends    map[incrKey]*incrSend
+	watchers map[xproto.Atom][]chan struct{}
+	watched  map[xproto.Atom]bool
+}
+
+// incrKey identifies an outgoing INCR transfer.
+type incrKey struct {
+	win  xproto.Window
+	prop xproto.Atom
+}
+
+type incrSend struct {
+	typ  xproto.Atom
+	data []byte
+}
+
+// OpenX11 connects to an X server; display "" means $DISPLAY.
+func OpenX11(display string) (Conn, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("connect to X server: %w", err)
+	}
+	c := &x11{
+		conn:     conn,
+		atoms:    make(map[string]xproto.Atom),
+		names:    make(map[xproto.Atom]string),
+		replies:  make(chan xproto.SelectionNotifyEvent, 1),
+		values:   make(chan struct{}, 1),
+		owned:    make(map[xproto.Atom][]Item),
+		sends:    make(map[incrKey]*incrSend),
+		watchers: make(map[xproto.Atom][]chan struct{}),
+		watched:  make(map[xproto.Atom]bool),
+	}
+
+	setup := xproto.Setup(conn)
+	c.chunk = min(int(setup.MaximumRequestLength)*4-1024, 256*1024)
+
+	wid, err := xproto.NewWindowId(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.win = wid
+	err = xproto.CreateWindowChecked(conn, 0, wid, setup.DefaultScreen(conn).Root,
+		0, 0, 1, 1, 0, xproto.WindowClassInputOnly, 0,
+		xproto.CwEventMask, []uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create selection window: %w", err)
+	}
+	if c.prop, err = c.atom("REMOTE_DESKTOP_SELECTION"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if xfixes.Init(conn) == nil {
+		if _, err := xfixes.QueryVersion(conn, 5, 0).Reply(); err == nil {
+			c.fixes = true
+		}
+	}
+
+	go c.eventLoop()
+	return c, nil
+}
+
+func (c *x11) Close() error {
+	xproto.DestroyWindow(c.conn, c.win)
+	c.conn.Close()
+	return nil
+}
+
+// atom interns a name, caching both directions.
+func (c *x11) atom(name string) (xproto.Atom, error) {
+	c.atomMu.Lock()
+	a, ok := c.atoms[name]
+	c.atomMu.Unlock()
+	if ok {
+		return a, nil
+	}
+	reply, err := xproto.InternAtom(c.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("intern %s: %w", name, err)
+	}
+	c.atomMu.Lock()
+	c.atoms[name] = reply.Atom
+	c.names[reply.Atom] = name
+	c.atomMu.Unlock()
+	return reply.Atom, nil
+}
+
+func (c *x11) atomName(a xproto.Atom) (string, error) {
+	c.atomMu.Lock()
+	name, ok := c.names[a]
+	c.atomMu.Unlock()
+	if ok {
+		return name, nil
+	}
+	reply, err := xproto.GetAtomName(c.conn, a).Reply()
+	if err != nil {
+		return "", err
+	}
+	c.atomMu.Lock()
+	c.atoms[reply.Name] = a
+	c.names[a] = reply.Name
+	c.atomMu.Unlock()
+	return reply.Name, nil
+}
+
+func (c *x11) eventLoop() {
+	for {
+		ev, err := c.conn.WaitForEvent()
+		if ev == nil && err == nil {
+			c.closeWatchers()
+			return
+		}
+		switch e := ev.(type) {
+		case xproto.SelectionNotifyEvent:
+			select {
+			case c.replies <- e:
+			default:
+			}
+		case xproto.PropertyNotifyEvent:
+			if e.Window == c.win {
+				if e.Atom == c.prop && e.State == xproto.PropertyNewValue {
+					select {
+					case c.values <- struct{}{}:
+					default:
+					}
+				}
+			} else if e.State == xproto.PropertyDelete {
+				c.continueSend(incrKey{e.Window, e.Atom})
+			}
+		case xproto.SelectionRequestEvent:
+			c.serve(e)
+		case xproto.SelectionClearEvent:
+			c.mu.Lock()
+			delete(c.owned, e.Selection)
+			c.mu.Unlock()
+		case xfixes.SelectionNotifyEvent:
+			c.notify(e.Selection)
+		}
+	}
+}
+
+// ownedItems returns what this connection serves for a selection.
+func (c *x11) ownedItems(sel xproto.Atom) []Item {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.owned[sel]
+}
+
+func (c *x11) Targets(sel Selection) ([]string, error) {
+	selAtom, err := c.atom(string(sel))
+	if err != nil {
+		return nil, err
+	}
+	if items := c.ownedItems(selAtom); items != nil {
+		var targets []string
+		for _, it := range items {
+			targets = append(targets, textAliases(it.Type)...)
+		}
+		return targets, nil
+	}
+	data, err := c.convert(selAtom, "TARGETS")
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for i := 0; i+4 <= len(data); i += 4 {
+		if name, err := c.atomName(xproto.Atom(xgb.Get32(data[i:]))); err == nil {
+			targets = append(targets, name)
+		}
+	}
+	return targets, nil
+}
+
+func (c *x11) Read(sel Selection, mimeType string) ([]byte, error) {
+	selAtom, err := c.atom(string(sel))
+	if err != nil {
+		return nil, err
+	}
+	if items := c.ownedItems(selAtom); items != nil {
+		for _, it := range items {
+			if slices.Contains(textAliases(it.Type), mimeType) {
+				return it.Data, nil
+			}
+		}
+		return nil, ErrNoContent
+	}
+	return c.convert(selAtom, mimeType)
+}
+
+// convert asks the selection owner for a target and collects the answer,
+// following INCR when the owner sends it in pieces.
+func (c *x11) convert(sel xproto.Atom, target string) ([]byte, error) {
+	targetAtom, err := c.atom(target)
+	if err != nil {
+		return nil, err
+	}
+	incr, err := c.atom("INCR")
+	if err != nil {
+		return nil, err
+	}
+
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	select {
+	case <-c.replies:
+	default:
+	}
+	xproto.DeleteProperty(c.conn, c.win, c.prop)
+	xproto.ConvertSelection(c.conn, c.win, sel, targetAtom, c.prop, xproto.TimeCurrentTime)
+
+	var reply xproto.SelectionNotifyEvent
+	select {
+	case reply = <-c.replies:
+	case <-time.After(readTimeout):
+		return nil, fmt.Errorf("selection owner did not answer for %s", target)
+	}
+	if reply.Property == xproto.AtomNone {
+		return nil, ErrNoContent
+	}
+
+	prop, err := c.takeProperty()
+	if err != nil {
+		return nil, err
+	}
+	if prop.Type != incr {
+		return prop.Value, nil
+	}
+
+	// INCR: each deletion of the property asks the owner for the next
+	// chunk, and an empty chunk ends the transfer. A notification whose
+	// property is already gone belongs to a chunk taken earlier.
+	var data []byte
+	for {
+		select {
+		case <-c.values:
+		case <-time.After(readTimeout):
+			return nil, fmt.Errorf("selection owner stalled sending %s", target)
+		}
+		chunk, err := c.takeProperty()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Type == xproto.AtomNone {
+			continue
+		}
+		if len(chunk.Value) == 0 {
+			return data, nil
+		}
+		if len(data)+len(chunk.Value) > MaxBytes {
+			return nil, fmt.Errorf("%s larger than %d bytes", target, MaxBytes)
+		}
+		data = append(data, chunk.Value...)
+	}
+}
+
+// takeProperty reads and deletes the conversion property.
+func (c *x11) takeProperty() (*xproto.GetPropertyReply, error) {
+	reply, err := xproto.GetProperty(c.conn, true, c.win, c.prop,
+		xproto.GetPropertyTypeAny, 0, MaxBytes/4).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if reply.BytesAfter > 0 {
+		return nil, fmt.Errorf("selection larger than %d bytes", MaxBytes)
+	}
+	// Value is padded to whole units of Format bits.
+	if n := int(reply.ValueLen) * int(reply.Format) / 8; n < len(reply.Value) {
+		reply.Value = reply.Value[:n]
+	}
+	return reply, nil
+}
+
+func (c *x11) Write(sel Selection, items []Item) error {
+	if len(items) == 0 {
+		return fmt.Errorf("nothing to write")
+	}
+	selAtom, err := c.atom(string(sel))
+	if err != nil {
+		return err
+	}
+	// Intern up front so serving requests never waits on the server.
+	for _, it := range items {
+		for _, name := range textAliases(it.Type) {
+			if _, err := c.atom(name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range []string{"TARGETS", "INCR", "ATOM"} {
+		if _, err := c.atom(name); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.owned[selAtom] = items
+	c.mu.Unlock()
+	xproto.SetSelectionOwner(c.conn, c.win, selAtom, xproto.TimeCurrentTime)
+	owner, err := xproto.GetSelectionOwner(c.conn, selAtom).Reply()
+	if err != nil {
+		return err
+	}
+	if owner.Owner != c.win {
+		c.mu.Lock()
+		delete(c.owned, selAtom)
+		c.mu.Unlock()
+		return fmt.Errorf("could not take ownership of %s", sel)
+	}
+	return nil
+}
+
+// serve answers another client's request for a selection this connection
+// owns.
+func (c *x11) serve(e xproto.SelectionRequestEvent) {
+	prop := e.Property
+	if prop == xproto.AtomNone {
+		prop = e.Target // Obsolete clients
+	}
+	if !c.answer(e.Requestor, e.Selection, e.Target, prop) {
+		prop = xproto.AtomNone
+	}
+	notify := xproto.SelectionNotifyEvent{
+		Time:      e.Time,
+		Requestor: e.Requestor,
+		Selection: e.Selection,
+		Target:    e.Target,
+		Property:  prop,
+	}
+	xproto.SendEvent(c.conn, false, e.Requestor, xproto.EventMaskNoEvent, string(notify.Bytes()))
+}
+
+// answer writes the requested target into the requestor's property.
+func (c *x11) answer(requestor xproto.Window, sel, target, prop xproto.Atom) bool {
+	items := c.ownedItems(sel)
+	if items == nil {
+		return false
+	}
+	if target == c.cached("TARGETS") {
+		list := []xproto.Atom{target}
+		for _, it := range items {
+			for _, name := range textAliases(it.Type) {
+				list = append(list, c.cached(name))
+			}
+		}
+		buf := make([]byte, 4*len(list))
+		for i, a := range list {
+			xgb.Put32(buf[4*i:], uint32(a))
+		}
+		xproto.ChangeProperty(c.conn, xproto.PropModeReplace, requestor, prop, c.cached("ATOM"), 32, uint32(len(list)), buf)
+		return true
+	}
+
+	data, found := []byte(nil), false
+	for _, it := range items {
+		for _, name := range textAliases(it.Type) {
+			if !found && c.cached(name) == target {
+				data, found = it.Data, true
+			}
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if len(data) > c.chunk {
+		c.mu.Lock()
+		c.sends[incrKey{requestor, prop}] = &incrSend{typ: target, data: data}
+		c.mu.Unlock()
+		xproto.ChangeWindowAttributes(c.conn, requestor, xproto.CwEventMask, []uint32{xproto.EventMaskPropertyChange})
+		size := make([]byte, 4)
+		xgb.Put32(size, uint32(len(data)))
+		xproto.ChangeProperty(c.conn, xproto.PropModeReplace, requestor, prop, c.cached("INCR"), 32, 1, size)
+		return true
+	}
+	xproto.ChangeProperty(c.conn, xproto.PropModeReplace, requestor, prop, target, 8, uint32(len(data)), data)
+	return true
+}
+
+// cached returns an atom Write interned beforehand.
+func (c *x11) cached(name string) xproto.Atom {
+	c.atomMu.Lock()
+	defer c.atomMu.Unlock()
+	return c.atoms[name]
+}
+
+// continueSend writes the next INCR chunk once the requestor deleted the
+// previous one, ending with an empty chunk.
+func (c *x11) continueSend(key incrKey) {
+	c.mu.Lock()
+	send, ok := c.sends[key]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	n := min(len(send.data), c.chunk)
+	chunk := send.data[:n]
+	send.data = send.data[n:]
+	idle := false
+	if n == 0 {
+		delete(c.sends, key)
+		idle = true
+		for k := range c.sends {
+			if k.win == key.win {
+				idle = false
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	xproto.ChangeProperty(c.conn, xproto.PropModeReplace, key.win, key.prop, send.typ, 8, uint32(n), chunk)
+	if idle {
+		xproto.ChangeWindowAttributes(c.conn, key.win, xproto.CwEventMask, []uint32{xproto.EventMaskNoEvent})
+	}
+}
+
+func (c *x11) Watch(ctx context.Context, sel Selection) (<-chan struct{}, error) {
+	selAtom, err := c.atom(string(sel))
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{}, 1)
+	if !c.fixes {
+		go c.pollOwner(ctx, selAtom, ch)
+		return ch, nil
+	}
+
+	c.mu.Lock()
+	c.watchers[selAtom] = append(c.watchers[selAtom], ch)
+	first := !c.watched[selAtom]
+	c.watched[selAtom] = true
+	c.mu.Unlock()
+	if first {
+		xfixes.SelectSelectionInput(c.conn, c.win, selAtom,
+			xfixes.SelectionEventMaskSetSelectionOwner|
+				xfixes.SelectionEventMaskSelectionWindowDestroy|
+				xfixes.SelectionEventMaskSelectionClientClose)
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		list := c.watchers[selAtom]
+		for i, w := range list {
+			if w == ch {
+				c.watchers[selAtom] = append(list[:i:i], list[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// pollOwner stands in for XFixes on servers without it. It only sees a
+// new owner, not an owner that changed its content.
+func (c *x11) pollOwner(ctx context.Context, sel xproto.Atom, ch chan struct{}) {
+	defer close(ch)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	var last xproto.Window
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reply, err := xproto.GetSelectionOwner(c.conn, sel).Reply()
+		if err != nil {
+			return
+		}
+		if reply.Owner != last {
+			last = reply.Owner
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (c *x11) notify(sel xproto.Atom) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.watchers[sel] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// closeWatchers ends every watch when the connection goes away.
+func (c *x11) closeWatchers() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sel, list := range c.watchers {
+		for _, ch := range list {
+			close(ch)
+		}
+		delete(c.watchers, sel)
+	}
+}
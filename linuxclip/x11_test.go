@@ -0,0 +1,173 @@
+package linuxclip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testDisplay starts a private Xvfb, or falls back to $DISPLAY (as under
+// xvfb-run). Tests skip when neither is there.
+func testDisplay(t *testing.T) string {
+	t.Helper()
+	xvfb, err := exec.LookPath("Xvfb")
+	if err != nil {
+		if d := os.Getenv("DISPLAY"); d != "" {
+			return d
+		}
+		t.Skip("needs Xvfb or an X display")
+	}
+	for n := 90; n < 110; n++ {
+		if _, err := os.Stat(fmt.Sprintf("/tmp/.X11-unix/X%d", n)); err == nil {
+			continue
+		}
+		display := fmt.Sprintf(":%d", n)
+		cmd := exec.Command(xvfb, display, "-nolisten", "tcp", "-screen", "0", "640x480x24")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start Xvfb: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		for i := 0; i < 50; i++ {
+			if _, err := os.Stat(fmt.Sprintf("/tmp/.X11-unix/X%d", n)); err == nil {
+				return display
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("Xvfb did not come up on %s", display)
+	}
+	t.Skip("no free X display number")
+	return ""
+}
+
+// openPair returns two clients of one X server: an owner and a reader.
+func openPair(t *testing.T) (Conn, Conn) {
+	t.Helper()
+	display := testDisplay(t)
+	var conns []Conn
+	for range 2 {
+		c, err := OpenX11(display)
+		if err != nil {
+			t.Fatalf("OpenX11(%s): %v", display, err)
+		}
+		t.Cleanup(func() { c.Close() })
+		conns = append(conns, c)
+	}
+	return conns[0], conns[1]
+}
+
+func TestX11TextAndHTML(t *testing.T) {
+	owner, reader := openPair(t)
+	err := owner.Write(Clipboard, []Item{
+		{Type: "text/html", Data: []byte("<b>hello</b>")},
+		{Type: TextType, Data: []byte("hello")},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	targets, err := reader.Targets(Clipboard)
+	if err != nil {
+		t.Fatalf("Targets: %v", err)
+	}
+	for _, want := range []string{"TARGETS", "text/html", "UTF8_STRING", TextType} {
+		if !slices.Contains(targets, want) {
+			t.Errorf("targets %v lack %s", targets, want)
+		}
+	}
+	text, typ, err := ReadFirst(reader, Clipboard, TextTypes)
+	if err != nil || string(text) != "hello" || typ != TextType {
+		t.Errorf("text = %q as %s, %v", text, typ, err)
+	}
+	if data, err := reader.Read(Clipboard, "UTF8_STRING"); err != nil || string(data) != "hello" {
+		t.Errorf("UTF8_STRING = %q, %v", data, err)
+	}
+	if data, err := reader.Read(Clipboard, "text/html"); err != nil || string(data) != "<b>hello</b>" {
+		t.Errorf("html = %q, %v", data, err)
+	}
+	if _, err := reader.Read(Clipboard, "image/png"); !errors.Is(err, ErrNoContent) {
+		t.Errorf("image/png error = %v, want ErrNoContent", err)
+	}
+}
+
+func TestX11PrimaryIsSeparate(t *testing.T) {
+	owner, reader := openPair(t)
+	if err := owner.Write(Primary, []Item{{Type: TextType, Data: []byte("selected")}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if data, err := reader.Read(Primary, TextType); err != nil || string(data) != "selected" {
+		t.Errorf("primary = %q, %v", data, err)
+	}
+	if data, err := reader.Read(Clipboard, TextType); err == nil {
+		t.Errorf("clipboard = %q, want nothing", data)
+	}
+}
+
+func TestX11LargeTransfer(t *testing.T) {
+	owner, reader := openPair(t)
+	image := make([]byte, 3*1024*1024+17) // Well past one request: sent with INCR
+	for i := range image {
+		image[i] = byte(i * 7)
+	}
+	if err := owner.Write(Clipboard, []Item{{Type: "image/png", Data: image}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, typ, err := ReadFirst(reader, Clipboard, ImageTypes)
+	if err != nil {
+		t.Fatalf("ReadFirst: %v", err)
+	}
+	if typ != "image/png" || !bytes.Equal(data, image) {
+		t.Errorf("read %d bytes as %s, want %d bytes image/png", len(data), typ, len(image))
+	}
+}
+
+func TestX11OwnershipMoves(t *testing.T) {
+	a, b := openPair(t)
+	if err := a.Write(Clipboard, []Item{{Type: TextType, Data: []byte("first")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Write(Clipboard, []Item{{Type: TextType, Data: []byte("second")}}); err != nil {
+		t.Fatal(err)
+	}
+	// a learns of the new owner through SelectionClear.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := a.Read(Clipboard, TextType)
+		if err == nil && string(data) == "second" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("a reads %q, %v after b took the clipboard", data, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestX11Watch(t *testing.T) {
+	owner, watcher := openPair(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := watcher.Watch(ctx, Clipboard)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if err := owner.Write(Clipboard, []Item{{Type: TextType, Data: []byte("x")}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change reported")
+	}
+	cancel()
+	for range changes {
+	}
+}
@@ -2,7 +2,10 @@ module remote-desktop-control
 
 go 1.21
 
-require github.com/go-vgo/robotgo v0.110.1
+require (
+	github.com/go-vgo/robotgo v0.110.1
+	github.com/stangtennis/Remote/protocol v0.0.0
+)
 
 require (
 	github.com/gen2brain/shm v0.0.0-20230802011745-f2460f5984f7 // indirect
@@ -27,3 +30,5 @@ require (
 	golang.org/x/image v0.12.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
 )
+
+replace github.com/stangtennis/Remote/protocol => ../protocol
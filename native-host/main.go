@@ -8,20 +8,16 @@ import (
 	"os"
 
 	"github.com/go-vgo/robotgo"
+	"github.com/stangtennis/Remote/protocol"
 )
 
 // Message represents a message from/to the extension
-type Message struct {
-	Type    string                 `json:"type"`
-	Command map[string]interface{} `json:"command,omitempty"`
-	Status  string                 `json:"status,omitempty"`
-	Error   string                 `json:"error,omitempty"`
-}
+type Message = protocol.NativeMessage
 
 func main() {
 	// Send initial connected message
 	sendMessage(Message{
-		Type:   "connected",
+		Type:   protocol.TypeNativeConnected,
 		Status: "ready",
 	})
 
@@ -89,11 +85,16 @@ func sendMessage(msg Message) error {
 // Handle incoming message
 func handleMessage(msg Message) {
 	switch msg.Type {
-	case "ping":
-		sendMessage(Message{Type: "pong"})
-
-	case "input":
-		handleInput(msg.Command)
+	case protocol.TypeNativePing:
+		sendMessage(Message{Type: protocol.TypeNativePong})
+
+	case protocol.TypeNativeInput:
+		var cmd protocol.NativeInput
+		if err := json.Unmarshal(msg.Command, &cmd); err != nil {
+			sendError("Invalid input command")
+			return
+		}
+		handleInput(cmd)
 
 	default:
 		logError("Unknown message type: %s", msg.Type)
@@ -101,78 +102,70 @@ func handleMessage(msg Message) {
 }
 
 // Handle input command
-func handleInput(cmd map[string]interface{}) {
-	inputType, ok := cmd["type"].(string)
-	if !ok {
+func handleInput(cmd protocol.NativeInput) {
+	if cmd.Type == "" {
 		sendError("Invalid input type")
 		return
 	}
 
-	switch inputType {
-	case "mouse_move":
+	switch cmd.Type {
+	case protocol.TypeNativeMouseMove:
 		handleMouseMove(cmd)
-	case "mouse_click":
+	case protocol.TypeNativeMouseClick:
 		handleMouseClick(cmd)
-	case "mouse_down":
+	case protocol.TypeNativeMouseDown:
 		handleMouseButton(cmd, true)
-	case "mouse_up":
+	case protocol.TypeNativeMouseUp:
 		handleMouseButton(cmd, false)
-	case "mouse_scroll":
+	case protocol.TypeNativeMouseScroll:
 		handleMouseScroll(cmd)
-	case "keyboard_press":
+	case protocol.TypeNativeKeyboardPress:
 		handleKeyboardPress(cmd)
-	case "keyboard_type":
+	case protocol.TypeNativeKeyboardType:
 		handleKeyboardType(cmd)
 	default:
-		sendError(fmt.Sprintf("Unknown input type: %s", inputType))
+		sendError(fmt.Sprintf("Unknown input type: %s", cmd.Type))
 	}
 }
 
 // Mouse move
-func handleMouseMove(cmd map[string]interface{}) {
-	xf, ok := cmd["x"].(float64)
-	if !ok {
+func handleMouseMove(cmd protocol.NativeInput) {
+	if cmd.X == nil {
 		sendError("Missing x coordinate")
 		return
 	}
-	yf, ok := cmd["y"].(float64)
-	if !ok {
+	if cmd.Y == nil {
 		sendError("Missing y coordinate")
 		return
 	}
-	x := int(xf)
-	y := int(yf)
+	x := int(*cmd.X)
+	y := int(*cmd.Y)
 
 	robotgo.Move(x, y)
-	sendMessage(Message{Type: "input_success"})
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Mouse click
-func handleMouseClick(cmd map[string]interface{}) {
+func handleMouseClick(cmd protocol.NativeInput) {
 	button := "left"
-	if b, ok := cmd["button"].(string); ok {
-		button = b
+	if cmd.Button != "" {
+		button = cmd.Button
 	}
 
-	double := false
-	if d, ok := cmd["double"].(bool); ok {
-		double = d
-	}
-
-	if double {
+	if cmd.Double {
 		robotgo.Click(button, true)
 	} else {
 		robotgo.Click(button, false)
 	}
 
-	sendMessage(Message{Type: "input_success"})
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Mouse button down/up
-func handleMouseButton(cmd map[string]interface{}, down bool) {
+func handleMouseButton(cmd protocol.NativeInput, down bool) {
 	button := "left"
-	if b, ok := cmd["button"].(string); ok {
-		button = b
+	if cmd.Button != "" {
+		button = cmd.Button
 	}
 
 	if down {
@@ -181,15 +174,12 @@ func handleMouseButton(cmd map[string]interface{}, down bool) {
 		robotgo.Toggle(button, "up")
 	}
 
-	sendMessage(Message{Type: "input_success"})
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Mouse scroll
-func handleMouseScroll(cmd map[string]interface{}) {
-	deltaY := 0
-	if d, ok := cmd["deltaY"].(float64); ok {
-		deltaY = int(d)
-	}
+func handleMouseScroll(cmd protocol.NativeInput) {
+	deltaY := int(cmd.DeltaY)
 
 	// Normalize scroll amount (positive = down, negative = up)
 	scrollAmount := deltaY / 10
@@ -203,36 +193,33 @@ func handleMouseScroll(cmd map[string]interface{}) {
 
 	// robotgo.Scroll(x, y) - negative y scrolls up, positive scrolls down
 	robotgo.Scroll(0, scrollAmount)
-	sendMessage(Message{Type: "input_success"})
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Keyboard press
-func handleKeyboardPress(cmd map[string]interface{}) {
-	key, ok := cmd["key"].(string)
-	if !ok {
+func handleKeyboardPress(cmd protocol.NativeInput) {
+	if cmd.Key == nil {
 		sendError("Missing key")
 		return
 	}
 
 	// Get modifiers
 	var mods []string
-	if modifiers, ok := cmd["modifiers"].(map[string]interface{}); ok {
-		if ctrl, ok := modifiers["ctrl"].(bool); ok && ctrl {
-			mods = append(mods, "ctrl")
-		}
-		if alt, ok := modifiers["alt"].(bool); ok && alt {
-			mods = append(mods, "alt")
-		}
-		if shift, ok := modifiers["shift"].(bool); ok && shift {
-			mods = append(mods, "shift")
-		}
-		if meta, ok := modifiers["meta"].(bool); ok && meta {
-			mods = append(mods, "cmd")
-		}
+	if cmd.Modifiers.Ctrl {
+		mods = append(mods, "ctrl")
+	}
+	if cmd.Modifiers.Alt {
+		mods = append(mods, "alt")
+	}
+	if cmd.Modifiers.Shift {
+		mods = append(mods, "shift")
+	}
+	if cmd.Modifiers.Meta {
+		mods = append(mods, "cmd")
 	}
 
 	// Map key to robotgo format
-	robotgoKey := mapKeyToRobotgo(key)
+	robotgoKey := mapKeyToRobotgo(*cmd.Key)
 
 	// Press key with modifiers
 	if len(mods) > 0 {
@@ -241,19 +228,18 @@ func handleKeyboardPress(cmd map[string]interface{}) {
 		robotgo.KeyTap(robotgoKey)
 	}
 
-	sendMessage(Message{Type: "input_success"})
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Keyboard type
-func handleKeyboardType(cmd map[string]interface{}) {
-	text, ok := cmd["text"].(string)
-	if !ok {
+func handleKeyboardType(cmd protocol.NativeInput) {
+	if cmd.Text == nil {
 		sendError("Missing text")
 		return
 	}
 
-	robotgo.TypeStr(text)
-	sendMessage(Message{Type: "input_success"})
+	robotgo.TypeStr(*cmd.Text)
+	sendMessage(Message{Type: protocol.TypeNativeInputSuccess})
 }
 
 // Map JavaScript key names to robotgo key names
@@ -296,7 +282,7 @@ func mapKeyToRobotgo(key string) string {
 // Send error message
 func sendError(errMsg string) {
 	sendMessage(Message{
-		Type:  "input_error",
+		Type:  protocol.TypeNativeInputError,
 		Error: errMsg,
 	})
 }
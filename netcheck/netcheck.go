@@ -0,0 +1,573 @@
+// Package netcheck diagnoses the network path WebRTC sessions depend on:
+// NAT mapping behaviour, TURN reachability over UDP/TCP/TLS, which ICE
+// candidate types can be gathered, the outbound interface MTU and clock skew
+// against Supabase (TURN credentials and JWTs are time-limited).
+package netcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/stun"
+	"github.com/pion/turn/v2"
+	"github.com/pion/webrtc/v3"
+)
+
+// Default STUN servers used for NAT classification when the ICE server list
+// has fewer than two STUN endpoints.
+var defaultSTUNServers = []string{
+	"stun:stun.l.google.com:19302",
+	"stun:stun1.l.google.com:19302",
+}
+
+// minWebRTCMTU is the smallest path MTU that fits pion's 1200-byte SCTP/RTP
+// packets plus IP/UDP/DTLS (and TURN ChannelData) overhead.
+const minWebRTCMTU = 1280
+
+// Options configures a diagnostic run.
+type Options struct {
+	ICEServers  []webrtc.ICEServer // STUN/TURN servers incl. credentials (from turn-credentials)
+	SupabaseURL string             // Used for the clock skew check
+	Timeout     time.Duration      // Per-check timeout (default 10s)
+}
+
+// Report is the result of a diagnostic run.
+type Report struct {
+	Time       time.Time       `json:"time"`
+	NAT        NATResult       `json:"nat"`
+	TURN       []TURNResult    `json:"turn"`
+	Candidates CandidateResult `json:"candidates"`
+	MTU        MTUResult       `json:"mtu"`
+	Clock      ClockResult     `json:"clock"`
+}
+
+// NATResult classifies the NAT mapping seen by two or more STUN servers.
+type NATResult struct {
+	Type     string        `json:"type"` // open | endpoint-independent | symmetric | blocked | unknown
+	LocalIP  string        `json:"local_ip,omitempty"`
+	Mappings []STUNMapping `json:"mappings"`
+}
+
+// STUNMapping is the reflexive address one STUN server saw.
+type STUNMapping struct {
+	Server    string `json:"server"`
+	Mapped    string `json:"mapped,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TURNResult is the outcome of one TURN allocation attempt.
+type TURNResult struct {
+	URL       string `json:"url"`
+	Transport string `json:"transport"` // udp | tcp | tls
+	OK        bool   `json:"ok"`
+	Relayed   string `json:"relayed,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CandidateResult counts the ICE candidates a peer connection gathered.
+type CandidateResult struct {
+	Host     int    `json:"host"`
+	Srflx    int    `json:"srflx"`
+	Prflx    int    `json:"prflx"`
+	Relay    int    `json:"relay"`
+	UDP      int    `json:"udp"`
+	TCP      int    `json:"tcp"`
+	Complete bool   `json:"complete"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MTUResult describes the interface used for outbound traffic.
+type MTUResult struct {
+	Interface string `json:"interface,omitempty"`
+	MTU       int    `json:"mtu,omitempty"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ClockResult is the local clock offset against the Supabase Date header.
+type ClockResult struct {
+	SkewMs int64  `json:"skew_ms"` // positive = local clock is ahead
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Run performs all checks. Checks are independent; one failing does not
+// stop the others.
+func Run(ctx context.Context, opts Options) *Report {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	r := &Report{Time: time.Now()}
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	go func() { defer wg.Done(); r.NAT = checkNAT(opts) }()
+	go func() { defer wg.Done(); r.TURN = checkTURN(opts) }()
+	go func() { defer wg.Done(); r.Candidates = checkCandidates(ctx, opts) }()
+	go func() { defer wg.Done(); r.MTU = checkMTU() }()
+	go func() { defer wg.Done(); r.Clock = checkClock(ctx, opts) }()
+	wg.Wait()
+	return r
+}
+
+func checkNAT(opts Options) NATResult {
+	res := NATResult{Type: "unknown", Mappings: []STUNMapping{}}
+
+	servers := stunServers(opts.ICEServers)
+
+	// One socket for all servers: the mapping must be compared for the
+	// same local port.
+	conn, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		res.Type = "blocked"
+		return res
+	}
+	defer conn.Close()
+
+	client, err := turn.NewClient(&turn.ClientConfig{Conn: conn, RTO: 300 * time.Millisecond})
+	if err != nil {
+		return res
+	}
+	defer client.Close()
+	if err := client.Listen(); err != nil {
+		return res
+	}
+
+	localIPs := localAddresses()
+	seenServers := map[string]bool{} // resolved server addresses that were queried
+	for _, raw := range servers {
+		m := STUNMapping{Server: raw}
+		uri, err := stun.ParseURI(raw)
+		if err != nil {
+			m.Error = err.Error()
+			res.Mappings = append(res.Mappings, m)
+			continue
+		}
+		addr, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(uri.Host, fmt.Sprint(uri.Port)))
+		if err != nil {
+			m.Error = err.Error()
+			res.Mappings = append(res.Mappings, m)
+			continue
+		}
+		if seenServers[addr.String()] {
+			continue // same server IP under two names tells us nothing new
+		}
+		seenServers[addr.String()] = true
+
+		start := time.Now()
+		reflexive, err := client.SendBindingRequestTo(addr)
+		if err != nil {
+			m.Error = err.Error()
+		} else {
+			m.Mapped = reflexive.String()
+			m.LatencyMs = time.Since(start).Milliseconds()
+			if host, _, err := net.SplitHostPort(m.Mapped); err == nil && localIPs[host] {
+				res.LocalIP = host
+			}
+		}
+		res.Mappings = append(res.Mappings, m)
+	}
+
+	res.Type = classifyNAT(len(seenServers), res.Mappings, res.LocalIP)
+	return res
+}
+
+// stunServers lists the STUN URLs from the ICE servers, topped up with
+// defaultSTUNServers so at least two servers can be compared.
+func stunServers(ice []webrtc.ICEServer) []string {
+	var servers []string
+	listed := map[string]bool{}
+	for _, s := range ice {
+		for _, u := range s.URLs {
+			if strings.HasPrefix(u, "stun:") && !listed[u] {
+				listed[u] = true
+				servers = append(servers, u)
+			}
+		}
+	}
+	for _, u := range defaultSTUNServers {
+		if len(servers) >= 2 {
+			break
+		}
+		if !listed[u] {
+			listed[u] = true
+			servers = append(servers, u)
+		}
+	}
+	return servers
+}
+
+// classifyNAT names the NAT type from the mappings of queried distinct
+// STUN servers. localIP is set when a mapping equals a local address.
+func classifyNAT(queried int, mappings []STUNMapping, localIP string) string {
+	mapped := map[string]bool{}
+	for _, m := range mappings {
+		if m.Mapped != "" {
+			mapped[m.Mapped] = true
+		}
+	}
+	switch {
+	case queried == 0:
+		// No STUN server could even be resolved: a DNS problem, not NAT
+		return "unknown"
+	case len(mapped) == 0:
+		return "blocked"
+	case localIP != "":
+		return "open"
+	case len(mapped) > 1:
+		return "symmetric"
+	case countOK(mappings) >= 2:
+		return "endpoint-independent"
+	}
+	return "unknown"
+}
+
+func countOK(mappings []STUNMapping) int {
+	n := 0
+	for _, m := range mappings {
+		if m.Mapped != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func localAddresses() map[string]bool {
+	out := map[string]bool{}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return out
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			out[ipnet.IP.String()] = true
+		}
+	}
+	return out
+}
+
+func checkTURN(opts Options) []TURNResult {
+	results := []TURNResult{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range opts.ICEServers {
+		for _, raw := range s.URLs {
+			if !strings.HasPrefix(raw, "turn:") && !strings.HasPrefix(raw, "turns:") {
+				continue
+			}
+			wg.Add(1)
+			go func(raw, user, pass string) {
+				defer wg.Done()
+				res := allocateTURN(raw, user, pass, opts.Timeout)
+				mu.Lock()
+				results = append(results, res)
+				mu.Unlock()
+			}(raw, s.Username, fmt.Sprint(s.Credential))
+		}
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].URL < results[j].URL })
+	return results
+}
+
+func allocateTURN(raw, user, pass string, timeout time.Duration) TURNResult {
+	res := TURNResult{URL: raw}
+	uri, err := stun.ParseURI(raw)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	addr := net.JoinHostPort(uri.Host, fmt.Sprint(uri.Port))
+
+	start := time.Now()
+	var conn net.PacketConn
+	switch {
+	case uri.Scheme == stun.SchemeTypeTURNS:
+		res.Transport = "tls"
+		c, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{ServerName: uri.Host})
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		conn = turn.NewSTUNConn(c)
+	case uri.Proto == stun.ProtoTypeTCP:
+		res.Transport = "tcp"
+		c, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		conn = turn.NewSTUNConn(c)
+	default:
+		res.Transport = "udp"
+		c, err := net.ListenPacket("udp4", "0.0.0.0:0")
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		conn = c
+	}
+	defer conn.Close()
+
+	client, err := turn.NewClient(&turn.ClientConfig{
+		STUNServerAddr: addr,
+		TURNServerAddr: addr,
+		Username:       user,
+		Password:       pass,
+		Conn:           conn,
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer client.Close()
+	if err := client.Listen(); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	type allocResult struct {
+		relay net.PacketConn
+		err   error
+	}
+	done := make(chan allocResult, 1)
+	go func() {
+		relay, err := client.Allocate()
+		done <- allocResult{relay, err}
+	}()
+	select {
+	case a := <-done:
+		if a.err != nil {
+			res.Error = a.err.Error()
+			return res
+		}
+		res.OK = true
+		res.Relayed = a.relay.LocalAddr().String()
+		res.LatencyMs = time.Since(start).Milliseconds()
+		a.relay.Close()
+	case <-time.After(timeout):
+		res.Error = "allocation timed out"
+	}
+	return res
+}
+
+func checkCandidates(ctx context.Context, opts Options) CandidateResult {
+	var res CandidateResult
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{ICEServers: opts.ICEServers})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer pc.Close()
+
+	var mu sync.Mutex
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch c.Typ {
+		case webrtc.ICECandidateTypeHost:
+			res.Host++
+		case webrtc.ICECandidateTypeSrflx:
+			res.Srflx++
+		case webrtc.ICECandidateTypePrflx:
+			res.Prflx++
+		case webrtc.ICECandidateTypeRelay:
+			res.Relay++
+		}
+		if c.Protocol == webrtc.ICEProtocolTCP {
+			res.TCP++
+		} else {
+			res.UDP++
+		}
+	})
+
+	if _, err := pc.CreateDataChannel("netcheck", nil); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(offer); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	complete := false
+	select {
+	case <-gatherComplete:
+		complete = true
+	case <-time.After(opts.Timeout):
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	out := res
+	out.Complete = complete
+	return out
+}
+
+func checkMTU() MTUResult {
+	var res MTUResult
+	// Connecting a UDP socket sends nothing; it only picks the outbound route.
+	c, err := net.Dial("udp4", "1.1.1.1:53")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	localIP := c.LocalAddr().(*net.UDPAddr).IP
+	c.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(localIP) {
+				res.Interface = iface.Name
+				res.MTU = iface.MTU
+				res.OK = iface.MTU >= minWebRTCMTU
+				return res
+			}
+		}
+	}
+	res.Error = "outbound interface not found for " + localIP.String()
+	return res
+}
+
+// maxClockSkew is how far the local clock may drift before time-limited
+// TURN credentials and JWTs start failing.
+const maxClockSkew = 30 * time.Second
+
+func checkClock(ctx context.Context, opts Options) ClockResult {
+	var res ClockResult
+	if opts.SupabaseURL == "" {
+		res.Error = "no Supabase URL configured"
+		return res
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, opts.SupabaseURL+"/rest/v1/", nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	client := &http.Client{Timeout: opts.Timeout}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	end := time.Now()
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		res.Error = "no usable Date header"
+		return res
+	}
+	// Date has 1s resolution; assume mid-second and compare to the request midpoint.
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	midpoint := start.Add(end.Sub(start) / 2)
+	skew := midpoint.Sub(serverTime)
+	res.SkewMs = skew.Milliseconds()
+	res.OK = skew < maxClockSkew && skew > -maxClockSkew
+	return res
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes a human-readable summary.
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Network diagnostics (%s)\n\n", r.Time.Format("2006-01-02 15:04:05"))
+
+	natHint := map[string]string{
+		"open":                 "public IP, no NAT",
+		"endpoint-independent": "P2P friendly",
+		"symmetric":            "P2P unlikely, TURN relay needed",
+		"blocked":              "UDP blocked, TURN over TCP/TLS needed",
+	}
+	fmt.Fprintf(w, "NAT:         %s", r.NAT.Type)
+	if hint, ok := natHint[r.NAT.Type]; ok {
+		fmt.Fprintf(w, " (%s)", hint)
+	}
+	fmt.Fprintln(w)
+	for _, m := range r.NAT.Mappings {
+		if m.Error != "" {
+			fmt.Fprintf(w, "  %-36s FAIL %s\n", m.Server, m.Error)
+		} else {
+			fmt.Fprintf(w, "  %-36s mapped %s (%dms)\n", m.Server, m.Mapped, m.LatencyMs)
+		}
+	}
+
+	fmt.Fprintln(w, "TURN:")
+	if len(r.TURN) == 0 {
+		fmt.Fprintln(w, "  (no TURN servers configured — not logged in?)")
+	}
+	for _, t := range r.TURN {
+		if t.OK {
+			fmt.Fprintf(w, "  %-48s %-4s OK   relay %s (%dms)\n", t.URL, t.Transport, t.Relayed, t.LatencyMs)
+		} else {
+			fmt.Fprintf(w, "  %-48s %-4s FAIL %s\n", t.URL, t.Transport, t.Error)
+		}
+	}
+
+	c := r.Candidates
+	if c.Error != "" {
+		fmt.Fprintf(w, "Candidates:  FAIL %s\n", c.Error)
+	} else {
+		partial := ""
+		if !c.Complete {
+			partial = " (gathering timed out)"
+		}
+		fmt.Fprintf(w, "Candidates:  host=%d srflx=%d relay=%d (udp=%d tcp=%d)%s\n", c.Host, c.Srflx, c.Relay, c.UDP, c.TCP, partial)
+	}
+
+	if r.MTU.Error != "" {
+		fmt.Fprintf(w, "MTU:         FAIL %s\n", r.MTU.Error)
+	} else {
+		status := "ok"
+		if !r.MTU.OK {
+			status = fmt.Sprintf("too small, WebRTC needs >= %d", minWebRTCMTU)
+		}
+		fmt.Fprintf(w, "MTU:         %s %d (%s)\n", r.MTU.Interface, r.MTU.MTU, status)
+	}
+
+	if r.Clock.Error != "" {
+		fmt.Fprintf(w, "Clock skew:  FAIL %s\n", r.Clock.Error)
+	} else {
+		status := "ok"
+		if !r.Clock.OK {
+			status = "too large — TURN credentials and logins may fail"
+		}
+		fmt.Fprintf(w, "Clock skew:  %+dms vs Supabase (%s)\n", r.Clock.SkewMs, status)
+	}
+}
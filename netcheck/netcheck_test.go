@@ -0,0 +1,112 @@
+package netcheck
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestClassifyNAT(t *testing.T) {
+	ok := func(server, mapped string) STUNMapping { return STUNMapping{Server: server, Mapped: mapped} }
+	fail := func(server string) STUNMapping { return STUNMapping{Server: server, Error: "timeout"} }
+	cases := []struct {
+		name     string
+		queried  int
+		mappings []STUNMapping
+		localIP  string
+		want     string
+	}{
+		{"nothing resolved", 0, []STUNMapping{{Server: "stun:x", Error: "no such host"}}, "", "unknown"},
+		{"no answers", 2, []STUNMapping{fail("a"), fail("b")}, "", "blocked"},
+		{"public address", 2, []STUNMapping{ok("a", "198.51.100.7:4000"), ok("b", "198.51.100.7:4000")}, "198.51.100.7", "open"},
+		{"same mapping", 2, []STUNMapping{ok("a", "203.0.113.9:5000"), ok("b", "203.0.113.9:5000")}, "", "endpoint-independent"},
+		{"mapping per server", 2, []STUNMapping{ok("a", "203.0.113.9:5000"), ok("b", "203.0.113.9:5001")}, "", "symmetric"},
+		{"one answer", 2, []STUNMapping{ok("a", "203.0.113.9:5000"), fail("b")}, "", "unknown"},
+	}
+	for _, tc := range cases {
+		if got := classifyNAT(tc.queried, tc.mappings, tc.localIP); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSTUNServers(t *testing.T) {
+	ice := []webrtc.ICEServer{
+		{URLs: []string{"stun:stun.example.com:3478", "turn:turn.example.com:3478"}},
+		{URLs: []string{"stun:stun.example.com:3478"}},
+	}
+	got := stunServers(ice)
+	want := []string{"stun:stun.example.com:3478", defaultSTUNServers[0]}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := stunServers(nil); len(got) != 2 {
+		t.Errorf("defaults: got %v", got)
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	offset := time.Minute
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-offset).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	res := checkClock(context.Background(), Options{SupabaseURL: srv.URL, Timeout: 5 * time.Second})
+	if res.Error != "" || res.OK {
+		t.Fatalf("got %+v, want a failing skew", res)
+	}
+	if skew := time.Duration(res.SkewMs) * time.Millisecond; skew < offset-2*time.Second || skew > offset+2*time.Second {
+		t.Errorf("skew %v, want about %v", skew, offset)
+	}
+
+	offset = 0
+	if res := checkClock(context.Background(), Options{SupabaseURL: srv.URL, Timeout: 5 * time.Second}); !res.OK {
+		t.Errorf("in-sync clock reported as %+v", res)
+	}
+	if res := checkClock(context.Background(), Options{}); res.Error == "" {
+		t.Error("missing Supabase URL not reported")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	r := &Report{
+		Time: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+		NAT: NATResult{Type: "symmetric", Mappings: []STUNMapping{
+			{Server: "stun:a", Mapped: "203.0.113.9:5000", LatencyMs: 12},
+			{Server: "stun:b", Error: "timeout"},
+		}},
+		TURN: []TURNResult{
+			{URL: "turn:t:3478", Transport: "udp", OK: true, Relayed: "203.0.113.1:60000", LatencyMs: 40},
+			{URL: "turns:t:5349", Transport: "tls", Error: "handshake failed"},
+		},
+		Candidates: CandidateResult{Host: 2, Srflx: 1, Relay: 1, UDP: 4},
+		MTU:        MTUResult{Interface: "wg0", MTU: 1200},
+		Clock:      ClockResult{SkewMs: 45000},
+	}
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"NAT:         symmetric (P2P unlikely, TURN relay needed)",
+		"stun:b                               FAIL timeout",
+		"OK   relay 203.0.113.1:60000 (40ms)",
+		"tls  FAIL handshake failed",
+		"host=2 srflx=1 relay=1 (udp=4 tcp=0) (gathering timed out)",
+		"MTU:         wg0 1200 (too small, WebRTC needs >= 1280)",
+		"Clock skew:  +45000ms vs Supabase (too large",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "no TURN servers configured") {
+		t.Error("TURN hint shown with TURN results")
+	}
+}
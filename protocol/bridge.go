@@ -0,0 +1,147 @@
+package protocol
+
+import "encoding/json"
+
+// Browser input bridges. The dashboard can inject input on the machine it
+// runs on through two local helpers: input-helper (a WebSocket server) and
+// native-host (the browser extension's native messaging host). Neither
+// travels over a data channel, but their messages are declared here with
+// everything else.
+
+// Input helper message types ("type", or "t" as a shorthand). mouse_move
+// and key reuse the data channel names with helper-specific fields.
+const (
+	TypeHelperAuth             = "auth"
+	TypeHelperMouseAbs         = "mouse_abs"
+	TypeHelperMouseButton      = "mouse_button"
+	TypeHelperWheel            = "wheel"
+	TypeHelperClipboard        = "clipboard"
+	TypeHelperControl          = "control"
+	TypeHelperAck              = "ack"
+	TypeHelperStatus           = "status"
+	TypeHelperClipboardContent = "clipboard_content"
+)
+
+// HelperAuth opens an input helper session.
+type HelperAuth struct {
+	Type      string `json:"type"` // "auth"
+	Token     string `json:"token"`
+	DeviceID  string `json:"device_id"`
+	SessionID string `json:"session_id"`
+}
+
+// HelperMouseMove moves the pointer by DX, DY pixels.
+type HelperMouseMove struct {
+	Type string `json:"type"` // "mouse_move"
+	DX   int    `json:"dx"`
+	DY   int    `json:"dy"`
+}
+
+// HelperMouseAbs moves the pointer to X, Y normalized to the primary
+// screen (0.0-1.0).
+type HelperMouseAbs struct {
+	Type string  `json:"type"` // "mouse_abs"
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// HelperMouseButton presses or releases a button.
+type HelperMouseButton struct {
+	Type   string `json:"type"` // "mouse_button"
+	Seq    int64  `json:"seq,omitempty"`
+	Button string `json:"button"`
+	Down   bool   `json:"down"`
+}
+
+// HelperWheel scrolls by DX, DY wheel units.
+type HelperWheel struct {
+	Type string `json:"type"` // "wheel"
+	DX   int    `json:"dx"`
+	DY   int    `json:"dy"`
+}
+
+// HelperKey is a data channel Key with the helper's sequence number.
+type HelperKey struct {
+	Key
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// HelperClipboard writes Content to the clipboard (Direction "to_system")
+// or asks for its text ("from_system").
+type HelperClipboard struct {
+	Type      string `json:"type"` // "clipboard"
+	Seq       int64  `json:"seq,omitempty"`
+	Direction string `json:"direction"`
+	Content   string `json:"content,omitempty"`
+}
+
+// HelperControl enables or disables input classes. Action is enable |
+// disable | pause | resume; Scope is mouse | keyboard | clipboard | all.
+type HelperControl struct {
+	Type   string `json:"type"` // "control"
+	Seq    int64  `json:"seq,omitempty"`
+	Action string `json:"action"`
+	Scope  string `json:"scope,omitempty"`
+}
+
+// HelperResponse is every message the input helper sends back.
+type HelperResponse struct {
+	Type    string `json:"type"` // "ack" | "status" | "clipboard_content"
+	Seq     int64  `json:"seq,omitempty"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Native messaging host message types. NativeMessage.Type is one of the
+// first group; NativeInput.Type one of the second.
+const (
+	TypeNativeConnected    = "connected"
+	TypeNativePing         = "ping"
+	TypeNativePong         = "pong"
+	TypeNativeInput        = "input"
+	TypeNativeInputSuccess = "input_success"
+	TypeNativeInputError   = "input_error"
+
+	TypeNativeMouseMove     = "mouse_move"
+	TypeNativeMouseClick    = "mouse_click"
+	TypeNativeMouseDown     = "mouse_down"
+	TypeNativeMouseUp       = "mouse_up"
+	TypeNativeMouseScroll   = "mouse_scroll"
+	TypeNativeKeyboardPress = "keyboard_press"
+	TypeNativeKeyboardType  = "keyboard_type"
+)
+
+// NativeMessage is one native messaging frame in either direction. Command
+// holds a NativeInput when Type is "input".
+type NativeMessage struct {
+	Type    string          `json:"type"`
+	Command json.RawMessage `json:"command,omitempty"`
+	Status  string          `json:"status,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// NativeInput is an input command from the extension. X and Y are screen
+// pixels and required for mouse_move; Key and Text are required for
+// keyboard_press and keyboard_type.
+// DeltaY follows DOM WheelEvent.deltaY (positive scrolls down). Key is a
+// DOM KeyboardEvent.key.
+type NativeInput struct {
+	Type      string          `json:"type"`
+	X         *float64        `json:"x,omitempty"`
+	Y         *float64        `json:"y,omitempty"`
+	Button    string          `json:"button,omitempty"`
+	Double    bool            `json:"double,omitempty"`
+	DeltaY    float64         `json:"deltaY,omitempty"`
+	Key       *string         `json:"key,omitempty"`
+	Modifiers NativeModifiers `json:"modifiers"`
+	Text      *string         `json:"text,omitempty"`
+}
+
+// NativeModifiers are the modifier keys held for keyboard_press.
+type NativeModifiers struct {
+	Ctrl  bool `json:"ctrl,omitempty"`
+	Alt   bool `json:"alt,omitempty"`
+	Shift bool `json:"shift,omitempty"`
+	Meta  bool `json:"meta,omitempty"`
+}
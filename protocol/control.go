@@ -0,0 +1,434 @@
+package protocol
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Control message types ("type" field) on the control/data channels.
+const (
+	TypeSetMode           = "set_mode"
+	TypeCodecStatus       = "codec_status"
+	TypeSetStreamParams   = "set_stream_params"
+	TypeStreamPause       = "stream_pause"
+	TypeStreamResume      = "stream_resume"
+	TypeSwitchMonitor     = "switch_monitor"
+	TypeMonitorSwitched   = "monitor_switched"
+	TypeMonitorList       = "monitor_list"
+	TypeReleaseAllKeys    = "release_all_keys"
+	TypeKeyboardLayout    = "keyboard_layout"
+	TypeRemoteLogin       = "remote_login"
+	TypeForceUpdate       = "force_update"
+	TypeUpdateStatus      = "update_status"
+	TypeRestartSafeMode   = "restart_safe_mode"
+	TypeRestartStatus     = "restart_status"
+	TypeICERestartOffer   = "ice_restart_offer"
+	TypeICERestartAnswer  = "ice_restart_answer"
+	TypeClipboardText     = "clipboard_text"
+	TypeClipboardImage    = "clipboard_image"
+	TypeClipboardFiles    = "clipboard_files"
+	TypeClipboardHTML     = "clipboard_html"
+	TypeClipboardRTF      = "clipboard_rtf"
+	TypeClipboardPolicy   = "clipboard_policy"
+	TypeLocalLock         = "local_lock"
+	TypeLocalLockState    = "local_lock_state"
+	TypeDirList           = "dir_list"
+	TypeDirListResponse   = "dir_list_response"
+	TypeDrivesList        = "drives_list"
+	TypeDrivesListResult  = "drives_list_response"
+	TypeFileRequest       = "file_request"
+	TypeFileResponseError = "file_response_error"
+)
+
+// Command is a control message without a payload (stream_pause,
+// stream_resume, release_all_keys, force_update, drives_list).
+type Command struct {
+	Type string `json:"type"`
+}
+
+// NewCommand builds a payload-less control message.
+func NewCommand(msgType string) Command {
+	return Command{Type: msgType}
+}
+
+// SetMode selects the streaming codec: tiles | h264 | hybrid. Bitrate is
+// in kbps; 0 keeps the agent default.
+type SetMode struct {
+	Type    string `json:"type"` // "set_mode"
+	Mode    string `json:"mode"`
+	Bitrate int    `json:"bitrate,omitempty"`
+}
+
+// CodecStatus answers SetMode with the codec actually in use.
+type CodecStatus struct {
+	Type      string `json:"type"` // "codec_status"
+	Requested string `json:"requested"`
+	Active    string `json:"active"`
+	Accepted  bool   `json:"accepted"`
+	Reason    string `json:"reason"`
+}
+
+// SetStreamParams caps the stream; zero fields are left unchanged.
+type SetStreamParams struct {
+	Type            string  `json:"type"` // "set_stream_params"
+	MaxQuality      int     `json:"max_quality,omitempty"`
+	MaxFPS          int     `json:"max_fps,omitempty"`
+	MaxScale        float64 `json:"max_scale,omitempty"`
+	H264BitrateKbps int     `json:"h264_bitrate_kbps,omitempty"`
+}
+
+// AllMonitors is the SwitchMonitor index for the spanning mode: every
+// display composited into one frame covering the virtual desktop.
+const AllMonitors = -1
+
+// SwitchMonitor selects the captured monitor, or AllMonitors.
+type SwitchMonitor struct {
+	Type  string `json:"type"` // "switch_monitor"
+	Index int    `json:"index"`
+}
+
+// MonitorSwitched confirms SwitchMonitor. In spanning mode OffsetX/OffsetY
+// is the virtual desktop's top-left corner, which is negative when a monitor
+// sits left of or above the primary.
+type MonitorSwitched struct {
+	Type    string `json:"type"` // "monitor_switched"
+	Index   int    `json:"index"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	OffsetX int    `json:"offsetX,omitempty"`
+	OffsetY int    `json:"offsetY,omitempty"`
+}
+
+// Monitor describes one display in MonitorList.
+type Monitor struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Primary bool   `json:"primary"`
+	OffsetX int    `json:"offsetX"`
+	OffsetY int    `json:"offsetY"`
+}
+
+// MonitorList is sent by the agent when streaming starts. Span reports
+// that the agent accepts AllMonitors.
+type MonitorList struct {
+	Type     string    `json:"type"` // "monitor_list"
+	Monitors []Monitor `json:"monitors"`
+	Active   int       `json:"active"`
+	Span     bool      `json:"span,omitempty"`
+}
+
+// KeyboardLayout reports the agent's active keyboard layout. It is sent
+// after the handshake and again whenever the layout changes. ID is
+// platform-specific (a Windows HKL such as "04060406", or a macOS input
+// source such as "com.apple.keylayout.Danish"); Lang is a BCP 47 tag.
+type KeyboardLayout struct {
+	Type string `json:"type"` // "keyboard_layout"
+	ID   string `json:"id"`
+	Lang string `json:"lang,omitempty"`
+}
+
+// RemoteLogin types credentials into the Windows logon screen.
+// SendUsername defaults to true when omitted.
+type RemoteLogin struct {
+	Type         string `json:"type"` // "remote_login"
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	SendUsername *bool  `json:"send_username,omitempty"`
+}
+
+// UpdateStatus reports progress of a force_update.
+type UpdateStatus struct {
+	Type    string `json:"type"`   // "update_status"
+	Status  string `json:"status"` // checking | up_to_date | downloading | installing | restarting | error
+	Message string `json:"message"`
+}
+
+// RestartSafeMode restarts the agent's device into Safe Mode with
+// Networking (Windows only). The agent service comes back there, and the
+// next restart returns to normal mode. DelaySec 0 means
+// RestartSafeModeDefaultDelay.
+type RestartSafeMode struct {
+	Type     string `json:"type"` // "restart_safe_mode"
+	DelaySec int    `json:"delay_sec,omitempty"`
+}
+
+// RestartSafeModeDefaultDelay is the restart delay in seconds, long
+// enough for the answer to reach the controller.
+const RestartSafeModeDefaultDelay = 10
+
+// RestartStatus answers RestartSafeMode.
+type RestartStatus struct {
+	Type     string `json:"type"`   // "restart_status"
+	Status   string `json:"status"` // restarting | error
+	SafeMode bool   `json:"safe_mode"`
+	Message  string `json:"message"`
+}
+
+// ICERestart carries an ICE restart offer or answer.
+type ICERestart struct {
+	Type      string `json:"type"` // "ice_restart_offer" | "ice_restart_answer"
+	RestartID string `json:"restart_id"`
+	SDP       string `json:"sdp"`
+}
+
+// Clipboard carries clipboard content in either direction. Content is
+// plain text for clipboard_text, a base64 PNG for clipboard_image, an HTML
+// fragment for clipboard_html and an RTF document for clipboard_rtf. Text
+// is the plain-text alternative of HTML and RTF, for pastes into editors
+// that take no formatting.
+type Clipboard struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Text    string `json:"text,omitempty"`
+}
+
+// Clipboard formats, as named in ClipboardPolicy.Formats.
+const (
+	ClipboardFormatText  = "text"
+	ClipboardFormatImage = "image"
+	ClipboardFormatHTML  = "html"
+	ClipboardFormatRTF   = "rtf"
+	ClipboardFormatFiles = "files"
+)
+
+// ClipboardFormats lists every format, in the order the UIs show them.
+var ClipboardFormats = []string{
+	ClipboardFormatText, ClipboardFormatImage, ClipboardFormatHTML,
+	ClipboardFormatRTF, ClipboardFormatFiles,
+}
+
+// ClipboardFormatOf maps a clipboard message type to its format, or "".
+func ClipboardFormatOf(msgType string) string {
+	switch msgType {
+	case TypeClipboardText:
+		return ClipboardFormatText
+	case TypeClipboardImage:
+		return ClipboardFormatImage
+	case TypeClipboardHTML:
+		return ClipboardFormatHTML
+	case TypeClipboardRTF:
+		return ClipboardFormatRTF
+	case TypeClipboardFiles:
+		return ClipboardFormatFiles
+	}
+	return ""
+}
+
+// Clipboard sync directions.
+const (
+	ClipboardBoth      = "both"
+	ClipboardToAgent   = "to_agent"   // Controller copies paste on the agent only
+	ClipboardFromAgent = "from_agent" // Agent copies paste on the controller only
+	ClipboardOff       = "off"
+)
+
+// ClipboardPolicy limits clipboard sync for one session. The controller
+// sends it to set its policy and the agent answers with the policy in
+// force, which is never wider than the one a support session was created
+// with. Zero values mean no limit: an empty Direction is both ways,
+// MaxBytes 0 is any size and an empty Formats allows every format.
+type ClipboardPolicy struct {
+	Type      string   `json:"type,omitempty"` // "clipboard_policy"
+	Direction string   `json:"direction,omitempty"`
+	MaxBytes  int64    `json:"max_bytes,omitempty"`
+	Formats   []string `json:"formats,omitempty"`
+	Error     string   `json:"error,omitempty"` // Set in a reply that rejected the request
+}
+
+// Validate checks the direction and format names.
+func (p ClipboardPolicy) Validate() error {
+	switch p.Direction {
+	case "", ClipboardBoth, ClipboardToAgent, ClipboardFromAgent, ClipboardOff:
+	default:
+		return fmt.Errorf("unknown clipboard direction %q", p.Direction)
+	}
+	if p.MaxBytes < 0 {
+		return fmt.Errorf("negative clipboard size limit")
+	}
+	for _, f := range p.Formats {
+		if !slices.Contains(ClipboardFormats, f) {
+			return fmt.Errorf("unknown clipboard format %q", f)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether size bytes of format may be synced towards the
+// agent (toAgent) or away from it.
+func (p ClipboardPolicy) Allows(format string, toAgent bool, size int64) bool {
+	switch p.Direction {
+	case ClipboardOff:
+		return false
+	case ClipboardToAgent:
+		if !toAgent {
+			return false
+		}
+	case ClipboardFromAgent:
+		if toAgent {
+			return false
+		}
+	}
+	if p.MaxBytes > 0 && size > p.MaxBytes {
+		return false
+	}
+	return len(p.Formats) == 0 || slices.Contains(p.Formats, format)
+}
+
+// Narrow returns the policy allowing only what both p and q allow.
+func (p ClipboardPolicy) Narrow(q ClipboardPolicy) ClipboardPolicy {
+	out := ClipboardPolicy{Type: p.Type, Direction: narrowDirection(p.Direction, q.Direction)}
+
+	out.MaxBytes = p.MaxBytes
+	if q.MaxBytes > 0 && (out.MaxBytes == 0 || q.MaxBytes < out.MaxBytes) {
+		out.MaxBytes = q.MaxBytes
+	}
+
+	switch {
+	case len(p.Formats) == 0:
+		out.Formats = slices.Clone(q.Formats)
+	case len(q.Formats) == 0:
+		out.Formats = slices.Clone(p.Formats)
+	default:
+		for _, f := range p.Formats {
+			if slices.Contains(q.Formats, f) {
+				out.Formats = append(out.Formats, f)
+			}
+		}
+		if len(out.Formats) == 0 {
+			out.Direction = ClipboardOff // No format left in common
+		}
+	}
+	return out
+}
+
+func narrowDirection(a, b string) string {
+	if a == "" || a == ClipboardBoth {
+		return b
+	}
+	if b == "" || b == ClipboardBoth || a == b {
+		return a
+	}
+	return ClipboardOff
+}
+
+// Limits on a file clipboard, counted over every file inside the copied
+// folders. Both sides refuse larger file lists.
+const (
+	MaxClipboardFiles = 10000
+	MaxClipboardBytes = 4 << 30
+)
+
+// ClipboardFile is one copied item. Size is the total for a folder.
+type ClipboardFile struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	IsDir bool   `json:"dir,omitempty"`
+}
+
+// ClipboardFiles announces files copied on the sender: names and sizes
+// only. The agent sends it when its clipboard holds a file list; the
+// content is fetched over the file channel ("clip", then "get") when the
+// user pastes. The controller sends it after uploading files to a
+// "clip_stage" folder, and the agent puts them on its clipboard. Error is
+// set instead of Files when the copy is over the limits.
+type ClipboardFiles struct {
+	Type  string          `json:"type"` // "clipboard_files"
+	Files []ClipboardFile `json:"files"`
+	Count int             `json:"count"` // Files inside, folders expanded
+	Total int64           `json:"total"`
+	Error string          `json:"error,omitempty"`
+}
+
+// DirList asks the agent for a directory listing on the control channel
+// (legacy browser; the file channel "list" op supersedes it).
+type DirList struct {
+	Type string `json:"type"` // "dir_list"
+	Path string `json:"path"`
+}
+
+// ControlFileRequest asks the agent to send a file on the control channel
+// (legacy browser; the file channel "get" op supersedes it).
+type ControlFileRequest struct {
+	Type       string `json:"type"` // "file_request"
+	RemotePath string `json:"remotePath"`
+}
+
+// DirEntry is one entry of DirListResponse.
+type DirEntry struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"is_dir"`
+	ModTime int64  `json:"mod_time"`
+}
+
+// DirListResponse answers DirList.
+type DirListResponse struct {
+	Type  string     `json:"type"` // "dir_list_response"
+	Path  string     `json:"path"`
+	Files []DirEntry `json:"files"`
+	Error string     `json:"error"`
+}
+
+// DrivesListResponse answers drives_list.
+type DrivesListResponse struct {
+	Type   string   `json:"type"` // "drives_list_response"
+	Drives []string `json:"drives"`
+}
+
+// LocalLock blocks the agent's local keyboard and mouse while remote input
+// keeps working, and with Blank covers the physical monitors with Message
+// (capture still sees the desktop). Enabled false releases the lock. The
+// lock ends by itself after TimeoutSec (0 = LocalLockDefaultTimeout, at
+// most LocalLockMaxTimeout), when the session ends, or when the local user
+// presses the emergency key combination.
+type LocalLock struct {
+	Type       string `json:"type"` // "local_lock"
+	Enabled    bool   `json:"enabled"`
+	Blank      bool   `json:"blank,omitempty"`
+	Message    string `json:"message,omitempty"`
+	TimeoutSec int    `json:"timeout_sec,omitempty"`
+}
+
+// Local lock timeouts, in seconds.
+const (
+	LocalLockDefaultTimeout = 10 * 60
+	LocalLockMaxTimeout     = 4 * 60 * 60
+)
+
+// Reasons a local lock ended (LocalLockState.Released).
+const (
+	LockReleasedController = "controller"
+	LockReleasedTimeout    = "timeout"
+	LockReleasedEmergency  = "emergency"
+)
+
+// LocalLockState answers LocalLock, and is sent unasked when a lock ends.
+// Blanked is false when the monitors could not be covered without hiding
+// them from capture too. Until is when the lock ends by itself (Unix
+// seconds) and Combo the local user's emergency release keys.
+type LocalLockState struct {
+	Type     string `json:"type"` // "local_lock_state"
+	Enabled  bool   `json:"enabled"`
+	Blanked  bool   `json:"blanked,omitempty"`
+	Until    int64  `json:"until,omitempty"`
+	Combo    string `json:"combo,omitempty"`
+	Released string `json:"released,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LockTimeout returns the lock's duration in seconds after defaults and
+// the cap apply.
+func (l LocalLock) LockTimeout() int {
+	switch {
+	case l.TimeoutSec <= 0:
+		return LocalLockDefaultTimeout
+	case l.TimeoutSec > LocalLockMaxTimeout:
+		return LocalLockMaxTimeout
+	}
+	return l.TimeoutSec
+}
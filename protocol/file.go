@@ -0,0 +1,106 @@
+package protocol
+
+// File channel ops ("op" field). Paths are agent-side paths; the agent
+// sanitizes them and refuses protected system locations.
+const (
+	OpList   = "list"
+	OpDrives = "drives"
+	OpGet    = "get"
+	OpPut    = "put"
+	OpMkdir  = "mkdir"
+	OpRm     = "rm"
+	OpMv     = "mv"
+	OpClip   = "clip"       // Flattened list of the clipboard's files
+	OpStage  = "clip_stage" // New folder to upload clipboard files into
+	OpAck    = "ack"
+	OpErr    = "err"
+)
+
+// FileRequest is a request on the file channel. Fid identifies a transfer
+// and Off resumes a download at a byte offset.
+type FileRequest struct {
+	Op     string `json:"op"`
+	Path   string `json:"path,omitempty"`
+	Target string `json:"target,omitempty"` // mv destination
+	Fid    int    `json:"fid,omitempty"`
+	Off    int64  `json:"off,omitempty"`
+}
+
+// FileChunk is one chunk of a transfer in either direction ("put"). Data is
+// raw bytes (base64 in JSON); C is the chunk index and T the chunk total.
+type FileChunk struct {
+	Op   string `json:"op"` // "put"
+	Path string `json:"path"`
+	Fid  int    `json:"fid"`
+	C    int    `json:"c"`
+	T    int    `json:"t"`
+	Size int64  `json:"size"`
+	Data []byte `json:"data"`
+}
+
+// FileEntry is one entry of a list or drives reply.
+type FileEntry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	IsDir bool   `json:"dir"`
+	Size  int64  `json:"size"`
+	Mod   int64  `json:"mod"`
+}
+
+// FileListing answers "list" and "drives".
+type FileListing struct {
+	Op      string      `json:"op"`
+	Path    string      `json:"path,omitempty"`
+	Entries []FileEntry `json:"entries"`
+}
+
+// ClipFile is one file of a "clip" reply. Rel is the path below the
+// copied item, using forward slashes.
+type ClipFile struct {
+	Rel  string `json:"rel"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// ClipListing answers "clip" and "clip_stage" (Path is the new folder).
+type ClipListing struct {
+	Op    string     `json:"op"`
+	Fid   int        `json:"fid"`
+	Path  string     `json:"path,omitempty"`
+	Files []ClipFile `json:"files,omitempty"`
+	Total int64      `json:"total,omitempty"`
+}
+
+// FileAck acknowledges a completed op or, with C set, progress of a put.
+type FileAck struct {
+	Op     string `json:"op"` // "ack"
+	Path   string `json:"path,omitempty"`
+	Target string `json:"target,omitempty"`
+	Fid    int    `json:"fid,omitempty"`
+	C      int    `json:"c,omitempty"`
+}
+
+// FileError reports a failed file op.
+type FileError struct {
+	Op    string `json:"op"` // "err"
+	Error string `json:"error"`
+}
+
+// Legacy file transfer messages ("type" field), kept for the dashboard.
+const (
+	TypeFileTransferStart    = "file_transfer_start"
+	TypeFileChunk            = "file_chunk"
+	TypeFileTransferComplete = "file_transfer_complete"
+	TypeFileTransferError    = "file_transfer_error"
+)
+
+// FileTransfer is a legacy transfer message. Data is base64 for
+// file_chunk; Filename and Size are set on file_transfer_start.
+type FileTransfer struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Filename string `json:"filename,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
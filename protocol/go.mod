@@ -0,0 +1,3 @@
+module github.com/stangtennis/Remote/protocol
+
+go 1.21
@@ -0,0 +1,195 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Handshake message types.
+const (
+	TypeHello       = "hello"
+	TypeUnsupported = "unsupported"
+)
+
+// Peer roles announced in Hello.
+const (
+	RoleAgent      = "agent"
+	RoleController = "controller"
+	RoleCLI        = "cli"
+	RoleDashboard  = "dashboard"
+)
+
+// Capabilities. A capability names a feature the sender implements; a
+// feature is used only when both sides announce it.
+const (
+	CapInput           = "input"            // mouse_move / mouse_click / mouse_scroll / key
+	CapInputChar       = "input.char"       // key events carrying "char" (Unicode typing)
+	CapInputWheel      = "input.wheel"      // mouse_scroll dx/dy (horizontal, high resolution)
+	CapInputTouch      = "input.touch"      // touch frames
+	CapInputPen        = "input.pen"        // pen samples with pressure/tilt
+	CapKeyLayout       = "input.layout"     // key scan/key translation + keyboard_layout
+	CapClipboardText   = "clipboard.text"   // clipboard_text
+	CapClipboardImage  = "clipboard.image"  // clipboard_image (base64 PNG)
+	CapClipboardFiles  = "clipboard.files"  // clipboard_files + file channel clip/clip_stage
+	CapClipboardRich   = "clipboard.rich"   // clipboard_html / clipboard_rtf
+	CapClipboardPolicy = "clipboard.policy" // clipboard_policy
+	CapH264            = "video.h264"       // set_mode h264/hybrid + codec_status
+	CapStreamParams    = "video.params"     // set_stream_params
+	CapStreamPause     = "video.pause"      // stream_pause / stream_resume
+	CapMonitors        = "monitors"         // monitor_list / switch_monitor
+	CapMonitorSpan     = "monitors.span"    // switch_monitor index AllMonitors (spanning desktop)
+	CapRemoteLogin     = "remote_login"     // remote_login at the Windows logon screen
+	CapForceUpdate     = "force_update"     // force_update + update_status
+	CapSafeModeRestart = "safe_mode"        // restart_safe_mode + restart_status
+	CapICERestart      = "ice_restart"      // ice_restart_offer / ice_restart_answer
+	CapFiles           = "files"            // file channel (list/drives/get/put/mkdir/rm/mv)
+	CapShell           = "shell"            // shell channel exec/kill
+	CapScripts         = "shell.scripts"    // shell channel run_script (signed library)
+	CapProcess         = "process"          // process channel ps/kill/sysinfo
+	CapStats           = "process.stats"    // process channel stats timeline
+	CapRegistry        = "process.registry" // process channel reg_* ops
+	CapServices        = "process.services" // process channel svc_* ops
+	CapLogs            = "process.logs"     // process channel log_query/log_stop
+	CapPrint           = "print"            // print channel (virtual printer jobs)
+	CapLocalLock       = "local_lock"       // local_lock / local_lock_state
+)
+
+// LegacyCapabilities is what a peer that predates the handshake supports.
+// Everything added after the handshake must be announced explicitly.
+var LegacyCapabilities = []string{
+	CapInput, CapInputChar,
+	CapClipboardText, CapClipboardImage,
+	CapH264, CapStreamParams, CapStreamPause,
+	CapMonitors, CapRemoteLogin, CapForceUpdate,
+	CapFiles, CapShell, CapProcess,
+}
+
+// Hello opens a session: the controller sends it when the control channel
+// opens and the agent answers with its own.
+type Hello struct {
+	Type         string   `json:"type"` // "hello"
+	Version      int      `json:"protocol_version"`
+	MinVersion   int      `json:"min_version"`
+	Role         string   `json:"role"`
+	AppVersion   string   `json:"app_version,omitempty"`
+	OS           string   `json:"os,omitempty"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// NewHello builds this build's Hello.
+func NewHello(role, appVersion, goos string, caps []string) Hello {
+	return Hello{
+		Type:         TypeHello,
+		Version:      Version,
+		MinVersion:   MinVersion,
+		Role:         role,
+		AppVersion:   appVersion,
+		OS:           goos,
+		Capabilities: caps,
+	}
+}
+
+// Unsupported is sent back for a message type the receiver does not handle,
+// so the sender can report it instead of waiting for a reply that never
+// comes. Only sent to peers that completed the handshake.
+type Unsupported struct {
+	Type    string `json:"type"` // "unsupported"
+	MsgType string `json:"msg_type"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// NewUnsupported builds an Unsupported reply for msgType.
+func NewUnsupported(msgType, reason string) Unsupported {
+	return Unsupported{Type: TypeUnsupported, MsgType: msgType, Reason: reason}
+}
+
+// Session is the outcome of a handshake.
+type Session struct {
+	Version    int    // negotiated version, 0 for a legacy peer
+	Legacy     bool   // peer never sent Hello
+	PeerRole   string // role announced by the peer
+	PeerApp    string // peer application version, if announced
+	PeerOS     string
+	caps       map[string]bool
+	mismatched bool
+}
+
+// Negotiate combines the local and the peer Hello. The negotiated version
+// is the highest both sides speak. When the ranges do not overlap the
+// returned session is a legacy session (only LegacyCapabilities) together
+// with an error describing the mismatch, so the caller can warn and carry
+// on with the basic feature set instead of failing the connection.
+func Negotiate(local, peer Hello) (Session, error) {
+	v := local.Version
+	if peer.Version < v {
+		v = peer.Version
+	}
+	if v < local.MinVersion || v < peer.MinVersion || v < 1 {
+		s := LegacySession()
+		s.Legacy = false
+		s.mismatched = true
+		s.PeerRole, s.PeerApp, s.PeerOS = peer.Role, peer.AppVersion, peer.OS
+		return s, fmt.Errorf("protocol version mismatch: local %d (min %d), peer %d (min %d)",
+			local.Version, local.MinVersion, peer.Version, peer.MinVersion)
+	}
+
+	peerCaps := make(map[string]bool, len(peer.Capabilities))
+	for _, c := range peer.Capabilities {
+		peerCaps[normalizeCap(c)] = true
+	}
+	caps := make(map[string]bool)
+	for _, c := range local.Capabilities {
+		if c = normalizeCap(c); peerCaps[c] {
+			caps[c] = true
+		}
+	}
+	return Session{
+		Version:  v,
+		PeerRole: peer.Role,
+		PeerApp:  peer.AppVersion,
+		PeerOS:   peer.OS,
+		caps:     caps,
+	}, nil
+}
+
+// LegacySession is the session used for a peer that never sent Hello.
+func LegacySession() Session {
+	caps := make(map[string]bool, len(LegacyCapabilities))
+	for _, c := range LegacyCapabilities {
+		caps[c] = true
+	}
+	return Session{Legacy: true, caps: caps}
+}
+
+// Has reports whether both sides support capability c.
+func (s Session) Has(c string) bool {
+	return s.caps[normalizeCap(c)]
+}
+
+// Handshaken reports whether the peer completed the handshake with an
+// overlapping version; only such peers get Unsupported replies.
+func (s Session) Handshaken() bool {
+	return !s.Legacy && !s.mismatched && s.Version >= 1
+}
+
+// Capabilities returns the shared capability set, sorted.
+func (s Session) Capabilities() []string {
+	out := make([]string, 0, len(s.caps))
+	for c := range s.caps {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Require returns an error naming the peer when capability c is missing.
+func (s Session) Require(c string) error {
+	if s.Has(c) {
+		return nil
+	}
+	peer := s.PeerApp
+	if peer == "" {
+		peer = "an older build"
+	}
+	return fmt.Errorf("remote peer (%s) does not support %s; update it to use this feature", peer, c)
+}
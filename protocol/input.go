@@ -0,0 +1,177 @@
+package protocol
+
+import "math"
+
+// Input event types ("t" field).
+const (
+	TypeMouseMove   = "mouse_move"
+	TypeMouseClick  = "mouse_click"
+	TypeMouseScroll = "mouse_scroll"
+	TypeKey         = "key"
+	TypeTouch       = "touch"
+	TypePen         = "pen"
+	TypePing        = "ping"
+	TypePong        = "pong"
+)
+
+// MouseMove moves the pointer. With Rel set, X and Y are normalized to the
+// captured screen (0.0-1.0); otherwise they are pixels.
+type MouseMove struct {
+	T   string  `json:"t"`
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Rel bool    `json:"rel,omitempty"`
+}
+
+// MouseClick presses or releases a button, optionally moving first.
+type MouseClick struct {
+	T      string   `json:"t"`
+	Button string   `json:"button"` // left | right | middle
+	Down   bool     `json:"down"`
+	X      *float64 `json:"x,omitempty"`
+	Y      *float64 `json:"y,omitempty"`
+	Rel    bool     `json:"rel,omitempty"`
+}
+
+// WheelDelta is one notch of a classic mouse wheel in DX/DY units.
+const WheelDelta = 120
+
+// MouseScroll scrolls the wheel; positive Delta scrolls up, in notches.
+// Agents announcing CapInputWheel use DX/DY instead when either is set:
+// high-resolution deltas in WheelDelta units, positive DY up and positive
+// DX right. Senders keep Delta filled in for older agents.
+type MouseScroll struct {
+	T     string  `json:"t"`
+	Delta float64 `json:"delta"`
+	DX    float64 `json:"dx,omitempty"`
+	DY    float64 `json:"dy,omitempty"`
+}
+
+// Pointer phases for TouchContact and Pen.
+const (
+	PhaseHover  = "hover" // pen in range, not touching
+	PhaseDown   = "down"
+	PhaseMove   = "move"
+	PhaseUp     = "up"
+	PhaseCancel = "cancel"
+)
+
+// Touch is one multi-touch frame. Contacts lists every finger on the
+// surface, including the ones whose Phase is up or cancel in this frame.
+type Touch struct {
+	T        string         `json:"t"`
+	Contacts []TouchContact `json:"contacts"`
+	Rel      bool           `json:"rel,omitempty"`
+}
+
+// TouchContact is one finger. ID stays the same from down to up.
+type TouchContact struct {
+	ID       int     `json:"id"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Phase    string  `json:"phase"`
+	Pressure float64 `json:"pressure,omitempty"` // 0.0-1.0, 0 = not reported
+}
+
+// Pen is one stylus sample. Tilt is in degrees (-90 to 90), Rotation
+// (barrel twist) in degrees (0-359).
+type Pen struct {
+	T        string  `json:"t"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Rel      bool    `json:"rel,omitempty"`
+	Phase    string  `json:"phase"`
+	Pressure float64 `json:"pressure"` // 0.0-1.0
+	TiltX    float64 `json:"tilt_x,omitempty"`
+	TiltY    float64 `json:"tilt_y,omitempty"`
+	Rotation float64 `json:"rotation,omitempty"`
+	Eraser   bool    `json:"eraser,omitempty"`
+	Barrel   bool    `json:"barrel,omitempty"`
+}
+
+// Key presses or releases a key identified by its DOM KeyboardEvent.code.
+// When Char is set on a key-down the agent types it as Unicode, which
+// bypasses the remote keyboard layout.
+//
+// With CapKeyLayout the agent translates instead: Scan is the physical key
+// and Key is what it produces on the controller's layout (DOM
+// KeyboardEvent.key, e.g. "z" with Ctrl held, "Dead" for a dead key, or
+// composed IME text with an empty Code). The agent then presses the key
+// that produces the same character on its own layout, types it as Unicode
+// when no key does, or presses the physical key for everything else.
+type Key struct {
+	T     string `json:"t"`
+	Code  string `json:"code"`
+	Down  bool   `json:"down"`
+	Ctrl  bool   `json:"ctrl"`
+	Shift bool   `json:"shift"`
+	Alt   bool   `json:"alt"`
+	Meta  bool   `json:"meta"`
+	Char  string `json:"char,omitempty"`
+	Scan  uint16 `json:"scan,omitempty"` // See ScanCode
+	Key   string `json:"key,omitempty"`
+}
+
+// KeyDead and KeyProcess are the DOM key values of a dead key and of a
+// keystroke consumed by an IME. Neither produces input by itself: the
+// composed character arrives with a later key event.
+const (
+	KeyDead    = "Dead"
+	KeyProcess = "Process"
+)
+
+// Ping and Pong measure round-trip time; TS is a millisecond timestamp
+// echoed back unchanged.
+type Ping struct {
+	T  string  `json:"t"`
+	TS float64 `json:"ts"`
+}
+
+// InputStatus reports how the agent routed input (direct or through the
+// Session 0 helper pipe). Sent at most every two seconds unless forced.
+type InputStatus struct {
+	Type        string `json:"type"` // "input_status"
+	Event       string `json:"event"`
+	Route       string `json:"route"`
+	Session0    bool   `json:"session0"`
+	Forwarder   bool   `json:"forwarder"`
+	LoginScreen bool   `json:"login_screen"`
+	Events      int64  `json:"events"`
+	Forwarded   int64  `json:"forwarded"`
+	Errors      int64  `json:"errors"`
+	Error       string `json:"error"`
+}
+
+// TypeInputStatus is the type of InputStatus.
+const TypeInputStatus = "input_status"
+
+// NewMouseMove builds a mouse_move event.
+func NewMouseMove(x, y float64, rel bool) MouseMove {
+	return MouseMove{T: TypeMouseMove, X: x, Y: y, Rel: rel}
+}
+
+// NewMouseClick builds a mouse_click event at x, y.
+func NewMouseClick(button string, down bool, x, y float64, rel bool) MouseClick {
+	return MouseClick{T: TypeMouseClick, Button: button, Down: down, X: &x, Y: &y, Rel: rel}
+}
+
+// NewMouseScroll builds a mouse_scroll event.
+func NewMouseScroll(delta float64) MouseScroll {
+	return MouseScroll{T: TypeMouseScroll, Delta: delta}
+}
+
+// NewWheel builds a high-resolution mouse_scroll event. Delta carries the
+// vertical part in whole notches (at least one) for agents without
+// CapInputWheel; they cannot scroll horizontally.
+func NewWheel(dx, dy float64) MouseScroll {
+	delta := math.Round(dy / WheelDelta)
+	if delta == 0 && dy != 0 {
+		delta = math.Copysign(1, dy)
+	}
+	return MouseScroll{T: TypeMouseScroll, Delta: delta, DX: dx, DY: dy}
+}
+
+// NewKey builds a key event without a Unicode character.
+func NewKey(code string, down, ctrl, shift, alt, meta bool) Key {
+	return Key{T: TypeKey, Code: code, Down: down, Ctrl: ctrl, Shift: shift, Alt: alt, Meta: meta, Scan: ScanCode(code)}
+}
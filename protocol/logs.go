@@ -0,0 +1,107 @@
+package protocol
+
+import "encoding/json"
+
+// Log ops on the process channel (CapLogs): the Windows Event Log, the macOS
+// unified log and the systemd journal on Linux. A log_query answers with
+// log_query_result messages (More set on all but the last); with Follow set
+// the agent then keeps sending log_entries for new entries until log_stop
+// with the same ID or the channel closes. Every reply carries the request's
+// ID so several queries can share the channel.
+const (
+	OpLogQuery       = "log_query"
+	OpLogQueryResult = "log_query_result"
+	OpLogEntries     = "log_entries"
+	OpLogStop        = "log_stop"
+)
+
+// Log levels, most severe first. A request's Level is the least severe one
+// wanted: "warning" matches critical, error and warning entries.
+const (
+	LogCritical = "critical"
+	LogError    = "error"
+	LogWarning  = "warning"
+	LogInfo     = "info"
+	LogDebug    = "debug"
+)
+
+// Log query limits: entries returned when the request has no Limit, and the
+// most it may ask for.
+const (
+	LogDefaultLimit = 100
+	LogMaxLimit     = 1000
+)
+
+// MaxLogMessageBytes is the longest message text sent; longer ones are cut.
+const MaxLogMessageBytes = 4 * 1024
+
+// LogRequest is a log_query or log_stop. Channel names the Windows event
+// logs to read, comma separated (default System and Application) and is
+// ignored elsewhere. Source matches the event provider on Windows, the
+// subsystem or process on macOS and the unit or syslog identifier on Linux.
+// Text is a case-insensitive substring of the message. SinceSec and
+// UntilSec bound the entries' age in seconds, measured on the agent's
+// clock so the two clocks need not agree.
+type LogRequest struct {
+	Op       string  `json:"op"`
+	ID       string  `json:"id,omitempty"`
+	Channel  string  `json:"channel,omitempty"`
+	Source   string  `json:"source,omitempty"`
+	Level    string  `json:"level,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	SinceSec float64 `json:"since_sec,omitempty"`
+	UntilSec float64 `json:"until_sec,omitempty"`
+	Limit    int     `json:"limit,omitempty"`
+	Follow   bool    `json:"follow,omitempty"`
+}
+
+// LogEntry is one log entry. Time is RFC 3339 in UTC on the agent's clock;
+// Channel is the Windows event log, the macOS category or the systemd unit.
+type LogEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Source  string `json:"source,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	EventID int    `json:"event_id,omitempty"`
+	PID     int    `json:"pid,omitempty"`
+	Message string `json:"message"`
+}
+
+// LogQueryResult answers log_query with the newest matching entries, oldest
+// first.
+type LogQueryResult struct {
+	Op      string     `json:"op"` // "log_query_result"
+	ID      string     `json:"id,omitempty"`
+	Entries []LogEntry `json:"entries"`
+	More    bool       `json:"more,omitempty"`
+}
+
+// LogEntries carries new entries of a followed query. Dropped counts
+// entries skipped because they arrived faster than the channel carries
+// them. Done marks the last message, with Error set if following failed.
+type LogEntries struct {
+	Op      string     `json:"op"` // "log_entries"
+	ID      string     `json:"id"`
+	Entries []LogEntry `json:"entries,omitempty"`
+	Dropped int        `json:"dropped,omitempty"`
+	Done    bool       `json:"done,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// SplitLogEntries splits entries into runs whose JSON stays below
+// RegChunkBytes, so each fits in one message. Entries are assumed to be
+// capped at MaxLogMessageBytes. No entries yield one empty run.
+func SplitLogEntries(entries []LogEntry) [][]LogEntry {
+	runs := [][]LogEntry{}
+	start, size := 0, 0
+	for i, e := range entries {
+		data, _ := json.Marshal(e)
+		// Leave 1 KB for the envelope around the entries.
+		if size+len(data)+1 > RegChunkBytes-1024 && i > start {
+			runs = append(runs, entries[start:i])
+			start, size = i, 0
+		}
+		size += len(data) + 1
+	}
+	return append(runs, entries[start:])
+}
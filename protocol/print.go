@@ -0,0 +1,72 @@
+package protocol
+
+// Print channel ("print") ops ("op" field). The agent's virtual printer
+// captures jobs; the controller opts in per session and decides per job.
+//
+//	→ print_enable | print_disable   ← print_state
+//	← print_job                      (offered on enable and for each new job)
+//	→ print_accept                   ← print_data* (T chunks)
+//	→ print_decline                  (job is dropped)
+//	→ print_done                     (printed or saved, or Error)
+//	← error                          (request refused)
+const (
+	OpPrintEnable  = "print_enable"
+	OpPrintDisable = "print_disable"
+	OpPrintState   = "print_state"
+	OpPrintJob     = "print_job"
+	OpPrintAccept  = "print_accept"
+	OpPrintDecline = "print_decline"
+	OpPrintData    = "print_data"
+	OpPrintDone    = "print_done"
+)
+
+// Print job formats, detected from the spooled data.
+const (
+	PrintFormatPDF = "pdf"
+	PrintFormatPS  = "ps"
+	PrintFormatXPS = "xps"
+	PrintFormatRaw = "raw" // Printer-native data (PCL and the like)
+)
+
+// PrintChunkSize is the raw size of a print_data chunk; base64 keeps the
+// message under the 64KB data channel limit.
+const PrintChunkSize = 45000
+
+// PrintRequest is a controller request on the print channel. ID names the
+// job for accept/decline/done; Error reports a failed print_done.
+type PrintRequest struct {
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// PrintState answers print_enable and print_disable. Installed is false
+// when the agent's virtual printer is not set up.
+type PrintState struct {
+	Op        string `json:"op"` // "print_state"
+	Enabled   bool   `json:"enabled"`
+	Installed bool   `json:"installed"`
+	Printer   string `json:"printer,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PrintJob offers a captured job.
+type PrintJob struct {
+	Op      string `json:"op"` // "print_job"
+	ID      string `json:"id"`
+	Title   string `json:"title,omitempty"`
+	User    string `json:"user,omitempty"`
+	Format  string `json:"format"`
+	Size    int64  `json:"size"`
+	Created int64  `json:"created"` // Unix seconds
+}
+
+// PrintChunk is one chunk of an accepted job. C is the chunk index and T
+// the chunk total.
+type PrintChunk struct {
+	Op   string `json:"op"` // "print_data"
+	ID   string `json:"id"`
+	C    int    `json:"c"`
+	T    int    `json:"t"`
+	Data []byte `json:"data"`
+}
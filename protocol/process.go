@@ -0,0 +1,89 @@
+package protocol
+
+import "encoding/json"
+
+// Process channel ops ("op" field). Every request is answered by
+// "<op>_result" or by "error".
+const (
+	OpPs            = "ps"
+	OpSysinfo       = "sysinfo"
+	OpStats         = "stats"
+	OpPsResult      = "ps_result"
+	OpKillResult    = "kill_result"
+	OpSysinfoResult = "sysinfo_result"
+	OpStatsResult   = "stats_result"
+)
+
+// ProcessRequest is a request on the process channel. PID is used by kill,
+// SinceSec by stats (0 = whole session).
+type ProcessRequest struct {
+	Op       string  `json:"op"`
+	PID      int     `json:"pid,omitempty"`
+	SinceSec float64 `json:"since_sec,omitempty"`
+}
+
+// Process is one entry of PsResult.
+type Process struct {
+	PID      int     `json:"pid"`
+	Name     string  `json:"name"`
+	CPU      float64 `json:"cpu"`
+	MemoryMB float64 `json:"memory_mb"`
+	User     string  `json:"user"`
+}
+
+// PsResult answers ps.
+type PsResult struct {
+	Op        string    `json:"op"` // "ps_result"
+	Processes []Process `json:"processes"`
+	Count     int       `json:"count"`
+}
+
+// KillResult answers kill.
+type KillResult struct {
+	Op    string `json:"op"` // "kill_result"
+	PID   int    `json:"pid"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Disk is one volume in SysinfoResult.
+type Disk struct {
+	Mount   string  `json:"mount"`
+	TotalGB float64 `json:"total_gb"`
+	FreeGB  float64 `json:"free_gb"`
+}
+
+// App is one installed application in SysinfoResult.
+type App struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+}
+
+// SysinfoResult answers sysinfo.
+type SysinfoResult struct {
+	Op            string  `json:"op"` // "sysinfo_result"
+	OS            string  `json:"os"`
+	Hostname      string  `json:"hostname"`
+	CPU           string  `json:"cpu"`
+	CPUCores      int     `json:"cpu_cores"`
+	RAMTotalGB    float64 `json:"ram_total_gb"`
+	RAMFreeGB     float64 `json:"ram_free_gb"`
+	Disks         []Disk  `json:"disks"`
+	UptimeSec     int64   `json:"uptime_sec"`
+	InstalledApps []App   `json:"installed_apps,omitempty"`
+}
+
+// StatsResult answers stats. The timeline layout belongs to the agent's
+// stats recorder and is passed through untouched.
+type StatsResult struct {
+	Op         string          `json:"op"` // "stats_result"
+	Timeline   json.RawMessage `json:"timeline"`
+	SampleStep int             `json:"sample_step"`
+}
+
+// ProcessError reports a failed process op.
+type ProcessError struct {
+	Op    string `json:"op"` // "error"
+	Error string `json:"error"`
+}
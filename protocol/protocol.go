@@ -0,0 +1,102 @@
+// Package protocol defines the messages exchanged over the agent's WebRTC
+// data channels. The agent, the controller and the CLI import it so every
+// message shape is declared once instead of being rebuilt as ad-hoc maps on
+// each side.
+//
+// All messages are JSON objects. Input events carry their type in "t" (kept
+// short because mouse moves are sent at pointer rate), control-plane messages
+// use "type", and request/response channels (file, shell, process) use "op".
+//
+// Compatibility: a peer opens the control channel by sending Hello. The
+// other side answers with its own Hello and both sides use Negotiate to pick
+// a version and the shared capability set. A peer that never sends Hello is
+// a pre-handshake build and is treated as LegacySession, so features newer
+// than that can be refused up front instead of being silently ignored.
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Version is the protocol version spoken by this build. Bump it when a
+// message changes incompatibly; additive fields only need a capability.
+const Version = 1
+
+// MinVersion is the oldest peer version this build still talks to natively.
+const MinVersion = 1
+
+// Data channel labels.
+const (
+	ChannelControl  = "control"
+	ChannelData     = "data"
+	ChannelVideo    = "video"
+	ChannelFile     = "file"
+	ChannelShell    = "shell"
+	ChannelProcess  = "process"
+	ChannelTerminal = "terminal"
+	ChannelChat     = "chat"
+)
+
+// header holds the discriminator fields of any message. "t" is raw because
+// file "put" chunks reuse it for the chunk total.
+type header struct {
+	Type string          `json:"type"`
+	Op   string          `json:"op"`
+	T    json.RawMessage `json:"t"`
+}
+
+// MsgType returns the message type of a raw JSON message: "type", then
+// "op", then the input shorthand "t". It returns "" for non-JSON data.
+func MsgType(data []byte) string {
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return ""
+	}
+	if h.Type != "" {
+		return h.Type
+	}
+	if h.Op != "" {
+		return h.Op
+	}
+	var t string
+	if len(h.T) > 0 && json.Unmarshal(h.T, &t) == nil {
+		return t
+	}
+	return ""
+}
+
+// TypeOf is MsgType for a message that is already decoded into a map.
+func TypeOf(m map[string]interface{}) string {
+	for _, key := range []string{"type", "op", "t"} {
+		if v, ok := m[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// FromMap decodes a generic message map into one of the typed messages.
+func FromMap(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Encode marshals a message. It exists so call sites read symmetrically
+// with Decode.
+func Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode unmarshals a raw message into one of the typed messages.
+func Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// normalizeCap lower-cases and trims a capability name.
+func normalizeCap(c string) string {
+	return strings.ToLower(strings.TrimSpace(c))
+}
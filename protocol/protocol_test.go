@@ -0,0 +1,245 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMsgType(t *testing.T) {
+	cases := map[string]string{
+		`{"type":"hello","protocol_version":1}`: "hello",
+		`{"t":"mouse_move","x":1,"y":2}`:        "mouse_move",
+		`{"op":"put","t":12,"c":3}`:             "put",
+		`{"op":"exec","id":"a"}`:                "exec",
+		`{"x":1}`:                               "",
+		`not json`:                              "",
+	}
+	for in, want := range cases {
+		if got := MsgType([]byte(in)); got != want {
+			t.Errorf("MsgType(%s) = %q, want %q", in, got, want)
+		}
+		var m map[string]interface{}
+		if json.Unmarshal([]byte(in), &m) == nil {
+			if got := TypeOf(m); got != want {
+				t.Errorf("TypeOf(%s) = %q, want %q", in, got, want)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	local := NewHello(RoleController, "v1", "linux", []string{CapInput, CapScripts, CapICERestart})
+	peer := NewHello(RoleAgent, "v2", "windows", []string{CapInput, "SHELL.SCRIPTS"})
+	peer.Version = 3
+	peer.MinVersion = 1
+
+	s, err := Negotiate(local, peer)
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if s.Version != Version || !s.Handshaken() {
+		t.Fatalf("version = %d handshaken = %v", s.Version, s.Handshaken())
+	}
+	if !s.Has(CapInput) || !s.Has(CapScripts) || s.Has(CapICERestart) {
+		t.Fatalf("capabilities = %v", s.Capabilities())
+	}
+	if s.Require(CapICERestart) == nil {
+		t.Fatal("Require should fail for a capability the peer lacks")
+	}
+
+	peer.MinVersion = Version + 1
+	peer.Version = Version + 1
+	s, err = Negotiate(local, peer)
+	if err == nil {
+		t.Fatal("expected a version mismatch error")
+	}
+	if s.Handshaken() || !s.Has(CapInput) || s.Has(CapScripts) {
+		t.Fatalf("mismatch should fall back to legacy capabilities, got %v", s.Capabilities())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	click := NewMouseClick("left", true, 10, 20, false)
+	data, err := Encode(click)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	var back MouseClick
+	if err := FromMap(m, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.T != TypeMouseClick || back.X == nil || *back.X != 10 || *back.Y != 20 || !back.Down {
+		t.Fatalf("round trip mismatch: %+v", back)
+	}
+
+	var noPos MouseClick
+	if err := Decode([]byte(`{"t":"mouse_click","button":"right","down":false}`), &noPos); err != nil {
+		t.Fatal(err)
+	}
+	if noPos.X != nil {
+		t.Fatal("click without coordinates must leave X nil")
+	}
+}
+
+func TestNewWheel(t *testing.T) {
+	tests := []struct {
+		dx, dy    float64
+		wantDelta float64
+	}{
+		{0, 240, 2},
+		{0, -360, -3},
+		{0, 15, 1},   // a trackpad sliver still moves legacy agents one notch
+		{0, -15, -1}, // in either direction
+		{120, 0, 0},  // horizontal-only has no legacy equivalent
+	}
+	for _, tt := range tests {
+		got := NewWheel(tt.dx, tt.dy)
+		if got.T != TypeMouseScroll || got.Delta != tt.wantDelta || got.DX != tt.dx || got.DY != tt.dy {
+			t.Errorf("NewWheel(%v, %v) = %+v, want delta %v", tt.dx, tt.dy, got, tt.wantDelta)
+		}
+	}
+
+	data, _ := Encode(NewMouseScroll(-1))
+	if string(data) != `{"t":"mouse_scroll","delta":-1}` {
+		t.Errorf("legacy scroll must not carry dx/dy: %s", data)
+	}
+}
+
+func TestScanCode(t *testing.T) {
+	tests := map[string]uint16{
+		"KeyA":         0x1E,
+		"KeyZ":         0x2C,
+		"Digit0":       0x0B,
+		"ControlRight": 0xE01D,
+		"ArrowLeft":    0xE04B,
+		"Unknown":      0,
+	}
+	for code, want := range tests {
+		if got := ScanCode(code); got != want {
+			t.Errorf("ScanCode(%q) = %#x, want %#x", code, got, want)
+		}
+	}
+	if k := NewKey("KeyQ", true, true, false, false, false); k.Scan != 0x10 {
+		t.Errorf("NewKey must fill Scan from Code, got %#x", k.Scan)
+	}
+}
+
+func TestClipboardPolicy(t *testing.T) {
+	open := ClipboardPolicy{}
+	if !open.Allows(ClipboardFormatHTML, true, 1<<30) || !open.Allows(ClipboardFormatText, false, 1) {
+		t.Fatal("zero policy must allow everything")
+	}
+
+	support := ClipboardPolicy{Direction: ClipboardToAgent, MaxBytes: 1 << 20, Formats: []string{ClipboardFormatText, ClipboardFormatHTML}}
+	if support.Allows(ClipboardFormatText, false, 10) {
+		t.Fatal("to_agent must block copies from the agent")
+	}
+	if support.Allows(ClipboardFormatImage, true, 10) || support.Allows(ClipboardFormatText, true, 2<<20) {
+		t.Fatal("format allow-list and size limit must apply")
+	}
+
+	got := support.Narrow(ClipboardPolicy{Direction: ClipboardBoth, MaxBytes: 4 << 20, Formats: []string{ClipboardFormatHTML, ClipboardFormatRTF}})
+	if got.Direction != ClipboardToAgent || got.MaxBytes != 1<<20 || len(got.Formats) != 1 || got.Formats[0] != ClipboardFormatHTML {
+		t.Fatalf("narrow = %+v", got)
+	}
+	if got := support.Narrow(ClipboardPolicy{Direction: ClipboardFromAgent}); got.Direction != ClipboardOff {
+		t.Fatalf("opposite directions must narrow to off, got %q", got.Direction)
+	}
+	if got := support.Narrow(ClipboardPolicy{Formats: []string{ClipboardFormatFiles}}); got.Allows(ClipboardFormatFiles, true, 1) {
+		t.Fatal("disjoint formats must allow nothing")
+	}
+
+	if err := (ClipboardPolicy{Direction: "sideways"}).Validate(); err == nil {
+		t.Fatal("unknown direction must not validate")
+	}
+	if err := (ClipboardPolicy{Formats: []string{"pdf"}}).Validate(); err == nil {
+		t.Fatal("unknown format must not validate")
+	}
+}
+
+func TestPrintChunkFits(t *testing.T) {
+	chunk := PrintChunk{Op: OpPrintData, ID: "job-0123456789abcdef", C: 999, T: 1000, Data: make([]byte, PrintChunkSize)}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) >= 64*1024 {
+		t.Fatalf("full print chunk is %d bytes, over the data channel limit", len(data))
+	}
+}
+
+func TestLocalLockTimeout(t *testing.T) {
+	cases := map[int]int{
+		0:                       LocalLockDefaultTimeout,
+		-5:                      LocalLockDefaultTimeout,
+		90:                      90,
+		LocalLockMaxTimeout + 1: LocalLockMaxTimeout,
+	}
+	for in, want := range cases {
+		if got := (LocalLock{TimeoutSec: in}).LockTimeout(); got != want {
+			t.Errorf("LockTimeout(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestSplitRegChunks(t *testing.T) {
+	s := strings.Repeat("å", RegChunkBytes) // 2 bytes per rune
+	chunks := SplitRegChunks(s)
+	if len(chunks) != 2 || strings.Join(chunks, "") != s {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c) > RegChunkBytes || !utf8.ValidString(c) {
+			t.Errorf("bad chunk of %d bytes", len(c))
+		}
+	}
+	if got := SplitRegChunks(""); len(got) != 1 || got[0] != "" {
+		t.Errorf("empty input = %q", got)
+	}
+}
+
+func TestSplitLogEntries(t *testing.T) {
+	entries := make([]LogEntry, 40)
+	for i := range entries {
+		entries[i] = LogEntry{Time: "2026-10-19T10:00:00Z", Level: LogInfo, Message: strings.Repeat("x", MaxLogMessageBytes)}
+	}
+	runs := SplitLogEntries(entries)
+	total := 0
+	for _, run := range runs {
+		data, _ := json.Marshal(LogQueryResult{Op: OpLogQueryResult, ID: "0123456789abcdef", Entries: run, More: true})
+		if len(data) > RegChunkBytes {
+			t.Errorf("run of %d entries is %d bytes", len(run), len(data))
+		}
+		total += len(run)
+	}
+	if len(runs) < 2 || total != len(entries) {
+		t.Fatalf("got %d runs with %d entries", len(runs), total)
+	}
+	if got := SplitLogEntries(nil); len(got) != 1 || len(got[0]) != 0 {
+		t.Errorf("no entries = %v", got)
+	}
+}
+
+func TestBridgeDecode(t *testing.T) {
+	var key HelperKey
+	if err := Decode([]byte(`{"type":"key","seq":7,"code":"KeyA","down":true,"ctrl":true}`), &key); err != nil {
+		t.Fatal(err)
+	}
+	if key.Seq != 7 || key.Code != "KeyA" || !key.Down || !key.Ctrl {
+		t.Errorf("helper key = %+v", key)
+	}
+
+	var in NativeInput
+	if err := Decode([]byte(`{"type":"keyboard_press","key":"a","modifiers":{"meta":true}}`), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Key == nil || *in.Key != "a" || !in.Modifiers.Meta || in.Text != nil || in.X != nil {
+		t.Errorf("native input = %+v", in)
+	}
+}
@@ -0,0 +1,112 @@
+package protocol
+
+import "unicode/utf8"
+
+// Registry ops on the process channel (CapRegistry). Paths use the regedit
+// form, e.g. `HKLM\SOFTWARE\Vendor`; on macOS and Linux the agent serves a
+// read-only view instead (`defaults\<domain>`, `/etc/...`).
+const (
+	OpRegList         = "reg_list"
+	OpRegGet          = "reg_get"
+	OpRegSet          = "reg_set"
+	OpRegDelete       = "reg_delete"
+	OpRegExport       = "reg_export"
+	OpRegImport       = "reg_import"
+	OpRegListResult   = "reg_list_result"
+	OpRegGetResult    = "reg_get_result"
+	OpRegSetResult    = "reg_set_result"
+	OpRegDeleteResult = "reg_delete_result"
+	OpRegExportResult = "reg_export_result"
+	OpRegImportResult = "reg_import_result"
+)
+
+// Registry value types. Values travel as JSON: strings for sz/expand_sz,
+// a string list for multi_sz, numbers for dword/qword (a decimal or 0x hex
+// string is accepted too) and hex for binary. "text" is a read-only config
+// file or plist value on macOS/Linux.
+const (
+	RegSZ       = "sz"
+	RegExpandSZ = "expand_sz"
+	RegMultiSZ  = "multi_sz"
+	RegDWord    = "dword"
+	RegQWord    = "qword"
+	RegBinary   = "binary"
+	RegText     = "text"
+)
+
+// RegChunkBytes is the largest .reg text or listing sent in one message;
+// bigger ones are split into messages with More set on all but the last,
+// keeping each below the default SCTP max message size.
+const RegChunkBytes = 48 * 1024
+
+// RegRequest is a registry request. Name "" is the key's default value;
+// reg_delete without Name deletes the key and its subkeys. Data carries
+// the .reg text for reg_import, in RegChunkBytes chunks.
+type RegRequest struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+	Data  string      `json:"data,omitempty"`
+	More  bool        `json:"more,omitempty"`
+}
+
+// RegValue is one named value of a key.
+type RegValue struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// RegListResult answers reg_list with the key's subkeys and values. A
+// value too large for a message has a nil Value; reg_get or reg_export
+// reads it.
+type RegListResult struct {
+	Op       string     `json:"op"` // "reg_list_result"
+	Path     string     `json:"path"`
+	Keys     []string   `json:"keys"`
+	Values   []RegValue `json:"values"`
+	ReadOnly bool       `json:"read_only,omitempty"`
+	More     bool       `json:"more,omitempty"`
+}
+
+// RegGetResult answers reg_get.
+type RegGetResult struct {
+	Op    string   `json:"op"` // "reg_get_result"
+	Path  string   `json:"path"`
+	Value RegValue `json:"value"`
+}
+
+// RegExportResult answers reg_export with the key as .reg text.
+type RegExportResult struct {
+	Op   string `json:"op"` // "reg_export_result"
+	Path string `json:"path"`
+	Data string `json:"data"`
+	More bool   `json:"more,omitempty"`
+}
+
+// RegResult answers reg_set, reg_delete and reg_import. Count is the
+// number of values and keys an import wrote.
+type RegResult struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	OK    bool   `json:"ok"`
+	Count int    `json:"count,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// SplitRegChunks splits s into pieces of at most RegChunkBytes, cutting only
+// at UTF-8 rune boundaries. An empty s yields one empty chunk.
+func SplitRegChunks(s string) []string {
+	chunks := []string{}
+	for len(s) > RegChunkBytes {
+		n := RegChunkBytes
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+	return append(chunks, s)
+}
@@ -0,0 +1,70 @@
+package protocol
+
+// Service manager ops on the process channel (CapServices). Services are
+// named as the OS knows them: the SCM service name on Windows (Spooler),
+// the launchd label on macOS and the systemd unit on Linux (cups.service).
+const (
+	OpSvcList             = "svc_list"
+	OpSvcStart            = "svc_start"
+	OpSvcStop             = "svc_stop"
+	OpSvcRestart          = "svc_restart"
+	OpSvcSetStartup       = "svc_set_startup"
+	OpSvcListResult       = "svc_list_result"
+	OpSvcStartResult      = "svc_start_result"
+	OpSvcStopResult       = "svc_stop_result"
+	OpSvcRestartResult    = "svc_restart_result"
+	OpSvcSetStartupResult = "svc_set_startup_result"
+)
+
+// Service states.
+const (
+	SvcRunning  = "running"
+	SvcStopped  = "stopped"
+	SvcStarting = "starting"
+	SvcStopping = "stopping"
+	SvcPaused   = "paused"
+	SvcFailed   = "failed"
+	SvcUnknown  = "unknown"
+)
+
+// Service startup types. SvcStartupDelayed is Windows only.
+const (
+	SvcStartupAuto     = "auto"
+	SvcStartupDelayed  = "delayed"
+	SvcStartupManual   = "manual"
+	SvcStartupDisabled = "disabled"
+)
+
+// SvcRequest is a service manager request. Startup is used by
+// svc_set_startup.
+type SvcRequest struct {
+	Op      string `json:"op"`
+	Name    string `json:"name,omitempty"`
+	Startup string `json:"startup,omitempty"`
+}
+
+// Service is one entry of SvcListResult.
+type Service struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	State       string `json:"state"`
+	Startup     string `json:"startup,omitempty"`
+	PID         int    `json:"pid,omitempty"`
+}
+
+// SvcListResult answers svc_list.
+type SvcListResult struct {
+	Op       string    `json:"op"` // "svc_list_result"
+	Services []Service `json:"services"`
+	Count    int       `json:"count"`
+}
+
+// SvcResult answers svc_start, svc_stop, svc_restart and svc_set_startup
+// with the service's state afterwards.
+type SvcResult struct {
+	Op      string   `json:"op"`
+	Name    string   `json:"name"`
+	OK      bool     `json:"ok"`
+	Service *Service `json:"service,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
@@ -0,0 +1,57 @@
+package protocol
+
+// Shell channel ops ("op" field).
+//
+//	→ exec | run_script        ← started, stdout*, stderr*, exit
+//	→ kill                     (cancels a running exec by id)
+//	← error                    (request refused before it started)
+const (
+	OpExec      = "exec"
+	OpRunScript = "run_script"
+	OpKill      = "kill"
+	OpStarted   = "started"
+	OpStdout    = "stdout"
+	OpStderr    = "stderr"
+	OpExit      = "exit"
+	OpError     = "error"
+)
+
+// ShellExec runs Cmd on the agent (PowerShell on Windows, the login shell
+// elsewhere). AsUser runs it in the interactive user's session.
+type ShellExec struct {
+	Op         string `json:"op"` // "exec"
+	ID         string `json:"id"`
+	Cmd        string `json:"cmd"`
+	AsUser     bool   `json:"as_user"`
+	TimeoutSec int    `json:"timeout_sec"`
+}
+
+// RunScript runs a signed library script. Script is the signed script row
+// as stored in the scripts table; the agent verifies it before rendering.
+type RunScript struct {
+	Op         string                 `json:"op"` // "run_script"
+	ID         string                 `json:"id"`
+	Script     interface{}            `json:"script"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	AsUser     bool                   `json:"as_user"`
+	TimeoutSec int                    `json:"timeout_sec"`
+}
+
+// ShellKill cancels a running exec.
+type ShellKill struct {
+	Op string `json:"op"` // "kill"
+	ID string `json:"id"`
+}
+
+// ShellEvent is any reply on the shell channel. Which fields are set
+// depends on Op: PID for started, Data for stdout/stderr, Code and
+// DurationMs for exit, Error for exit or error.
+type ShellEvent struct {
+	Op         string `json:"op"`
+	ID         string `json:"id"`
+	PID        int    `json:"pid,omitempty"`
+	Data       string `json:"data,omitempty"`
+	Code       int    `json:"code"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
@@ -0,0 +1,109 @@
+package releasesig
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// maxSmallFile bounds version.json and .sig downloads.
+const maxSmallFile = 1 << 20
+
+// Fetcher downloads release files from the update server.
+type Fetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// FetchVerified downloads the small file at fileURL (version.json) and its
+// signature, and returns the file only when a trusted key signed it.
+func (f Fetcher) FetchVerified(fileURL string, trusted map[string]ed25519.PublicKey) ([]byte, error) {
+	if len(trusted) == 0 {
+		return nil, ErrNoTrustedKeys
+	}
+	name, err := FileName(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.fetchSmall(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	sigData, err := f.fetchSmall(fileURL + SignatureSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signature for %s: %w", name, err)
+	}
+	digest := sha256.Sum256(data)
+	if err := Verify(name, digest[:], sigData, trusted); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyDownload checks the file at filePath, downloaded from fileURL,
+// against the signature at fileURL + SignatureSuffix.
+func (f Fetcher) VerifyDownload(filePath, fileURL string, trusted map[string]ed25519.PublicKey) error {
+	if len(trusted) == 0 {
+		return ErrNoTrustedKeys
+	}
+	name, err := FileName(fileURL)
+	if err != nil {
+		return err
+	}
+	sigData, err := f.fetchSmall(fileURL + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to get signature for %s: %w", name, err)
+	}
+	digest, err := hashFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	return Verify(name, digest, sigData, trusted)
+}
+
+// FileName is the name a release file is signed under: the last element of
+// its URL path.
+func FileName(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid release URL: %w", err)
+	}
+	name := path.Base(u.Path)
+	if name == "" || name == "/" || name == "." {
+		return "", fmt.Errorf("invalid release URL %q", fileURL)
+	}
+	return name, nil
+}
+
+func (f Fetcher) fetchSmall(fileURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSmallFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSmallFile {
+		return nil, errors.New("response too large")
+	}
+	return data, nil
+}
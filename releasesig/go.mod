@@ -0,0 +1,3 @@
+module github.com/stangtennis/Remote/releasesig
+
+go 1.24.0
@@ -0,0 +1,32 @@
+package releasesig
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	_ "embed"
+	"strings"
+)
+
+// keysFile is keys.txt, compiled into every agent and controller.
+//
+//go:embed keys.txt
+var keysFile string
+
+// BuiltinKeys returns the release keys compiled in from keys.txt.
+func BuiltinKeys() map[string]ed25519.PublicKey {
+	return TrustedKeys(parseKeyList(keysFile))
+}
+
+// parseKeyList returns the non-empty, non-comment lines of a key list.
+func parseKeyList(text string) []string {
+	var keys []string
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys
+}
@@ -0,0 +1,160 @@
+// Package releasesig signs and verifies release files. Every file the agent
+// and controller updaters fetch — version.json and each binary — has a
+// detached signature next to it (<file>.sig), made offline with an ed25519
+// key by controller/cmd/sign-release. The update server only hosts the
+// files, so whoever controls it cannot push a binary an updater will
+// accept.
+package releasesig
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureSuffix is appended to a release file's URL to get its
+// signature.
+const SignatureSuffix = ".sig"
+
+const signingContext = "remote-desktop-release-v1\n"
+
+// ErrNoTrustedKeys is returned by Verify when no release key is trusted:
+// the build has none in keys.txt, so every update is refused.
+var ErrNoTrustedKeys = errors.New("no release signing keys built in — refusing update")
+
+// Signature is one key's signature over a release file.
+type Signature struct {
+	KeyID string `json:"key_id"`
+	Sig   string `json:"sig"` // base64 ed25519
+}
+
+// SignatureFile is the content of a <file>.sig.
+type SignatureFile struct {
+	Signatures []Signature `json:"signatures"`
+}
+
+// KeyID returns the short identifier of a public key (first 8 bytes of its
+// SHA-256, hex).
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// SigningPayload is the message signed for a release file: the file name
+// is included so a signed binary cannot be served in place of another.
+func SigningPayload(name string, digest []byte) []byte {
+	return []byte(signingContext + name + "\n" + hex.EncodeToString(digest))
+}
+
+// TrustedKeys builds the key set used by Verify from base64 public keys.
+// Invalid entries are skipped.
+func TrustedKeys(b64Keys []string) map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, k := range b64Keys {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(k))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			continue
+		}
+		pub := ed25519.PublicKey(raw)
+		keys[KeyID(pub)] = pub
+	}
+	return keys
+}
+
+// Verify checks sigData (a SignatureFile) against the SHA-256 digest of
+// the file called name. One valid signature from a trusted key is enough;
+// signatures from unknown keys are ignored, and a bad one from a trusted
+// key only fails the file when no other trusted signature holds.
+func Verify(name string, digest []byte, sigData []byte, trusted map[string]ed25519.PublicKey) error {
+	if len(trusted) == 0 {
+		return ErrNoTrustedKeys
+	}
+	var sf SignatureFile
+	if err := json.Unmarshal(sigData, &sf); err != nil {
+		return fmt.Errorf("invalid signature file for %s: %w", name, err)
+	}
+	if len(sf.Signatures) == 0 {
+		return fmt.Errorf("%s is not signed", name)
+	}
+	payload := SigningPayload(name, digest)
+	var failed error
+	for _, s := range sf.Signatures {
+		pub, ok := trusted[s.KeyID]
+		if !ok {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			failed = fmt.Errorf("invalid signature encoding for %s: %w", name, err)
+			continue
+		}
+		if !ed25519.Verify(pub, payload, sig) {
+			failed = fmt.Errorf("signature verification failed for %s (key %s)", name, s.KeyID)
+			continue
+		}
+		return nil
+	}
+	if failed != nil {
+		return failed
+	}
+	return fmt.Errorf("%s is not signed by a trusted key", name)
+}
+
+// ParsePrivateKey decodes a base64 ed25519 seed or full private key.
+func ParsePrivateKey(b64 string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("invalid private key length %d", len(raw))
+	}
+}
+
+// SignFile signs the file at filePath with every key, for publishing under
+// the file's base name, and returns the content of its .sig file.
+func SignFile(filePath string, keys []ed25519.PrivateKey) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing key")
+	}
+	digest, err := hashFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	payload := SigningPayload(filepath.Base(filePath), digest)
+
+	var sf SignatureFile
+	for _, priv := range keys {
+		sf.Signatures = append(sf.Signatures, Signature{
+			KeyID: KeyID(priv.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+		})
+	}
+	return json.MarshalIndent(sf, "", "  ")
+}
+
+func hashFile(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,147 @@
+package releasesig
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func trust(keys ...ed25519.PrivateKey) map[string]ed25519.PublicKey {
+	m := map[string]ed25519.PublicKey{}
+	for _, k := range keys {
+		pub := k.Public().(ed25519.PublicKey)
+		m[KeyID(pub)] = pub
+	}
+	return m
+}
+
+func TestVerify(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	content := []byte(`{"agent_version":"v3.2.0"}`)
+	file := filepath.Join(t.TempDir(), "version.json")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Signed with both keys, as during a rotation
+	sig, err := SignFile(file, []ed25519.PrivateKey{oldKey, newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(content)
+	tampered := sha256.Sum256([]byte(`{"agent_version":"v9.9.9"}`))
+
+	// A broken signature from one trusted key must not hide a good one
+	// from the other.
+	var sf SignatureFile
+	json.Unmarshal(sig, &sf)
+	sf.Signatures[0].Sig = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+	firstBroken, _ := json.Marshal(sf)
+
+	tests := []struct {
+		name    string
+		file    string
+		digest  []byte
+		sig     []byte
+		trusted map[string]ed25519.PublicKey
+		wantErr bool
+	}{
+		{"old key only", "version.json", digest[:], sig, trust(oldKey), false},
+		{"new key only", "version.json", digest[:], sig, trust(newKey), false},
+		{"first signature broken", "version.json", digest[:], firstBroken, trust(oldKey, newKey), false},
+		{"only trusted signature broken", "version.json", digest[:], firstBroken, trust(oldKey), true},
+		{"untrusted key", "version.json", digest[:], sig, trust(otherKey), true},
+		{"no keys built in", "version.json", digest[:], sig, nil, true},
+		{"tampered content", "version.json", tampered[:], sig, trust(oldKey), true},
+		{"other file name", "remote-agent-v3.2.0.exe", digest[:], sig, trust(oldKey), true},
+		{"unsigned", "version.json", digest[:], []byte(`{"signatures":[]}`), trust(oldKey), true},
+		{"garbage", "version.json", digest[:], []byte("not json"), trust(oldKey), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.file, tt.digest, tt.sig, tt.trusted)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := Verify("version.json", digest[:], sig, nil); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("Verify() without keys = %v, want ErrNoTrustedKeys", err)
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	write := func(name string, data []byte, keys ...ed25519.PrivateKey) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) == 0 {
+			return
+		}
+		sig, err := SignFile(path, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path+SignatureSuffix, sig, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("version.json", []byte(`{"agent_version":"v3.2.0"}`), key)
+	write("forged.json", []byte(`{"agent_version":"v9.9.9"}`), otherKey)
+	write("unsigned.json", []byte(`{}`))
+	write("remote-agent-v3.2.0.exe", []byte("MZ agent"), key)
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	f := Fetcher{Client: srv.Client(), UserAgent: "test"}
+
+	if data, err := f.FetchVerified(srv.URL+"/version.json", trust(key)); err != nil || string(data) != `{"agent_version":"v3.2.0"}` {
+		t.Errorf("FetchVerified(signed) = %q, %v", data, err)
+	}
+	for _, name := range []string{"forged.json", "unsigned.json", "missing.json"} {
+		if _, err := f.FetchVerified(srv.URL+"/"+name, trust(key)); err == nil {
+			t.Errorf("FetchVerified(%s) accepted", name)
+		}
+	}
+	if _, err := f.FetchVerified(srv.URL+"/version.json", nil); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("FetchVerified without keys = %v, want ErrNoTrustedKeys", err)
+	}
+
+	exe := filepath.Join(dir, "remote-agent-v3.2.0.exe")
+	if err := f.VerifyDownload(exe, srv.URL+"/remote-agent-v3.2.0.exe", trust(key)); err != nil {
+		t.Errorf("VerifyDownload(signed) = %v", err)
+	}
+	// The same bytes served under another name carry the wrong signature.
+	if err := f.VerifyDownload(exe, srv.URL+"/version.json", trust(key)); err == nil {
+		t.Error("VerifyDownload accepted a file under another name's signature")
+	}
+	if err := f.VerifyDownload(exe, srv.URL+"/remote-agent-v3.2.0.exe", nil); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("VerifyDownload without keys = %v, want ErrNoTrustedKeys", err)
+	}
+}
+
+func TestParseKeyList(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	b64 := base64.StdEncoding.EncodeToString(pub)
+	text := "# comment\n\n  " + b64 + "  \nnot-a-key\n"
+	keys := TrustedKeys(parseKeyList(text))
+	if len(keys) != 1 || !keys[KeyID(pub)].Equal(pub) {
+		t.Errorf("TrustedKeys(parseKeyList) = %v", keys)
+	}
+	if len(parseKeyList("# only comments\n")) != 0 {
+		t.Error("comment parsed as a key")
+	}
+}
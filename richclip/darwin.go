@@ -0,0 +1,100 @@
+//go:build darwin
+
+package richclip
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+#include <stdlib.h>
+
+// pbReadType returns the pasteboard data for a type (public.html,
+// public.rtf, public.utf8-plain-text), or NULL. *n receives its length.
+static void *pbReadType(const char *type, int *n) {
+    @autoreleasepool {
+        NSData *data = [[NSPasteboard generalPasteboard]
+            dataForType:[NSString stringWithUTF8String:type]];
+        if (data == nil || data.length == 0) {
+            return NULL;
+        }
+        void *buf = malloc(data.length);
+        memcpy(buf, data.bytes, data.length);
+        *n = (int)data.length;
+        return buf;
+    }
+}
+
+// pbWriteRich sets one rich type plus its plain-text alternative.
+static int pbWriteRich(const char *type, const void *data, int n, const char *text) {
+    @autoreleasepool {
+        NSPasteboard *pb = [NSPasteboard generalPasteboard];
+        [pb clearContents];
+        BOOL ok = [pb setData:[NSData dataWithBytes:data length:n]
+                      forType:[NSString stringWithUTF8String:type]];
+        if (ok && text[0] != 0) {
+            [pb setString:[NSString stringWithUTF8String:text] forType:NSPasteboardTypeString];
+        }
+        return ok ? 1 : 0;
+    }
+}
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Uniform type identifiers of the rich formats.
+const (
+	utiHTML = "public.html"
+	utiRTF  = "public.rtf"
+	utiText = "public.utf8-plain-text"
+)
+
+func pasteboardData(uti string) string {
+	ctype := C.CString(uti)
+	defer C.free(unsafe.Pointer(ctype))
+	var n C.int
+	buf := C.pbReadType(ctype, &n)
+	if buf == nil {
+		return ""
+	}
+	defer C.free(buf)
+	if int(n) > MaxBytes {
+		return ""
+	}
+	return string(C.GoBytes(buf, n))
+}
+
+// Read reads HTML and RTF from the general pasteboard plus the plain text
+// copied with them. ok is false when neither is present.
+func Read() (Rich, bool) {
+	r := Rich{HTML: pasteboardData(utiHTML), RTF: pasteboardData(utiRTF)}
+	if r.HTML == "" && r.RTF == "" {
+		return Rich{}, false
+	}
+	r.Text = pasteboardData(utiText)
+	return r, true
+}
+
+// Write sets HTML or RTF (format FormatHTML or FormatRTF) together with
+// its plain-text alternative.
+func Write(format, content, text string) error {
+	uti := utiHTML
+	if format == FormatRTF {
+		uti = utiRTF
+	}
+	if content == "" {
+		return fmt.Errorf("empty %s", format)
+	}
+	ctype := C.CString(uti)
+	defer C.free(unsafe.Pointer(ctype))
+	cdata := C.CBytes([]byte(content))
+	defer C.free(cdata)
+	ctext := C.CString(text)
+	defer C.free(unsafe.Pointer(ctext))
+	if C.pbWriteRich(ctype, cdata, C.int(len(content)), ctext) == 0 {
+		return fmt.Errorf("pasteboard refused %s", format)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+module github.com/stangtennis/Remote/richclip
+
+go 1.24.0
+
+require github.com/stangtennis/Remote/linuxclip v0.0.0
+
+require github.com/jezek/xgb v1.1.1 // indirect
+
+replace github.com/stangtennis/Remote/linuxclip => ../linuxclip
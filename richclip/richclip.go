@@ -0,0 +1,91 @@
+// Package richclip reads and writes rich text on the system clipboard: the
+// formatted copy Office, browsers and mail clients put beside plain text.
+// That is HTML ("HTML Format" / public.html / text/html) and RTF ("Rich Text
+// Format" / public.rtf / text/rtf).
+//
+// macOS and Linux are implemented here, shared by the agent and the
+// controller. Windows callers keep their own clipboard access, which
+// serializes with their other Win32 clipboard writes, and use the CF_HTML
+// helpers below.
+package richclip
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rich formats, named like the protocol's clipboard formats.
+const (
+	FormatHTML = "html"
+	FormatRTF  = "rtf"
+)
+
+// MaxBytes matches the plain text limit.
+const MaxBytes = 10 * 1024 * 1024
+
+// Rich is the formatted content on the clipboard. Text is the plain-text
+// alternative copied alongside it.
+type Rich struct {
+	HTML string
+	RTF  string
+	Text string
+}
+
+// Preferred returns the format to sync: HTML when both are present, as
+// every target that takes RTF also takes HTML. ok is false without either.
+func (r Rich) Preferred() (format, content string, ok bool) {
+	switch {
+	case r.HTML != "":
+		return FormatHTML, r.HTML, true
+	case r.RTF != "":
+		return FormatRTF, r.RTF, true
+	}
+	return "", "", false
+}
+
+// CF_HTML wraps an HTML fragment for the Windows "HTML Format" clipboard
+// format: a header of byte offsets into the UTF-8 data, then the fragment
+// between StartFragment/EndFragment comments.
+const cfHTMLHeader = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+
+// BuildCFHTML wraps an HTML fragment in a CF_HTML document.
+func BuildCFHTML(fragment string) []byte {
+	const prefix = "<html><body>\r\n<!--StartFragment-->"
+	const suffix = "<!--EndFragment-->\r\n</body></html>"
+	headerLen := len(fmt.Sprintf(cfHTMLHeader, 0, 0, 0, 0))
+	startHTML := headerLen
+	startFragment := startHTML + len(prefix)
+	endFragment := startFragment + len(fragment)
+	endHTML := endFragment + len(suffix)
+	return []byte(fmt.Sprintf(cfHTMLHeader, startHTML, endHTML, startFragment, endFragment) + prefix + fragment + suffix)
+}
+
+// ParseCFHTML returns the fragment of a CF_HTML document, or the whole
+// HTML part when the fragment offsets are missing or out of range.
+func ParseCFHTML(data []byte) string {
+	offset := func(key string) int {
+		i := strings.Index(string(data[:min(len(data), 512)]), key+":")
+		if i < 0 {
+			return -1
+		}
+		rest := string(data[i+len(key)+1:])
+		if end := strings.IndexAny(rest, "\r\n"); end >= 0 {
+			rest = rest[:end]
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil || n < 0 || n > len(data) {
+			return -1
+		}
+		return n
+	}
+	start, end := offset("StartFragment"), offset("EndFragment")
+	if start >= 0 && end >= start {
+		return string(data[start:end])
+	}
+	start, end = offset("StartHTML"), offset("EndHTML")
+	if start >= 0 && end >= start {
+		return string(data[start:end])
+	}
+	return ""
+}
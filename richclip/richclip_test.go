@@ -0,0 +1,40 @@
+package richclip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCFHTMLRoundTrip(t *testing.T) {
+	fragment := "<table><tr><td><b>Q3</b></td><td>€ 1.200</td></tr></table>"
+	doc := BuildCFHTML(fragment)
+	if !strings.HasPrefix(string(doc), "Version:0.9\r\nStartHTML:") {
+		t.Fatalf("missing CF_HTML header: %q", doc[:40])
+	}
+	if got := ParseCFHTML(doc); got != fragment {
+		t.Fatalf("ParseCFHTML = %q, want %q", got, fragment)
+	}
+}
+
+func TestParseCFHTMLWithoutFragment(t *testing.T) {
+	html := "<html><body>hi</body></html>"
+	header := "Version:0.9\r\nStartHTML:0000000055\r\nEndHTML:0000000083\r\n"
+	if got := ParseCFHTML([]byte(header + html)); got != html {
+		t.Fatalf("ParseCFHTML = %q, want %q", got, html)
+	}
+	if got := ParseCFHTML([]byte("garbage")); got != "" {
+		t.Fatalf("ParseCFHTML(garbage) = %q, want empty", got)
+	}
+}
+
+func TestRichPreferred(t *testing.T) {
+	if f, _, _ := (Rich{HTML: "<b>x</b>", RTF: `{\rtf1 x}`}).Preferred(); f != FormatHTML {
+		t.Fatalf("HTML should win over RTF, got %q", f)
+	}
+	if f, c, _ := (Rich{RTF: `{\rtf1 x}`}).Preferred(); f != FormatRTF || c != `{\rtf1 x}` {
+		t.Fatalf("RTF only = %q %q", f, c)
+	}
+	if _, _, ok := (Rich{Text: "x"}).Preferred(); ok {
+		t.Fatal("plain text is not rich")
+	}
+}
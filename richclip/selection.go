@@ -0,0 +1,58 @@
+//go:build !windows && !darwin
+
+package richclip
+
+import (
+	"slices"
+
+	"github.com/stangtennis/Remote/linuxclip"
+)
+
+// MIME types browsers and office suites offer rich text as.
+var (
+	mimeHTML = []string{"text/html"}
+	mimeRTF  = []string{"text/rtf", "application/rtf"}
+)
+
+// ReadSelection reads text/html and RTF from CLIPBOARD plus the plain text
+// copied with them. ok is false when neither is offered.
+func ReadSelection(conn linuxclip.Conn) (Rich, bool) {
+	targets, err := conn.Targets(linuxclip.Clipboard)
+	if err != nil {
+		return Rich{}, false
+	}
+	read := func(types []string) string {
+		for _, t := range types {
+			if !slices.Contains(targets, t) {
+				continue
+			}
+			data, err := conn.Read(linuxclip.Clipboard, t)
+			if err != nil || len(data) > MaxBytes {
+				return ""
+			}
+			return string(data)
+		}
+		return ""
+	}
+	r := Rich{HTML: read(mimeHTML), RTF: read(mimeRTF)}
+	if r.HTML == "" && r.RTF == "" {
+		return Rich{}, false
+	}
+	r.Text = read(linuxclip.TextTypes)
+	return r, true
+}
+
+// WriteSelection offers HTML or RTF on CLIPBOARD with its plain-text
+// alternative. On Wayland wl-copy serves a single type, so only the rich
+// one is offered.
+func WriteSelection(conn linuxclip.Conn, format, content, text string) error {
+	mime := mimeHTML[0]
+	if format == FormatRTF {
+		mime = mimeRTF[0]
+	}
+	items := []linuxclip.Item{{Type: mime, Data: []byte(content)}}
+	if text != "" {
+		items = append(items, linuxclip.Item{Type: linuxclip.TextType, Data: []byte(text)})
+	}
+	return conn.Write(linuxclip.Clipboard, items)
+}
@@ -0,0 +1,3 @@
+module github.com/stangtennis/Remote/scriptlib
+
+go 1.24.0
@@ -0,0 +1,28 @@
+package scriptlib
+
+import (
+	"crypto/ed25519"
+	"os"
+	"strings"
+)
+
+// builtinKeys are the script signing public keys (base64) trusted by every
+// agent build. Generate a pair with `remote-desktop-cli scripts keygen` and
+// add the public key here; keep the private key offline.
+//
+// The list ships empty on purpose: script signing keys belong to whoever
+// runs the deployment, not to this repository. Until a key is added here or
+// set in RD_SCRIPT_SIGNING_KEYS, the agent refuses every library script and
+// every queued run_script job (Verify returns ErrNoTrustedKeys).
+var builtinKeys = []string{}
+
+// AgentTrustedKeys returns the built-in keys plus any listed in
+// RD_SCRIPT_SIGNING_KEYS (comma-separated base64), which lets an admin trust
+// an organisation key without rebuilding the agent.
+func AgentTrustedKeys() map[string]ed25519.PublicKey {
+	keys := append([]string{}, builtinKeys...)
+	if extra := os.Getenv("RD_SCRIPT_SIGNING_KEYS"); extra != "" {
+		keys = append(keys, strings.Split(extra, ",")...)
+	}
+	return TrustedKeys(keys)
+}
@@ -0,0 +1,397 @@
+// Package scriptlib implements the signed, parameterized script library.
+//
+// Scripts live in the Supabase "scripts" table. Each version is signed
+// offline with an ed25519 key; the agent only runs a script whose signature
+// verifies against one of its trusted keys. Parameters are never spliced
+// into the body: they are validated against the script's schema and bound
+// as shell variables in a prologue ($Name in PowerShell, $name in bash).
+package scriptlib
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxBodyBytes caps script bodies so a script plus the request envelope fits
+// in one data channel message.
+const MaxBodyBytes = 48 * 1024
+
+// ErrNoTrustedKeys is returned by Verify when no signing key is trusted at
+// all, which is how an agent starts out (see AgentTrustedKeys).
+var ErrNoTrustedKeys = errors.New("no script signing keys are trusted (set RD_SCRIPT_SIGNING_KEYS on the agent)")
+
+// signingContext is prepended to the signed payload so a script signature can
+// never be replayed as a signature for anything else.
+const signingContext = "remote-desktop-script-v1\n"
+
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// bashReserved are variables a bash prologue must not set: they change how
+// the body is parsed or which binaries and libraries it loads. Exact names
+// (bash is case-sensitive); see bashReservedPrefixes for whole families.
+var bashReserved = map[string]bool{
+	"PATH": true, "IFS": true, "ENV": true, "BASH_ENV": true, "SHELLOPTS": true,
+	"BASHOPTS": true, "CDPATH": true, "GLOBIGNORE": true, "PROMPT_COMMAND": true,
+	"PS4": true, "HOME": true, "SHELL": true, "PWD": true, "OLDPWD": true,
+	"TMPDIR": true, "PYTHONPATH": true, "PERL5LIB": true, "NODE_OPTIONS": true,
+}
+
+var bashReservedPrefixes = []string{"LD_", "DYLD_", "BASH_", "LC_"}
+
+// psReserved are PowerShell automatic and preference variables (compared
+// lower-case; PowerShell variables are case-insensitive). Names ending in
+// "preference" are reserved as well.
+var psReserved = map[string]bool{
+	"_": true, "args": true, "input": true, "this": true, "psitem": true,
+	"true": true, "false": true, "null": true, "host": true, "home": true,
+	"pid": true, "pwd": true, "error": true, "executioncontext": true,
+	"myinvocation": true, "psboundparameters": true, "pscmdlet": true,
+	"pscommandpath": true, "psscriptroot": true, "pshome": true, "shellid": true,
+	"lastexitcode": true, "matches": true, "ofs": true, "stacktrace": true,
+	"psdefaultparametervalues": true, "psversiontable": true,
+}
+
+// reservedParam reports whether name would clobber a variable the shell for
+// goos relies on.
+func reservedParam(goos, name string) bool {
+	if goos == "windows" {
+		lower := strings.ToLower(name)
+		return psReserved[lower] || strings.HasSuffix(lower, "preference")
+	}
+	if bashReserved[name] {
+		return true
+	}
+	for _, prefix := range bashReservedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Param describes one script parameter.
+type Param struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // string | int | bool | enum
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Values      []string `json:"values,omitempty"`  // allowed values for enum
+	Pattern     string   `json:"pattern,omitempty"` // optional regexp for string
+}
+
+// Script is one version of a library script.
+type Script struct {
+	ID          string  `json:"id,omitempty"`
+	Name        string  `json:"name"`
+	Version     int     `json:"version"`
+	Platform    string  `json:"platform"` // windows | darwin | linux | any
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params"`
+	Body        string  `json:"body"`
+	KeyID       string  `json:"key_id,omitempty"`
+	Signature   string  `json:"signature,omitempty"` // base64 ed25519
+}
+
+// signedFields is what the signature covers. A fixed struct keeps the JSON
+// encoding stable regardless of how the row was stored (jsonb reorders keys).
+type signedFields struct {
+	Name     string  `json:"name"`
+	Version  int     `json:"version"`
+	Platform string  `json:"platform"`
+	Params   []Param `json:"params"`
+	Body     string  `json:"body"`
+}
+
+// SigningPayload returns the bytes the signature is computed over.
+func (s *Script) SigningPayload() []byte {
+	params := s.Params
+	if params == nil {
+		params = []Param{}
+	}
+	data, _ := json.Marshal(signedFields{
+		Name:     s.Name,
+		Version:  s.Version,
+		Platform: s.Platform,
+		Params:   params,
+		Body:     s.Body,
+	})
+	return append([]byte(signingContext), data...)
+}
+
+// KeyID returns the short identifier of a public key (first 8 bytes of its
+// SHA-256, hex).
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key.
+func ParsePublicKey(b64 string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParsePrivateKey decodes a base64 ed25519 seed or full private key.
+func ParsePrivateKey(b64 string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("invalid private key length %d", len(raw))
+	}
+}
+
+// Validate checks the script definition itself (not the signature).
+func (s *Script) Validate() error {
+	if s.Name == "" {
+		return errors.New("script name is required")
+	}
+	if s.Version < 1 {
+		return errors.New("script version must be >= 1")
+	}
+	switch s.Platform {
+	case "windows", "darwin", "linux", "any":
+	default:
+		return fmt.Errorf("invalid platform %q", s.Platform)
+	}
+	if strings.TrimSpace(s.Body) == "" {
+		return errors.New("script body is empty")
+	}
+	if len(s.Body) > MaxBodyBytes {
+		return fmt.Errorf("script body exceeds %d bytes", MaxBodyBytes)
+	}
+	seen := map[string]bool{}
+	for _, p := range s.Params {
+		if !paramNameRe.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		for _, goos := range s.targets() {
+			if reservedParam(goos, p.Name) {
+				return fmt.Errorf("parameter name %q is reserved on %s", p.Name, goos)
+			}
+		}
+		key := strings.ToLower(p.Name) // PowerShell variables are case-insensitive
+		if seen[key] {
+			return fmt.Errorf("duplicate parameter %q", p.Name)
+		}
+		seen[key] = true
+		switch p.Type {
+		case "string", "int", "bool":
+		case "enum":
+			if len(p.Values) == 0 {
+				return fmt.Errorf("enum parameter %q has no values", p.Name)
+			}
+		default:
+			return fmt.Errorf("parameter %q has invalid type %q", p.Name, p.Type)
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("parameter %q has invalid pattern: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// targets returns the platforms whose shell the script is rendered for.
+func (s *Script) targets() []string {
+	if s.Platform == "any" {
+		return []string{"windows", "linux"}
+	}
+	return []string{s.Platform}
+}
+
+// Sign validates the script and sets KeyID and Signature.
+func (s *Script) Sign(priv ed25519.PrivateKey) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	s.KeyID = KeyID(pub)
+	s.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, s.SigningPayload()))
+	return nil
+}
+
+// Verify checks the signature against the trusted keys (keyed by KeyID).
+func (s *Script) Verify(trusted map[string]ed25519.PublicKey) error {
+	if s.Signature == "" {
+		return errors.New("script is not signed")
+	}
+	if len(trusted) == 0 {
+		return ErrNoTrustedKeys
+	}
+	pub, ok := trusted[s.KeyID]
+	if !ok {
+		return fmt.Errorf("script signed with untrusted key %q", s.KeyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(pub, s.SigningPayload(), sig) {
+		return errors.New("signature verification failed")
+	}
+	return s.Validate()
+}
+
+// ResolveParams validates user-supplied values against the schema, applies
+// defaults and returns the final value of every declared parameter.
+func (s *Script) ResolveParams(values map[string]string) (map[string]string, error) {
+	declared := map[string]Param{}
+	for _, p := range s.Params {
+		declared[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+
+	out := make(map[string]string, len(s.Params))
+	for _, p := range s.Params {
+		v, ok := values[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter %q", p.Name)
+			}
+			v = p.Default
+		}
+		switch p.Type {
+		case "int":
+			if v == "" {
+				v = "0"
+			}
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, fmt.Errorf("parameter %q must be an integer", p.Name)
+			}
+		case "bool":
+			if v == "" {
+				v = "false"
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %q must be true or false", p.Name)
+			}
+			v = strconv.FormatBool(b)
+		case "enum":
+			if ok || v != "" {
+				found := false
+				for _, allowed := range p.Values {
+					if v == allowed {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return nil, fmt.Errorf("parameter %q must be one of %s", p.Name, strings.Join(p.Values, ", "))
+				}
+			}
+		case "string":
+			if strings.ContainsRune(v, 0) {
+				return nil, fmt.Errorf("parameter %q contains a NUL byte", p.Name)
+			}
+		}
+		if p.Pattern != "" && (ok || v != "") {
+			if re, err := regexp.Compile(p.Pattern); err != nil || !re.MatchString(v) {
+				return nil, fmt.Errorf("parameter %q does not match %s", p.Name, p.Pattern)
+			}
+		}
+		out[p.Name] = v
+	}
+	return out, nil
+}
+
+// Render returns the command text for goos: a prologue binding the resolved
+// parameters as variables, followed by the script body.
+func (s *Script) Render(goos string, values map[string]string) (string, error) {
+	if s.Platform != "any" && s.Platform != goos {
+		return "", fmt.Errorf("script is for %s, this device runs %s", s.Platform, goos)
+	}
+	for _, p := range s.Params {
+		if reservedParam(goos, p.Name) {
+			return "", fmt.Errorf("parameter name %q is reserved on %s", p.Name, goos)
+		}
+	}
+	resolved, err := s.ResolveParams(values)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(resolved))
+	for name := range resolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	types := map[string]string{}
+	for _, p := range s.Params {
+		types[p.Name] = p.Type
+	}
+
+	var b strings.Builder
+	for _, name := range names {
+		v := resolved[name]
+		if goos == "windows" {
+			switch types[name] {
+			case "int":
+				fmt.Fprintf(&b, "$%s = %s\n", name, v)
+			case "bool":
+				fmt.Fprintf(&b, "$%s = $%s\n", name, v)
+			default:
+				fmt.Fprintf(&b, "$%s = '%s'\n", name, psQuoteEscaper.Replace(v))
+			}
+		} else {
+			fmt.Fprintf(&b, "%s='%s'\n", name, strings.ReplaceAll(v, "'", `'\''`))
+		}
+	}
+	b.WriteString(s.Body)
+	return b.String(), nil
+}
+
+// psQuoteEscaper doubles every character PowerShell accepts as a single
+// quote, including the typographic ones.
+var psQuoteEscaper = strings.NewReplacer(
+	"'", "''",
+	"\u2018", "\u2018\u2018",
+	"\u2019", "\u2019\u2019",
+	"\u201A", "\u201A\u201A",
+	"\u201B", "\u201B\u201B",
+)
+
+// TrustedKeys builds the key set used by Verify from base64 public keys.
+// Invalid entries are skipped.
+func TrustedKeys(b64Keys []string) map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, k := range b64Keys {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		pub, err := ParsePublicKey(k)
+		if err != nil {
+			continue
+		}
+		keys[KeyID(pub)] = pub
+	}
+	return keys
+}
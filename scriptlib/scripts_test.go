@@ -0,0 +1,161 @@
+package scriptlib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func testScript() *Script {
+	return &Script{
+		Name:     "clear-temp",
+		Version:  2,
+		Platform: "any",
+		Params: []Param{
+			{Name: "Path", Type: "string", Required: true},
+			{Name: "Days", Type: "int", Default: "7"},
+			{Name: "DryRun", Type: "bool"},
+			{Name: "Mode", Type: "enum", Values: []string{"fast", "full"}, Default: "fast"},
+		},
+		Body: "echo done",
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	trusted := map[string]ed25519.PublicKey{KeyID(pub): pub}
+
+	s := testScript()
+	if err := s.Sign(priv); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := s.Verify(trusted); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	tampered := *s
+	tampered.Body = "rm -rf /"
+	if err := tampered.Verify(trusted); err == nil {
+		t.Fatal("tampered body verified")
+	}
+
+	tampered = *s
+	tampered.Params = append([]Param{}, s.Params...)
+	tampered.Params[0].Pattern = ".*"
+	if err := tampered.Verify(trusted); err == nil {
+		t.Fatal("tampered params verified")
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if err := s.Verify(map[string]ed25519.PublicKey{KeyID(otherPub): otherPub}); err == nil {
+		t.Fatal("verified with untrusted key")
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	s := testScript()
+	if _, err := s.ResolveParams(map[string]string{}); err == nil {
+		t.Error("missing required parameter accepted")
+	}
+	if _, err := s.ResolveParams(map[string]string{"Path": "x", "Other": "1"}); err == nil {
+		t.Error("unknown parameter accepted")
+	}
+	if _, err := s.ResolveParams(map[string]string{"Path": "x", "Days": "1; rm"}); err == nil {
+		t.Error("non-integer accepted")
+	}
+	if _, err := s.ResolveParams(map[string]string{"Path": "x", "Mode": "other"}); err == nil {
+		t.Error("invalid enum accepted")
+	}
+	got, err := s.ResolveParams(map[string]string{"Path": "x", "DryRun": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["Days"] != "7" || got["DryRun"] != "true" || got["Mode"] != "fast" {
+		t.Errorf("unexpected resolved values: %v", got)
+	}
+}
+
+func TestRenderQuoting(t *testing.T) {
+	s := testScript()
+	params := map[string]string{"Path": "C:\\it's \u2019here'; Remove-Item *"}
+
+	ps, err := s.Render("windows", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ps, "$Path = 'C:\\it''s \u2019\u2019here''; Remove-Item *'\n") {
+		t.Errorf("PowerShell quoting wrong:\n%s", ps)
+	}
+	if !strings.Contains(ps, "$DryRun = $false\n") || !strings.HasSuffix(ps, "echo done") {
+		t.Errorf("PowerShell prologue wrong:\n%s", ps)
+	}
+
+	sh, err := s.Render("darwin", map[string]string{"Path": "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sh, `Path='it'\''s'`+"\n") {
+		t.Errorf("bash quoting wrong:\n%s", sh)
+	}
+
+	s.Platform = "windows"
+	if _, err := s.Render("darwin", map[string]string{"Path": "x"}); err == nil {
+		t.Error("rendered a windows script for darwin")
+	}
+}
+
+func TestReservedParamNames(t *testing.T) {
+	tests := []struct {
+		platform string
+		name     string
+		reserved bool
+	}{
+		{"linux", "PATH", true},
+		{"linux", "IFS", true},
+		{"darwin", "LD_PRELOAD", true},
+		{"darwin", "DYLD_INSERT_LIBRARIES", true},
+		{"linux", "BASH_ENV", true},
+		{"linux", "Path", false}, // bash is case-sensitive
+		{"linux", "Target", false},
+		{"windows", "PATH", false}, // PowerShell reads it as $env:PATH
+		{"windows", "args", true},
+		{"windows", "Input", true},
+		{"windows", "ErrorActionPreference", true},
+		{"windows", "Target", false},
+		{"any", "IFS", true},
+		{"any", "Host", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform+"/"+tt.name, func(t *testing.T) {
+			s := testScript()
+			s.Platform = tt.platform
+			s.Params = []Param{{Name: tt.name, Type: "string"}}
+			err := s.Validate()
+			if tt.reserved && (err == nil || !strings.Contains(err.Error(), "reserved")) {
+				t.Errorf("Validate() = %v, want reserved error", err)
+			}
+			if !tt.reserved && err != nil {
+				t.Errorf("Validate() = %v, want ok", err)
+			}
+		})
+	}
+
+	// Render refuses too, for scripts that never went through Validate.
+	s := testScript()
+	s.Params = []Param{{Name: "LD_PRELOAD", Type: "string", Default: "/tmp/x.so"}}
+	if _, err := s.Render("linux", nil); err == nil {
+		t.Error("rendered a bash prologue that sets LD_PRELOAD")
+	}
+}
+
+func TestVerifyWithoutKeys(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	s := testScript()
+	if err := s.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(nil); err != ErrNoTrustedKeys {
+		t.Errorf("Verify(nil) = %v, want ErrNoTrustedKeys", err)
+	}
+}
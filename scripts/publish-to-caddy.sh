@@ -4,6 +4,15 @@ set -euo pipefail
 if [[ $# -lt 1 ]]; then
   echo "Usage: $0 <version> [downloads_dir]"
   echo "Example: $0 v3.1.51 /home/dennis/caddy/downloads"
+  echo "Signs with the key file(s) in RELEASE_SIGNING_KEY (comma-separated; see controller/cmd/sign-release)"
+  echo "Agent rollout: ROLLOUT_PERCENT (default 100), ROLLOUT_TAGS (comma-separated device tags),"
+  echo "               HEALTH_TIMEOUT_MIN (default 10; minutes before a silent agent rolls back)"
+  echo "Agent deltas:  DELTA_FROM (default 3; zstd patches from that many earlier versions, 0 = none)"
+  exit 1
+fi
+
+if [[ -z "${RELEASE_SIGNING_KEY:-}" ]]; then
+  echo "RELEASE_SIGNING_KEY is not set — agents and controllers refuse unsigned updates" >&2
   exit 1
 fi
 
@@ -23,6 +32,7 @@ copy_file() {
 }
 
 mkdir -p "$DOWNLOADS_DIR"
+DOWNLOADS_DIR="$(cd "$DOWNLOADS_DIR" && pwd)"
 
 AGENT_EXE="$BUILDS_DIR/remote-agent-${VERSION}.exe"
 SUPPORT_EXE="$BUILDS_DIR/remote-support-${VERSION}.exe"
@@ -90,6 +100,38 @@ AGENT_MAC_SHA256="$(sha256sum "$DOWNLOADS_DIR/remote-agent-macos-${VERSION}" | a
 CONTROLLER_SHA256="$(sha256sum "$DOWNLOADS_DIR/controller-${VERSION}.exe" | awk '{print $1}')"
 CONTROLLER_MAC_SHA256="$(sha256sum "$DOWNLOADS_DIR/controller-macos-${VERSION}" | awk '{print $1}')"
 
+# zstd --patch-from deltas from the last DELTA_FROM published agent
+# versions. Agents whose binary matches from_sha256 download the patch
+# instead of the whole binary; the result is checked against agent_sha256
+# and the release signature, with the full download as fallback.
+DELTA_FROM="${DELTA_FROM:-3}"
+make_deltas() {
+  local prefix="$1" suffix="$2" target="$3" json="" old from patch
+  [[ "$DELTA_FROM" -gt 0 ]] && command -v zstd >/dev/null || { echo ""; return; }
+  while read -r old; do
+    [[ -n "$old" ]] || continue
+    from="${old#"$DOWNLOADS_DIR/$prefix"}"
+    from="${from%"$suffix"}"
+    [[ "$from" == "$VERSION" ]] && continue
+    patch="${prefix}${from}-to-${VERSION}${suffix}.zst"
+    zstd -q -f -19 --patch-from="$old" "$target" -o "$DOWNLOADS_DIR/$patch" || continue
+    json+="${json:+, }{\"from\": \"${from}\", \"format\": \"zstd\", \"url\": \"https://updates.hawkeye123.dk/${patch}\", \"size\": $(stat -c %s "$DOWNLOADS_DIR/$patch"), \"from_sha256\": \"$(sha256sum "$old" | awk '{print $1}')\"}"
+  done < <(ls "$DOWNLOADS_DIR/$prefix"v*"$suffix" 2>/dev/null | grep -v -- "-to-" | grep -vF "$DOWNLOADS_DIR/$prefix$VERSION$suffix" | sort -V | tail -n "$DELTA_FROM")
+  echo "$json"
+}
+AGENT_DELTAS="$(make_deltas "remote-agent-" ".exe" "$DOWNLOADS_DIR/remote-agent-${VERSION}.exe")"
+AGENT_DELTAS_MAC="$(make_deltas "remote-agent-macos-" "" "$DOWNLOADS_DIR/remote-agent-macos-${VERSION}")"
+
+# Rollout ring for agents. To widen a rollout later, edit "rollout" in
+# version.json and sign it again with sign-release.
+ROLLOUT_PERCENT="${ROLLOUT_PERCENT:-100}"
+HEALTH_TIMEOUT_MIN="${HEALTH_TIMEOUT_MIN:-10}"
+ROLLOUT_TAGS_JSON=""
+IFS=',' read -ra TAGS <<< "${ROLLOUT_TAGS:-}"
+for t in "${TAGS[@]}"; do
+  [[ -n "$t" ]] && ROLLOUT_TAGS_JSON+="${ROLLOUT_TAGS_JSON:+, }\"$t\""
+done
+
 cat > "$DOWNLOADS_DIR/version.json" <<EOF
 {
   "agent_version": "${VERSION}",
@@ -103,11 +145,35 @@ cat > "$DOWNLOADS_DIR/version.json" <<EOF
   "support_sha256": "${SUPPORT_SHA256}",
   "agent_sha256_macos": "${AGENT_MAC_SHA256}",
   "controller_sha256": "${CONTROLLER_SHA256}",
-  "controller_sha256_macos": "${CONTROLLER_MAC_SHA256}"
+  "controller_sha256_macos": "${CONTROLLER_MAC_SHA256}",
+  "agent_deltas": [${AGENT_DELTAS}],
+  "agent_deltas_macos": [${AGENT_DELTAS_MAC}],
+  "rollout": {
+    "percent": ${ROLLOUT_PERCENT},
+    "tags": [${ROLLOUT_TAGS_JSON}],
+    "health_timeout_min": ${HEALTH_TIMEOUT_MIN}
+  }
 }
 EOF
 
+# Detached signatures (<file>.sig) checked by the updaters
+SIGNING_KEYS=()
+IFS=',' read -ra KEY_FILES <<< "$RELEASE_SIGNING_KEY"
+for k in "${KEY_FILES[@]}"; do
+  require_file "$k"
+  SIGNING_KEYS+=("$(realpath "$k")")
+done
+(cd controller && go run ./cmd/sign-release -key "$(IFS=','; echo "${SIGNING_KEYS[*]}")" \
+  "$DOWNLOADS_DIR/version.json" \
+  "$DOWNLOADS_DIR/remote-agent-${VERSION}.exe" \
+  "$DOWNLOADS_DIR/remote-agent-console-${VERSION}.exe" \
+  "$DOWNLOADS_DIR/$SUPPORT_IMMUTABLE" \
+  "$DOWNLOADS_DIR/remote-agent-macos-${VERSION}" \
+  "$DOWNLOADS_DIR/controller-${VERSION}.exe" \
+  "$DOWNLOADS_DIR/controller-macos-${VERSION}")
+
 echo "Published ${VERSION} to ${DOWNLOADS_DIR}"
 echo "agent_sha256=${AGENT_SHA256}"
 echo "support_sha256=${SUPPORT_SHA256}"
 echo "controller_sha256=${CONTROLLER_SHA256}"
+echo "rollout=${ROLLOUT_PERCENT}% tags=[${ROLLOUT_TAGS_JSON}] health_timeout=${HEALTH_TIMEOUT_MIN}m"
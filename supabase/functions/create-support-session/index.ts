@@ -9,6 +9,31 @@ const corsHeaders = {
   'Access-Control-Allow-Headers': 'authorization, x-client-info, apikey, content-type',
 }
 
+const CLIPBOARD_DIRECTIONS = ['both', 'to_agent', 'from_agent', 'off']
+const CLIPBOARD_FORMATS = ['text', 'image', 'html', 'rtf', 'files']
+
+// Keeps the known fields of a requested clipboard policy (see the
+// support_clipboard_policy migration). An empty format list means no
+// clipboard at all rather than every format.
+function sanitizeClipboardPolicy(value: unknown) {
+  if (!value || typeof value !== 'object' || Array.isArray(value)) return null
+  const raw = value as Record<string, unknown>
+  const policy: Record<string, unknown> = {
+    direction: CLIPBOARD_DIRECTIONS.includes(raw.direction as string) ? raw.direction : 'both',
+  }
+  if (Number.isSafeInteger(raw.max_bytes) && (raw.max_bytes as number) > 0) {
+    policy.max_bytes = raw.max_bytes
+  }
+  if (Array.isArray(raw.formats)) {
+    const formats = [...new Set(raw.formats.filter((format: unknown) =>
+      typeof format === 'string' && CLIPBOARD_FORMATS.includes(format)
+    ))]
+    if (formats.length === 0) return { direction: 'off' }
+    if (formats.length < CLIPBOARD_FORMATS.length) policy.formats = formats
+  }
+  return policy
+}
+
 serve(async (req) => {
   if (req.method === 'OPTIONS') {
     return new Response('ok', { headers: corsHeaders })
@@ -56,7 +81,7 @@ serve(async (req) => {
     }
 
     const supportMode = body.support_mode === 'ai' ? 'ai' : 'screen'
-    const allowedScopes = ['screen', 'input', 'files', 'terminal', 'process', 'admin']
+    const allowedScopes = ['screen', 'input', 'files', 'terminal', 'process', 'print', 'admin']
     const requestedScopes = Array.isArray(body.requested_scopes)
       ? [...new Set(body.requested_scopes.filter((scope: unknown) =>
           typeof scope === 'string' && allowedScopes.includes(scope)
@@ -66,6 +91,7 @@ serve(async (req) => {
     if (requestedScopes.length === 0 || !requestedScopes.includes('screen')) {
       throw new Error('A support session must include the screen scope')
     }
+    const clipboardPolicy = sanitizeClipboardPolicy(body.clipboard_policy)
 
     // Generate 6-digit PIN using cryptographically strong randomness
     const pinBuf = new Uint32Array(1)
@@ -89,6 +115,7 @@ serve(async (req) => {
         expires_at,
         support_mode: supportMode,
         requested_scopes: requestedScopes,
+        clipboard_policy: clipboardPolicy,
         requires_client_code: supportMode === 'ai',
         controller_requested: false,
       })
@@ -113,6 +140,7 @@ serve(async (req) => {
       summary: `Created ${supportMode} support session`,
       details: {
         requested_scopes: requestedScopes,
+        clipboard_policy: clipboardPolicy,
         requires_client_code: supportMode === 'ai',
         expires_at,
       },
@@ -136,6 +164,7 @@ serve(async (req) => {
         expires_at,
         support_mode: supportMode,
         requested_scopes: requestedScopes,
+        clipboard_policy: clipboardPolicy,
         requires_client_code: supportMode === 'ai',
       }),
       {
@@ -17,7 +17,7 @@ const TURN_SERVER = Deno.env.get('TURN_SERVER') || ''
 const TURN_SECRET = Deno.env.get('TURN_SECRET') || ''
 const TURN_TTL = parseInt(Deno.env.get('TURN_TTL') || '3600')
 
-const AI_SCOPES = ['screen', 'input', 'files', 'terminal', 'process', 'admin']
+const AI_SCOPES = ['screen', 'input', 'files', 'terminal', 'process', 'print', 'admin']
 
 function response(body: unknown, status = 200) {
   return new Response(JSON.stringify(body), {
@@ -37,6 +37,7 @@ function requiredActionScope(actionType: string) {
   if (actionType.startsWith('SHELL_') || actionType.startsWith('TERMINAL_')) return 'terminal'
   if (actionType.startsWith('PROCESS_')) return 'process'
   if (actionType.startsWith('FILE_')) return 'files'
+  if (actionType.startsWith('PRINT_')) return 'print'
   if (actionType.startsWith('INPUT_') || actionType.startsWith('CLIPBOARD_')) return 'input'
   if (actionType.startsWith('ADMIN_')) return 'admin'
   return ''
@@ -46,30 +47,47 @@ function requiredActionScopes(actionType: string) {
   const scopes = []
   const primary = requiredActionScope(actionType)
   if (primary) scopes.push(primary)
-  if (actionType.startsWith('SHELL_') || actionType.startsWith('TERMINAL_') || actionType === 'PROCESS_KILL') {
+  if (actionType.startsWith('SHELL_') || actionType.startsWith('TERMINAL_') || actionType === 'PROCESS_KILL' ||
+    actionType === 'PROCESS_REG_SET' || actionType === 'PROCESS_REG_DELETE' || actionType === 'PROCESS_REG_IMPORT' ||
+    (actionType.startsWith('PROCESS_SVC_') && actionType !== 'PROCESS_SVC_LIST')) {
     scopes.push('admin')
   }
   return scopes
 }
 
 const ALLOWED_SUPPORT_ACTIONS = new Set([
-  'SCREEN_SCREENSHOT', 'INPUT_CLICK', 'INPUT_TYPE', 'INPUT_KEY', 'INPUT_SCROLL',
-  'INPUT_MOUSE_CLICK', 'INPUT_MOUSE_SCROLL', 'SHELL_EXEC', 'FILE_UPLOAD',
-  'FILE_DOWNLOAD', 'FILE_OPERATION', 'TERMINAL_INPUT', 'TERMINAL_START',
-  'TERMINAL_CLOSE', 'PROCESS_PS', 'PROCESS_KILL', 'PROCESS_SYSINFO',
-  'ADMIN_REMOTE_LOGIN', 'ADMIN_FORCE_UPDATE',
+  'SCREEN_SCREENSHOT', 'SCREEN_MONITOR', 'INPUT_CLICK', 'INPUT_TYPE', 'INPUT_KEY', 'INPUT_SCROLL',
+  'INPUT_MOUSE_CLICK', 'INPUT_MOUSE_SCROLL', 'SHELL_EXEC', 'SHELL_SCRIPT', 'FILE_UPLOAD',
+  'FILE_DOWNLOAD', 'FILE_CLIPBOARD_PASTE', 'FILE_OPERATION', 'TERMINAL_INPUT', 'TERMINAL_START',
+  'TERMINAL_CLOSE', 'PROCESS_PS', 'PROCESS_KILL', 'PROCESS_SYSINFO', 'PROCESS_STATS',
+  'PROCESS_REG_LIST', 'PROCESS_REG_GET', 'PROCESS_REG_SET', 'PROCESS_REG_DELETE',
+  'PROCESS_REG_EXPORT', 'PROCESS_REG_IMPORT',
+  'PROCESS_SVC_LIST', 'PROCESS_SVC_START', 'PROCESS_SVC_STOP', 'PROCESS_SVC_RESTART', 'PROCESS_SVC_SET_STARTUP',
+  'PROCESS_LOG_QUERY', 'PROCESS_LOG_STOP',
+  'ADMIN_REMOTE_LOGIN', 'ADMIN_FORCE_UPDATE', 'ADMIN_LOCAL_LOCK', 'ADMIN_LOCAL_UNLOCK',
+  'ADMIN_RESTART_SAFE_MODE',
+  'CLIPBOARD_TO_AGENT', 'CLIPBOARD_FROM_AGENT', 'CLIPBOARD_POLICY',
+  'PRINT_ENABLE', 'PRINT_DISABLE', 'PRINT_JOB',
 ])
 
 function safeActionDetails(value: unknown) {
   const allowed = new Set([
     'action_id', 'exit_code', 'duration_ms', 'bytes', 'items', 'result', 'error',
-    'scope', 'path', 'operation', 'reason', 'as_user', 'length', 'command_length', 'command_sha256',
+    'scope', 'path', 'operation', 'reason', 'as_user', 'length', 'command_length', 'command_sha256', 'via',
+    'service', 'script_name', 'script_version', 'params', 'index', 'delay_sec',
   ])
   if (!value || typeof value !== 'object' || Array.isArray(value)) return {}
   return Object.fromEntries(Object.entries(value)
     .filter(([key]) => allowed.has(key))
     .slice(0, 20)
-    .map(([key, item]) => [key, typeof item === 'string' ? redactText(item, 500) : item]))
+    .map(([key, item]) => [key, safeDetailValue(item)]))
+}
+
+// params is a list of parameter names; values are never sent.
+function safeDetailValue(item: unknown): unknown {
+  if (typeof item === 'string') return redactText(item, 500)
+  if (Array.isArray(item)) return item.slice(0, 50).map((v) => typeof v === 'string' ? redactText(v, 100) : null)
+  return item
 }
 
 async function auditSupportEvent(supabase: any, event: Record<string, unknown>) {
@@ -672,6 +690,7 @@ serve(async (req) => {
           status: session.status,
           support_mode: session.support_mode,
           requested_scopes: session.requested_scopes || ['screen'],
+          clipboard_policy: session.clipboard_policy || null,
           expires_at: session.expires_at,
         })
       }
@@ -715,7 +734,7 @@ serve(async (req) => {
           action_type: 'CLIENT_CONSENT_GRANTED',
           status: 'succeeded',
           summary: 'Client approved AI support scopes',
-           details: { scopes: chosen, client_label: typeof clientLabel === 'string' ? redactText(clientLabel, 120) : null, policy_version: 'ai-support-v1' },
+           details: { scopes: chosen, clipboard_policy: session.clipboard_policy || null, client_label: typeof clientLabel === 'string' ? redactText(clientLabel, 120) : null, policy_version: 'ai-support-v1' },
           completed_at: now,
         })
         if (!auditWritten) {
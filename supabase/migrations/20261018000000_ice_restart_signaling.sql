@@ -0,0 +1,18 @@
+-- ICE restart over signaling.
+--
+-- When a controller's network changes (Wi-Fi → LTE) the existing peer
+-- connection is renegotiated with an ICE restart instead of being torn
+-- down. The restart offer/answer travel over the control data channel when
+-- possible and over session_signaling when the old path is already gone.
+-- New msg_types keep them apart from the initial offer/answer so existing
+-- listeners (dashboard, agent waitForOffer) ignore them.
+
+ALTER TABLE public.session_signaling
+  DROP CONSTRAINT IF EXISTS session_signaling_msg_type_check;
+
+ALTER TABLE public.session_signaling
+  ADD CONSTRAINT session_signaling_msg_type_check
+  CHECK (msg_type IN ('offer', 'answer', 'ice', 'kick', 'bye', 'restart_offer', 'restart_answer'));
+
+CREATE INDEX IF NOT EXISTS idx_signaling_session_msg_type
+  ON public.session_signaling(session_id, msg_type, created_at);
@@ -0,0 +1,66 @@
+-- Per-session stats timeline.
+--
+-- The agent records a sample every 2s while a session is active (candidate
+-- pair type, RTT, jitter, loss, bitrate, encoder, streaming mode, send
+-- buffer) plus events (mode switches, candidate changes, disconnects, ICE
+-- restarts, buffer pressure). When the session ends the whole timeline is
+-- uploaded here so "it was laggy yesterday" can be investigated afterwards.
+
+CREATE TABLE IF NOT EXISTS public.session_stats (
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  session_id text NOT NULL,
+  device_id text NOT NULL,
+  started_at timestamptz NOT NULL,
+  ended_at timestamptz,
+  timeline jsonb NOT NULL,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_stats_device
+  ON public.session_stats(device_id, started_at DESC);
+
+ALTER TABLE public.session_stats ENABLE ROW LEVEL SECURITY;
+
+-- Agents upload their own timelines with the per-device api_key
+CREATE POLICY "Device writes own session stats via api_key" ON public.session_stats
+  FOR INSERT WITH CHECK (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = session_stats.device_id
+        AND d.api_key = current_setting('request.headers', true)::json->>'x-device-key'
+    )
+  );
+
+CREATE POLICY "Device owners read own session stats" ON public.session_stats
+  FOR SELECT USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = session_stats.device_id
+        AND d.owner_id = auth.uid()
+    )
+  );
+
+-- Keep 30 days of timelines
+CREATE OR REPLACE FUNCTION public.cleanup_old_session_stats()
+RETURNS void AS $$
+BEGIN
+  DELETE FROM public.session_stats
+  WHERE created_at < now() - interval '30 days';
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+-- Schedule daily cleanup (guarded — no-op if pg_cron absent).
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_cron') THEN
+    IF EXISTS (SELECT 1 FROM cron.job WHERE jobname = 'session-stats-cleanup') THEN
+      PERFORM cron.unschedule('session-stats-cleanup');
+    END IF;
+    PERFORM cron.schedule(
+      'session-stats-cleanup',
+      '30 3 * * *',
+      'SELECT public.cleanup_old_session_stats()'
+    );
+  END IF;
+EXCEPTION WHEN OTHERS THEN NULL;
+END $$;
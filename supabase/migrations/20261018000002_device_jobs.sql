@@ -0,0 +1,190 @@
+-- Queued and scheduled jobs per device.
+--
+-- remote_devices.pending_command only holds a single string that the agent
+-- clears on the next heartbeat — a second command overwrites the first, and
+-- nothing comes back. device_jobs replaces it for anything that needs a
+-- payload, a schedule or a result:
+--
+--   run_script      {script, params, as_user, timeout_sec} (script = signed scripts row)
+--   copy_file       {url, dest, sha256, bucket, path}   (url = signed storage URL)
+--   install_update  {}
+--   reboot          {delay_sec}                          (schedule via run_at)
+--
+-- The heartbeat response carries remote_devices.next_job_at (maintained by
+-- a trigger below), so agents only query device_jobs when something is due.
+-- pending_command keeps working for older dashboards.
+
+CREATE TABLE IF NOT EXISTS public.device_jobs (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  device_id text NOT NULL REFERENCES public.remote_devices(device_id) ON DELETE CASCADE,
+  created_by uuid DEFAULT auth.uid(),
+  job_type text NOT NULL
+    CHECK (job_type IN ('run_script', 'copy_file', 'install_update', 'reboot')),
+  payload jsonb NOT NULL DEFAULT '{}'::jsonb,
+  status text NOT NULL DEFAULT 'queued'
+    CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'expired', 'cancelled')),
+  run_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz,
+  max_attempts int NOT NULL DEFAULT 1 CHECK (max_attempts BETWEEN 1 AND 10),
+  attempts int NOT NULL DEFAULT 0,
+  retry_delay_sec int NOT NULL DEFAULT 300 CHECK (retry_delay_sec >= 0),
+  exit_code int,
+  output_tail text,
+  error text,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  started_at timestamptz,
+  finished_at timestamptz,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_jobs_due
+  ON public.device_jobs(device_id, run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_device_jobs_device_created
+  ON public.device_jobs(device_id, created_at DESC);
+
+ALTER TABLE public.device_jobs ENABLE ROW LEVEL SECURITY;
+
+-- Device owners manage their devices' jobs
+CREATE POLICY "Device owners read jobs" ON public.device_jobs
+  FOR SELECT USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.owner_id = auth.uid()
+    )
+  );
+
+CREATE POLICY "Device owners queue jobs" ON public.device_jobs
+  FOR INSERT WITH CHECK (
+    created_by = auth.uid()
+    AND status = 'queued'
+    AND EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.owner_id = auth.uid()
+    )
+  );
+
+CREATE POLICY "Device owners update jobs" ON public.device_jobs
+  FOR UPDATE USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.owner_id = auth.uid()
+    )
+  );
+
+CREATE POLICY "Device owners delete jobs" ON public.device_jobs
+  FOR DELETE USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.owner_id = auth.uid()
+    )
+  );
+
+-- Agents read and report on their own jobs with the per-device api_key
+CREATE POLICY "Device reads own jobs via api_key" ON public.device_jobs
+  FOR SELECT USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.api_key = current_setting('request.headers', true)::json->>'x-device-key'
+    )
+  );
+
+CREATE POLICY "Device updates own jobs via api_key" ON public.device_jobs
+  FOR UPDATE
+  USING (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.api_key = current_setting('request.headers', true)::json->>'x-device-key'
+    )
+  )
+  WITH CHECK (
+    EXISTS (
+      SELECT 1 FROM public.remote_devices d
+      WHERE d.device_id = device_jobs.device_id
+        AND d.api_key = current_setting('request.headers', true)::json->>'x-device-key'
+    )
+  );
+
+-- Agents may only touch status/result columns, never the job definition
+CREATE OR REPLACE FUNCTION public.device_jobs_guard()
+RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  IF auth.uid() IS NULL AND current_setting('request.headers', true)::json->>'x-device-key' IS NOT NULL THEN
+    NEW.job_type := OLD.job_type;
+    NEW.payload := OLD.payload;
+    NEW.max_attempts := OLD.max_attempts;
+    NEW.retry_delay_sec := OLD.retry_delay_sec;
+    NEW.expires_at := OLD.expires_at;
+    NEW.created_by := OLD.created_by;
+    IF OLD.status IN ('succeeded', 'failed', 'expired', 'cancelled') THEN
+      RAISE EXCEPTION 'job % is already finished', OLD.id;
+    END IF;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_device_jobs_guard ON public.device_jobs;
+CREATE TRIGGER trg_device_jobs_guard
+  BEFORE UPDATE ON public.device_jobs
+  FOR EACH ROW EXECUTE FUNCTION public.device_jobs_guard();
+
+-- next_job_at on remote_devices is what the heartbeat reads back
+ALTER TABLE public.remote_devices ADD COLUMN IF NOT EXISTS next_job_at timestamptz;
+
+CREATE OR REPLACE FUNCTION public.refresh_device_next_job()
+RETURNS trigger AS $$
+DECLARE
+  v_device text := COALESCE(NEW.device_id, OLD.device_id);
+BEGIN
+  UPDATE public.remote_devices
+  SET next_job_at = (
+    SELECT min(run_at) FROM public.device_jobs
+    WHERE device_id = v_device AND status = 'queued'
+  )
+  WHERE device_id = v_device;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = public;
+
+DROP TRIGGER IF EXISTS trg_device_jobs_next ON public.device_jobs;
+CREATE TRIGGER trg_device_jobs_next
+  AFTER INSERT OR UPDATE OF status, run_at OR DELETE ON public.device_jobs
+  FOR EACH ROW EXECUTE FUNCTION public.refresh_device_next_job();
+
+-- Expire queued jobs whose device never came back in time, and drop
+-- finished jobs after 90 days.
+CREATE OR REPLACE FUNCTION public.cleanup_device_jobs()
+RETURNS void AS $$
+BEGIN
+  UPDATE public.device_jobs
+  SET status = 'expired', finished_at = now(), error = 'expired before the device picked it up'
+  WHERE status = 'queued' AND expires_at IS NOT NULL AND expires_at < now();
+
+  DELETE FROM public.device_jobs
+  WHERE status IN ('succeeded', 'failed', 'expired', 'cancelled')
+    AND finished_at < now() - interval '90 days';
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+-- Schedule hourly cleanup (guarded — no-op if pg_cron absent).
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_cron') THEN
+    IF EXISTS (SELECT 1 FROM cron.job WHERE jobname = 'device-jobs-cleanup') THEN
+      PERFORM cron.unschedule('device-jobs-cleanup');
+    END IF;
+    PERFORM cron.schedule(
+      'device-jobs-cleanup',
+      '15 * * * *',
+      'SELECT public.cleanup_device_jobs()'
+    );
+  END IF;
+EXCEPTION WHEN OTHERS THEN NULL;
+END $$;
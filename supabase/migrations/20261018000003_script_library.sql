@@ -0,0 +1,39 @@
+-- Signed script library.
+--
+-- Reviewed scripts replace ad-hoc `exec` snippets. Every row is one
+-- immutable version: name + version are unique, and a change means
+-- publishing version n+1. The body, parameter schema and platform are
+-- covered by an ed25519 signature made offline with
+-- `remote-desktop-cli scripts publish`; agents refuse to run a script whose
+-- signature does not verify against a key they trust, so write access to
+-- this table alone is not enough to get code executed.
+
+CREATE TABLE IF NOT EXISTS public.scripts (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name text NOT NULL CHECK (name ~ '^[a-z0-9][a-z0-9._-]{0,63}$'),
+  version int NOT NULL CHECK (version >= 1),
+  platform text NOT NULL CHECK (platform IN ('windows', 'darwin', 'linux', 'any')),
+  description text,
+  params jsonb NOT NULL DEFAULT '[]'::jsonb,
+  body text NOT NULL,
+  key_id text NOT NULL,
+  signature text NOT NULL,
+  created_by uuid DEFAULT auth.uid(),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (name, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scripts_name_version
+  ON public.scripts(name, version DESC);
+
+ALTER TABLE public.scripts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Authenticated users read scripts" ON public.scripts
+  FOR SELECT USING (auth.uid() IS NOT NULL);
+
+CREATE POLICY "Authenticated users publish scripts" ON public.scripts
+  FOR INSERT WITH CHECK (auth.uid() IS NOT NULL AND created_by = auth.uid());
+
+-- Versions are immutable; only the publisher may withdraw one.
+CREATE POLICY "Publishers delete own scripts" ON public.scripts
+  FOR DELETE USING (created_by = auth.uid());
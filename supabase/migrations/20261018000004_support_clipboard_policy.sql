@@ -0,0 +1,10 @@
+-- Clipboard policy for support sessions.
+--
+-- Chosen by the admin when the session is created and returned to the
+-- support client with its scopes. The agent enforces it for the whole
+-- session: {"direction": "both|to_agent|from_agent|off", "max_bytes": n,
+-- "formats": ["text", "image", "html", "rtf", "files"]}. Missing fields
+-- mean no limit; NULL means no policy beyond the scopes.
+
+ALTER TABLE public.support_sessions
+  ADD COLUMN IF NOT EXISTS clipboard_policy JSONB;
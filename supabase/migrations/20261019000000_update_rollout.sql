@@ -0,0 +1,70 @@
+-- Staged agent rollouts.
+--
+-- Which devices take a release is decided by the agent against the
+-- "rollout" block of the signed version.json:
+--
+--   "rollout": {"percent": 10, "tags": ["canary"], "health_timeout_min": 10}
+--
+-- percent picks a stable share of devices per release, tags name
+-- device_tags whose devices always take it, and a per-device pin holds a
+-- device on one version. Agents read their pin and tags back from the
+-- heartbeat, so both live on remote_devices:
+--
+--   update_pin   set by the owner (dashboard/controller), NULL = follow rollout
+--   update_tags  copy of the device's device_tags, maintained by a trigger
+--
+-- Outcomes land in audit_logs as UPDATE_HEALTHY, UPDATE_ROLLED_BACK and
+-- UPDATE_ROLLBACK_FAILED (written by the agent).
+
+ALTER TABLE public.remote_devices ADD COLUMN IF NOT EXISTS update_pin text;
+ALTER TABLE public.remote_devices ADD COLUMN IF NOT EXISTS update_tags text[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN public.remote_devices.update_pin IS 'Agent version this device is held on; NULL follows the rollout in version.json';
+COMMENT ON COLUMN public.remote_devices.update_tags IS 'Copy of device_tags for update rollout rings (read back by the heartbeat)';
+
+CREATE OR REPLACE FUNCTION public.refresh_device_update_tags()
+RETURNS trigger AS $$
+DECLARE
+  v_device text := COALESCE(NEW.device_id, OLD.device_id);
+BEGIN
+  UPDATE public.remote_devices
+  SET update_tags = COALESCE((
+    SELECT array_agg(tag ORDER BY tag) FROM public.device_tags
+    WHERE device_id = v_device
+  ), '{}')
+  WHERE device_id = v_device;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = public;
+
+DROP TRIGGER IF EXISTS trg_device_tags_update_tags ON public.device_tags;
+CREATE TRIGGER trg_device_tags_update_tags
+  AFTER INSERT OR UPDATE OR DELETE ON public.device_tags
+  FOR EACH ROW EXECUTE FUNCTION public.refresh_device_update_tags();
+
+UPDATE public.remote_devices d
+SET update_tags = t.tags
+FROM (
+  SELECT device_id, array_agg(tag ORDER BY tag) AS tags
+  FROM public.device_tags
+  GROUP BY device_id
+) t
+WHERE t.device_id = d.device_id;
+
+-- The agent updates its own row with its api_key; it must not move itself
+-- in or out of a rollout.
+CREATE OR REPLACE FUNCTION public.remote_devices_rollout_guard()
+RETURNS trigger AS $$
+BEGIN
+  IF auth.uid() IS NULL AND current_setting('request.headers', true)::json->>'x-device-key' IS NOT NULL THEN
+    NEW.update_pin := OLD.update_pin;
+    NEW.update_tags := OLD.update_tags;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_remote_devices_rollout_guard ON public.remote_devices;
+CREATE TRIGGER trg_remote_devices_rollout_guard
+  BEFORE UPDATE OF update_pin, update_tags ON public.remote_devices
+  FOR EACH ROW EXECUTE FUNCTION public.remote_devices_rollout_guard();